@@ -3,11 +3,13 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	_ "embed"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/atinylittleshell/gsh/internal/analytics"
@@ -16,9 +18,12 @@ import (
 	"github.com/atinylittleshell/gsh/internal/completion"
 	"github.com/atinylittleshell/gsh/internal/config"
 	"github.com/atinylittleshell/gsh/internal/core"
+	"github.com/atinylittleshell/gsh/internal/crash"
 	"github.com/atinylittleshell/gsh/internal/environment"
 	"github.com/atinylittleshell/gsh/internal/evaluate"
 	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/atinylittleshell/gsh/internal/predict"
+	"github.com/atinylittleshell/gsh/internal/server"
 	"go.uber.org/zap"
 	"golang.org/x/term"
 	"mvdan.cc/sh/v3/expand"
@@ -27,12 +32,19 @@ import (
 
 var BUILD_VERSION = "dev"
 
+// crashReportCommands is how many recent commands go in a crash report
+const crashReportCommands = 20
+
 //go:embed .gshrc.default
 var DEFAULT_VARS []byte
 
 var command = flag.String("c", "", "run a command")
 var loginShell = flag.Bool("l", false, "run as a login shell")
 var rcFile = flag.String("rcfile", "", "use a custom rc file instead of ~/.gshrc")
+var completeLine = flag.String("complete", "", "print the completions of a command line as JSON and exit, the cursor position may follow as an argument")
+var serve = flag.Bool("serve", false, "serve predictions, completions, explanations and history search to editors as JSON-RPC over stdio")
+var setup = flag.Bool("setup", false, "configure the models and the coach with a few questions, and save the answers to ~/.gshrc")
+var safeMode = flag.Bool("safe", false, "start without predictions, explanations, the coach, analytics and logging, for troubleshooting")
 var strictConfig = flag.Bool("strict-config", false, "fail fast if configuration files contain errors (like bash 'set -e')")
 
 var helpFlag = flag.Bool("h", false, "display help information")
@@ -52,6 +64,28 @@ func main() {
 		return
 	}
 
+	// gsh --setup, or a first interactive run without ~/.gshrc
+	if *setup || isFirstRun() {
+		saved, err := config.RunSetupWizard(!*setup)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gsh: setup failed: %v\n", err)
+			os.Exit(1)
+		}
+		if saved {
+			fmt.Printf("gsh: saved your settings to %s\n", config.SetupRCPath())
+		}
+		if *setup {
+			return
+		}
+	}
+
+	safe := inSafeMode()
+	if safe {
+		// The shell reads safe mode from its environment, which nested gsh
+		// sessions inherit too
+		_ = os.Setenv("GSH_SAFE_MODE", "1")
+	}
+
 	// Initialize the history manager
 	historyManager, err := initializeHistoryManager()
 	if err != nil {
@@ -59,7 +93,7 @@ func main() {
 	}
 
 	// Initialize the analytics manager
-	analyticsManager, err := initializeAnalyticsManager()
+	analyticsManager, err := initializeAnalyticsManager(safe)
 	if err != nil {
 		panic("failed to initialize analytics manager")
 	}
@@ -80,7 +114,7 @@ func main() {
 	environment.SetSessionConfigOverrideGetter(config.GetSessionOverride)
 
 	// Initialize the logger
-	logger, err := initializeLogger(runner)
+	logger, err := initializeLogger(runner, safe)
 	if err != nil {
 		panic(err)
 	}
@@ -93,13 +127,17 @@ func main() {
 	logger.Info("-------- new gsh session --------", zap.Any("args", os.Args))
 
 	// Initialize the coach manager (uses same database as history)
-	coachManager, err := coach.NewCoachManager(historyManager.GetDB(), historyManager, runner, logger)
+	coachManager, err := initializeCoachManager(historyManager, runner, logger, safe)
 	if err != nil {
 		logger.Warn("failed to initialize coach manager", zap.Error(err))
 		// Coach is optional, continue without it
 		coachManager = nil
 	}
 
+	// Save a local crash report if anything below panics
+	reporter := newCrashReporter(runner, historyManager)
+	defer reporter.Recover()
+
 	// Start running
 	err = run(runner, historyManager, analyticsManager, completionManager, coachManager, logger, stderrCapturer)
 
@@ -130,6 +168,20 @@ func run(
 		return bash.RunBashScriptFromReader(ctx, runner, strings.NewReader(*command), "gsh")
 	}
 
+	// gsh --complete "git checkout ma" 15
+	if *completeLine != "" {
+		if err := printCompletions(runner, historyManager, completionManager, *completeLine, flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "gsh: %v\n", err)
+			return interp.NewExitStatus(2)
+		}
+		return nil
+	}
+
+	// gsh --serve
+	if *serve {
+		return serveEditor(ctx, runner, historyManager, completionManager, logger)
+	}
+
 	// gsh
 	if flag.NArg() == 0 {
 		if term.IsTerminal(int(os.Stdin.Fd())) {
@@ -149,7 +201,111 @@ func run(
 	return nil
 }
 
-func initializeLogger(runner *interp.Runner) (*zap.Logger, error) {
+// printCompletions writes the completions of line as a JSON array, so editors
+// and external pickers can reuse gsh's completion engine. The optional argument
+// is the cursor position as a byte offset, which defaults to the end of line.
+func printCompletions(runner *interp.Runner, historyManager *history.HistoryManager, completionManager *completion.CompletionManager, line string, args []string) error {
+	pos := len(line)
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed < 0 || parsed > len(line) {
+			return fmt.Errorf("invalid cursor position %q for a line of %d bytes", args[0], len(line))
+		}
+		pos = parsed
+	}
+
+	provider := completion.NewShellCompletionProvider(completionManager, runner)
+	provider.SetDirectoryProvider(historyManager)
+	provider.SetBookmarkProvider(historyManager)
+	provider.SetManPageCacheDir(core.ManPageCacheDir())
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(provider.GetCompletionResults(line, pos))
+}
+
+// serveEditor answers editor requests on stdin and stdout until stdin closes,
+// see the server package for the protocol
+func serveEditor(ctx context.Context, runner *interp.Runner, historyManager *history.HistoryManager, completionManager *completion.CompletionManager, logger *zap.Logger) error {
+	provider := completion.NewShellCompletionProvider(completionManager, runner)
+	provider.SetDirectoryProvider(historyManager)
+	provider.SetBookmarkProvider(historyManager)
+	provider.SetManPageCacheDir(core.ManPageCacheDir())
+
+	predictor := &predict.PredictRouter{
+		DirectoryPredictor: predict.NewNextDirectoryPredictor(runner, historyManager, logger),
+		RepoPredictor:      predict.NewRepoHistoryPredictor(runner, historyManager, logger),
+		PrefixPredictor:    predict.NewLLMPrefixPredictor(runner, historyManager, logger),
+	}
+	explainer := predict.NewLLMExplainer(runner, logger)
+	// Load the context settings, there's no session context to share
+	predictor.UpdateContext(nil)
+	explainer.UpdateContext(nil)
+
+	s := &server.Server{
+		Predictor: predictor,
+		Explainer: explainer,
+		Completer: provider,
+		History:   historyManager,
+		Version:   BUILD_VERSION,
+		Logger:    logger,
+	}
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// newCrashReporter reports panics with the recent commands and the GSH_
+// settings, when GSH_CRASH_REPORT is enabled
+func newCrashReporter(runner *interp.Runner, historyManager *history.HistoryManager) *crash.Reporter {
+	return &crash.Reporter{
+		Dir:     core.CrashReportDir(),
+		Version: BUILD_VERSION,
+		Enabled: func() bool { return environment.GetCrashReport(runner) },
+		RecentCommands: func() []string {
+			entries, err := historyManager.GetRecentEntries("", crashReportCommands)
+			if err != nil {
+				return nil
+			}
+			commands := make([]string, len(entries))
+			for i, entry := range entries {
+				commands[i] = entry.Command
+			}
+			return commands
+		},
+		Config: func() map[string]string {
+			config := make(map[string]string)
+			for name, variable := range runner.Vars {
+				if strings.HasPrefix(name, "GSH_") {
+					config[name] = variable.String()
+				}
+			}
+			return config
+		},
+	}
+}
+
+// inSafeMode returns whether gsh starts in safe mode, from --safe or
+// GSH_SAFE_MODE in the environment
+func inSafeMode() bool {
+	if *safeMode {
+		return true
+	}
+	value := strings.ToLower(os.Getenv("GSH_SAFE_MODE"))
+	return value == "1" || value == "true"
+}
+
+// isFirstRun returns whether gsh starts as an interactive shell for the first
+// time, with no ~/.gshrc, so the setup wizard should be offered
+func isFirstRun() bool {
+	interactive := *command == "" && *completeLine == "" && !*serve && *rcFile == "" && flag.NArg() == 0 &&
+		term.IsTerminal(int(os.Stdin.Fd()))
+	return interactive && !inSafeMode() && config.NeedsSetup()
+}
+
+func initializeLogger(runner *interp.Runner, safe bool) (*zap.Logger, error) {
+	if safe {
+		return zap.NewNop(), nil
+	}
+
 	logLevel := environment.GetLogLevel(runner)
 	if BUILD_VERSION == "dev" {
 		logLevel = zap.NewAtomicLevelAt(zap.DebugLevel)
@@ -173,16 +329,26 @@ func initializeLogger(runner *interp.Runner) (*zap.Logger, error) {
 	return logger, nil
 }
 
+// initializeHistoryManager opens the history database. If it can't be opened,
+// for example because another gsh holds a lock on it or it is corrupt, the shell
+// still starts with a history that isn't saved.
 func initializeHistoryManager() (*history.HistoryManager, error) {
 	historyManager, err := history.NewHistoryManager(core.HistoryFile())
 	if err != nil {
-		return nil, err
+		fmt.Fprintf(os.Stderr, "gsh: %s. History will not be saved for this session.\n", history.DescribeDatabaseError(core.HistoryFile(), err))
+		return history.NewInMemoryHistoryManager()
 	}
 
 	return historyManager, nil
 }
 
-func initializeAnalyticsManager() (*analytics.AnalyticsManager, error) {
+// initializeAnalyticsManager opens the analytics database, or keeps analytics
+// in memory in safe mode
+func initializeAnalyticsManager(safe bool) (*analytics.AnalyticsManager, error) {
+	if safe {
+		return analytics.NewInMemoryAnalyticsManager()
+	}
+
 	analyticsManager, err := analytics.NewAnalyticsManager(core.AnalyticsFile())
 	if err != nil {
 		return nil, err
@@ -191,8 +357,20 @@ func initializeAnalyticsManager() (*analytics.AnalyticsManager, error) {
 	return analyticsManager, nil
 }
 
+// initializeCoachManager sets up the coach, which is left out in safe mode
+func initializeCoachManager(historyManager *history.HistoryManager, runner *interp.Runner, logger *zap.Logger, safe bool) (*coach.CoachManager, error) {
+	if safe || !environment.GetCoachEnabled(runner) {
+		return nil, nil
+	}
+	return coach.NewCoachManager(historyManager.GetDB(), historyManager, runner, logger)
+}
+
 func initializeCompletionManager() *completion.CompletionManager {
-	return completion.NewCompletionManager()
+	completionManager := completion.NewCompletionManager()
+	if _, err := completionManager.LoadSpecFiles(core.CompletionSpecDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "gsh: failed to load completion specs: %v\n", err)
+	}
+	return completionManager
 }
 
 // initializeRunner loads the shell configuration files and sets up the interpreter.
@@ -208,6 +386,8 @@ func initializeRunner(analyticsManager *analytics.AnalyticsManager, historyManag
 	// Add GSH-specific environment variables
 	dynamicEnv.UpdateGSHVar("SHELL", shellPath)
 	dynamicEnv.UpdateGSHVar("GSH_BUILD_VERSION", BUILD_VERSION)
+	// Track nesting so the prompt can show when gsh runs inside another gsh
+	dynamicEnv.UpdateGSHVar("GSH_SHLVL", strconv.Itoa(environment.NextShellLevel(os.Getenv("GSH_SHLVL"))))
 	env := expand.Environ(dynamicEnv)
 
 	var runner *interp.Runner
@@ -220,15 +400,29 @@ func initializeRunner(analyticsManager *analytics.AnalyticsManager, historyManag
 		interp.ExecHandlers(
 			bash.NewTypesetCommandHandler(),
 			bash.SetBuiltinHandler(),
+			bash.NewWhichCommandHandler(),
 			analytics.NewAnalyticsCommandHandler(analyticsManager),
 			evaluate.NewEvaluateCommandHandler(analyticsManager),
 			history.NewHistoryCommandHandler(historyManager),
+			history.NewZCommandHandler(historyManager),
 			completion.NewCompleteCommandHandler(completionManager),
 		),
 	)
 	if err != nil {
 		panic(err)
 	}
+	// compgen runs completion functions on the runner, so it's added once the
+	// runner exists. Handlers can be added until the runner first runs. The
+	// command-not-found hook goes last, so it only sees commands no other
+	// handler took.
+	if err := interp.ExecHandlers(
+		completion.NewCompgenCommandHandler(runner),
+		bash.NewCommandNotFoundHandler(),
+		// Runs external commands itself when they have a timeout, so it goes last
+		bash.NewCommandTimeoutHandler(),
+	)(runner); err != nil {
+		panic(err)
+	}
 
 	// load default vars
 	if err := bash.RunBashScriptFromReader(
@@ -282,6 +476,10 @@ func initializeRunner(analyticsManager *analytics.AnalyticsManager, historyManag
 		// File not found or empty - this is normal behavior, not an error
 	}
 
+	for _, warning := range environment.ValidateContextTypes(runner) {
+		fmt.Fprintf(os.Stderr, "gsh: %s\n", warning)
+	}
+
 	// Sync gsh variables to system environment so they're visible to 'env' command
 	environment.SyncVariablesToEnv(runner)
 
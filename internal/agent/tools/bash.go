@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/atinylittleshell/gsh/internal/bash"
 	"github.com/atinylittleshell/gsh/internal/environment"
 	"github.com/atinylittleshell/gsh/internal/history"
 	"github.com/atinylittleshell/gsh/internal/styles"
@@ -222,6 +223,17 @@ func BashTool(runner *interp.Runner, historyManager *history.HistoryManager, log
 	}
 	if confirmResponse == "n" {
 		return failedToolResponse("User declined this request")
+	} else if confirmResponse == "s" {
+		// User chose "s" (session) - approve commands like this one until gsh exits, without persisting
+		patterns, err := GenerateCompoundCommandRegex(command)
+		if err != nil {
+			logger.Error("Failed to generate session approval patterns", zap.Error(err))
+			return failedToolResponse("Failed to generate session approval patterns")
+		}
+		for _, pattern := range patterns {
+			environment.AppendToSessionAuthorizedCommands(pattern)
+		}
+		logger.Info("Approved command patterns for this session", zap.Strings("patterns", patterns))
 	} else if confirmResponse == "m" {
 		// User chose "m" (manage) - show permissions menu for command prefixes
 		menuResponse, err := ShowPermissionsMenu(logger, command)
@@ -257,10 +269,17 @@ func BashTool(runner *interp.Runner, historyManager *history.HistoryManager, log
 
 	historyEntry, _ := historyManager.StartCommand(command, environment.GetPwd(runner))
 
-	err = runner.Run(context.Background(), prog)
+	commandTimeout := environment.GetCommandTimeout(runner, logger)
+	ctx, cancel := bash.WithCommandTimeout(context.Background(), commandTimeout)
+	err = runner.Run(ctx, prog)
+	timedOut := bash.CommandTimedOut(ctx)
+	cancel()
 
 	exitCode := 0
-	if err != nil {
+	if timedOut {
+		exitCode = bash.CommandTimeoutExitCode
+		fmt.Fprintf(multiErr, "gsh: command timed out after %s\n", commandTimeout)
+	} else if err != nil {
 		status, ok := interp.IsExitStatus(err)
 		if ok {
 			exitCode = int(status)
@@ -27,9 +27,9 @@ var defaultUserConfirmation = func(logger *zap.Logger, runner *interp.Runner, qu
 		defaultToYes = environment.GetDefaultToYes(runner)
 	}
 
-	promptSuffix := " (y/N/manage/freeform) "
+	promptSuffix := " (y/N/session/manage/freeform) "
 	if defaultToYes {
-		promptSuffix = " (Y/n/manage/freeform) "
+		promptSuffix = " (Y/n/session/manage/freeform) "
 	}
 	prompt := styles.AGENT_QUESTION(question + promptSuffix)
 
@@ -73,6 +73,10 @@ var defaultUserConfirmation = func(logger *zap.Logger, runner *interp.Runner, qu
 		return "m"
 	}
 
+	if lowerLine == "s" || lowerLine == "session" {
+		return "s"
+	}
+
 	return line
 }
 
@@ -41,6 +41,24 @@ func NewAnalyticsManager(dbFilePath string) (*AnalyticsManager, error) {
 	}, nil
 }
 
+// NewInMemoryAnalyticsManager creates an analytics manager that only lasts
+// for the session, for safe mode
+func NewInMemoryAnalyticsManager() (*AnalyticsManager, error) {
+	analyticsManager, err := NewAnalyticsManager(":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each connection to :memory: is a separate database, so keep a single one
+	sqlDB, err := analyticsManager.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return analyticsManager, nil
+}
+
 func (analyticsManager *AnalyticsManager) NewEntry(input string, prediction string, actual string) error {
 	entry := AnalyticsEntry{
 		Input:      input,
@@ -65,6 +83,23 @@ func (analyticsManager *AnalyticsManager) GetRecentEntries(limit int) ([]Analyti
 	return entries, nil
 }
 
+// GetMismatches returns the most recent entries whose prediction was wrong,
+// meaning the command run doesn't start with it. Entries without a prediction
+// or without a command, and agent chats, are left out.
+func (analyticsManager *AnalyticsManager) GetMismatches(limit int) ([]AnalyticsEntry, error) {
+	var entries []AnalyticsEntry
+	result := analyticsManager.db.
+		Where("prediction <> '' AND actual <> '' AND actual NOT LIKE '#%' AND actual NOT LIKE '@%'").
+		Where("substr(actual, 1, length(prediction)) <> prediction").
+		Order("created_at desc, id desc").
+		Limit(limit).
+		Find(&entries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return entries, nil
+}
+
 func (analyticsManager *AnalyticsManager) ResetAnalytics() error {
 	result := analyticsManager.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AnalyticsEntry{})
 	return result.Error
@@ -73,6 +73,36 @@ func TestBasicOperations(t *testing.T) {
 	assert.Len(t, limitedEntries, 1, "Expected 1 entry")
 }
 
+func TestGetMismatches(t *testing.T) {
+	analyticsManager, err := NewAnalyticsManager(":memory:")
+	assert.NoError(t, err, "Failed to create analytics manager")
+
+	entries := []struct{ input, prediction, actual string }{
+		{"git ", "git status", "git stash"},
+		{"git ", "git status", "git status"},
+		{"ls", "ls", "ls -la"},
+		{"", "", "make"},
+		{"make", "make test", ""},
+		{"", "ls", "@what's in here"},
+		{"cd ", "cd ~/src", "cd /tmp"},
+	}
+	for _, e := range entries {
+		assert.NoError(t, analyticsManager.NewEntry(e.input, e.prediction, e.actual))
+	}
+
+	// Only wrong predictions, where the command doesn't start with it, most recent first
+	mismatches, err := analyticsManager.GetMismatches(10)
+	assert.NoError(t, err)
+	assert.Len(t, mismatches, 2)
+	assert.Equal(t, "cd ~/src", mismatches[0].Prediction)
+	assert.Equal(t, "cd /tmp", mismatches[0].Actual)
+	assert.Equal(t, "git stash", mismatches[1].Actual)
+
+	mismatches, err = analyticsManager.GetMismatches(1)
+	assert.NoError(t, err)
+	assert.Len(t, mismatches, 1)
+}
+
 func TestDeleteEntry(t *testing.T) {
 	analyticsManager, err := NewAnalyticsManager(":memory:")
 	assert.NoError(t, err, "Failed to create analytics manager")
@@ -175,3 +205,17 @@ func TestResetAnalytics(t *testing.T) {
 	assert.Len(t, entries, 1)
 }
 
+
+func TestInMemoryAnalyticsManager(t *testing.T) {
+	analyticsManager, err := NewInMemoryAnalyticsManager()
+	assert.NoError(t, err, "Failed to create analytics manager")
+
+	// Entries written from one goroutine are seen from another
+	done := make(chan error)
+	go func() { done <- analyticsManager.NewEntry("git ", "git status", "git status") }()
+	assert.NoError(t, <-done, "Failed to create entry")
+
+	count, err := analyticsManager.GetTotalCount()
+	assert.NoError(t, err, "Failed to get count")
+	assert.Equal(t, int64(1), count, "Expected the entry to be counted")
+}
@@ -0,0 +1,73 @@
+package bash
+
+import (
+	"sort"
+	"strings"
+
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// ExpandAliases returns command with the aliases it runs replaced by their
+// definitions, the way the interpreter expands them: in the command word of
+// each simple command, in the definitions themselves, and in the word after an
+// alias whose definition ends with a blank, like `alias sudo='sudo '`. Commands
+// that don't parse are returned as is.
+func ExpandAliases(runner *interp.Runner, command string) string {
+	aliases := Aliases(runner)
+	if len(aliases) == 0 {
+		return command
+	}
+	return expandAliases(command, aliases, map[string]bool{})
+}
+
+// expandAliases expands the aliases in command, leaving out the ones being
+// expanded already so that `alias ls='ls --color'` stops at itself
+func expandAliases(command string, aliases map[string]string, expanding map[string]bool) string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return command
+	}
+
+	type replacement struct {
+		start, end int
+		text       string
+	}
+	var replacements []replacement
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok {
+			return true
+		}
+		for _, word := range call.Args {
+			name := word.Lit()
+			definition, ok := aliases[name]
+			if name == "" || !ok || expanding[name] {
+				break
+			}
+
+			expanding[name] = true
+			text := expandAliases(strings.TrimRight(definition, " \t"), aliases, expanding)
+			delete(expanding, name)
+			replacements = append(replacements, replacement{
+				start: int(word.Pos().Offset()),
+				end:   int(word.End().Offset()),
+				text:  text,
+			})
+
+			if !strings.HasSuffix(definition, " ") && !strings.HasSuffix(definition, "\t") {
+				break
+			}
+		}
+		return true
+	})
+
+	// Replace from the end so the offsets of earlier words stay valid
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].start > replacements[j].start
+	})
+	for _, r := range replacements {
+		command = command[:r.start] + r.text + command[r.end:]
+	}
+	return command
+}
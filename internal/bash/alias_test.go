@@ -0,0 +1,43 @@
+package bash
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestExpandAliases(t *testing.T) {
+	runner, err := interp.New(interp.Interactive(true), interp.StdIO(nil, nil, nil))
+	require.NoError(t, err)
+
+	// Without aliases nothing changes
+	assert.Equal(t, "gs -s", ExpandAliases(runner, "gs -s"))
+
+	script := "alias gs='git status'\nalias ll='ls -la'\nalias ls='ls --color'\nalias s='sudo '\nalias g=gs\n"
+	require.NoError(t, RunBashScriptFromReader(context.Background(), runner, strings.NewReader(script), "test"))
+
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{command: "gs", expected: "git status"},
+		{command: "gs -s", expected: "git status -s"},
+		{command: "cd src && gs", expected: "cd src && git status"},
+		{command: "ll | grep go", expected: "ls --color -la | grep go"},
+		{command: "g", expected: "git status"},
+		{command: "s gs", expected: "sudo git status"},
+		{command: "echo gs", expected: "echo gs"},
+		{command: "'gs'", expected: "'gs'"},
+		{command: "echo $(gs --short)", expected: "echo $(git status --short)"},
+		{command: "gs 'unclosed", expected: "gs 'unclosed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExpandAliases(runner, tt.command))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package bash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// commandNotFoundStatus is the exit status of a command that wasn't found
+const commandNotFoundStatus = 127
+
+// NewCommandNotFoundHandler creates an ExecHandler that runs the command in
+// GSH_COMMAND_NOT_FOUND, with the missing command and its arguments appended,
+// when a command isn't found on PATH. It plays the role of bash's
+// command_not_found_handle, and the hook's exit status becomes the command's.
+// It must come after the handlers of gsh's own commands, so they are found.
+func NewCommandNotFoundHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			if len(args) == 0 {
+				return next(ctx, args)
+			}
+
+			hc := interp.HandlerCtx(ctx)
+			hook := strings.TrimSpace(hc.Env.Get("GSH_COMMAND_NOT_FOUND").String())
+			if hook == "" {
+				return next(ctx, args)
+			}
+			if _, err := interp.LookPathDir(hc.Dir, hc.Env, args[0]); err == nil {
+				return next(ctx, args)
+			}
+
+			return runCommandNotFoundHook(ctx, hc, hook, args)
+		}
+	}
+}
+
+func runCommandNotFoundHook(ctx context.Context, hc interp.HandlerContext, hook string, args []string) error {
+	cmd := exec.CommandContext(ctx, "sh", append([]string{"-c", hook + ` "$@"`, "sh"}, args...)...)
+	cmd.Dir = hc.Dir
+	cmd.Env = exportedEnv(hc.Env)
+	cmd.Stdin = hc.Stdin
+	cmd.Stdout = hc.Stdout
+	cmd.Stderr = hc.Stderr
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return interp.NewExitStatus(uint8(exitErr.ExitCode()))
+	}
+
+	// The hook itself couldn't run, so report the missing command as usual
+	_, _ = fmt.Fprintf(hc.Stderr, "%s: command not found\n", args[0])
+	return interp.NewExitStatus(commandNotFoundStatus)
+}
+
+// exportedEnv lists the exported variables as KEY=value pairs, for the
+// environment of a command run outside the interpreter
+func exportedEnv(env expand.Environ) []string {
+	var list []string
+	env.Each(func(name string, vr expand.Variable) bool {
+		if !vr.IsSet() {
+			// Unset in the runner but set in the environment it started from,
+			// which comes first
+			list = slices.DeleteFunc(list, func(kv string) bool { return strings.HasPrefix(kv, name+"=") })
+		}
+		if vr.Exported && vr.Kind == expand.String {
+			list = append(list, name+"="+vr.String())
+		}
+		return true
+	})
+	return list
+}
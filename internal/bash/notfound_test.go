@@ -0,0 +1,64 @@
+package bash
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+func runWithCommandNotFoundHandler(t *testing.T, script string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	runner, err := interp.New(
+		interp.StdIO(nil, &stdout, &stderr),
+		interp.ExecHandlers(NewCommandNotFoundHandler()),
+	)
+	require.NoError(t, err)
+
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	require.NoError(t, err)
+	err = runner.Run(context.Background(), file)
+	return stdout.String(), stderr.String(), err
+}
+
+func TestCommandNotFoundHandler(t *testing.T) {
+	t.Run("runs the hook with the command and its arguments", func(t *testing.T) {
+		stdout, _, err := runWithCommandNotFoundHandler(t, `GSH_COMMAND_NOT_FOUND='printf "%s|"'; gsh_missing_command a "b c"`)
+		assert.NoError(t, err)
+		assert.Equal(t, "gsh_missing_command|a|b c|", stdout)
+	})
+
+	t.Run("uses the exit status of the hook", func(t *testing.T) {
+		_, _, err := runWithCommandNotFoundHandler(t, `GSH_COMMAND_NOT_FOUND='f() { exit 127; }; f'; gsh_missing_command`)
+		status, ok := interp.IsExitStatus(err)
+		assert.True(t, ok)
+		assert.Equal(t, uint8(127), status)
+	})
+
+	t.Run("leaves found commands alone", func(t *testing.T) {
+		stdout, _, err := runWithCommandNotFoundHandler(t, `GSH_COMMAND_NOT_FOUND='echo hook'; sh -c 'echo found'`)
+		assert.NoError(t, err)
+		assert.Equal(t, "found\n", stdout)
+	})
+
+	t.Run("reports missing commands as usual without a hook", func(t *testing.T) {
+		stdout, stderr, err := runWithCommandNotFoundHandler(t, `gsh_missing_command`)
+		status, ok := interp.IsExitStatus(err)
+		assert.True(t, ok)
+		assert.Equal(t, uint8(127), status)
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "gsh_missing_command")
+	})
+
+	t.Run("leaves unset variables out of the hook's environment", func(t *testing.T) {
+		t.Setenv("GSH_TEST_UNSET", "inherited")
+		stdout, _, err := runWithCommandNotFoundHandler(t, `unset GSH_TEST_UNSET; GSH_COMMAND_NOT_FOUND='echo "${GSH_TEST_UNSET-unset}"'; gsh_missing_command`)
+		assert.NoError(t, err)
+		assert.Equal(t, "unset gsh_missing_command\n", stdout)
+	})
+}
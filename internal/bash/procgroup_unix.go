@@ -0,0 +1,51 @@
+//go:build !windows
+
+package bash
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// startProcessGroup makes cmd lead a process group of its own, so the
+// processes it starts are signalled with it. If it reads from the terminal
+// gsh is in the foreground of, the group takes the foreground like in a job
+// control shell, so it can still read it and gets the keys' signals. The
+// returned function gives the foreground back once cmd exited.
+func startProcessGroup(cmd *exec.Cmd) (restore func()) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdin, ok := cmd.Stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(stdin.Fd())) {
+		return func() {}
+	}
+	fd := int(stdin.Fd())
+	if pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err != nil || pgrp != syscall.Getpgrp() {
+		return func() {}
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = fd
+
+	return func() {
+		// gsh is in the background until then, where changing the
+		// foreground would stop it
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		_ = unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, syscall.Getpgrp())
+	}
+}
+
+// terminateProcessGroup sends SIGTERM to the process group p leads
+func terminateProcessGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGTERM)
+}
+
+// killProcessGroup kills the process group p leads
+func killProcessGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package bash
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startProcessGroup does nothing on Windows, which has no process groups to
+// signal
+func startProcessGroup(cmd *exec.Cmd) (restore func()) {
+	return func() {}
+}
+
+// terminateProcessGroup kills p, since Windows can't deliver SIGTERM
+func terminateProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
+
+// killProcessGroup kills p
+func killProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,102 @@
+package bash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// CommandTimeoutExitCode is the exit code of a command stopped by its
+// timeout, the same as coreutils' timeout
+const CommandTimeoutExitCode = 124
+
+// commandTimeoutKillDelay is how long a timed out command has to exit after
+// SIGTERM before it's killed
+const commandTimeoutKillDelay = 2 * time.Second
+
+type commandTimeoutKey struct{}
+
+// WithCommandTimeout returns a context that stops what the runner runs with
+// it after timeout. With the exec handler from NewCommandTimeoutHandler,
+// external commands get SIGTERM, then SIGKILL if they're still running
+// commandTimeoutKillDelay later. A timeout of 0 means none.
+func WithCommandTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return context.WithValue(ctx, commandTimeoutKey{}, timeout), cancel
+}
+
+// CommandTimedOut reports whether the context from WithCommandTimeout ran out
+func CommandTimedOut(ctx context.Context) bool {
+	_, ok := ctx.Value(commandTimeoutKey{}).(time.Duration)
+	return ok && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// NewCommandTimeoutHandler runs external commands under a context from
+// WithCommandTimeout, so they can be terminated with SIGTERM rather than the
+// interpreter's SIGINT. Other commands go to the next handler. It must be the
+// last handler, since it runs the commands itself.
+func NewCommandTimeoutHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			if _, ok := ctx.Value(commandTimeoutKey{}).(time.Duration); !ok {
+				return next(ctx, args)
+			}
+			return execTerminable(ctx, args)
+		}
+	}
+}
+
+// execTerminable runs an external command like the interpreter does, sending
+// SIGTERM to it and the processes it started when ctx is done. Windows can't
+// deliver SIGTERM, so there the command is killed right away.
+func execTerminable(ctx context.Context, args []string) error {
+	hc := interp.HandlerCtx(ctx)
+	path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+	if err != nil {
+		fmt.Fprintln(hc.Stderr, err)
+		return interp.NewExitStatus(127)
+	}
+
+	cmd := exec.CommandContext(ctx, path, args[1:]...)
+	cmd.Args = args
+	cmd.Env = exportedEnv(hc.Env)
+	cmd.Dir = hc.Dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = hc.Stdin, hc.Stdout, hc.Stderr
+	restoreTerminal := startProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		// Kills what's left of the group if it outlives SIGTERM
+		time.AfterFunc(commandTimeoutKillDelay, func() { _ = killProcessGroup(cmd.Process) })
+		return terminateProcessGroup(cmd.Process)
+	}
+	// Stops waiting for output from processes that left the group
+	cmd.WaitDelay = commandTimeoutKillDelay
+
+	if err := cmd.Start(); err != nil {
+		restoreTerminal()
+		fmt.Fprintln(hc.Stderr, err)
+		return interp.NewExitStatus(127)
+	}
+	err = cmd.Wait()
+	restoreTerminal()
+
+	if ctx.Err() != nil {
+		// Stops the rest of the script too, like the interpreter does for a cancelled context
+		return ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return interp.NewExitStatus(uint8(128 + int(status.Signal())))
+		}
+		return interp.NewExitStatus(uint8(exitErr.ExitCode()))
+	}
+	return err
+}
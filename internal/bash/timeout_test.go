@@ -0,0 +1,72 @@
+package bash
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+func runWithTimeout(t *testing.T, command string, timeout time.Duration) (string, error, bool) {
+	var stdout bytes.Buffer
+	runner, err := interp.New(
+		interp.StdIO(nil, &stdout, &stdout),
+		interp.ExecHandlers(NewCommandTimeoutHandler()),
+	)
+	require.NoError(t, err)
+
+	prog, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	require.NoError(t, err)
+
+	ctx, cancel := WithCommandTimeout(context.Background(), timeout)
+	defer cancel()
+	err = runner.Run(ctx, prog)
+	return stdout.String(), err, CommandTimedOut(ctx)
+}
+
+func TestCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh and SIGTERM")
+	}
+
+	// Commands that finish in time keep their output and exit status
+	out, err, timedOut := runWithTimeout(t, "sh -c 'echo done; exit 3'", 5*time.Second)
+	assert.False(t, timedOut)
+	assert.Equal(t, "done\n", out)
+	status, ok := interp.IsExitStatus(err)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(3), status)
+
+	// Commands that run too long get SIGTERM
+	start := time.Now()
+	out, _, timedOut = runWithTimeout(t, `sh -c 'sleep 5 & trap "echo terminated; exit 1" TERM; wait'; echo after`, 200*time.Millisecond)
+	assert.True(t, timedOut)
+	assert.Equal(t, "terminated\n", out)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// So do the processes they started, which would otherwise hold on to the
+	// output until the kill delay
+	start = time.Now()
+	_, _, timedOut = runWithTimeout(t, "sh -c 'sleep 5 & wait'", 200*time.Millisecond)
+	assert.True(t, timedOut)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Loops that never run an external command stop too
+	_, _, timedOut = runWithTimeout(t, "while true; do :; done", 200*time.Millisecond)
+	assert.True(t, timedOut)
+}
+
+func TestCommandTimeout_None(t *testing.T) {
+	ctx, cancel := WithCommandTimeout(context.Background(), 0)
+	defer cancel()
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+	assert.False(t, CommandTimedOut(ctx))
+}
@@ -0,0 +1,214 @@
+package bash
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+var whichLookPath = exec.LookPath
+
+// shellBuiltins lists the builtins implemented by the interpreter itself as well
+// as the ones gsh adds through its exec handlers.
+var shellBuiltins = map[string]bool{
+	"true": true, ":": true, "false": true, "exit": true, "set": true, "shift": true,
+	"unset": true, "echo": true, "printf": true, "break": true, "continue": true,
+	"pwd": true, "cd": true, "wait": true, "builtin": true, "trap": true, "type": true,
+	"source": true, ".": true, "command": true, "dirs": true, "pushd": true, "popd": true,
+	"umask": true, "alias": true, "unalias": true, "fg": true, "bg": true, "getopts": true,
+	"eval": true, "test": true, "[": true, "exec": true, "return": true, "read": true,
+	"mapfile": true, "readarray": true, "shopt": true,
+	"typeset": true, "declare": true, "history": true, "complete": true, "which": true,
+	"gsh_typeset": true, "gsh_analytics": true, "gsh_evaluate": true, "gsh_z": true,
+}
+
+// NewWhichCommandHandler creates a new ExecHandler for the which command. Unlike
+// the external which, it reports how gsh itself resolves a name, following the
+// order alias > function > builtin > PATH. The interpreter handles "type" on its
+// own, so the handler is also reachable as gsh_type.
+func NewWhichCommandHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			if len(args) == 0 {
+				return next(ctx, args)
+			}
+
+			if args[0] != "which" && args[0] != "gsh_type" {
+				return next(ctx, args)
+			}
+
+			if globalRunner == nil {
+				return fmt.Errorf("%s: runner not initialized", args[0])
+			}
+
+			return handleWhichCommand(ctx, globalRunner, args)
+		}
+	}
+}
+
+func handleWhichCommand(ctx context.Context, runner *interp.Runner, args []string) error {
+	hc := interp.HandlerCtx(ctx)
+	showAll := false
+	names := []string{}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if len(names) == 0 && strings.HasPrefix(arg, "-") && arg != "-" {
+			for _, ch := range arg[1:] {
+				switch ch {
+				case 'a':
+					showAll = true
+				default:
+					fmt.Fprintf(hc.Stderr, "%s: -%c: invalid option\n", args[0], ch)
+					return interp.NewExitStatus(2)
+				}
+			}
+			continue
+		}
+		names = append(names, arg)
+	}
+
+	if len(names) == 0 {
+		fmt.Fprintf(hc.Stderr, "usage: %s [-a] name [name ...]\n", args[0])
+		return interp.NewExitStatus(2)
+	}
+
+	anyNotFound := false
+	for _, name := range names {
+		resolutions := ResolveCommand(runner, name, showAll)
+		if len(resolutions) == 0 {
+			fmt.Fprintf(hc.Stderr, "%s: %s: not found\n", args[0], name)
+			anyNotFound = true
+			continue
+		}
+		for _, resolution := range resolutions {
+			fmt.Fprintln(hc.Stdout, resolution)
+		}
+	}
+
+	if anyNotFound {
+		return interp.NewExitStatus(1)
+	}
+	return nil
+}
+
+// ResolveCommand describes how the shell would resolve name, in the style of
+// bash's type builtin. Only the first match is returned unless all is set.
+func ResolveCommand(runner *interp.Runner, name string, all bool) []string {
+	var resolutions []string
+	done := func() bool {
+		return !all && len(resolutions) > 0
+	}
+
+	if definition, ok := LookupAlias(runner, name); ok {
+		resolutions = append(resolutions, fmt.Sprintf("%s is aliased to `%s'", name, definition))
+		if done() {
+			return resolutions
+		}
+	}
+
+	if syntax.IsKeyword(name) {
+		resolutions = append(resolutions, fmt.Sprintf("%s is a shell keyword", name))
+		if done() {
+			return resolutions
+		}
+	}
+
+	if runner != nil && runner.Funcs != nil {
+		if fn, ok := runner.Funcs[name]; ok && fn != nil {
+			var buf strings.Builder
+			_ = syntax.NewPrinter().Print(&buf, fn)
+			resolutions = append(resolutions, fmt.Sprintf("%s is a function\n%s () %s", name, name, strings.TrimRight(buf.String(), "\n")))
+			if done() {
+				return resolutions
+			}
+		}
+	}
+
+	if shellBuiltins[name] {
+		resolutions = append(resolutions, fmt.Sprintf("%s is a shell builtin", name))
+		if done() {
+			return resolutions
+		}
+	}
+
+	if path, err := whichLookPath(name); err == nil {
+		resolutions = append(resolutions, fmt.Sprintf("%s is %s", name, path))
+	}
+
+	return resolutions
+}
+
+// aliasMap returns the interpreter's alias map. The interpreter doesn't expose
+// its aliases, so we read the unexported field through reflection.
+func aliasMap(runner *interp.Runner) (reflect.Value, bool) {
+	if runner == nil {
+		return reflect.Value{}, false
+	}
+	aliasField := reflect.ValueOf(runner).Elem().FieldByName("alias")
+	if !aliasField.IsValid() || aliasField.Kind() != reflect.Map || aliasField.IsNil() {
+		return reflect.Value{}, false
+	}
+	return aliasField, true
+}
+
+// Aliases returns the definition of each alias by name
+func Aliases(runner *interp.Runner) map[string]string {
+	aliases := make(map[string]string)
+	aliasField, ok := aliasMap(runner)
+	if !ok {
+		return aliases
+	}
+	for _, key := range aliasField.MapKeys() {
+		aliases[key.String()] = aliasDefinition(aliasField.MapIndex(key))
+	}
+	return aliases
+}
+
+// LookupAlias returns the definition of an alias
+func LookupAlias(runner *interp.Runner, name string) (string, bool) {
+	aliasField, ok := aliasMap(runner)
+	if !ok {
+		return "", false
+	}
+
+	value := aliasField.MapIndex(reflect.ValueOf(name))
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return "", false
+	}
+	return aliasDefinition(value), true
+}
+
+// aliasDefinition prints an alias the way it was defined
+func aliasDefinition(value reflect.Value) string {
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+
+	// The map values are interp.alias{args []*syntax.Word; blank bool}
+	var words []*syntax.Word
+	if argsField := value.FieldByName("args"); argsField.IsValid() && argsField.Kind() == reflect.Slice {
+		for i := 0; i < argsField.Len(); i++ {
+			wordPtr := argsField.Index(i)
+			if wordPtr.Kind() != reflect.Ptr || wordPtr.IsNil() {
+				continue
+			}
+			words = append(words, (*syntax.Word)(unsafe.Pointer(wordPtr.Pointer())))
+		}
+	}
+
+	var buf strings.Builder
+	if len(words) > 0 {
+		_ = syntax.NewPrinter().Print(&buf, &syntax.CallExpr{Args: words})
+	}
+	if blankField := value.FieldByName("blank"); blankField.IsValid() && blankField.Kind() == reflect.Bool && blankField.Bool() {
+		buf.WriteByte(' ')
+	}
+	return buf.String()
+}
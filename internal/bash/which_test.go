@@ -0,0 +1,147 @@
+package bash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+func TestResolveCommand(t *testing.T) {
+	runner, err := interp.New(interp.Interactive(true), interp.StdIO(nil, nil, nil))
+	assert.NoError(t, err)
+
+	testScript := `
+alias ll='ls -la'
+greet() { echo "hello"; }
+`
+	err = RunBashScriptFromReader(context.Background(), runner, strings.NewReader(testScript), "test")
+	assert.NoError(t, err)
+
+	originalLookPath := whichLookPath
+	defer func() { whichLookPath = originalLookPath }()
+	whichLookPath = func(file string) (string, error) {
+		if file == "ls" || file == "echo" {
+			return "/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		all      bool
+		expected []string
+	}{
+		{
+			name:     "alias",
+			input:    "ll",
+			expected: []string{"ll is aliased to `ls -la'"},
+		},
+		{
+			name:     "function",
+			input:    "greet",
+			expected: []string{"greet is a function\ngreet () { echo \"hello\"; }"},
+		},
+		{
+			name:     "builtin shadows PATH",
+			input:    "echo",
+			expected: []string{"echo is a shell builtin"},
+		},
+		{
+			name:     "builtin and PATH with all",
+			input:    "echo",
+			all:      true,
+			expected: []string{"echo is a shell builtin", "echo is /bin/echo"},
+		},
+		{
+			name:     "keyword",
+			input:    "if",
+			expected: []string{"if is a shell keyword"},
+		},
+		{
+			name:     "PATH",
+			input:    "ls",
+			expected: []string{"ls is /bin/ls"},
+		},
+		{
+			name:     "not found",
+			input:    "nonexistent",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ResolveCommand(runner, tt.input, tt.all))
+		})
+	}
+}
+
+func TestWhichCommandHandler(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	runner, err := interp.New(
+		interp.StdIO(nil, &stdout, &stderr),
+		interp.ExecHandlers(NewWhichCommandHandler()),
+	)
+	require.NoError(t, err)
+	SetTypesetRunner(runner)
+
+	run := func(command string) error {
+		stdout.Reset()
+		stderr.Reset()
+		prog, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+		require.NoError(t, err)
+		return runner.Run(context.Background(), prog)
+	}
+
+	// Output goes through the shell's pipes and redirects
+	require.NoError(t, run("which cd | sed 's/^/piped: /'"))
+	assert.Equal(t, "piped: cd is a shell builtin\n", stdout.String())
+
+	require.NoError(t, run("which cd > /dev/null"))
+	assert.Empty(t, stdout.String())
+
+	err = run("which definitely-not-a-command-xyz")
+	status, ok := interp.IsExitStatus(err)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(1), status)
+	assert.Equal(t, "which: definitely-not-a-command-xyz: not found\n", stderr.String())
+
+	err = run("which definitely-not-a-command-xyz 2>/dev/null")
+	_, ok = interp.IsExitStatus(err)
+	assert.True(t, ok)
+	assert.Empty(t, stderr.String())
+
+	err = run("which -z cd")
+	status, ok = interp.IsExitStatus(err)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(2), status)
+	assert.Contains(t, stderr.String(), "invalid option")
+
+	// Other commands go to the next handler
+	require.NoError(t, run("true"))
+}
+
+func TestAliases(t *testing.T) {
+	runner, err := interp.New(interp.Interactive(true), interp.StdIO(nil, nil, nil))
+	assert.NoError(t, err)
+	assert.Empty(t, Aliases(runner))
+
+	err = RunBashScriptFromReader(context.Background(), runner, strings.NewReader("alias gs='git status'\nalias s='sudo '\n"), "test")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"gs": "git status", "s": "sudo "}, Aliases(runner))
+
+	definition, ok := LookupAlias(runner, "gs")
+	assert.True(t, ok)
+	assert.Equal(t, "git status", definition)
+	_, ok = LookupAlias(runner, "missing")
+	assert.False(t, ok)
+}
@@ -195,6 +195,21 @@ var StreakMilestones = []StreakMilestone{
 	{Days: 365, XPReward: 10000, BadgeID: "streak_legendary", Message: "ONE FULL YEAR! Multiplier now 5x!", Multiplier: 5.0},
 }
 
+const (
+	// dailyCheckInXP is the bonus for the first use of the shell on a day
+	dailyCheckInXP = 20
+	// dailyCheckInStreakXP is added to the bonus for each day of the streak
+	// after the first, up to dailyCheckInMaxStreakDays days
+	dailyCheckInStreakXP      = 5
+	dailyCheckInMaxStreakDays = 10
+)
+
+// DailyCheckInXP returns the check-in bonus for a day of the given streak,
+// which grows the longer the streak is kept
+func DailyCheckInXP(streak int) int {
+	return dailyCheckInXP + dailyCheckInStreakXP*min(max(streak-1, 0), dailyCheckInMaxStreakDays)
+}
+
 // GetStreakMilestone returns the milestone for a given streak day if it's a milestone
 func GetStreakMilestone(days int) *StreakMilestone {
 	for _, m := range StreakMilestones {
@@ -318,13 +333,9 @@ func CalculateNewStreak(currentStreak int, lastActive time.Time, useFreeze bool,
 }
 
 // StreakFreezesEarned calculates how many freezes have been earned based on streak
-// Earn 1 freeze per week of streak (max 3)
-func StreakFreezesEarned(currentStreak int) int {
-	earned := currentStreak / 7
-	if earned > 3 {
-		return 3
-	}
-	return earned
+// Earn 1 freeze per week of streak, up to MaxStreakFreezes for the level
+func StreakFreezesEarned(currentStreak int, level int) int {
+	return min(currentStreak/7, MaxStreakFreezes(level))
 }
 
 // LevelUpInfo contains information about a level up
@@ -334,7 +345,8 @@ type LevelUpInfo struct {
 	OldTitle string
 	NewTitle string
 	XPToNext int
-	Unlocks  []string // Features unlocked at this level
+	Unlocks  []string    // Features unlocked at this level
+	Perks    []LevelPerk // Perks unlocked by this level up
 }
 
 // CheckLevelUp checks if user leveled up and returns info
@@ -353,6 +365,7 @@ func CheckLevelUp(oldTotalXP, newTotalXP int) *LevelUpInfo {
 		NewTitle: GetTitleForLevel(newLevel),
 		XPToNext: XPForNextLevel(newLevel),
 		Unlocks:  getUnlocksForLevel(newLevel),
+		Perks:    PerksUnlockedBetween(oldLevel, newLevel),
 	}
 }
 
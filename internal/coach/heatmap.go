@@ -0,0 +1,201 @@
+package coach
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/styles"
+	"github.com/muesli/termenv"
+	"go.uber.org/zap"
+)
+
+// heatmapWeeks is how many weeks the activity heatmap covers
+const heatmapWeeks = 53
+
+var heatmapOutput = termenv.NewOutput(os.Stdout)
+
+// heatmapGlyphs and heatmapColors form the ramp from no activity to the busiest
+// day. Glyphs differ per level too, so the heatmap still reads without color.
+var (
+	heatmapGlyphs = []string{"·", "░", "▒", "▓", "█"}
+	heatmapColors = []string{"240", "22", "28", "34", "40"}
+)
+
+// histogramGlyphs draw the hourly histogram, from lowest to highest
+var histogramGlyphs = []rune("▁▂▃▄▅▆▇█")
+
+// GetDailyCommandCounts returns the number of commands executed per day, keyed by
+// YYYY-MM-DD, for days on or after since
+func (m *CoachManager) GetDailyCommandCounts(since time.Time) (map[string]int, error) {
+	var rows []CoachDailyStats
+	err := m.db.Select("date", "commands_executed").
+		Where("profile_id = ? AND date >= ?", m.profile.ID, since.Format("2006-01-02")).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Date] += row.CommandsExecuted
+	}
+	return counts, nil
+}
+
+// RenderHeatmap renders `@!coach heatmap`: commands per day over the last year
+// and commands per hour today
+func (m *CoachManager) RenderHeatmap() string {
+	var sb strings.Builder
+
+	sb.WriteString(styles.AGENT_MESSAGE("╔══════════════════════════════════════════════════════════════════════════╗\n"))
+	sb.WriteString(styles.AGENT_MESSAGE("║  🗓️  ACTIVITY HEATMAP                                                     ║\n"))
+	sb.WriteString(styles.AGENT_MESSAGE("╚══════════════════════════════════════════════════════════════════════════╝\n"))
+
+	now := time.Now()
+	counts, err := m.GetDailyCommandCounts(heatmapStart(now))
+	if err != nil {
+		m.logger.Debug("failed to load daily stats for heatmap", zap.Error(err))
+		sb.WriteString(styles.ERROR("Failed to load activity history\n"))
+		return sb.String()
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(renderActivityHeatmap(counts, now))
+	sb.WriteString("\n")
+
+	var hourly [24]int
+	if m.todayStats != nil && m.todayStats.HourlyActivity != "" {
+		_ = json.Unmarshal([]byte(m.todayStats.HourlyActivity), &hourly)
+	}
+	sb.WriteString(styles.AGENT_MESSAGE("  ⏰ TODAY BY HOUR\n"))
+	sb.WriteString(renderHourlyHistogram(hourly))
+
+	return sb.String()
+}
+
+// heatmapStart returns the first day shown in the heatmap ending at now, a
+// Sunday so that each column is a full week
+func heatmapStart(now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	lastSunday := today.AddDate(0, 0, -int(today.Weekday()))
+	return lastSunday.AddDate(0, 0, -7*(heatmapWeeks-1))
+}
+
+// heatmapLevel maps a count onto the ramp. The ramp is scaled to the busiest day
+// observed rather than a fixed threshold, so a few active days still show a
+// range of shades.
+func heatmapLevel(count int, max int) int {
+	if count <= 0 || max <= 0 {
+		return 0
+	}
+	levels := len(heatmapGlyphs) - 1
+	level := int(math.Ceil(float64(count) / float64(max) * float64(levels)))
+	if level > levels {
+		level = levels
+	}
+	return level
+}
+
+func heatmapCell(level int) string {
+	return heatmapOutput.String(heatmapGlyphs[level]).
+		Foreground(heatmapOutput.Color(heatmapColors[level])).
+		String()
+}
+
+// renderActivityHeatmap renders a GitHub-style grid with a row per weekday and
+// a column per week, ending with the week of now
+func renderActivityHeatmap(counts map[string]int, now time.Time) string {
+	start := heatmapStart(now)
+	today := now.Format("2006-01-02")
+
+	max, total, activeDays := 0, 0, 0
+	for date, count := range counts {
+		if date < start.Format("2006-01-02") || date > today || count <= 0 {
+			continue
+		}
+		total += count
+		activeDays++
+		if count > max {
+			max = count
+		}
+	}
+
+	var sb strings.Builder
+
+	// Month labels above the first week of each month, when there is room
+	labels := []rune(strings.Repeat(" ", heatmapWeeks+3))
+	lastLabelEnd := 0
+	for week := 0; week < heatmapWeeks; week++ {
+		weekStart := start.AddDate(0, 0, 7*week)
+		if week > 0 && weekStart.AddDate(0, 0, -7).Month() == weekStart.Month() {
+			continue
+		}
+		if week < lastLabelEnd {
+			continue
+		}
+		copy(labels[week:], []rune(weekStart.Format("Jan")))
+		lastLabelEnd = week + 4
+	}
+	sb.WriteString("      " + strings.TrimRight(string(labels), " ") + "\n")
+
+	dayLabels := []string{"", "Mon", "", "Wed", "", "Fri", ""}
+	for weekday := 0; weekday < 7; weekday++ {
+		sb.WriteString(fmt.Sprintf("  %-4s", dayLabels[weekday]))
+		for week := 0; week < heatmapWeeks; week++ {
+			date := start.AddDate(0, 0, 7*week+weekday).Format("2006-01-02")
+			if date > today {
+				break
+			}
+			sb.WriteString(heatmapCell(heatmapLevel(counts[date], max)))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n      Less ")
+	for level := range heatmapGlyphs {
+		sb.WriteString(heatmapCell(level))
+	}
+	sb.WriteString(" More\n")
+
+	if activeDays == 0 {
+		sb.WriteString("      No activity recorded in the last year yet\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("      %d commands on %d active days, busiest day %d\n", total, activeDays, max))
+	}
+	return sb.String()
+}
+
+// renderHourlyHistogram renders a single-line histogram of commands per hour,
+// scaled to the busiest hour
+func renderHourlyHistogram(hourly [24]int) string {
+	max := 0
+	for _, count := range hourly {
+		if count > max {
+			max = count
+		}
+	}
+
+	var bars strings.Builder
+	for _, count := range hourly {
+		glyph := " "
+		if count > 0 {
+			index := int(math.Ceil(float64(count)/float64(max)*float64(len(histogramGlyphs)))) - 1
+			glyph = string(histogramGlyphs[index])
+		}
+		bars.WriteString(strings.Repeat(glyph, 2))
+	}
+
+	var sb strings.Builder
+	if max == 0 {
+		sb.WriteString("      No commands yet today\n")
+		return sb.String()
+	}
+	sb.WriteString("      " + bars.String() + "\n")
+	sb.WriteString("      0           6           12          18        23\n")
+	sb.WriteString(fmt.Sprintf("      Busiest hour: %d commands\n", max))
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package coach
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestHeatmapLevel(t *testing.T) {
+	assert.Equal(t, 0, heatmapLevel(0, 10))
+	assert.Equal(t, 0, heatmapLevel(5, 0))
+	assert.Equal(t, 1, heatmapLevel(1, 10))
+	assert.Equal(t, 2, heatmapLevel(5, 10))
+	assert.Equal(t, 4, heatmapLevel(10, 10))
+
+	// With only a few quiet days, the busiest one still gets the top level
+	assert.Equal(t, 4, heatmapLevel(3, 3))
+	assert.Equal(t, 2, heatmapLevel(1, 3))
+}
+
+func TestHeatmapStart(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	start := heatmapStart(now)
+	assert.Equal(t, time.Sunday, start.Weekday())
+	assert.Equal(t, "2025-10-12", start.Format("2006-01-02"))
+}
+
+func TestRenderActivityHeatmap(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	counts := map[string]int{
+		"2026-10-12": 40,
+		"2026-10-11": 10,
+		"2024-01-01": 999, // outside the range, ignored for scaling
+	}
+
+	lines := strings.Split(renderActivityHeatmap(counts, now), "\n")
+	require.GreaterOrEqual(t, len(lines), 8)
+
+	// Month labels, then a row per weekday
+	assert.Contains(t, lines[0], "Oct")
+	sunday, monday, wednesday := lines[1], lines[2], lines[4]
+	assert.True(t, strings.HasSuffix(sunday, "░"), sunday)
+	assert.True(t, strings.HasSuffix(monday, "█"), monday)
+	assert.True(t, strings.HasPrefix(monday, "  Mon "), monday)
+	assert.True(t, strings.HasSuffix(wednesday, "·"), wednesday)
+
+	// Days after today are left out of the last week
+	assert.Equal(t, heatmapWeeks, strings.Count(wednesday, "·")+strings.Count(wednesday, "░")+strings.Count(wednesday, "▒")+strings.Count(wednesday, "▓")+strings.Count(wednesday, "█"))
+	assert.Equal(t, heatmapWeeks-1, strings.Count(lines[5], "·"))
+
+	output := strings.Join(lines, "\n")
+	assert.Contains(t, output, "50 commands on 2 active days, busiest day 40")
+	assert.Contains(t, renderActivityHeatmap(nil, now), "No activity recorded")
+}
+
+func TestRenderHourlyHistogram(t *testing.T) {
+	var hourly [24]int
+	assert.Contains(t, renderHourlyHistogram(hourly), "No commands yet today")
+
+	hourly[9] = 8
+	hourly[14] = 1
+	output := renderHourlyHistogram(hourly)
+	bars := strings.Split(output, "\n")[0]
+	assert.Equal(t, "      "+strings.Repeat(" ", 18)+"██"+strings.Repeat(" ", 8)+"▁▁"+strings.Repeat(" ", 18), bars)
+	assert.Contains(t, output, "Busiest hour: 8 commands")
+}
+
+func TestGetDailyCommandCounts(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CoachDailyStats{}))
+
+	require.NoError(t, db.Create(&CoachDailyStats{ProfileID: 1, Date: "2026-10-01", CommandsExecuted: 12}).Error)
+	require.NoError(t, db.Create(&CoachDailyStats{ProfileID: 1, Date: "2025-01-01", CommandsExecuted: 3}).Error)
+	require.NoError(t, db.Create(&CoachDailyStats{ProfileID: 2, Date: "2026-10-02", CommandsExecuted: 7}).Error)
+
+	manager := &CoachManager{db: db, profile: &CoachProfile{ID: 1}}
+	counts, err := manager.GetDailyCommandCounts(time.Date(2025, 10, 12, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"2026-10-01": 12}, counts)
+}
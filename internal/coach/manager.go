@@ -42,6 +42,9 @@ type CoachManager struct {
 
 	// Pending notifications
 	pendingNotifications []CoachNotification
+
+	// Warning from CheckSecretExposure, shown once in the assistant box
+	secretWarning *CoachDisplayContent
 }
 
 // NewCoachManager creates a new coach manager
@@ -187,7 +190,7 @@ func (m *CoachManager) loadActiveChallenges() {
 	// If no active daily challenges, create new ones
 	if len(dailies) == 0 {
 		seed := GetDailySeed()
-		defs := GetRandomDailyChallenges(4, seed)
+		defs := GetRandomDailyChallenges(DailyChallengeCount(m.profile.Level), seed)
 		resetTime := GetDailyResetTime()
 
 		for _, def := range defs {
@@ -274,7 +277,7 @@ func (m *CoachManager) updateStreak() {
 		}
 
 		// Update streak freezes earned
-		earnedFreezes := StreakFreezesEarned(newStreak)
+		earnedFreezes := StreakFreezesEarned(newStreak, m.profile.Level)
 		if earnedFreezes > m.profile.StreakFreezes {
 			m.profile.StreakFreezes = earnedFreezes
 		}
@@ -285,9 +288,22 @@ func (m *CoachManager) updateStreak() {
 	}
 
 	m.profile.LastActiveDate = sql.NullTime{Time: now, Valid: true}
+	// Only reached on the first use of the day, since that's when the streak changes
+	m.checkDailyBonus()
 	m.db.Save(m.profile)
 }
 
+// checkDailyBonus awards the daily check-in bonus for the current streak
+func (m *CoachManager) checkDailyBonus() {
+	bonus := DailyCheckInXP(m.profile.CurrentStreak)
+	message := "Daily check-in! +" + formatInt(bonus) + " XP"
+	if m.profile.CurrentStreak > 1 {
+		message += " for day " + formatInt(m.profile.CurrentStreak) + " of your streak"
+	}
+	m.addNotification("check_in", message, "📅", bonus)
+	m.addXP(bonus, "daily_check_in")
+}
+
 // RecordCommand records a command execution for gamification
 func (m *CoachManager) RecordCommand(command string, exitCode int, durationMs int64) {
 	m.sessionCommands++
@@ -297,8 +313,8 @@ func (m *CoachManager) RecordCommand(command string, exitCode int, durationMs in
 	m.profile.CommandsSinceLastTipGen++
 	m.db.Save(m.profile)
 
-	// Check if we need to generate new tips (every 1000 commands)
-	if m.profile.CommandsSinceLastTipGen >= 1000 {
+	// Check if we need to generate new tips (every 1000 commands, fewer with perks)
+	if m.profile.CommandsSinceLastTipGen >= TipGenerationInterval(m.profile.Level) {
 		m.checkAndTriggerTipGeneration()
 	}
 
@@ -379,9 +395,11 @@ func (m *CoachManager) addXP(baseXP int, source string) {
 		if levelUp != nil {
 			m.profile.Level = newLevel
 			m.profile.Title = GetTitleForLevel(newLevel)
-			m.addNotification("level_up",
-				"You are now Level "+formatInt(newLevel)+" - "+m.profile.Title,
-				"⭐", 0)
+			message := "You are now Level " + formatInt(newLevel) + " - " + m.profile.Title
+			for _, perk := range levelUp.Perks {
+				message += ". Perk unlocked: " + perk.Description
+			}
+			m.addNotification("level_up", message, "⭐", 0)
 		}
 	}
 
@@ -644,6 +662,24 @@ func (m *CoachManager) GetPendingNotifications() []CoachNotification {
 	return notifs
 }
 
+// GetRecentNotifications returns up to limit notifications, newest first,
+// whether they were shown or not
+func (m *CoachManager) GetRecentNotifications(limit int) []CoachNotification {
+	var notifs []CoachNotification
+	m.db.Where("profile_id = ?", m.profile.ID).Order("created_at DESC, id DESC").Limit(limit).Find(&notifs)
+	return notifs
+}
+
+// MarkAllNotificationsRead marks every notification as shown, including the
+// pending ones, and returns how many were unread
+func (m *CoachManager) MarkAllNotificationsRead() int64 {
+	m.pendingNotifications = nil
+	result := m.db.Model(&CoachNotification{}).
+		Where("profile_id = ? AND shown = ?", m.profile.ID, false).
+		Update("shown", true)
+	return result.RowsAffected
+}
+
 // GetProfile returns the user profile
 func (m *CoachManager) GetProfile() *CoachProfile {
 	return m.profile
@@ -666,6 +702,13 @@ func (m *CoachManager) GetWeeklyChallenges() []CoachChallenge {
 
 // GetDisplayContent returns content for the Assistant Box
 func (m *CoachManager) GetDisplayContent() *CoachDisplayContent {
+	// Safety warnings come before anything else
+	if m.secretWarning != nil {
+		warning := m.secretWarning
+		m.secretWarning = nil
+		return warning
+	}
+
 	// Priority 1: Pending notifications
 	if len(m.pendingNotifications) > 0 {
 		notif := m.pendingNotifications[0]
@@ -724,6 +767,19 @@ func (m *CoachManager) GetStartupContent() *CoachDisplayContent {
 		}
 	}
 
+	// Banked freezes go on the streak line to keep the content compact
+	if m.profile.StreakFreezes > 0 {
+		freezeInfo := "🧊 " + formatInt(m.profile.StreakFreezes) + " streak freeze"
+		if m.profile.StreakFreezes > 1 {
+			freezeInfo += "s"
+		}
+		if streakInfo != "" {
+			streakInfo += " · " + freezeInfo
+		} else {
+			streakInfo = freezeInfo
+		}
+	}
+
 	content := streakInfo
 	if m.todayStats != nil && m.todayStats.CommandsExecuted > 0 {
 		content += "\n📊 Today: " + formatInt(m.todayStats.CommandsExecuted) + " commands"
@@ -743,6 +799,21 @@ func (m *CoachManager) GetStartupContent() *CoachDisplayContent {
 		content += "\n🎯 " + formatInt(incomplete) + " daily challenges remaining"
 	}
 
+	// Add weekly challenge summary
+	if len(m.weeklyChallenges) > 0 {
+		completed := 0
+		for _, c := range m.weeklyChallenges {
+			if c.Completed {
+				completed++
+			}
+		}
+		if completed == len(m.weeklyChallenges) {
+			content += "\n📅 All weekly challenges complete"
+		} else {
+			content += "\n📅 Weekly challenges: " + formatInt(completed) + "/" + formatInt(len(m.weeklyChallenges)) + " complete"
+		}
+	}
+
 	return &CoachDisplayContent{
 		Type:    "startup",
 		Icon:    icon,
@@ -861,6 +932,8 @@ func getTitleForNotificationType(t string) string {
 		return "Challenge Complete!"
 	case "streak":
 		return "Streak Milestone!"
+	case "check_in":
+		return "Daily Check-in!"
 	default:
 		return "Notification"
 	}
@@ -919,9 +992,9 @@ func (m *CoachManager) checkAndTriggerTipGeneration() {
 		shouldGenerate = true
 	}
 
-	// Check if we've hit 1000 commands since last generation
-	if m.profile.CommandsSinceLastTipGen >= 1000 {
-		m.logger.Info("Triggering tip generation - 1000 commands reached",
+	// Check if we've hit the tip generation interval since last generation
+	if m.profile.CommandsSinceLastTipGen >= TipGenerationInterval(m.profile.Level) {
+		m.logger.Info("Triggering tip generation - interval reached",
 			zap.Int("commands_since_last", m.profile.CommandsSinceLastTipGen))
 		shouldGenerate = true
 	}
@@ -1109,15 +1182,18 @@ func ConvertDatabaseTipToDisplay(tip *CoachDatabaseTip) *CoachDisplayContent {
 	}
 }
 
-// ResetAndRegenerateTips clears all tips and generates new ones from the slow LLM
+// ResetAndRegenerateTips clears all tips and generates new ones from the slow LLM,
+// based on the history within window.
 // This runs synchronously and returns the result message
-func (m *CoachManager) ResetAndRegenerateTips() string {
+func (m *CoachManager) ResetAndRegenerateTips(window TipHistoryWindow) string {
 	// Check if essential components are available
 	if m.historyManager == nil || m.runner == nil {
 		return "Cannot regenerate tips - missing required components"
 	}
 
-	m.logger.Info("Resetting and regenerating all tips")
+	m.logger.Info("Resetting and regenerating all tips",
+		zap.Duration("since", window.Since),
+		zap.Int("last", window.Last))
 
 	// Step 1: Delete existing tips
 	progress := NewProgressIndicator("[1/3] Clearing existing tips...")
@@ -1133,6 +1209,7 @@ func (m *CoachManager) ResetAndRegenerateTips() string {
 	progress.Start()
 
 	generator := NewLLMTipGenerator(m.runner, m.historyManager, m, m.logger)
+	generator.SetHistoryWindow(window)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
@@ -1193,6 +1270,6 @@ func (m *CoachManager) ResetAndRegenerateTips() string {
 		zap.Int64("deleted", deletedCount),
 		zap.Int("llm_generated", storedCount))
 
-	return fmt.Sprintf("\nDone! Tips reset complete.\n  - Deleted: %d old tips\n  - Generated: %d AI tips based on your command history\n\nAll tips are now personalized to your shell usage!",
-		deletedCount, storedCount)
+	return fmt.Sprintf("\nDone! Tips reset complete.\n  - Deleted: %d old tips\n  - Generated: %d AI tips based on %s\n\nAll tips are now personalized to your shell usage!",
+		deletedCount, storedCount, window.Describe())
 }
@@ -0,0 +1,116 @@
+package coach
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestGetStartupContentShowsFreezesAndWeeklyChallenges(t *testing.T) {
+	manager := &CoachManager{
+		profile: &CoachProfile{Username: "sam", CurrentStreak: 4, StreakFreezes: 2},
+		weeklyChallenges: []CoachChallenge{
+			{ChallengeID: "weekly_a", Completed: true},
+			{ChallengeID: "weekly_b"},
+		},
+	}
+
+	content := manager.GetStartupContent().Content
+	assert.Contains(t, content, "🔥 Day 4 streak")
+	assert.Contains(t, content, "🧊 2 streak freezes")
+	assert.Contains(t, content, "📅 Weekly challenges: 1/2 complete")
+
+	manager.profile.StreakFreezes = 1
+	manager.weeklyChallenges[1].Completed = true
+	content = manager.GetStartupContent().Content
+	assert.Contains(t, content, "🧊 1 streak freeze")
+	assert.NotContains(t, content, "freezes")
+	assert.Contains(t, content, "📅 All weekly challenges complete")
+
+	// Nothing is shown without freezes or weekly challenges
+	manager.profile.StreakFreezes = 0
+	manager.weeklyChallenges = nil
+	content = manager.GetStartupContent().Content
+	assert.NotContains(t, content, "🧊")
+	assert.NotContains(t, content, "📅")
+}
+
+func TestRecentNotifications(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	manager, err := NewCoachManager(db, &history.HistoryManager{}, &interp.Runner{}, zap.NewNop())
+	require.NoError(t, err)
+	db.Where("1 = 1").Delete(&CoachNotification{})
+	manager.pendingNotifications = nil
+
+	manager.addNotification("achievement", "First Steps", "🏆", 50)
+	manager.addNotification("level_up", "Level 2", "⬆️", 0)
+	assert.Len(t, manager.GetPendingNotifications(), 2)
+
+	// Shown notifications stay reviewable, along with new ones
+	manager.addNotification("streak", "3 day streak", "🔥", 10)
+	db.Model(&CoachNotification{}).Where("content = ?", "First Steps").Update("created_at", time.Now().Add(-time.Hour))
+
+	recent := manager.GetRecentNotifications(10)
+	require.Len(t, recent, 3)
+	assert.Equal(t, "3 day streak", recent[0].Content)
+	assert.False(t, recent[0].Shown)
+	assert.Equal(t, "First Steps", recent[2].Content)
+	assert.True(t, recent[2].Shown)
+	assert.Len(t, manager.GetRecentNotifications(1), 1)
+
+	rendered := manager.RenderNotifications(10)
+	assert.Contains(t, rendered, "🆕")
+	assert.Contains(t, rendered, "First Steps (+50 XP)")
+	assert.Contains(t, rendered, "1 unread")
+
+	assert.Equal(t, int64(1), manager.MarkAllNotificationsRead())
+	assert.Empty(t, manager.GetPendingNotifications())
+	for _, n := range manager.GetRecentNotifications(10) {
+		assert.True(t, n.Shown)
+	}
+	assert.NotContains(t, manager.RenderNotifications(10), "unread")
+}
+
+// newTestCoachManager returns a manager on an in-memory database. Without a
+// history manager it generates no tips in the background, which would read the
+// profile while the test changes it.
+func newTestCoachManager(t *testing.T) *CoachManager {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	manager, err := NewCoachManager(db, nil, &interp.Runner{}, zap.NewNop())
+	require.NoError(t, err)
+	return manager
+}
+
+func TestDailyCheckIn(t *testing.T) {
+	manager := newTestCoachManager(t)
+
+	// The first session of the day checks in
+	notifications := manager.GetPendingNotifications()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "check_in", notifications[0].Type)
+	assert.Equal(t, "Daily check-in! +20 XP", notifications[0].Content)
+	assert.Positive(t, manager.profile.TotalXP)
+
+	// Later sessions on the same day don't
+	totalXP := manager.profile.TotalXP
+	manager.updateStreak()
+	assert.Empty(t, manager.GetPendingNotifications())
+	assert.Equal(t, totalXP, manager.profile.TotalXP)
+
+	// The bonus grows with the streak
+	manager.profile.CurrentStreak = 3
+	manager.profile.LastActiveDate.Time = time.Now().AddDate(0, 0, -1)
+	manager.updateStreak()
+	notifications = manager.GetPendingNotifications()
+	require.NotEmpty(t, notifications)
+	assert.Equal(t, "Daily check-in! +35 XP for day 4 of your streak", notifications[len(notifications)-1].Content)
+}
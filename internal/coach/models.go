@@ -191,7 +191,7 @@ type CoachNotification struct {
 	CreatedAt time.Time `gorm:"index"`
 	ProfileID uint      `gorm:"index"`
 
-	Type    string // "achievement", "level_up", "challenge", "streak", "milestone"
+	Type    string // "achievement", "level_up", "challenge", "streak", "check_in", "milestone"
 	Title   string
 	Content string `gorm:"type:text"`
 	Icon    string
@@ -0,0 +1,94 @@
+package coach
+
+// PerkType is the kind of functional bonus a perk grants
+type PerkType string
+
+const (
+	// PerkStreakFreeze lets one more streak freeze be held
+	PerkStreakFreeze PerkType = "streak_freeze"
+	// PerkTipCadence generates new tips after fewer commands
+	PerkTipCadence PerkType = "tip_cadence"
+	// PerkDailyChallenge adds a daily challenge
+	PerkDailyChallenge PerkType = "daily_challenge"
+)
+
+const (
+	// baseStreakFreezes is how many streak freezes can be held without perks
+	baseStreakFreezes = 3
+	// baseTipGenerationInterval is how many commands run between tip
+	// generations without perks
+	baseTipGenerationInterval = 1000
+	// tipCadenceStep is how many commands each tip cadence perk takes off
+	tipCadenceStep = 250
+	// baseDailyChallenges is how many daily challenges are given without perks
+	baseDailyChallenges = 4
+)
+
+// LevelPerk is a bonus unlocked once a level is reached
+type LevelPerk struct {
+	Level       int
+	Type        PerkType
+	Description string
+}
+
+// LevelPerks lists the perks in the order they're unlocked
+var LevelPerks = []LevelPerk{
+	{Level: 15, Type: PerkStreakFreeze, Description: "Hold up to 4 streak freezes"},
+	{Level: 30, Type: PerkDailyChallenge, Description: "5 daily challenges"},
+	{Level: 45, Type: PerkTipCadence, Description: "New tips every 750 commands"},
+	{Level: 60, Type: PerkStreakFreeze, Description: "Hold up to 5 streak freezes"},
+	{Level: 75, Type: PerkTipCadence, Description: "New tips every 500 commands"},
+	{Level: 90, Type: PerkDailyChallenge, Description: "6 daily challenges"},
+}
+
+// PerksForLevel returns the perks unlocked at or below level
+func PerksForLevel(level int) []LevelPerk {
+	return PerksUnlockedBetween(0, level)
+}
+
+// PerksUnlockedBetween returns the perks unlocked by going from oldLevel to newLevel
+func PerksUnlockedBetween(oldLevel, newLevel int) []LevelPerk {
+	var perks []LevelPerk
+	for _, perk := range LevelPerks {
+		if perk.Level > oldLevel && perk.Level <= newLevel {
+			perks = append(perks, perk)
+		}
+	}
+	return perks
+}
+
+// NextPerk returns the next perk to unlock after level, if any
+func NextPerk(level int) (LevelPerk, bool) {
+	for _, perk := range LevelPerks {
+		if perk.Level > level {
+			return perk, true
+		}
+	}
+	return LevelPerk{}, false
+}
+
+func countPerks(level int, perkType PerkType) int {
+	count := 0
+	for _, perk := range PerksForLevel(level) {
+		if perk.Type == perkType {
+			count++
+		}
+	}
+	return count
+}
+
+// MaxStreakFreezes returns how many streak freezes can be held at level
+func MaxStreakFreezes(level int) int {
+	return baseStreakFreezes + countPerks(level, PerkStreakFreeze)
+}
+
+// TipGenerationInterval returns how many commands run between tip
+// generations at level
+func TipGenerationInterval(level int) int {
+	return baseTipGenerationInterval - tipCadenceStep*countPerks(level, PerkTipCadence)
+}
+
+// DailyChallengeCount returns how many daily challenges are given at level
+func DailyChallengeCount(level int) int {
+	return baseDailyChallenges + countPerks(level, PerkDailyChallenge)
+}
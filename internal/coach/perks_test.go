@@ -0,0 +1,57 @@
+package coach
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelPerks(t *testing.T) {
+	assert.Empty(t, PerksForLevel(14))
+	assert.Len(t, PerksForLevel(15), 1)
+	assert.Len(t, PerksForLevel(100), len(LevelPerks))
+
+	perks := PerksUnlockedBetween(14, 45)
+	require.Len(t, perks, 3)
+	assert.Equal(t, []PerkType{PerkStreakFreeze, PerkDailyChallenge, PerkTipCadence}, []PerkType{perks[0].Type, perks[1].Type, perks[2].Type})
+	assert.Empty(t, PerksUnlockedBetween(15, 29))
+
+	next, ok := NextPerk(15)
+	assert.True(t, ok)
+	assert.Equal(t, 30, next.Level)
+	_, ok = NextPerk(90)
+	assert.False(t, ok)
+
+	assert.Equal(t, 3, MaxStreakFreezes(1))
+	assert.Equal(t, 5, MaxStreakFreezes(100))
+	assert.Equal(t, 1000, TipGenerationInterval(1))
+	assert.Equal(t, 750, TipGenerationInterval(45))
+	assert.Equal(t, 500, TipGenerationInterval(100))
+	assert.Equal(t, 4, DailyChallengeCount(1))
+	assert.Equal(t, 6, DailyChallengeCount(100))
+
+	assert.Equal(t, 3, StreakFreezesEarned(40, 1))
+	assert.Equal(t, 5, StreakFreezesEarned(40, 60))
+	assert.Equal(t, 1, StreakFreezesEarned(10, 60))
+}
+
+func TestLevelUpAnnouncesPerks(t *testing.T) {
+	manager := newTestCoachManager(t)
+	manager.pendingNotifications = nil
+
+	manager.profile.Level = 14
+	manager.profile.TotalXP = XPForLevel(15) - 1
+	manager.addXP(10, "test")
+
+	assert.Equal(t, 15, manager.profile.Level)
+	notifications := manager.GetPendingNotifications()
+	require.NotEmpty(t, notifications)
+	levelUp := notifications[len(notifications)-1]
+	assert.Equal(t, "level_up", levelUp.Type)
+	assert.Contains(t, levelUp.Content, "Perk unlocked: Hold up to 4 streak freezes")
+
+	dashboard := manager.RenderDashboard()
+	assert.Contains(t, dashboard, "Hold up to 4 streak freezes (Level 15)")
+	assert.Contains(t, dashboard, "Next at Level 30: 5 daily challenges")
+}
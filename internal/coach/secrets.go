@@ -0,0 +1,242 @@
+package coach
+
+import (
+	"context"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// secretFilePatterns match files that usually hold credentials and should not
+// be committed. Patterns are matched against the base name of each file.
+var secretFilePatterns = []string{
+	".env", ".env.*", "*.pem", "*.key", "*.p12", "*.pfx", "*.keystore",
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	".netrc", ".npmrc", ".pypirc", "credentials.json", "*.tfstate",
+}
+
+// secretFileExemptSuffixes mark templates that are meant to be committed, like .env.example
+var secretFileExemptSuffixes = []string{".example", ".sample", ".template", ".dist"}
+
+// gitStatusOutput returns `git status --porcelain` for dir, including untracked
+// files that aren't ignored, limited to pathspecs if any. Can be replaced in tests.
+var gitStatusOutput = func(dir string, pathspecs []string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	args := append([]string{"status", "--porcelain", "--untracked-files=all", "--"}, pathspecs...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// isSecretFile reports whether the file's name matches one of secretFilePatterns
+func isSecretFile(file string) bool {
+	name := path.Base(file)
+	for _, suffix := range secretFileExemptSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	for _, pattern := range secretFilePatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// gitStaging describes which files a `git add` or `git commit` picks up
+type gitStaging struct {
+	pathspecs []string // the files given on the command line, if any
+	untracked bool     // whether untracked files are included
+	unstaged  bool     // whether modified files that aren't staged yet are included
+}
+
+// commandWords returns the words of each simple command in a command line,
+// with quotes removed. Words that can't be expanded statically are kept as typed.
+func commandWords(command string) [][]string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return [][]string{strings.Fields(command)}
+	}
+
+	var commands [][]string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok {
+			return true
+		}
+		words := make([]string, 0, len(call.Args))
+		for _, word := range call.Args {
+			literal, err := expand.Literal(nil, word)
+			if err != nil {
+				var buf strings.Builder
+				_ = syntax.NewPrinter().Print(&buf, word)
+				literal = buf.String()
+			}
+			words = append(words, literal)
+		}
+		commands = append(commands, words)
+		return true
+	})
+	return commands
+}
+
+// parseGitStaging returns what a simple command stages or commits, or false if
+// it isn't a `git add` or `git commit`
+func parseGitStaging(words []string) (gitStaging, bool) {
+	if len(words) < 2 || words[0] != "git" {
+		return gitStaging{}, false
+	}
+
+	// Skip global options like -C <dir> to find the subcommand
+	i := 1
+	for ; i < len(words) && strings.HasPrefix(words[i], "-"); i++ {
+		if words[i] == "-C" || words[i] == "-c" {
+			i++
+		}
+	}
+	if i >= len(words) {
+		return gitStaging{}, false
+	}
+
+	subcommand, args := words[i], words[i+1:]
+	var staging gitStaging
+	switch subcommand {
+	case "add":
+		staging = gitStaging{untracked: true, unstaged: true}
+	case "commit":
+		staging = gitStaging{}
+	default:
+		return gitStaging{}, false
+	}
+
+	for j := 0; j < len(args); j++ {
+		arg := args[j]
+		switch {
+		case arg == "--":
+			staging.pathspecs = append(staging.pathspecs, args[j+1:]...)
+			j = len(args)
+		case subcommand == "add" && (arg == "-u" || arg == "--update"):
+			staging.untracked = false
+		case subcommand == "commit" && arg == "--all":
+			staging.unstaged = true
+		case subcommand == "commit" && (arg == "--message" || arg == "--file" || arg == "--author"):
+			j++ // skip the option's value
+		case subcommand == "commit" && len(arg) > 1 && arg[0] == '-' && arg[1] != '-':
+			// Short options may be combined, as in -am "message"
+			if strings.Contains(arg, "a") {
+				staging.unstaged = true
+			}
+			if strings.ContainsAny(arg[len(arg)-1:], "mFC") {
+				j++
+			}
+		case strings.HasPrefix(arg, "-"):
+		default:
+			staging.pathspecs = append(staging.pathspecs, arg)
+		}
+	}
+
+	// `git commit <paths>` commits the current content of those files
+	if subcommand == "commit" && len(staging.pathspecs) > 0 {
+		staging.unstaged = true
+	}
+	return staging, true
+}
+
+// findExposedSecrets returns the secret files in porcelain that staging would
+// add to the repository. Ignored files never show up in git status.
+func findExposedSecrets(porcelain string, staging gitStaging) []string {
+	var secrets []string
+	for _, line := range strings.Split(porcelain, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		untracked := line[0] == '?'
+		staged := line[0] != ' ' && !untracked
+		switch {
+		case untracked && !staging.untracked:
+			continue
+		case !untracked && !staged && !staging.unstaged:
+			continue
+		}
+		// Deleted files can't leak anything new
+		if line[0] == 'D' || (line[1] == 'D' && !staged) {
+			continue
+		}
+
+		file := line[3:]
+		if _, renamedTo, ok := strings.Cut(file, " -> "); ok {
+			file = renamedTo
+		}
+		file = strings.Trim(file, `"`)
+		if isSecretFile(file) {
+			secrets = append(secrets, file)
+		}
+	}
+	return secrets
+}
+
+// CheckSecretExposure warns when command is a `git add` or `git commit` that
+// could add files that look like secrets, such as .env files or private keys
+// that aren't gitignored. The warning is also shown in the assistant box at
+// the next prompt. It returns "" when there is nothing to warn about.
+func (m *CoachManager) CheckSecretExposure(command string) string {
+	if m.runner == nil {
+		return ""
+	}
+
+	var secrets []string
+	unstageHint := false
+	seen := make(map[string]bool)
+	for _, words := range commandWords(command) {
+		staging, ok := parseGitStaging(words)
+		if !ok {
+			continue
+		}
+		porcelain, err := gitStatusOutput(m.runner.Dir, staging.pathspecs)
+		if err != nil {
+			continue
+		}
+		for _, secret := range findExposedSecrets(porcelain, staging) {
+			if !seen[secret] {
+				seen[secret] = true
+				secrets = append(secrets, secret)
+				// Staged secrets need to be unstaged, not just ignored
+				unstageHint = unstageHint || !staging.untracked
+			}
+		}
+	}
+	if len(secrets) == 0 {
+		return ""
+	}
+
+	listed := secrets
+	if len(listed) > 3 {
+		listed = listed[:3]
+	}
+	files := strings.Join(listed, ", ")
+	if len(secrets) > len(listed) {
+		files += " and " + formatInt(len(secrets)-len(listed)) + " more"
+	}
+
+	warning := "Possible secrets about to be committed: " + files + ". Add them to .gitignore"
+	if unstageHint {
+		warning += " and unstage them with git restore --staged"
+	}
+
+	m.secretWarning = &CoachDisplayContent{
+		Type:     "secret_warning",
+		Icon:     "🔐",
+		Title:    "Check before committing",
+		Content:  warning,
+		Priority: 20,
+	}
+	return warning
+}
@@ -0,0 +1,71 @@
+package coach
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestIsSecretFile(t *testing.T) {
+	for _, file := range []string{".env", "config/.env.production", "certs/server.pem", "id_ed25519", "infra/terraform.tfstate"} {
+		assert.True(t, isSecretFile(file), file)
+	}
+	for _, file := range []string{".env.example", "main.go", "docs/keys.md", "id_rsa.pub"} {
+		assert.False(t, isSecretFile(file), file)
+	}
+}
+
+func TestParseGitStaging(t *testing.T) {
+	staging, ok := parseGitStaging([]string{"git", "add", "."})
+	assert.True(t, ok)
+	assert.Equal(t, gitStaging{pathspecs: []string{"."}, untracked: true, unstaged: true}, staging)
+
+	staging, _ = parseGitStaging([]string{"git", "-C", "repo", "commit", "-am", "fix bug"})
+	assert.Equal(t, gitStaging{unstaged: true}, staging)
+
+	staging, _ = parseGitStaging([]string{"git", "commit", "-m", "fix"})
+	assert.Equal(t, gitStaging{}, staging)
+
+	_, ok = parseGitStaging([]string{"git", "status"})
+	assert.False(t, ok)
+
+	assert.Equal(t, [][]string{{"git", "add", "."}, {"git", "commit", "-m", "fix bug"}}, commandWords(`git add . && git commit -m "fix bug"`))
+}
+
+func TestCheckSecretExposure(t *testing.T) {
+	porcelain := "M  main.go\nA  .env\n?? certs/dev.pem\n?? .env.example\n M config/.env.local\nD  old.key\n"
+	var pathspecs []string
+	orig := gitStatusOutput
+	gitStatusOutput = func(dir string, specs []string) (string, error) {
+		pathspecs = specs
+		return porcelain, nil
+	}
+	t.Cleanup(func() { gitStatusOutput = orig })
+
+	runner, _ := interp.New()
+	manager := &CoachManager{runner: runner}
+
+	// add picks up untracked and modified files
+	warning := manager.CheckSecretExposure("git add -A")
+	assert.Contains(t, warning, ".env, certs/dev.pem, config/.env.local")
+	assert.NotContains(t, warning, "example")
+
+	// The warning is shown once in the assistant box
+	content := manager.GetDisplayContent()
+	assert.Equal(t, "secret_warning", content.Type)
+	assert.Equal(t, warning, content.Content)
+	assert.Nil(t, manager.secretWarning)
+
+	// commit only includes staged files
+	warning = manager.CheckSecretExposure(`git commit -m "add config"`)
+	assert.Contains(t, warning, ": .env.")
+	assert.Contains(t, warning, "git restore --staged")
+
+	// Paths given to add are passed on to git status
+	manager.CheckSecretExposure("git add src")
+	assert.Equal(t, []string{"src"}, pathspecs)
+
+	assert.Empty(t, manager.CheckSecretExposure("git status"))
+	assert.Empty(t, manager.CheckSecretExposure("ls .env"))
+}
@@ -25,6 +25,7 @@ type LLMTipGenerator struct {
 	coachManager   *CoachManager
 	logger         *zap.Logger
 	cache          *TipCache
+	window         TipHistoryWindow
 }
 
 // NewLLMTipGenerator creates a new LLM tip generator
@@ -43,6 +44,11 @@ func NewLLMTipGenerator(
 	}
 }
 
+// SetHistoryWindow limits the history tips are generated from
+func (g *LLMTipGenerator) SetHistoryWindow(window TipHistoryWindow) {
+	g.window = window
+}
+
 // TipContext contains all data needed for personalized tip generation
 type TipContext struct {
 	Username        string
@@ -138,7 +144,7 @@ func (g *LLMTipGenerator) buildTipContext(ctx context.Context) (*TipContext, err
 
 	// Get recent history (skip if historyManager or its db is nil)
 	if g.historyManager != nil && g.historyManager.GetDB() != nil {
-		entries, err := g.historyManager.GetRecentEntries("", 500)
+		entries, err := g.window.entries(g.historyManager, time.Now())
 		if err != nil {
 			g.logger.Warn("Failed to get history", zap.Error(err))
 		} else {
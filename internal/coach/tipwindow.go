@@ -0,0 +1,97 @@
+package coach
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/atinylittleshell/gsh/internal/utils"
+)
+
+// defaultTipHistoryLimit is how many recent commands tips are generated from
+// when no window is given
+const defaultTipHistoryLimit = 500
+
+// TipHistoryWindow limits the history tips are generated from, so tips can
+// reflect a workflow that changed recently. The zero value uses the last
+// defaultTipHistoryLimit commands.
+type TipHistoryWindow struct {
+	Since time.Duration // only commands run within this long ago, if positive
+	Last  int           // only the most recent commands, if positive
+}
+
+// ParseTipHistoryWindow parses the arguments of `@!coach reset-tips`, which
+// accepts `--since <duration>` (e.g. 30d, 2w, 12h) and `--last <count>`
+func ParseTipHistoryWindow(args []string) (TipHistoryWindow, error) {
+	var window TipHistoryWindow
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--since" && name != "--last" {
+			return window, fmt.Errorf("unknown option %q, expected --since or --last", args[i])
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return window, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "--since":
+			since, err := utils.ParseDuration(value)
+			if err != nil {
+				return window, fmt.Errorf("invalid duration %q for --since", value)
+			}
+			window.Since = since
+		case "--last":
+			last, err := strconv.Atoi(value)
+			if err != nil || last <= 0 {
+				return window, fmt.Errorf("invalid count %q for --last", value)
+			}
+			window.Last = last
+		}
+	}
+	return window, nil
+}
+
+// Describe summarizes the window for messages, e.g. "the last 30 days"
+func (w TipHistoryWindow) Describe() string {
+	var parts []string
+	if w.Since > 0 {
+		if w.Since%(24*time.Hour) == 0 {
+			parts = append(parts, fmt.Sprintf("the last %d days", int(w.Since/(24*time.Hour))))
+		} else {
+			parts = append(parts, "the last "+w.Since.String())
+		}
+	}
+	if w.Last > 0 {
+		parts = append(parts, fmt.Sprintf("your last %d commands", w.Last))
+	}
+	if len(parts) == 0 {
+		return "your command history"
+	}
+	return strings.Join(parts, ", limited to ")
+}
+
+// entries returns the history entries within the window, oldest first
+func (w TipHistoryWindow) entries(historyManager *history.HistoryManager, now time.Time) ([]history.HistoryEntry, error) {
+	if w.Since <= 0 {
+		limit := w.Last
+		if limit <= 0 {
+			limit = defaultTipHistoryLimit
+		}
+		return historyManager.GetRecentEntries("", limit)
+	}
+
+	entries, err := historyManager.GetEntriesSince(now.Add(-w.Since))
+	if err != nil {
+		return nil, err
+	}
+	if w.Last > 0 && len(entries) > w.Last {
+		entries = entries[len(entries)-w.Last:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package coach
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTipHistoryWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected TipHistoryWindow
+		wantErr  bool
+	}{
+		{name: "no options", args: nil, expected: TipHistoryWindow{}},
+		{name: "days", args: []string{"--since", "30d"}, expected: TipHistoryWindow{Since: 30 * 24 * time.Hour}},
+		{name: "weeks with equals", args: []string{"--since=2w"}, expected: TipHistoryWindow{Since: 14 * 24 * time.Hour}},
+		{name: "go duration", args: []string{"--since", "12h"}, expected: TipHistoryWindow{Since: 12 * time.Hour}},
+		{name: "count", args: []string{"--last", "500"}, expected: TipHistoryWindow{Last: 500}},
+		{name: "both", args: []string{"--since", "7d", "--last=100"}, expected: TipHistoryWindow{Since: 7 * 24 * time.Hour, Last: 100}},
+		{name: "missing value", args: []string{"--since"}, wantErr: true},
+		{name: "bad duration", args: []string{"--since", "soon"}, wantErr: true},
+		{name: "negative count", args: []string{"--last", "-5"}, wantErr: true},
+		{name: "unknown option", args: []string{"--all"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := ParseTipHistoryWindow(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, window)
+		})
+	}
+}
+
+func TestTipHistoryWindowDescribe(t *testing.T) {
+	assert.Equal(t, "your command history", TipHistoryWindow{}.Describe())
+	assert.Equal(t, "the last 30 days", TipHistoryWindow{Since: 30 * 24 * time.Hour}.Describe())
+	assert.Equal(t, "your last 200 commands", TipHistoryWindow{Last: 200}.Describe())
+	assert.Equal(t, "the last 12h0m0s, limited to your last 50 commands", TipHistoryWindow{Since: 12 * time.Hour, Last: 50}.Describe())
+}
+
+func TestTipHistoryWindowEntries(t *testing.T) {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	defer historyManager.Close()
+
+	for _, command := range []string{"old-1", "old-2", "new-1", "new-2", "new-3"} {
+		_, err := historyManager.StartCommand(command, "/tmp")
+		require.NoError(t, err)
+	}
+	// Age the first two entries
+	require.NoError(t, historyManager.GetDB().Exec(
+		"UPDATE history_entries SET created_at = ? WHERE command LIKE 'old-%'", time.Now().Add(-60*24*time.Hour)).Error)
+
+	commands := func(window TipHistoryWindow) []string {
+		entries, err := window.entries(historyManager, time.Now())
+		require.NoError(t, err)
+		result := []string{}
+		for _, entry := range entries {
+			result = append(result, entry.Command)
+		}
+		return result
+	}
+
+	assert.Len(t, commands(TipHistoryWindow{}), 5)
+	assert.Equal(t, []string{"new-2", "new-3"}, commands(TipHistoryWindow{Last: 2}))
+	assert.Equal(t, []string{"new-1", "new-2", "new-3"}, commands(TipHistoryWindow{Since: 30 * 24 * time.Hour}))
+	assert.Equal(t, []string{"new-3"}, commands(TipHistoryWindow{Since: 30 * 24 * time.Hour, Last: 1}))
+}
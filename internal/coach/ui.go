@@ -44,6 +44,7 @@ func (m *CoachManager) RenderDashboard() string {
 	sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  LEVEL %d %s ⭐ %d / %d XP\n", profile.Level, padRight("", 30), xpCurrent, xpNeeded)))
 	sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  %s %.1f%%\n", progressBar, progress*100)))
 	sb.WriteString(styles.AGENT_MESSAGE("║\n"))
+	m.renderPerks(&sb)
 	sb.WriteString(styles.AGENT_MESSAGE("║══════════════════════════════════════════════════════════════════════════║\n"))
 	sb.WriteString(styles.AGENT_MESSAGE("║\n"))
 
@@ -113,12 +114,34 @@ func (m *CoachManager) RenderDashboard() string {
 
 	// Footer
 	sb.WriteString(styles.AGENT_MESSAGE("╠══════════════════════════════════════════════════════════════════════════╣\n"))
-	sb.WriteString(styles.AGENT_MESSAGE("║  @!coach [stats|achievements|challenges|tips|reset-tips]                 ║\n"))
+	sb.WriteString(styles.AGENT_MESSAGE("║  @!coach [stats|achievements|challenges|heatmap|tips|reset-tips]         ║\n"))
 	sb.WriteString(styles.AGENT_MESSAGE("╚══════════════════════════════════════════════════════════════════════════╝\n"))
 
 	return sb.String()
 }
 
+// renderPerks lists the perks unlocked so far and the next one to unlock
+func (m *CoachManager) renderPerks(sb *strings.Builder) {
+	perks := PerksForLevel(m.profile.Level)
+	next, hasNext := NextPerk(m.profile.Level)
+	if len(perks) == 0 && !hasNext {
+		return
+	}
+
+	sb.WriteString(styles.AGENT_MESSAGE("║  🎁 PERKS\n"))
+	for i, perk := range perks {
+		branch := "├──"
+		if i == len(perks)-1 && !hasNext {
+			branch = "└──"
+		}
+		sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  %s %s (Level %d)\n", branch, perk.Description, perk.Level)))
+	}
+	if hasNext {
+		sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  └── Next at Level %d: %s\n", next.Level, next.Description)))
+	}
+	sb.WriteString(styles.AGENT_MESSAGE("║\n"))
+}
+
 // RenderStats renders detailed statistics
 func (m *CoachManager) RenderStats() string {
 	var sb strings.Builder
@@ -484,7 +507,7 @@ func (m *CoachManager) RenderAllTips() string {
 	// Show tip generation status
 	sb.WriteString(styles.AGENT_MESSAGE("║──────────────────────────────────────────────────────────────────────────║\n"))
 	sb.WriteString(styles.AGENT_MESSAGE("║  📊 TIP GENERATION STATUS\n"))
-	sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  ├── Commands since last generation: %d / 1000\n", m.profile.CommandsSinceLastTipGen)))
+	sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  ├── Commands since last generation: %d / %d\n", m.profile.CommandsSinceLastTipGen, TipGenerationInterval(m.profile.Level))))
 	if m.profile.LastTipGenTime.Valid {
 		sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  └── Last generated: %s\n", m.profile.LastTipGenTime.Time.Format("2006-01-02 15:04"))))
 	} else {
@@ -496,3 +519,41 @@ func (m *CoachManager) RenderAllTips() string {
 
 	return sb.String()
 }
+
+// RenderNotifications renders the latest notifications, marking the ones that
+// were never shown
+func (m *CoachManager) RenderNotifications(limit int) string {
+	var sb strings.Builder
+
+	sb.WriteString(styles.AGENT_MESSAGE("╔══════════════════════════════════════════════════════════════════════════╗\n"))
+	sb.WriteString(styles.AGENT_MESSAGE("║  🔔 NOTIFICATIONS                                                        ║\n"))
+	sb.WriteString(styles.AGENT_MESSAGE("╠══════════════════════════════════════════════════════════════════════════╣\n"))
+
+	notifs := m.GetRecentNotifications(limit)
+	if len(notifs) == 0 {
+		sb.WriteString(styles.AGENT_MESSAGE("║  No notifications yet\n"))
+	}
+
+	unread := 0
+	for _, n := range notifs {
+		marker := "  "
+		if !n.Shown {
+			marker = "🆕"
+			unread++
+		}
+		xp := ""
+		if n.XPGain > 0 {
+			xp = fmt.Sprintf(" (+%d XP)", n.XPGain)
+		}
+		sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  %s %s %s %s: %s%s\n",
+			marker, n.CreatedAt.Local().Format("2006-01-02 15:04"), n.Icon, n.Title, n.Content, xp)))
+	}
+
+	if unread > 0 {
+		sb.WriteString(styles.AGENT_MESSAGE("║\n"))
+		sb.WriteString(styles.AGENT_MESSAGE(fmt.Sprintf("║  %d unread, mark them read with @!coach notifications mark-read\n", unread)))
+	}
+	sb.WriteString(styles.AGENT_MESSAGE("╚══════════════════════════════════════════════════════════════════════════╝\n"))
+
+	return sb.String()
+}
@@ -0,0 +1,319 @@
+package completion
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+// ArchiveCompleter completes member paths inside tar and zip archives, e.g.
+// `tar -xf archive.tar <path>` or `unzip archive.zip <path>`
+type ArchiveCompleter struct {
+	mu     sync.Mutex
+	cache  map[string]archiveListing
+	limits archiveLimits
+}
+
+// archiveLimits bound the work of listing an archive while completing. A
+// listing cut short by them still completes the entries read until then.
+type archiveLimits struct {
+	// maxTarSize skips larger tar archives, since listing one reads all of it
+	maxTarSize int64
+	// maxTarBytes stops reading a tar archive once this much of it was
+	// decompressed
+	maxTarBytes int64
+	// maxEntries stops listing an archive after this many entries
+	maxEntries int
+}
+
+var defaultArchiveLimits = archiveLimits{
+	maxTarSize:  64 << 20,
+	maxTarBytes: 256 << 20,
+	maxEntries:  10000,
+}
+
+// archiveListing is a cached list of entries, valid as long as the archive
+// on disk hasn't been modified
+type archiveListing struct {
+	modTime time.Time
+	size    int64
+	entries []string
+}
+
+// NewArchiveCompleter creates a new ArchiveCompleter
+func NewArchiveCompleter() *ArchiveCompleter {
+	return &ArchiveCompleter{
+		cache:  make(map[string]archiveListing),
+		limits: defaultArchiveLimits,
+	}
+}
+
+// GetCompletions returns archive entries matching the word being completed, or
+// nil if the command line doesn't reference an archive to complete from
+func (a *ArchiveCompleter) GetCompletions(command string, args []string, line string, currentDirectory string) []shellinput.CompletionCandidate {
+	currentWord := ""
+	if len(args) > 0 {
+		currentWord = args[len(args)-1]
+	}
+	// If line ends with space, we're starting a new word
+	if len(line) > 0 && line[len(line)-1] == ' ' {
+		currentWord = ""
+		args = append(args, "")
+	}
+
+	var archivePath string
+	switch command {
+	case "tar":
+		archivePath = findTarArchive(args)
+	case "unzip":
+		archivePath = findZipArchive(args)
+	}
+	if archivePath == "" {
+		return nil
+	}
+
+	entries, err := a.listEntries(resolveArchivePath(archivePath, currentDirectory))
+	if err != nil {
+		return nil
+	}
+
+	return matchArchiveEntries(entries, currentWord)
+}
+
+// findTarArchive returns the archive given to -f when tar is extracting or
+// listing and the word being completed comes after it
+func findTarArchive(args []string) string {
+	last := len(args) - 1
+	archive := ""
+	archiveIndex := -1
+	creating := false
+
+	for i := 0; i < last; i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--file="):
+			archive = strings.TrimPrefix(arg, "--file=")
+			archiveIndex = i
+		case arg == "--file":
+			if i+1 < last {
+				archive = args[i+1]
+				archiveIndex = i + 1
+			}
+			i++
+		case arg == "--create" || arg == "--append" || arg == "--update":
+			creating = true
+		case strings.HasPrefix(arg, "--"):
+			continue
+		case i == 0 || strings.HasPrefix(arg, "-"):
+			// Option clusters such as -xzf, or the traditional xzf form as the first argument
+			cluster := strings.TrimPrefix(arg, "-")
+			if i == 0 && !strings.HasPrefix(arg, "-") && strings.ContainsAny(cluster, "./") {
+				continue
+			}
+			if strings.ContainsAny(cluster, "cru") {
+				creating = true
+			}
+			if idx := strings.IndexByte(cluster, 'f'); idx >= 0 {
+				if idx < len(cluster)-1 {
+					archive = cluster[idx+1:]
+					archiveIndex = i
+				} else if i+1 < last {
+					archive = args[i+1]
+					archiveIndex = i + 1
+					i++
+				}
+			}
+		}
+	}
+
+	if creating || archiveIndex < 0 || archiveIndex >= last {
+		return ""
+	}
+	// The word after -C is a directory on disk, not an archive member
+	if last > 0 && (args[last-1] == "-C" || args[last-1] == "--directory") {
+		return ""
+	}
+	return archive
+}
+
+// findZipArchive returns the first operand of unzip when the word being
+// completed comes after it
+func findZipArchive(args []string) string {
+	last := len(args) - 1
+	// The word after -d is the extraction directory, not an archive member
+	if last > 0 && args[last-1] == "-d" {
+		return ""
+	}
+	for i := 0; i < last; i++ {
+		arg := args[i]
+		if arg == "-d" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		return arg
+	}
+	return ""
+}
+
+func resolveArchivePath(path string, currentDirectory string) string {
+	path = strings.Trim(path, "\"'")
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(currentDirectory, path)
+	}
+	return path
+}
+
+// listEntries returns the entries of the archive at path, reusing the cached
+// listing if the file hasn't changed since it was read
+func (a *ArchiveCompleter) listEntries(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	cached, ok := a.cache[path]
+	a.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.entries, nil
+	}
+
+	var entries []string
+	if isZipArchive(path) {
+		entries, err = readZipEntries(path, a.limits)
+	} else if info.Size() <= a.limits.maxTarSize {
+		entries, err = readTarEntries(path, a.limits)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.cache[path] = archiveListing{modTime: info.ModTime(), size: info.Size(), entries: entries}
+	a.mu.Unlock()
+
+	return entries, nil
+}
+
+func isZipArchive(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip", ".jar", ".war", ".whl":
+		return true
+	}
+	return false
+}
+
+// readZipEntries lists a zip archive from its central directory, which
+// doesn't need the rest of the archive read
+func readZipEntries(path string, limits archiveLimits) ([]string, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	files := reader.File
+	if len(files) > limits.maxEntries {
+		files = files[:limits.maxEntries]
+	}
+	entries := make([]string, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, file.Name)
+	}
+	return entries, nil
+}
+
+// readTarEntries lists a tar archive, which means reading through all of it
+// as far as the limits allow
+func readTarEntries(path string, limits archiveLimits) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var stream io.Reader = file
+	lowerPath := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lowerPath, ".gz") || strings.HasSuffix(lowerPath, ".tgz"):
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		stream = gzipReader
+	case strings.HasSuffix(lowerPath, ".bz2") || strings.HasSuffix(lowerPath, ".tbz2"):
+		stream = bzip2.NewReader(file)
+	}
+
+	limited := &io.LimitedReader{R: stream, N: limits.maxTarBytes}
+	var entries []string
+	reader := tar.NewReader(limited)
+	for len(entries) < limits.maxEntries {
+		header, err := reader.Next()
+		if err == io.EOF || (err != nil && limited.N <= 0) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(header.Name, "./")
+		if name == "" {
+			continue
+		}
+		if header.Typeflag == tar.TypeDir && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		entries = append(entries, name)
+	}
+	return entries, nil
+}
+
+// matchArchiveEntries returns the entries under prefix, one directory level at
+// a time so that large archives stay navigable
+func matchArchiveEntries(entries []string, prefix string) []shellinput.CompletionCandidate {
+	seen := make(map[string]bool)
+	var matches []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry, prefix) {
+			continue
+		}
+		rest := entry[len(prefix):]
+		if idx := strings.Index(rest, "/"); idx >= 0 && idx < len(rest)-1 {
+			entry = prefix + rest[:idx+1]
+		}
+		if entry == prefix || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		matches = append(matches, entry)
+	}
+	sort.Strings(matches)
+
+	candidates := make([]shellinput.CompletionCandidate, len(matches))
+	for i, match := range matches {
+		candidates[i] = shellinput.CompletionCandidate{
+			Value:       match,
+			Description: "Archive entry",
+		}
+	}
+	return candidates
+}
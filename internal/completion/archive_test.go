@@ -0,0 +1,241 @@
+package completion
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestTar(t *testing.T, path string, names []string) {
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	writer := tar.NewWriter(file)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg}
+		if name[len(name)-1] == '/' {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0755
+		}
+		require.NoError(t, writer.WriteHeader(header))
+	}
+	require.NoError(t, writer.Close())
+}
+
+func writeTestZip(t *testing.T, path string, names []string) {
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for _, name := range names {
+		_, err := writer.Create(name)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+}
+
+func candidateValues(candidates []shellinput.CompletionCandidate) []string {
+	values := make([]string, len(candidates))
+	for i, c := range candidates {
+		values[i] = c.Value
+	}
+	return values
+}
+
+func TestArchiveCompleter(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTar(t, filepath.Join(dir, "archive.tar"), []string{"src/", "src/main.go", "src/util/helpers.go", "README.md"})
+	writeTestZip(t, filepath.Join(dir, "archive.zip"), []string{"docs/guide.md", "docs/api.md", "LICENSE"})
+
+	completer := NewArchiveCompleter()
+
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		line     string
+		expected []string
+	}{
+		{
+			name:     "tar lists top level entries",
+			command:  "tar",
+			args:     []string{"-xf", "archive.tar"},
+			line:     "tar -xf archive.tar ",
+			expected: []string{"README.md", "src/"},
+		},
+		{
+			name:     "tar descends into directories",
+			command:  "tar",
+			args:     []string{"-xf", "archive.tar", "src/"},
+			line:     "tar -xf archive.tar src/",
+			expected: []string{"src/main.go", "src/util/"},
+		},
+		{
+			name:     "traditional tar options",
+			command:  "tar",
+			args:     []string{"tf", "archive.tar", "R"},
+			line:     "tar tf archive.tar R",
+			expected: []string{"README.md"},
+		},
+		{
+			name:     "tar long file option",
+			command:  "tar",
+			args:     []string{"--extract", "--file=archive.tar", "s"},
+			line:     "tar --extract --file=archive.tar s",
+			expected: []string{"src/"},
+		},
+		{
+			name:     "tar create is not completed",
+			command:  "tar",
+			args:     []string{"-cf", "archive.tar"},
+			line:     "tar -cf archive.tar ",
+			expected: nil,
+		},
+		{
+			name:     "tar archive name itself is not completed",
+			command:  "tar",
+			args:     []string{"-xf", "arch"},
+			line:     "tar -xf arch",
+			expected: nil,
+		},
+		{
+			name:     "tar -C directory is not completed",
+			command:  "tar",
+			args:     []string{"-xf", "archive.tar", "-C"},
+			line:     "tar -xf archive.tar -C ",
+			expected: nil,
+		},
+		{
+			name:     "unzip entries",
+			command:  "unzip",
+			args:     []string{"archive.zip", "docs/"},
+			line:     "unzip archive.zip docs/",
+			expected: []string{"docs/api.md", "docs/guide.md"},
+		},
+		{
+			name:     "unzip with options",
+			command:  "unzip",
+			args:     []string{"-o", "archive.zip"},
+			line:     "unzip -o archive.zip ",
+			expected: []string{"LICENSE", "docs/"},
+		},
+		{
+			name:     "unzip -d directory is not completed",
+			command:  "unzip",
+			args:     []string{"archive.zip", "-d"},
+			line:     "unzip archive.zip -d ",
+			expected: nil,
+		},
+		{
+			name:     "missing archive",
+			command:  "unzip",
+			args:     []string{"missing.zip"},
+			line:     "unzip missing.zip ",
+			expected: nil,
+		},
+		{
+			name:     "other commands",
+			command:  "ls",
+			args:     []string{"archive.tar"},
+			line:     "ls archive.tar ",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := completer.GetCompletions(tt.command, tt.args, tt.line, dir)
+			if tt.expected == nil {
+				assert.Empty(t, result)
+				return
+			}
+			assert.Equal(t, tt.expected, candidateValues(result))
+		})
+	}
+}
+
+func TestArchiveCompleterCache(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "archive.tar")
+	writeTestTar(t, archivePath, []string{"first.txt"})
+
+	completer := NewArchiveCompleter()
+	args := []string{"-tf", "archive.tar"}
+	line := "tar -tf archive.tar "
+
+	assert.Equal(t, []string{"first.txt"}, candidateValues(completer.GetCompletions("tar", args, line, dir)))
+
+	// An unchanged archive is served from the cache
+	completer.cache[archivePath] = archiveListing{
+		modTime: completer.cache[archivePath].modTime,
+		size:    completer.cache[archivePath].size,
+		entries: []string{"cached.txt"},
+	}
+	assert.Equal(t, []string{"cached.txt"}, candidateValues(completer.GetCompletions("tar", args, line, dir)))
+
+	// Rewriting the archive invalidates the cached listing
+	writeTestTar(t, archivePath, []string{"second.txt"})
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(archivePath, future, future))
+	assert.Equal(t, []string{"second.txt"}, candidateValues(completer.GetCompletions("tar", args, line, dir)))
+}
+
+func TestArchiveCompleterLimits(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTar(t, filepath.Join(dir, "archive.tar"), []string{"a.txt", "b.txt", "c.txt"})
+	writeTestZip(t, filepath.Join(dir, "archive.zip"), []string{"a.txt", "b.txt", "c.txt"})
+
+	// Files with contents, which have to be read past to reach the next header
+	file, err := os.Create(filepath.Join(dir, "large.tar"))
+	require.NoError(t, err)
+	writer := tar.NewWriter(file)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		require.NoError(t, writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: 4096}))
+		_, err := writer.Write(make([]byte, 4096))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+
+	complete := func(limits archiveLimits, command string, archive string) []string {
+		completer := NewArchiveCompleter()
+		completer.limits = limits
+		if command == "unzip" {
+			return candidateValues(completer.GetCompletions("unzip", []string{archive, ""}, "unzip "+archive+" ", dir))
+		}
+		return candidateValues(completer.GetCompletions("tar", []string{"-tf", archive, ""}, "tar -tf "+archive+" ", dir))
+	}
+
+	t.Run("entries", func(t *testing.T) {
+		limits := defaultArchiveLimits
+		limits.maxEntries = 2
+		assert.Equal(t, []string{"a.txt", "b.txt"}, complete(limits, "tar", "archive.tar"))
+		assert.Equal(t, []string{"a.txt", "b.txt"}, complete(limits, "unzip", "archive.zip"))
+	})
+
+	t.Run("bytes read", func(t *testing.T) {
+		limits := defaultArchiveLimits
+		assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, complete(limits, "tar", "large.tar"))
+
+		// Enough for the first file and the second header
+		limits.maxTarBytes = 2*512 + 4096
+		assert.Equal(t, []string{"a.txt", "b.txt"}, complete(limits, "tar", "large.tar"))
+	})
+
+	t.Run("archive size", func(t *testing.T) {
+		limits := defaultArchiveLimits
+		limits.maxTarSize = 1024
+		assert.Empty(t, complete(limits, "tar", "large.tar"))
+		// Zip archives are listed from their directory whatever their size
+		assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, complete(limits, "unzip", "archive.zip"))
+	})
+}
@@ -38,7 +38,9 @@ func handleCompleteCommand(manager *CompletionManager, args []string) error {
 		wordList   string
 		function   string
 		commandCmd string
-		command    string
+		options    []string
+		flags      []FlagSpec
+		commands   []string
 	)
 
 	for i := 0; i < len(args); i++ {
@@ -66,57 +68,119 @@ func handleCompleteCommand(manager *CompletionManager, args []string) error {
 			}
 			i++
 			commandCmd = args[i]
+		case "-o":
+			// Accepted for compatibility with bash completion scripts. Only
+			// default and bashdefault change how completion behaves.
+			if i+1 >= len(args) {
+				return fmt.Errorf("option -o requires an option name")
+			}
+			i++
+			options = append(options, args[i])
+		case "-V":
+			if i+1 >= len(args) {
+				return fmt.Errorf("option -V requires a flag and its values")
+			}
+			i++
+			flag, err := parseFlagValues(args[i])
+			if err != nil {
+				return err
+			}
+			flags = append(flags, flag)
+		case "--":
+			commands = append(commands, args[i+1:]...)
+			i = len(args)
 		default:
 			if !strings.HasPrefix(arg, "-") {
-				command = arg
+				commands = append(commands, arg)
 				break
 			}
 			return fmt.Errorf("unknown option: %s", arg)
 		}
 	}
 
-	if command == "" && !printMode {
+	if len(commands) == 0 && !printMode {
 		return fmt.Errorf("no command specified")
 	}
 
 	// Handle different modes
 	if printMode {
-		return printCompletionSpecs(manager, command)
+		if len(commands) == 0 {
+			return printCompletionSpecs(manager, "")
+		}
+		for _, command := range commands {
+			if err := printCompletionSpecs(manager, command); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	if removeMode {
-		manager.RemoveSpec(command)
+		for _, command := range commands {
+			manager.RemoveSpec(command)
+		}
 		return nil
 	}
 
-	if wordList != "" {
-		manager.AddSpec(CompletionSpec{
-			Command: command,
-			Type:    WordListCompletion,
-			Value:   wordList,
-		})
-		return nil
+	var spec CompletionSpec
+	switch {
+	case wordList != "":
+		spec = CompletionSpec{Type: WordListCompletion, Value: wordList}
+	case function != "":
+		spec = CompletionSpec{Type: FunctionCompletion, Value: function}
+	case commandCmd != "":
+		spec = CompletionSpec{Type: CommandCompletion, Value: commandCmd}
+	default:
+		return fmt.Errorf("invalid complete command usage")
 	}
 
-	if function != "" {
-		manager.AddSpec(CompletionSpec{
-			Command: command,
-			Type:    FunctionCompletion,
-			Value:   function,
-		})
-		return nil
+	spec.Options = options
+	spec.Flags = flags
+	for _, command := range commands {
+		spec.Command = command
+		manager.AddSpec(spec)
+	}
+	return nil
+}
+
+// parseFlagValues parses the argument of -V, a flag and what its value
+// completes to: `--format=json yaml` for a list of values, `--out=@files`,
+// `--dir=@directories`, or `--ctx=@command <command>` for the lines a command
+// prints
+func parseFlagValues(arg string) (FlagSpec, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || !strings.HasPrefix(name, "-") {
+		return FlagSpec{}, fmt.Errorf("option -V expects --flag=values, got %q", arg)
 	}
 
-	if commandCmd != "" {
-		manager.AddSpec(CompletionSpec{
-			Command: command,
-			Type:    CommandCompletion,
-			Value:   commandCmd,
-		})
-		return nil
+	var values ArgSpec
+	switch {
+	case value == "@files":
+		values.Type = "files"
+	case value == "@directories":
+		values.Type = "directories"
+	case strings.HasPrefix(value, "@command "):
+		values.Type = "command"
+		values.Command = strings.TrimSpace(strings.TrimPrefix(value, "@command "))
+	default:
+		values.Type = "values"
+		values.Values = strings.Fields(value)
 	}
+	if err := values.validate("option -V " + name); err != nil {
+		return FlagSpec{}, err
+	}
+	return FlagSpec{Name: name, Value: &values}, nil
+}
 
-	return fmt.Errorf("invalid complete command usage")
+// formatFlagValues is the inverse of parseFlagValues
+func formatFlagValues(flag FlagSpec) string {
+	switch flag.Value.Type {
+	case "files", "directories":
+		return flag.Name + "=@" + flag.Value.Type
+	case "command":
+		return flag.Name + "=@command " + flag.Value.Command
+	}
+	return flag.Name + "=" + strings.Join(flag.Value.Values, " ")
 }
 
 func printCompletionSpecs(manager *CompletionManager, command string) error {
@@ -136,12 +200,20 @@ func printCompletionSpecs(manager *CompletionManager, command string) error {
 }
 
 func printCompletionSpec(spec CompletionSpec) {
+	options := ""
+	for _, option := range spec.Options {
+		options += "-o " + option + " "
+	}
+	for _, flag := range spec.Flags {
+		options += fmt.Sprintf("-V %q ", formatFlagValues(flag))
+	}
+
 	switch spec.Type {
 	case WordListCompletion:
-		_, _ = printf("complete -W %q %s\n", spec.Value, spec.Command)
+		_, _ = printf("complete %s-W %q %s\n", options, spec.Value, spec.Command)
 	case FunctionCompletion:
-		_, _ = printf("complete -F %s %s\n", spec.Value, spec.Command)
+		_, _ = printf("complete %s-F %s %s\n", options, spec.Value, spec.Command)
 	case CommandCompletion:
-		_, _ = printf("complete -C %q %s\n", spec.Value, spec.Command)
+		_, _ = printf("complete %s-C %q %s\n", options, spec.Value, spec.Command)
 	}
 }
@@ -0,0 +1,97 @@
+package completion
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandIndexTTL is how long the command index is used before it's refreshed
+// in the background, so newly installed tools eventually show up without @!rehash
+var commandIndexTTL = time.Minute
+
+// commandIndex holds the sorted names of the executables in PATH, so completing
+// a command name is a binary search instead of reading every PATH directory
+type commandIndex struct {
+	mu         sync.RWMutex
+	path       string
+	names      []string
+	builtAt    time.Time
+	refreshing bool
+}
+
+// lookup returns the indexed commands starting with prefix. The index is built
+// on first use and rebuilt whenever PATH changes. An index older than
+// commandIndexTTL is still used while a fresh one is built in the background.
+func (i *commandIndex) lookup(pathEnv string, prefix string) []string {
+	i.mu.RLock()
+	built := !i.builtAt.IsZero() && i.path == pathEnv
+	stale := built && time.Since(i.builtAt) > commandIndexTTL && !i.refreshing
+	i.mu.RUnlock()
+
+	if !built {
+		i.rebuild(pathEnv)
+	} else if stale {
+		i.mu.Lock()
+		if !i.refreshing {
+			i.refreshing = true
+			go i.rebuild(pathEnv)
+		}
+		i.mu.Unlock()
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	start := sort.SearchStrings(i.names, prefix)
+	var matches []string
+	for _, name := range i.names[start:] {
+		if !strings.HasPrefix(name, prefix) {
+			break
+		}
+		matches = append(matches, name)
+	}
+	return matches
+}
+
+// rebuild scans PATH and replaces the index, returning the number of commands found
+func (i *commandIndex) rebuild(pathEnv string) int {
+	names := scanPathCommands(pathEnv, "")
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.path = pathEnv
+	i.names = names
+	i.builtAt = time.Now()
+	i.refreshing = false
+	return len(names)
+}
+
+// scanPathCommands reads every directory in pathEnv and returns the sorted,
+// deduplicated names of the files starting with prefix
+func scanPathCommands(pathEnv string, prefix string) []string {
+	if pathEnv == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range strings.Split(pathEnv, string(os.PathListSeparator)) {
+		entries, err := osReadDir(dir)
+		if err != nil {
+			continue // Skip directories we can't read
+		}
+
+		for _, entry := range entries {
+			// Only consider regular files, executable permissions aren't checked
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) && !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,91 @@
+package completion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeExecutables(t testing.TB, dir string, names ...string) {
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755))
+	}
+}
+
+func TestCommandIndex(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeExecutables(t, first, "gofmt", "git", "grep")
+	writeExecutables(t, second, "git", "go")
+	require.NoError(t, os.Mkdir(filepath.Join(second, "gopls.d"), 0755))
+	pathEnv := first + string(os.PathListSeparator) + second
+
+	var index commandIndex
+	assert.Equal(t, []string{"go", "gofmt"}, index.lookup(pathEnv, "go"))
+	assert.Equal(t, []string{"git", "go", "gofmt", "grep"}, index.lookup(pathEnv, "g"))
+	assert.Empty(t, index.lookup(pathEnv, "x"))
+
+	// New tools aren't seen until the index is rebuilt
+	writeExecutables(t, second, "goreleaser")
+	assert.Equal(t, []string{"go", "gofmt"}, index.lookup(pathEnv, "go"))
+	assert.Equal(t, 5, index.rebuild(pathEnv))
+	assert.Equal(t, []string{"go", "gofmt", "goreleaser"}, index.lookup(pathEnv, "go"))
+
+	// A different PATH rebuilds the index right away
+	assert.Equal(t, []string{"go", "goreleaser"}, index.lookup(second, "go"))
+}
+
+func TestCommandIndexRefreshesInBackground(t *testing.T) {
+	dir := t.TempDir()
+	writeExecutables(t, dir, "make")
+
+	orig := commandIndexTTL
+	commandIndexTTL = 0
+	t.Cleanup(func() { commandIndexTTL = orig })
+
+	var index commandIndex
+	assert.Equal(t, []string{"make"}, index.lookup(dir, "ma"))
+
+	writeExecutables(t, dir, "man")
+	// The stale index is still answered from while the refresh runs
+	index.lookup(dir, "ma")
+	assert.Eventually(t, func() bool {
+		return len(index.lookup(dir, "ma")) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+// benchmarkPath creates a PATH of dirs directories holding files executables each
+func benchmarkPath(b *testing.B, dirs int, files int) string {
+	var pathDirs []string
+	for i := 0; i < dirs; i++ {
+		dir := b.TempDir()
+		for j := 0; j < files; j++ {
+			writeExecutables(b, dir, fmt.Sprintf("cmd%d-%d", i, j))
+		}
+		pathDirs = append(pathDirs, dir)
+	}
+	return strings.Join(pathDirs, string(os.PathListSeparator))
+}
+
+func BenchmarkCommandLookupScan(b *testing.B) {
+	pathEnv := benchmarkPath(b, 30, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanPathCommands(pathEnv, "cmd1")
+	}
+}
+
+func BenchmarkCommandLookupIndexed(b *testing.B) {
+	pathEnv := benchmarkPath(b, 30, 100)
+	var index commandIndex
+	index.rebuild(pathEnv)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.lookup(pathEnv, "cmd1")
+	}
+}
@@ -3,6 +3,7 @@ package completion
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 
 	"mvdan.cc/sh/v3/interp"
@@ -16,13 +17,14 @@ func NewCompgenCommandHandler(runner *interp.Runner) func(next interp.ExecHandle
 				return next(ctx, args)
 			}
 
-			// Handle the compgen command
-			return handleCompgenCommand(ctx, runner, args[1:])
+			// Handle the compgen command, printing to the command's stdout so
+			// COMPREPLY=($(compgen -W ...)) works
+			return handleCompgenCommand(ctx, runner, args[1:], interp.HandlerCtx(ctx).Stdout)
 		}
 	}
 }
 
-func handleCompgenCommand(ctx context.Context, runner *interp.Runner, args []string) error {
+func handleCompgenCommand(ctx context.Context, runner *interp.Runner, args []string, out io.Writer) error {
 	if len(args) == 0 {
 		return fmt.Errorf("compgen: no options specified")
 	}
@@ -49,6 +51,11 @@ func handleCompgenCommand(ctx context.Context, runner *interp.Runner, args []str
 			}
 			i++
 			functionName = args[i]
+		case "--":
+			if i+1 < len(args) {
+				word = args[i+1]
+			}
+			i = len(args)
 		default:
 			if !strings.HasPrefix(arg, "-") {
 				word = arg
@@ -60,27 +67,27 @@ func handleCompgenCommand(ctx context.Context, runner *interp.Runner, args []str
 
 	// Generate completions based on the options
 	if wordList != "" {
-		return generateWordListCompletions(word, wordList)
+		return generateWordListCompletions(out, word, wordList)
 	}
 
 	if functionName != "" {
-		return generateFunctionCompletions(ctx, runner, functionName, word)
+		return generateFunctionCompletions(ctx, out, runner, functionName, word)
 	}
 
 	return fmt.Errorf("compgen: no completion type specified")
 }
 
-func generateWordListCompletions(word string, wordList string) error {
+func generateWordListCompletions(out io.Writer, word string, wordList string) error {
 	words := strings.Fields(wordList)
 	for _, w := range words {
 		if word == "" || strings.HasPrefix(w, word) {
-			_, _ = printf("%s\n", w)
+			_, _ = fmt.Fprintln(out, w)
 		}
 	}
 	return nil
 }
 
-func generateFunctionCompletions(ctx context.Context, runner *interp.Runner, functionName string, word string) error {
+func generateFunctionCompletions(ctx context.Context, out io.Writer, runner *interp.Runner, functionName string, word string) error {
 	// Create a completion function
 	fn := NewCompletionFunction(functionName, runner)
 
@@ -93,7 +100,7 @@ func generateFunctionCompletions(ctx context.Context, runner *interp.Runner, fun
 	// Print the completions
 	for _, completion := range completions {
 		if word == "" || strings.HasPrefix(completion, word) {
-			_, _ = printf("%s\n", completion)
+			_, _ = fmt.Fprintln(out, completion)
 		}
 	}
 	return nil
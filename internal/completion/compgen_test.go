@@ -1,8 +1,8 @@
 package completion
 
 import (
+	"bytes"
 	"context"
-	"fmt"
 	"strings"
 	"testing"
 
@@ -11,10 +11,6 @@ import (
 )
 
 func TestCompgenCommand(t *testing.T) {
-	// Save and restore printf
-	oldPrintf := printf
-	defer func() { printf = oldPrintf }()
-
 	tests := []struct {
 		name          string
 		args          []string
@@ -92,13 +88,6 @@ func TestCompgenCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Create a buffer to capture output
-			var output []string
-			printf = func(format string, a ...interface{}) (n int, err error) {
-				output = append(output, strings.TrimSpace(fmt.Sprintf(format, a...)))
-				return 0, nil
-			}
-
 			// Create a new runner
 			parser := syntax.NewParser()
 			runner, err := interp.New()
@@ -117,12 +106,10 @@ func TestCompgenCommand(t *testing.T) {
 				}
 			}
 
-			// Create the handler
-			handler := NewCompgenCommandHandler(runner)
-			execHandler := handler(nil)
-
-			// Run the command
-			err = execHandler(context.Background(), tt.args)
+			// Run the command, capturing its output
+			var buf bytes.Buffer
+			err = handleCompgenCommand(context.Background(), runner, tt.args[1:], &buf)
+			output := strings.Fields(buf.String())
 
 			// Check error
 			if tt.wantErr {
@@ -154,3 +141,26 @@ func TestCompgenCommand(t *testing.T) {
 	}
 }
 
+
+func TestCompgenInCommandSubstitution(t *testing.T) {
+	var stdout bytes.Buffer
+	runner, err := interp.New(interp.StdIO(nil, &stdout, nil))
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+	if err := interp.ExecHandlers(NewCompgenCommandHandler(runner))(runner); err != nil {
+		t.Fatalf("failed to add handler: %v", err)
+	}
+
+	script := `cur=-b; COMPREPLY=($(compgen -W "-a -b --bar" -- "$cur")); echo "${COMPREPLY[@]}"`
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		t.Fatalf("failed to parse script: %v", err)
+	}
+	if err := runner.Run(context.Background(), file); err != nil {
+		t.Fatalf("failed to run script: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "-b" {
+		t.Errorf("COMPREPLY = %q, want %q", got, "-b")
+	}
+}
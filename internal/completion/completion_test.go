@@ -37,6 +37,10 @@ func TestCompletionManager(t *testing.T) {
 					fi
 				done
 			}
+
+			empty_completion() {
+				COMPREPLY=()
+			}
 		`
 		file, err := parser.Parse(strings.NewReader(setupScript), "")
 		assert.NoError(t, err)
@@ -91,6 +95,50 @@ func TestCompletionManager(t *testing.T) {
 				pos:  9,
 				want: []shellinput.CompletionCandidate{},
 			},
+			{
+				name: "flag value after equals",
+				spec: CompletionSpec{
+					Type:  WordListCompletion,
+					Value: "apple banana cherry",
+					Flags: []FlagSpec{{Name: "--format", Value: &ArgSpec{Type: "values", Values: []string{"json", "yaml", "text"}}}},
+				},
+				args: []string{"command", "--format=j"},
+				line: "command --format=j",
+				pos:  18,
+				want: []shellinput.CompletionCandidate{
+					{Value: "--format=json"},
+				},
+			},
+			{
+				name: "flag value as the next word",
+				spec: CompletionSpec{
+					Type:  WordListCompletion,
+					Value: "apple banana cherry",
+					Flags: []FlagSpec{{Name: "--format", Value: &ArgSpec{Type: "values", Values: []string{"json", "yaml", "text"}}}},
+				},
+				args: []string{"command", "--format"},
+				line: "command --format ",
+				pos:  17,
+				want: []shellinput.CompletionCandidate{
+					{Value: "json"},
+					{Value: "yaml"},
+					{Value: "text"},
+				},
+			},
+			{
+				name: "word after a flag value",
+				spec: CompletionSpec{
+					Type:  WordListCompletion,
+					Value: "apple banana cherry",
+					Flags: []FlagSpec{{Name: "--format", Value: &ArgSpec{Type: "values", Values: []string{"json", "yaml", "text"}}}},
+				},
+				args: []string{"command", "--format", "json", "b"},
+				line: "command --format json b",
+				pos:  23,
+				want: []shellinput.CompletionCandidate{
+					{Value: "banana"},
+				},
+			},
 			{
 				name: "function completion - basic",
 				spec: CompletionSpec{
@@ -135,6 +183,55 @@ func TestCompletionManager(t *testing.T) {
 					{Value: "baz"},
 				},
 			},
+			{
+				name: "function completion - after a space",
+				spec: CompletionSpec{
+					Type:  FunctionCompletion,
+					Value: "prefix_completion",
+				},
+				args: []string{"command"},
+				line: "command ",
+				pos:  8,
+				want: []shellinput.CompletionCandidate{
+					{Value: "foo"},
+					{Value: "bar"},
+					{Value: "baz"},
+				},
+			},
+			{
+				name: "function completion - no results",
+				spec: CompletionSpec{
+					Type:  FunctionCompletion,
+					Value: "empty_completion",
+				},
+				args: []string{"command", "x"},
+				line: "command x",
+				pos:  9,
+				want: []shellinput.CompletionCandidate{},
+			},
+			{
+				name: "function completion - no results with -o default",
+				spec: CompletionSpec{
+					Type:    FunctionCompletion,
+					Value:   "empty_completion",
+					Options: []string{"default"},
+				},
+				args: []string{"command", "x"},
+				line: "command x",
+				pos:  9,
+				want: nil,
+			},
+			{
+				name: "function completion - undefined function",
+				spec: CompletionSpec{
+					Type:  FunctionCompletion,
+					Value: "undefined_completion",
+				},
+				args:    []string{"command", "x"},
+				line:    "command x",
+				pos:     9,
+				wantErr: true,
+			},
 			{
 				name: "invalid completion type",
 				spec: CompletionSpec{
@@ -269,6 +366,74 @@ func TestCompleteCommandHandler(t *testing.T) {
 		assert.False(t, exists)
 	})
 
+	t.Run("options and several commands", func(t *testing.T) {
+		manager := NewCompletionManager()
+		wrappedHandler := NewCompleteCommandHandler(manager)(func(ctx context.Context, args []string) error {
+			return nil
+		})
+
+		var captured []string
+		oldPrintf := printf
+		printf = func(format string, a ...any) (int, error) {
+			captured = append(captured, fmt.Sprintf(format, a...))
+			return len(format), nil
+		}
+		defer func() { printf = oldPrintf }()
+
+		err := wrappedHandler(context.Background(), []string{"complete", "-o", "default", "-o", "nospace", "-F", "_tool", "tool", "tool2"})
+		assert.NoError(t, err)
+
+		for _, command := range []string{"tool", "tool2"} {
+			spec, exists := manager.GetSpec(command)
+			assert.True(t, exists)
+			assert.Equal(t, CompletionSpec{Command: command, Type: FunctionCompletion, Value: "_tool", Options: []string{"default", "nospace"}}, spec)
+			assert.True(t, spec.HasOption("default"))
+			assert.False(t, spec.HasOption("filenames"))
+		}
+
+		err = wrappedHandler(context.Background(), []string{"complete", "-p", "tool"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"complete -o default -o nospace -F _tool tool\n"}, captured)
+
+		err = wrappedHandler(context.Background(), []string{"complete", "-r", "tool", "tool2"})
+		assert.NoError(t, err)
+		assert.Empty(t, manager.ListSpecs())
+	})
+
+	t.Run("flag values", func(t *testing.T) {
+		manager := NewCompletionManager()
+		wrappedHandler := NewCompleteCommandHandler(manager)(func(ctx context.Context, args []string) error {
+			return nil
+		})
+
+		var captured []string
+		oldPrintf := printf
+		printf = func(format string, a ...any) (int, error) {
+			captured = append(captured, fmt.Sprintf(format, a...))
+			return len(format), nil
+		}
+		defer func() { printf = oldPrintf }()
+
+		err := wrappedHandler(context.Background(), []string{"complete", "-W", "build test", "-V", "--format=json yaml", "-V", "-o=@files", "-V", "--ctx=@command echo dev", "tool"})
+		assert.NoError(t, err)
+
+		spec, exists := manager.GetSpec("tool")
+		assert.True(t, exists)
+		assert.Equal(t, []FlagSpec{
+			{Name: "--format", Value: &ArgSpec{Type: "values", Values: []string{"json", "yaml"}}},
+			{Name: "-o", Value: &ArgSpec{Type: "files"}},
+			{Name: "--ctx", Value: &ArgSpec{Type: "command", Command: "echo dev"}},
+		}, spec.Flags)
+
+		got, err := manager.ExecuteCompletion(context.Background(), nil, spec, []string{"tool", "--ctx="}, "tool --ctx=", 11)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"--ctx=dev"}, candidateValues(got))
+
+		err = wrappedHandler(context.Background(), []string{"complete", "-p", "tool"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`complete -V "--format=json yaml" -V "-o=@files" -V "--ctx=@command echo dev" -W "build test" tool` + "\n"}, captured)
+	})
+
 	t.Run("error cases", func(t *testing.T) {
 		manager := NewCompletionManager()
 		handler := NewCompleteCommandHandler(manager)
@@ -292,6 +457,16 @@ func TestCompleteCommandHandler(t *testing.T) {
 				args:    []string{"complete", "-x", "mycmd"},
 				wantErr: "unknown option: -x",
 			},
+			{
+				name:    "flag values without a flag",
+				args:    []string{"complete", "-W", "foo", "-V", "json yaml", "mycmd"},
+				wantErr: "option -V expects --flag=values",
+			},
+			{
+				name:    "flag values from an empty command",
+				args:    []string{"complete", "-W", "foo", "-V", "--ctx=@command ", "mycmd"},
+				wantErr: "command type requires a command",
+			},
 			{
 				name:    "no command specified",
 				args:    []string{"complete", "-W", "foo bar"},
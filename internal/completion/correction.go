@@ -0,0 +1,97 @@
+package completion
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/bash"
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+// correctionHeader heads the completion box when the candidates are
+// corrections rather than completions of what was typed
+const correctionHeader = "(did you mean)"
+
+// correctionMaxDistance returns how many edits a correction of word may be
+// away from it. Short words allow one, since two edits of `ls` match most of PATH.
+func correctionMaxDistance(word string) int {
+	if len(word) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// getCommandCorrections returns the commands in PATH and the aliases close to
+// word, the closest first
+func (p *ShellCompletionProvider) getCommandCorrections(word string) []shellinput.CompletionCandidate {
+	if len(word) < 2 {
+		return nil
+	}
+	maxDistance := correctionMaxDistance(word)
+
+	names := p.commandIndex.lookup(os.Getenv("PATH"), "")
+	descriptions := make(map[string]string)
+	if p.Runner != nil {
+		for name, definition := range bash.Aliases(p.Runner) {
+			names = append(names, name)
+			descriptions[name] = strings.TrimSpace(definition)
+		}
+	}
+
+	distances := make(map[string]int)
+	for _, name := range names {
+		if _, ok := distances[name]; ok || len(name) > len(word)+maxDistance || len(name) < len(word)-maxDistance {
+			continue
+		}
+		if distance := editDistance(word, name); distance <= maxDistance {
+			distances[name] = distance
+		}
+	}
+
+	corrections := make([]shellinput.CompletionCandidate, 0, len(distances))
+	for name := range distances {
+		corrections = append(corrections, shellinput.CompletionCandidate{
+			Value:       name,
+			Description: descriptions[name],
+		})
+	}
+	sort.Slice(corrections, func(i, j int) bool {
+		a, b := corrections[i].Value, corrections[j].Value
+		if distances[a] != distances[b] {
+			return distances[a] < distances[b]
+		}
+		return a < b
+	})
+	return corrections
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and
+// transpositions of adjacent characters that turn a into b, so that the
+// transposed letters of `gti` are one edit away from `git`
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// d[i][j] is the distance between s[:i] and t[:j]
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
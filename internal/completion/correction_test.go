@@ -0,0 +1,90 @@
+package completion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/stretchr/testify/assert"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"git", "git", 0},
+		{"gti", "git", 1},
+		{"gi", "git", 1},
+		{"gitt", "git", 1},
+		{"got", "git", 1},
+		{"pyhton", "python", 1},
+		{"dokcer", "docker", 1},
+		{"kubeclt", "kubectl", 1},
+		{"sl", "ls", 1},
+		{"mkae", "make", 1},
+		{"vim", "nvim", 1},
+		{"", "ls", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"->"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.expected, editDistance(tt.a, tt.b))
+			assert.Equal(t, tt.expected, editDistance(tt.b, tt.a))
+		})
+	}
+}
+
+func TestCommandCorrections(t *testing.T) {
+	dir := t.TempDir()
+	writeExecutables(t, dir, "git", "gist", "grep", "docker", "python", "python3", "ls")
+	t.Setenv("PATH", dir)
+
+	runner, _ := interp.New()
+	setupTestAliases(runner)
+	provider := NewShellCompletionProvider(NewCompletionManager(), runner)
+
+	t.Run("transposed letters", func(t *testing.T) {
+		batch, _ := provider.complete(context.Background(), "gti", 3)
+		assert.Equal(t, []string{"git"}, candidateValues(batch.Candidates))
+		assert.Equal(t, correctionHeader, batch.Header)
+
+		results := provider.GetCompletionResults("gti", 3)
+		assert.Equal(t, "correction", results[0].Group)
+	})
+
+	t.Run("closest first", func(t *testing.T) {
+		// Longer words allow two edits, as python3 is from pyhton
+		suggestions := provider.GetCompletions("pyhton", 6)
+		assert.Equal(t, []string{"python", "python3"}, candidateValues(suggestions))
+	})
+
+	t.Run("aliases with their expansion", func(t *testing.T) {
+		suggestions := provider.GetCompletions("mylaias", 7)
+		assert.Equal(t, []shellinput.CompletionCandidate{{Value: "myalias", Description: "pwd"}}, suggestions)
+	})
+
+	t.Run("completions win over corrections", func(t *testing.T) {
+		batch, _ := provider.complete(context.Background(), "gi", 2)
+		assert.Equal(t, []string{"gist", "git"}, candidateValues(batch.Candidates))
+		assert.Empty(t, batch.Header)
+	})
+
+	t.Run("nothing close", func(t *testing.T) {
+		batch, _ := provider.complete(context.Background(), "xyzzy", 5)
+		assert.Empty(t, batch.Candidates)
+		assert.Empty(t, batch.Header)
+	})
+
+	t.Run("only the command word", func(t *testing.T) {
+		assert.Empty(t, provider.GetCompletions("echo gti", 8))
+		assert.Empty(t, provider.GetCompletions("./gti", 5))
+	})
+
+	t.Run("disabled through the sources", func(t *testing.T) {
+		t.Setenv("GSH_COMPLETION_SOURCES", `["command","file"]`)
+		provider := NewShellCompletionProvider(NewCompletionManager(), nil)
+		assert.Empty(t, provider.GetCompletions("gti", 3))
+	})
+}
@@ -23,8 +23,8 @@ func (d *DefaultCompleter) GetCompletions(command string, args []string, line st
 		return d.completeSSHHosts(args), true
 	case "make":
 		return d.completeMakeTargets(args), true
-	case "kill":
-		return d.completeKillSignals(args), true
+	case "kill", "pkill", "killall":
+		return completeSignals(args, line), true
 	case "man", "help":
 		// For now, just return nil to let it fall back or implementation TODO
 		// Implementing full man page scanning is expensive for a default
@@ -174,34 +174,3 @@ func (d *DefaultCompleter) completeMakeTargets(args []string) []shellinput.Compl
 	}
 	return candidates
 }
-
-func (d *DefaultCompleter) completeKillSignals(args []string) []shellinput.CompletionCandidate {
-	prefix := ""
-	if len(args) > 0 {
-		prefix = args[len(args)-1]
-	}
-
-	// If prefix doesn't start with -, maybe they are typing a PID.
-	// If it starts with -, they want a signal.
-	if strings.HasPrefix(prefix, "-") {
-		signals := []string{
-			"-HUP", "-INT", "-QUIT", "-ILL", "-TRAP", "-ABRT", "-BUS", "-FPE",
-			"-KILL", "-USR1", "-SEGV", "-USR2", "-PIPE", "-ALRM", "-TERM",
-			"-STKFLT", "-CHLD", "-CONT", "-STOP", "-TSTP", "-TTIN", "-TTOU",
-			"-URG", "-XCPU", "-XFSZ", "-VTALRM", "-PROF", "-WINCH", "-IO",
-			"-PWR", "-SYS",
-		}
-
-		var candidates []shellinput.CompletionCandidate
-		for _, sig := range signals {
-			if strings.HasPrefix(sig, prefix) {
-				candidates = append(candidates, shellinput.CompletionCandidate{
-					Value:       sig,
-					Description: "Signal",
-				})
-			}
-		}
-		return candidates
-	}
-	return nil
-}
@@ -0,0 +1,127 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// carapaceListTimeout bounds listing the commands carapace can complete
+const carapaceListTimeout = 3 * time.Second
+
+// carapaceCompleters lists the commands the carapace at path can complete, can
+// be replaced in tests
+var carapaceCompleters = func(path string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), carapaceListTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--list").Output()
+	if err != nil {
+		return nil, err
+	}
+	// Each line starts with the name of a completer, followed by its description
+	var commands []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			commands = append(commands, fields[0])
+		}
+	}
+	return commands, nil
+}
+
+// Discover looks for a way to complete command and registers the best one it
+// finds, returning what it did. It tries, in order, a spec file in specDir
+// that isn't loaded yet, carapace, and the command's man page. Commands that
+// already complete through a spec or a built-in completer are left alone.
+func (p *ShellCompletionProvider) Discover(command string, specDir string) (string, error) {
+	manager, ok := p.CompletionManager.(*CompletionManager)
+	if !ok {
+		return "", errors.New("completions can't be registered in this session")
+	}
+
+	if spec, ok := manager.GetSpec(command); ok {
+		return fmt.Sprintf("%s already has completions (%s).", command, describeSpec(spec)), nil
+	}
+	if p.hasBuiltinCompletions(command) {
+		return fmt.Sprintf("%s already has built-in completions.", command), nil
+	}
+
+	if specDir != "" {
+		for _, ext := range []string{".yaml", ".yml"} {
+			path := filepath.Join(specDir, command+ext)
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			spec, err := ParseCommandSpec(data)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", path, err)
+			}
+			manager.AddCommandSpec(spec)
+			return fmt.Sprintf("Registered completions for %s from %s.", command, path), nil
+		}
+	}
+
+	if path, err := execLookPath("carapace"); err == nil {
+		if commands, err := carapaceCompleters(path); err == nil && containsString(commands, command) {
+			manager.AddSpec(CompletionSpec{Command: command, Type: CommandCompletion, Value: path})
+			return fmt.Sprintf("Registered completions for %s from carapace.", command), nil
+		}
+	}
+
+	if manCommandNamePattern.MatchString(command) {
+		if options := p.manPageCompleter.options(command); len(options) > 0 {
+			spec := &CommandSpec{Name: command}
+			for _, option := range options {
+				spec.Flags = append(spec.Flags, FlagSpec{Name: option.Flag, Description: option.Description})
+			}
+			manager.AddCommandSpec(spec)
+			return fmt.Sprintf("Registered %d flags for %s from its man page.", len(options), command), nil
+		}
+	}
+
+	return fmt.Sprintf("No completions found for %s. Checked %s, carapace and man pages.", command, specDirName(specDir)), nil
+}
+
+// hasBuiltinCompletions returns whether one of the dedicated completers
+// handles command
+func (p *ShellCompletionProvider) hasBuiltinCompletions(command string) bool {
+	if command == "git" || command == "docker" || command == "tmux" {
+		return true
+	}
+	if _, ok := p.staticCompleter.completions[command]; ok {
+		return true
+	}
+	_, found := p.defaultCompleter.GetCompletions(command, nil, command, len(command))
+	return found
+}
+
+// describeSpec says where a registered spec's completions come from
+func describeSpec(spec CompletionSpec) string {
+	switch spec.Type {
+	case WordListCompletion:
+		return "complete -W"
+	case FunctionCompletion:
+		return "complete -F " + spec.Value
+	case CommandCompletion:
+		return "complete -C " + spec.Value
+	case SpecFileCompletion:
+		return "spec file"
+	}
+	return string(spec.Type)
+}
+
+func specDirName(specDir string) string {
+	if specDir == "" {
+		return "spec files"
+	}
+	return filepath.Join(specDir, "<command>.yaml")
+}
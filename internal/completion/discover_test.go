@@ -0,0 +1,82 @@
+package completion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscover(t *testing.T) {
+	origExecLookPath := execLookPath
+	execLookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { execLookPath = origExecLookPath }()
+
+	origCarapaceCompleters := carapaceCompleters
+	carapaceCompleters = func(path string) ([]string, error) {
+		return []string{"kubectx", "terraform"}, nil
+	}
+	defer func() { carapaceCompleters = origCarapaceCompleters }()
+
+	origManPageOutput := manPageOutput
+	manPageOutput = func(command string) (string, error) {
+		if command != "frob" {
+			return "", errors.New("no manual entry")
+		}
+		return sampleManPage, nil
+	}
+	defer func() { manPageOutput = origManPageOutput }()
+
+	specDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(specDir, "mytool.yaml"), []byte("name: mytool\nflags:\n  - name: --verbose\n"), 0644))
+
+	manager := NewCompletionManager()
+	manager.AddSpec(CompletionSpec{Command: "deploy", Type: WordListCompletion, Value: "staging production"})
+	provider := NewShellCompletionProvider(manager, nil)
+
+	discover := func(command string) string {
+		message, err := provider.Discover(command, specDir)
+		require.NoError(t, err)
+		return message
+	}
+
+	assert.Equal(t, "deploy already has completions (complete -W).", discover("deploy"))
+	assert.Equal(t, "git already has built-in completions.", discover("git"))
+	assert.Equal(t, "cd already has built-in completions.", discover("cd"))
+	assert.Equal(t, "npm already has built-in completions.", discover("npm"))
+
+	// A spec file is preferred over the other sources
+	assert.Equal(t, "Registered completions for mytool from "+filepath.Join(specDir, "mytool.yaml")+".", discover("mytool"))
+	spec, ok := manager.GetSpec("mytool")
+	assert.True(t, ok)
+	assert.Equal(t, SpecFileCompletion, spec.Type)
+
+	assert.Equal(t, "Registered completions for kubectx from carapace.", discover("kubectx"))
+	assert.Equal(t, CompletionSpec{Command: "kubectx", Type: CommandCompletion, Value: "/usr/bin/carapace"}, mustGetSpec(t, manager, "kubectx"))
+
+	assert.Equal(t, "Registered 6 flags for frob from its man page.", discover("frob"))
+	completions := provider.GetCompletions("frob --o", 8)
+	assert.Equal(t, []string{"--output"}, candidateValues(completions))
+
+	// Once registered, discovering again leaves the command alone
+	assert.Equal(t, "frob already has completions (spec file).", discover("frob"))
+
+	assert.Equal(t, "No completions found for nope. Checked "+filepath.Join(specDir, "<command>.yaml")+", carapace and man pages.", discover("nope"))
+	_, ok = manager.GetSpec("nope")
+	assert.False(t, ok)
+}
+
+func TestDiscoverWithoutManager(t *testing.T) {
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+	_, err := provider.Discover("kubectx", "")
+	assert.Error(t, err)
+}
+
+func mustGetSpec(t *testing.T, manager *CompletionManager, command string) CompletionSpec {
+	spec, ok := manager.GetSpec(command)
+	require.True(t, ok)
+	return spec
+}
@@ -0,0 +1,213 @@
+package completion
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+const (
+	// dockerCacheTTL is how long listings of containers and images are reused
+	dockerCacheTTL = 5 * time.Second
+
+	// dockerTimeout bounds each docker call, so a slow or stopped daemon
+	// doesn't freeze completion
+	dockerTimeout = 2 * time.Second
+)
+
+// dockerOutput runs docker with the given arguments, can be replaced in tests
+var dockerOutput = func(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	return string(out), err
+}
+
+// dockerListing is what a docker subcommand operates on
+type dockerListing int
+
+const (
+	dockerRunningContainers dockerListing = iota + 1
+	dockerAllContainers
+	dockerImages
+)
+
+// dockerSubcommandTargets maps subcommands to the objects they take. Commands
+// that only make sense for running containers don't offer stopped ones.
+var dockerSubcommandTargets = map[string]dockerListing{
+	"attach":  dockerRunningContainers,
+	"exec":    dockerRunningContainers,
+	"kill":    dockerRunningContainers,
+	"pause":   dockerRunningContainers,
+	"port":    dockerRunningContainers,
+	"stats":   dockerRunningContainers,
+	"stop":    dockerRunningContainers,
+	"top":     dockerRunningContainers,
+	"diff":    dockerAllContainers,
+	"export":  dockerAllContainers,
+	"inspect": dockerAllContainers,
+	"logs":    dockerAllContainers,
+	"rename":  dockerAllContainers,
+	"restart": dockerAllContainers,
+	"rm":      dockerAllContainers,
+	"start":   dockerAllContainers,
+	"unpause": dockerAllContainers,
+	"wait":    dockerAllContainers,
+	"create":  dockerImages,
+	"history": dockerImages,
+	"push":    dockerImages,
+	"rmi":     dockerImages,
+	"run":     dockerImages,
+	"save":    dockerImages,
+	"tag":     dockerImages,
+}
+
+// dockerSingleTargetSubcommands take one object followed by other arguments,
+// such as the command to run
+var dockerSingleTargetSubcommands = map[string]bool{
+	"create": true,
+	"exec":   true,
+	"port":   true,
+	"rename": true,
+	"run":    true,
+	"tag":    true,
+}
+
+// dockerValueFlags are flags of run, create and exec that take a separate value
+var dockerValueFlags = map[string]bool{
+	"-e": true, "--env": true, "--env-file": true,
+	"-v": true, "--volume": true, "--mount": true,
+	"-p": true, "--publish": true,
+	"-u": true, "--user": true,
+	"-w": true, "--workdir": true,
+	"-l": true, "--label": true,
+	"--name": true, "--network": true, "--entrypoint": true, "--platform": true,
+}
+
+// DockerCompleter completes container and image names for docker subcommands
+// by asking the docker daemon
+type DockerCompleter struct {
+	mu    sync.Mutex
+	cache map[dockerListing]dockerCacheEntry
+}
+
+type dockerCacheEntry struct {
+	candidates []shellinput.CompletionCandidate
+	expires    time.Time
+}
+
+// GetCompletions returns container or image names for the object argument of
+// a docker subcommand. Subcommands themselves are left to the static completer.
+func (d *DockerCompleter) GetCompletions(args []string, line string) []shellinput.CompletionCandidate {
+	if len(args) == 0 {
+		return nil
+	}
+
+	currentWord := args[len(args)-1]
+	preceding := args[:len(args)-1]
+	if strings.HasSuffix(line, " ") {
+		currentWord = ""
+		preceding = args
+	}
+	if len(preceding) == 0 || strings.HasPrefix(currentWord, "-") {
+		return nil
+	}
+
+	// `docker container stop` and `docker image rm` work like `docker stop` and `docker rmi`
+	subcommand, rest := preceding[0], preceding[1:]
+	if (subcommand == "container" || subcommand == "image") && len(rest) > 0 {
+		group := subcommand
+		subcommand, rest = rest[0], rest[1:]
+		if group == "image" && subcommand == "rm" {
+			subcommand = "rmi"
+		}
+	}
+
+	listing, ok := dockerSubcommandTargets[subcommand]
+	if !ok {
+		return nil
+	}
+	if dockerSingleTargetSubcommands[subcommand] && dockerPositionalCount(rest) > 0 {
+		return nil
+	}
+
+	return filterCandidates(d.cached(listing), currentWord)
+}
+
+// dockerPositionalCount counts the arguments that aren't flags or flag values
+func dockerPositionalCount(args []string) int {
+	count := 0
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case dockerValueFlags[arg]:
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			count++
+		}
+	}
+	return count
+}
+
+// cached returns the listing, asking docker if there is none younger than dockerCacheTTL
+func (d *DockerCompleter) cached(listing dockerListing) []shellinput.CompletionCandidate {
+	d.mu.Lock()
+	entry, ok := d.cache[listing]
+	d.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.candidates
+	}
+
+	var candidates []shellinput.CompletionCandidate
+	switch listing {
+	case dockerRunningContainers:
+		candidates = listDockerContainers(false)
+	case dockerAllContainers:
+		candidates = listDockerContainers(true)
+	case dockerImages:
+		candidates = listDockerImages()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache == nil {
+		d.cache = make(map[dockerListing]dockerCacheEntry)
+	}
+	d.cache[listing] = dockerCacheEntry{candidates: candidates, expires: time.Now().Add(dockerCacheTTL)}
+	return candidates
+}
+
+func listDockerContainers(all bool) []shellinput.CompletionCandidate {
+	args := []string{"ps", "--format", "{{.Names}}|{{.Image}} ({{.Status}})"}
+	if all {
+		args = append(args, "--all")
+	}
+	out, err := dockerOutput(args...)
+	if err != nil {
+		return nil
+	}
+	return parseGitListing(out)
+}
+
+func listDockerImages() []shellinput.CompletionCandidate {
+	out, err := dockerOutput("images", "--format", "{{.Repository}}:{{.Tag}}|{{.Size}}, created {{.CreatedSince}}")
+	if err != nil {
+		return nil
+	}
+
+	var candidates []shellinput.CompletionCandidate
+	for _, candidate := range parseGitListing(out) {
+		// Dangling images have no name to complete
+		if strings.Contains(candidate.Value, "<none>") {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
@@ -0,0 +1,65 @@
+package completion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockDockerOutput(t *testing.T) *[]string {
+	var calls []string
+	orig := dockerOutput
+	dockerOutput = func(args ...string) (string, error) {
+		call := strings.Join(args, " ")
+		calls = append(calls, call)
+		switch {
+		case strings.HasPrefix(call, "ps") && strings.HasSuffix(call, "--all"):
+			return "web|nginx:latest (Up 2 hours)\nworker|app:dev (Exited (1) 3 minutes ago)\n", nil
+		case strings.HasPrefix(call, "ps"):
+			return "web|nginx:latest (Up 2 hours)\n", nil
+		case strings.HasPrefix(call, "images"):
+			return "nginx:latest|187MB, created 2 weeks ago\napp:dev|1.2GB, created 3 minutes ago\n<none>:<none>|1.1GB, created 4 days ago\n", nil
+		}
+		return "", nil
+	}
+	t.Cleanup(func() { dockerOutput = orig })
+	return &calls
+}
+
+func TestDockerCompleter_Containers(t *testing.T) {
+	calls := mockDockerOutput(t)
+	completer := &DockerCompleter{}
+
+	got := completer.GetCompletions([]string{"exec", "-it"}, "docker exec -it ")
+	assert.Equal(t, []string{"web"}, candidateValues(got))
+	assert.Equal(t, "nginx:latest (Up 2 hours)", got[0].Description)
+
+	// Stopped containers are offered where they make sense
+	assert.Equal(t, []string{"web", "worker"}, candidateValues(completer.GetCompletions([]string{"rm"}, "docker rm ")))
+	assert.Equal(t, []string{"worker"}, candidateValues(completer.GetCompletions([]string{"container", "start", "wo"}, "docker container start wo")))
+
+	// Only the container of exec is completed, not the command run in it
+	assert.Nil(t, completer.GetCompletions([]string{"exec", "-u", "root", "web"}, "docker exec -u root web "))
+
+	// Listings are cached
+	completer.GetCompletions([]string{"stop"}, "docker stop ")
+	assert.Len(t, *calls, 2)
+}
+
+func TestDockerCompleter_Images(t *testing.T) {
+	mockDockerOutput(t)
+	completer := &DockerCompleter{}
+
+	got := completer.GetCompletions([]string{"rmi", "a"}, "docker rmi a")
+	assert.Equal(t, []string{"app:dev"}, candidateValues(got))
+	assert.Equal(t, "1.2GB, created 3 minutes ago", got[0].Description)
+
+	assert.Equal(t, []string{"nginx:latest", "app:dev"}, candidateValues(completer.GetCompletions([]string{"image", "rm"}, "docker image rm ")))
+	assert.Equal(t, []string{"nginx:latest", "app:dev"}, candidateValues(completer.GetCompletions([]string{"run", "--rm", "-p", "8080:80"}, "docker run --rm -p 8080:80 ")))
+
+	// Subcommands and flags are left to other completers
+	assert.Nil(t, completer.GetCompletions([]string{"ru"}, "docker ru"))
+	assert.Nil(t, completer.GetCompletions([]string{"run", "--r"}, "docker run --r"))
+	assert.Nil(t, completer.GetCompletions([]string{"build"}, "docker build "))
+}
@@ -3,10 +3,12 @@ package completion
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
 )
 
 // fileCompleter is the function type for file completion
@@ -56,6 +58,22 @@ func formatFileDisplay(name string, entry os.DirEntry) string {
 	return style.Render(name) + indicator
 }
 
+// fileCompletionIgnoreCase makes file names match the typed prefix regardless
+// of case, like readline's completion-ignore-case
+var fileCompletionIgnoreCase bool
+
+// SetFileCompletionIgnoreCase sets whether file completion ignores case
+func SetFileCompletionIgnoreCase(ignoreCase bool) {
+	fileCompletionIgnoreCase = ignoreCase
+}
+
+func hasFilePrefix(name string, prefix string) bool {
+	if fileCompletionIgnoreCase {
+		return strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix))
+	}
+	return strings.HasPrefix(name, prefix)
+}
+
 // getFileCompletions is the default implementation of file completion
 var getFileCompletions fileCompleter = func(prefix string, currentDirectory string) []shellinput.CompletionCandidate {
 	if prefix == "" {
@@ -164,7 +182,7 @@ var getFileCompletions fileCompleter = func(prefix string, currentDirectory stri
 	matches := make([]shellinput.CompletionCandidate, 0, len(entries))
 	for _, entry := range entries {
 		name := entry.Name()
-		if !strings.HasPrefix(name, filePrefix) {
+		if !hasFilePrefix(name, filePrefix) {
 			continue
 		}
 
@@ -211,3 +229,83 @@ var getFileCompletions fileCompleter = func(prefix string, currentDirectory stri
 
 	return matches
 }
+
+// fileSortMode is the order file completions are listed in
+type fileSortMode string
+
+const (
+	fileSortName  fileSortMode = "name"
+	fileSortMtime fileSortMode = "mtime"
+	fileSortSize  fileSortMode = "size"
+)
+
+// recentFileCommands are commands usually run on a file that was just created
+// or edited, so their completions list the newest files first by default
+var recentFileCommands = map[string]bool{
+	"bat": true, "cat": true, "code": true, "emacs": true, "head": true, "hx": true,
+	"less": true, "micro": true, "more": true, "nano": true, "nvim": true, "open": true,
+	"subl": true, "tail": true, "vi": true, "vim": true,
+}
+
+// fileSortModeFor returns the sort mode to use for command given the
+// GSH_FILE_COMPLETION_SORT setting. Any value other than name, mtime or size
+// means auto: editors and pagers get mtime, everything else name.
+func fileSortModeFor(setting string, command string) fileSortMode {
+	switch mode := fileSortMode(strings.ToLower(strings.TrimSpace(setting))); mode {
+	case fileSortName, fileSortMtime, fileSortSize:
+		return mode
+	}
+	if recentFileCommands[filepath.Base(command)] {
+		return fileSortMtime
+	}
+	return fileSortName
+}
+
+// sortFileCandidates orders file completions by mode, newest or largest first,
+// and describes each with its age or size. Name order is left as returned by
+// getFileCompletions, which is already alphabetical.
+func sortFileCandidates(candidates []shellinput.CompletionCandidate, mode fileSortMode, currentDirectory string) {
+	if mode != fileSortMtime && mode != fileSortSize {
+		return
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	infos := make(map[string]os.FileInfo, len(candidates))
+	for _, candidate := range candidates {
+		path := candidate.Value
+		switch {
+		case path == "~" || strings.HasPrefix(path, "~"+string(os.PathSeparator)):
+			path = filepath.Join(homeDir, path[1:])
+		case !filepath.IsAbs(path):
+			path = filepath.Join(currentDirectory, path)
+		}
+		if info, err := os.Stat(path); err == nil {
+			infos[candidate.Value] = info
+		}
+	}
+
+	for i, candidate := range candidates {
+		info, ok := infos[candidate.Value]
+		if !ok {
+			continue
+		}
+		if mode == fileSortMtime {
+			candidates[i].Description = humanize.Time(info.ModTime())
+		} else if !info.IsDir() {
+			candidates[i].Description = humanize.Bytes(uint64(info.Size()))
+		}
+	}
+
+	// Files that couldn't be read sort last
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, aok := infos[candidates[i].Value]
+		b, bok := infos[candidates[j].Value]
+		if !aok || !bok {
+			return aok && !bok
+		}
+		if mode == fileSortMtime {
+			return a.ModTime().After(b.ModTime())
+		}
+		return a.Size() > b.Size()
+	})
+}
@@ -5,9 +5,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFileCompletions(t *testing.T) {
@@ -193,3 +195,59 @@ func TestFileCompletions(t *testing.T) {
 		})
 	}
 }
+
+func TestFileCompletionsIgnoreCase(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "README.md"), nil, 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "Docs"), 0755))
+
+	assert.Empty(t, getFileCompletions("read", tmpDir))
+
+	SetFileCompletionIgnoreCase(true)
+	t.Cleanup(func() { SetFileCompletionIgnoreCase(false) })
+
+	assert.Equal(t, []string{"README.md"}, candidateValues(getFileCompletions("read", tmpDir)))
+	assert.Equal(t, []string{"Docs"}, candidateValues(getFileCompletions("docs", tmpDir)))
+}
+
+func TestFileSortModeFor(t *testing.T) {
+	assert.Equal(t, fileSortMtime, fileSortModeFor("auto", "vim"))
+	assert.Equal(t, fileSortMtime, fileSortModeFor("", "/usr/bin/less"))
+	assert.Equal(t, fileSortName, fileSortModeFor("auto", "rm"))
+	assert.Equal(t, fileSortSize, fileSortModeFor("SIZE", "vim"))
+	assert.Equal(t, fileSortName, fileSortModeFor("name", "vim"))
+}
+
+func TestSortFileCandidates(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := []struct {
+		name    string
+		size    int
+		modTime time.Time
+	}{
+		{"a.txt", 10, now.Add(-48 * time.Hour)},
+		{"b.txt", 3000, now.Add(-time.Hour)},
+		{"c.txt", 200, now.Add(-10 * time.Minute)},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		require.NoError(t, os.WriteFile(path, make([]byte, f.size), 0644))
+		require.NoError(t, os.Chtimes(path, f.modTime, f.modTime))
+	}
+
+	byMtime := getFileCompletions("", dir)
+	sortFileCandidates(byMtime, fileSortMtime, dir)
+	assert.Equal(t, []string{"c.txt", "b.txt", "a.txt"}, candidateValues(byMtime))
+	assert.Equal(t, "10 minutes ago", byMtime[0].Description)
+
+	bySize := getFileCompletions("", dir)
+	sortFileCandidates(bySize, fileSortSize, dir)
+	assert.Equal(t, []string{"b.txt", "c.txt", "a.txt"}, candidateValues(bySize))
+	assert.Equal(t, "3.0 kB", bySize[0].Description)
+
+	byName := getFileCompletions("", dir)
+	sortFileCandidates(byName, fileSortName, dir)
+	assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, candidateValues(byName))
+	assert.Empty(t, byName[0].Description)
+}
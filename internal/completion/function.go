@@ -24,50 +24,108 @@ func NewCompletionFunction(name string, runner *interp.Runner) *CompletionFuncti
 	}
 }
 
-// Execute runs the completion function with the given arguments
+// Execute runs the completion function with the given arguments, as if they
+// were the whole line and the cursor was at its end
 func (f *CompletionFunction) Execute(ctx context.Context, args []string) ([]string, error) {
+	line := strings.Join(args, " ")
+	return f.Complete(ctx, args, line, len(line))
+}
+
+// Complete runs the completion function the way bash's `complete -F` does. The
+// last of words is the word being completed, which is empty after a space.
+// COMP_WORDS, COMP_CWORD, COMP_LINE and COMP_POINT are set, the function is
+// called with the command name, the current word and the previous word, and
+// the values it put in COMPREPLY are returned. The function runs in a subshell,
+// so neither those variables nor anything else it sets reach the shell, which
+// may be running a command by the time a background completion finishes.
+func (f *CompletionFunction) Complete(ctx context.Context, words []string, line string, pos int) ([]string, error) {
+	if _, ok := f.Runner.Funcs[f.Name]; !ok {
+		return nil, fmt.Errorf("completion function %s is not defined", f.Name)
+	}
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		q, err := syntax.Quote(word, syntax.LangBash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to quote completion word: %w", err)
+		}
+		quoted[i] = q
+	}
+	quotedLine, err := syntax.Quote(line, syntax.LangBash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote completion line: %w", err)
+	}
+
+	command, current, previous := "''", "''", "''"
+	if len(quoted) > 0 {
+		command = quoted[0]
+		current = quoted[len(quoted)-1]
+	}
+	if len(quoted) > 1 {
+		previous = quoted[len(quoted)-2]
+	}
+
+	// The function's own output would garble the prompt, so it's discarded
 	script := fmt.Sprintf(`
-		# Set up completion environment
-		COMP_LINE=%q
+		COMP_LINE=%s
 		COMP_POINT=%d
 		COMP_WORDS=(%s)
 		COMP_CWORD=%d
-
-		# Initialize empty COMPREPLY
+		COMP_KEY=9
+		COMP_TYPE=9
 		COMPREPLY=()
-
-		# Call the completion function
-		%s
+		%s %s %s %s </dev/null >/dev/null 2>&1
 	`,
-		strings.Join(args, " "),
-		len(strings.Join(args, " ")),
-		strings.Join(args, " "),
-		len(args)-1,
-		f.Name,
+		quotedLine,
+		pos,
+		strings.Join(quoted, " "),
+		len(words)-1,
+		f.Name, command, current, previous,
 	)
 
-	// Parse and execute the script
-	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	// Run statement by statement, since running a whole file would exit the shell
+	var stmts []*syntax.Stmt
+	err = syntax.NewParser().Stmts(strings.NewReader(script), func(stmt *syntax.Stmt) bool {
+		stmts = append(stmts, stmt)
+		return true
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse completion script: %w", err)
 	}
-
-	if err := f.Runner.Run(ctx, file); err != nil {
-		return nil, fmt.Errorf("failed to execute completion function: %w", err)
+	subshell := f.Runner.Subshell()
+	for _, stmt := range stmts {
+		// Like bash, use COMPREPLY even if the function returns non-zero
+		if err := subshell.Run(ctx, stmt); err != nil {
+			if _, ok := interp.IsExitStatus(err); ok {
+				continue
+			}
+			return nil, fmt.Errorf("failed to execute completion function: %w", err)
+		}
 	}
 
-	// Get COMPREPLY from the runner's variables
-	compreply, ok := f.Runner.Vars["COMPREPLY"]
+	compreply, ok := subshell.Vars["COMPREPLY"]
 	if !ok {
 		return []string{}, nil
 	}
 
-	if compreply.Kind != expand.Indexed {
-		return []string{}, nil
+	switch compreply.Kind {
+	case expand.Indexed:
+		return append([]string{}, compreply.List...), nil
+	case expand.String:
+		// COMPREPLY=foo sets the first element in bash
+		if compreply.Str != "" {
+			return []string{compreply.Str}, nil
+		}
 	}
-
-	// Get all elements of the array
-	results := compreply.List
-	return results, nil
+	return []string{}, nil
 }
 
+// completionWords returns the words of line for COMP_WORDS, adding the empty
+// word being completed when the cursor follows a space
+func completionWords(args []string, line string) []string {
+	words := append([]string{}, args...)
+	if len(words) == 0 || strings.HasSuffix(line, " ") || strings.HasSuffix(line, "\t") {
+		words = append(words, "")
+	}
+	return words
+}
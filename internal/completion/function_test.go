@@ -81,4 +81,38 @@ _test_completion() {
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"foo", "bar", "baz"}, results)
 	})
-}
\ No newline at end of file
+}
+func TestCompletionFunctionComplete(t *testing.T) {
+	script := `
+trap 'echo exited' EXIT
+_mycmd() {
+    COMPREPLY=("$1|$2|$3" "$COMP_LINE|$COMP_POINT|$COMP_CWORD|${#COMP_WORDS[@]}|${COMP_WORDS[1]}")
+    echo "should not be printed"
+    return 1
+}
+`
+	var stdout strings.Builder
+	runner, err := interp.New(interp.StdIO(nil, &stdout, nil))
+	assert.NoError(t, err)
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	assert.NoError(t, err)
+	for _, stmt := range file.Stmts {
+		assert.NoError(t, runner.Run(context.Background(), stmt))
+	}
+
+	fn := NewCompletionFunction("_mycmd", runner)
+	line := `mycmd "a b" `
+	results, err := fn.Complete(context.Background(), completionWords([]string{"mycmd", `"a b"`}, line), line, len(line))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`mycmd||"a b"`, `mycmd "a b" |12|2|3|"a b"`}, results)
+
+	// The shell keeps running, and the function's output and variables don't leak
+	assert.False(t, runner.Exited())
+	assert.Empty(t, stdout.String())
+	for _, name := range []string{"COMP_LINE", "COMP_POINT", "COMP_WORDS", "COMP_CWORD", "COMP_KEY", "COMP_TYPE", "COMPREPLY"} {
+		assert.False(t, runner.Vars[name].IsSet(), name)
+	}
+
+	_, err = NewCompletionFunction("_missing", runner).Complete(context.Background(), []string{"mycmd", ""}, "mycmd ", 6)
+	assert.ErrorContains(t, err, "_missing is not defined")
+}
@@ -1,52 +1,72 @@
 package completion
 
 import (
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
 )
 
+// gitCacheTTL is how long listings of stashes, tags and worktrees are reused
+const gitCacheTTL = 5 * time.Second
+
+// gitOutput runs git with the given arguments, can be replaced in tests
+var gitOutput = func(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	return string(out), err
+}
+
 // GitCompleter handles built-in completion for git
-type GitCompleter struct{}
+type GitCompleter struct {
+	mu    sync.Mutex
+	cache map[string]gitCacheEntry
+
+	// User-defined aliases by name, read again when aliasStamp changes
+	aliasMap   map[string]string
+	aliasStamp string
+}
+
+type gitCacheEntry struct {
+	candidates []shellinput.CompletionCandidate
+	expires    time.Time
+}
+
+// gitSubcommands are the common git commands, completed as the first argument
+var gitSubcommands = []shellinput.CompletionCandidate{
+	{Value: "add", Description: "Add file contents to the index"},
+	{Value: "branch", Description: "List, create, or delete branches"},
+	{Value: "checkout", Description: "Switch branches or restore working tree files"},
+	{Value: "clone", Description: "Clone a repository into a new directory"},
+	{Value: "commit", Description: "Record changes to the repository"},
+	{Value: "diff", Description: "Show changes between commits, commit and working tree, etc"},
+	{Value: "fetch", Description: "Download objects and refs from another repository"},
+	{Value: "init", Description: "Create an empty Git repository or reinitialize an existing one"},
+	{Value: "log", Description: "Show commit logs"},
+	{Value: "merge", Description: "Join two or more development histories together"},
+	{Value: "pull", Description: "Fetch from and integrate with another repository or a local branch"},
+	{Value: "push", Description: "Update remote refs along with associated objects"},
+	{Value: "rebase", Description: "Reapply commits on top of another base tip"},
+	{Value: "remote", Description: "Manage set of tracked repositories"},
+	{Value: "reset", Description: "Reset current HEAD to the specified state"},
+	{Value: "restore", Description: "Restore working tree files"},
+	{Value: "show", Description: "Show various types of objects"},
+	{Value: "stash", Description: "Stash the changes in a dirty working directory away"},
+	{Value: "status", Description: "Show the working tree status"},
+	{Value: "switch", Description: "Switch branches"},
+	{Value: "tag", Description: "Create, list, delete or verify a tag object signed with GPG"},
+	{Value: "worktree", Description: "Manage multiple working trees"},
+}
 
 func (g *GitCompleter) GetCompletions(args []string, line string) []shellinput.CompletionCandidate {
 	if len(args) == 0 {
-		// Complete git subcommands
-		commands := []struct {
-			val  string
-			desc string
-		}{
-			{"add", "Add file contents to the index"},
-			{"branch", "List, create, or delete branches"},
-			{"checkout", "Switch branches or restore working tree files"},
-			{"clone", "Clone a repository into a new directory"},
-			{"commit", "Record changes to the repository"},
-			{"diff", "Show changes between commits, commit and working tree, etc"},
-			{"fetch", "Download objects and refs from another repository"},
-			{"init", "Create an empty Git repository or reinitialize an existing one"},
-			{"log", "Show commit logs"},
-			{"merge", "Join two or more development histories together"},
-			{"pull", "Fetch from and integrate with another repository or a local branch"},
-			{"push", "Update remote refs along with associated objects"},
-			{"rebase", "Reapply commits on top of another base tip"},
-			{"remote", "Manage set of tracked repositories"},
-			{"reset", "Reset current HEAD to the specified state"},
-			{"restore", "Restore working tree files"},
-			{"show", "Show various types of objects"},
-			{"status", "Show the working tree status"},
-			{"switch", "Switch branches"},
-			{"tag", "Create, list, delete or verify a tag object signed with GPG"},
-		}
-
-		var candidates []shellinput.CompletionCandidate
-		for _, cmd := range commands {
-			candidates = append(candidates, shellinput.CompletionCandidate{
-				Value:       cmd.val,
-				Description: cmd.desc,
-			})
-		}
-		return candidates
+		// Complete git subcommands and the user's aliases
+		return g.subcommands()
+	}
+	if len(args) == 1 && !strings.HasSuffix(line, " ") {
+		return filterCandidates(g.subcommands(), args[0])
 	}
 
 	subcommand := args[0]
@@ -62,55 +82,336 @@ func (g *GitCompleter) GetCompletions(args []string, line string) []shellinput.C
 		currentWord = ""
 	}
 
+	// An alias such as co = checkout completes like the command it stands for
+	if !containsCandidate(gitSubcommands, subcommand) {
+		if fields := strings.Fields(g.aliases()[subcommand]); len(fields) > 0 {
+			subcommand = fields[0]
+		}
+	}
+
 	switch subcommand {
 	case "checkout", "switch", "merge", "rebase":
-		return g.completeBranches(currentWord)
+		return g.completeBranches(subcommand, currentWord)
 	case "add", "rm", "restore":
 		return g.completeFiles(currentWord)
+	case "stash":
+		return g.completeStash(args[1:], currentWord)
+	case "tag":
+		return g.completeTag(args[1:], currentWord)
+	case "worktree":
+		return g.completeWorktree(args[1:], currentWord)
 	}
 
 	return nil
 }
 
-func (g *GitCompleter) completeBranches(prefix string) []shellinput.CompletionCandidate {
-	// Run git branch with format to get branch names and their latest commit messages
-	// Format: branch_name|commit_subject
-	cmd := exec.Command("git", "branch", "--format=%(refname:short)|%(contents:subject)")
-	out, err := cmd.Output()
+var gitStashSubcommands = []shellinput.CompletionCandidate{
+	{Value: "apply", Description: "Apply a stash on top of the working tree"},
+	{Value: "branch", Description: "Create a branch from a stash"},
+	{Value: "clear", Description: "Remove all stashes"},
+	{Value: "drop", Description: "Remove a single stash"},
+	{Value: "list", Description: "List the stashes"},
+	{Value: "pop", Description: "Apply a stash and remove it"},
+	{Value: "push", Description: "Save local modifications to a new stash"},
+	{Value: "show", Description: "Show the changes recorded in a stash"},
+}
+
+var gitWorktreeSubcommands = []shellinput.CompletionCandidate{
+	{Value: "add", Description: "Create a new working tree"},
+	{Value: "list", Description: "List the working trees"},
+	{Value: "lock", Description: "Prevent a working tree from being pruned"},
+	{Value: "move", Description: "Move a working tree"},
+	{Value: "prune", Description: "Prune stale working tree information"},
+	{Value: "remove", Description: "Remove a working tree"},
+	{Value: "repair", Description: "Repair working tree administrative files"},
+	{Value: "unlock", Description: "Unlock a working tree"},
+}
+
+// gitPositionalArgs returns the arguments of a subcommand that aren't flags,
+// excluding the word being completed
+func gitPositionalArgs(subArgs []string, currentWord string) []string {
+	if currentWord != "" && len(subArgs) > 0 {
+		subArgs = subArgs[:len(subArgs)-1]
+	}
+	var positional []string
+	for _, arg := range subArgs {
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+		}
+	}
+	return positional
+}
+
+// completeStash completes stash subcommands, then stash@{n} refs for the
+// subcommands that take one
+func (g *GitCompleter) completeStash(subArgs []string, currentWord string) []shellinput.CompletionCandidate {
+	positional := gitPositionalArgs(subArgs, currentWord)
+	if len(positional) == 0 {
+		return filterCandidates(gitStashSubcommands, currentWord)
+	}
+
+	switch positional[0] {
+	case "apply", "drop", "pop", "show":
+		if len(positional) == 1 {
+			return filterCandidates(g.cached("stash", g.listStashes), currentWord)
+		}
+	case "branch":
+		// git stash branch <branchname> [<stash>]
+		if len(positional) == 2 {
+			return filterCandidates(g.cached("stash", g.listStashes), currentWord)
+		}
+	}
+	return nil
+}
+
+// completeTag completes existing tags for the options that operate on them
+func (g *GitCompleter) completeTag(subArgs []string, currentWord string) []shellinput.CompletionCandidate {
+	if strings.HasPrefix(currentWord, "-") {
+		return nil
+	}
+	for _, arg := range subArgs {
+		switch arg {
+		case "-d", "--delete", "-v", "--verify":
+			return filterCandidates(g.cached("tag", g.listTags), currentWord)
+		}
+	}
+	return nil
+}
+
+// completeWorktree completes worktree subcommands, then the paths of existing
+// worktrees or, for add, the branch to check out
+func (g *GitCompleter) completeWorktree(subArgs []string, currentWord string) []shellinput.CompletionCandidate {
+	positional := gitPositionalArgs(subArgs, currentWord)
+	if len(positional) == 0 {
+		return filterCandidates(gitWorktreeSubcommands, currentWord)
+	}
+
+	switch positional[0] {
+	case "lock", "move", "remove", "repair", "unlock":
+		if len(positional) == 1 {
+			return filterCandidates(g.cached("worktree", g.listWorktrees), currentWord)
+		}
+	case "add":
+		// git worktree add <path> [<commit-ish>]; the path is left to file completion
+		if len(positional) == 2 {
+			return g.completeBranches("worktree", currentWord)
+		}
+	}
+	return nil
+}
+
+// cached returns the listing of kind for the current repository, running list
+// if there is none younger than gitCacheTTL
+func (g *GitCompleter) cached(kind string, list func() []shellinput.CompletionCandidate) []shellinput.CompletionCandidate {
+	dir, _ := os.Getwd()
+	key := dir + "\x00" + kind
+
+	g.mu.Lock()
+	entry, ok := g.cache[key]
+	g.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.candidates
+	}
+
+	candidates := list()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cache == nil {
+		g.cache = make(map[string]gitCacheEntry)
+	}
+	g.cache[key] = gitCacheEntry{candidates: candidates, expires: time.Now().Add(gitCacheTTL)}
+	return candidates
+}
+
+func (g *GitCompleter) listStashes() []shellinput.CompletionCandidate {
+	out, err := gitOutput("stash", "list", "--format=%gd|%gs")
+	if err != nil {
+		return nil
+	}
+	return parseGitListing(out)
+}
+
+func (g *GitCompleter) listTags() []shellinput.CompletionCandidate {
+	out, err := gitOutput("tag", "--list", "--format=%(refname:strip=2)|%(contents:subject)")
 	if err != nil {
 		return nil
 	}
+	return parseGitListing(out)
+}
 
+func (g *GitCompleter) listWorktrees() []shellinput.CompletionCandidate {
+	out, err := gitOutput("worktree", "list", "--porcelain")
+	if err != nil {
+		return nil
+	}
+
+	// Entries are blocks of "worktree <path>", "HEAD <sha>" and "branch <ref>" lines
 	var candidates []shellinput.CompletionCandidate
-	lines := strings.Split(string(out), "\n")
-	for _, line := range lines {
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			candidates = append(candidates, shellinput.CompletionCandidate{
+				Value: strings.TrimPrefix(line, "worktree "),
+			})
+		case strings.HasPrefix(line, "branch ") && len(candidates) > 0:
+			candidates[len(candidates)-1].Description = strings.TrimPrefix(line, "branch refs/heads/")
+		case line == "detached" && len(candidates) > 0:
+			candidates[len(candidates)-1].Description = "detached HEAD"
+		}
+	}
+	return candidates
+}
+
+// parseGitListing parses "value|description" lines
+func parseGitListing(out string) []shellinput.CompletionCandidate {
+	var candidates []shellinput.CompletionCandidate
+	for _, line := range strings.Split(out, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
-
-		// Split by the delimiter to get branch name and commit message
 		parts := strings.SplitN(line, "|", 2)
-		branchName := parts[0]
-		commitMsg := ""
+		description := ""
 		if len(parts) > 1 {
-			commitMsg = parts[1]
-			// Truncate long commit messages
-			if len(commitMsg) > 80 {
-				commitMsg = commitMsg[:77] + "..."
+			description = parts[1]
+			if len(description) > 80 {
+				description = description[:77] + "..."
 			}
 		}
+		candidates = append(candidates, shellinput.CompletionCandidate{
+			Value:       parts[0],
+			Description: description,
+		})
+	}
+	return candidates
+}
 
-		if strings.HasPrefix(branchName, prefix) {
-			candidates = append(candidates, shellinput.CompletionCandidate{
-				Value:       branchName,
-				Description: commitMsg,
-			})
+func filterCandidates(candidates []shellinput.CompletionCandidate, prefix string) []shellinput.CompletionCandidate {
+	var filtered []shellinput.CompletionCandidate
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate.Value, prefix) {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// completeBranches completes the branches to give to subcommand. Branches
+// recently checked out come first, then the other local branches and the
+// remote ones, each tagged in its description. checkout and switch also offer
+// the remote branches without a local one by their plain name, which creates a
+// tracking branch.
+func (g *GitCompleter) completeBranches(subcommand string, prefix string) []shellinput.CompletionCandidate {
+	locals := g.cached("branch", g.listLocalBranches)
+	remotes := g.cached("remote-branch", g.listRemoteBranches)
+
+	localByName := make(map[string]shellinput.CompletionCandidate, len(locals))
+	for _, branch := range locals {
+		localByName[branch.Value] = branch
+	}
+
+	var candidates []shellinput.CompletionCandidate
+	seen := make(map[string]bool)
+	add := func(value string, tag string, subject string) {
+		if seen[value] || !strings.HasPrefix(value, prefix) {
+			return
+		}
+		seen[value] = true
+		description := tag
+		if subject != "" {
+			description += ": " + subject
+		}
+		candidates = append(candidates, shellinput.CompletionCandidate{Value: value, Description: description})
+	}
+
+	for _, name := range g.recentBranches() {
+		if branch, ok := localByName[name]; ok {
+			add(name, "recent local", branch.Description)
+		}
+	}
+	for _, branch := range locals {
+		add(branch.Value, "local", branch.Description)
+	}
+	if subcommand == "checkout" || subcommand == "switch" {
+		for _, branch := range remotes {
+			_, name, _ := strings.Cut(branch.Value, "/")
+			if _, ok := localByName[name]; !ok {
+				add(name, "new branch tracking "+branch.Value, branch.Description)
+			}
 		}
 	}
+	for _, branch := range remotes {
+		add(branch.Value, "remote", branch.Description)
+	}
+	if subcommand == "merge" || subcommand == "rebase" {
+		add("@{upstream}", "upstream of the current branch", "")
+	}
 	return candidates
 }
 
+func (g *GitCompleter) listLocalBranches() []shellinput.CompletionCandidate {
+	out, err := gitOutput("for-each-ref", "refs/heads", "--format=%(refname:short)|%(contents:subject)")
+	if err != nil {
+		return nil
+	}
+	return parseGitListing(out)
+}
+
+func (g *GitCompleter) listRemoteBranches() []shellinput.CompletionCandidate {
+	out, err := gitOutput("for-each-ref", "refs/remotes", "--format=%(refname:short)|%(contents:subject)")
+	if err != nil {
+		return nil
+	}
+
+	var branches []shellinput.CompletionCandidate
+	for _, branch := range parseGitListing(out) {
+		// Skip the symbolic origin/HEAD, which shortens to the remote name
+		if !strings.Contains(branch.Value, "/") || strings.HasSuffix(branch.Value, "/HEAD") {
+			continue
+		}
+		branches = append(branches, branch)
+	}
+	return branches
+}
+
+// recentBranches returns the branches recently checked out, most recent first,
+// read from the "checkout: moving from a to b" entries of the HEAD reflog
+func (g *GitCompleter) recentBranches() []string {
+	var names []string
+	for _, entry := range g.cached("recent-branch", g.listRecentCheckouts) {
+		names = append(names, entry.Value)
+	}
+	return names
+}
+
+func (g *GitCompleter) listRecentCheckouts() []shellinput.CompletionCandidate {
+	out, err := gitOutput("reflog", "--format=%gs", "-n", "200")
+	if err != nil {
+		return nil
+	}
+
+	var recent []shellinput.CompletionCandidate
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		moves, ok := strings.CutPrefix(strings.TrimSpace(line), "checkout: moving from ")
+		if !ok {
+			continue
+		}
+		from, to, ok := strings.Cut(moves, " to ")
+		if !ok {
+			continue
+		}
+		for _, name := range []string{to, from} {
+			if !seen[name] {
+				seen[name] = true
+				recent = append(recent, shellinput.CompletionCandidate{Value: name})
+			}
+		}
+	}
+	return recent
+}
+
 func (g *GitCompleter) completeFiles(prefix string) []shellinput.CompletionCandidate {
 	// For 'add', 'rm', etc., we usually want modified files or all files.
 	// 'git status --porcelain' gives status of files.
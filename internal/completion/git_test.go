@@ -0,0 +1,160 @@
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockGitOutput(t *testing.T, outputs map[string]string) *int {
+	calls := 0
+	orig := gitOutput
+	gitOutput = func(args ...string) (string, error) {
+		calls++
+		return outputs[strings.Join(args[:2], " ")], nil
+	}
+	t.Cleanup(func() { gitOutput = orig })
+	return &calls
+}
+
+func TestGitCompleter_Stash(t *testing.T) {
+	calls := mockGitOutput(t, map[string]string{
+		"stash list": "stash@{0}|WIP on main: 1234abc fix tests\nstash@{1}|On feature: experiment\n",
+	})
+	completer := &GitCompleter{}
+
+	assert.Equal(t, []string{"pop", "push"}, candidateValues(completer.GetCompletions([]string{"stash", "p"}, "git stash p")))
+
+	got := completer.GetCompletions([]string{"stash", "pop"}, "git stash pop ")
+	assert.Equal(t, []string{"stash@{0}", "stash@{1}"}, candidateValues(got))
+	assert.Equal(t, "WIP on main: 1234abc fix tests", got[0].Description)
+
+	assert.Equal(t, []string{"stash@{1}"}, candidateValues(completer.GetCompletions([]string{"stash", "branch", "topic", "stash@{1"}, "git stash branch topic stash@{1")))
+	assert.Nil(t, completer.GetCompletions([]string{"stash", "branch"}, "git stash branch "))
+
+	// The listing is cached for the repository
+	completer.GetCompletions([]string{"stash", "drop"}, "git stash drop ")
+	assert.Equal(t, 1, *calls)
+}
+
+func TestGitCompleter_Tag(t *testing.T) {
+	mockGitOutput(t, map[string]string{
+		"tag --list": "v1.0.0|First release\nv1.1.0|\nnightly|\n",
+	})
+	completer := &GitCompleter{}
+
+	got := completer.GetCompletions([]string{"tag", "-d", "v1"}, "git tag -d v1")
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, candidateValues(got))
+	assert.Equal(t, "First release", got[0].Description)
+
+	// Creating a tag needs a new name, not an existing one
+	assert.Nil(t, completer.GetCompletions([]string{"tag"}, "git tag "))
+}
+
+func TestGitCompleter_Worktree(t *testing.T) {
+	mockGitOutput(t, map[string]string{
+		"worktree list": "worktree /src/repo\nHEAD 1234\nbranch refs/heads/main\n\nworktree /src/repo-hotfix\nHEAD 5678\ndetached\n",
+	})
+	completer := &GitCompleter{}
+
+	assert.Equal(t, []string{"remove", "repair"}, candidateValues(completer.GetCompletions([]string{"worktree", "re"}, "git worktree re")))
+
+	got := completer.GetCompletions([]string{"worktree", "remove", "--force"}, "git worktree remove --force ")
+	assert.Equal(t, []string{"/src/repo", "/src/repo-hotfix"}, candidateValues(got))
+	assert.Equal(t, "main", got[0].Description)
+	assert.Equal(t, "detached HEAD", got[1].Description)
+
+	// The path given to add is left to file completion
+	assert.Nil(t, completer.GetCompletions([]string{"worktree", "add"}, "git worktree add "))
+}
+
+func mockBranches(t *testing.T) {
+	mockGitOutput(t, map[string]string{
+		"for-each-ref refs/heads":   "docs|Update README\nfeature|Add search\nhotfix|Fix crash\nmain|Release 2.0\n",
+		"for-each-ref refs/remotes": "origin|\norigin/HEAD|Release 2.0\norigin/main|Release 2.0\norigin/review|Refactor parser\n",
+		"reflog --format=%gs":       "checkout: moving from main to hotfix\ncommit: Fix crash\ncheckout: moving from 1234abc to main\ncheckout: moving from feature to main\n",
+	})
+}
+
+func TestGitCompleter_Branches(t *testing.T) {
+	mockBranches(t)
+	completer := &GitCompleter{}
+
+	got := completer.GetCompletions([]string{"checkout"}, "git checkout ")
+	// Recent branches first, then the other local ones, then tracking and remote branches
+	assert.Equal(t, []string{"hotfix", "main", "feature", "docs", "review", "origin/main", "origin/review"}, candidateValues(got))
+	assert.Equal(t, "recent local: Fix crash", got[0].Description)
+	assert.Equal(t, "local: Update README", got[3].Description)
+	assert.Equal(t, "new branch tracking origin/review: Refactor parser", got[4].Description)
+	assert.Equal(t, "remote: Release 2.0", got[5].Description)
+
+	assert.Equal(t, []string{"origin/main", "origin/review"}, candidateValues(completer.GetCompletions([]string{"switch", "or"}, "git switch or")))
+}
+
+func TestGitCompleter_BranchesForMergeAndRebase(t *testing.T) {
+	mockBranches(t)
+	completer := &GitCompleter{}
+
+	// Only checkout and switch create tracking branches
+	got := completer.GetCompletions([]string{"rebase"}, "git rebase ")
+	assert.Equal(t, []string{"hotfix", "main", "feature", "docs", "origin/main", "origin/review", "@{upstream}"}, candidateValues(got))
+
+	got = completer.GetCompletions([]string{"merge", "@"}, "git merge @")
+	assert.Equal(t, []string{"@{upstream}"}, candidateValues(got))
+	assert.Equal(t, "upstream of the current branch", got[0].Description)
+}
+
+func mockGitConfigFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), ".gitconfig")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	orig := gitConfigFiles
+	gitConfigFiles = func() []string { return []string{path} }
+	t.Cleanup(func() { gitConfigFiles = orig })
+	return path
+}
+
+func TestGitCompleter_Aliases(t *testing.T) {
+	mockGitConfigFile(t)
+	calls := mockGitOutput(t, map[string]string{
+		"config --get-regexp":     "alias.co checkout\nalias.lg log --graph --oneline\nalias.status status -sb\n",
+		"for-each-ref refs/heads": "main|Initial commit\n",
+	})
+	completer := &GitCompleter{}
+
+	got := completer.GetCompletions(nil, "git ")
+	assert.Len(t, got, len(gitSubcommands)+2)
+	assert.Equal(t, []string{"checkout", "clone", "co", "commit"}, candidateValues(completer.GetCompletions([]string{"c"}, "git c")))
+
+	got = completer.GetCompletions([]string{"l"}, "git l")
+	assert.Equal(t, []string{"lg", "log"}, candidateValues(got))
+	assert.Equal(t, "alias for log --graph --oneline", got[0].Description)
+
+	// Aliases are read once while the config doesn't change
+	assert.Equal(t, 1, *calls)
+
+	// An alias completes the arguments of the command it stands for
+	assert.Equal(t, []string{"main"}, candidateValues(completer.GetCompletions([]string{"co"}, "git co ")))
+}
+
+func TestGitCompleter_AliasesReloadWhenConfigChanges(t *testing.T) {
+	path := mockGitConfigFile(t)
+	outputs := map[string]string{"config --get-regexp": "alias.co checkout\n"}
+	calls := mockGitOutput(t, outputs)
+	completer := &GitCompleter{}
+
+	assert.Equal(t, []string{"co", "commit"}, candidateValues(completer.GetCompletions([]string{"co"}, "git co")))
+
+	outputs["config --get-regexp"] = "alias.co checkout\nalias.cp cherry-pick\n"
+	assert.Equal(t, []string{"co", "commit"}, candidateValues(completer.GetCompletions([]string{"co"}, "git co")))
+	assert.Equal(t, 1, *calls)
+
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.Equal(t, []string{"cp"}, candidateValues(completer.GetCompletions([]string{"cp"}, "git cp")))
+	assert.Equal(t, 2, *calls)
+}
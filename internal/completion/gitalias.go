@@ -0,0 +1,110 @@
+package completion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+// gitConfigFiles returns the global git config files that may define aliases
+var gitConfigFiles = func() []string {
+	var files []string
+	if path := os.Getenv("GIT_CONFIG_GLOBAL"); path != "" {
+		files = append(files, path)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".gitconfig"))
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		files = append(files, filepath.Join(configHome, "git", "config"))
+	}
+	return files
+}
+
+// subcommands returns the built-in subcommands merged with the user's aliases,
+// in alphabetical order
+func (g *GitCompleter) subcommands() []shellinput.CompletionCandidate {
+	aliases := g.aliases()
+	if len(aliases) == 0 {
+		return gitSubcommands
+	}
+
+	candidates := append([]shellinput.CompletionCandidate{}, gitSubcommands...)
+	for name, expansion := range aliases {
+		// git ignores aliases that hide a built-in command
+		if containsCandidate(gitSubcommands, name) {
+			continue
+		}
+		description := "alias for " + expansion
+		if len(description) > 80 {
+			description = description[:77] + "..."
+		}
+		candidates = append(candidates, shellinput.CompletionCandidate{Value: name, Description: description})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Value < candidates[j].Value
+	})
+	return candidates
+}
+
+// aliases returns the git aliases defined for the current directory, asking
+// git again only when the directory or a global config file has changed
+func (g *GitCompleter) aliases() map[string]string {
+	stamp := gitAliasStamp()
+
+	g.mu.Lock()
+	if g.aliasMap != nil && g.aliasStamp == stamp {
+		defer g.mu.Unlock()
+		return g.aliasMap
+	}
+	g.mu.Unlock()
+
+	aliases := make(map[string]string)
+	// Fails with nothing to list when there are no aliases
+	out, _ := gitOutput("config", "--get-regexp", `^alias\.`)
+	for _, line := range strings.Split(out, "\n") {
+		key, expansion, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if name, ok := strings.CutPrefix(key, "alias."); ok && name != "" {
+			aliases[name] = strings.TrimSpace(expansion)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.aliasMap = aliases
+	g.aliasStamp = stamp
+	return aliases
+}
+
+// gitAliasStamp identifies the state the aliases were read in: the current
+// directory, whose repository may define its own aliases, and the
+// modification times of the global config files
+func gitAliasStamp() string {
+	dir, _ := os.Getwd()
+	stamp := dir
+	for _, file := range gitConfigFiles() {
+		if info, err := os.Stat(file); err == nil {
+			stamp += fmt.Sprintf("\x00%s@%d", file, info.ModTime().UnixNano())
+		}
+	}
+	return stamp
+}
+
+func containsCandidate(candidates []shellinput.CompletionCandidate, value string) bool {
+	for _, candidate := range candidates {
+		if candidate.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package completion
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+const (
+	// gitIgnoreCacheTTL is how long whether a path is ignored is remembered
+	gitIgnoreCacheTTL = 10 * time.Second
+
+	// gitIgnoreTimeout bounds each git check-ignore call
+	gitIgnoreTimeout = time.Second
+)
+
+// gitIgnoreCommands are the commands whose file completions leave out
+// gitignored paths when GSH_COMPLETION_RESPECT_GITIGNORE is on. Commands like
+// rm and ls keep seeing everything.
+var gitIgnoreCommands = map[string]bool{
+	"code": true, "emacs": true, "git": true, "hx": true, "micro": true, "nano": true,
+	"nvim": true, "subl": true, "vi": true, "vim": true,
+}
+
+// gitCheckIgnore runs git check-ignore in dir on paths, returning the ones
+// that are ignored. It fails outside a repository. Can be replaced in tests.
+var gitCheckIgnore = func(dir string, paths []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitIgnoreTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "check-ignore", "--stdin", "-z")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// None of the paths are ignored
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00"), nil
+}
+
+// gitIgnoreFilter drops gitignored paths from file completions, remembering
+// which paths are ignored for gitIgnoreCacheTTL
+type gitIgnoreFilter struct {
+	mu    sync.Mutex
+	cache map[string]gitIgnoreCacheEntry
+}
+
+type gitIgnoreCacheEntry struct {
+	ignored bool
+	expires time.Time
+}
+
+// filter returns candidates without the paths git ignores. Candidates outside
+// a repository, or that git can't be asked about, are kept.
+func (f *gitIgnoreFilter) filter(candidates []shellinput.CompletionCandidate, currentDirectory string) []shellinput.CompletionCandidate {
+	if len(candidates) == 0 {
+		return candidates
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	paths := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		path := candidate.Value
+		switch {
+		case path == "~" || strings.HasPrefix(path, "~"+string(os.PathSeparator)):
+			path = filepath.Join(homeDir, path[1:])
+		case !filepath.IsAbs(path):
+			path = filepath.Join(currentDirectory, path)
+		}
+		paths[i] = path
+	}
+
+	now := time.Now()
+	ignored := make(map[string]bool, len(paths))
+	var unknown []string
+	f.mu.Lock()
+	for _, path := range paths {
+		if entry, ok := f.cache[path]; ok && now.Before(entry.expires) {
+			ignored[path] = entry.ignored
+		} else {
+			unknown = append(unknown, path)
+		}
+	}
+	f.mu.Unlock()
+
+	if len(unknown) > 0 {
+		// The candidates of a completion are all in one directory
+		matches, err := gitCheckIgnore(filepath.Dir(unknown[0]), unknown)
+		if err != nil {
+			return candidates
+		}
+		matched := make(map[string]bool, len(matches))
+		for _, match := range matches {
+			matched[match] = true
+		}
+
+		f.mu.Lock()
+		if f.cache == nil {
+			f.cache = make(map[string]gitIgnoreCacheEntry)
+		}
+		for _, path := range unknown {
+			ignored[path] = matched[path]
+			f.cache[path] = gitIgnoreCacheEntry{ignored: matched[path], expires: now.Add(gitIgnoreCacheTTL)}
+		}
+		f.mu.Unlock()
+	}
+
+	kept := candidates[:0]
+	for i, candidate := range candidates {
+		if !ignored[paths[i]] {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,81 @@
+package completion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func mockGitCheckIgnore(t *testing.T, ignored ...string) *int {
+	calls := 0
+	orig := gitCheckIgnore
+	gitCheckIgnore = func(dir string, paths []string) ([]string, error) {
+		calls++
+		var matches []string
+		for _, path := range paths {
+			if containsString(ignored, filepath.Base(path)) {
+				matches = append(matches, path)
+			}
+		}
+		return matches, nil
+	}
+	t.Cleanup(func() { gitCheckIgnore = orig })
+	return &calls
+}
+
+func TestGitIgnoreFilter(t *testing.T) {
+	calls := mockGitCheckIgnore(t, "node_modules", "app.o")
+	filter := &gitIgnoreFilter{}
+
+	candidates := toCandidates([]string{"app.o", "main.c", "node_modules", "src"})
+	assert.Equal(t, []string{"main.c", "src"}, candidateValues(filter.filter(candidates, "/repo")))
+	assert.Equal(t, 1, *calls)
+
+	// Whether paths are ignored is cached
+	candidates = toCandidates([]string{"app.o", "main.c"})
+	assert.Equal(t, []string{"main.c"}, candidateValues(filter.filter(candidates, "/repo")))
+	assert.Equal(t, 1, *calls)
+}
+
+func TestGitIgnoreFilter_OutsideRepository(t *testing.T) {
+	orig := gitCheckIgnore
+	gitCheckIgnore = func(dir string, paths []string) ([]string, error) {
+		return nil, errors.New("not a git repository")
+	}
+	t.Cleanup(func() { gitCheckIgnore = orig })
+
+	candidates := toCandidates([]string{"app.o", "main.c"})
+	assert.Equal(t, []string{"app.o", "main.c"}, candidateValues((&gitIgnoreFilter{}).filter(candidates, "/tmp")))
+}
+
+func TestCompleteFilePaths_RespectGitignore(t *testing.T) {
+	mockGitCheckIgnore(t, "node_modules")
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "node_modules"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644))
+
+	runner, _ := interp.New()
+	runner.Vars = map[string]expand.Variable{
+		"PWD":                              {Kind: expand.String, Str: dir},
+		"GSH_FILE_COMPLETION_SORT":         {Kind: expand.String, Str: "name"},
+		"GSH_COMPLETION_RESPECT_GITIGNORE": {Kind: expand.String, Str: "1"},
+		"GSH_COMPLETION_SOURCES":           {Kind: expand.String, Str: `["file"]`},
+	}
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, runner)
+
+	complete := func(line string) []string {
+		return candidateValues(provider.GetCompletions(line, len(line)))
+	}
+
+	assert.Equal(t, []string{"notes.txt"}, complete("vim n"))
+	assert.Equal(t, []string{"node_modules", "notes.txt"}, complete("rm n"))
+
+	runner.Vars["GSH_COMPLETION_RESPECT_GITIGNORE"] = expand.Variable{Kind: expand.String, Str: "0"}
+	assert.Equal(t, []string{"node_modules", "notes.txt"}, complete("vim n"))
+}
@@ -23,25 +23,40 @@ const (
 	FunctionCompletion CompletionType = "F"
 	// CommandCompletion represents command based completion (-C option)
 	CommandCompletion CompletionType = "C"
+	// SpecFileCompletion represents completion from a declarative YAML spec
+	SpecFileCompletion CompletionType = "S"
 )
 
 // CompletionSpec represents a completion specification for a command
 type CompletionSpec struct {
 	Command string
 	Type    CompletionType
-	Value   string   // function name, wordlist, or command
-	Options []string // additional options like -o dirname
+	Value   string     // function name, wordlist, or command
+	Options []string   // additional options like -o dirname
+	Flags   []FlagSpec // flags whose values complete from their Value, set with -V
+}
+
+// HasOption reports whether the spec was registered with `-o option`
+func (s CompletionSpec) HasOption(option string) bool {
+	for _, o := range s.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
 }
 
 // CompletionManager manages command completion specifications
 type CompletionManager struct {
-	specs map[string]CompletionSpec
+	specs        map[string]CompletionSpec
+	commandSpecs map[string]*CommandSpec
 }
 
 // NewCompletionManager creates a new CompletionManager
 func NewCompletionManager() *CompletionManager {
 	return &CompletionManager{
-		specs: make(map[string]CompletionSpec),
+		specs:        make(map[string]CompletionSpec),
+		commandSpecs: make(map[string]*CommandSpec),
 	}
 }
 
@@ -73,6 +88,10 @@ func (m *CompletionManager) ListSpecs() []CompletionSpec {
 // ExecuteCompletion executes a completion specification for a given command line
 // and returns the list of possible completions
 func (m *CompletionManager) ExecuteCompletion(ctx context.Context, runner *interp.Runner, spec CompletionSpec, args []string, line string, pos int) ([]shellinput.CompletionCandidate, error) {
+	if candidates, ok := completeFlagValue(ctx, runner, spec.Flags, args, line); ok {
+		return candidates, nil
+	}
+
 	switch spec.Type {
 	case WordListCompletion:
 		words := strings.Fields(spec.Value)
@@ -90,10 +109,14 @@ func (m *CompletionManager) ExecuteCompletion(ctx context.Context, runner *inter
 
 	case FunctionCompletion:
 		fn := NewCompletionFunction(spec.Value, runner)
-		strs, err := fn.Execute(ctx, args)
+		strs, err := fn.Complete(ctx, completionWords(args, line), line, pos)
 		if err != nil {
 			return nil, err
 		}
+		// -o default and -o bashdefault fall back to the usual completion
+		if len(strs) == 0 && (spec.HasOption("default") || spec.HasOption("bashdefault")) {
+			return nil, nil
+		}
 		completions := make([]shellinput.CompletionCandidate, len(strs))
 		for i, s := range strs {
 			completions[i] = shellinput.CompletionCandidate{Value: s}
@@ -103,6 +126,9 @@ func (m *CompletionManager) ExecuteCompletion(ctx context.Context, runner *inter
 	case CommandCompletion:
 		return m.RunExternalCompleter(ctx, spec.Value, args, line, pos)
 
+	case SpecFileCompletion:
+		return completeFromCommandSpec(ctx, runner, m.commandSpecs[spec.Command], args, line), nil
+
 	default:
 		return nil, fmt.Errorf("unsupported completion type: %s", spec.Type)
 	}
@@ -0,0 +1,233 @@
+package completion
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+// manPageOutput renders the man page of a command as plain text, can be replaced in tests
+var manPageOutput = func(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "man", command)
+	// man-db keeps its escapes when MAN_KEEP_FORMATTING is set to anything, even 0
+	env := slices.DeleteFunc(os.Environ(), func(kv string) bool { return strings.HasPrefix(kv, "MAN_KEEP_FORMATTING=") })
+	cmd.Env = append(env, "MANPAGER=cat", "PAGER=cat", "MANWIDTH=160")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+var (
+	manCommandNamePattern = regexp.MustCompile(`^[A-Za-z0-9._+-]+$`)
+	manOverstrikePattern  = regexp.MustCompile(".\x08")
+	manSGRPattern         = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	manColumnGapPattern   = regexp.MustCompile(`\s{2,}`)
+	manFlagPattern        = regexp.MustCompile(`^(--?[A-Za-z0-9?][A-Za-z0-9_-]*)`)
+	manSectionPattern     = regexp.MustCompile(`^[A-Z][A-Z ]+$`)
+)
+
+// ManPageOption is a flag documented in a man page
+type ManPageOption struct {
+	Flag        string `json:"flag"`
+	Description string `json:"description"`
+}
+
+// ManPageCompleter completes flags for commands without a dedicated completer by
+// parsing their man page, similar to fish's generated completions. Since running
+// man is slow, parsed options are cached in memory and, if a cache directory is
+// set, on disk keyed by command.
+type ManPageCompleter struct {
+	cacheDir string
+
+	mu    sync.Mutex
+	cache map[string][]ManPageOption
+}
+
+// NewManPageCompleter creates a ManPageCompleter that persists parsed options in
+// cacheDir. An empty cacheDir keeps them in memory only.
+func NewManPageCompleter(cacheDir string) *ManPageCompleter {
+	return &ManPageCompleter{
+		cacheDir: cacheDir,
+		cache:    make(map[string][]ManPageOption),
+	}
+}
+
+// SetCacheDir changes where parsed options are persisted
+func (c *ManPageCompleter) SetCacheDir(cacheDir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cacheDir = cacheDir
+}
+
+// GetCompletions returns the documented flags of command that start with the
+// word being completed. Only words starting with "-" are completed.
+func (c *ManPageCompleter) GetCompletions(command string, args []string, line string) []shellinput.CompletionCandidate {
+	if len(args) == 0 || strings.HasSuffix(line, " ") {
+		return nil
+	}
+	currentWord := args[len(args)-1]
+	if !strings.HasPrefix(currentWord, "-") || !manCommandNamePattern.MatchString(command) {
+		return nil
+	}
+
+	var candidates []shellinput.CompletionCandidate
+	for _, option := range c.options(command) {
+		if strings.HasPrefix(option.Flag, currentWord) {
+			candidates = append(candidates, shellinput.CompletionCandidate{
+				Value:       option.Flag,
+				Description: option.Description,
+			})
+		}
+	}
+	return candidates
+}
+
+// options returns the parsed options of command, from the memory cache, the disk
+// cache or by running man, in that order
+func (c *ManPageCompleter) options(command string) []ManPageOption {
+	c.mu.Lock()
+	options, ok := c.cache[command]
+	cacheDir := c.cacheDir
+	c.mu.Unlock()
+	if ok {
+		return options
+	}
+
+	var cacheFile string
+	if cacheDir != "" {
+		cacheFile = filepath.Join(cacheDir, command+".json")
+		if data, err := os.ReadFile(cacheFile); err == nil {
+			if err := json.Unmarshal(data, &options); err == nil {
+				c.store(command, options)
+				return options
+			}
+		}
+	}
+
+	output, err := manPageOutput(command)
+	if err == nil {
+		options = ParseManPageOptions(output)
+	}
+	c.store(command, options)
+
+	// Only persist successful lookups, a missing man page may be installed later
+	if cacheFile != "" && err == nil {
+		if data, err := json.Marshal(options); err == nil {
+			if err := os.MkdirAll(cacheDir, 0755); err == nil {
+				_ = os.WriteFile(cacheFile, data, 0644)
+			}
+		}
+	}
+	return options
+}
+
+func (c *ManPageCompleter) store(command string, options []ManPageOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache[command] = options
+}
+
+// ParseManPageOptions extracts "-x, --xxx  description" entries from a rendered
+// man page. Options are read from the OPTIONS section, or from DESCRIPTION for
+// pages like ls(1) that document their flags there.
+func ParseManPageOptions(page string) []ManPageOption {
+	page = manOverstrikePattern.ReplaceAllString(page, "")
+	page = manSGRPattern.ReplaceAllString(page, "")
+	lines := strings.Split(page, "\n")
+
+	sections := map[string][]string{}
+	current := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if line == trimmed && manSectionPattern.MatchString(trimmed) {
+			current = trimmed
+			continue
+		}
+		sections[current] = append(sections[current], line)
+	}
+
+	body, ok := sections["OPTIONS"]
+	if !ok {
+		body = sections["DESCRIPTION"]
+	}
+
+	seen := make(map[string]bool)
+	var options []ManPageOption
+	for i, line := range body {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+
+		flagsPart, description := trimmed, ""
+		if loc := manColumnGapPattern.FindStringIndex(trimmed); loc != nil {
+			flagsPart, description = trimmed[:loc[0]], strings.TrimSpace(trimmed[loc[1]:])
+		}
+		if description == "" {
+			description = nextDescriptionLine(body, i, indentOf(line))
+		}
+		description = summarizeManDescription(description)
+
+		for _, part := range strings.Split(flagsPart, ",") {
+			match := manFlagPattern.FindStringSubmatch(strings.TrimSpace(part))
+			if match == nil || match[1] == "-" || match[1] == "--" || seen[match[1]] {
+				continue
+			}
+			seen[match[1]] = true
+			options = append(options, ManPageOption{Flag: match[1], Description: description})
+		}
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Flag < options[j].Flag
+	})
+	return options
+}
+
+// nextDescriptionLine returns the description on the line after an option, if
+// it is indented deeper than the option itself
+func nextDescriptionLine(lines []string, index int, indent int) string {
+	for _, line := range lines[index+1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if indentOf(line) > indent && !strings.HasPrefix(trimmed, "-") {
+			return trimmed
+		}
+		return ""
+	}
+	return ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// summarizeManDescription keeps the first sentence of a description, short
+// enough to show next to a completion
+func summarizeManDescription(description string) string {
+	if idx := strings.Index(description, ". "); idx >= 0 {
+		description = description[:idx]
+	}
+	description = strings.TrimSuffix(description, ".")
+	if runes := []rune(description); len(runes) > 80 {
+		description = string(runes[:77]) + "..."
+	}
+	return description
+}
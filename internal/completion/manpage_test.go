@@ -0,0 +1,129 @@
+package completion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// manBold renders text the way man marks bold output, by overstriking each character
+func manBold(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		b.WriteRune(r)
+		b.WriteRune('\b')
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var sampleManPage = "FROB(1)                   General Commands Manual                  FROB(1)\n" +
+	"\n" +
+	"NAME\n" +
+	"       frob - frobnicate files\n" +
+	"\n" +
+	"SYNOPSIS\n" +
+	"       frob [-v] [--output=FILE] file...\n" +
+	"\n" +
+	"OPTIONS\n" +
+	"       -v, --verbose\n" +
+	"              Print each file as it is frobnicated. Repeat for more detail.\n" +
+	"\n" +
+	"       -o, --output=FILE   Write the result to FILE instead of stdout.\n" +
+	"\n" +
+	"       " + manBold("-n, --dry-run") + "\n" +
+	"              Show what would be done.\n" +
+	"\n" +
+	"       --  End of options.\n" +
+	"\n" +
+	"SEE ALSO\n" +
+	"       -x is not an option here\n"
+
+func TestParseManPageOptions(t *testing.T) {
+	options := ParseManPageOptions(sampleManPage)
+
+	assert.Equal(t, []ManPageOption{
+		{Flag: "--dry-run", Description: "Show what would be done"},
+		{Flag: "--output", Description: "Write the result to FILE instead of stdout"},
+		{Flag: "--verbose", Description: "Print each file as it is frobnicated"},
+		{Flag: "-n", Description: "Show what would be done"},
+		{Flag: "-o", Description: "Write the result to FILE instead of stdout"},
+		{Flag: "-v", Description: "Print each file as it is frobnicated"},
+	}, options)
+}
+
+func TestParseManPageOptionsWithColors(t *testing.T) {
+	// Formatting kept by man-db comes as SGR escapes instead of overstrikes
+	page := "OPTIONS\n" +
+		"       \x1b[1m-v\x1b[0m, \x1b[1m--verbose\x1b[0m\n" +
+		"              Print each file as it is \x1b[4mfrobnicated\x1b[24m.\n" +
+		"\n" +
+		"       \x1b[1m-o\x1b[22m, \x1b[1;31m--output\x1b[0m=\x1b[4mFILE\x1b[24m   Write the result to FILE.\n"
+
+	assert.Equal(t, []ManPageOption{
+		{Flag: "--output", Description: "Write the result to FILE"},
+		{Flag: "--verbose", Description: "Print each file as it is frobnicated"},
+		{Flag: "-o", Description: "Write the result to FILE"},
+		{Flag: "-v", Description: "Print each file as it is frobnicated"},
+	}, ParseManPageOptions(page))
+}
+
+func TestParseManPageOptionsFallsBackToDescription(t *testing.T) {
+	page := "DESCRIPTION\n" +
+		"       List files.\n" +
+		"\n" +
+		"       -a, --all\n" +
+		"              do not ignore entries starting with .\n"
+
+	options := ParseManPageOptions(page)
+	assert.Equal(t, []string{"--all", "-a"}, []string{options[0].Flag, options[1].Flag})
+}
+
+func TestManPageCompleter(t *testing.T) {
+	calls := 0
+	origManPageOutput := manPageOutput
+	manPageOutput = func(command string) (string, error) {
+		calls++
+		if command != "frob" {
+			return "", errors.New("no manual entry")
+		}
+		return sampleManPage, nil
+	}
+	defer func() { manPageOutput = origManPageOutput }()
+
+	cacheDir := t.TempDir()
+	completer := NewManPageCompleter(cacheDir)
+
+	completions := completer.GetCompletions("frob", []string{"--o"}, "frob --o")
+	require.Len(t, completions, 1)
+	assert.Equal(t, "--output", completions[0].Value)
+	assert.Equal(t, "Write the result to FILE instead of stdout", completions[0].Description)
+
+	// Only flags are completed
+	assert.Empty(t, completer.GetCompletions("frob", []string{"fi"}, "frob fi"))
+	assert.Empty(t, completer.GetCompletions("frob", []string{"-v"}, "frob -v "))
+
+	// Results are cached in memory and on disk
+	completer.GetCompletions("frob", []string{"-"}, "frob -")
+	assert.Equal(t, 1, calls)
+	_, err := os.Stat(filepath.Join(cacheDir, "frob.json"))
+	assert.NoError(t, err)
+
+	fresh := NewManPageCompleter(cacheDir)
+	assert.Len(t, fresh.GetCompletions("frob", []string{"-"}, "frob -"), 6)
+	assert.Equal(t, 1, calls)
+
+	// Missing man pages aren't persisted
+	assert.Empty(t, completer.GetCompletions("nope", []string{"-"}, "nope -"))
+	_, err = os.Stat(filepath.Join(cacheDir, "nope.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Unsafe command names never reach man
+	assert.Empty(t, completer.GetCompletions("../frob", []string{"-"}, "../frob -"))
+	assert.Equal(t, 2, calls)
+}
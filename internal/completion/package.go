@@ -0,0 +1,227 @@
+package completion
+
+import (
+	"context"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+const (
+	// packageCacheTTL is how long package listings are reused. Listing every
+	// available package can take seconds, and the list rarely changes.
+	packageCacheTTL = 10 * time.Minute
+
+	// packageTimeout bounds each package manager call
+	packageTimeout = 5 * time.Second
+)
+
+// packageOutput runs a package manager command, can be replaced in tests
+var packageOutput = func(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), packageTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	return string(out), err
+}
+
+// packageManager describes the subcommands of a package manager that take
+// package names, and how to list those packages
+type packageManager struct {
+	name string
+	// available are the subcommands taking any package the manager knows of
+	available map[string]bool
+	// installed are the subcommands taking installed packages
+	installed map[string]bool
+
+	listAvailable func(command string) []string
+	listInstalled func(command string) []string
+}
+
+var aptPackageManager = packageManager{
+	name: "apt",
+	available: map[string]bool{
+		"build-dep": true, "changelog": true, "depends": true, "download": true, "install": true,
+		"policy": true, "rdepends": true, "show": true, "showsrc": true, "source": true,
+	},
+	installed: map[string]bool{"purge": true, "reinstall": true, "remove": true, "upgrade": true},
+	listAvailable: func(string) []string {
+		return packageLines(packageOutput("apt-cache", "pkgnames"))
+	},
+	listInstalled: func(string) []string {
+		return packageLines(packageOutput("dpkg-query", "-W", "-f=${Package}\n"))
+	},
+}
+
+var dnfPackageManager = packageManager{
+	name:      "dnf",
+	available: map[string]bool{"download": true, "info": true, "install": true},
+	installed: map[string]bool{
+		"downgrade": true, "erase": true, "reinstall": true, "remove": true, "update": true, "upgrade": true,
+	},
+	listAvailable: func(command string) []string {
+		// -C reads the metadata already downloaded instead of refreshing it
+		return parseDnfList(packageOutput(command, "-C", "-q", "list", "available"))
+	},
+	listInstalled: func(string) []string {
+		return packageLines(packageOutput("rpm", "-qa", "--qf", "%{NAME}\n"))
+	},
+}
+
+// packageManagers maps package manager commands to how their packages are completed
+var packageManagers = map[string]packageManager{
+	"apt":     aptPackageManager,
+	"apt-get": aptPackageManager,
+	"brew": {
+		name: "brew",
+		available: map[string]bool{
+			"cat": true, "deps": true, "desc": true, "fetch": true, "home": true, "info": true,
+			"install": true, "options": true, "uses": true,
+		},
+		installed: map[string]bool{
+			"link": true, "pin": true, "reinstall": true, "remove": true, "rm": true, "uninstall": true,
+			"unlink": true, "unpin": true, "upgrade": true,
+		},
+		listAvailable: func(string) []string {
+			formulae := packageLines(packageOutput("brew", "formulae"))
+			return append(formulae, packageLines(packageOutput("brew", "casks"))...)
+		},
+		listInstalled: func(string) []string {
+			return packageLines(packageOutput("brew", "list", "-1"))
+		},
+	},
+	"dnf": dnfPackageManager,
+	"yum": dnfPackageManager,
+}
+
+// packageListing identifies a cached list of packages
+type packageListing struct {
+	manager   string
+	installed bool
+}
+
+// PackageCompleter completes package names for apt, brew and dnf by asking
+// the package manager's local cache
+type PackageCompleter struct {
+	mu    sync.Mutex
+	cache map[packageListing]packageCacheEntry
+}
+
+type packageCacheEntry struct {
+	candidates []shellinput.CompletionCandidate
+	expires    time.Time
+}
+
+// GetCompletions returns package names for the arguments of a package manager
+// subcommand, such as `apt install`. Subcommands themselves are left to the
+// other completers.
+func (p *PackageCompleter) GetCompletions(command string, args []string, line string) []shellinput.CompletionCandidate {
+	manager, ok := packageManagers[command]
+	if !ok || len(args) == 0 {
+		return nil
+	}
+
+	currentWord := args[len(args)-1]
+	preceding := args[:len(args)-1]
+	if strings.HasSuffix(line, " ") {
+		currentWord = ""
+		preceding = args
+	}
+	if strings.HasPrefix(currentWord, "-") {
+		return nil
+	}
+
+	// The subcommand is the first argument that isn't a flag, as in `apt-get -y install`
+	subcommand := ""
+	for _, arg := range preceding {
+		if !strings.HasPrefix(arg, "-") {
+			subcommand = arg
+			break
+		}
+	}
+
+	switch {
+	case manager.installed[subcommand]:
+		return filterCandidates(p.cached(command, manager, true), currentWord)
+	case manager.available[subcommand]:
+		return filterCandidates(p.cached(command, manager, false), currentWord)
+	}
+	return nil
+}
+
+// cached returns the listing, asking the package manager if there is none
+// younger than packageCacheTTL
+func (p *PackageCompleter) cached(command string, manager packageManager, installed bool) []shellinput.CompletionCandidate {
+	listing := packageListing{manager: manager.name, installed: installed}
+
+	p.mu.Lock()
+	entry, ok := p.cache[listing]
+	p.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.candidates
+	}
+
+	var names []string
+	if installed {
+		names = manager.listInstalled(command)
+	} else {
+		names = manager.listAvailable(command)
+	}
+	sort.Strings(names)
+
+	candidates := make([]shellinput.CompletionCandidate, 0, len(names))
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		candidates = append(candidates, shellinput.CompletionCandidate{Value: name})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cache == nil {
+		p.cache = make(map[packageListing]packageCacheEntry)
+	}
+	p.cache[listing] = packageCacheEntry{candidates: candidates, expires: time.Now().Add(packageCacheTTL)}
+	return candidates
+}
+
+// packageLines returns the package names a command printed one per line
+func packageLines(out string, err error) []string {
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseDnfList returns the package names of `dnf list`, whose lines look like
+// `curl.x86_64  8.2.1-3.fc39  updates`. Headers have no architecture, and long
+// names push the rest of their line to an indented line of its own.
+func parseDnfList(out string, err error) []string {
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(line, " ") {
+			continue
+		}
+		dot := strings.LastIndex(fields[0], ".")
+		if dot <= 0 {
+			continue
+		}
+		names = append(names, fields[0][:dot])
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package completion
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func mockPackageOutput(t *testing.T) *[]string {
+	var calls []string
+	orig := packageOutput
+	packageOutput = func(name string, args ...string) (string, error) {
+		call := strings.Join(append([]string{name}, args...), " ")
+		calls = append(calls, call)
+		switch call {
+		case "apt-cache pkgnames":
+			return "curl\nvim\ncurlftpfs\nvim-gtk3\n", nil
+		case "dpkg-query -W -f=${Package}\n":
+			return "curl\nvim\n", nil
+		case "brew formulae":
+			return "jq\nripgrep\n", nil
+		case "brew casks":
+			return "iterm2\n", nil
+		case "dnf -C -q list available":
+			return "Available Packages\n" +
+				"curl.x86_64                 8.2.1-3.fc39       updates\n" +
+				"curl.i686                   8.2.1-3.fc39       updates\n" +
+				"python3-some-very-long-package-name.noarch\n" +
+				"                            1.0-1.fc39         fedora\n", nil
+		}
+		return "", errors.New("command not found")
+	}
+	t.Cleanup(func() { packageOutput = orig })
+	return &calls
+}
+
+func TestPackageCompleter_Apt(t *testing.T) {
+	calls := mockPackageOutput(t)
+	completer := &PackageCompleter{}
+
+	assert.Equal(t, []string{"curl", "curlftpfs"}, candidateValues(completer.GetCompletions("apt", []string{"install", "cu"}, "apt install cu")))
+	assert.Equal(t, []string{"vim", "vim-gtk3"}, candidateValues(completer.GetCompletions("apt-get", []string{"-y", "install", "curl", "v"}, "apt-get -y install curl v")))
+	assert.Equal(t, []string{"curl", "vim"}, candidateValues(completer.GetCompletions("apt", []string{"remove"}, "apt remove ")))
+
+	// apt and apt-get share their listings, which are cached
+	assert.Equal(t, []string{"apt-cache pkgnames", "dpkg-query -W -f=${Package}\n"}, *calls)
+
+	// Subcommands and flags are left to other completers
+	assert.Nil(t, completer.GetCompletions("apt", []string{"ins"}, "apt ins"))
+	assert.Nil(t, completer.GetCompletions("apt", []string{"install", "--no"}, "apt install --no"))
+	assert.Nil(t, completer.GetCompletions("apt", []string{"update"}, "apt update "))
+}
+
+func TestPackageCompleter_Brew(t *testing.T) {
+	mockPackageOutput(t)
+	completer := &PackageCompleter{}
+
+	assert.Equal(t, []string{"iterm2", "jq", "ripgrep"}, candidateValues(completer.GetCompletions("brew", []string{"install"}, "brew install ")))
+}
+
+func TestPackageCompleter_Dnf(t *testing.T) {
+	mockPackageOutput(t)
+	completer := &PackageCompleter{}
+
+	assert.Equal(t, []string{"curl", "python3-some-very-long-package-name"}, candidateValues(completer.GetCompletions("dnf", []string{"install"}, "dnf install ")))
+
+	// Without rpm there is nothing to offer
+	assert.Empty(t, completer.GetCompletions("dnf", []string{"remove"}, "dnf remove "))
+}
+
+func TestCompleteFromPackages(t *testing.T) {
+	mockPackageOutput(t)
+	runner, _ := interp.New()
+	runner.Vars = map[string]expand.Variable{
+		"GSH_COMPLETION_SOURCES": {Kind: expand.String, Str: `["package","file"]`},
+	}
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, runner)
+
+	results := provider.GetCompletionResults("/usr/bin/apt install vi", 23)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "vim", results[0].Value)
+		assert.Equal(t, "package", results[0].Group)
+	}
+}
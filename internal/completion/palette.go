@@ -0,0 +1,85 @@
+package completion
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+// paletteSubcommandPattern finds documented subcommands such as
+// "**@!coach stats** - View detailed statistics" in builtin command help
+var paletteSubcommandPattern = regexp.MustCompile(`\*\*(@![a-z-]+ [a-z-]+)[^*]*\*\* - ([^\n]+)`)
+
+// GetPaletteEntries lists everything the command palette offers: agent
+// controls and their subcommands, @?, chat macros and subagents. Descriptions
+// come from the same help shown while typing them.
+func (p *ShellCompletionProvider) GetPaletteEntries() []shellinput.CompletionCandidate {
+	var entries []shellinput.CompletionCandidate
+	seen := make(map[string]bool)
+	add := func(value, description string) {
+		if seen[value] {
+			return
+		}
+		seen[value] = true
+		entries = append(entries, shellinput.CompletionCandidate{Value: value, Description: description})
+	}
+
+	for _, command := range builtinCommands {
+		help := p.getBuiltinCommandHelp(command)
+		add("@!"+command, helpSummary(help))
+		for _, match := range paletteSubcommandPattern.FindAllStringSubmatch(help, -1) {
+			add(match[1], strings.TrimSpace(match[2]))
+		}
+	}
+
+	add("@?", "Magic fix for failed commands")
+
+	for _, macro := range p.getMacroCompletions("@/") {
+		add(macro, p.macroExpansion(strings.TrimPrefix(macro, "@/")))
+	}
+
+	if p.SubagentProvider != nil {
+		subagents := p.SubagentProvider.GetAllSubagents()
+		ids := make([]string, 0, len(subagents))
+		for id := range subagents {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			add("@"+id, subagents[id].Description)
+		}
+	}
+
+	return entries
+}
+
+// helpSummary returns the one-line description from help text formatted as
+// "**name** - description\n\ndetails"
+func helpSummary(help string) string {
+	firstLine, _, _ := strings.Cut(help, "\n")
+	if _, description, ok := strings.Cut(firstLine, "** - "); ok {
+		return strings.TrimSpace(description)
+	}
+	return strings.TrimSpace(firstLine)
+}
+
+// macroExpansion returns the message a chat macro expands to
+func (p *ShellCompletionProvider) macroExpansion(name string) string {
+	var macrosStr string
+	if p.Runner != nil {
+		macrosStr = p.Runner.Vars["GSH_AGENT_MACROS"].String()
+	} else {
+		macrosStr = os.Getenv("GSH_AGENT_MACROS")
+	}
+
+	var macros map[string]interface{}
+	if err := json.Unmarshal([]byte(macrosStr), &macros); err != nil {
+		return ""
+	}
+	message, _ := macros[name].(string)
+	return message
+}
@@ -0,0 +1,35 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPaletteEntries(t *testing.T) {
+	t.Setenv("GSH_AGENT_MACROS", `{"review": "Review my staged changes"}`)
+	provider := NewShellCompletionProvider(NewCompletionManager(), nil)
+
+	entries := provider.GetPaletteEntries()
+	byValue := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		byValue[entry.Value] = entry.Description
+	}
+
+	assert.Equal(t, "@!config", entries[0].Value, "Agent controls come first, in their usual order")
+	assert.Equal(t, "Open the configuration menu", byValue["@!config"])
+	assert.Equal(t, "Rebuild the index of commands in PATH", byValue["@!rehash"])
+	assert.Equal(t, "View detailed statistics", byValue["@!coach stats"])
+	assert.Equal(t, "View main dashboard", byValue["@!coach dashboard"])
+	assert.Equal(t, "Delete a bookmark", byValue["@!bookmark delete"])
+	assert.Contains(t, byValue, "@?")
+	assert.Equal(t, "Review my staged changes", byValue["@/review"])
+
+	var _ shellinput.PaletteProvider = provider
+}
+
+func TestHelpSummary(t *testing.T) {
+	assert.Equal(t, "Start a new chat session with the agent", helpSummary("**@!new** - Start a new chat session with the agent\n\nThis command resets..."))
+	assert.Equal(t, "No header", helpSummary("No header"))
+}
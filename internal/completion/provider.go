@@ -7,12 +7,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"reflect"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"github.com/atinylittleshell/gsh/internal/bash"
 	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/history"
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
 	"mvdan.cc/sh/v3/interp"
 )
@@ -37,29 +40,71 @@ type SubagentProvider interface {
 	GetSubagent(id string) (*SubagentInfo, bool)
 }
 
+// DirectoryProvider ranks previously visited directories for `z` completions
+type DirectoryProvider interface {
+	RankDirectories(query []string) ([]history.DirectoryMatch, error)
+}
+
+// BookmarkProvider lists saved bookmarks for @!bookmark completions
+type BookmarkProvider interface {
+	ListBookmarks() ([]history.Bookmark, error)
+}
+
 // ShellCompletionProvider implements shellinput.CompletionProvider using the shell's CompletionManager
 type ShellCompletionProvider struct {
 	CompletionManager CompletionManagerInterface
 	Runner            *interp.Runner
-	SubagentProvider  SubagentProvider // Optional, for @ completions
+	SubagentProvider  SubagentProvider  // Optional, for @ completions
+	DirectoryProvider DirectoryProvider // Optional, for z completions
+	BookmarkProvider  BookmarkProvider  // Optional, for @!bookmark completions
 
 	// Default completers
 	defaultCompleter *DefaultCompleter
 	gitCompleter     *GitCompleter
+	dockerCompleter  *DockerCompleter
+	tmuxCompleter    *TmuxCompleter
+	packageCompleter *PackageCompleter
 	staticCompleter  *StaticCompleter
+	archiveCompleter *ArchiveCompleter
+	manPageCompleter *ManPageCompleter
+
+	// Completion sources by name, see completionSources
+	sources map[string]CompletionSource
+
+	// Leaves gitignored paths out of file completions, see completeFilePaths
+	gitIgnore gitIgnoreFilter
+
+	// Executables in PATH, see getAvailableCommands
+	commandIndex commandIndex
+
+	// Cancels the completions running in the background, see Cancel
+	cancels map[int]context.CancelFunc
+	nextID  int
+	running sync.WaitGroup
+	// Guards cancels and nextID
+	mu sync.Mutex
 }
 
 // NewShellCompletionProvider creates a new ShellCompletionProvider
 func NewShellCompletionProvider(manager CompletionManagerInterface, runner *interp.Runner) *ShellCompletionProvider {
-	return &ShellCompletionProvider{
+	p := &ShellCompletionProvider{
 		CompletionManager: manager,
 		Runner:            runner,
 		SubagentProvider:  nil, // Set later via SetSubagentProvider if needed
 
 		defaultCompleter: &DefaultCompleter{},
 		gitCompleter:     &GitCompleter{},
+		dockerCompleter:  &DockerCompleter{},
+		tmuxCompleter:    &TmuxCompleter{},
+		packageCompleter: &PackageCompleter{},
 		staticCompleter:  NewStaticCompleter(),
+		archiveCompleter: NewArchiveCompleter(),
+		manPageCompleter: NewManPageCompleter(""),
+		cancels:          make(map[int]context.CancelFunc),
 	}
+	// Built up front, since completions may run concurrently
+	p.sources = p.buildCompletionSources()
+	return p
 }
 
 // SetSubagentProvider sets the subagent provider for @ completions
@@ -67,137 +112,342 @@ func (p *ShellCompletionProvider) SetSubagentProvider(provider SubagentProvider)
 	p.SubagentProvider = provider
 }
 
+// SetDirectoryProvider sets the directory provider for z completions
+func (p *ShellCompletionProvider) SetDirectoryProvider(provider DirectoryProvider) {
+	p.DirectoryProvider = provider
+}
+
+// SetBookmarkProvider sets the bookmark provider for @!bookmark completions
+func (p *ShellCompletionProvider) SetBookmarkProvider(provider BookmarkProvider) {
+	p.BookmarkProvider = provider
+}
+
+// Rehash rebuilds the index of commands in PATH, like zsh's rehash, so newly
+// installed tools can be completed right away. It returns the number of commands found.
+func (p *ShellCompletionProvider) Rehash() int {
+	return p.commandIndex.rebuild(os.Getenv("PATH"))
+}
+
+// SetManPageCacheDir sets where flags parsed from man pages are cached
+func (p *ShellCompletionProvider) SetManPageCacheDir(dir string) {
+	p.manPageCompleter.SetCacheDir(dir)
+}
+
 // GetCompletions returns completion suggestions for the current input line
 func (p *ShellCompletionProvider) GetCompletions(line string, pos int) []shellinput.CompletionCandidate {
+	batch, _ := p.complete(context.Background(), line, pos)
+	return batch.Candidates
+}
+
+// GetCompletionsAsync completes in the background, so completers waiting on a
+// subprocess, like docker, don't freeze the prompt. Each completion source
+// sends its candidates in a batch of its own as soon as it has them, see
+// streamSources, and the channel is closed once they're all done.
+func (p *ShellCompletionProvider) GetCompletionsAsync(ctx context.Context, line string, pos int) <-chan shellinput.CompletionBatch {
+	ctx, cancel := context.WithCancel(ctx)
+	p.mu.Lock()
+	id := p.nextID
+	p.nextID++
+	p.cancels[id] = cancel
+	p.mu.Unlock()
+	p.running.Add(1)
+
+	batches := make(chan shellinput.CompletionBatch)
+	go func() {
+		defer func() {
+			p.mu.Lock()
+			delete(p.cancels, id)
+			p.mu.Unlock()
+			cancel()
+			p.running.Done()
+		}()
+		defer close(batches)
+
+		// The limit on candidates applies to all the batches together, and a
+		// candidate already sent by another source isn't sent again
+		maxCandidates := p.maxCandidates()
+		sent := make(map[string]bool)
+		send := func(suggestions []shellinput.CompletionCandidate, group string) {
+			var batch shellinput.CompletionBatch
+			for _, suggestion := range suggestions {
+				if sent[suggestion.Value] {
+					continue
+				}
+				sent[suggestion.Value] = true
+				if maxCandidates > 0 && len(sent) > maxCandidates {
+					batch.Omitted++
+					continue
+				}
+				batch.Candidates = append(batch.Candidates, suggestion)
+			}
+			if len(batch.Candidates) == 0 && batch.Omitted == 0 || ctx.Err() != nil {
+				return
+			}
+			if group == "correction" && len(batch.Candidates) > 0 {
+				batch.Header = correctionHeader
+			}
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+			}
+		}
+
+		// Candidates that don't come from the sources, like those of a
+		// redirection, are returned instead of streamed
+		send(p.completeAll(ctx, line, pos, send))
+	}()
+	return batches
+}
+
+// Cancel stops the completions running in the background and waits for them
+// to return, so they're done with the runner before it runs a command
+func (p *ShellCompletionProvider) Cancel() {
+	p.mu.Lock()
+	for _, cancel := range p.cancels {
+		cancel()
+	}
+	p.mu.Unlock()
+	p.running.Wait()
+}
+
+// CompletionResult is a completion candidate along with the group, the
+// completion source or "agent" for @ commands, that produced it. It's the JSON
+// format used by `gsh --complete` for editors and external pickers.
+type CompletionResult struct {
+	Value       string `json:"value"`
+	Display     string `json:"display,omitempty"`
+	Description string `json:"description,omitempty"`
+	Suffix      string `json:"suffix,omitempty"`
+	Group       string `json:"group"`
+}
+
+// GetCompletionResults returns the same candidates as GetCompletions with their group
+func (p *ShellCompletionProvider) GetCompletionResults(line string, pos int) []CompletionResult {
+	batch, group := p.complete(context.Background(), line, pos)
+	results := make([]CompletionResult, len(batch.Candidates))
+	for i, suggestion := range batch.Candidates {
+		results[i] = CompletionResult{
+			Value:       suggestion.Value,
+			Display:     suggestion.Display,
+			Description: suggestion.Description,
+			Suffix:      suggestion.Suffix,
+			Group:       group,
+		}
+	}
+	return results
+}
+
+// complete returns the completions for line, up to the configured maximum,
+// and the name of the group that produced them
+func (p *ShellCompletionProvider) complete(ctx context.Context, line string, pos int) (shellinput.CompletionBatch, string) {
+	suggestions, group := p.completeAll(ctx, line, pos, nil)
+
+	batch := shellinput.CompletionBatch{Candidates: suggestions}
+	if group == "correction" && len(suggestions) > 0 {
+		batch.Header = correctionHeader
+	}
+	// Sources return their candidates sorted, so the first ones are kept
+	if maxCandidates := p.maxCandidates(); maxCandidates > 0 && len(suggestions) > maxCandidates {
+		batch.Omitted = len(suggestions) - maxCandidates
+		batch.Candidates = suggestions[:maxCandidates]
+	}
+	return batch, group
+}
+
+// maxCandidates returns how many candidates are shown at most, 0 for no limit
+func (p *ShellCompletionProvider) maxCandidates() int {
+	if p.Runner == nil {
+		return environment.DEFAULT_COMPLETION_MAX_CANDIDATES
+	}
+	return environment.GetCompletionMaxCandidates(p.Runner)
+}
+
+// candidateSink receives the candidates of a completion source as it answers,
+// see streamSources
+type candidateSink func(suggestions []shellinput.CompletionCandidate, group string)
+
+// completeAll returns the completions for line and the name of the group that
+// produced them. With a sink, the candidates of the completion sources are
+// streamed to it instead of returned.
+func (p *ShellCompletionProvider) completeAll(ctx context.Context, line string, pos int, sink candidateSink) ([]shellinput.CompletionCandidate, string) {
 	// First check for special prefixes (#/ and #!)
 	if completion := p.checkSpecialPrefixes(line, pos); completion != nil {
-		return completion
+		return completion, "agent"
 	}
 
 	// Skip completions for agentic commands (starting with @)
 	truncatedLine := line[:pos]
 	trimmedLine := strings.TrimSpace(truncatedLine)
 	if strings.HasPrefix(trimmedLine, "@") {
-		return make([]shellinput.CompletionCandidate, 0)
+		return make([]shellinput.CompletionCandidate, 0), ""
 	}
 
-	// Split the line into words, preserving quotes
-	words := splitPreservingQuotes(truncatedLine)
-	if len(words) == 0 {
-		return make([]shellinput.CompletionCandidate, 0)
+	// Inside $(...) or backticks, the substitution is a command of its own
+	if start, ok := innermostSubstitution(truncatedLine); ok {
+		return p.completeInSubstitution(ctx, truncatedLine, start, sink)
 	}
 
-	// Get the command (first word)
-	command := words[0]
-
-	// 1. Explicit Spec: Look up completion spec for this command
-	spec, ok := p.CompletionManager.GetSpec(command)
-	if ok {
-		// Execute the completion
-		suggestions, err := p.CompletionManager.ExecuteCompletion(context.Background(), p.Runner, spec, words, truncatedLine, pos)
-		if err == nil && suggestions != nil {
-			return suggestions
-		}
-	}
+	return p.completeCommand(ctx, truncatedLine, sink)
+}
 
-	// 2. Built-in Defaults (Git, cd, etc.)
-	if command == "git" {
-		// Git args are words[1:]
-		gitArgs := []string{}
-		if len(words) > 1 {
-			gitArgs = words[1:]
-		}
-		if suggestions := p.gitCompleter.GetCompletions(gitArgs, truncatedLine); len(suggestions) > 0 {
-			return suggestions
-		}
-	}
+// completeCommand completes the last word of a command, line being the
+// command up to the cursor
+func (p *ShellCompletionProvider) completeCommand(ctx context.Context, line string, sink candidateSink) ([]shellinput.CompletionCandidate, string) {
+	// Split the line into words, preserving quotes
+	words := splitPreservingQuotes(line)
 
-	// Check DefaultCompleter (cd, ssh, make, etc.)
-	// default args words[1:]
-	defaultArgs := []string{}
-	if len(words) > 1 {
-		defaultArgs = words[1:]
+	// Leading NAME=value assignments only set the environment of the command,
+	// so the command is the first word after them
+	for len(words) > 0 && isEnvAssignment(words[0]) && (len(words) > 1 || strings.HasSuffix(line, " ")) {
+		line = strings.TrimLeftFunc(strings.TrimLeftFunc(line, unicode.IsSpace)[len(words[0]):], unicode.IsSpace)
+		words = words[1:]
 	}
-	if suggestions, found := p.defaultCompleter.GetCompletions(command, defaultArgs, truncatedLine, pos); found {
-		if suggestions != nil {
-			return suggestions
+	if len(words) == 0 {
+		return make([]shellinput.CompletionCandidate, 0), ""
+	}
+
+	// The target of a redirection is a file whatever the command
+	if target, operator, ok := redirectionTarget(words, line); ok {
+		suggestions := p.fileCandidates(target, words[0])
+		// The input replaces the whole word, so an operator typed without a
+		// space, like `2>err`, is kept in front of each candidate
+		if operator != "" {
+			for i := range suggestions {
+				if suggestions[i].Display == "" {
+					suggestions[i].Display = suggestions[i].Value
+				}
+				suggestions[i].Value = operator + suggestions[i].Value
+			}
 		}
-		// If found but nil, it means we handled it but found no matches (or defer fallback)
-		// The default implementation returns nil, false if not handled.
+		return suggestions, "file"
 	}
 
-	// Check StaticCompleter (docker, npm, etc.)
-	if suggestions := p.staticCompleter.GetCompletions(command, defaultArgs); len(suggestions) > 0 {
-		return suggestions
+	req := &CompletionRequest{
+		Words:   words,
+		Command: words[0],
+		Args:    words[1:],
+		Line:    line,
+		Pos:     len(line),
 	}
 
-	// 3. Global Programmable Fallback (GSH_COMPLETION_COMMAND or Auto-Discovery)
-	globalCompleter := os.Getenv("GSH_COMPLETION_COMMAND")
-	if globalCompleter == "" {
-		// Auto-discovery: Check for carapace
-		if path, err := execLookPath("carapace"); err == nil {
-			globalCompleter = path
-		}
+	if sink != nil {
+		p.streamSources(ctx, req, sink)
+		return nil, ""
 	}
 
-	if globalCompleter != "" {
-		// Create a temporary spec for the global completer
-		globalSpec := CompletionSpec{
-			Command: command,
-			Type:    CommandCompletion,
-			Value:   globalCompleter,
+	// Try each source in the configured order and use the first that has an answer
+	for _, source := range p.completionSources() {
+		// An abandoned completion stops before the next source
+		if ctx.Err() != nil {
+			break
 		}
-
-		suggestions, err := p.CompletionManager.ExecuteCompletion(context.Background(), p.Runner, globalSpec, words, truncatedLine, pos)
-		if err == nil && len(suggestions) > 0 {
-			return suggestions
+		if suggestions, done := source.Complete(ctx, req); done {
+			if suggestions == nil {
+				return make([]shellinput.CompletionCandidate, 0), source.Name()
+			}
+			return suggestions, source.Name()
 		}
 	}
 
-	// 4. Fallback: File/Command Completion
+	return make([]shellinput.CompletionCandidate, 0), ""
+}
 
-	// No specific completion spec, check if we should complete command names
-	if len(words) == 1 && !strings.HasSuffix(truncatedLine, " ") {
-		// Single word that doesn't end with space
-		// Check if this looks like a path-based command
-		if p.isPathBasedCommand(command) {
-			// For path-based commands, complete with executable files in that path
-			executableCompletions := p.getExecutableCompletions(command)
-			if len(executableCompletions) > 0 {
-				return toCandidates(executableCompletions)
-			}
-		} else {
-			// Regular command name completion
-			commandCompletions := p.getAvailableCommands(command)
-			if len(commandCompletions) > 0 {
-				return toCandidates(commandCompletions)
+// completeInSubstitution completes the command of the substitution starting
+// at start. The input replaces the whole word around the cursor, like `$(gi`,
+// so what comes before the word inside the substitution is kept in front of
+// each candidate.
+func (p *ShellCompletionProvider) completeInSubstitution(ctx context.Context, line string, start int, sink candidateSink) ([]shellinput.CompletionCandidate, string) {
+	inner := line[start:]
+	wordStart := currentWordStart(line)
+	innerWordStart := start + currentWordStart(inner)
+	if wordStart >= innerWordStart {
+		return p.completeCommand(ctx, inner, sink)
+	}
+
+	prefix := line[wordStart:innerWordStart]
+	withPrefix := func(suggestions []shellinput.CompletionCandidate) []shellinput.CompletionCandidate {
+		for i := range suggestions {
+			if suggestions[i].Display == "" {
+				suggestions[i].Display = suggestions[i].Value
 			}
+			suggestions[i].Value = prefix + suggestions[i].Value
 		}
+		return suggestions
 	}
-
-	// No command matches or multiple words, try file path completion
-	var prefix string
-	if len(words) > 1 {
-		// Get the last word as the prefix for file completion
-		prefix = words[len(words)-1]
-	} else if strings.HasSuffix(truncatedLine, " ") {
-		// If line ends with space, use empty prefix to list all files
-		prefix = ""
-	} else {
-		return make([]shellinput.CompletionCandidate, 0)
+	if sink != nil {
+		next := sink
+		sink = func(suggestions []shellinput.CompletionCandidate, group string) {
+			next(withPrefix(suggestions), group)
+		}
 	}
+	suggestions, group := p.completeCommand(ctx, inner, sink)
+	return withPrefix(suggestions), group
+}
 
-	completions := getFileCompletions(prefix, environment.GetPwd(p.Runner))
+// asyncSourceGrace is how long streamSources waits for a source to answer
+// before it tries the next ones while the source keeps running
+var asyncSourceGrace = 100 * time.Millisecond
+
+// streamSources tries the sources in the configured order like completeCommand,
+// passing the candidates of the source that answers to sink. A source that
+// takes longer than asyncSourceGrace keeps running while the next ones are
+// tried, so a slow completer doesn't hold back a fast one. Once a source has
+// answered no more are started, but the slow sources before it still running
+// are waited for and their candidates passed to sink as they come.
+func (p *ShellCompletionProvider) streamSources(ctx context.Context, req *CompletionRequest, sink candidateSink) {
+	type answer struct {
+		index       int
+		suggestions []shellinput.CompletionCandidate
+		done        bool
+	}
+
+	sources := p.completionSources()
+	answers := make(chan answer, len(sources))
+	pending := 0
+	// The index of the first source that answered, sources after it are ignored
+	answered := len(sources)
+	receive := func(a answer) {
+		pending--
+		if !a.done || a.index > answered || ctx.Err() != nil {
+			return
+		}
+		answered = a.index
+		sink(a.suggestions, sources[a.index].Name())
+	}
 
-	// Quote completions that contain spaces, but don't add command prefix
-	// The completion handler will replace only the current word (file path)
-	for i, completion := range completions {
-		if strings.Contains(completion.Value, " ") {
-			// Quote completions that contain spaces
-			completions[i].Value = "\"" + completion.Value + "\""
+	for i, source := range sources {
+		// An abandoned completion stops before the next source
+		if answered < len(sources) || ctx.Err() != nil {
+			break
 		}
+		pending++
+		go func() {
+			suggestions, done := source.Complete(ctx, req)
+			answers <- answer{i, suggestions, done}
+		}()
+
+		timer := time.NewTimer(asyncSourceGrace)
+	wait:
+		for {
+			select {
+			case a := <-answers:
+				receive(a)
+				if a.index == i {
+					break wait
+				}
+			case <-timer.C:
+				break wait
+			case <-ctx.Done():
+				break wait
+			}
+		}
+		timer.Stop()
+	}
+
+	// The sources are done with the runner once this returns, see Cancel
+	for pending > 0 {
+		receive(<-answers)
 	}
-	return completions
 }
 
 // toCandidates converts a list of strings to CompletionCandidate list
@@ -247,6 +497,81 @@ func (p *ShellCompletionProvider) checkSpecialPrefixes(line string, pos int) []s
 		}
 	}
 
+	// Check for @!history subcommand completion
+	if afterHistory, ok := strings.CutPrefix(strings.TrimLeft(line[:pos], " "), "@!history "); ok && !strings.Contains(afterHistory, " ") {
+		var completions []shellinput.CompletionCandidate
+		for _, subcommand := range historySubcommands {
+			if strings.HasPrefix(subcommand, afterHistory) {
+				completions = append(completions, shellinput.CompletionCandidate{Value: line[:start] + subcommand})
+			}
+		}
+		if len(completions) > 0 {
+			return completions
+		}
+	}
+
+	// Check for @!localenv subcommand completion
+	if afterLocalEnv, ok := strings.CutPrefix(strings.TrimLeft(line[:pos], " "), "@!localenv "); ok && !strings.Contains(afterLocalEnv, " ") {
+		var completions []shellinput.CompletionCandidate
+		for _, subcommand := range localEnvSubcommands {
+			if strings.HasPrefix(subcommand, afterLocalEnv) {
+				completions = append(completions, shellinput.CompletionCandidate{Value: line[:start] + subcommand})
+			}
+		}
+		if len(completions) > 0 {
+			return completions
+		}
+	}
+
+	// Check for @!analytics subcommand completion
+	if afterAnalytics, ok := strings.CutPrefix(strings.TrimLeft(line[:pos], " "), "@!analytics "); ok && !strings.Contains(afterAnalytics, " ") {
+		var completions []shellinput.CompletionCandidate
+		for _, subcommand := range analyticsSubcommands {
+			if strings.HasPrefix(subcommand, afterAnalytics) {
+				completions = append(completions, shellinput.CompletionCandidate{Value: line[:start] + subcommand})
+			}
+		}
+		if len(completions) > 0 {
+			return completions
+		}
+	}
+
+	// Check for @!complete subcommand completion
+	if afterComplete, ok := strings.CutPrefix(strings.TrimLeft(line[:pos], " "), "@!complete "); ok && !strings.Contains(afterComplete, " ") {
+		var completions []shellinput.CompletionCandidate
+		for _, subcommand := range completeSubcommands {
+			if strings.HasPrefix(subcommand, afterComplete) {
+				completions = append(completions, shellinput.CompletionCandidate{Value: line[:start] + subcommand})
+			}
+		}
+		if len(completions) > 0 {
+			return completions
+		}
+	}
+
+	// Check for @!toggle setting completion
+	if afterToggle, ok := strings.CutPrefix(strings.TrimLeft(line[:pos], " "), "@!toggle "); ok {
+		completions := getToggleCompletions(afterToggle)
+		for i := range completions {
+			completions[i].Value = line[:start] + completions[i].Value
+		}
+		if len(completions) > 0 {
+			return completions
+		}
+	}
+
+	// Check for @!bookmark subcommand and bookmark name completion
+	if afterBookmark, ok := strings.CutPrefix(strings.TrimLeft(line[:pos], " "), "@!bookmark "); ok && !strings.HasPrefix(currentWord, "@") {
+		completions := p.getBookmarkCompletions(afterBookmark)
+		if len(completions) > 0 {
+			linePrefix := line[:start]
+			for i := range completions {
+				completions[i].Value = linePrefix + completions[i].Value
+			}
+			return completions
+		}
+	}
+
 	// Check if the current word starts with @/, @!, or @
 	if strings.HasPrefix(currentWord, "@/") {
 		completions := p.getMacroCompletions(currentWord)
@@ -563,94 +888,87 @@ func (p *ShellCompletionProvider) getExecutableCompletions(pathPrefix string) []
 	return completions
 }
 
-// getAvailableCommands returns available system commands that match the given prefix
-func (p *ShellCompletionProvider) getAvailableCommands(prefix string) []string {
-	// Use a map to avoid duplicates
-	commands := make(map[string]bool)
+// getAvailableCommands returns the aliases and system commands that match the
+// given prefix. Aliases are described by what they expand to.
+func (p *ShellCompletionProvider) getAvailableCommands(prefix string) []shellinput.CompletionCandidate {
+	// Use a map to avoid duplicates, letting aliases shadow commands like the shell does
+	commands := make(map[string]shellinput.CompletionCandidate)
 
-	// First, add shell aliases
-	aliasCompletions := p.getAliasCompletions(prefix)
-	for _, alias := range aliasCompletions {
-		commands[alias] = true
+	// Add system commands from the PATH index
+	for _, command := range p.commandIndex.lookup(os.Getenv("PATH"), prefix) {
+		commands[command] = shellinput.CompletionCandidate{Value: command}
 	}
 
-	// Then, get PATH from environment for system commands
-	pathEnv := os.Getenv("PATH")
-	if pathEnv != "" {
-		// Split PATH into directories
-		pathDirs := strings.Split(pathEnv, string(os.PathListSeparator))
-
-		// Search each directory in PATH
-		for _, dir := range pathDirs {
-			entries, err := osReadDir(dir)
-			if err != nil {
-				continue // Skip directories we can't read
-			}
-
-			for _, entry := range entries {
-				// Only consider regular files that are executable
-				if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
-					// Check if file is executable (this is a simplified check)
-					// In a real implementation, you'd want to check file permissions
-					commands[entry.Name()] = true
-				}
-			}
-		}
+	// Then, add shell aliases
+	for _, alias := range p.getAliasCompletions(prefix) {
+		commands[alias.Value] = alias
 	}
 
-	// Convert map to sorted slice
-	var completions []string
-	for cmd := range commands {
-		completions = append(completions, cmd)
+	completions := make([]shellinput.CompletionCandidate, 0, len(commands))
+	for _, candidate := range commands {
+		completions = append(completions, candidate)
 	}
 
 	// Sort alphabetically for consistent ordering
-	sort.Strings(completions)
+	sort.Slice(completions, func(i, j int) bool {
+		return completions[i].Value < completions[j].Value
+	})
 	return completions
 }
 
-// getAliasCompletions returns shell aliases that match the given prefix
-func (p *ShellCompletionProvider) getAliasCompletions(prefix string) []string {
+// getAliasCompletions returns shell aliases that match the given prefix, with
+// their expansion as the description
+func (p *ShellCompletionProvider) getAliasCompletions(prefix string) []shellinput.CompletionCandidate {
 	if p.Runner == nil {
-		return []string{}
-	}
-
-	// Use reflection to access the unexported alias field
-	runnerValue := reflect.ValueOf(p.Runner).Elem()
-	aliasField := runnerValue.FieldByName("alias")
-
-	if !aliasField.IsValid() || aliasField.IsNil() {
-		return []string{}
+		return []shellinput.CompletionCandidate{}
 	}
 
-	// The alias field is a map[string]interp.alias
-	// We need to iterate over the keys (alias names)
-	var completions []string
-
-	// Get the map keys using reflection
-	for _, key := range aliasField.MapKeys() {
-		aliasName := key.String()
-		if strings.HasPrefix(aliasName, prefix) {
-			completions = append(completions, aliasName)
+	var completions []shellinput.CompletionCandidate
+	for name, definition := range bash.Aliases(p.Runner) {
+		if strings.HasPrefix(name, prefix) {
+			completions = append(completions, shellinput.CompletionCandidate{
+				Value:       name,
+				Description: strings.TrimSpace(definition),
+			})
 		}
 	}
 
 	// Sort alphabetically for consistent ordering
-	sort.Strings(completions)
+	sort.Slice(completions, func(i, j int) bool {
+		return completions[i].Value < completions[j].Value
+	})
 	return completions
 }
 
+// builtinCommands lists the agent controls that can be invoked with @!
+var builtinCommands = []string{
+	"config",
+	"new",
+	"tokens",
+	"subagents",
+	"reload-subagents",
+	"coach",
+	"copy-output",
+	"latency",
+	"predict-debug",
+	"why",
+	"bookmark",
+	"transcript",
+	"history",
+	"localenv",
+	"bench",
+	"rehash",
+	"toggle",
+	"note",
+	"analytics",
+	"complete",
+}
+
+// agentControlsOverview is the help shown for @! before a specific control is typed
+const agentControlsOverview = "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, heatmap, tips, notifications, reset-tips)\n• **@!copy-output** - Copy the output of the last command\n• **@!latency** - Show prediction and explanation latency\n• **@!predict-debug** - Show what the last prediction was made from\n• **@!why** - Explain why the last command failed\n• **@!bookmark [subcommand]** - Save and run named commands (save, list, run, delete)\n• **@!transcript [query]** - Review or search recorded commands and their output\n• **@!history [subcommand]** - Manage the history database (stats, prune, vacuum, export, import)\n• **@!localenv [subcommand]** - Load the .gshenv.local of this directory (status, allow, deny)\n• **@!bench [--show-output] <runs> <command>** - Time a command over several runs\n• **@!rehash** - Rebuild the index of commands in PATH\n• **@!toggle [--persist] <setting>** - Turn a boolean setting on or off\n• **@!note <text>** - Attach a note to the last command in history\n• **@!analytics mismatches [n]** - List recent commands the prediction got wrong\n• **@!complete discover <command>** - Find and register completions for a command"
+
 // getBuiltinCommandCompletions returns completions for built-in commands starting with @!
 func (p *ShellCompletionProvider) getBuiltinCommandCompletions(prefix string) []string {
-	builtinCommands := []string{
-		"config",
-		"new",
-		"tokens",
-		"subagents",
-		"reload-subagents",
-		"coach",
-	}
-
 	var completions []string
 	prefixAfterBang := strings.TrimPrefix(prefix, "@!")
 
@@ -671,7 +989,9 @@ func (p *ShellCompletionProvider) getCoachSubcommandCompletions(prefix string) [
 		"stats",
 		"achievements",
 		"challenges",
+		"heatmap",
 		"tips",
+		"notifications",
 		"reset-tips",
 		"dashboard",
 	}
@@ -687,6 +1007,91 @@ func (p *ShellCompletionProvider) getCoachSubcommandCompletions(prefix string) [
 	return completions
 }
 
+// historySubcommands are the subcommands of @!history
+var historySubcommands = []string{"export", "import", "prune", "stats", "vacuum"}
+
+// localEnvSubcommands are the subcommands of @!localenv
+var localEnvSubcommands = []string{"allow", "deny", "status"}
+
+// analyticsSubcommands are the subcommands of @!analytics
+var analyticsSubcommands = []string{"mismatches"}
+
+// completeSubcommands are the subcommands of @!complete
+var completeSubcommands = []string{"discover"}
+
+// getToggleCompletions completes the setting names of @!toggle, and --persist
+// until it's given
+func getToggleCompletions(args string) []shellinput.CompletionCandidate {
+	fields := strings.Fields(args)
+	prefix := ""
+	if len(fields) > 0 && !strings.HasSuffix(args, " ") {
+		prefix = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+
+	// At most --persist may come before the setting name
+	if len(fields) > 1 || (len(fields) == 1 && fields[0] != "--persist") {
+		return nil
+	}
+	options := environment.ToggleSettings
+	if len(fields) == 0 {
+		options = append([]string{"--persist"}, options...)
+	}
+
+	var candidates []shellinput.CompletionCandidate
+	for _, option := range options {
+		if strings.HasPrefix(option, strings.ToUpper(prefix)) || strings.HasPrefix(option, prefix) {
+			candidates = append(candidates, shellinput.CompletionCandidate{Value: option})
+		}
+	}
+	return candidates
+}
+
+// bookmarkSubcommands are the subcommands of @!bookmark
+var bookmarkSubcommands = []string{"delete", "list", "run", "save"}
+
+// getBookmarkCompletions completes the arguments of @!bookmark. The first
+// argument is a subcommand or a bookmark name to run, and the argument after
+// run, delete or save is a bookmark name.
+func (p *ShellCompletionProvider) getBookmarkCompletions(args string) []shellinput.CompletionCandidate {
+	fields := strings.Fields(args)
+	prefix := ""
+	if len(fields) > 0 && !strings.HasSuffix(args, " ") {
+		prefix = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+
+	var candidates []shellinput.CompletionCandidate
+	switch {
+	case len(fields) == 0:
+		for _, subcommand := range bookmarkSubcommands {
+			if strings.HasPrefix(subcommand, prefix) {
+				candidates = append(candidates, shellinput.CompletionCandidate{Value: subcommand})
+			}
+		}
+	case len(fields) == 1 && (fields[0] == "run" || fields[0] == "delete" || fields[0] == "save"):
+	default:
+		return nil
+	}
+
+	if p.BookmarkProvider == nil {
+		return candidates
+	}
+	bookmarks, err := p.BookmarkProvider.ListBookmarks()
+	if err != nil {
+		return candidates
+	}
+	for _, bookmark := range bookmarks {
+		if strings.HasPrefix(bookmark.Name, prefix) {
+			candidates = append(candidates, shellinput.CompletionCandidate{
+				Value:       bookmark.Name,
+				Description: bookmark.Command,
+			})
+		}
+	}
+	return candidates
+}
+
 // getSubagentCompletions returns completions for subagents starting with @
 func (p *ShellCompletionProvider) getSubagentCompletions(prefix string) []string {
 	// If no subagent provider is available, return no completions
@@ -778,7 +1183,7 @@ func (p *ShellCompletionProvider) GetHelpInfo(line string, pos int) string {
 func (p *ShellCompletionProvider) getBuiltinCommandHelp(command string) string {
 	switch command {
 	case "config":
-		return "**@!config** - Open the configuration menu\n\nLaunches an interactive UI to configure gsh settings including model configuration, assistant height, and safety checks."
+		return "**@!config** - Open the configuration menu\n\nLaunches an interactive UI to configure gsh settings including model configuration, assistant height, and safety checks.\n\n**@!config context-types** lists the context types that the GSH_CONTEXT_TYPES_FOR_* settings accept and where each is currently used."
 	case "new":
 		return "**@!new** - Start a new chat session with the agent\n\nThis command resets the conversation history and starts fresh."
 	case "tokens":
@@ -788,16 +1193,43 @@ func (p *ShellCompletionProvider) getBuiltinCommandHelp(command string) string {
 	case "reload-subagents":
 		return "**@!reload-subagents** - Reload subagent configurations from disk\n\nRefreshes the subagent configurations by rescanning the .claude/agents/ and .roo/modes/ directories."
 	case "coach":
-		return "**@!coach [subcommand]** - Productivity coach dashboard\n\nSubcommands:\n• **@!coach** or **@!coach dashboard** - View main dashboard\n• **@!coach stats** - View detailed statistics\n• **@!coach achievements** - Browse achievements\n• **@!coach challenges** - View active challenges\n• **@!coach tips** - View all tips\n• **@!coach reset-tips** - Regenerate tips from history"
+		return "**@!coach [subcommand]** - Productivity coach dashboard\n\nSubcommands:\n• **@!coach** or **@!coach dashboard** - View main dashboard\n• **@!coach stats** - View detailed statistics\n• **@!coach achievements** - Browse achievements\n• **@!coach challenges** - View active challenges\n• **@!coach heatmap** - View daily activity over the last year and hourly activity today\n• **@!coach tips** - View all tips\n• **@!coach notifications [mark-read]** - Review recent notifications or mark them all read\n• **@!coach reset-tips [--since 30d] [--last 500]** - Regenerate tips from all or recent history"
+	case "copy-output":
+		return "**@!copy-output** - Copy the output of the last command\n\nPuts the captured stdout of the previous command on the clipboard. Requires GSH_OUTPUT_CAPTURE_MAX_BYTES to be greater than 0."
+	case "latency":
+		return "**@!latency** - Show prediction and explanation latency\n\nDisplays how long LLM predictions and explanations have taken during this session, to help tell whether slowness comes from the model."
+	case "predict-debug":
+		return "**@!predict-debug** - Show what the last prediction was made from\n\nShows what had been typed, the prediction, and the exact context sent to the fast model for it, such as history, files and the working directory, through GSH_PAGER when it's set. Useful to understand a poor prediction and the effect of the GSH_CONTEXT_TYPES_FOR_PREDICTION_* settings."
+	case "why":
+		return "**@!why** - Explain why the last command failed\n\nAsks the fast model what the last command's exit code and error output most likely mean, along with common fixes. Unlike @?, it only explains and never runs anything."
+	case "bookmark":
+		return "**@!bookmark [subcommand]** - Save and run named commands\n\nSubcommands:\n• **@!bookmark save <name> [command]** - Bookmark a command, or the last command if none is given\n• **@!bookmark list** - List bookmarks\n• **@!bookmark run <name>** or **@!bookmark <name>** - Run a bookmark\n• **@!bookmark delete <name>** - Delete a bookmark"
+	case "transcript":
+		return "**@!transcript [query]** - Review or search recorded commands and their output\n\nWith GSH_TRANSCRIPT=1, each command is recorded in the history database with its exit code and output, up to GSH_TRANSCRIPT_MAX_BYTES per command. Without a query, shows the most recent entries. With a query, shows entries from any session whose command or output contains it."
+	case "history":
+		return "**@!history [subcommand]** - Manage the history database\n\nSubcommands:\n• **@!history stats** - Show the number of entries and the database size\n• **@!history prune --older-than <age>** - Delete entries older than an age such as 90d, 6w or 1y, keeping the latest entry of each directory\n• **@!history vacuum** - Reclaim the space left by deleted entries\n• **@!history export [--format bash|zsh|json] <path>** - Write the history in another shell's format\n• **@!history import [--format bash|zsh|json] <path>** - Add the history of another shell, e.g. ~/.zsh_history"
+	case "localenv":
+		return "**@!localenv [subcommand]** - Load the .gshenv.local of this directory\n\nWhen you enter a directory with a .gshenv.local file, or one of its subdirectories, gsh sets the variables it assigns and restores them when you leave. The file may only contain assignments such as FOO=bar or export PATH=$PWD/bin:$PATH, and is only loaded once allowed. Editing the file requires allowing it again.\n\nSubcommands:\n• **@!localenv status** - Show whether the file is loaded and what it sets\n• **@!localenv allow** - Allow the current content of the file\n• **@!localenv deny** - Revoke the approval and unload the file"
+	case "bench":
+		return "**@!bench [--show-output] <runs> <command>** - Time a command over several runs\n\nRuns the command the given number of times and shows the minimum, maximum, mean and standard deviation of its wall-clock time, like a small hyperfine. Runs are not added to history. Output is discarded unless --show-output is given. Press Ctrl+C to stop early."
+	case "rehash":
+		return "**@!rehash** - Rebuild the index of commands in PATH\n\nCommand name completion uses an index of PATH that is rebuilt when PATH changes and refreshed in the background every minute. Run this after installing a tool to complete it right away."
+	case "toggle":
+		return "**@!toggle [--persist] <setting>** - Turn a boolean setting on or off\n\nFlips the setting for this session, taking effect from the next prompt. With --persist it's also saved to ~/.gsh_config_ui, which ~/.gshrc sources, like changes made in @!config.\n\nSettings: " + strings.Join(environment.ToggleSettings, ", ")
+	case "note":
+		return "**@!note <text>** - Attach a note to the last command in history\n\nThe note is shown next to the command in history search (Ctrl+R), which also matches on notes, so a command can be found by what it was for. Running the command again keeps its note. Use **@!note --clear** to remove the note."
+	case "analytics":
+		return "**@!analytics mismatches [n]** - List recent commands the prediction got wrong\n\nShows the last n (20 by default) predictions that the command you ran didn't start with, next to that command, to see where predictions tend to go wrong."
+	case "complete":
+		return "**@!complete discover <command>** - Find and register completions for a command\n\nLooks for a spec file in ~/.config/gsh/completions that isn't loaded yet, then carapace, then the command's man page, and registers the first one found for this session. Commands that already complete, through `complete` or a built-in completer, are left as they are."
 	case "":
-		return "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, tips, reset-tips)"
+		return agentControlsOverview
 	default:
 		// Check for partial matches
-		builtinCommands := []string{"config", "new", "tokens", "subagents", "reload-subagents", "coach"}
 		for _, cmd := range builtinCommands {
 			if strings.HasPrefix(cmd, command) {
 				// Partial match, show general help
-				return "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, tips, reset-tips)"
+				return agentControlsOverview
 			}
 		}
 		return ""
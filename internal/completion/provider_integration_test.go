@@ -267,23 +267,23 @@ func TestShellCompletionProvider_BuiltinCompletion_Integration(t *testing.T) {
 			name:          "builtin completion with @! prefix",
 			line:          "@!",
 			pos:           2,
-			expectedCount: 6,
-			shouldContain: []string{"@!config", "@!new", "@!tokens", "@!subagents", "@!reload-subagents", "@!coach"},
+			expectedCount: 20,
+			shouldContain: []string{"@!config", "@!new", "@!tokens", "@!subagents", "@!reload-subagents", "@!coach", "@!copy-output", "@!latency", "@!predict-debug", "@!why", "@!bookmark", "@!transcript", "@!history", "@!localenv", "@!bench", "@!rehash", "@!toggle", "@!note", "@!analytics", "@!complete"},
 		},
 		{
 			name:             "builtin completion with 'n' prefix",
 			line:             "@!n",
 			pos:              3,
-			expectedCount:    1,
-			shouldContain:    []string{"@!new"},
+			expectedCount:    2,
+			shouldContain:    []string{"@!new", "@!note"},
 			shouldNotContain: []string{"@!tokens"},
 		},
 		{
 			name:             "builtin completion with 't' prefix",
 			line:             "@!t",
 			pos:              3,
-			expectedCount:    1,
-			shouldContain:    []string{"@!tokens"},
+			expectedCount:    3,
+			shouldContain:    []string{"@!toggle", "@!tokens", "@!transcript"},
 			shouldNotContain: []string{"@!new"},
 		},
 		{
@@ -476,7 +476,7 @@ func TestShellCompletionProvider_HelpInfo_Integration(t *testing.T) {
 			name:     "help for @! prefix",
 			line:     "@!",
 			pos:      2,
-			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, tips, reset-tips)",
+			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, heatmap, tips, notifications, reset-tips)\n• **@!copy-output** - Copy the output of the last command\n• **@!latency** - Show prediction and explanation latency\n• **@!predict-debug** - Show what the last prediction was made from\n• **@!why** - Explain why the last command failed\n• **@!bookmark [subcommand]** - Save and run named commands (save, list, run, delete)\n• **@!transcript [query]** - Review or search recorded commands and their output\n• **@!history [subcommand]** - Manage the history database (stats, prune, vacuum, export, import)\n• **@!localenv [subcommand]** - Load the .gshenv.local of this directory (status, allow, deny)\n• **@!bench [--show-output] <runs> <command>** - Time a command over several runs\n• **@!rehash** - Rebuild the index of commands in PATH\n• **@!toggle [--persist] <setting>** - Turn a boolean setting on or off\n• **@!note <text>** - Attach a note to the last command in history\n• **@!analytics mismatches [n]** - List recent commands the prediction got wrong\n• **@!complete discover <command>** - Find and register completions for a command",
 		},
 		{
 			name:     "help for @!new command",
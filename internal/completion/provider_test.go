@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/atinylittleshell/gsh/internal/history"
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -30,7 +31,7 @@ var mockGetFileCompletions fileCompleter = func(prefix, currentDirectory string)
 			{Value: "/usr/local/bin", Suffix: "/"},
 			{Value: "/usr/local/bin/"},
 		}
-	case "'my documents/som":
+	case "my documents/som":
 		return []shellinput.CompletionCandidate{
 			{Value: "my documents/something.txt"},
 			{Value: "my documents/somefile.txt"},
@@ -148,6 +149,8 @@ func TestGetCompletions(t *testing.T) {
 	runner, _ := interp.New(interp.StdIO(nil, nil, nil))
 	runner.Vars = map[string]expand.Variable{
 		"GSH_AGENT_MACROS": {Kind: expand.String, Str: `{"macro1": {}, "macro2": {}, "macro3": {}}`},
+		// Keep file completions in the order the mocks return them
+		"GSH_FILE_COMPLETION_SORT": {Kind: expand.String, Str: "name"},
 	}
 
 	manager := &mockCompletionManager{}
@@ -269,8 +272,8 @@ func TestGetCompletions(t *testing.T) {
 				manager.On("GetSpec", "less").Return(CompletionSpec{}, false)
 			},
 			expected: []shellinput.CompletionCandidate{
-				{Value: "\"my documents/something.txt\""},
-				{Value: "\"my documents/somefile.txt\""},
+				{Value: "'my documents/something.txt'"},
+				{Value: "'my documents/somefile.txt'"},
 			},
 		},
 		{
@@ -343,6 +346,7 @@ func TestGetCompletions(t *testing.T) {
 			},
 			expected: []shellinput.CompletionCandidate{
 				{Value: "@!new"},
+				{Value: "@!note"},
 			},
 		},
 		{
@@ -366,7 +370,9 @@ func TestGetCompletions(t *testing.T) {
 				// No setup needed - should match builtins
 			},
 			expected: []shellinput.CompletionCandidate{
+				{Value: "@!toggle"},
 				{Value: "@!tokens"},
+				{Value: "@!transcript"},
 			},
 		},
 		{
@@ -385,9 +391,10 @@ func TestGetCompletions(t *testing.T) {
 			line: "@!r",
 			pos:  3,
 			setup: func() {
-				// No setup needed - should match builtin reload command
+				// No setup needed - should match builtin reload and rehash commands
 			},
 			expected: []shellinput.CompletionCandidate{
+				{Value: "@!rehash"},
 				{Value: "@!reload-subagents"},
 			},
 		},
@@ -429,8 +436,8 @@ func TestGetCompletions(t *testing.T) {
 				setupTestAliases(runner)
 			},
 			expected: []shellinput.CompletionCandidate{
-				{Value: "test123"},
-				{Value: "testfoo"},
+				{Value: "test123", Description: "ls"},
+				{Value: "testfoo", Description: "echo hello"},
 			},
 		},
 		{
@@ -445,7 +452,7 @@ func TestGetCompletions(t *testing.T) {
 				setupTestAliases(runner)
 			},
 			expected: []shellinput.CompletionCandidate{
-				{Value: "test123"},
+				{Value: "test123", Description: "ls"},
 			},
 		},
 		{
@@ -509,7 +516,7 @@ func TestGetHelpInfo(t *testing.T) {
 			name:     "help for @! empty",
 			line:     "@!",
 			pos:      2,
-			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, tips, reset-tips)",
+			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, heatmap, tips, notifications, reset-tips)\n• **@!copy-output** - Copy the output of the last command\n• **@!latency** - Show prediction and explanation latency\n• **@!predict-debug** - Show what the last prediction was made from\n• **@!why** - Explain why the last command failed\n• **@!bookmark [subcommand]** - Save and run named commands (save, list, run, delete)\n• **@!transcript [query]** - Review or search recorded commands and their output\n• **@!history [subcommand]** - Manage the history database (stats, prune, vacuum, export, import)\n• **@!localenv [subcommand]** - Load the .gshenv.local of this directory (status, allow, deny)\n• **@!bench [--show-output] <runs> <command>** - Time a command over several runs\n• **@!rehash** - Rebuild the index of commands in PATH\n• **@!toggle [--persist] <setting>** - Turn a boolean setting on or off\n• **@!note <text>** - Attach a note to the last command in history\n• **@!analytics mismatches [n]** - List recent commands the prediction got wrong\n• **@!complete discover <command>** - Find and register completions for a command",
 		},
 		{
 			name:     "help for @!new",
@@ -530,16 +537,16 @@ func TestGetHelpInfo(t *testing.T) {
 			expected: "**Chat Macros** - Quick shortcuts for common agent messages\n\nNo macros are currently configured.",
 		},
 		{
-			name:     "help for partial @!n (matches new)",
+			name:     "help for partial @!n (matches new and note)",
 			line:     "@!n",
 			pos:      3,
-			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, tips, reset-tips)",
+			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, heatmap, tips, notifications, reset-tips)\n• **@!copy-output** - Copy the output of the last command\n• **@!latency** - Show prediction and explanation latency\n• **@!predict-debug** - Show what the last prediction was made from\n• **@!why** - Explain why the last command failed\n• **@!bookmark [subcommand]** - Save and run named commands (save, list, run, delete)\n• **@!transcript [query]** - Review or search recorded commands and their output\n• **@!history [subcommand]** - Manage the history database (stats, prune, vacuum, export, import)\n• **@!localenv [subcommand]** - Load the .gshenv.local of this directory (status, allow, deny)\n• **@!bench [--show-output] <runs> <command>** - Time a command over several runs\n• **@!rehash** - Rebuild the index of commands in PATH\n• **@!toggle [--persist] <setting>** - Turn a boolean setting on or off\n• **@!note <text>** - Attach a note to the last command in history\n• **@!analytics mismatches [n]** - List recent commands the prediction got wrong\n• **@!complete discover <command>** - Find and register completions for a command",
 		},
 		{
-			name:     "help for partial @!t (matches tokens)",
+			name:     "help for partial @!t (matches tokens and transcript)",
 			line:     "@!t",
 			pos:      3,
-			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, tips, reset-tips)",
+			expected: "**Agent Controls** - Built-in commands for managing the agent\n\nAvailable commands:\n• **@!config** - Open the configuration menu\n• **@!new** - Start a new chat session\n• **@!tokens** - Show token usage statistics\n• **@!subagents [name]** - List subagents or show details\n• **@!reload-subagents** - Reload subagent configurations\n• **@!coach [subcommand]** - Productivity coach (stats, achievements, challenges, heatmap, tips, notifications, reset-tips)\n• **@!copy-output** - Copy the output of the last command\n• **@!latency** - Show prediction and explanation latency\n• **@!predict-debug** - Show what the last prediction was made from\n• **@!why** - Explain why the last command failed\n• **@!bookmark [subcommand]** - Save and run named commands (save, list, run, delete)\n• **@!transcript [query]** - Review or search recorded commands and their output\n• **@!history [subcommand]** - Manage the history database (stats, prune, vacuum, export, import)\n• **@!localenv [subcommand]** - Load the .gshenv.local of this directory (status, allow, deny)\n• **@!bench [--show-output] <runs> <command>** - Time a command over several runs\n• **@!rehash** - Rebuild the index of commands in PATH\n• **@!toggle [--persist] <setting>** - Turn a boolean setting on or off\n• **@!note <text>** - Attach a note to the last command in history\n• **@!analytics mismatches [n]** - List recent commands the prediction got wrong\n• **@!complete discover <command>** - Find and register completions for a command",
 		},
 		{
 			name:     "help for @!subagents",
@@ -620,3 +627,277 @@ func TestGetHelpInfoWithMacros(t *testing.T) {
 		})
 	}
 }
+
+type mockBookmarkProvider struct {
+	bookmarks []history.Bookmark
+}
+
+func (m *mockBookmarkProvider) ListBookmarks() ([]history.Bookmark, error) {
+	return m.bookmarks, nil
+}
+
+func TestBookmarkCompletions(t *testing.T) {
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+	provider.SetBookmarkProvider(&mockBookmarkProvider{bookmarks: []history.Bookmark{
+		{Name: "deploy", Command: "make deploy ENV=prod"},
+		{Name: "logs", Command: "kubectl logs -f deploy/api"},
+	}})
+
+	completions := provider.GetCompletions("@!bookmark ", 11)
+	assert.Equal(t, []string{"@!bookmark delete", "@!bookmark list", "@!bookmark run", "@!bookmark save", "@!bookmark deploy", "@!bookmark logs"}, candidateValues(completions))
+
+	completions = provider.GetCompletions("@!bookmark run d", 16)
+	assert.Equal(t, []string{"@!bookmark run deploy"}, candidateValues(completions))
+	assert.Equal(t, "make deploy ENV=prod", completions[0].Description)
+
+	// Nothing is completed after the bookmark name
+	assert.Empty(t, provider.GetCompletions("@!bookmark run deploy ", 22))
+}
+
+func TestHistoryControlCompletions(t *testing.T) {
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+
+	completions := provider.GetCompletions("@!history ", 10)
+	assert.Equal(t, []string{"@!history export", "@!history import", "@!history prune", "@!history stats", "@!history vacuum"}, candidateValues(completions))
+
+	completions = provider.GetCompletions("@!history v", 11)
+	assert.Equal(t, []string{"@!history vacuum"}, candidateValues(completions))
+}
+
+func TestCompletionsInsideSubstitution(t *testing.T) {
+	origOsReadDir := osReadDir
+	osReadDir = mockOsReadDir
+	defer func() {
+		osReadDir = origOsReadDir
+	}()
+
+	runner, _ := interp.New(interp.StdIO(nil, nil, nil))
+	manager := &mockCompletionManager{}
+	spec := CompletionSpec{Command: "git", Type: WordListCompletion, Value: "checkout cherry-pick"}
+	manager.On("GetSpec", "git").Return(spec, true)
+	manager.On("GetSpec", mock.Anything).Return(CompletionSpec{}, false)
+	manager.On("ExecuteCompletion", mock.Anything, runner, spec, []string{"git", "ch"}).
+		Return([]shellinput.CompletionCandidate{{Value: "checkout"}, {Value: "cherry-pick"}}, nil)
+	provider := NewShellCompletionProvider(manager, runner)
+
+	complete := func(line string) []string {
+		return candidateValues(provider.GetCompletions(line, len(line)))
+	}
+
+	// Arguments are completed for the command inside the substitution
+	assert.Equal(t, []string{"checkout", "cherry-pick"}, complete("echo $(git ch"))
+	assert.Equal(t, []string{"checkout", "cherry-pick"}, complete("echo `git ch"))
+	assert.Equal(t, []string{"checkout", "cherry-pick"}, complete("echo $(cat $(git ch"))
+
+	// The command name is completed at the start of the substitution, keeping
+	// the $( in front since the input replaces the whole word
+	if runtime.GOOS != "windows" {
+		completions := provider.GetCompletions("echo $(/bin/c", 13)
+		assert.Equal(t, []string{"$(/bin/cat"}, candidateValues(completions))
+		assert.Equal(t, "/bin/cat", completions[0].Display)
+	}
+
+	// Inside double quotes the input's word begins at the quote
+	assert.Equal(t, []string{"\"$(cat $(git checkout", "\"$(cat $(git cherry-pick"}, complete("echo \"$(cat $(git ch"))
+}
+
+func TestCompletionsAfterEnvAssignments(t *testing.T) {
+	origOsReadDir := osReadDir
+	osReadDir = mockOsReadDir
+	defer func() {
+		osReadDir = origOsReadDir
+	}()
+
+	runner, _ := interp.New(interp.StdIO(nil, nil, nil))
+	manager := &mockCompletionManager{}
+	spec := CompletionSpec{Command: "git", Type: WordListCompletion, Value: "checkout cherry-pick"}
+	manager.On("GetSpec", "git").Return(spec, true)
+	manager.On("GetSpec", mock.Anything).Return(CompletionSpec{}, false)
+	manager.On("ExecuteCompletion", mock.Anything, runner, spec, []string{"git", "ch"}).
+		Return([]shellinput.CompletionCandidate{{Value: "checkout"}, {Value: "cherry-pick"}}, nil)
+	provider := NewShellCompletionProvider(manager, runner)
+
+	complete := func(line string) []string {
+		return candidateValues(provider.GetCompletions(line, len(line)))
+	}
+
+	assert.Equal(t, []string{"checkout", "cherry-pick"}, complete("GIT_PAGER=cat git ch"))
+	assert.Equal(t, []string{"checkout", "cherry-pick"}, complete("FOO=1 BAR=\"a b\" git ch"))
+	assert.Equal(t, []string{"checkout", "cherry-pick"}, complete("echo $(FOO=1 git ch"))
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, []string{"/bin/cat"}, complete("FOO=1 BAR=2 /bin/c"))
+	}
+	assert.Empty(t, complete("FOO=1 "))
+}
+
+func TestCompletionsAfterRedirection(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt"), nil, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.log"), nil, 0644))
+
+	runner, _ := interp.New(interp.StdIO(nil, nil, nil))
+	runner.Vars = map[string]expand.Variable{
+		"PWD":                      {Kind: expand.String, Str: dir},
+		"GSH_FILE_COMPLETION_SORT": {Kind: expand.String, Str: "name"},
+	}
+	manager := &mockCompletionManager{}
+	spec := CompletionSpec{Command: "make", Type: WordListCompletion, Value: "build test"}
+	manager.On("GetSpec", "make").Return(spec, true)
+	manager.On("GetSpec", mock.Anything).Return(CompletionSpec{}, false)
+	provider := NewShellCompletionProvider(manager, runner)
+
+	complete := func(line string) []string {
+		return candidateValues(provider.GetCompletions(line, len(line)))
+	}
+
+	// The redirection target is a file, even for commands with their own completions
+	for _, operator := range []string{">", ">>", "2>", "&>", "<"} {
+		assert.Equal(t, []string{"other.log", "out.txt"}, complete("make "+operator+" "), operator)
+		assert.Equal(t, []string{"out.txt"}, complete("make "+operator+" ou"), operator)
+	}
+	assert.Equal(t, []string{"other.log", "out.txt"}, complete("> "))
+	assert.Equal(t, []string{"out.txt"}, complete("FOO=1 make > ou"))
+
+	// An operator without a space stays in front of the path
+	completions := provider.GetCompletions("make 2>ou", 9)
+	assert.Equal(t, []string{"2>out.txt"}, candidateValues(completions))
+	assert.Equal(t, "out.txt", completions[0].Display)
+
+	manager.AssertNotCalled(t, "ExecuteCompletion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetCompletionsAsync(t *testing.T) {
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+	var _ shellinput.AsyncCompletionProvider = provider
+
+	var batches []shellinput.CompletionBatch
+	for batch := range provider.GetCompletionsAsync(context.Background(), "@!localenv ", 11) {
+		batches = append(batches, batch)
+	}
+	assert.Len(t, batches, 1)
+	assert.Equal(t, []string{"@!localenv allow", "@!localenv deny", "@!localenv status"}, candidateValues(batches[0].Candidates))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range provider.GetCompletionsAsync(ctx, "@!localenv ", 11) {
+	}
+}
+
+func TestCancelWaitsForBackgroundCompletions(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	manager := &mockCompletionManager{}
+	manager.On("GetSpec", "slow").Return(CompletionSpec{Command: "slow", Type: FunctionCompletion, Value: "_slow"}, true)
+	manager.On("ExecuteCompletion", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			close(started)
+			// Stands in for a completion function noticing it was canceled
+			select {
+			case <-args.Get(0).(context.Context).Done():
+			case <-release:
+			}
+		}).
+		Return([]shellinput.CompletionCandidate{{Value: "late"}}, nil)
+	provider := NewShellCompletionProvider(manager, nil)
+
+	batches := provider.GetCompletionsAsync(context.Background(), "slow ", 5)
+	<-started
+	provider.Cancel()
+
+	// The completion has returned without sending its candidates
+	_, ok := <-batches
+	assert.False(t, ok)
+	close(release)
+}
+
+func TestAsyncCompletionStreamsEachSource(t *testing.T) {
+	t.Setenv("GSH_COMPLETION_SOURCES", `["slow","fast","file"]`)
+	release := make(chan struct{})
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+	provider.sources["slow"] = completionSourceFunc{"slow", func(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+		<-release
+		return []shellinput.CompletionCandidate{{Value: "deploy-late"}, {Value: "deploy-now"}}, true
+	}}
+	provider.sources["fast"] = completionSourceFunc{"fast", func(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+		return []shellinput.CompletionCandidate{{Value: "deploy-now"}}, true
+	}}
+
+	batches := provider.GetCompletionsAsync(context.Background(), "deploy ", 7)
+
+	// The fast source doesn't wait for the slow one before it
+	select {
+	case batch := <-batches:
+		assert.Equal(t, []string{"deploy-now"}, candidateValues(batch.Candidates))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fast source was held back by the slow one")
+	}
+
+	// The slow source is merged in once it answers, without the candidates already sent
+	close(release)
+	batch, ok := <-batches
+	assert.True(t, ok)
+	assert.Equal(t, []string{"deploy-late"}, candidateValues(batch.Candidates))
+	_, ok = <-batches
+	assert.False(t, ok)
+}
+
+func TestLocalEnvControlCompletions(t *testing.T) {
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+
+	completions := provider.GetCompletions("@!localenv ", 11)
+	assert.Equal(t, []string{"@!localenv allow", "@!localenv deny", "@!localenv status"}, candidateValues(completions))
+
+	completions = provider.GetCompletions("@!localenv a", 12)
+	assert.Equal(t, []string{"@!localenv allow"}, candidateValues(completions))
+}
+
+func TestAnalyticsControlCompletions(t *testing.T) {
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+
+	completions := provider.GetCompletions("@!analytics ", 12)
+	assert.Equal(t, []string{"@!analytics mismatches"}, candidateValues(completions))
+
+	completions = provider.GetCompletions("@!analytics mis", 15)
+	assert.Equal(t, []string{"@!analytics mismatches"}, candidateValues(completions))
+}
+
+func TestToggleControlCompletions(t *testing.T) {
+	provider := NewShellCompletionProvider(&mockCompletionManager{}, nil)
+
+	completions := provider.GetCompletions("@!toggle ", 9)
+	values := candidateValues(completions)
+	assert.Equal(t, "@!toggle --persist", values[0])
+	assert.Contains(t, values, "@!toggle GSH_FOCUS_MODE")
+
+	completions = provider.GetCompletions("@!toggle gsh_fo", 15)
+	assert.Equal(t, []string{"@!toggle GSH_FOCUS_MODE"}, candidateValues(completions))
+
+	completions = provider.GetCompletions("@!toggle --persist GSH_TR", 25)
+	assert.Equal(t, []string{"@!toggle --persist GSH_TRANSCRIPT"}, candidateValues(completions))
+
+	completions = provider.GetCompletions("@!toggle GSH_FOCUS_MODE ", 24)
+	assert.Empty(t, completions)
+}
+
+func TestCompletionMaxCandidates(t *testing.T) {
+	runner, _ := interp.New(interp.StdIO(nil, nil, nil))
+	runner.Vars = map[string]expand.Variable{
+		"GSH_COMPLETION_MAX_CANDIDATES": {Kind: expand.String, Str: "2"},
+	}
+	manager := NewCompletionManager()
+	manager.AddSpec(CompletionSpec{Command: "deploy", Type: WordListCompletion, Value: "app-alpha app-beta app-delta app-gamma"})
+	provider := NewShellCompletionProvider(manager, runner)
+
+	batch, _ := provider.complete(context.Background(), "deploy app-", 11)
+	assert.Equal(t, []shellinput.CompletionCandidate{{Value: "app-alpha"}, {Value: "app-beta"}}, batch.Candidates)
+	assert.Equal(t, 2, batch.Omitted)
+
+	// Nothing is left out when the candidates fit
+	batch, _ = provider.complete(context.Background(), "deploy app-d", 12)
+	assert.Len(t, batch.Candidates, 1)
+	assert.Equal(t, 0, batch.Omitted)
+
+	runner.Vars["GSH_COMPLETION_MAX_CANDIDATES"] = expand.Variable{Kind: expand.String, Str: "0"}
+	batch, _ = provider.complete(context.Background(), "deploy app-", 11)
+	assert.Len(t, batch.Candidates, 4)
+	assert.Equal(t, 0, batch.Omitted)
+}
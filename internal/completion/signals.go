@@ -0,0 +1,105 @@
+package completion
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+// signalDescriptions are the standard signals, without the SIG prefix. Those
+// the platform doesn't define are left out of completions.
+var signalDescriptions = map[string]string{
+	"HUP":    "hangup",
+	"INT":    "interrupt",
+	"QUIT":   "quit",
+	"ILL":    "illegal instruction",
+	"TRAP":   "trace trap",
+	"ABRT":   "abort",
+	"EMT":    "emulator trap",
+	"BUS":    "bus error",
+	"FPE":    "floating point exception",
+	"KILL":   "kill, can't be caught",
+	"USR1":   "user defined 1",
+	"SEGV":   "segmentation fault",
+	"USR2":   "user defined 2",
+	"PIPE":   "broken pipe",
+	"ALRM":   "alarm clock",
+	"TERM":   "terminate",
+	"STKFLT": "stack fault",
+	"CHLD":   "child status changed",
+	"CONT":   "continue",
+	"STOP":   "stop, can't be caught",
+	"TSTP":   "terminal stop",
+	"TTIN":   "background read from terminal",
+	"TTOU":   "background write to terminal",
+	"URG":    "urgent socket data",
+	"XCPU":   "CPU time limit exceeded",
+	"XFSZ":   "file size limit exceeded",
+	"VTALRM": "virtual alarm clock",
+	"PROF":   "profiling timer expired",
+	"WINCH":  "window size changed",
+	"IO":     "I/O possible",
+	"INFO":   "status request",
+	"PWR":    "power failure",
+	"SYS":    "bad system call",
+}
+
+// completeSignals completes signal names for kill, pkill and killall, either
+// as `-TERM` or after `-s` and `--signal`. It returns nil when the current word
+// isn't a signal, so other completions get a chance.
+func completeSignals(args []string, line string) []shellinput.CompletionCandidate {
+	currentWord := ""
+	preceding := args
+	if !strings.HasSuffix(line, " ") && len(args) > 0 {
+		currentWord = args[len(args)-1]
+		preceding = args[:len(args)-1]
+	}
+
+	var prefix, name string
+	switch {
+	case len(preceding) > 0 && (preceding[len(preceding)-1] == "-s" || preceding[len(preceding)-1] == "--signal"):
+		// Names after -s are matched in any case, since there are no flags to mix up
+		name = strings.ToUpper(currentWord)
+	case strings.HasPrefix(currentWord, "-") && !strings.HasPrefix(currentWord, "--"):
+		// Lowercase words after a dash are flags like pkill -f, so match case
+		prefix = "-"
+		name = currentWord[1:]
+	default:
+		return nil
+	}
+	if strings.HasPrefix(name, "SIG") {
+		prefix += "SIG"
+		name = name[3:]
+	}
+
+	type signal struct {
+		name   string
+		number int
+	}
+	var matches []signal
+	for candidate := range signalDescriptions {
+		if number := signalNumber("SIG" + candidate); number > 0 && strings.HasPrefix(candidate, name) {
+			matches = append(matches, signal{candidate, number})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].number != matches[j].number {
+			return matches[i].number < matches[j].number
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	candidates := make([]shellinput.CompletionCandidate, len(matches))
+	for i, match := range matches {
+		candidates[i] = shellinput.CompletionCandidate{
+			Value:       prefix + match.name,
+			Description: fmt.Sprintf("%d %s", match.number, signalDescriptions[match.name]),
+		}
+	}
+	return candidates
+}
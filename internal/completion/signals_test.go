@@ -0,0 +1,43 @@
+//go:build !windows
+
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestCompleteSignals(t *testing.T) {
+	got := completeSignals([]string{"-K"}, "kill -K")
+	require.Len(t, got, 1)
+	assert.Equal(t, "-KILL", got[0].Value)
+	assert.Equal(t, "9 kill, can't be caught", got[0].Description)
+
+	got = completeSignals([]string{"-SIGTE"}, "kill -SIGTE")
+	assert.Equal(t, []string{"-SIGTERM"}, candidateValues(got))
+
+	got = completeSignals([]string{"-"}, "kill -")
+	require.NotEmpty(t, got)
+	assert.Equal(t, "-HUP", got[0].Value, "signals are listed by number")
+	assert.Contains(t, candidateValues(got), "-STOP")
+}
+
+func TestCompleteSignalsAfterSignalFlag(t *testing.T) {
+	got := completeSignals([]string{"-s"}, "kill -s ")
+	assert.Contains(t, candidateValues(got), "TERM")
+
+	got = completeSignals([]string{"--signal", "sto"}, "pkill --signal sto")
+	require.Len(t, got, 1)
+	assert.Equal(t, "STOP", got[0].Value)
+	assert.Equal(t, int(unix.SIGSTOP), signalNumber("SIGSTOP"))
+}
+
+func TestCompleteSignalsLeavesOtherWords(t *testing.T) {
+	assert.Nil(t, completeSignals([]string{}, "kill "))
+	assert.Nil(t, completeSignals([]string{"12"}, "kill 12"))
+	assert.Nil(t, completeSignals([]string{"-f"}, "pkill -f"), "lowercase words are pkill flags")
+	assert.Nil(t, completeSignals([]string{"--signal"}, "killall --signal"))
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package completion
+
+import "golang.org/x/sys/unix"
+
+// signalNumber returns the number of the named signal on this platform, or 0
+// if it doesn't have one
+func signalNumber(name string) int {
+	return int(unix.SignalNum(name))
+}
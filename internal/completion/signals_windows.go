@@ -0,0 +1,18 @@
+//go:build windows
+
+package completion
+
+// windowsSignals are the POSIX numbers of the signals that tools like the
+// kill of Git for Windows and Cygwin accept
+var windowsSignals = map[string]int{
+	"SIGHUP":  1,
+	"SIGINT":  2,
+	"SIGQUIT": 3,
+	"SIGKILL": 9,
+	"SIGTERM": 15,
+}
+
+// signalNumber returns the number of the named signal, or 0 if it isn't known
+func signalNumber(name string) int {
+	return windowsSignals[name]
+}
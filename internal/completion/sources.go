@@ -0,0 +1,317 @@
+package completion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+// CompletionRequest describes the command line being completed
+type CompletionRequest struct {
+	Words   []string // Words of the line up to the cursor, preserving quotes
+	Command string   // The first word
+	Args    []string // Words after the command
+	Line    string   // The line up to the cursor
+	Pos     int
+}
+
+// CompletionSource is one step of the completion pipeline. Complete returns the
+// candidates it found and whether the pipeline should stop at this source.
+type CompletionSource interface {
+	Name() string
+	Complete(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool)
+}
+
+// completionSourceFunc adapts a function to the CompletionSource interface
+type completionSourceFunc struct {
+	name     string
+	complete func(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool)
+}
+
+func (s completionSourceFunc) Name() string {
+	return s.name
+}
+
+func (s completionSourceFunc) Complete(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	return s.complete(ctx, req)
+}
+
+// defaultCompletionSources is the order sources are tried in unless
+// GSH_COMPLETION_SOURCES says otherwise
+var defaultCompletionSources = []string{"spec", "z", "git", "docker", "tmux", "package", "default", "archive", "static", "man", "global", "command", "correction", "file"}
+
+// completionSourceAliases maps alternative names users may configure to source names
+var completionSourceAliases = map[string]string{
+	"carapace": "global",
+	"builtin":  "default",
+}
+
+// buildCompletionSources creates the named sources of the completion pipeline
+func (p *ShellCompletionProvider) buildCompletionSources() map[string]CompletionSource {
+	sources := []CompletionSource{
+		completionSourceFunc{"spec", p.completeFromSpec},
+		completionSourceFunc{"z", p.completeDirectoryJumps},
+		completionSourceFunc{"git", p.completeFromGit},
+		completionSourceFunc{"docker", p.completeFromDocker},
+		completionSourceFunc{"tmux", p.completeFromTmux},
+		completionSourceFunc{"package", p.completeFromPackages},
+		completionSourceFunc{"default", p.completeFromDefaults},
+		completionSourceFunc{"archive", p.completeFromArchive},
+		completionSourceFunc{"static", p.completeFromStatic},
+		completionSourceFunc{"man", p.completeFromManPage},
+		completionSourceFunc{"global", p.completeFromGlobalCompleter},
+		completionSourceFunc{"command", p.completeCommandNames},
+		completionSourceFunc{"correction", p.completeCommandCorrections},
+		completionSourceFunc{"file", p.completeFilePaths},
+	}
+
+	byName := make(map[string]CompletionSource, len(sources))
+	for _, source := range sources {
+		byName[source.Name()] = source
+	}
+	return byName
+}
+
+// completionSources returns the sources to try, in the order configured through
+// GSH_COMPLETION_SOURCES, a JSON array of source names. Sources left out of the
+// list are disabled. Invalid configuration falls back to the default order.
+func (p *ShellCompletionProvider) completionSources() []CompletionSource {
+	var configured string
+	if p.Runner != nil {
+		configured = p.Runner.Vars["GSH_COMPLETION_SOURCES"].String()
+	} else {
+		configured = os.Getenv("GSH_COMPLETION_SOURCES")
+	}
+
+	names := defaultCompletionSources
+	if strings.TrimSpace(configured) != "" {
+		var parsed []string
+		if err := json.Unmarshal([]byte(configured), &parsed); err == nil {
+			names = parsed
+		}
+	}
+
+	seen := make(map[string]bool, len(names))
+	result := make([]CompletionSource, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := completionSourceAliases[name]; ok {
+			name = alias
+		}
+		source, ok := p.sources[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, source)
+	}
+	return result
+}
+
+// completeFromSpec runs an explicit completion spec registered with `complete`
+func (p *ShellCompletionProvider) completeFromSpec(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	spec, ok := p.CompletionManager.GetSpec(req.Command)
+	if !ok {
+		return nil, false
+	}
+
+	suggestions, err := p.CompletionManager.ExecuteCompletion(ctx, p.Runner, spec, req.Words, req.Line, req.Pos)
+	if err != nil || suggestions == nil {
+		return nil, false
+	}
+	// A spec that ran successfully is authoritative, even with no results
+	return suggestions, true
+}
+
+// completeDirectoryJumps offers the ranked matches of `z <query>`
+func (p *ShellCompletionProvider) completeDirectoryJumps(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	if req.Command != "z" || p.DirectoryProvider == nil {
+		return nil, false
+	}
+
+	// Only the first argument is completed, since the match replaces the query
+	var query []string
+	switch {
+	case len(req.Args) == 0 && strings.HasSuffix(req.Line, " "):
+	case len(req.Args) == 1 && !strings.HasSuffix(req.Line, " "):
+		if strings.HasPrefix(req.Args[0], "-") {
+			return nil, false
+		}
+		query = req.Args
+	default:
+		return nil, false
+	}
+
+	matches, err := p.DirectoryProvider.RankDirectories(query)
+	if err != nil || len(matches) == 0 {
+		return nil, false
+	}
+
+	candidates := make([]shellinput.CompletionCandidate, len(matches))
+	for i, match := range matches {
+		candidates[i] = shellinput.CompletionCandidate{
+			Value:       match.Directory,
+			Description: fmt.Sprintf("frecency %.1f", match.Score),
+		}
+	}
+	return candidates, true
+}
+
+func (p *ShellCompletionProvider) completeFromGit(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	if req.Command != "git" {
+		return nil, false
+	}
+	suggestions := p.gitCompleter.GetCompletions(req.Args, req.Line)
+	return suggestions, len(suggestions) > 0
+}
+
+// completeFromDocker completes container and image names from the docker daemon
+func (p *ShellCompletionProvider) completeFromDocker(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	if req.Command != "docker" {
+		return nil, false
+	}
+	suggestions := p.dockerCompleter.GetCompletions(req.Args, req.Line)
+	return suggestions, len(suggestions) > 0
+}
+
+// completeFromTmux completes tmux subcommands and the sessions and windows they target
+func (p *ShellCompletionProvider) completeFromTmux(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	if req.Command != "tmux" {
+		return nil, false
+	}
+	suggestions := p.tmuxCompleter.GetCompletions(req.Args, req.Line)
+	return suggestions, len(suggestions) > 0
+}
+
+// completeFromPackages completes package names for apt, brew and dnf
+func (p *ShellCompletionProvider) completeFromPackages(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	suggestions := p.packageCompleter.GetCompletions(filepath.Base(req.Command), req.Args, req.Line)
+	return suggestions, len(suggestions) > 0
+}
+
+// completeFromDefaults handles cd, ssh, make, etc.
+func (p *ShellCompletionProvider) completeFromDefaults(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	suggestions, found := p.defaultCompleter.GetCompletions(req.Command, req.Args, req.Line, req.Pos)
+	// Found but nil means the command is known but has nothing to offer, so keep going
+	return suggestions, found && suggestions != nil
+}
+
+func (p *ShellCompletionProvider) completeFromArchive(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	suggestions := p.archiveCompleter.GetCompletions(req.Command, req.Args, req.Line, environment.GetPwd(p.Runner))
+	return suggestions, len(suggestions) > 0
+}
+
+// completeFromStatic handles docker, npm, etc.
+func (p *ShellCompletionProvider) completeFromStatic(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	suggestions := p.staticCompleter.GetCompletions(req.Command, req.Args)
+	return suggestions, len(suggestions) > 0
+}
+
+// completeFromManPage completes flags documented in the command's man page, if GSH_COMPLETION_MAN is on
+func (p *ShellCompletionProvider) completeFromManPage(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	if p.Runner == nil || !environment.GetCompletionManEnabled(p.Runner) {
+		return nil, false
+	}
+	suggestions := p.manPageCompleter.GetCompletions(req.Command, req.Args, req.Line)
+	return suggestions, len(suggestions) > 0
+}
+
+// completeFromGlobalCompleter uses GSH_COMPLETION_COMMAND, or carapace if it's installed
+func (p *ShellCompletionProvider) completeFromGlobalCompleter(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	globalCompleter := os.Getenv("GSH_COMPLETION_COMMAND")
+	if globalCompleter == "" {
+		// Auto-discovery: Check for carapace
+		if path, err := execLookPath("carapace"); err == nil {
+			globalCompleter = path
+		}
+	}
+	if globalCompleter == "" {
+		return nil, false
+	}
+
+	// Create a temporary spec for the global completer
+	globalSpec := CompletionSpec{
+		Command: req.Command,
+		Type:    CommandCompletion,
+		Value:   globalCompleter,
+	}
+
+	suggestions, err := p.CompletionManager.ExecuteCompletion(ctx, p.Runner, globalSpec, req.Words, req.Line, req.Pos)
+	if err != nil {
+		return nil, false
+	}
+	return suggestions, len(suggestions) > 0
+}
+
+// completeCommandNames completes the command itself, from PATH or a path prefix
+func (p *ShellCompletionProvider) completeCommandNames(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	if len(req.Words) != 1 || strings.HasSuffix(req.Line, " ") {
+		return nil, false
+	}
+
+	var completions []shellinput.CompletionCandidate
+	if p.isPathBasedCommand(req.Command) {
+		// For path-based commands, complete with executable files in that path
+		completions = toCandidates(p.getExecutableCompletions(req.Command))
+	} else {
+		completions = p.getAvailableCommands(req.Command)
+	}
+	if len(completions) == 0 {
+		return nil, false
+	}
+	return completions, true
+}
+
+// completeCommandCorrections offers the commands close to a command name that
+// matches nothing, so a typo like `gti` completes to `git`
+func (p *ShellCompletionProvider) completeCommandCorrections(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	if len(req.Words) != 1 || strings.HasSuffix(req.Line, " ") || p.isPathBasedCommand(req.Command) {
+		return nil, false
+	}
+	corrections := p.getCommandCorrections(req.Command)
+	return corrections, len(corrections) > 0
+}
+
+// completeFilePaths completes the current word as a file path
+func (p *ShellCompletionProvider) completeFilePaths(ctx context.Context, req *CompletionRequest) ([]shellinput.CompletionCandidate, bool) {
+	var prefix string
+	if len(req.Words) > 1 {
+		// Get the last word as the prefix for file completion
+		prefix = req.Words[len(req.Words)-1]
+	} else if strings.HasSuffix(req.Line, " ") {
+		// If line ends with space, use empty prefix to list all files
+		prefix = ""
+	} else {
+		return nil, false
+	}
+
+	return p.fileCandidates(prefix, req.Command), true
+}
+
+// fileCandidates returns the paths word may complete to as an argument of command
+func (p *ShellCompletionProvider) fileCandidates(word string, command string) []shellinput.CompletionCandidate {
+	// Complete the path the quoted word stands for, e.g. `"my fil` is `my fil`
+	prefix, openQuote := unquoteWord(word)
+
+	currentDirectory := environment.GetPwd(p.Runner)
+	completions := getFileCompletions(prefix, currentDirectory)
+	if environment.GetCompletionRespectGitignore(p.Runner) && gitIgnoreCommands[filepath.Base(command)] {
+		completions = p.gitIgnore.filter(completions, currentDirectory)
+	}
+
+	sortMode := fileSortModeFor(environment.GetFileCompletionSort(p.Runner), command)
+	sortFileCandidates(completions, sortMode, currentDirectory)
+
+	// Quote completions that contain spaces or continue an open quote, but don't
+	// add command prefix. The completion handler replaces the whole current word.
+	for i, completion := range completions {
+		completions[i].Value = quoteCompletion(completion.Value, openQuote, completion.Suffix != "")
+	}
+	return completions
+}
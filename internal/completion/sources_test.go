@@ -0,0 +1,164 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/bash"
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func newSourcesTestProvider(t *testing.T, sources string) (*ShellCompletionProvider, *mockCompletionManager) {
+	runner, err := interp.New(interp.StdIO(nil, nil, nil))
+	require.NoError(t, err)
+	if sources != "" {
+		script := "GSH_COMPLETION_SOURCES='" + sources + "'"
+		require.NoError(t, bash.RunBashScriptFromReader(context.Background(), runner, strings.NewReader(script), "test"))
+	}
+
+	manager := &mockCompletionManager{}
+	return NewShellCompletionProvider(manager, runner), manager
+}
+
+func sourceNames(sources []CompletionSource) []string {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name()
+	}
+	return names
+}
+
+func TestCompletionSourcesOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  string
+		expected []string
+	}{
+		{
+			name:     "default order",
+			expected: defaultCompletionSources,
+		},
+		{
+			name:     "custom order with aliases, duplicates and unknown names",
+			sources:  `["spec", "Carapace", "bogus", "file", "spec"]`,
+			expected: []string{"spec", "global", "file"},
+		},
+		{
+			name:     "invalid configuration falls back to default",
+			sources:  `spec,file`,
+			expected: defaultCompletionSources,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, _ := newSourcesTestProvider(t, tt.sources)
+			assert.Equal(t, tt.expected, sourceNames(provider.completionSources()))
+		})
+	}
+}
+
+func TestGetCompletionsUsesConfiguredSources(t *testing.T) {
+	origGetFileCompletions := getFileCompletions
+	getFileCompletions = mockGetFileCompletions
+	defer func() { getFileCompletions = origGetFileCompletions }()
+
+	origExecLookPath := execLookPath
+	execLookPath = func(file string) (string, error) { return "", errors.New("not found") }
+	defer func() { execLookPath = origExecLookPath }()
+
+	// By default the static docker subcommands win over files
+	provider, manager := newSourcesTestProvider(t, "")
+	manager.On("GetSpec", "docker").Return(CompletionSpec{}, false)
+	completions := provider.GetCompletions("docker ", 7)
+	assert.Contains(t, candidateValues(completions), "ps")
+	assert.NotContains(t, candidateValues(completions), "file1.txt")
+
+	// Restricting the pipeline to files skips specs and built-ins entirely
+	provider, manager = newSourcesTestProvider(t, `["file"]`)
+	completions = provider.GetCompletions("docker ", 7)
+	assert.Equal(t, []string{"folder1", "folder2", "file1.txt", "file2.txt"}, candidateValues(completions))
+	manager.AssertNotCalled(t, "GetSpec", "docker")
+
+	// Disabling every source returns no completions
+	provider, _ = newSourcesTestProvider(t, `[]`)
+	assert.Equal(t, []shellinput.CompletionCandidate{}, provider.GetCompletions("docker ", 7))
+}
+
+func TestFileCompletionInsideOpenQuote(t *testing.T) {
+	var prefixes []string
+	origGetFileCompletions := getFileCompletions
+	getFileCompletions = func(prefix, currentDirectory string) []shellinput.CompletionCandidate {
+		prefixes = append(prefixes, prefix)
+		return []shellinput.CompletionCandidate{
+			{Value: "my big file.txt"},
+			{Value: "my big folder", Suffix: "/"},
+		}
+	}
+	defer func() { getFileCompletions = origGetFileCompletions }()
+
+	provider, manager := newSourcesTestProvider(t, `["file"]`)
+	manager.On("GetSpec", "cat").Return(CompletionSpec{}, false)
+
+	line := `cat "my big f`
+	assert.Equal(t, []string{`"my big file.txt"`, `"my big folder`}, candidateValues(provider.GetCompletions(line, len(line))))
+
+	line = `cat 'my big f`
+	assert.Equal(t, []string{`'my big file.txt'`, `'my big folder`}, candidateValues(provider.GetCompletions(line, len(line))))
+
+	// Closed quotes and escaped spaces are completed without the quoting
+	line = `cat "my big"\ f`
+	assert.Equal(t, []string{`"my big file.txt"`, `"my big folder"`}, candidateValues(provider.GetCompletions(line, len(line))))
+
+	assert.Equal(t, []string{"my big f", "my big f", "my big f"}, prefixes)
+}
+
+type mockDirectoryProvider struct {
+	queries [][]string
+}
+
+func (m *mockDirectoryProvider) RankDirectories(query []string) ([]history.DirectoryMatch, error) {
+	m.queries = append(m.queries, query)
+	return []history.DirectoryMatch{
+		{Directory: "/home/user/src/project", Score: 12},
+		{Directory: "/home/user/old/project", Score: 1.5},
+	}, nil
+}
+
+func TestDirectoryJumpCompletions(t *testing.T) {
+	provider, manager := newSourcesTestProvider(t, "")
+	manager.On("GetSpec", "z").Return(CompletionSpec{}, false)
+	directories := &mockDirectoryProvider{}
+	provider.SetDirectoryProvider(directories)
+
+	completions := provider.GetCompletions("z proj", 6)
+	assert.Equal(t, []string{"/home/user/src/project", "/home/user/old/project"}, candidateValues(completions))
+	assert.Equal(t, "frecency 12.0", completions[0].Description)
+	assert.Equal(t, [][]string{{"proj"}}, directories.queries)
+
+	// Only the first argument is completed from history
+	provider.GetCompletions("z proj ", 7)
+	assert.Len(t, directories.queries, 1)
+}
+
+func TestGetCompletionResultsIncludeGroup(t *testing.T) {
+	provider, manager := newSourcesTestProvider(t, `["z"]`)
+	manager.On("GetSpec", "z").Return(CompletionSpec{}, false)
+	provider.SetDirectoryProvider(&mockDirectoryProvider{})
+
+	results := provider.GetCompletionResults("z proj", 6)
+	require.Len(t, results, 2)
+	assert.Equal(t, CompletionResult{
+		Value:       "/home/user/src/project",
+		Description: "frecency 12.0",
+		Group:       "z",
+	}, results[0])
+
+	assert.Empty(t, provider.GetCompletionResults("unknown ", 8))
+}
@@ -0,0 +1,424 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"gopkg.in/yaml.v3"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// specFileCommandTimeout bounds the commands that list argument values, so a
+// slow one doesn't freeze completion
+const specFileCommandTimeout = 2 * time.Second
+
+// CommandSpec is a command declared in a YAML completion file, with its flags,
+// arguments and subcommands. Flags of a command also apply to its subcommands.
+//
+//	name: mytool
+//	flags:
+//	  - name: --verbose
+//	    short: -v
+//	    description: Print more output
+//	subcommands:
+//	  - name: deploy
+//	    description: Deploy the app
+//	    flags:
+//	      - name: --env
+//	        value: [staging, production]
+//	    args: directories
+type CommandSpec struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	Aliases     []string      `yaml:"aliases"`
+	Flags       []FlagSpec    `yaml:"flags"`
+	Args        *ArgSpec      `yaml:"args"`
+	Subcommands []CommandSpec `yaml:"subcommands"`
+}
+
+// FlagSpec is a flag of a CommandSpec. Value is set for flags that take one.
+type FlagSpec struct {
+	Name        string   `yaml:"name"`
+	Short       string   `yaml:"short"`
+	Description string   `yaml:"description"`
+	Value       *ArgSpec `yaml:"value"`
+}
+
+// ArgSpec describes what an argument or flag value completes to. In YAML it's
+// either a type name such as `files`, a list of values, or a mapping with
+// type, values and command.
+type ArgSpec struct {
+	// Type is files, directories, values, command or none
+	Type   string   `yaml:"type"`
+	Values []string `yaml:"values"`
+	// Command is run with sh for the command type. Each line of its output is a
+	// value, optionally followed by a tab and a description.
+	Command string `yaml:"command"`
+}
+
+func (a *ArgSpec) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Decode(&a.Type)
+	case yaml.SequenceNode:
+		a.Type = "values"
+		return node.Decode(&a.Values)
+	default:
+		type plain ArgSpec
+		if err := node.Decode((*plain)(a)); err != nil {
+			return err
+		}
+		if a.Type == "" {
+			switch {
+			case a.Command != "":
+				a.Type = "command"
+			case len(a.Values) > 0:
+				a.Type = "values"
+			}
+		}
+		return nil
+	}
+}
+
+// validate checks the argument types, naming the spec element in errors
+func (a *ArgSpec) validate(where string) error {
+	if a == nil {
+		return nil
+	}
+	switch a.Type {
+	case "files", "directories", "values", "none":
+		return nil
+	case "command":
+		if a.Command == "" {
+			return fmt.Errorf("%s: command type requires a command", where)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s: unknown argument type %q, expected files, directories, values, command or none", where, a.Type)
+	}
+}
+
+func (c *CommandSpec) validate(where string) error {
+	if c.Name == "" {
+		return fmt.Errorf("%s: missing name", where)
+	}
+	where += " " + c.Name
+	if err := c.Args.validate(where); err != nil {
+		return err
+	}
+	for _, flag := range c.Flags {
+		if flag.Name == "" && flag.Short == "" {
+			return fmt.Errorf("%s: flag without a name", where)
+		}
+		if err := flag.Value.validate(where + " " + flag.names()[0]); err != nil {
+			return err
+		}
+	}
+	for i := range c.Subcommands {
+		if err := c.Subcommands[i].validate(where); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f FlagSpec) names() []string {
+	var names []string
+	if f.Name != "" {
+		names = append(names, f.Name)
+	}
+	if f.Short != "" {
+		names = append(names, f.Short)
+	}
+	return names
+}
+
+func (c *CommandSpec) findSubcommand(word string) *CommandSpec {
+	for i := range c.Subcommands {
+		sub := &c.Subcommands[i]
+		if sub.Name == word || containsString(sub.Aliases, word) {
+			return sub
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCommandSpec parses and validates a YAML completion file
+func ParseCommandSpec(data []byte) (*CommandSpec, error) {
+	var spec CommandSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	if err := spec.validate("command"); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// LoadSpecFiles registers the commands declared in the .yaml and .yml files of
+// dir, and returns their names. A missing directory is not an error, and
+// invalid files are skipped and reported in the returned error.
+func (m *CompletionManager) LoadSpecFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []string
+	var errs []error
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		spec, err := ParseCommandSpec(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		m.AddCommandSpec(spec)
+		loaded = append(loaded, spec.Name)
+	}
+	return loaded, errors.Join(errs...)
+}
+
+// AddCommandSpec registers a declarative spec for its command and aliases,
+// replacing any earlier spec for them
+func (m *CompletionManager) AddCommandSpec(spec *CommandSpec) {
+	for _, name := range append([]string{spec.Name}, spec.Aliases...) {
+		m.commandSpecs[name] = spec
+		m.AddSpec(CompletionSpec{Command: name, Type: SpecFileCompletion, Value: spec.Name})
+	}
+}
+
+// completeFromCommandSpec completes the line from a declarative spec. It
+// returns nil when the spec says nothing about the current word, so other
+// completion sources get a chance.
+func completeFromCommandSpec(ctx context.Context, runner *interp.Runner, spec *CommandSpec, args []string, line string) []shellinput.CompletionCandidate {
+	if spec == nil || len(args) == 0 {
+		return nil
+	}
+
+	words := args[1:]
+	currentWord := ""
+	preceding := words
+	if !strings.HasSuffix(line, " ") && len(words) > 0 {
+		currentWord = words[len(words)-1]
+		preceding = words[:len(words)-1]
+	}
+
+	// Walk the words before the cursor to find the subcommand and whether
+	// the current word is the value of a flag
+	path := []*CommandSpec{spec}
+	var pendingFlag *FlagSpec
+	positional := 0
+	for _, word := range preceding {
+		if pendingFlag != nil {
+			pendingFlag = nil
+			continue
+		}
+		if strings.HasPrefix(word, "-") && word != "-" {
+			name, _, hasValue := strings.Cut(word, "=")
+			if flag := findFlag(path, name); flag != nil && flag.Value != nil && !hasValue {
+				pendingFlag = flag
+			}
+			continue
+		}
+		if positional == 0 {
+			if sub := path[len(path)-1].findSubcommand(word); sub != nil {
+				path = append(path, sub)
+				continue
+			}
+		}
+		positional++
+	}
+	node := path[len(path)-1]
+
+	if pendingFlag != nil {
+		return completeArgSpec(ctx, runner, pendingFlag.Value, currentWord, "")
+	}
+
+	if strings.HasPrefix(currentWord, "-") {
+		if name, value, ok := strings.Cut(currentWord, "="); ok {
+			if flag := findFlag(path, name); flag != nil && flag.Value != nil {
+				return completeArgSpec(ctx, runner, flag.Value, value, name+"=")
+			}
+			return nil
+		}
+		return completeFlags(path, currentWord)
+	}
+
+	candidates := []shellinput.CompletionCandidate{}
+	if positional == 0 {
+		for _, sub := range node.Subcommands {
+			if strings.HasPrefix(sub.Name, currentWord) {
+				candidates = append(candidates, shellinput.CompletionCandidate{Value: sub.Name, Description: sub.Description})
+			}
+		}
+	}
+	if node.Args != nil {
+		candidates = append(candidates, completeArgSpec(ctx, runner, node.Args, currentWord, "")...)
+	} else if len(candidates) == 0 {
+		return nil
+	}
+	return candidates
+}
+
+// findFlag looks for a flag of the subcommand or any command above it
+func findFlag(path []*CommandSpec, name string) *FlagSpec {
+	for i := len(path) - 1; i >= 0; i-- {
+		for j := range path[i].Flags {
+			if containsString(path[i].Flags[j].names(), name) {
+				return &path[i].Flags[j]
+			}
+		}
+	}
+	return nil
+}
+
+// completeFlagValue completes the value of one of flags, after `--flag=` in
+// the current word or as the word after `--flag`. It returns false when the
+// current word isn't the value of one of them.
+func completeFlagValue(ctx context.Context, runner *interp.Runner, flags []FlagSpec, args []string, line string) ([]shellinput.CompletionCandidate, bool) {
+	if len(flags) == 0 || len(args) == 0 {
+		return nil, false
+	}
+	path := []*CommandSpec{{Flags: flags}}
+
+	words := args[1:]
+	currentWord := ""
+	preceding := words
+	if !strings.HasSuffix(line, " ") && len(words) > 0 {
+		currentWord = words[len(words)-1]
+		preceding = words[:len(words)-1]
+	}
+
+	if strings.HasPrefix(currentWord, "-") {
+		name, value, ok := strings.Cut(currentWord, "=")
+		if !ok {
+			return nil, false
+		}
+		if flag := findFlag(path, name); flag != nil && flag.Value != nil {
+			return completeArgSpec(ctx, runner, flag.Value, value, name+"="), true
+		}
+		return nil, false
+	}
+	if len(preceding) > 0 {
+		if flag := findFlag(path, preceding[len(preceding)-1]); flag != nil && flag.Value != nil {
+			return completeArgSpec(ctx, runner, flag.Value, currentWord, ""), true
+		}
+	}
+	return nil, false
+}
+
+// completeFlags offers the flags of the subcommand and the commands above it
+func completeFlags(path []*CommandSpec, prefix string) []shellinput.CompletionCandidate {
+	seen := make(map[string]bool)
+	candidates := []shellinput.CompletionCandidate{}
+	for i := len(path) - 1; i >= 0; i-- {
+		for _, flag := range path[i].Flags {
+			for _, name := range flag.names() {
+				if seen[name] || !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				// A bare dash lists each flag once, by its long name
+				if name == flag.Short && flag.Name != "" && prefix == "-" {
+					continue
+				}
+				seen[name] = true
+				candidates = append(candidates, shellinput.CompletionCandidate{Value: name, Description: flag.Description})
+			}
+		}
+	}
+	return candidates
+}
+
+// completeArgSpec lists the values of an argument that start with prefix. The
+// insert prefix is prepended to each value, e.g. `--env=` for `--env=prod`.
+func completeArgSpec(ctx context.Context, runner *interp.Runner, arg *ArgSpec, prefix string, insert string) []shellinput.CompletionCandidate {
+	var candidates []shellinput.CompletionCandidate
+	switch arg.Type {
+	case "files", "directories":
+		for _, candidate := range getFileCompletions(prefix, specFileDirectory(runner)) {
+			if arg.Type == "directories" && candidate.Suffix == "" {
+				continue
+			}
+			candidates = append(candidates, candidate)
+		}
+	case "values":
+		for _, value := range arg.Values {
+			if strings.HasPrefix(value, prefix) {
+				candidates = append(candidates, shellinput.CompletionCandidate{Value: value})
+			}
+		}
+	case "command":
+		candidates = runSpecFileCommand(ctx, arg.Command, prefix)
+	}
+
+	for i := range candidates {
+		candidates[i].Value = insert + candidates[i].Value
+	}
+	if candidates == nil {
+		candidates = []shellinput.CompletionCandidate{}
+	}
+	return candidates
+}
+
+func specFileDirectory(runner *interp.Runner) string {
+	if runner != nil {
+		if pwd := environment.GetPwd(runner); pwd != "" {
+			return pwd
+		}
+	}
+	pwd, _ := os.Getwd()
+	return pwd
+}
+
+// runSpecFileCommand runs the command of a `command` argument and returns the
+// values it printed that start with prefix
+func runSpecFileCommand(ctx context.Context, command string, prefix string) []shellinput.CompletionCandidate {
+	ctx, cancel := context.WithTimeout(ctx, specFileCommandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+	if err != nil {
+		return nil
+	}
+
+	var candidates []shellinput.CompletionCandidate
+	for _, line := range strings.Split(string(out), "\n") {
+		value, description, _ := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+		value = strings.TrimSpace(value)
+		if value == "" || !strings.HasPrefix(value, prefix) {
+			continue
+		}
+		candidates = append(candidates, shellinput.CompletionCandidate{Value: value, Description: description})
+	}
+	return candidates
+}
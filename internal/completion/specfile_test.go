@@ -0,0 +1,144 @@
+package completion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCommandSpec = `
+name: mytool
+aliases: [mt]
+description: My tool
+flags:
+  - name: --verbose
+    short: -v
+    description: Print more output
+  - name: --config
+    description: Config file
+    value: files
+subcommands:
+  - name: deploy
+    description: Deploy the app
+    flags:
+      - name: --env
+        short: -e
+        description: Target environment
+        value: [staging, production]
+    args: none
+  - name: logs
+    description: Show logs
+    args:
+      command: printf 'api\tThe API\nweb\n'
+  - name: status
+    description: Show status
+`
+
+func TestParseCommandSpec(t *testing.T) {
+	spec, err := ParseCommandSpec([]byte(testCommandSpec))
+	require.NoError(t, err)
+	assert.Equal(t, "mytool", spec.Name)
+	assert.Len(t, spec.Subcommands, 3)
+	assert.Equal(t, &ArgSpec{Type: "files"}, spec.Flags[1].Value)
+	assert.Equal(t, &ArgSpec{Type: "values", Values: []string{"staging", "production"}}, spec.Subcommands[0].Flags[0].Value)
+	assert.Equal(t, "command", spec.Subcommands[1].Args.Type)
+
+	_, err = ParseCommandSpec([]byte("description: no name\n"))
+	assert.ErrorContains(t, err, "missing name")
+
+	_, err = ParseCommandSpec([]byte("name: tool\nargs: everything\n"))
+	assert.ErrorContains(t, err, `unknown argument type "everything"`)
+
+	_, err = ParseCommandSpec([]byte("name: tool\nflags:\n  - description: nameless\n"))
+	assert.ErrorContains(t, err, "flag without a name")
+}
+
+func TestCompleteFromCommandSpec(t *testing.T) {
+	spec, err := ParseCommandSpec([]byte(testCommandSpec))
+	require.NoError(t, err)
+
+	complete := func(args []string, line string) []shellinput.CompletionCandidate {
+		return completeFromCommandSpec(context.Background(), nil, spec, args, line)
+	}
+
+	assert.Equal(t, []shellinput.CompletionCandidate{
+		{Value: "deploy", Description: "Deploy the app"},
+		{Value: "logs", Description: "Show logs"},
+		{Value: "status", Description: "Show status"},
+	}, complete([]string{"mytool"}, "mytool "))
+
+	assert.Equal(t, []string{"deploy"}, candidateValues(complete([]string{"mytool", "de"}, "mytool de")))
+
+	// Flags of the parent apply to subcommands, and a bare dash lists long names
+	assert.Equal(t, []string{"--env", "--verbose", "--config"}, candidateValues(complete([]string{"mytool", "deploy", "-"}, "mytool deploy -")))
+	assert.Equal(t, []string{"-e"}, candidateValues(complete([]string{"mytool", "deploy", "-e"}, "mytool deploy -e")))
+
+	// Flag values, separate or after =
+	assert.Equal(t, []string{"staging", "production"}, candidateValues(complete([]string{"mytool", "deploy", "--env"}, "mytool deploy --env ")))
+	assert.Equal(t, []string{"production"}, candidateValues(complete([]string{"mytool", "deploy", "-e", "p"}, "mytool deploy -e p")))
+	assert.Equal(t, []string{"--env=staging"}, candidateValues(complete([]string{"mytool", "deploy", "--env=s"}, "mytool deploy --env=s")))
+
+	// A flag value isn't mistaken for a subcommand
+	assert.Equal(t, []string{"deploy", "logs", "status"}, candidateValues(complete([]string{"mytool", "-v"}, "mytool -v ")))
+
+	// Arguments from a command, with descriptions after a tab
+	assert.Equal(t, []shellinput.CompletionCandidate{
+		{Value: "api", Description: "The API"},
+		{Value: "web"},
+	}, complete([]string{"mytool", "logs"}, "mytool logs "))
+
+	// none stops completion, while an undeclared argument leaves it to other sources
+	assert.Equal(t, []shellinput.CompletionCandidate{}, complete([]string{"mytool", "deploy"}, "mytool deploy "))
+	assert.Nil(t, complete([]string{"mytool", "status"}, "mytool status "))
+}
+
+func TestCompleteFromCommandSpecFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "conf"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yaml"), nil, 0644))
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(oldWd) })
+
+	spec, err := ParseCommandSpec([]byte("name: tool\nflags:\n  - name: --dir\n    value: directories\nargs: files\n"))
+	require.NoError(t, err)
+
+	files := completeFromCommandSpec(context.Background(), nil, spec, []string{"tool"}, "tool ")
+	assert.ElementsMatch(t, []string{"app.yaml", "conf"}, candidateValues(files))
+
+	dirs := completeFromCommandSpec(context.Background(), nil, spec, []string{"tool", "--dir"}, "tool --dir ")
+	assert.Equal(t, []string{"conf"}, candidateValues(dirs))
+}
+
+func TestLoadSpecFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mytool.yaml"), []byte(testCommandSpec), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yml"), []byte("name: [\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644))
+
+	manager := NewCompletionManager()
+	loaded, err := manager.LoadSpecFiles(dir)
+	assert.Equal(t, []string{"mytool"}, loaded)
+	assert.ErrorContains(t, err, "broken.yml")
+
+	for _, name := range []string{"mytool", "mt"} {
+		spec, ok := manager.GetSpec(name)
+		require.True(t, ok, name)
+		assert.Equal(t, SpecFileCompletion, spec.Type)
+
+		completions, err := manager.ExecuteCompletion(context.Background(), nil, spec, []string{name, "st"}, name+" st", len(name)+3)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"status"}, candidateValues(completions))
+	}
+
+	loaded, err = NewCompletionManager().LoadSpecFiles(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, loaded)
+}
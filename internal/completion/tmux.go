@@ -0,0 +1,140 @@
+package completion
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+)
+
+const (
+	// tmuxCacheTTL is how long listings of sessions and windows are reused
+	tmuxCacheTTL = 5 * time.Second
+
+	// tmuxTimeout bounds each tmux call, so a stuck server doesn't freeze
+	// completion
+	tmuxTimeout = time.Second
+)
+
+// tmuxOutput runs tmux with the given arguments, can be replaced in tests
+var tmuxOutput = func(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tmuxTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "tmux", args...).Output()
+	return string(out), err
+}
+
+// tmuxSubcommands are the common tmux commands, completed as the first argument
+var tmuxSubcommands = []shellinput.CompletionCandidate{
+	{Value: "attach-session", Description: "Attach to a session"},
+	{Value: "detach-client", Description: "Detach the current client"},
+	{Value: "has-session", Description: "Check whether a session exists"},
+	{Value: "kill-server", Description: "Kill the server and all sessions"},
+	{Value: "kill-session", Description: "Kill a session"},
+	{Value: "kill-window", Description: "Kill a window"},
+	{Value: "list-sessions", Description: "List sessions"},
+	{Value: "list-windows", Description: "List the windows of a session"},
+	{Value: "new-session", Description: "Create a session"},
+	{Value: "new-window", Description: "Create a window"},
+	{Value: "rename-session", Description: "Rename a session"},
+	{Value: "rename-window", Description: "Rename a window"},
+	{Value: "select-window", Description: "Switch to a window"},
+	{Value: "source-file", Description: "Run the commands of a file"},
+	{Value: "split-window", Description: "Split a window into panes"},
+	{Value: "switch-client", Description: "Switch the client to another session"},
+}
+
+// tmuxListing is what the -t target of a tmux subcommand refers to
+type tmuxListing int
+
+const (
+	tmuxSessions tmuxListing = iota + 1
+	tmuxWindows
+)
+
+// tmuxTargets maps subcommands and their aliases to what their -t flag takes
+var tmuxTargets = map[string]tmuxListing{
+	"attach-session": tmuxSessions, "attach": tmuxSessions, "a": tmuxSessions, "at": tmuxSessions,
+	"has-session": tmuxSessions, "has": tmuxSessions,
+	"kill-session": tmuxSessions,
+	"list-windows": tmuxSessions, "lsw": tmuxSessions,
+	"rename-session": tmuxSessions, "rename": tmuxSessions,
+	"switch-client": tmuxSessions, "switchc": tmuxSessions,
+	"kill-window": tmuxWindows, "killw": tmuxWindows,
+	"new-window": tmuxWindows, "neww": tmuxWindows,
+	"rename-window": tmuxWindows, "renamew": tmuxWindows,
+	"select-window": tmuxWindows, "selectw": tmuxWindows,
+	"split-window": tmuxWindows, "splitw": tmuxWindows,
+}
+
+// TmuxCompleter completes tmux subcommands and the sessions and windows their
+// -t flag targets, by asking the tmux server
+type TmuxCompleter struct {
+	mu    sync.Mutex
+	cache map[tmuxListing]tmuxCacheEntry
+}
+
+type tmuxCacheEntry struct {
+	candidates []shellinput.CompletionCandidate
+	expires    time.Time
+}
+
+// GetCompletions returns the subcommands for the first argument of tmux, and
+// session or window names for the value of -t
+func (c *TmuxCompleter) GetCompletions(args []string, line string) []shellinput.CompletionCandidate {
+	currentWord := ""
+	preceding := args
+	if len(args) > 0 && !strings.HasSuffix(line, " ") {
+		currentWord = args[len(args)-1]
+		preceding = args[:len(args)-1]
+	}
+
+	if len(preceding) == 0 {
+		if strings.HasPrefix(currentWord, "-") {
+			return nil
+		}
+		return filterCandidates(tmuxSubcommands, currentWord)
+	}
+
+	listing, ok := tmuxTargets[preceding[0]]
+	if !ok || preceding[len(preceding)-1] != "-t" {
+		return nil
+	}
+	return filterCandidates(c.cached(listing), currentWord)
+}
+
+// cached returns the listing, asking tmux if there is none younger than tmuxCacheTTL
+func (c *TmuxCompleter) cached(listing tmuxListing) []shellinput.CompletionCandidate {
+	c.mu.Lock()
+	entry, ok := c.cache[listing]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.candidates
+	}
+
+	var out string
+	var err error
+	switch listing {
+	case tmuxSessions:
+		out, err = tmuxOutput("list-sessions", "-F", "#{session_name}|#{?session_attached,attached,detached}, #{session_windows} windows")
+	case tmuxWindows:
+		out, err = tmuxOutput("list-windows", "-a", "-F", "#{session_name}:#{window_index}|#{window_name}")
+	}
+	// Without a server there's nothing to complete
+	var candidates []shellinput.CompletionCandidate
+	if err == nil {
+		candidates = parseGitListing(out)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil {
+		c.cache = make(map[tmuxListing]tmuxCacheEntry)
+	}
+	c.cache[listing] = tmuxCacheEntry{candidates: candidates, expires: time.Now().Add(tmuxCacheTTL)}
+	return candidates
+}
@@ -0,0 +1,77 @@
+package completion
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockTmuxOutput(t *testing.T) *[]string {
+	var calls []string
+	orig := tmuxOutput
+	tmuxOutput = func(args ...string) (string, error) {
+		call := strings.Join(args, " ")
+		calls = append(calls, call)
+		switch {
+		case strings.HasPrefix(call, "list-sessions"):
+			return "work|attached, 3 windows\nscratch|detached, 1 windows\n", nil
+		case strings.HasPrefix(call, "list-windows"):
+			return "work:0|vim\nwork:1|server\nscratch:0|zsh\n", nil
+		}
+		return "", nil
+	}
+	t.Cleanup(func() { tmuxOutput = orig })
+	return &calls
+}
+
+func TestTmuxCompleter_Subcommands(t *testing.T) {
+	calls := mockTmuxOutput(t)
+	completer := &TmuxCompleter{}
+
+	assert.Equal(t, []string{"kill-server", "kill-session", "kill-window"}, candidateValues(completer.GetCompletions([]string{"kill"}, "tmux kill")))
+	assert.Len(t, completer.GetCompletions(nil, "tmux "), len(tmuxSubcommands))
+	assert.Empty(t, completer.GetCompletions([]string{"-L"}, "tmux -L"))
+	assert.Empty(t, *calls)
+}
+
+func TestTmuxCompleter_Targets(t *testing.T) {
+	calls := mockTmuxOutput(t)
+	completer := &TmuxCompleter{}
+
+	got := completer.GetCompletions([]string{"attach", "-t"}, "tmux attach -t ")
+	assert.Equal(t, []string{"work", "scratch"}, candidateValues(got))
+	assert.Equal(t, "attached, 3 windows", got[0].Description)
+	assert.Equal(t, "detached, 1 windows", got[1].Description)
+
+	assert.Equal(t, []string{"scratch"}, candidateValues(completer.GetCompletions([]string{"kill-session", "-t", "s"}, "tmux kill-session -t s")))
+	assert.Equal(t, []string{"work:0", "work:1"}, candidateValues(completer.GetCompletions([]string{"select-window", "-t", "work"}, "tmux select-window -t work")))
+
+	// Only the value of -t is completed
+	assert.Empty(t, completer.GetCompletions([]string{"attach", "-d"}, "tmux attach -d "))
+	assert.Empty(t, completer.GetCompletions([]string{"new-session", "-t"}, "tmux new-session -t "))
+
+	// Listings are cached
+	completer.GetCompletions([]string{"switch-client", "-t"}, "tmux switch-client -t ")
+	assert.Equal(t, 2, len(*calls))
+}
+
+func TestTmuxCompleter_NoServer(t *testing.T) {
+	orig := tmuxOutput
+	tmuxOutput = func(args ...string) (string, error) {
+		return "", errors.New("no server running")
+	}
+	t.Cleanup(func() { tmuxOutput = orig })
+
+	completer := &TmuxCompleter{}
+	assert.Empty(t, completer.GetCompletions([]string{"attach", "-t"}, "tmux attach -t "))
+}
+
+func TestCompletionsForTmux(t *testing.T) {
+	mockTmuxOutput(t)
+	provider := NewShellCompletionProvider(NewCompletionManager(), nil)
+
+	got := provider.GetCompletions("tmux attach -t w", len("tmux attach -t w"))
+	assert.Equal(t, []string{"work"}, candidateValues(got))
+}
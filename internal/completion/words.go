@@ -1,20 +1,36 @@
 package completion
 
 import (
+	"regexp"
 	"strings"
 	"unicode"
+
+	"mvdan.cc/sh/v3/syntax"
 )
 
-// splitPreservingQuotes splits a command line into words while preserving quotes
+// splitPreservingQuotes splits a command line into words while preserving quotes.
+// A backslash outside single quotes escapes the next character, so `my\ file`
+// and `"say \"hi\""` are single words.
 func splitPreservingQuotes(line string) []string {
 	var words []string
 	var currentWord strings.Builder
 	inQuote := false
 	quoteChar := rune(0)
 	lastWasSpace := true // Start with true to handle leading spaces
+	escaped := false
 
 	for _, r := range line {
+		if escaped {
+			currentWord.WriteRune(r)
+			escaped = false
+			continue
+		}
+
 		switch {
+		case r == '\\' && quoteChar != '\'':
+			currentWord.WriteRune(r)
+			escaped = true
+			lastWasSpace = false
 		case r == '\'' || r == '"':
 			if inQuote {
 				if r == quoteChar {
@@ -54,3 +70,194 @@ func splitPreservingQuotes(line string) []string {
 	return words
 }
 
+// unquoteWord removes the shell quoting and escaping from a word being
+// completed, e.g. `"my fil` becomes `my fil`. It also returns the quote that is
+// still open at the end of the word, or 0 if every quote is closed.
+func unquoteWord(word string) (string, rune) {
+	var unquoted strings.Builder
+	quoteChar := rune(0)
+	escaped := false
+
+	for _, r := range word {
+		switch {
+		case escaped:
+			// Inside double quotes, a backslash only escapes a few characters
+			if quoteChar == '"' && !strings.ContainsRune("\"\\$`", r) {
+				unquoted.WriteRune('\\')
+			}
+			unquoted.WriteRune(r)
+			escaped = false
+		case r == '\\' && quoteChar != '\'':
+			escaped = true
+		case quoteChar == 0 && (r == '\'' || r == '"'):
+			quoteChar = r
+		case r == quoteChar:
+			quoteChar = 0
+		default:
+			unquoted.WriteRune(r)
+		}
+	}
+	if escaped {
+		unquoted.WriteRune('\\')
+	}
+
+	return unquoted.String(), quoteChar
+}
+
+// quoteCompletion quotes a completed path for the command line. Inside an open
+// quote, the quote is kept and closed after the path, unless the path is a
+// directory that may be completed further. Otherwise paths with spaces are
+// wrapped in double quotes.
+func quoteCompletion(value string, openQuote rune, isDir bool) string {
+	switch openQuote {
+	case '"':
+		escaper := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "$", "\\$", "`", "\\`")
+		value = "\"" + escaper.Replace(value)
+	case '\'':
+		value = "'" + strings.ReplaceAll(value, "'", `'\''`)
+	default:
+		if strings.Contains(value, " ") {
+			return "\"" + value + "\""
+		}
+		return value
+	}
+
+	if !isDir {
+		value += string(openQuote)
+	}
+	return value
+}
+
+// substitutionScope is a command substitution, or the line itself, open at
+// some point of the line
+type substitutionScope struct {
+	start    int  // where the command inside begins
+	backtick bool // opened with ` rather than $(
+	parens   int  // unclosed parentheses that aren't the substitution's own
+	quote    byte // quote open inside the scope
+}
+
+// innermostSubstitution returns where the command of the innermost $(...),
+// backtick or <(...) and >(...) process substitution still open at the end of
+// line begins, so that `echo $(git ch` completes `git ch`. Each substitution
+// has its own quoting. Nothing is substituted inside single quotes, and
+// process substitutions aren't inside double quotes either.
+func innermostSubstitution(line string) (int, bool) {
+	scopes := []substitutionScope{{}}
+	escaped := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		scope := &scopes[len(scopes)-1]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && scope.quote != '\'':
+			escaped = true
+		case scope.quote == '\'':
+			if c == '\'' {
+				scope.quote = 0
+			}
+		case c == '$' && i+1 < len(line) && line[i+1] == '(':
+			scopes = append(scopes, substitutionScope{start: i + 2})
+			i++
+		case c == '`':
+			if scope.backtick {
+				scopes = scopes[:len(scopes)-1]
+			} else {
+				scopes = append(scopes, substitutionScope{start: i + 1, backtick: true})
+			}
+		case scope.quote == '"':
+			if c == '"' {
+				scope.quote = 0
+			}
+		case c == '\'' || c == '"':
+			scope.quote = c
+		case (c == '<' || c == '>') && i+1 < len(line) && line[i+1] == '(':
+			scopes = append(scopes, substitutionScope{start: i + 2})
+			i++
+		case c == '(':
+			scope.parens++
+		case c == ')':
+			if scope.parens > 0 {
+				scope.parens--
+			} else if len(scopes) > 1 && !scope.backtick {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+	}
+
+	if len(scopes) == 1 {
+		return 0, false
+	}
+	return scopes[len(scopes)-1].start, true
+}
+
+// currentWordStart returns where the word at the end of line begins, the same
+// way the input finds the word a candidate replaces: at the last space that
+// isn't quoted or escaped
+func currentWordStart(line string) int {
+	start := 0
+	quoteChar := byte(0)
+	escaped := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoteChar != '\'':
+			escaped = true
+		case quoteChar != 0:
+			if c == quoteChar {
+				quoteChar = 0
+			}
+		case c == '\'' || c == '"':
+			quoteChar = c
+		case unicode.IsSpace(rune(c)):
+			start = i + 1
+		}
+	}
+	return start
+}
+
+// redirectionOperator matches a word that begins with a redirection to or from
+// a file, like `>`, `2>>`, `&>` or `<`, and the target typed right after it
+var redirectionOperator = regexp.MustCompile(`^([0-9]*(?:>>|>\||<>|>|<)|&>>?)(.*)$`)
+
+// redirectionTarget returns the file target of a redirection being typed at
+// the end of line, words being the words of line. When the operator and the
+// target are one word, like `2>err`, the operator is returned too. Heredocs
+// and duplicated descriptors like `2>&1` have no file target.
+func redirectionTarget(words []string, line string) (string, string, bool) {
+	if len(words) == 0 {
+		return "", "", false
+	}
+	last := words[len(words)-1]
+	if strings.HasSuffix(line, " ") {
+		// A new word after a bare operator, as in `echo hi > `
+		if match := redirectionOperator.FindStringSubmatch(last); match != nil && match[2] == "" {
+			return "", "", true
+		}
+		return "", "", false
+	}
+
+	if match := redirectionOperator.FindStringSubmatch(last); match != nil {
+		if match[2] != "" && strings.ContainsRune("&<>(", rune(match[2][0])) {
+			return "", "", false
+		}
+		return match[2], match[1], true
+	}
+	if len(words) > 1 {
+		if match := redirectionOperator.FindStringSubmatch(words[len(words)-2]); match != nil && match[2] == "" {
+			return last, "", true
+		}
+	}
+	return "", "", false
+}
+
+// isEnvAssignment returns whether a word is a NAME=value assignment, like
+// FOO=bar or FOO="a b"
+func isEnvAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	return ok && syntax.ValidName(name)
+}
@@ -82,6 +82,21 @@ func TestSplitPreservingQuotes(t *testing.T) {
 			input:    "echo \"\" ''",
 			expected: []string{"echo", "\"\"", "''"},
 		},
+		{
+			name:     "escaped space",
+			input:    "cat my\\ file.txt",
+			expected: []string{"cat", "my\\ file.txt"},
+		},
+		{
+			name:     "escaped quote outside quotes",
+			input:    "echo it\\'s here",
+			expected: []string{"echo", "it\\'s", "here"},
+		},
+		{
+			name:     "backslash inside single quotes",
+			input:    "echo 'a\\' b",
+			expected: []string{"echo", "'a\\'", "b"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -143,3 +158,132 @@ func TestSplitPreservingQuotesEdgeCases(t *testing.T) {
 	}
 }
 
+func TestUnquoteWord(t *testing.T) {
+	tests := []struct {
+		word      string
+		unquoted  string
+		openQuote rune
+	}{
+		{word: "plain", unquoted: "plain"},
+		{word: "\"my fil", unquoted: "my fil", openQuote: '"'},
+		{word: "'my fil", unquoted: "my fil", openQuote: '\''},
+		{word: "\"", unquoted: "", openQuote: '"'},
+		{word: "\"my dir\"/fi", unquoted: "my dir/fi"},
+		{word: "'it''s", unquoted: "its", openQuote: '\''},
+		{word: "my\\ fil", unquoted: "my fil"},
+		{word: "\"say \\\"hi", unquoted: "say \"hi", openQuote: '"'},
+		{word: "\"a\\b", unquoted: "a\\b", openQuote: '"'},
+		{word: "'a\\b", unquoted: "a\\b", openQuote: '\''},
+		{word: "\"it's", unquoted: "it's", openQuote: '"'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			unquoted, openQuote := unquoteWord(tt.word)
+			assert.Equal(t, tt.unquoted, unquoted)
+			assert.Equal(t, tt.openQuote, openQuote)
+		})
+	}
+}
+
+func TestQuoteCompletion(t *testing.T) {
+	// Without an open quote, only paths with spaces get quoted
+	assert.Equal(t, "file.txt", quoteCompletion("file.txt", 0, false))
+	assert.Equal(t, "\"my file.txt\"", quoteCompletion("my file.txt", 0, false))
+
+	// An open quote is kept and closed after files
+	assert.Equal(t, "\"my file.txt\"", quoteCompletion("my file.txt", '"', false))
+	assert.Equal(t, "'my file.txt'", quoteCompletion("my file.txt", '\'', false))
+
+	// Directories stay open so the path can be completed further
+	assert.Equal(t, "\"my dir", quoteCompletion("my dir", '"', true))
+	assert.Equal(t, "'my dir", quoteCompletion("my dir", '\'', true))
+
+	// Characters special inside the quote are escaped
+	assert.Equal(t, "\"cost \\$5 \\\"final\\\".txt\"", quoteCompletion("cost $5 \"final\".txt", '"', false))
+	assert.Equal(t, "'it'\\''s.txt'", quoteCompletion("it's.txt", '\'', false))
+}
+
+func TestInnermostSubstitution(t *testing.T) {
+	tests := []struct {
+		line  string
+		start int
+		ok    bool
+	}{
+		{line: "git ch", ok: false},
+		{line: "echo $(git ch", start: 7, ok: true},
+		{line: "echo `git ch", start: 6, ok: true},
+		{line: "echo $(pwd) ", ok: false},
+		{line: "echo `pwd` ", ok: false},
+		{line: "echo $(cat $(git ch", start: 13, ok: true},
+		{line: "echo $(cat $(pwd)/fi", start: 7, ok: true},
+		{line: "echo \"$(git ch", start: 8, ok: true},
+		{line: "echo $(echo \"a)\" b", start: 7, ok: true},
+		{line: "echo $(if (true) ", start: 7, ok: true},
+		{line: "echo '$(git ch", ok: false},
+		{line: "echo \\$(git ch", ok: false},
+		{line: "echo $(echo `git ch", start: 13, ok: true},
+		{line: "diff <(sort a) <(so", start: 17, ok: true},
+		{line: "tee >(gzip ", start: 6, ok: true},
+		{line: "echo \"<(so", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			start, ok := innermostSubstitution(tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.start, start)
+		})
+	}
+}
+
+func TestCurrentWordStart(t *testing.T) {
+	assert.Equal(t, 0, currentWordStart("git"))
+	assert.Equal(t, 4, currentWordStart("git ch"))
+	assert.Equal(t, 4, currentWordStart("cat \"my fi"))
+	assert.Equal(t, 4, currentWordStart("cat my\\ fi"))
+	assert.Equal(t, 5, currentWordStart("echo \"$(git ch"))
+}
+
+func TestIsEnvAssignment(t *testing.T) {
+	assert.True(t, isEnvAssignment("FOO=bar"))
+	assert.True(t, isEnvAssignment("_foo1="))
+	assert.True(t, isEnvAssignment("FOO=\"a b\""))
+	assert.False(t, isEnvAssignment("git"))
+	assert.False(t, isEnvAssignment("--opt=value"))
+	assert.False(t, isEnvAssignment("1FOO=bar"))
+	assert.False(t, isEnvAssignment("=bar"))
+}
+
+func TestRedirectionTarget(t *testing.T) {
+	tests := []struct {
+		line     string
+		target   string
+		operator string
+		ok       bool
+	}{
+		{line: "echo hi > ", ok: true},
+		{line: "echo hi >> ou", target: "ou", ok: true},
+		{line: "make 2> ", ok: true},
+		{line: "make 2>&1 ", ok: false},
+		{line: "make &> bu", target: "bu", ok: true},
+		{line: "sort < in", target: "in", ok: true},
+		{line: "make 2>er", target: "er", operator: "2>", ok: true},
+		{line: "echo hi >|", operator: ">|", ok: true},
+		{line: "cat <<EO", ok: false},
+		{line: "cat <<< ", ok: false},
+		{line: "make 2>&", ok: false},
+		{line: "echo hi > out ", ok: false},
+		{line: "echo hi", ok: false},
+		{line: "> ", ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			target, operator, ok := redirectionTarget(splitPreservingQuotes(tt.line), tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.target, target)
+			assert.Equal(t, tt.operator, operator)
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+var (
+	setupTitleStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Bold(true)
+	setupQuestionStyle = lipgloss.NewStyle().Bold(true)
+)
+
+// providerDefaults are the models and endpoint suggested for each provider
+var providerDefaults = map[string]struct {
+	fastModel string
+	slowModel string
+	baseURL   string
+}{
+	"ollama":     {fastModel: "qwen2.5", slowModel: "qwen2.5:32b", baseURL: "http://localhost:11434/v1/"},
+	"openai":     {fastModel: "gpt-4o-mini", slowModel: "gpt-4o", baseURL: "https://api.openai.com/v1"},
+	"openrouter": {fastModel: "openai/gpt-4o-mini", slowModel: "openai/gpt-4o", baseURL: "https://openrouter.ai/api/v1"},
+}
+
+// setupChoice is one of the answers offered for a multiple choice question
+type setupChoice struct {
+	label string
+	value string
+}
+
+// setupStep is one question of the setup wizard, answered with one of its
+// choices or, when it has none, with text
+type setupStep struct {
+	envVar   string
+	question string
+	help     string
+	choices  []setupChoice
+	// defaultValue suggests an answer given the earlier ones
+	defaultValue func(answers map[string]string) string
+	// skip leaves the question out given the earlier answers
+	skip func(answers map[string]string) bool
+	// validate checks a text answer
+	validate func(value string) error
+}
+
+var providerChoices = []setupChoice{
+	{label: "Ollama, running models on this machine", value: "ollama"},
+	{label: "OpenAI", value: "openai"},
+	{label: "OpenRouter", value: "openrouter"},
+}
+
+func notEmpty(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("this can't be empty")
+	}
+	return nil
+}
+
+func validBaseURL(value string) error {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("enter an http:// or https:// URL")
+	}
+	return nil
+}
+
+// modelSteps are the questions configuring the fast or slow model. The slow
+// model suggests the fast model's answers.
+func modelSteps(kind string, purpose string) []setupStep {
+	prefix := "GSH_" + strings.ToUpper(kind) + "_MODEL_"
+	provider := func(answers map[string]string) string {
+		return answers[prefix+"PROVIDER"]
+	}
+	sameProviderAsFast := func(answers map[string]string) bool {
+		return kind == "slow" && answers["GSH_SLOW_MODEL_PROVIDER"] == answers["GSH_FAST_MODEL_PROVIDER"]
+	}
+
+	return []setupStep{
+		{
+			envVar:   prefix + "PROVIDER",
+			question: "Which provider serves the " + kind + " model?",
+			help:     "The " + kind + " model " + purpose + ".",
+			choices:  providerChoices,
+			defaultValue: func(answers map[string]string) string {
+				if kind == "slow" {
+					return answers["GSH_FAST_MODEL_PROVIDER"]
+				}
+				return "ollama"
+			},
+		},
+		{
+			envVar:   prefix + "API_KEY",
+			question: "API key for the " + kind + " model",
+			help:     "It's saved in ~/.gshrc.",
+			defaultValue: func(answers map[string]string) string {
+				if sameProviderAsFast(answers) {
+					return answers["GSH_FAST_MODEL_API_KEY"]
+				}
+				return ""
+			},
+			// Ollama doesn't check keys
+			skip: func(answers map[string]string) bool {
+				return provider(answers) == "ollama"
+			},
+			validate: notEmpty,
+		},
+		{
+			envVar:   prefix + "ID",
+			question: "Which " + kind + " model?",
+			help:     "The model ID as the provider names it.",
+			defaultValue: func(answers map[string]string) string {
+				if kind == "slow" {
+					return providerDefaults[provider(answers)].slowModel
+				}
+				return providerDefaults[provider(answers)].fastModel
+			},
+			validate: notEmpty,
+		},
+		{
+			envVar:   prefix + "BASE_URL",
+			question: "Base URL of the " + kind + " model's API",
+			help:     "Change it for a proxy or a server on another machine.",
+			defaultValue: func(answers map[string]string) string {
+				if sameProviderAsFast(answers) {
+					return answers["GSH_FAST_MODEL_BASE_URL"]
+				}
+				return providerDefaults[provider(answers)].baseURL
+			},
+			validate: validBaseURL,
+		},
+	}
+}
+
+// setupSteps are the questions of the setup wizard, in order
+var setupSteps = append(append(
+	modelSteps("fast", "predicts and explains commands as you type"),
+	modelSteps("slow", "answers chat messages and runs the agent")...),
+	setupStep{
+		envVar:   "GSH_COACH",
+		question: "Show the coach?",
+		help:     "The coach shows tips, stats and challenges about your shell usage in the assistant box.",
+		choices: []setupChoice{
+			{label: "Yes", value: "1"},
+			{label: "No", value: "0"},
+		},
+		defaultValue: func(map[string]string) string { return "1" },
+	},
+)
+
+type setupModel struct {
+	steps   []setupStep
+	answers map[string]string
+	// step is the index of the question shown, len(steps) for the summary
+	step   int
+	choice int
+	input  textinput.Model
+	err    string
+
+	done      bool
+	cancelled bool
+}
+
+func newSetupModel() setupModel {
+	input := textinput.New()
+	input.Cursor.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
+	input.Focus()
+
+	m := setupModel{steps: setupSteps, answers: make(map[string]string), input: input}
+	m.enterStep(0, 1)
+	return m
+}
+
+// enterStep shows the first question from step on, moving by direction, that
+// isn't skipped, or the summary past the last one
+func (m *setupModel) enterStep(step int, direction int) {
+	for step >= 0 && step < len(m.steps) && m.steps[step].skip != nil && m.steps[step].skip(m.answers) {
+		step += direction
+	}
+	if step < 0 {
+		step = 0
+	}
+	m.step = step
+	m.err = ""
+	if step >= len(m.steps) {
+		return
+	}
+
+	current := m.steps[step]
+	value, answered := m.answers[current.envVar]
+	if !answered && current.defaultValue != nil {
+		value = current.defaultValue(m.answers)
+	}
+	m.choice = 0
+	for i, choice := range current.choices {
+		if choice.value == value {
+			m.choice = i
+		}
+	}
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+}
+
+func (m setupModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m setupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	switch keyMsg.String() {
+	case "ctrl+c":
+		m.cancelled = true
+		return m, tea.Quit
+	case "esc":
+		if m.step == 0 {
+			m.cancelled = true
+			return m, tea.Quit
+		}
+		m.enterStep(m.step-1, -1)
+		return m, nil
+	case "enter":
+		if m.step >= len(m.steps) {
+			m.done = true
+			return m, tea.Quit
+		}
+		return m.answer()
+	}
+
+	if m.step >= len(m.steps) {
+		return m, nil
+	}
+	if choices := m.steps[m.step].choices; len(choices) > 0 {
+		switch keyMsg.String() {
+		case "up", "k", "shift+tab":
+			m.choice = (m.choice + len(choices) - 1) % len(choices)
+		case "down", "j", "tab":
+			m.choice = (m.choice + 1) % len(choices)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(keyMsg)
+	return m, cmd
+}
+
+// answer records the answer to the current question and moves to the next
+func (m setupModel) answer() (tea.Model, tea.Cmd) {
+	current := m.steps[m.step]
+	value := strings.TrimSpace(m.input.Value())
+	if len(current.choices) > 0 {
+		value = current.choices[m.choice].value
+	} else if current.validate != nil {
+		if err := current.validate(value); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
+	}
+
+	// Answers to later questions depend on this one, so suggest them afresh
+	if m.answers[current.envVar] != value {
+		for _, later := range m.steps[m.step+1:] {
+			delete(m.answers, later.envVar)
+		}
+	}
+	m.answers[current.envVar] = value
+	m.enterStep(m.step+1, 1)
+	return m, nil
+}
+
+func (m setupModel) View() string {
+	if m.done || m.cancelled {
+		return ""
+	}
+
+	var sb strings.Builder
+	if m.step >= len(m.steps) {
+		sb.WriteString(setupTitleStyle.Render("gsh setup") + "\n\n")
+		sb.WriteString(setupQuestionStyle.Render("Save these settings to "+setupRCPathDisplay+"?") + "\n\n")
+		for _, step := range m.steps {
+			if value, ok := m.answers[step.envVar]; ok {
+				if strings.HasSuffix(step.envVar, "API_KEY") {
+					value = maskSecret(value)
+				}
+				sb.WriteString(fmt.Sprintf("  %s=%s\n", step.envVar, value))
+			}
+		}
+		sb.WriteString("\n" + helpStyle.Render("Enter: save | Esc: back | Ctrl+C: cancel") + "\n")
+		return sb.String()
+	}
+
+	current := m.steps[m.step]
+	sb.WriteString(setupTitleStyle.Render(fmt.Sprintf("gsh setup (%d/%d)", m.step+1, len(m.steps))) + "\n\n")
+	sb.WriteString(setupQuestionStyle.Render(current.question) + "\n")
+	sb.WriteString(helpStyle.Render(current.help) + "\n\n")
+	if len(current.choices) > 0 {
+		for i, choice := range current.choices {
+			if i == m.choice {
+				sb.WriteString(selectedItemStyle.Render("> "+choice.label) + "\n")
+			} else {
+				sb.WriteString("    " + choice.label + "\n")
+			}
+		}
+		sb.WriteString("\n" + helpStyle.Render("↑/↓: choose | Enter: next | Esc: back | Ctrl+C: cancel") + "\n")
+	} else {
+		sb.WriteString(m.input.View() + "\n")
+		if m.err != "" {
+			sb.WriteString(errorStyle.Render(m.err) + "\n")
+		}
+		sb.WriteString("\n" + helpStyle.Render("Enter: next | Esc: back | Ctrl+C: cancel") + "\n")
+	}
+	return sb.String()
+}
+
+// maskSecret hides all but the end of a key
+func maskSecret(value string) string {
+	if len(value) <= 4 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-4) + value[len(value)-4:]
+}
+
+const setupRCPathDisplay = "~/.gshrc"
+
+// SetupRCPath is the file the setup wizard writes
+func SetupRCPath() string {
+	return filepath.Join(homeDir(), ".gshrc")
+}
+
+// NeedsSetup returns whether gsh runs for the first time, with no ~/.gshrc
+func NeedsSetup() bool {
+	_, err := os.Stat(SetupRCPath())
+	return os.IsNotExist(err)
+}
+
+// RunSetupWizard asks for the model and coach settings and writes them to
+// ~/.gshrc, returning whether it did. An existing ~/.gshrc is kept, with the
+// settings appended so they take precedence. When the wizard is cancelled on
+// the first run, a ~/.gshrc without settings is written so it isn't offered
+// again.
+func RunSetupWizard(firstRun bool) (bool, error) {
+	result, err := tea.NewProgram(newSetupModel()).Run()
+	if err != nil {
+		return false, err
+	}
+	m, ok := result.(setupModel)
+	if !ok || !m.done {
+		if firstRun {
+			return false, writeSetupRC(SetupRCPath(), nil, nil)
+		}
+		return false, nil
+	}
+	return true, writeSetupRC(SetupRCPath(), m.steps, m.answers)
+}
+
+// writeSetupRC writes the answers as a starter gshrc, or appends them to an
+// existing one
+func writeSetupRC(path string, steps []setupStep, answers map[string]string) error {
+	var sb strings.Builder
+	_, err := os.Stat(path)
+	if err == nil {
+		sb.WriteString("\n# -------- Written by gsh --setup --------\n")
+	} else {
+		sb.WriteString("# gsh configuration, written by gsh --setup. Run it again or @!config to\n")
+		sb.WriteString("# change these; every setting is described in docs/CONFIGURATION.md.\n")
+	}
+
+	comments := map[string]string{
+		"GSH_FAST_MODEL_PROVIDER": "The fast model predicts and explains commands as you type",
+		"GSH_SLOW_MODEL_PROVIDER": "The slow model answers chat messages and runs the agent",
+		"GSH_COACH":               "Whether the coach shows tips, stats and challenges in the assistant box",
+	}
+	for _, step := range steps {
+		value, ok := answers[step.envVar]
+		if !ok {
+			continue
+		}
+		if comment, ok := comments[step.envVar]; ok {
+			sb.WriteString("\n# " + comment + "\n")
+		}
+		// The interpreter reads 'it'\''s' in an assignment as it\'s, so quote
+		// the way it expects
+		quoted, err := syntax.Quote(value, syntax.LangBash)
+		if err != nil {
+			return fmt.Errorf("%s: %w", step.envVar, err)
+		}
+		sb.WriteString(step.envVar + "=" + quoted + "\n")
+	}
+
+	// The file holds API keys
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(sb.String()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
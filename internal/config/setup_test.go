@@ -0,0 +1,192 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// pressKeys sends keys to the wizard, typing the ones that aren't named keys
+func pressKeys(m setupModel, keys ...string) setupModel {
+	for _, key := range keys {
+		var msg tea.KeyMsg
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+		next, _ := m.Update(msg)
+		m = next.(setupModel)
+	}
+	return m
+}
+
+func currentEnvVar(m setupModel) string {
+	if m.step >= len(m.steps) {
+		return ""
+	}
+	return m.steps[m.step].envVar
+}
+
+func TestSetupWizardOllamaSkipsAPIKey(t *testing.T) {
+	m := newSetupModel()
+	assert.Equal(t, "GSH_FAST_MODEL_PROVIDER", currentEnvVar(m))
+	assert.Equal(t, "ollama", m.steps[m.step].choices[m.choice].value)
+
+	m = pressKeys(m, "enter")
+	assert.Equal(t, "GSH_FAST_MODEL_ID", currentEnvVar(m))
+	assert.Equal(t, "qwen2.5", m.input.Value())
+
+	m = pressKeys(m, "enter", "enter", "enter")
+	assert.Equal(t, "GSH_SLOW_MODEL_ID", currentEnvVar(m))
+	assert.Equal(t, "qwen2.5:32b", m.input.Value())
+	assert.NotContains(t, m.answers, "GSH_FAST_MODEL_API_KEY")
+
+	// Going back skips the key too
+	m = pressKeys(m, "esc")
+	assert.Equal(t, "GSH_SLOW_MODEL_PROVIDER", currentEnvVar(m))
+}
+
+func TestSetupWizardSlowModelReusesFastProvider(t *testing.T) {
+	m := pressKeys(newSetupModel(), "down", "enter")
+	assert.Equal(t, "GSH_FAST_MODEL_API_KEY", currentEnvVar(m))
+
+	// Keys can't be empty
+	m = pressKeys(m, "enter")
+	assert.Equal(t, "GSH_FAST_MODEL_API_KEY", currentEnvVar(m))
+	assert.NotEmpty(t, m.err)
+
+	m = pressKeys(m, "sk-test", "enter")
+	assert.Equal(t, "gpt-4o-mini", m.input.Value())
+	m = pressKeys(m, "enter")
+	assert.Equal(t, "https://api.openai.com/v1", m.input.Value())
+	m = pressKeys(m, "/proxy", "enter")
+
+	assert.Equal(t, "GSH_SLOW_MODEL_PROVIDER", currentEnvVar(m))
+	assert.Equal(t, "openai", m.steps[m.step].choices[m.choice].value)
+	m = pressKeys(m, "enter")
+	assert.Equal(t, "sk-test", m.input.Value())
+	m = pressKeys(m, "enter")
+	assert.Equal(t, "gpt-4o", m.input.Value())
+	m = pressKeys(m, "enter")
+	assert.Equal(t, "https://api.openai.com/v1/proxy", m.input.Value())
+
+	// A different provider suggests its own endpoint
+	m = pressKeys(m, "esc", "esc", "esc", "down", "enter")
+	assert.Equal(t, "GSH_SLOW_MODEL_API_KEY", currentEnvVar(m))
+	assert.Empty(t, m.input.Value())
+	m = pressKeys(m, "sk-other", "enter", "enter")
+	assert.Equal(t, "https://openrouter.ai/api/v1", m.input.Value())
+}
+
+func TestSetupWizardChangedAnswerResetsLaterOnes(t *testing.T) {
+	m := pressKeys(newSetupModel(), "enter", "llama3", "enter")
+	assert.Equal(t, "qwen2.5llama3", m.answers["GSH_FAST_MODEL_ID"])
+
+	// Picking another provider suggests its model again
+	m = pressKeys(m, "esc", "esc", "down", "enter", "sk-test", "enter")
+	assert.Equal(t, "GSH_FAST_MODEL_ID", currentEnvVar(m))
+	assert.Equal(t, "gpt-4o-mini", m.input.Value())
+}
+
+func TestValidBaseURL(t *testing.T) {
+	for _, value := range []string{"http://localhost:11434/v1/", "https://api.openai.com/v1", " https://example.com "} {
+		assert.NoError(t, validBaseURL(value), value)
+	}
+	for _, value := range []string{"", "localhost:11434", "ftp://example.com", "https://", "not a url"} {
+		assert.Error(t, validBaseURL(value), value)
+	}
+}
+
+// sourceRC runs the file like gsh does and returns the variables it sets
+func sourceRC(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	prog, err := syntax.NewParser().Parse(f, path)
+	require.NoError(t, err)
+
+	runner, err := interp.New(interp.Env(expand.ListEnviron()))
+	require.NoError(t, err)
+	require.NoError(t, runner.Run(context.Background(), prog))
+
+	vars := make(map[string]string)
+	for name, vr := range runner.Vars {
+		vars[name] = vr.String()
+	}
+	return vars
+}
+
+func TestWriteSetupRC(t *testing.T) {
+	steps := []setupStep{{envVar: "GSH_FAST_MODEL_PROVIDER"}, {envVar: "GSH_FAST_MODEL_API_KEY"}, {envVar: "GSH_COACH"}}
+	answers := map[string]string{
+		"GSH_FAST_MODEL_PROVIDER": "openai",
+		"GSH_FAST_MODEL_API_KEY":  `it's "quoted" $HOME`,
+	}
+
+	t.Run("creates a starter file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".gshrc")
+		require.NoError(t, writeSetupRC(path, steps, answers))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(content), "# gsh configuration"))
+		assert.Contains(t, string(content), "# The fast model predicts")
+		// Unanswered questions are left out
+		assert.NotContains(t, string(content), "GSH_COACH")
+
+		vars := sourceRC(t, path)
+		assert.Equal(t, "openai", vars["GSH_FAST_MODEL_PROVIDER"])
+		assert.Equal(t, `it's "quoted" $HOME`, vars["GSH_FAST_MODEL_API_KEY"])
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		}
+	})
+
+	t.Run("appends to an existing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".gshrc")
+		require.NoError(t, os.WriteFile(path, []byte("GSH_FAST_MODEL_PROVIDER=ollama\nMY_SETTING=kept\n"), 0644))
+		require.NoError(t, writeSetupRC(path, steps, answers))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(content), "GSH_FAST_MODEL_PROVIDER=ollama\nMY_SETTING=kept\n"))
+		assert.Contains(t, string(content), "# -------- Written by gsh --setup --------")
+		assert.NotContains(t, string(content), "# gsh configuration")
+
+		// The appended settings take precedence
+		vars := sourceRC(t, path)
+		assert.Equal(t, "openai", vars["GSH_FAST_MODEL_PROVIDER"])
+		assert.Equal(t, "kept", vars["MY_SETTING"])
+	})
+
+	t.Run("writes a file without settings when cancelled", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".gshrc")
+		require.NoError(t, writeSetupRC(path, nil, nil))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "GSH_")
+		for name := range sourceRC(t, path) {
+			assert.False(t, strings.HasPrefix(name, "GSH_"), name)
+		}
+	})
+}
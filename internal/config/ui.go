@@ -601,7 +601,12 @@ func saveConfig(key, value string, runner *interp.Runner) error {
 	// Sync to environment so changes take effect immediately
 	environment.SyncVariableToEnv(runner, key)
 
-	// Persist to file for future sessions
+	return PersistSetting(key, value)
+}
+
+// PersistSetting saves a setting for future sessions in ~/.gsh_config_ui, and
+// makes sure ~/.gshrc sources that file
+func PersistSetting(key, value string) error {
 	configPath := filepath.Join(homeDir(), ".gsh_config_ui")
 	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/analytics"
+	"github.com/atinylittleshell/gsh/pkg/textwidth"
+)
+
+const analyticsUsage = "Usage: @!analytics mismatches [n]"
+
+const (
+	// analyticsMismatchesShown is how many mismatches are listed by default
+	analyticsMismatchesShown = 20
+	// mismatchColumnWidth caps the width of the predicted column, so a long
+	// prediction doesn't push the actual command off the screen
+	mismatchColumnWidth = 40
+)
+
+// runAnalyticsControl handles `@!analytics <subcommand>` for looking into how
+// predictions compare to the commands run, returning a message to show
+func runAnalyticsControl(args string, analyticsManager *analytics.AnalyticsManager) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return analyticsUsage, nil
+	}
+
+	switch fields[0] {
+	case "mismatches":
+		limit := analyticsMismatchesShown
+		if len(fields) > 2 {
+			return "", fmt.Errorf("unexpected argument %q. %s", fields[2], analyticsUsage)
+		}
+		if len(fields) == 2 {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n <= 0 {
+				return "", fmt.Errorf("invalid number of mismatches %q. %s", fields[1], analyticsUsage)
+			}
+			limit = n
+		}
+
+		mismatches, err := analyticsManager.GetMismatches(limit)
+		if err != nil {
+			return "", err
+		}
+		if len(mismatches) == 0 {
+			return "No mispredicted commands recorded yet.", nil
+		}
+		return formatMismatches(mismatches), nil
+
+	default:
+		return "", fmt.Errorf("unknown analytics command %q. %s", fields[0], analyticsUsage)
+	}
+}
+
+// formatMismatches lays out predictions and the commands actually run in two
+// columns, most recent first
+func formatMismatches(mismatches []analytics.AnalyticsEntry) string {
+	width := textwidth.StringWidth("Predicted")
+	for _, entry := range mismatches {
+		width = max(width, textwidth.StringWidth(entry.Prediction))
+	}
+	width = min(width, mismatchColumnWidth)
+
+	var sb strings.Builder
+	writeRow := func(predicted, actual string) {
+		if textwidth.StringWidth(predicted) > width {
+			predicted = textwidth.Truncate(predicted, width-1) + "…"
+		}
+		sb.WriteString(predicted)
+		sb.WriteString(strings.Repeat(" ", width-textwidth.StringWidth(predicted)+2))
+		sb.WriteString(actual)
+		sb.WriteString("\n")
+	}
+
+	writeRow("Predicted", "Actual")
+	for _, entry := range mismatches {
+		writeRow(entry.Prediction, entry.Actual)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/analytics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAnalyticsControl(t *testing.T) {
+	analyticsManager, err := analytics.NewAnalyticsManager(":memory:")
+	require.NoError(t, err)
+
+	message, err := runAnalyticsControl("", analyticsManager)
+	require.NoError(t, err)
+	assert.Equal(t, analyticsUsage, message)
+
+	message, err = runAnalyticsControl(" mismatches", analyticsManager)
+	require.NoError(t, err)
+	assert.Equal(t, "No mispredicted commands recorded yet.", message)
+
+	require.NoError(t, analyticsManager.NewEntry("git ", "git status", "git stash"))
+	require.NoError(t, analyticsManager.NewEntry("", "docker compose up --build --force-recreate --remove-orphans", "make up"))
+	require.NoError(t, analyticsManager.NewEntry("ls", "ls", "ls -la"))
+
+	message, err = runAnalyticsControl("mismatches", analyticsManager)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"Predicted                                 Actual",
+		"docker compose up --build --force-recre…  make up",
+		"git status                                git stash",
+	}, "\n"), message)
+
+	message, err = runAnalyticsControl("mismatches 1", analyticsManager)
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(message, "\n"), 2)
+
+	_, err = runAnalyticsControl("mismatches many", analyticsManager)
+	assert.ErrorContains(t, err, "invalid number of mismatches")
+	_, err = runAnalyticsControl("hits", analyticsManager)
+	assert.ErrorContains(t, err, "unknown analytics command")
+}
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/bash"
+	"github.com/atinylittleshell/gsh/internal/styles"
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+const benchUsage = "Usage: @!bench [--show-output] <runs> <command>"
+
+// maxBenchRuns guards against typos like `@!bench 1000000 make`
+const maxBenchRuns = 10000
+
+// interruptedExitCode is what commands exit with on Ctrl+C
+const interruptedExitCode = 130
+
+// benchOptions are the parsed arguments of @!bench
+type benchOptions struct {
+	runs       int
+	command    string
+	showOutput bool
+}
+
+// benchStats summarizes the wall-clock time of the runs of a benchmark
+type benchStats struct {
+	Runs     int
+	Failures int
+	Min      time.Duration
+	Max      time.Duration
+	Mean     time.Duration
+	Stddev   time.Duration
+}
+
+// parseBenchArgs parses `[--show-output] <runs> <command>`
+func parseBenchArgs(args string) (benchOptions, error) {
+	var options benchOptions
+	rest := strings.TrimSpace(args)
+
+	if after, ok := strings.CutPrefix(rest, "--show-output"); ok && (after == "" || after[0] == ' ') {
+		options.showOutput = true
+		rest = strings.TrimSpace(after)
+	}
+
+	runs, command, _ := strings.Cut(rest, " ")
+	if runs == "" {
+		return options, fmt.Errorf("missing number of runs. %s", benchUsage)
+	}
+	count, err := strconv.Atoi(runs)
+	if err != nil || count < 1 || count > maxBenchRuns {
+		return options, fmt.Errorf("invalid number of runs %q, expected 1 to %d. %s", runs, maxBenchRuns, benchUsage)
+	}
+	options.runs = count
+
+	options.command = strings.TrimSpace(command)
+	if options.command == "" {
+		return options, fmt.Errorf("missing command. %s", benchUsage)
+	}
+	return options, nil
+}
+
+// runBench runs a command the given number of times on the runner, the same
+// way executeCommand does but without recording history, and returns how long
+// each run took. Output is discarded unless showOutput is set. It stops early
+// when a run is interrupted or exits the shell.
+func runBench(ctx context.Context, runner *interp.Runner, options benchOptions, stderrCapturer *StderrCapturer) ([]time.Duration, int, error) {
+	var stmts []*syntax.Stmt
+	err := syntax.NewParser().Stmts(strings.NewReader(bash.PreprocessTypesetCommands(options.command)), func(stmt *syntax.Stmt) bool {
+		stmts = append(stmts, stmt)
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var stderrWriter io.Writer = os.Stderr
+	if stderrCapturer != nil {
+		stderrWriter = stderrCapturer
+	}
+	if !options.showOutput {
+		_ = interp.StdIO(os.Stdin, io.Discard, io.Discard)(runner)
+	}
+	defer func() {
+		_ = interp.StdIO(os.Stdin, os.Stdout, stderrWriter)(runner)
+	}()
+
+	durations := make([]time.Duration, 0, options.runs)
+	failures := 0
+	for i := 0; i < options.runs; i++ {
+		startTime := time.Now()
+		var err error
+		for _, stmt := range stmts {
+			if err = runner.Run(ctx, stmt); runner.Exited() {
+				break
+			}
+		}
+		durations = append(durations, time.Since(startTime))
+
+		exitCode := commandExitCode(err)
+		if exitCode != 0 {
+			failures++
+		}
+		if exitCode == interruptedExitCode || runner.Exited() || ctx.Err() != nil {
+			break
+		}
+	}
+	return durations, failures, nil
+}
+
+// computeBenchStats computes min, max, mean and sample standard deviation
+func computeBenchStats(durations []time.Duration, failures int) benchStats {
+	stats := benchStats{Runs: len(durations), Failures: failures}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	var total time.Duration
+	stats.Min = durations[0]
+	for _, d := range durations {
+		total += d
+		stats.Min = min(stats.Min, d)
+		stats.Max = max(stats.Max, d)
+	}
+	stats.Mean = total / time.Duration(len(durations))
+
+	if len(durations) > 1 {
+		var sumSquares float64
+		for _, d := range durations {
+			diff := float64(d - stats.Mean)
+			sumSquares += diff * diff
+		}
+		stats.Stddev = time.Duration(math.Sqrt(sumSquares / float64(len(durations)-1)))
+	}
+	return stats
+}
+
+// formatBenchStats renders the summary table printed by @!bench
+func formatBenchStats(command string, stats benchStats) string {
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("Command", "Runs", "Failed", "Mean", "Stddev", "Min", "Max").
+		Row(
+			command,
+			fmt.Sprintf("%d", stats.Runs),
+			fmt.Sprintf("%d", stats.Failures),
+			formatBenchDuration(stats.Mean),
+			formatBenchDuration(stats.Stddev),
+			formatBenchDuration(stats.Min),
+			formatBenchDuration(stats.Max),
+		)
+	return t.String()
+}
+
+// formatBenchDuration rounds to a precision that fits the duration, so both
+// 3.2ms and 1m12s read well
+func formatBenchDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+// benchCommand handles `@!bench [--show-output] <runs> <command>`
+func benchCommand(ctx context.Context, args string, runner *interp.Runner, stderrCapturer *StderrCapturer) {
+	options, err := parseBenchArgs(args)
+	if err != nil {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(fmt.Sprintf("gsh: Running %s %d times...\n", options.command, options.runs)) + gline.RESET_CURSOR_COLUMN)
+	durations, failures, err := runBench(ctx, runner, options, stderrCapturer)
+	if err != nil {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	stats := computeBenchStats(durations, failures)
+	fmt.Print(gline.RESET_CURSOR_COLUMN + formatBenchStats(options.command, stats) + "\n" + gline.RESET_CURSOR_COLUMN)
+	if stats.Runs < options.runs {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR(fmt.Sprintf("gsh: Stopped after %d of %d runs.\n", stats.Runs, options.runs)) + gline.RESET_CURSOR_COLUMN)
+	}
+}
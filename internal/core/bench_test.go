@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestParseBenchArgs(t *testing.T) {
+	options, err := parseBenchArgs(" 10 make build")
+	require.NoError(t, err)
+	assert.Equal(t, benchOptions{runs: 10, command: "make build"}, options)
+
+	options, err = parseBenchArgs(" --show-output 3 ls -la")
+	require.NoError(t, err)
+	assert.Equal(t, benchOptions{runs: 3, command: "ls -la", showOutput: true}, options)
+
+	_, err = parseBenchArgs("")
+	assert.ErrorContains(t, err, "missing number of runs")
+
+	_, err = parseBenchArgs(" 5")
+	assert.ErrorContains(t, err, "missing command")
+
+	_, err = parseBenchArgs(" make build")
+	assert.ErrorContains(t, err, "invalid number of runs")
+
+	_, err = parseBenchArgs(" 0 make")
+	assert.ErrorContains(t, err, "invalid number of runs")
+}
+
+func TestComputeBenchStats(t *testing.T) {
+	stats := computeBenchStats([]time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+	}, 1)
+	assert.Equal(t, 3, stats.Runs)
+	assert.Equal(t, 1, stats.Failures)
+	assert.Equal(t, 10*time.Millisecond, stats.Min)
+	assert.Equal(t, 30*time.Millisecond, stats.Max)
+	assert.Equal(t, 20*time.Millisecond, stats.Mean)
+	assert.Equal(t, 10*time.Millisecond, stats.Stddev)
+
+	single := computeBenchStats([]time.Duration{5 * time.Millisecond}, 0)
+	assert.Equal(t, time.Duration(0), single.Stddev)
+
+	assert.Equal(t, benchStats{}, computeBenchStats(nil, 0))
+}
+
+func TestRunBench(t *testing.T) {
+	runner, err := interp.New()
+	require.NoError(t, err)
+
+	durations, failures, err := runBench(context.Background(), runner, benchOptions{runs: 3, command: "echo hi"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, durations, 3)
+	assert.Equal(t, 0, failures)
+
+	durations, failures, err = runBench(context.Background(), runner, benchOptions{runs: 2, command: "false"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, durations, 2)
+	assert.Equal(t, 2, failures)
+
+	// Exiting the shell stops the benchmark
+	durations, _, err = runBench(context.Background(), runner, benchOptions{runs: 5, command: "exit 0"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, durations, 1)
+}
+
+func TestFormatBenchStats(t *testing.T) {
+	output := formatBenchStats("make", benchStats{Runs: 2, Mean: 1500 * time.Millisecond, Min: time.Second, Max: 2 * time.Second, Stddev: 707106781})
+	assert.Contains(t, output, "make")
+	assert.Contains(t, output, "1.5s")
+	assert.Contains(t, output, "707.11ms")
+	assert.Contains(t, output, "Stddev")
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+const bookmarkUsage = "Usage: @!bookmark [save <name> [command]|list|run <name>|delete <name>]"
+
+// runBookmarkControl handles `@!bookmark <subcommand>`. It returns a message to
+// show and, for run (or a bare bookmark name), the command line to execute.
+// save without a command bookmarks the last command.
+func runBookmarkControl(args string, historyManager *history.HistoryManager, pwd string, lastCommand string) (message string, command string, err error) {
+	subcommand, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch subcommand {
+	case "", "list":
+		bookmarks, err := historyManager.ListBookmarks()
+		if err != nil {
+			return "", "", err
+		}
+		if len(bookmarks) == 0 {
+			return "No bookmarks yet. Save the last command with @!bookmark save <name>.", "", nil
+		}
+		t := table.New().
+			Border(lipgloss.NormalBorder()).
+			Headers("Name", "Command", "Directory")
+		for _, bookmark := range bookmarks {
+			t.Row(bookmark.Name, bookmark.Command, bookmark.Directory)
+		}
+		return t.String(), "", nil
+
+	case "save":
+		name, bookmarkCommand, _ := strings.Cut(rest, " ")
+		bookmarkCommand = strings.TrimSpace(bookmarkCommand)
+		if name == "" {
+			return "", "", fmt.Errorf("missing bookmark name. %s", bookmarkUsage)
+		}
+		if bookmarkCommand == "" {
+			bookmarkCommand = lastCommand
+		}
+		if bookmarkCommand == "" {
+			return "", "", fmt.Errorf("there is no last command to bookmark")
+		}
+		if err := historyManager.SaveBookmark(name, bookmarkCommand, pwd); err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("Saved bookmark %s: %s", name, bookmarkCommand), "", nil
+
+	case "delete":
+		if rest == "" {
+			return "", "", fmt.Errorf("missing bookmark name. %s", bookmarkUsage)
+		}
+		if err := historyManager.DeleteBookmark(rest); err != nil {
+			return "", "", err
+		}
+		return "Deleted bookmark " + rest, "", nil
+
+	case "run":
+		if rest == "" {
+			return "", "", fmt.Errorf("missing bookmark name. %s", bookmarkUsage)
+		}
+		return runBookmark(historyManager, rest)
+
+	default:
+		// `@!bookmark <name>` is a shorthand for run
+		if rest != "" {
+			return "", "", fmt.Errorf("unknown bookmark command %q. %s", subcommand, bookmarkUsage)
+		}
+		return runBookmark(historyManager, subcommand)
+	}
+}
+
+func runBookmark(historyManager *history.HistoryManager, name string) (string, string, error) {
+	bookmark, err := historyManager.GetBookmark(name)
+	if err != nil {
+		return "", "", err
+	}
+	return "", bookmark.Command, nil
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBookmarkControl(t *testing.T) {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	message, _, err := runBookmarkControl("list", historyManager, "/src", "")
+	require.NoError(t, err)
+	assert.Contains(t, message, "No bookmarks yet")
+
+	// save without a command bookmarks the last command
+	message, _, err = runBookmarkControl("save deploy", historyManager, "/src", "make deploy ENV=prod")
+	require.NoError(t, err)
+	assert.Equal(t, "Saved bookmark deploy: make deploy ENV=prod", message)
+
+	_, _, err = runBookmarkControl("save logs kubectl logs -f deploy/api", historyManager, "/src", "make deploy ENV=prod")
+	require.NoError(t, err)
+
+	message, _, err = runBookmarkControl("", historyManager, "/src", "")
+	require.NoError(t, err)
+	assert.Contains(t, message, "kubectl logs -f deploy/api")
+
+	_, command, err := runBookmarkControl("run deploy", historyManager, "/src", "")
+	require.NoError(t, err)
+	assert.Equal(t, "make deploy ENV=prod", command)
+
+	_, command, err = runBookmarkControl("logs", historyManager, "/src", "")
+	require.NoError(t, err)
+	assert.Equal(t, "kubectl logs -f deploy/api", command)
+
+	_, _, err = runBookmarkControl("delete logs", historyManager, "/src", "")
+	require.NoError(t, err)
+	_, _, err = runBookmarkControl("run logs", historyManager, "/src", "")
+	assert.Error(t, err)
+
+	_, _, err = runBookmarkControl("save", historyManager, "/src", "ls")
+	assert.Error(t, err)
+	_, _, err = runBookmarkControl("save empty", historyManager, "/src", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+const completeUsage = "Usage: @!complete discover <command>"
+
+// runCompleteControl handles `@!complete discover <command>`, which finds and
+// registers completions for a command with discover, returning a message to show
+func runCompleteControl(args string, discover func(command string) (string, error)) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return completeUsage, nil
+	}
+
+	switch fields[0] {
+	case "discover":
+		if len(fields) != 2 {
+			return "", fmt.Errorf("expected one command. %s", completeUsage)
+		}
+		return discover(fields[1])
+
+	default:
+		return "", fmt.Errorf("unknown complete command %q. %s", fields[0], completeUsage)
+	}
+}
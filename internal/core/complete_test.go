@@ -0,0 +1,32 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCompleteControl(t *testing.T) {
+	var discovered []string
+	discover := func(command string) (string, error) {
+		discovered = append(discovered, command)
+		return "Registered completions for " + command + " from carapace.", nil
+	}
+
+	message, err := runCompleteControl("", discover)
+	assert.NoError(t, err)
+	assert.Equal(t, completeUsage, message)
+
+	message, err = runCompleteControl(" discover kubectl", discover)
+	assert.NoError(t, err)
+	assert.Equal(t, "Registered completions for kubectl from carapace.", message)
+	assert.Equal(t, []string{"kubectl"}, discovered)
+
+	_, err = runCompleteControl(" discover", discover)
+	assert.ErrorContains(t, err, completeUsage)
+	_, err = runCompleteControl(" discover a b", discover)
+	assert.ErrorContains(t, err, completeUsage)
+	_, err = runCompleteControl(" install kubectl", discover)
+	assert.ErrorContains(t, err, "unknown complete command")
+	assert.Len(t, discovered, 1)
+}
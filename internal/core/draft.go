@@ -0,0 +1,50 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"go.uber.org/zap"
+)
+
+// draftFile is where this session saves the line being typed. Each session has
+// its own, so concurrent sessions don't overwrite each other's drafts.
+func draftFile(dir string) string {
+	return filepath.Join(dir, strconv.Itoa(os.Getpid())+".json")
+}
+
+// recoverDraft returns the newest draft in dir left by a session that is no
+// longer running, along with the files of all such drafts
+func recoverDraft(dir string) (*gline.Draft, []string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil
+	}
+
+	var newest *gline.Draft
+	var stale []string
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || !strings.HasSuffix(entry.Name(), ".json") || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		stale = append(stale, path)
+		if draft, ok := gline.LoadDraft(path); ok && (newest == nil || draft.SavedAt.After(newest.SavedAt)) {
+			newest = &draft
+		}
+	}
+	return newest, stale
+}
+
+// discardDrafts removes drafts that were offered for recovery
+func discardDrafts(paths []string, logger *zap.Logger) {
+	for _, path := range paths {
+		if err := gline.ClearDraft(path); err != nil {
+			logger.Warn("failed to remove a recovered draft", zap.String("path", path), zap.Error(err))
+		}
+	}
+}
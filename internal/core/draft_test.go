@@ -0,0 +1,35 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverDraft(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// Pids far above any pid limit belong to sessions that are gone
+	require.NoError(t, gline.SaveDraft(filepath.Join(dir, "999999991.json"), gline.Draft{Input: "make old", SavedAt: now.Add(-time.Hour)}))
+	require.NoError(t, gline.SaveDraft(filepath.Join(dir, "999999992.json"), gline.Draft{Input: "make deploy", SavedAt: now}))
+	// This session's own draft is still being typed
+	require.NoError(t, gline.SaveDraft(draftFile(dir), gline.Draft{Input: "ls", SavedAt: now.Add(time.Hour)}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0600))
+
+	draft, stale := recoverDraft(dir)
+	require.NotNil(t, draft)
+	assert.Equal(t, "make deploy", draft.Input)
+	assert.ElementsMatch(t, []string{filepath.Join(dir, "999999991.json"), filepath.Join(dir, "999999992.json")}, stale)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+".json", filepath.Base(draftFile(dir)))
+
+	draft, stale = recoverDraft(filepath.Join(dir, "missing"))
+	assert.Nil(t, draft)
+	assert.Empty(t, stale)
+}
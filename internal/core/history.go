@@ -0,0 +1,225 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/atinylittleshell/gsh/internal/utils"
+	"github.com/dustin/go-humanize"
+)
+
+// ignoredByHistory returns whether command matches one of the GSH_HISTORY_IGNORE
+// patterns, and so shouldn't be recorded
+func ignoredByHistory(command string, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+const noteUsage = "Usage: @!note <text>, or @!note --clear"
+
+// runNoteControl handles `@!note <text>`, attaching a note to the last command
+// of the session so it shows up, and can be searched for, in history search
+func runNoteControl(args string, historyManager *history.HistoryManager, lastEntryID uint) (string, error) {
+	note := strings.TrimSpace(args)
+	if note == "" {
+		return noteUsage, nil
+	}
+	if lastEntryID == 0 {
+		return "", fmt.Errorf("no command to annotate yet")
+	}
+
+	if note == "--clear" {
+		if err := historyManager.SetNote(lastEntryID, ""); err != nil {
+			return "", err
+		}
+		return "gsh: Removed the note from the last command.", nil
+	}
+	if err := historyManager.SetNote(lastEntryID, note); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("gsh: Noted %q on the last command.", note), nil
+}
+
+const historyUsage = "Usage: @!history [stats|prune --older-than <age>|vacuum|export [--format bash|zsh|json] <path>|import [--format bash|zsh|json] <path>]"
+
+// runHistoryControl handles `@!history <subcommand>` for managing the history
+// database, returning a message to show
+func runHistoryControl(args string, historyManager *history.HistoryManager, pwd string, now time.Time) (string, error) {
+	fields := strings.Fields(args)
+	subcommand := ""
+	if len(fields) > 0 {
+		subcommand = fields[0]
+	}
+
+	switch subcommand {
+	case "", "stats":
+		stats, err := historyManager.GetStats()
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("History entries:    %d\n", stats.Entries))
+		sb.WriteString(fmt.Sprintf("Transcript entries: %d\n", stats.TranscriptEntries))
+		if !stats.Oldest.IsZero() {
+			sb.WriteString(fmt.Sprintf("Oldest entry:       %s (%s)\n", stats.Oldest.Format("2006-01-02"), humanize.RelTime(stats.Oldest, now, "ago", "from now")))
+		}
+		sb.WriteString(fmt.Sprintf("Database size:      %s", humanize.Bytes(uint64(stats.SizeBytes))))
+		if stats.FreeBytes > 0 {
+			sb.WriteString(fmt.Sprintf(" (%s reclaimable with @!history vacuum)", humanize.Bytes(uint64(stats.FreeBytes))))
+		}
+		return sb.String(), nil
+
+	case "prune":
+		age, err := parseOlderThan(fields[1:])
+		if err != nil {
+			return "", err
+		}
+		cutoff := now.Add(-age)
+		deleted, err := historyManager.PruneOlderThan(cutoff)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted %d history entries from before %s. Run @!history vacuum to reclaim the space.", deleted, cutoff.Format("2006-01-02")), nil
+
+	case "vacuum":
+		before, err := historyManager.GetStats()
+		if err != nil {
+			return "", err
+		}
+		if err := historyManager.Vacuum(); err != nil {
+			return "", err
+		}
+		after, err := historyManager.GetStats()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Vacuumed the history database: %s -> %s", humanize.Bytes(uint64(before.SizeBytes)), humanize.Bytes(uint64(after.SizeBytes))), nil
+
+	case "export":
+		format, path, err := parseHistoryFileArgs(fields[1:], pwd)
+		if err != nil {
+			return "", err
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		count, err := historyManager.ExportHistory(file, format)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Exported %d history entries to %s in %s format.", count, path, format), nil
+
+	case "import":
+		format, path, err := parseHistoryFileArgs(fields[1:], pwd)
+		if err != nil {
+			return "", err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		count, err := historyManager.ImportHistory(file, format)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Imported %d history entries from %s.", count, path), nil
+
+	default:
+		return "", fmt.Errorf("unknown history command %q. %s", subcommand, historyUsage)
+	}
+}
+
+// parseHistoryFileArgs parses `[--format <format>] <path>` for export and
+// import. Without --format, the format is guessed from the file name.
+func parseHistoryFileArgs(args []string, pwd string) (history.HistoryFormat, string, error) {
+	var formatName, path string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch {
+		case name == "--format":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return "", "", fmt.Errorf("--format requires bash, zsh or json")
+				}
+				i++
+				value = args[i]
+			}
+			formatName = value
+		case strings.HasPrefix(args[i], "-"):
+			return "", "", fmt.Errorf("unknown option %q. %s", args[i], historyUsage)
+		case path == "":
+			path = args[i]
+		default:
+			return "", "", fmt.Errorf("unexpected argument %q. %s", args[i], historyUsage)
+		}
+	}
+
+	if path == "" {
+		return "", "", fmt.Errorf("missing file path. %s", historyUsage)
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(pwd, path)
+	}
+
+	if formatName == "" {
+		return history.GuessHistoryFormat(path), path, nil
+	}
+	format, err := history.ParseHistoryFormat(formatName)
+	return format, path, err
+}
+
+// historyImportHint suggests importing the history of the previous shell when
+// gsh starts with an empty history, returning "" otherwise
+func historyImportHint(historyManager *history.HistoryManager, home string) string {
+	stats, err := historyManager.GetStats()
+	if err != nil || stats.Entries > 0 || home == "" {
+		return ""
+	}
+
+	for _, name := range []string{".zsh_history", ".bash_history"} {
+		if info, err := os.Stat(filepath.Join(home, name)); err == nil && info.Size() > 0 {
+			return fmt.Sprintf("Your gsh history is empty. Run @!history import ~/%s to bring over your existing history.", name)
+		}
+	}
+	return ""
+}
+
+// parseOlderThan parses the `--older-than <age>` option of prune
+func parseOlderThan(args []string) (time.Duration, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("missing --older-than. %s", historyUsage)
+	}
+
+	name, value, hasValue := strings.Cut(args[0], "=")
+	if name != "--older-than" {
+		return 0, fmt.Errorf("unknown option %q. %s", args[0], historyUsage)
+	}
+	if !hasValue {
+		if len(args) < 2 {
+			return 0, fmt.Errorf("--older-than requires an age such as 90d or 1y")
+		}
+		value = args[1]
+	}
+	age, err := utils.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q, use e.g. 90d, 6w or 1y", value)
+	}
+	return age, nil
+}
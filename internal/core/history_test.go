@@ -0,0 +1,132 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHistoryControl(t *testing.T) {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	_, err = historyManager.StartCommand("make build", "/src")
+	require.NoError(t, err)
+	_, err = historyManager.StartCommand("make test", "/src")
+	require.NoError(t, err)
+
+	message, err := runHistoryControl("", historyManager, "/src", time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, message, "History entries:    2")
+	assert.Contains(t, message, "Database size:")
+
+	// Entries are all recent, and the latest one in /src would be kept anyway
+	message, err = runHistoryControl(" prune --older-than 1y", historyManager, "/src", time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, message, "Deleted 0 history entries")
+
+	message, err = runHistoryControl("prune --older-than=1h", historyManager, "/src", time.Now().Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Contains(t, message, "Deleted 1 history entries")
+
+	message, err = runHistoryControl("vacuum", historyManager, "/src", time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, message, "Vacuumed the history database")
+
+	_, err = runHistoryControl("prune", historyManager, "/src", time.Now())
+	assert.ErrorContains(t, err, "missing --older-than")
+	_, err = runHistoryControl("prune --older-than", historyManager, "/src", time.Now())
+	assert.ErrorContains(t, err, "requires an age")
+	_, err = runHistoryControl("prune --older-than soon", historyManager, "/src", time.Now())
+	assert.ErrorContains(t, err, "invalid age")
+	_, err = runHistoryControl("shrink", historyManager, "/src", time.Now())
+	assert.ErrorContains(t, err, "unknown history command")
+}
+
+func TestRunHistoryControlExportImport(t *testing.T) {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	_, err = historyManager.StartCommand("git status", "/src")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	message, err := runHistoryControl("export --format zsh exported", historyManager, dir, time.Now())
+	require.NoError(t, err)
+	exportedPath := filepath.Join(dir, "exported")
+	assert.Equal(t, "Exported 1 history entries to "+exportedPath+" in zsh format.", message)
+
+	content, err := os.ReadFile(exportedPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), ";git status\n")
+
+	// The format is guessed from the file name
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".bash_history"), []byte("ls\nmake\n"), 0o644))
+	message, err = runHistoryControl("import .bash_history", historyManager, dir, time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, message, "Imported 2 history entries")
+
+	_, err = runHistoryControl("export", historyManager, dir, time.Now())
+	assert.ErrorContains(t, err, "missing file path")
+	_, err = runHistoryControl("export --format fish out", historyManager, dir, time.Now())
+	assert.ErrorContains(t, err, "unknown history format")
+	_, err = runHistoryControl("import missing_file", historyManager, dir, time.Now())
+	assert.Error(t, err)
+}
+
+func TestHistoryImportHint(t *testing.T) {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	home := t.TempDir()
+	assert.Empty(t, historyImportHint(historyManager, home))
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".zsh_history"), []byte(": 1700000000:0;ls\n"), 0o644))
+	assert.Contains(t, historyImportHint(historyManager, home), "@!history import ~/.zsh_history")
+
+	_, err = historyManager.StartCommand("ls", "/src")
+	require.NoError(t, err)
+	assert.Empty(t, historyImportHint(historyManager, home))
+}
+
+func TestIgnoredByHistory(t *testing.T) {
+	patterns := []*regexp.Regexp{regexp.MustCompile("^ "), regexp.MustCompile("PASSWORD=")}
+
+	assert.False(t, ignoredByHistory("ls -la", nil))
+	assert.False(t, ignoredByHistory("ls -la", patterns))
+	assert.True(t, ignoredByHistory(" ls -la", patterns))
+	assert.True(t, ignoredByHistory("PASSWORD=hunter2 ./deploy", patterns))
+}
+
+func TestRunNoteControl(t *testing.T) {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	_, err = runNoteControl(" works", historyManager, 0)
+	assert.ErrorContains(t, err, "no command to annotate")
+
+	entry, err := historyManager.StartCommand("make deploy ENV=staging", "/src")
+	require.NoError(t, err)
+
+	message, err := runNoteControl("", historyManager, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, noteUsage, message)
+
+	message, err = runNoteControl(" the one that actually worked ", historyManager, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, `gsh: Noted "the one that actually worked" on the last command.`, message)
+	entries, err := historyManager.GetAllEntries()
+	require.NoError(t, err)
+	assert.Equal(t, "the one that actually worked", entries[0].Note)
+
+	_, err = runNoteControl(" --clear", historyManager, entry.ID)
+	require.NoError(t, err)
+	entries, err = historyManager.GetAllEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries[0].Note)
+}
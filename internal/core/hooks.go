@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// PreExecHook is called with the command line right before it is executed
+type PreExecHook func(ctx context.Context, command string)
+
+// PostExecHook is called with the command line and its exit code after it completes
+type PostExecHook func(ctx context.Context, command string, exitCode int)
+
+// commandHooks holds the Go callbacks registered for the interactive loop
+var commandHooks struct {
+	mu       sync.Mutex
+	preExec  []PreExecHook
+	postExec []PostExecHook
+}
+
+// AddPreExecHook registers a callback to run before every interactive command
+func AddPreExecHook(hook PreExecHook) {
+	commandHooks.mu.Lock()
+	defer commandHooks.mu.Unlock()
+	commandHooks.preExec = append(commandHooks.preExec, hook)
+}
+
+// AddPostExecHook registers a callback to run after every interactive command
+func AddPostExecHook(hook PostExecHook) {
+	commandHooks.mu.Lock()
+	defer commandHooks.mu.Unlock()
+	commandHooks.postExec = append(commandHooks.postExec, hook)
+}
+
+// ResetHooks removes all registered callbacks
+func ResetHooks() {
+	commandHooks.mu.Lock()
+	defer commandHooks.mu.Unlock()
+	commandHooks.preExec = nil
+	commandHooks.postExec = nil
+}
+
+// runPreExecHooks runs the registered callbacks followed by $GSH_PREEXEC
+func runPreExecHooks(ctx context.Context, runner *interp.Runner, logger *zap.Logger, command string) {
+	commandHooks.mu.Lock()
+	hooks := append([]PreExecHook(nil), commandHooks.preExec...)
+	commandHooks.mu.Unlock()
+
+	for _, hook := range hooks {
+		safeRunHook(logger, "preexec", func() {
+			hook(ctx, command)
+		})
+	}
+
+	runShellHook(ctx, runner, logger, "GSH_PREEXEC", command)
+}
+
+// runPostExecHooks runs the registered callbacks followed by $GSH_PRECMD
+func runPostExecHooks(ctx context.Context, runner *interp.Runner, logger *zap.Logger, command string, exitCode int) {
+	commandHooks.mu.Lock()
+	hooks := append([]PostExecHook(nil), commandHooks.postExec...)
+	commandHooks.mu.Unlock()
+
+	for _, hook := range hooks {
+		safeRunHook(logger, "postexec", func() {
+			hook(ctx, command, exitCode)
+		})
+	}
+
+	runShellHook(ctx, runner, logger, "GSH_PRECMD", command)
+}
+
+// runShellHook runs the command string stored in the given variable on the
+// main runner, so hooks can change the shell's environment like direnv does.
+// The command that triggered the hook is available as $GSH_HOOK_COMMAND.
+func runShellHook(ctx context.Context, runner *interp.Runner, logger *zap.Logger, variable string, command string) {
+	if runner == nil {
+		return
+	}
+
+	hookCommand := strings.TrimSpace(runner.Vars[variable].String())
+	if hookCommand == "" {
+		return
+	}
+
+	var stmts []*syntax.Stmt
+	err := syntax.NewParser().Stmts(strings.NewReader(hookCommand), func(stmt *syntax.Stmt) bool {
+		stmts = append(stmts, stmt)
+		return true
+	})
+	if err != nil {
+		logger.Warn("error parsing hook", zap.String("hook", variable), zap.Error(err))
+		return
+	}
+
+	quotedCommand, err := syntax.Quote(command, syntax.LangBash)
+	if err != nil {
+		quotedCommand = "''"
+	}
+	runShellStatement(ctx, runner, "GSH_HOOK_COMMAND="+quotedCommand)
+	defer runShellStatement(ctx, runner, "unset GSH_HOOK_COMMAND")
+
+	safeRunHook(logger, variable, func() {
+		for _, stmt := range stmts {
+			if err := runner.Run(ctx, stmt); err != nil {
+				logger.Debug("hook returned an error", zap.String("hook", variable), zap.Error(err))
+			}
+			if runner.Exited() {
+				return
+			}
+		}
+	})
+}
+
+// runShellStatement runs a single statement such as a variable assignment on
+// the main runner, ignoring its result
+func runShellStatement(ctx context.Context, runner *interp.Runner, statement string) {
+	prog, err := syntax.NewParser().Parse(strings.NewReader(statement), "")
+	if err != nil || len(prog.Stmts) == 0 {
+		return
+	}
+	_ = runner.Run(ctx, prog.Stmts[0])
+}
+
+// safeRunHook keeps a misbehaving hook from taking down the shell
+func safeRunHook(logger *zap.Logger, name string, run func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("hook panicked", zap.String("hook", name), zap.String("panic", fmt.Sprint(r)))
+		}
+	}()
+	run()
+}
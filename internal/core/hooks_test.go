@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/bash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestCommandHooks(t *testing.T) {
+	defer ResetHooks()
+
+	runner, err := interp.New(interp.StdIO(nil, nil, nil))
+	require.NoError(t, err)
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	script := `
+GSH_PREEXEC='BEFORE="$GSH_HOOK_COMMAND"'
+GSH_PRECMD='AFTER="$GSH_HOOK_COMMAND:$GSH_LAST_COMMAND_EXIT_CODE"'
+GSH_LAST_COMMAND_EXIT_CODE=3
+`
+	require.NoError(t, bash.RunBashScriptFromReader(ctx, runner, strings.NewReader(script), "test"))
+
+	var preExecCommands []string
+	var postExecCodes []int
+	AddPreExecHook(func(ctx context.Context, command string) {
+		preExecCommands = append(preExecCommands, command)
+	})
+	AddPostExecHook(func(ctx context.Context, command string, exitCode int) {
+		postExecCodes = append(postExecCodes, exitCode)
+	})
+
+	runPreExecHooks(ctx, runner, logger, "ls -la")
+	runPostExecHooks(ctx, runner, logger, "ls -la", 3)
+
+	assert.Equal(t, []string{"ls -la"}, preExecCommands)
+	assert.Equal(t, []int{3}, postExecCodes)
+	assert.Equal(t, "ls -la", runner.Vars["BEFORE"].String())
+	assert.Equal(t, "ls -la:3", runner.Vars["AFTER"].String())
+	assert.False(t, runner.Vars["GSH_HOOK_COMMAND"].IsSet())
+}
+
+func TestCommandHookFailuresAreIgnored(t *testing.T) {
+	defer ResetHooks()
+
+	runner, err := interp.New(interp.StdIO(nil, nil, nil))
+	require.NoError(t, err)
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	script := `
+GSH_PREEXEC='false'
+GSH_PRECMD='if then'
+`
+	require.NoError(t, bash.RunBashScriptFromReader(ctx, runner, strings.NewReader(script), "test"))
+
+	ran := false
+	AddPreExecHook(func(ctx context.Context, command string) {
+		panic("broken hook")
+	})
+	AddPreExecHook(func(ctx context.Context, command string) {
+		ran = true
+	})
+
+	assert.NotPanics(t, func() {
+		runPreExecHooks(ctx, runner, logger, "echo hi")
+		runPostExecHooks(ctx, runner, logger, "echo hi", 0)
+	})
+	assert.True(t, ran)
+}
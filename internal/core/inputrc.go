@@ -0,0 +1,26 @@
+package core
+
+import (
+	"github.com/atinylittleshell/gsh/internal/completion"
+	"github.com/atinylittleshell/gsh/internal/inputrc"
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"go.uber.org/zap"
+)
+
+// loadInputrc reads the user's inputrc, applies its completion settings and
+// returns the key bindings to use for the prompt
+func loadInputrc(path string, logger *zap.Logger) shellinput.KeyMap {
+	keyMap := shellinput.DefaultKeyMap
+
+	config, err := inputrc.Load(path, logger)
+	if err != nil {
+		logger.Warn("failed to read inputrc", zap.String("path", path), zap.Error(err))
+	}
+	if config.EditingMode() == "vi" {
+		logger.Debug("inputrc: vi editing mode is not supported, using emacs bindings")
+	}
+
+	config.Apply(&keyMap)
+	completion.SetFileCompletionIgnoreCase(config.CompletionIgnoreCase())
+	return keyMap
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/styles"
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"mvdan.cc/sh/v3/interp"
+)
+
+const localEnvUsage = "Usage: @!localenv [status|allow|deny]"
+
+// updateLocalEnv applies the .gshenv.local of the current directory and
+// reports what was loaded, unloaded or blocked
+func updateLocalEnv(ctx context.Context, localEnv *environment.LocalEnv, runner *interp.Runner) {
+	change, err := localEnv.Update(ctx, runner, environment.GetPwd(runner))
+	if err != nil {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+	if message := describeLocalEnvChange(change); message != "" {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+	}
+}
+
+func describeLocalEnvChange(change environment.LocalEnvChange) string {
+	var lines []string
+	if change.Unloaded != "" && change.Unloaded != change.Loaded {
+		lines = append(lines, "gsh: Unloaded "+change.Unloaded)
+	}
+	if change.Loaded != "" {
+		lines = append(lines, fmt.Sprintf("gsh: Loaded %s (%s)", change.Loaded, strings.Join(change.Variables, ", ")))
+	}
+	if change.Blocked != "" {
+		lines = append(lines, fmt.Sprintf("gsh: %s is not allowed. Review it and run @!localenv allow to load it.", change.Blocked))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runLocalEnvControl handles `@!localenv [status|allow|deny]` for the
+// .gshenv.local that covers pwd, returning a message to show
+func runLocalEnvControl(args string, localEnv *environment.LocalEnv, pwd string) (string, error) {
+	subcommand := strings.TrimSpace(args)
+	path := environment.FindLocalEnvFile(pwd)
+
+	switch subcommand {
+	case "", "status":
+		if path == "" {
+			return fmt.Sprintf("No %s in %s or its parents.", environment.LocalEnvFileName, pwd), nil
+		}
+		if localEnv.File() != path {
+			return fmt.Sprintf("%s is not loaded. Review it and run @!localenv allow to load it.", path), nil
+		}
+		return fmt.Sprintf("%s is loaded (%s).", path, strings.Join(localEnv.Variables(), ", ")), nil
+
+	case "allow":
+		if path == "" {
+			return "", fmt.Errorf("no %s in %s or its parents", environment.LocalEnvFileName, pwd)
+		}
+		if err := environment.AllowLocalEnv(path); err != nil {
+			return "", err
+		}
+		return "Allowed " + path, nil
+
+	case "deny":
+		if path == "" {
+			return "", fmt.Errorf("no %s in %s or its parents", environment.LocalEnvFileName, pwd)
+		}
+		if err := environment.DenyLocalEnv(path); err != nil {
+			return "", err
+		}
+		return "Denied " + path, nil
+
+	default:
+		return "", fmt.Errorf("unknown localenv command %q. %s", subcommand, localEnvUsage)
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLocalEnvControl(t *testing.T) {
+	environment.SetAllowedLocalEnvsFileForTesting(filepath.Join(t.TempDir(), "allowed_local_envs"))
+	t.Cleanup(func() {
+		environment.SetAllowedLocalEnvsFileForTesting(filepath.Join(environment.GetConfigDirForTesting(), "allowed_local_envs"))
+	})
+
+	dir := t.TempDir()
+	localEnv := environment.NewLocalEnv()
+
+	message, err := runLocalEnvControl("", localEnv, dir)
+	require.NoError(t, err)
+	assert.Contains(t, message, "No .gshenv.local")
+
+	_, err = runLocalEnvControl(" allow", localEnv, dir)
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, environment.LocalEnvFileName)
+	require.NoError(t, os.WriteFile(path, []byte("FOO=bar\n"), 0644))
+
+	message, err = runLocalEnvControl(" status", localEnv, dir)
+	require.NoError(t, err)
+	assert.Equal(t, path+" is not loaded. Review it and run @!localenv allow to load it.", message)
+
+	message, err = runLocalEnvControl(" allow", localEnv, dir)
+	require.NoError(t, err)
+	assert.Equal(t, "Allowed "+path, message)
+
+	message, err = runLocalEnvControl(" deny", localEnv, dir)
+	require.NoError(t, err)
+	assert.Equal(t, "Denied "+path, message)
+
+	_, err = runLocalEnvControl(" trust", localEnv, dir)
+	assert.ErrorContains(t, err, "unknown localenv command")
+}
+
+func TestDescribeLocalEnvChange(t *testing.T) {
+	assert.Equal(t, "", describeLocalEnvChange(environment.LocalEnvChange{}))
+	assert.Equal(t, "gsh: Loaded /src/.gshenv.local (FOO, BAR)", describeLocalEnvChange(environment.LocalEnvChange{
+		Loaded:    "/src/.gshenv.local",
+		Variables: []string{"FOO", "BAR"},
+	}))
+	assert.Equal(t, "gsh: Unloaded /src/.gshenv.local", describeLocalEnvChange(environment.LocalEnvChange{
+		Unloaded: "/src/.gshenv.local",
+	}))
+	assert.Equal(t, "gsh: /src/.gshenv.local is not allowed. Review it and run @!localenv allow to load it.", describeLocalEnvChange(environment.LocalEnvChange{
+		Blocked: "/src/.gshenv.local",
+	}))
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/styles"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// outputDiffMaxBytes bounds the output kept to compare the next run with, and
+// the output held back while comparing. Longer output is written as is.
+const outputDiffMaxBytes = 256 * 1024
+
+// outputDiffDelay is how long the output of a repeated command is held back
+// to compare it as a whole. Commands that are still running by then, like
+// `kubectl get pods -w`, have their lines compared as they arrive instead.
+const outputDiffDelay = 300 * time.Millisecond
+
+// outputDiffer records the output of a command so the next run of the same
+// command can be compared with it. When there's a previous output, the new
+// output is written with the added lines highlighted and the removed ones
+// shown in between: on Close for commands that finish quickly, and line by
+// line for those still running after outputDiffDelay.
+type outputDiffer struct {
+	mu       sync.Mutex
+	out      io.Writer
+	previous string
+	diffing  bool
+	buffer   bytes.Buffer
+	overflow bool
+	closed   bool
+
+	timer     *time.Timer
+	streaming bool
+	// The lines of previous not matched yet while streaming
+	previousLines []string
+	// The start of a line that isn't complete yet while streaming
+	pending []byte
+}
+
+// newOutputDiffer returns a differ writing to out. With diffing, the output is
+// compared with previous, otherwise it's only recorded.
+func newOutputDiffer(out io.Writer, previous string, diffing bool) *outputDiffer {
+	return &outputDiffer{out: out, previous: previous, diffing: diffing}
+}
+
+func (d *outputDiffer) Write(data []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.overflow {
+		return d.out.Write(data)
+	}
+	if d.buffer.Len()+len(data) > outputDiffMaxBytes {
+		// Too long to keep, so stop comparing and write everything through
+		d.overflow = true
+		if d.timer != nil {
+			d.timer.Stop()
+		}
+		if d.diffing {
+			held := d.buffer.Bytes()
+			if d.streaming {
+				held = d.pending
+			}
+			if _, err := d.out.Write(held); err != nil {
+				return 0, err
+			}
+		}
+		d.buffer = bytes.Buffer{}
+		d.pending = nil
+		return d.out.Write(data)
+	}
+
+	d.buffer.Write(data)
+	if !d.diffing {
+		return d.out.Write(data)
+	}
+	if d.streaming {
+		return len(data), d.writeLines(data)
+	}
+	if d.timer == nil {
+		d.timer = time.AfterFunc(outputDiffDelay, d.stream)
+	}
+	return len(data), nil
+}
+
+// stream writes the complete lines held back so far, and makes the following
+// ones be written as they arrive
+func (d *outputDiffer) stream() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.streaming || d.overflow || d.closed {
+		return
+	}
+	d.streaming = true
+	d.previousLines = strings.SplitAfter(d.previous, "\n")
+	_ = d.writeLines(d.buffer.Bytes())
+}
+
+// writeLines writes the complete lines of data compared with the previous
+// output, and keeps the start of an incomplete one for later
+func (d *outputDiffer) writeLines(data []byte) error {
+	d.pending = append(d.pending, data...)
+	var sb strings.Builder
+	for {
+		i := bytes.IndexByte(d.pending, '\n')
+		if i < 0 {
+			break
+		}
+		d.writeLine(&sb, string(d.pending[:i+1]))
+		d.pending = d.pending[i+1:]
+	}
+	_, err := io.WriteString(d.out, sb.String())
+	return err
+}
+
+// writeLine writes a line found further on in the previous output as is,
+// after the previous lines it skipped over as removed, and highlights a line
+// that isn't there as added
+func (d *outputDiffer) writeLine(sb *strings.Builder, line string) {
+	for i, previous := range d.previousLines {
+		if previous != line {
+			continue
+		}
+		for _, removed := range d.previousLines[:i] {
+			sb.WriteString(styleDiffLine(removed, styles.DIFF_REMOVED))
+		}
+		d.previousLines = d.previousLines[i+1:]
+		sb.WriteString(line)
+		return
+	}
+	sb.WriteString(styleDiffLine(line, styles.DIFF_ADDED))
+}
+
+// Close writes the held back output compared with the previous one, or while
+// streaming, the rest of it and the previous lines that never came
+func (d *outputDiffer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	if !d.diffing || d.overflow {
+		return nil
+	}
+	if !d.streaming {
+		_, err := io.WriteString(d.out, highlightOutputDiff(d.previous, d.buffer.String()))
+		return err
+	}
+
+	var sb strings.Builder
+	if len(d.pending) > 0 {
+		d.writeLine(&sb, string(d.pending))
+		d.pending = nil
+	}
+	for _, removed := range d.previousLines {
+		sb.WriteString(styleDiffLine(removed, styles.DIFF_REMOVED))
+	}
+	d.previousLines = nil
+	_, err := io.WriteString(d.out, sb.String())
+	return err
+}
+
+// Output returns the recorded output, and false if it was too long to keep
+func (d *outputDiffer) Output() (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.buffer.String(), !d.overflow
+}
+
+// highlightOutputDiff returns current with the lines that weren't in previous
+// highlighted, and the lines of previous that are gone shown where they were
+func highlightOutputDiff(previous, current string) string {
+	if previous == current {
+		return current
+	}
+	a := strings.SplitAfter(previous, "\n")
+	b := strings.SplitAfter(current, "\n")
+
+	var sb strings.Builder
+	for _, op := range difflib.NewMatcher(a, b).GetOpCodes() {
+		if op.Tag == 'r' || op.Tag == 'd' {
+			for _, line := range a[op.I1:op.I2] {
+				sb.WriteString(styleDiffLine(line, styles.DIFF_REMOVED))
+			}
+		}
+		for _, line := range b[op.J1:op.J2] {
+			if op.Tag == 'e' {
+				sb.WriteString(line)
+			} else {
+				sb.WriteString(styleDiffLine(line, styles.DIFF_ADDED))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// styleDiffLine colors a line without coloring its line break
+func styleDiffLine(line string, style func(string) string) string {
+	text, found := strings.CutSuffix(line, "\n")
+	if text == "" {
+		return line
+	}
+	if found {
+		return style(text) + "\n"
+	}
+	return style(text)
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/styles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightOutputDiff(t *testing.T) {
+	previous := "NAME    STATUS\napi     Running\nworker  Pending\n"
+	current := "NAME    STATUS\napi     Running\nworker  Running\ncron    Running\n"
+
+	assert.Equal(t,
+		"NAME    STATUS\napi     Running\n"+
+			styles.DIFF_REMOVED("worker  Pending")+"\n"+
+			styles.DIFF_ADDED("worker  Running")+"\n"+
+			styles.DIFF_ADDED("cron    Running")+"\n",
+		highlightOutputDiff(previous, current))
+
+	assert.Equal(t, current, highlightOutputDiff(current, current))
+}
+
+func TestOutputDifferRecordsWithoutDiffing(t *testing.T) {
+	var out bytes.Buffer
+	differ := newOutputDiffer(&out, "", false)
+
+	_, _ = differ.Write([]byte("one\n"))
+	assert.Equal(t, "one\n", out.String(), "output is written right away")
+	assert.NoError(t, differ.Close())
+
+	output, ok := differ.Output()
+	assert.True(t, ok)
+	assert.Equal(t, "one\n", output)
+}
+
+func TestOutputDifferHoldsBackRepeatedOutput(t *testing.T) {
+	var out bytes.Buffer
+	differ := newOutputDiffer(&out, "one\ntwo\n", true)
+
+	_, _ = differ.Write([]byte("one\n"))
+	_, _ = differ.Write([]byte("three\n"))
+	assert.Empty(t, out.String())
+
+	assert.NoError(t, differ.Close())
+	assert.Equal(t, highlightOutputDiff("one\ntwo\n", "one\nthree\n"), out.String())
+
+	output, ok := differ.Output()
+	assert.True(t, ok)
+	assert.Equal(t, "one\nthree\n", output)
+}
+
+func TestOutputDifferStreamsSlowOutput(t *testing.T) {
+	var out lockedBuffer
+	differ := newOutputDiffer(&out, "NAME  STATUS\napi   Pending\nweb   Running\n", true)
+
+	_, _ = differ.Write([]byte("NAME  STATUS\napi   Run"))
+	assert.Eventually(t, func() bool {
+		return out.String() == "NAME  STATUS\n"
+	}, 5*outputDiffDelay, 10*time.Millisecond, "complete lines are written once the command runs for a while")
+
+	// Lines are compared as they arrive
+	_, _ = differ.Write([]byte("ning\n"))
+	assert.Equal(t, "NAME  STATUS\n"+styles.DIFF_ADDED("api   Running")+"\n", out.String())
+
+	_, _ = differ.Write([]byte("web   Running\n"))
+	assert.NoError(t, differ.Close())
+	assert.Equal(t,
+		"NAME  STATUS\n"+
+			styles.DIFF_ADDED("api   Running")+"\n"+
+			styles.DIFF_REMOVED("api   Pending")+"\n"+
+			"web   Running\n",
+		out.String())
+
+	output, ok := differ.Output()
+	assert.True(t, ok)
+	assert.Equal(t, "NAME  STATUS\napi   Running\nweb   Running\n", output)
+}
+
+func TestOutputDifferWritesLongOutputAsIs(t *testing.T) {
+	var out bytes.Buffer
+	differ := newOutputDiffer(&out, "old\n", true)
+
+	_, _ = differ.Write([]byte("start\n"))
+	long := strings.Repeat("x", outputDiffMaxBytes)
+	_, _ = differ.Write([]byte(long))
+	assert.NoError(t, differ.Close())
+
+	assert.Equal(t, "start\n"+long, out.String())
+	_, ok := differ.Output()
+	assert.False(t, ok, "output too long to keep isn't compared next time")
+}
@@ -0,0 +1,204 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"golang.org/x/term"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// pagerDecisionDelay is how long output is held back to find out whether it
+// fills the screen. Commands that take longer to print a screenful, like
+// builds, stream straight to the terminal instead.
+const pagerDecisionDelay = 300 * time.Millisecond
+
+// transparentCommands run the command that follows them
+var transparentCommands = map[string]bool{
+	"command": true, "env": true, "exec": true, "nice": true, "nohup": true, "sudo": true, "time": true,
+}
+
+type pagerState int
+
+const (
+	pagerBuffering pagerState = iota
+	pagerPassthrough
+	pagerPaging
+)
+
+// autoPager holds back command output until it either fills the screen, and
+// then starts the pager with it, or the command finishes or pauses, and then
+// writes it to the terminal as is
+type autoPager struct {
+	mu       sync.Mutex
+	command  string
+	out      io.Writer
+	maxLines int
+	state    pagerState
+	buffer   bytes.Buffer
+	lines    int
+	timer    *time.Timer
+	pager    *exec.Cmd
+	stdin    io.WriteCloser
+}
+
+func newAutoPager(command string, out io.Writer, maxLines int) *autoPager {
+	return &autoPager{
+		command:  command,
+		out:      out,
+		maxLines: maxLines,
+	}
+}
+
+func (p *autoPager) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.state {
+	case pagerPassthrough:
+		return p.out.Write(data)
+	case pagerPaging:
+		// Fails once the pager quits, which stops the command like a closed pipe would
+		return p.stdin.Write(data)
+	}
+
+	if p.timer == nil {
+		p.timer = time.AfterFunc(pagerDecisionDelay, p.passthrough)
+	}
+	p.buffer.Write(data)
+	p.lines += bytes.Count(data, []byte("\n"))
+	if p.lines < p.maxLines {
+		return len(data), nil
+	}
+
+	p.timer.Stop()
+	if err := p.startPager(); err != nil {
+		p.state = pagerPassthrough
+		_, err := p.out.Write(p.buffer.Bytes())
+		p.buffer.Reset()
+		return len(data), err
+	}
+	p.state = pagerPaging
+	_, err := p.stdin.Write(p.buffer.Bytes())
+	p.buffer.Reset()
+	return len(data), err
+}
+
+// passthrough writes the held back output and stops holding back any more
+func (p *autoPager) passthrough() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != pagerBuffering {
+		return
+	}
+	p.state = pagerPassthrough
+	_, _ = p.out.Write(p.buffer.Bytes())
+	p.buffer.Reset()
+}
+
+func (p *autoPager) startPager() error {
+	pager := exec.Command("sh", "-c", p.command)
+	pager.Stdout = p.out
+	pager.Stderr = os.Stderr
+	stdin, err := pager.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := pager.Start(); err != nil {
+		return err
+	}
+	p.pager = pager
+	p.stdin = stdin
+	return nil
+}
+
+// Close writes any held back output, or waits for the user to quit the pager
+func (p *autoPager) Close() error {
+	p.mu.Lock()
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	if p.state == pagerBuffering {
+		p.state = pagerPassthrough
+		_, err := p.out.Write(p.buffer.Bytes())
+		p.buffer.Reset()
+		p.mu.Unlock()
+		return err
+	}
+	pager := p.pager
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	if pager == nil {
+		return nil
+	}
+	_ = stdin.Close()
+	return pager.Wait()
+}
+
+// newCommandPager returns a pager for the output of a command, or nil when
+// paging is disabled, stdout isn't a terminal or the command isn't one of
+// GSH_PAGER_COMMANDS
+func newCommandPager(runner *interp.Runner, stmt *syntax.Stmt) *autoPager {
+	command := environment.GetPager(runner)
+	if command == "" || !callsOnly(stmt, environment.GetPagerCommands(runner)) {
+		return nil
+	}
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return nil
+	}
+	_, height, err := term.GetSize(fd)
+	if err != nil || height < 2 {
+		return nil
+	}
+	// Leave room for the prompt, as less -F does
+	return newAutoPager(command, os.Stdout, height-1)
+}
+
+// callsOnly reports whether every command stmt runs is one of commands, in
+// the foreground. Output that goes through the pager or the output differ
+// goes to a pipe rather than the terminal, so commands only opt in when they
+// print the same either way; `ls` would lose its columns and colors, and a TUI
+// wouldn't work at all.
+func callsOnly(stmt *syntax.Stmt, commands []string) bool {
+	if stmt.Background || stmt.Coprocess || len(commands) == 0 {
+		return false
+	}
+
+	only := false
+	syntax.Walk(stmt, func(node syntax.Node) bool {
+		name, ok := calledCommand(node)
+		if !ok {
+			return true
+		}
+		only = slices.Contains(commands, name)
+		return only
+	})
+	return only
+}
+
+// calledCommand returns the name of the command node calls, looking past
+// wrappers like `sudo vim`
+func calledCommand(node syntax.Node) (string, bool) {
+	call, ok := node.(*syntax.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return "", false
+	}
+	var name string
+	for _, arg := range call.Args {
+		name = filepath.Base(arg.Lit())
+		if !transparentCommands[name] {
+			break
+		}
+	}
+	return name, true
+}
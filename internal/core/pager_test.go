@@ -0,0 +1,108 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// lockedBuffer is a bytes.Buffer that the pager process and the test can share
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAutoPagerShortOutput(t *testing.T) {
+	out := &lockedBuffer{}
+	pager := newAutoPager("sed 's/^/paged: /'", out, 5)
+
+	_, err := pager.Write([]byte("one\ntwo\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "", out.String(), "Output is held back until it's known to fit")
+
+	require.NoError(t, pager.Close())
+	assert.Equal(t, "one\ntwo\n", out.String())
+}
+
+func TestAutoPagerLongOutput(t *testing.T) {
+	out := &lockedBuffer{}
+	pager := newAutoPager("sed 's/^/paged: /'", out, 3)
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		_, err := pager.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, pager.Close())
+	assert.Equal(t, "paged: one\npaged: two\npaged: three\npaged: four\n", out.String())
+}
+
+func TestAutoPagerSlowOutput(t *testing.T) {
+	out := &lockedBuffer{}
+	pager := newAutoPager("sed 's/^/paged: /'", out, 3)
+
+	_, err := pager.Write([]byte("compiling\n"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return out.String() == "compiling\n"
+	}, 5*pagerDecisionDelay, 10*time.Millisecond)
+
+	// Once streaming, output is never paged
+	_, err = pager.Write([]byte(strings.Repeat("line\n", 10)))
+	require.NoError(t, err)
+	require.NoError(t, pager.Close())
+	assert.Equal(t, "compiling\n"+strings.Repeat("line\n", 10), out.String())
+}
+
+func parseStmt(t *testing.T, command string) *syntax.Stmt {
+	var stmt *syntax.Stmt
+	err := syntax.NewParser().Stmts(strings.NewReader(command), func(s *syntax.Stmt) bool {
+		stmt = s
+		return false
+	})
+	require.NoError(t, err)
+	return stmt
+}
+
+func TestCallsOnly(t *testing.T) {
+	commands := []string{"cat", "find", "grep"}
+	tests := map[string]bool{
+		"cat big.log":           true,
+		"cat big.log | grep x":  true,
+		"find . -name '*.go'":   true,
+		"sudo find / -name x":   true,
+		"ls -la":                false,
+		"cat file | less":       false,
+		"k9s":                   false,
+		"cat big.log &":         false,
+		"find . && vim main.go": false,
+		"/usr/bin/cat big.log":  true,
+		"env FOO=1 lazygit":     false,
+		"grep -r x . | sort -u": false,
+	}
+
+	for command, expected := range tests {
+		assert.Equal(t, expected, callsOnly(parseStmt(t, command), commands), command)
+	}
+
+	// Nothing is let through with no commands opted in
+	assert.False(t, callsOnly(parseStmt(t, "cat big.log"), nil))
+}
@@ -12,6 +12,10 @@ type Paths struct {
 	HistoryFile       string
 	AnalyticsFile     string
 	LatestVersionFile string
+	ManPageCacheDir   string
+	CompletionSpecDir string
+	CrashReportDir    string
+	DraftDir          string
 }
 
 var defaultPaths *Paths
@@ -30,6 +34,10 @@ func ensureDefaultPaths() {
 			HistoryFile:       filepath.Join(homeDir, ".local", "share", "gsh", "history.db"),
 			AnalyticsFile:     filepath.Join(homeDir, ".local", "share", "gsh", "analytics.db"),
 			LatestVersionFile: filepath.Join(homeDir, ".local", "share", "gsh", "latest_version.txt"),
+			ManPageCacheDir:   filepath.Join(homeDir, ".local", "share", "gsh", "man_completions"),
+			CompletionSpecDir: filepath.Join(homeDir, ".config", "gsh", "completions"),
+			CrashReportDir:    filepath.Join(homeDir, ".local", "share", "gsh", "crash_reports"),
+			DraftDir:          filepath.Join(homeDir, ".local", "share", "gsh", "drafts"),
 		}
 
 		err = os.MkdirAll(defaultPaths.DataDir, 0755)
@@ -68,3 +76,23 @@ func LatestVersionFile() string {
 	ensureDefaultPaths()
 	return defaultPaths.LatestVersionFile
 }
+
+func ManPageCacheDir() string {
+	ensureDefaultPaths()
+	return defaultPaths.ManPageCacheDir
+}
+
+func CompletionSpecDir() string {
+	ensureDefaultPaths()
+	return defaultPaths.CompletionSpecDir
+}
+
+func CrashReportDir() string {
+	ensureDefaultPaths()
+	return defaultPaths.CrashReportDir
+}
+
+func DraftDir() string {
+	ensureDefaultPaths()
+	return defaultPaths.DraftDir
+}
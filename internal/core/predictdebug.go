@@ -0,0 +1,54 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/styles"
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"github.com/dustin/go-humanize"
+	"golang.org/x/term"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// showPredictionContext handles `@!predict-debug`, showing what the last
+// prediction was made from through the pager, since the context is usually
+// longer than the screen
+func showPredictionContext(runner *interp.Runner) {
+	context, ok := gline.LastPredictionContext()
+	if !ok {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: No prediction has been made in this session yet.\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+	text := formatPredictionContext(context, time.Now())
+
+	if pager := environment.GetPager(runner); pager != "" && term.IsTerminal(int(os.Stdout.Fd())) {
+		cmd := exec.Command("sh", "-c", pager)
+		cmd.Stdin = strings.NewReader(text)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err == nil {
+			return
+		}
+	}
+	fmt.Print(gline.RESET_CURSOR_COLUMN + text + gline.RESET_CURSOR_COLUMN)
+}
+
+// formatPredictionContext renders what was typed, the prediction, and the
+// context sent to the model for it
+func formatPredictionContext(context gline.PredictionContext, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Typed:      %q (%s)\n", context.Input, humanize.RelTime(context.At, now, "ago", "from now")))
+	sb.WriteString(fmt.Sprintf("Prediction: %q\n\n", context.Prediction))
+	if context.Context == "" {
+		sb.WriteString("Predicted from history without the model, so no context was sent.\n")
+		return sb.String()
+	}
+	sb.WriteString("Context sent to the model:\n\n")
+	sb.WriteString(strings.TrimRight(context.Context, "\n") + "\n")
+	return sb.String()
+}
@@ -0,0 +1,24 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPredictionContext(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	text := formatPredictionContext(gline.PredictionContext{
+		Input:      "git st",
+		Prediction: "git status",
+		Context:    "#Current directory\n/home/me/project\n\n#Recent commands\ngit add .\n",
+		At:         now.Add(-5 * time.Second),
+	}, now)
+	assert.Equal(t, "Typed:      \"git st\" (5 seconds ago)\nPrediction: \"git status\"\n\nContext sent to the model:\n\n#Current directory\n/home/me/project\n\n#Recent commands\ngit add .\n", text)
+
+	text = formatPredictionContext(gline.PredictionContext{Input: "cd ", Prediction: "cd src", At: now}, now)
+	assert.Contains(t, text, "Predicted from history without the model")
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package core
+
+import "syscall"
+
+// processAlive returns whether a process with pid is running
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM means it runs as another user
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package core
+
+import "os"
+
+// processAlive returns whether a process with pid is running
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
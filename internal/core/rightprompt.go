@@ -0,0 +1,25 @@
+package core
+
+import (
+	"context"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/bash"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// rightPromptFetcher returns a function that runs the GSH_RIGHT_PROMPT command
+// in a subshell and returns the first line it printed. The subshell keeps the
+// command from changing the shell's state while the prompt is open.
+func rightPromptFetcher(runner *interp.Runner, logger *zap.Logger, command string) func(ctx context.Context) string {
+	return func(ctx context.Context) string {
+		stdout, _, err := bash.RunBashCommandInSubShell(ctx, runner, command)
+		if err != nil {
+			logger.Debug("GSH_RIGHT_PROMPT failed", zap.String("command", command), zap.Error(err))
+			return ""
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(stdout), "\n")
+		return strings.TrimSpace(line)
+	}
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestRightPromptFetcher(t *testing.T) {
+	runner, err := interp.New()
+	require.NoError(t, err)
+
+	fetch := rightPromptFetcher(runner, zap.NewNop(), `printf '  prod-cluster \nsecond line\n'`)
+	assert.Equal(t, "prod-cluster", fetch(context.Background()))
+
+	fetch = rightPromptFetcher(runner, zap.NewNop(), "exit 1")
+	assert.Equal(t, "", fetch(context.Background()))
+}
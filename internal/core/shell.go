@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"regexp"
@@ -17,6 +18,7 @@ import (
 	"github.com/atinylittleshell/gsh/internal/config"
 	"github.com/atinylittleshell/gsh/internal/environment"
 	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/atinylittleshell/gsh/internal/inputrc"
 	"github.com/atinylittleshell/gsh/internal/idle"
 	"github.com/atinylittleshell/gsh/internal/predict"
 	"github.com/atinylittleshell/gsh/internal/rag"
@@ -26,12 +28,19 @@ import (
 	"github.com/atinylittleshell/gsh/internal/termtitle"
 	"github.com/atinylittleshell/gsh/pkg/gline"
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 	"golang.org/x/term"
 	"mvdan.cc/sh/v3/interp"
 	"mvdan.cc/sh/v3/syntax"
 )
 
+// coachNotificationsShown is how many notifications @!coach notifications lists
+const coachNotificationsShown = 20
+
 func RunInteractiveShell(
 	ctx context.Context,
 	runner *interp.Runner,
@@ -43,6 +52,7 @@ func RunInteractiveShell(
 	stderrCapturer *StderrCapturer,
 ) error {
 	state := &ShellState{}
+	failureExplainer := NewFailureExplainer(runner)
 	contextProvider := &rag.ContextProvider{
 		Logger: logger,
 		Retrievers: []rag.ContextRetriever{
@@ -54,18 +64,45 @@ func RunInteractiveShell(
 		},
 	}
 	predictor := &predict.PredictRouter{
+		DirectoryPredictor: predict.NewNextDirectoryPredictor(runner, historyManager, logger),
+		RepoPredictor:      predict.NewRepoHistoryPredictor(runner, historyManager, logger),
 		PrefixPredictor:    predict.NewLLMPrefixPredictor(runner, historyManager, logger),
 		NullStatePredictor: predict.NewLLMNullStatePredictor(runner, logger),
 	}
 	explainer := predict.NewLLMExplainer(runner, logger)
 	agent := agent.NewAgent(runner, historyManager, logger)
 
+	// Safe mode leaves the prompt without predictions and explanations
+	safeMode := environment.GetSafeMode(runner)
+	var linePredictor gline.Predictor = predictor
+	var lineExplainer gline.Explainer = explainer
+	if safeMode {
+		linePredictor, lineExplainer = nil, nil
+	} else {
+		warmUpPrediction(ctx, runner, predictor, logger)
+	}
+
+	// Warn before git add/commit picks up files that look like secrets
+	if coachManager != nil {
+		AddPreExecHook(func(ctx context.Context, command string) {
+			if warning := coachManager.CheckSecretExposure(command); warning != "" {
+				fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+warning+"\n") + gline.RESET_CURSOR_COLUMN)
+			}
+		})
+	}
+
 	// Set up subagent integration
 	subagentIntegration := subagent.NewSubagentIntegration(runner, historyManager, logger)
 
 	// Set up completion
 	completionProvider := completion.NewShellCompletionProvider(completionManager, runner)
 	completionProvider.SetSubagentProvider(subagentIntegration.GetCompletionProvider())
+	completionProvider.SetDirectoryProvider(historyManager)
+	completionProvider.SetBookmarkProvider(historyManager)
+	completionProvider.SetManPageCacheDir(ManPageCacheDir())
+
+	// Apply the key bindings and settings of ~/.inputrc that gsh supports
+	keyMap := loadInputrc(inputrc.DefaultPath(), logger)
 
 	// Set up idle summary generator
 	idleSummaryGenerator := idle.NewSummaryGenerator(runner, historyManager, logger)
@@ -73,6 +110,35 @@ func RunInteractiveShell(
 	// Set up terminal title manager
 	termTitleManager := termtitle.NewManager(runner, logger)
 
+	// Set up capturing of command output for @!copy-output
+	stdoutCapturer := NewStdoutCapturer(os.Stdout)
+
+	// Set up the transcript of commands and their output for @!transcript
+	transcriptManager, err := history.NewTranscriptManager(historyManager.GetDB(), environment.GetTranscriptMaxBytes(runner, logger))
+	if err != nil {
+		logger.Warn("failed to initialize transcript", zap.Error(err))
+		transcriptManager = nil
+	}
+
+	// Set up loading of .gshenv.local files on directory change
+	localEnv := environment.NewLocalEnv()
+
+	if safeMode {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: safe mode, predictions, explanations, the coach, analytics and logging are off\n") + gline.RESET_CURSOR_COLUMN)
+	}
+	if hint := historyImportHint(historyManager, environment.GetHomeDir(runner)); hint != "" {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: "+hint+"\n") + gline.RESET_CURSOR_COLUMN)
+	}
+
+	// Offer the line a crashed session was typing at the first prompt
+	var recoveredDraft *gline.Draft
+	var staleDrafts []string
+	if environment.GetDraftAutosave(runner) {
+		recoveredDraft, staleDrafts = recoverDraft(DraftDir())
+	}
+
+	terminalSize := newTerminalSizeWatcher(int(os.Stdout.Fd()))
+
 	chanSIGINT := make(chan os.Signal, 1)
 	signal.Notify(chanSIGINT, os.Interrupt)
 
@@ -84,9 +150,13 @@ func RunInteractiveShell(
 	}()
 
 	for {
-		prompt := environment.GetPrompt(runner, logger)
+		updateLocalEnv(ctx, localEnv, runner)
+
+		prompt := environment.GetPrompt(runner, logger, state.LastExitCode)
 		logger.Debug("prompt updated", zap.String("prompt", prompt))
 
+		termTitleManager.SetFormattedTitle("")
+
 		ragContext := contextProvider.GetContext()
 		logger.Debug("context updated", zap.Any("context", ragContext))
 
@@ -114,19 +184,58 @@ func RunInteractiveShell(
 			allHistoryEntries = []history.HistoryEntry{}
 		}
 
+		// A note stays with its command when the command runs again, since the
+		// search only shows the latest run of each command
+		notes := make(map[string]string)
+		for _, entry := range allHistoryEntries {
+			if _, ok := notes[entry.Command]; !ok && entry.Note != "" {
+				notes[entry.Command] = entry.Note
+			}
+		}
+
 		richHistory := make([]shellinput.HistoryItem, len(allHistoryEntries))
 		for i, entry := range allHistoryEntries {
 			richHistory[i] = shellinput.HistoryItem{
 				Command:   entry.Command,
 				Directory: entry.Directory,
 				Timestamp: entry.CreatedAt,
+				Note:      notes[entry.Command],
 			}
 		}
 
 		// Read input
 		options := gline.NewOptions()
 		options.AssistantHeight = environment.GetAssistantHeight(runner, logger)
+		options.Width, options.Height = terminalSize.Size()
+		options.SuggestAfterKill = environment.GetSuggestAfterKill(runner)
+		options.PredictionTimeout = environment.GetPredictionTimeout(runner, logger)
+		options.PredictIgnore = environment.GetPredictIgnore(runner)
+		if environment.GetSubstitutionPreview(runner) {
+			options.SubstitutionPreview = newSubstitutionPreviewer(environment.GetPwd(runner)).Preview
+		}
+		options.CombinedInference = environment.GetCombinedInference(runner)
+		options.ThinkingText = environment.GetThinkingText(runner)
+		options.ExplanationMarkdown = environment.GetExplanationMarkdown(runner)
+		options.AssistantPosition = gline.ParseAssistantPosition(environment.GetAssistantPosition(runner))
+		options.FocusMode = environment.GetFocusMode(runner)
+		options.HistorySearchStyle = shellinput.ParseHistorySearchStyle(environment.GetHistorySearchStyle(runner))
+		options.SuggestCase = shellinput.ParseSuggestCase(environment.GetSuggestCase(runner))
+		options.CompletionPolicy = shellinput.ParseCompletionPolicy(environment.GetPredictionVsCompletion(runner))
+		options.AutoCompleteAfter = environment.GetAutoCompleteAfter(runner, logger)
+		options.FocusModeChanged = func(enabled bool) {
+			value := "0"
+			if enabled {
+				value = "1"
+			}
+			runShellStatement(ctx, runner, "GSH_FOCUS_MODE="+value)
+		}
+		if environment.GetDraftAutosave(runner) {
+			options.DraftFile = draftFile(DraftDir())
+		}
+		options.RecoveredDraft = recoveredDraft
 		options.CompletionProvider = completionProvider
+		options.KeyMap = &keyMap
+		options.CrashReport = environment.GetCrashReport(runner)
 		options.RichHistory = richHistory
 		options.CurrentDirectory = environment.GetPwd(runner)
 
@@ -134,11 +243,20 @@ func RunInteractiveShell(
 		options.CurrentDirectory = environment.GetPwd(runner)
 		options.User = environment.GetUser(runner)
 		options.Host, _ = os.Hostname()
+		options.ShellLevel = environment.GetShellLevel(runner)
+		options.SSHSession = environment.IsSSHSession(runner)
+		if rightPrompt := environment.GetRightPrompt(runner); rightPrompt != "" {
+			options.RightPrompt = []gline.AsyncSegment{{
+				Name:    "GSH_RIGHT_PROMPT",
+				Fetch:   rightPromptFetcher(runner, logger, rightPrompt),
+				Timeout: environment.GetRightPromptTimeout(runner, logger),
+			}}
+		}
 
 		// Configure idle summary
 		idleTimeout := environment.GetIdleSummaryTimeout(runner, logger)
 		options.IdleSummaryTimeout = idleTimeout
-		if idleTimeout > 0 {
+		if idleTimeout > 0 && !safeMode {
 			options.IdleSummaryGenerator = idleSummaryGenerator.GenerateSummary
 		}
 
@@ -156,19 +274,43 @@ func RunInteractiveShell(
 			}
 		}
 
-		line, err := gline.Gline(prompt, historyCommands, coachContent, predictor, explainer, analyticsManager, logger, options)
-
-		logger.Debug("received command", zap.String("line", line))
+		result, err := gline.GlineWithResult(prompt, historyCommands, coachContent, linePredictor, lineExplainer, analyticsManager, logger, options)
+		// A completion still running in the background would read the runner
+		// while the line runs on it
+		completionProvider.Cancel()
 
+		// A recovered draft is only offered at the first prompt
+		if recoveredDraft != nil || len(staleDrafts) > 0 {
+			discardDrafts(staleDrafts, logger)
+			recoveredDraft, staleDrafts = nil, nil
+		}
 		if err != nil {
-			if err == gline.ErrInterrupted {
-				// User pressed Ctrl+C, restart loop with fresh prompt
-				logger.Debug("input interrupted by user")
-				continue
-			}
 			logger.Error("error reading input through gline", zap.Error(err))
 			return err
 		}
+		if result.Interrupted {
+			// User pressed Ctrl+C, restart loop with fresh prompt
+			logger.Debug("input interrupted by user")
+			continue
+		}
+
+		line := result.Command
+		logger.Debug("received command", zap.String("line", line), zap.Bool("fromPrediction", result.FromPrediction), zap.Bool("multiline", result.Multiline))
+
+		// Bookmarks that run a command continue as if the command had been typed
+		if bookmarkArgs, ok := strings.CutPrefix(strings.TrimSpace(line), "@!bookmark"); ok && (bookmarkArgs == "" || strings.HasPrefix(bookmarkArgs, " ")) {
+			message, bookmarkCommand, err := runBookmarkControl(bookmarkArgs, historyManager, environment.GetPwd(runner), state.LastCommand)
+			if err != nil {
+				fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+				continue
+			}
+			if bookmarkCommand == "" {
+				fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+				continue
+			}
+			fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: "+bookmarkCommand+"\n") + gline.RESET_CURSOR_COLUMN)
+			line = bookmarkCommand
+		}
 
 		// Handle agent chat and macros
 		if strings.HasPrefix(line, "@") {
@@ -192,6 +334,28 @@ func RunInteractiveShell(
 				case "tokens":
 					agent.PrintTokenStats()
 					continue
+				case "copy-output":
+					copyLastOutput(runner, logger, state)
+					continue
+				case "latency":
+					printLatencyStats()
+					continue
+				case "rehash":
+					count := completionProvider.Rehash()
+					fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(fmt.Sprintf("gsh: Indexed %d commands in PATH.\n", count)) + gline.RESET_CURSOR_COLUMN)
+					continue
+				case "predict-debug":
+					showPredictionContext(runner)
+					continue
+				case "why":
+					explainLastFailure(failureExplainer, logger, state)
+					continue
+				case "transcript":
+					printTranscript("", transcriptManager, runner)
+					continue
+				case "config context-types":
+					printContextTypes(runner)
+					continue
 				case "config":
 					if err := config.RunConfigUI(runner); err != nil {
 						logger.Error("error running config UI", zap.Error(err))
@@ -201,6 +365,78 @@ func RunInteractiveShell(
 					environment.SyncVariablesToEnv(runner)
 					continue
 				default:
+					if historyArgs, ok := strings.CutPrefix(control, "history"); ok && (historyArgs == "" || strings.HasPrefix(historyArgs, " ")) {
+						message, err := runHistoryControl(historyArgs, historyManager, environment.GetPwd(runner), time.Now())
+						if err != nil {
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+							continue
+						}
+						fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+						continue
+					}
+
+					if benchArgs, ok := strings.CutPrefix(control, "bench"); ok && (benchArgs == "" || strings.HasPrefix(benchArgs, " ")) {
+						benchCommand(ctx, benchArgs, runner, stderrCapturer)
+						continue
+					}
+
+					if localEnvArgs, ok := strings.CutPrefix(control, "localenv"); ok && (localEnvArgs == "" || strings.HasPrefix(localEnvArgs, " ")) {
+						message, err := runLocalEnvControl(localEnvArgs, localEnv, environment.GetPwd(runner))
+						if err != nil {
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+							continue
+						}
+						fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+						continue
+					}
+
+					if analyticsArgs, ok := strings.CutPrefix(control, "analytics"); ok && (analyticsArgs == "" || strings.HasPrefix(analyticsArgs, " ")) {
+						message, err := runAnalyticsControl(analyticsArgs, analyticsManager)
+						if err != nil {
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+							continue
+						}
+						fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+						continue
+					}
+
+					if completeArgs, ok := strings.CutPrefix(control, "complete"); ok && (completeArgs == "" || strings.HasPrefix(completeArgs, " ")) {
+						message, err := runCompleteControl(completeArgs, func(command string) (string, error) {
+							return completionProvider.Discover(command, CompletionSpecDir())
+						})
+						if err != nil {
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+							continue
+						}
+						fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+						continue
+					}
+
+					if noteArgs, ok := strings.CutPrefix(control, "note"); ok && (noteArgs == "" || strings.HasPrefix(noteArgs, " ")) {
+						message, err := runNoteControl(noteArgs, historyManager, state.LastHistoryEntryID)
+						if err != nil {
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+							continue
+						}
+						fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+						continue
+					}
+
+					if toggleArgs, ok := strings.CutPrefix(control, "toggle"); ok && (toggleArgs == "" || strings.HasPrefix(toggleArgs, " ")) {
+						message, err := runToggleControl(ctx, toggleArgs, runner, config.PersistSetting)
+						if err != nil {
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+							continue
+						}
+						fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(message+"\n") + gline.RESET_CURSOR_COLUMN)
+						continue
+					}
+
+					if transcriptArgs, ok := strings.CutPrefix(control, "transcript "); ok {
+						printTranscript(transcriptArgs, transcriptManager, runner)
+						continue
+					}
+
 					// Handle coach command with subcommands
 					if strings.HasPrefix(control, "coach") {
 						if coachManager == nil {
@@ -210,8 +446,9 @@ func RunInteractiveShell(
 
 						// Parse subcommand (e.g., "coach tips" -> "tips")
 						coachArgs := strings.TrimSpace(strings.TrimPrefix(control, "coach"))
+						coachSubcommand, coachOptions, _ := strings.Cut(coachArgs, " ")
 
-						switch coachArgs {
+						switch coachSubcommand {
 						case "", "dashboard":
 							fmt.Print(coachManager.RenderDashboard())
 						case "stats":
@@ -220,15 +457,33 @@ func RunInteractiveShell(
 							fmt.Print(coachManager.RenderAchievements())
 						case "challenges":
 							fmt.Print(coachManager.RenderChallenges())
+						case "heatmap":
+							fmt.Print(coachManager.RenderHeatmap())
 						case "tips":
 							fmt.Print(coachManager.RenderAllTips())
+						case "notifications":
+							switch strings.TrimSpace(coachOptions) {
+							case "":
+								fmt.Print(coachManager.RenderNotifications(coachNotificationsShown))
+							case "mark-read":
+								marked := coachManager.MarkAllNotificationsRead()
+								fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(fmt.Sprintf("Marked %d notifications as read\n", marked)) + gline.RESET_CURSOR_COLUMN)
+							default:
+								fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("Usage: @!coach notifications [mark-read]\n") + gline.RESET_CURSOR_COLUMN)
+							}
 						case "reset-tips":
-							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("Resetting tips and generating new ones from your history...\nThis may take a moment.\n\n") + gline.RESET_CURSOR_COLUMN)
-							result := coachManager.ResetAndRegenerateTips()
+							window, err := coach.ParseTipHistoryWindow(strings.Fields(coachOptions))
+							if err != nil {
+								fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+								fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("Usage: @!coach reset-tips [--since 30d] [--last 500]\n") + gline.RESET_CURSOR_COLUMN)
+								continue
+							}
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("Resetting tips and generating new ones from "+window.Describe()+"...\nThis may take a moment.\n\n") + gline.RESET_CURSOR_COLUMN)
+							result := coachManager.ResetAndRegenerateTips(window)
 							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(result+"\n") + gline.RESET_CURSOR_COLUMN)
 						default:
 							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: Unknown coach command: "+coachArgs+"\n") + gline.RESET_CURSOR_COLUMN)
-							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("Available: @!coach [stats|achievements|challenges|tips|reset-tips]\n") + gline.RESET_CURSOR_COLUMN)
+							fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("Available: @!coach [stats|achievements|challenges|heatmap|tips|notifications|reset-tips]\n") + gline.RESET_CURSOR_COLUMN)
 						}
 						continue
 					}
@@ -306,7 +561,8 @@ func RunInteractiveShell(
 
 					if confirmed {
 						fmt.Println()
-						shouldExit, err := executeCommand(ctx, fixedCmd, historyManager, coachManager, runner, logger, state, stderrCapturer)
+						termTitleManager.SetFormattedTitle(fixedCmd)
+						shouldExit, err := executeCommand(ctx, fixedCmd, historyManager, coachManager, transcriptManager, runner, logger, state, stderrCapturer, stdoutCapturer)
 						if err != nil {
 							fmt.Fprintf(os.Stderr, "Error executing command: %v\n", err)
 						}
@@ -379,7 +635,8 @@ func RunInteractiveShell(
 		}
 
 		// Execute the command
-		shouldExit, err := executeCommand(ctx, line, historyManager, coachManager, runner, logger, state, stderrCapturer)
+		termTitleManager.SetFormattedTitle(line)
+		shouldExit, err := executeCommand(ctx, line, historyManager, coachManager, transcriptManager, runner, logger, state, stderrCapturer, stdoutCapturer)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error executing command: %v\n", err)
 		}
@@ -399,7 +656,7 @@ func RunInteractiveShell(
 	return nil
 }
 
-func executeCommand(ctx context.Context, input string, historyManager *history.HistoryManager, coachManager *coach.CoachManager, runner *interp.Runner, logger *zap.Logger, state *ShellState, stderrCapturer *StderrCapturer) (bool, error) {
+func executeCommand(ctx context.Context, input string, historyManager *history.HistoryManager, coachManager *coach.CoachManager, transcriptManager *history.TranscriptManager, runner *interp.Runner, logger *zap.Logger, state *ShellState, stderrCapturer *StderrCapturer, stdoutCapturer *StdoutCapturer) (bool, error) {
 	// Pre-process input to transform typeset/declare -f/-F/-p commands to gsh_typeset
 	logger.Debug("preprocessing input", zap.String("original_input", input), zap.Int("input_length", len(input)))
 
@@ -409,6 +666,9 @@ func executeCommand(ctx context.Context, input string, historyManager *history.H
 		return false, nil
 	}
 
+	// Decide on the input as typed, since preprocessing may rewrite it
+	recordHistory := !ignoredByHistory(input, environment.GetHistoryIgnore(runner, logger))
+
 	// Add timeout protection for preprocessing
 	preprocessStart := time.Now()
 	logger.Debug("calling bash.PreprocessTypesetCommands", zap.String("input", input))
@@ -445,19 +705,108 @@ func executeCommand(ctx context.Context, input string, historyManager *history.H
 		return false, err
 	}
 
-	historyEntry, _ := historyManager.StartCommand(input, environment.GetPwd(runner))
+	directory := environment.GetPwd(runner)
+	var historyEntry *history.HistoryEntry
+	if recordHistory {
+		recorded := input
+		if environment.GetHistoryExpandAliases(runner) {
+			recorded = bash.ExpandAliases(runner, input)
+		}
+		historyEntry, _ = historyManager.StartCommand(recorded, directory)
+		if historyEntry != nil {
+			state.LastHistoryEntryID = historyEntry.ID
+		}
+	}
+
+	runPreExecHooks(ctx, runner, logger, input)
 
+	previousCommand := state.LastCommand
 	state.LastCommand = input
 	if stderrCapturer != nil {
 		stderrCapturer.StartCapture()
 	}
 
+	// Only route stdout through the capturer when enabled, since it hides the terminal from the command
+	captureMaxBytes := environment.GetOutputCaptureMaxBytes(runner, logger)
+	transcriptMaxBytes := 0
+	if transcriptManager != nil && environment.GetTranscriptEnabled(runner) {
+		transcriptMaxBytes = environment.GetTranscriptMaxBytes(runner, logger)
+		transcriptManager.SetMaxOutputBytes(transcriptMaxBytes)
+	}
+	stdoutMaxBytes := max(captureMaxBytes, transcriptMaxBytes)
+	capturingStdout := stdoutCapturer != nil && stdoutMaxBytes > 0
+	var stderrWriter io.Writer = os.Stderr
+	if stderrCapturer != nil {
+		stderrWriter = stderrCapturer
+	}
+
+	// Send long output through the pager, after the capturer so it still sees everything
+	var stdout io.Writer = os.Stdout
+	pager := newCommandPager(runner, prog)
+	if pager != nil {
+		stdout = pager
+	}
+	// Compare with the last output when the same command runs twice in a row,
+	// for the commands opted in, which lose the terminal as paged ones do
+	var differ *outputDiffer
+	if environment.GetDiffRepeated(runner) && callsOnly(prog, environment.GetDiffCommands(runner)) {
+		repeated := input == previousCommand && input == state.DiffCommand
+		differ = newOutputDiffer(stdout, state.DiffOutput, repeated)
+		stdout = differ
+	}
+	state.DiffCommand, state.DiffOutput = "", ""
+	if capturingStdout {
+		stdoutCapturer.StartCapture(stdoutMaxBytes)
+		stdoutCapturer.SetOutput(stdout)
+		stdout = stdoutCapturer
+	}
+	if stdout != os.Stdout {
+		_ = interp.StdIO(os.Stdin, stdout, stderrWriter)(runner)
+	}
+
 	startTime := time.Now()
-	err = runner.Run(ctx, prog)
+	commandTimeout := environment.GetCommandTimeout(runner, logger)
+	runCtx, cancelRun := bash.WithCommandTimeout(ctx, commandTimeout)
+	err = runner.Run(runCtx, prog)
+	timedOut := bash.CommandTimedOut(runCtx)
+	cancelRun()
 	exited := runner.Exited()
+	if timedOut {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR(fmt.Sprintf("gsh: command timed out after %s\n", commandTimeout)) + gline.RESET_CURSOR_COLUMN)
+	}
 
+	stderrOutput := ""
 	if stderrCapturer != nil {
-		state.LastStderr = stderrCapturer.StopCapture()
+		stderrOutput = stderrCapturer.StopCapture()
+		state.LastStderr = stderrOutput
+	}
+
+	if differ != nil {
+		if err := differ.Close(); err != nil {
+			logger.Debug("failed to write the output diff", zap.Error(err))
+		}
+		if output, ok := differ.Output(); ok {
+			state.DiffCommand, state.DiffOutput = input, output
+		}
+	}
+
+	if pager != nil {
+		if err := pager.Close(); err != nil {
+			logger.Debug("pager exited with an error", zap.Error(err))
+		}
+	}
+
+	output := ""
+	if capturingStdout {
+		output = stdoutCapturer.StopCapture()
+		stdoutCapturer.SetOutput(os.Stdout)
+	}
+	if stdout != os.Stdout {
+		_ = interp.StdIO(os.Stdin, os.Stdout, stderrWriter)(runner)
+	}
+	state.LastOutput = output
+	if len(state.LastOutput) > captureMaxBytes {
+		state.LastOutput = state.LastOutput[:captureMaxBytes]
 	}
 
 	endTime := time.Now()
@@ -465,23 +814,28 @@ func executeCommand(ctx context.Context, input string, historyManager *history.H
 	durationMs := endTime.Sub(startTime).Milliseconds()
 	_, _, _ = bash.RunBashCommand(ctx, runner, fmt.Sprintf("GSH_LAST_COMMAND_DURATION_MS=%d", durationMs))
 
-	var exitCode int
-	if err != nil {
-		status, ok := interp.IsExitStatus(err)
-		if !ok {
-			exitCode = -1
-		} else {
-			exitCode = int(status)
-		}
-	} else {
-		exitCode = 0
+	exitCode := commandExitCode(err)
+	if timedOut {
+		exitCode = bash.CommandTimeoutExitCode
 	}
-
 	state.LastExitCode = exitCode
 
-	_, _ = historyManager.FinishCommand(historyEntry, exitCode)
+	if historyEntry != nil {
+		_, _ = historyManager.FinishCommand(historyEntry, exitCode)
+	}
+
+	// stdout and stderr are captured separately, so the transcript keeps them one after the other
+	if transcriptMaxBytes > 0 && recordHistory {
+		if err := transcriptManager.Record(input, directory, output+stderrOutput, exitCode); err != nil {
+			logger.Warn("failed to record transcript entry", zap.Error(err))
+		}
+	}
 	_, _, _ = bash.RunBashCommand(ctx, runner, fmt.Sprintf("GSH_LAST_COMMAND_EXIT_CODE=%d", exitCode))
 
+	if !exited {
+		runPostExecHooks(ctx, runner, logger, input, exitCode)
+	}
+
 	// Record command for coach gamification
 	if coachManager != nil {
 		coachManager.RecordCommand(input, exitCode, durationMs)
@@ -489,3 +843,108 @@ func executeCommand(ctx context.Context, input string, historyManager *history.H
 
 	return exited, nil
 }
+
+// commandExitCode returns the exit code of a command from the error returned by
+// runner.Run, or -1 if the command couldn't run at all
+func commandExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	status, ok := interp.IsExitStatus(err)
+	if !ok {
+		return -1
+	}
+	return int(status)
+}
+
+// copyLastOutput puts the captured stdout of the last command on the clipboard
+func copyLastOutput(runner *interp.Runner, logger *zap.Logger, state *ShellState) {
+	if environment.GetOutputCaptureMaxBytes(runner, logger) <= 0 {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: Output capture is disabled. Set GSH_OUTPUT_CAPTURE_MAX_BYTES to enable it.\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	if state.LastOutput == "" {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: The last command produced no output.\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	if err := clipboard.WriteAll(state.LastOutput); err != nil {
+		logger.Error("error copying output to clipboard", zap.Error(err))
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: Error copying output: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(fmt.Sprintf("gsh: Copied %d bytes of output to the clipboard.\n", len(state.LastOutput))) + gline.RESET_CURSOR_COLUMN)
+}
+
+// explainLastFailure prints why the last command most likely failed
+func explainLastFailure(explainer *FailureExplainer, logger *zap.Logger, state *ShellState) {
+	if state.LastCommand == "" {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: No command has been run yet.\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+	if state.LastExitCode == 0 {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE("gsh: Last command succeeded.\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	explanation, err := explainer.Explain(state.LastCommand, state.LastExitCode, state.LastStderr)
+	if err != nil {
+		logger.Error("error explaining last failure", zap.Error(err))
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: Error explaining failure: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	header := fmt.Sprintf("gsh: `%s` exited with code %d\n", state.LastCommand, state.LastExitCode)
+	fmt.Print(gline.RESET_CURSOR_COLUMN + styles.AGENT_MESSAGE(header+explanation+"\n") + gline.RESET_CURSOR_COLUMN)
+}
+
+// printContextTypes lists the valid context types and which settings currently use them
+func printContextTypes(runner *interp.Runner) {
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("Context type", "Description", "Used for")
+	for _, contextType := range environment.ContextTypes {
+		var usedFor []string
+		for _, setting := range environment.ContextTypeSettings {
+			if lo.Contains(environment.GetContextTypesForSetting(runner, setting.Name), contextType.Name) {
+				usedFor = append(usedFor, setting.Description)
+			}
+		}
+		t.Row(contextType.Name, contextType.Description, strings.Join(usedFor, ", "))
+	}
+
+	fmt.Print(gline.RESET_CURSOR_COLUMN + t.String() + "\n" + gline.RESET_CURSOR_COLUMN)
+	for _, warning := range environment.ValidateContextTypes(runner) {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+warning+"\n") + gline.RESET_CURSOR_COLUMN)
+	}
+}
+
+// printLatencyStats shows how long predictions and explanations have taken in this session
+func printLatencyStats() {
+	prediction, explanation := gline.SessionLatencyStats()
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("Call", "Count", "Errors", "Last", "Average", "Min", "Max")
+	for _, row := range []struct {
+		name  string
+		stats gline.LatencyStats
+	}{
+		{"Prediction", prediction},
+		{"Explanation", explanation},
+	} {
+		t.Row(
+			row.name,
+			fmt.Sprintf("%d", row.stats.Count),
+			fmt.Sprintf("%d", row.stats.Errors),
+			row.stats.Last.Round(time.Millisecond).String(),
+			row.stats.Average().Round(time.Millisecond).String(),
+			row.stats.Min.Round(time.Millisecond).String(),
+			row.stats.Max.Round(time.Millisecond).String(),
+		)
+	}
+
+	fmt.Print(gline.RESET_CURSOR_COLUMN + t.String() + "\n" + gline.RESET_CURSOR_COLUMN)
+}
@@ -11,6 +11,14 @@ type ShellState struct {
 	LastCommand  string
 	LastExitCode int
 	LastStderr   string
+	LastOutput   string
+	// DiffOutput is the output of DiffCommand, kept with GSH_DIFF_REPEATED to
+	// compare with when the command runs again
+	DiffCommand string
+	DiffOutput  string
+	// LastHistoryEntryID is the history entry of the last command recorded in
+	// this session, which @!note annotates
+	LastHistoryEntryID uint
 }
 
 // StderrCapturer wraps an io.Writer and captures the output into a buffer
@@ -65,3 +73,63 @@ func (c *StderrCapturer) StopCapture() string {
 	c.buffer = nil
 	return res
 }
+
+// StdoutCapturer wraps an io.Writer and captures up to a configurable number of
+// bytes written to it, so the output of the last command can be copied later
+type StdoutCapturer struct {
+	original  io.Writer
+	buffer    *bytes.Buffer
+	maxSize   int
+	mu        sync.Mutex
+	capturing bool
+}
+
+func NewStdoutCapturer(original io.Writer) *StdoutCapturer {
+	return &StdoutCapturer{
+		original: original,
+	}
+}
+
+func (c *StdoutCapturer) Write(p []byte) (n int, err error) {
+	c.mu.Lock()
+	if c.capturing && c.buffer != nil {
+		remaining := c.maxSize - c.buffer.Len()
+		if remaining > 0 {
+			toWrite := p
+			if len(toWrite) > remaining {
+				toWrite = toWrite[:remaining]
+			}
+			c.buffer.Write(toWrite)
+		}
+	}
+	c.mu.Unlock()
+	return c.original.Write(p)
+}
+
+// SetOutput changes where output is written on to, e.g. to the pager
+func (c *StdoutCapturer) SetOutput(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.original = w
+}
+
+// StartCapture begins capturing output, keeping at most maxSize bytes
+func (c *StdoutCapturer) StartCapture(maxSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capturing = true
+	c.maxSize = maxSize
+	c.buffer = new(bytes.Buffer)
+}
+
+func (c *StdoutCapturer) StopCapture() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capturing = false
+	if c.buffer == nil {
+		return ""
+	}
+	res := c.buffer.String()
+	c.buffer = nil
+	return res
+}
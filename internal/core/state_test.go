@@ -0,0 +1,25 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdoutCapturer(t *testing.T) {
+	var original bytes.Buffer
+	capturer := NewStdoutCapturer(&original)
+
+	// Output written outside of a capture is passed through but not kept
+	_, _ = capturer.Write([]byte("before "))
+	capturer.StartCapture(10)
+	_, _ = capturer.Write([]byte("hello "))
+	_, _ = capturer.Write([]byte("world, this is long"))
+	captured := capturer.StopCapture()
+	_, _ = capturer.Write([]byte(" after"))
+
+	assert.Equal(t, "hello worl", captured)
+	assert.Equal(t, "before hello world, this is long after", original.String())
+	assert.Equal(t, "", capturer.StopCapture())
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+const (
+	// substitutionPreviewTimeout bounds each command run for a preview
+	substitutionPreviewTimeout = time.Second
+	// substitutionPreviewMaxLength truncates long previewed output
+	substitutionPreviewMaxLength = 120
+)
+
+// noArgs accepts a command only when it's given no arguments, for commands
+// like hostname that change things when given some
+func noArgs(args []string) bool {
+	return len(args) == 0
+}
+
+func anyArgs(args []string) bool {
+	return true
+}
+
+func onlyFlags(args []string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// substitutionPreviewCommands lists the read-only commands a substitution may
+// run for a preview, with a check of their arguments
+var substitutionPreviewCommands = map[string]func(args []string) bool{
+	"pwd":      noArgs,
+	"whoami":   noArgs,
+	"hostname": noArgs,
+	"arch":     noArgs,
+	"tty":      noArgs,
+	"nproc":    onlyFlags,
+	"uname":    onlyFlags,
+	"id":       onlyFlags,
+	"basename": anyArgs,
+	"dirname":  anyArgs,
+	"echo":     anyArgs,
+	"date": func(args []string) bool {
+		// Other arguments may set the clock
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "+") && !slices.Contains([]string{"-u", "--utc", "-I", "--iso-8601", "-R"}, arg) {
+				return false
+			}
+		}
+		return true
+	},
+	"git": func(args []string) bool {
+		if len(args) == 0 {
+			return false
+		}
+		switch args[0] {
+		case "rev-parse", "describe":
+			return true
+		case "branch":
+			return len(args) == 2 && args[1] == "--show-current"
+		case "config":
+			return len(args) == 3 && args[1] == "--get"
+		}
+		return false
+	},
+}
+
+// substitutionPreviewer previews what the command substitutions of a command
+// line expand to, running only the ones in substitutionPreviewCommands.
+// Results are kept for the life of the prompt, so they don't run again on
+// every keystroke.
+type substitutionPreviewer struct {
+	dir string
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func newSubstitutionPreviewer(dir string) *substitutionPreviewer {
+	return &substitutionPreviewer{dir: dir, cache: make(map[string]string)}
+}
+
+// Preview returns a line per substitution that could be previewed, such as
+// "$(git rev-parse --short HEAD) → 1a2b3c4", or an empty string
+func (p *substitutionPreviewer) Preview(ctx context.Context, input string) string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(input), "")
+	if err != nil {
+		return ""
+	}
+
+	var lines []string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		subst, ok := node.(*syntax.CmdSubst)
+		if !ok {
+			return true
+		}
+		args, ok := previewableCommand(subst)
+		if !ok {
+			return true
+		}
+		source := input[subst.Pos().Offset():subst.End().Offset()]
+		lines = append(lines, source+" → "+p.run(ctx, source, args))
+		return true
+	})
+	return strings.Join(lines, "\n")
+}
+
+func (p *substitutionPreviewer) run(ctx context.Context, source string, args []string) string {
+	p.mu.Lock()
+	output, ok := p.cache[source]
+	p.mu.Unlock()
+	if ok {
+		return output
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, substitutionPreviewTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = p.dir
+	cmd.Env = os.Environ()
+	stdout, err := cmd.Output()
+	if ctx.Err() != nil {
+		// Don't remember a timeout or a closed prompt, it may work next time
+		return "(timed out)"
+	}
+
+	// The shell joins the lines of the output with spaces when it's unquoted,
+	// which is also what fits on one line
+	output = strings.Join(strings.Fields(string(stdout)), " ")
+	if err != nil {
+		output = "(failed: " + err.Error() + ")"
+	}
+	if len(output) > substitutionPreviewMaxLength {
+		output = output[:substitutionPreviewMaxLength] + "…"
+	}
+
+	p.mu.Lock()
+	p.cache[source] = output
+	p.mu.Unlock()
+	return output
+}
+
+// previewableCommand returns the command and arguments of a substitution that
+// is a single allowed command with literal arguments, and nothing else
+func previewableCommand(subst *syntax.CmdSubst) ([]string, bool) {
+	if len(subst.Stmts) != 1 {
+		return nil, false
+	}
+	stmt := subst.Stmts[0]
+	if stmt.Negated || stmt.Background || stmt.Coprocess || len(stmt.Redirs) > 0 {
+		return nil, false
+	}
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Assigns) > 0 || len(call.Args) == 0 {
+		return nil, false
+	}
+
+	args := make([]string, len(call.Args))
+	for i, word := range call.Args {
+		if args[i], ok = literalWord(word); !ok {
+			return nil, false
+		}
+	}
+	allowed, ok := substitutionPreviewCommands[args[0]]
+	if !ok || !allowed(args[1:]) {
+		return nil, false
+	}
+	return args, true
+}
+
+// literalWord returns the value of a word without expansions, such as 'a b'
+// or "+%F", so what a preview runs is exactly what's on the command line
+func literalWord(word *syntax.Word) (string, bool) {
+	var sb strings.Builder
+	for _, part := range word.Parts {
+		switch part := part.(type) {
+		case *syntax.Lit:
+			if strings.ContainsAny(part.Value, "*?[~\\") {
+				return "", false
+			}
+			sb.WriteString(part.Value)
+		case *syntax.SglQuoted:
+			if part.Dollar {
+				return "", false
+			}
+			sb.WriteString(part.Value)
+		case *syntax.DblQuoted:
+			for _, inner := range part.Parts {
+				lit, ok := inner.(*syntax.Lit)
+				if !ok || strings.Contains(lit.Value, "\\") {
+					return "", false
+				}
+				sb.WriteString(lit.Value)
+			}
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+func TestPreviewableCommand(t *testing.T) {
+	tests := []struct {
+		subst    string
+		expected []string
+	}{
+		{"$(pwd)", []string{"pwd"}},
+		{"$(date +%F)", []string{"date", "+%F"}},
+		{"$(date '+%Y %m')", []string{"date", "+%Y %m"}},
+		{"`git rev-parse --short HEAD`", []string{"git", "rev-parse", "--short", "HEAD"}},
+		{`$(basename "my file.txt")`, []string{"basename", "my file.txt"}},
+		{"$(git branch --show-current)", []string{"git", "branch", "--show-current"}},
+		{"$(date -s tomorrow)", nil},
+		{"$(hostname evil)", nil},
+		{"$(git branch -D main)", nil},
+		{"$(rm -rf /)", nil},
+		{"$(echo $HOME)", nil},
+		{"$(echo $(rm x))", nil},
+		{"$(echo *)", nil},
+		{"$(pwd > out)", nil},
+		{"$(pwd; rm x)", nil},
+		{"$(pwd | tee out)", nil},
+		{"$(FOO=1 pwd)", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subst, func(t *testing.T) {
+			file, err := syntax.NewParser().Parse(strings.NewReader("echo "+tt.subst), "")
+			require.NoError(t, err)
+			var subst *syntax.CmdSubst
+			syntax.Walk(file, func(node syntax.Node) bool {
+				if s, ok := node.(*syntax.CmdSubst); ok && subst == nil {
+					subst = s
+				}
+				return true
+			})
+			require.NotNil(t, subst)
+
+			args, ok := previewableCommand(subst)
+			assert.Equal(t, tt.expected != nil, ok)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+}
+
+func TestSubstitutionPreviewer(t *testing.T) {
+	dir := t.TempDir()
+	previewer := newSubstitutionPreviewer(dir)
+	ctx := context.Background()
+
+	assert.Equal(t, "", previewer.Preview(ctx, "ls -la"))
+	assert.Equal(t, "", previewer.Preview(ctx, "echo $(rm -rf x)"))
+	assert.Equal(t, "$(pwd) → "+dir, previewer.Preview(ctx, "cd $(pwd)"))
+	assert.Equal(t,
+		"$(echo a   b) → a b\n`dirname /tmp/x` → /tmp",
+		previewer.Preview(ctx, "cp $(echo a   b) `dirname /tmp/x`"))
+	assert.Equal(t, "", previewer.Preview(ctx, "echo $(unclosed"))
+}
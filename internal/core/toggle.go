@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"mvdan.cc/sh/v3/interp"
+)
+
+const toggleUsage = "Usage: @!toggle [--persist] <setting>"
+
+// runToggleControl handles `@!toggle [--persist] <setting>`, flipping one of
+// environment.ToggleSettings for this session and, with --persist, for future
+// ones through persist. It returns a message to show.
+func runToggleControl(ctx context.Context, args string, runner *interp.Runner, persist func(key, value string) error) (string, error) {
+	var name string
+	persistent := false
+	for _, arg := range strings.Fields(args) {
+		switch {
+		case arg == "--persist":
+			persistent = true
+		case name == "":
+			name = strings.ToUpper(arg)
+		default:
+			return "", fmt.Errorf("unexpected argument %q. %s", arg, toggleUsage)
+		}
+	}
+	if name == "" {
+		return toggleUsage + "\nSettings: " + strings.Join(environment.ToggleSettings, ", "), nil
+	}
+	if !strings.HasPrefix(name, "GSH_") {
+		name = "GSH_" + name
+	}
+	if !slices.Contains(environment.ToggleSettings, name) {
+		return "", fmt.Errorf("%s can't be toggled. Settings: %s", name, strings.Join(environment.ToggleSettings, ", "))
+	}
+
+	current := strings.ToLower(runner.Vars[name].String())
+	value, state := "1", "on"
+	if current == "1" || current == "true" {
+		value, state = "0", "off"
+	}
+	runShellStatement(ctx, runner, name+"="+value)
+	environment.SyncVariableToEnv(runner, name)
+
+	if !persistent {
+		return fmt.Sprintf("gsh: %s is now %s for this session.", name, state), nil
+	}
+	if err := persist(name, value); err != nil {
+		return "", fmt.Errorf("%s is now %s, but could not be saved: %w", name, state, err)
+	}
+	return fmt.Sprintf("gsh: %s is now %s, and saved for future sessions.", name, state), nil
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestRunToggleControl(t *testing.T) {
+	runner, err := interp.New()
+	require.NoError(t, err)
+	ctx := context.Background()
+	persisted := map[string]string{}
+	persist := func(key, value string) error {
+		persisted[key] = value
+		return nil
+	}
+
+	message, err := runToggleControl(ctx, "", runner, persist)
+	require.NoError(t, err)
+	assert.Contains(t, message, "GSH_FOCUS_MODE")
+
+	message, err = runToggleControl(ctx, " GSH_FOCUS_MODE", runner, persist)
+	require.NoError(t, err)
+	assert.Equal(t, "gsh: GSH_FOCUS_MODE is now on for this session.", message)
+	assert.Equal(t, "1", runner.Vars["GSH_FOCUS_MODE"].String())
+	assert.Empty(t, persisted)
+
+	message, err = runToggleControl(ctx, " --persist focus_mode", runner, persist)
+	require.NoError(t, err)
+	assert.Equal(t, "gsh: GSH_FOCUS_MODE is now off, and saved for future sessions.", message)
+	assert.Equal(t, "0", runner.Vars["GSH_FOCUS_MODE"].String())
+	assert.Equal(t, map[string]string{"GSH_FOCUS_MODE": "0"}, persisted)
+
+	_, err = runToggleControl(ctx, " GSH_PROMPT", runner, persist)
+	assert.ErrorContains(t, err, "GSH_PROMPT can't be toggled")
+
+	_, err = runToggleControl(ctx, " GSH_FOCUS_MODE GSH_TRANSCRIPT", runner, persist)
+	assert.ErrorContains(t, err, "unexpected argument")
+
+	_, err = runToggleControl(ctx, " --persist GSH_TRANSCRIPT", runner, func(key, value string) error {
+		return errors.New("read-only")
+	})
+	assert.ErrorContains(t, err, "GSH_TRANSCRIPT is now on, but could not be saved: read-only")
+	assert.Equal(t, "1", runner.Vars["GSH_TRANSCRIPT"].String())
+}
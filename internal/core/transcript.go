@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/atinylittleshell/gsh/internal/styles"
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"github.com/atinylittleshell/gsh/pkg/reverse"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// transcriptResultLimit is how many transcript entries @!transcript shows
+const transcriptResultLimit = 20
+
+// printTranscript prints the result of `@!transcript [query]`
+func printTranscript(args string, transcriptManager *history.TranscriptManager, runner *interp.Runner) {
+	if transcriptManager == nil {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: Transcript not initialized\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+
+	message, err := runTranscriptControl(args, transcriptManager, environment.GetTranscriptEnabled(runner))
+	if err != nil {
+		fmt.Print(gline.RESET_CURSOR_COLUMN + styles.ERROR("gsh: "+err.Error()+"\n") + gline.RESET_CURSOR_COLUMN)
+		return
+	}
+	fmt.Print(gline.RESET_CURSOR_COLUMN + strings.TrimRight(message, "\n") + "\n" + gline.RESET_CURSOR_COLUMN)
+}
+
+// runTranscriptControl handles `@!transcript [query]`, showing the most recent
+// transcript entries or those whose command or output contains query
+func runTranscriptControl(args string, transcriptManager *history.TranscriptManager, transcriptEnabled bool) (string, error) {
+	query := strings.TrimSpace(args)
+	entries, err := transcriptManager.Search(query, transcriptResultLimit)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 0 {
+		switch {
+		case query != "":
+			return fmt.Sprintf("No transcript entries match %q.", query), nil
+		case !transcriptEnabled:
+			return "The transcript is empty. Set GSH_TRANSCRIPT=1 to start recording commands and their output.", nil
+		default:
+			return "The transcript is empty.", nil
+		}
+	}
+
+	// Oldest first, so the most recent entry ends up next to the prompt
+	reverse.Reverse(entries)
+	return formatTranscriptEntries(entries), nil
+}
+
+// formatTranscriptEntries renders entries as a header line with time, directory
+// and exit code, the command, and the output indented below it
+func formatTranscriptEntries(entries []history.TranscriptEntry) string {
+	var sb strings.Builder
+	for i, entry := range entries {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		header := fmt.Sprintf("%s  %s", entry.CreatedAt.Format("2006-01-02 15:04:05"), entry.Directory)
+		if entry.ExitCode != 0 {
+			sb.WriteString(styles.ERROR(fmt.Sprintf("%s  exit %d", header, entry.ExitCode)) + "\n")
+		} else {
+			sb.WriteString(styles.AGENT_MESSAGE(header) + "\n")
+		}
+		sb.WriteString("$ " + entry.Command + "\n")
+
+		output := strings.TrimRight(entry.Output, "\n")
+		if output == "" {
+			continue
+		}
+		for _, line := range strings.Split(output, "\n") {
+			sb.WriteString("    " + strings.TrimRight(line, "\r") + "\n")
+		}
+	}
+	return sb.String()
+}
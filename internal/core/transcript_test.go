@@ -0,0 +1,44 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTranscriptControl(t *testing.T) {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	transcriptManager, err := history.NewTranscriptManager(historyManager.GetDB(), 1024)
+	require.NoError(t, err)
+
+	message, err := runTranscriptControl("", transcriptManager, false)
+	require.NoError(t, err)
+	assert.Contains(t, message, "GSH_TRANSCRIPT=1")
+
+	message, err = runTranscriptControl("", transcriptManager, true)
+	require.NoError(t, err)
+	assert.Equal(t, "The transcript is empty.", message)
+
+	require.NoError(t, transcriptManager.Record("make build", "/src", "compiling\ndone\n", 0))
+	require.NoError(t, transcriptManager.Record("make test", "/src", "FAIL: TestLogin\n", 2))
+
+	// Most recent entry last, with the output indented under its command
+	message, err = runTranscriptControl("", transcriptManager, true)
+	require.NoError(t, err)
+	assert.Less(t, strings.Index(message, "$ make build"), strings.Index(message, "$ make test"))
+	assert.Contains(t, message, "$ make build\n    compiling\n    done\n")
+	assert.Contains(t, message, "exit 2")
+
+	message, err = runTranscriptControl(" TestLogin ", transcriptManager, true)
+	require.NoError(t, err)
+	assert.Contains(t, message, "$ make test")
+	assert.NotContains(t, message, "make build")
+
+	message, err = runTranscriptControl("deploy", transcriptManager, true)
+	require.NoError(t, err)
+	assert.Equal(t, `No transcript entries match "deploy".`, message)
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/utils"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// predictionWarmUpTimeout bounds the warm-up request, which may have to wait
+// for the model to load
+const predictionWarmUpTimeout = 30 * time.Second
+
+type predictionWarmer interface {
+	WarmUp(ctx context.Context) error
+}
+
+// warmUpPrediction warms up the prediction model in the background when
+// GSH_WARMUP_PREDICTION asks for it, so the first prediction of the session
+// doesn't pay for opening the connection or loading the model
+func warmUpPrediction(ctx context.Context, runner *interp.Runner, warmer predictionWarmer, logger *zap.Logger) {
+	if !shouldWarmUpPrediction(runner) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, predictionWarmUpTimeout)
+		defer cancel()
+
+		start := time.Now()
+		if err := warmer.WarmUp(ctx); err != nil {
+			logger.Debug("failed to warm up prediction model", zap.Error(err))
+			return
+		}
+		logger.Debug("warmed up prediction model", zap.Duration("duration", time.Since(start)))
+	}()
+}
+
+func shouldWarmUpPrediction(runner *interp.Runner) bool {
+	switch environment.GetWarmupPrediction(runner) {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	// Local models are often loaded on demand, warming them up would take
+	// memory from a machine that may not predict at all
+	return !utils.IsLocalModel(runner, utils.FastModel)
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+type fakeWarmer struct {
+	called chan struct{}
+}
+
+func (w *fakeWarmer) WarmUp(ctx context.Context) error {
+	close(w.called)
+	return nil
+}
+
+func newWarmUpRunner(t *testing.T, vars map[string]string) *interp.Runner {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	for name, value := range vars {
+		runner.Vars[name] = expand.Variable{Kind: expand.String, Str: value}
+	}
+	return runner
+}
+
+func TestShouldWarmUpPrediction(t *testing.T) {
+	// Ollama on localhost by default
+	assert.False(t, shouldWarmUpPrediction(newWarmUpRunner(t, nil)))
+	assert.True(t, shouldWarmUpPrediction(newWarmUpRunner(t, map[string]string{"GSH_FAST_MODEL_PROVIDER": "openai"})))
+	assert.True(t, shouldWarmUpPrediction(newWarmUpRunner(t, map[string]string{"GSH_WARMUP_PREDICTION": "1"})))
+	assert.False(t, shouldWarmUpPrediction(newWarmUpRunner(t, map[string]string{"GSH_WARMUP_PREDICTION": "0", "GSH_FAST_MODEL_PROVIDER": "openai"})))
+}
+
+func TestWarmUpPrediction(t *testing.T) {
+	warmer := &fakeWarmer{called: make(chan struct{})}
+	warmUpPrediction(context.Background(), newWarmUpRunner(t, map[string]string{"GSH_WARMUP_PREDICTION": "1"}), warmer, zap.NewNop())
+
+	select {
+	case <-warmer.called:
+	case <-time.After(time.Second):
+		t.Fatal("prediction model was not warmed up")
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/utils"
+	openai "github.com/sashabaranov/go-openai"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// maxWhyStderrBytes limits how much of the captured stderr is sent to the LLM
+const maxWhyStderrBytes = 4096
+
+// failureKey identifies a failure whose explanation can be reused
+type failureKey struct {
+	command  string
+	exitCode int
+}
+
+// FailureExplainer explains why the last command failed for `@!why`. Unlike
+// the magic fix, it only explains and never proposes a command to run.
+// Explanations are cached per command and exit code, so asking again is free.
+type FailureExplainer struct {
+	runner *interp.Runner
+
+	// complete sends the prompt to the fast model, can be replaced in tests
+	complete func(systemPrompt string, userPrompt string) (string, error)
+
+	mu    sync.Mutex
+	cache map[failureKey]string
+}
+
+// NewFailureExplainer creates a FailureExplainer using the fast model
+func NewFailureExplainer(runner *interp.Runner) *FailureExplainer {
+	e := &FailureExplainer{
+		runner: runner,
+		cache:  make(map[failureKey]string),
+	}
+	e.complete = e.completeWithFastModel
+	return e
+}
+
+// Explain returns a short explanation of the failure and common fixes
+func (e *FailureExplainer) Explain(command string, exitCode int, stderr string) (string, error) {
+	key := failureKey{command: command, exitCode: exitCode}
+
+	e.mu.Lock()
+	cached, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	explanation, err := e.complete(whySystemPrompt, buildWhyPrompt(command, exitCode, stderr))
+	if err != nil {
+		return "", err
+	}
+	explanation = strings.TrimSpace(explanation)
+
+	e.mu.Lock()
+	e.cache[key] = explanation
+	e.mu.Unlock()
+	return explanation, nil
+}
+
+const whySystemPrompt = `You are a shell expert explaining why a command failed.
+Given the command, its exit code and its stderr output, explain in at most 3 short sentences what most likely went wrong, then list up to 3 common fixes as bullet points.
+
+Rules:
+- Mention what the exit code conventionally means when it is informative (e.g. 126, 127, 130, 137)
+- Do not rewrite or run the command, only explain
+- Output plain text without markdown code blocks`
+
+// buildWhyPrompt describes the failure, keeping only the tail of long stderr
+// output since that is usually where the error is
+func buildWhyPrompt(command string, exitCode int, stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) > maxWhyStderrBytes {
+		stderr = "..." + stderr[len(stderr)-maxWhyStderrBytes:]
+	}
+	if stderr == "" {
+		stderr = "(no stderr output was captured)"
+	}
+	return fmt.Sprintf("Command: %s\nExit code: %d\nStderr:\n%s", command, exitCode, stderr)
+}
+
+func (e *FailureExplainer) completeWithFastModel(systemPrompt string, userPrompt string) (string, error) {
+	client, modelConfig := utils.GetLLMClient(e.runner, utils.FastModel)
+
+	request := openai.ChatCompletionRequest{
+		Model: modelConfig.ModelId,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: userPrompt},
+		},
+	}
+	if modelConfig.Temperature != nil {
+		request.Temperature = float32(*modelConfig.Temperature)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := client.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("LLM API call failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from LLM")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureExplainerCachesPerCommandAndExitCode(t *testing.T) {
+	explainer := NewFailureExplainer(nil)
+	calls := 0
+	var lastPrompt string
+	explainer.complete = func(systemPrompt string, userPrompt string) (string, error) {
+		calls++
+		lastPrompt = userPrompt
+		return "  command not found  \n", nil
+	}
+
+	explanation, err := explainer.Explain("gti status", 127, "gti: command not found")
+	require.NoError(t, err)
+	assert.Equal(t, "command not found", explanation)
+	assert.Contains(t, lastPrompt, "Command: gti status")
+	assert.Contains(t, lastPrompt, "Exit code: 127")
+	assert.Contains(t, lastPrompt, "gti: command not found")
+
+	_, err = explainer.Explain("gti status", 127, "gti: command not found")
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	_, err = explainer.Explain("gti status", 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestBuildWhyPrompt(t *testing.T) {
+	assert.Contains(t, buildWhyPrompt("false", 1, ""), "(no stderr output was captured)")
+
+	long := strings.Repeat("a", maxWhyStderrBytes) + "the real error"
+	prompt := buildWhyPrompt("make", 2, long)
+	assert.Contains(t, prompt, "...")
+	assert.True(t, strings.HasSuffix(prompt, "the real error"))
+}
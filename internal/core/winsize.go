@@ -0,0 +1,52 @@
+package core
+
+import (
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// terminalSizeWatcher keeps track of the terminal size. Resizes while a
+// command runs are picked up through SIGWINCH, so the next prompt doesn't
+// render its borders with the width the terminal had before.
+type terminalSizeWatcher struct {
+	fd     int
+	mu     sync.Mutex
+	width  int
+	height int
+}
+
+func newTerminalSizeWatcher(fd int) *terminalSizeWatcher {
+	w := &terminalSizeWatcher{fd: fd}
+	w.update()
+
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	go func() {
+		for range resized {
+			w.update()
+		}
+	}()
+	return w
+}
+
+func (w *terminalSizeWatcher) update() {
+	width, height, err := term.GetSize(w.fd)
+	if err != nil || width <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.width = width
+	w.height = height
+}
+
+// Size queries the current terminal size, falling back to the last known one
+// when the terminal can't be queried. It returns zeros if it never could.
+func (w *terminalSizeWatcher) Size() (width int, height int) {
+	w.update()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.width, w.height
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package core
+
+import "os"
+
+// notifyResize does nothing, as Windows has no resize signal. The size is
+// still queried before each prompt.
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,173 @@
+// Package crash writes a local diagnostic file when gsh panics, so bug reports
+// can include the stack and the state that led to it. Reports never leave the
+// machine.
+package crash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Panic is a recovered panic value with the stack it was raised on. It's
+// re-panicked by code that has to clean up before the panic goes further, so
+// the report still points at where it started.
+type Panic struct {
+	Value any
+	Stack []byte
+}
+
+func (p *Panic) Error() string {
+	return fmt.Sprint(p.Value)
+}
+
+// Capture wraps a recovered value with the current stack, which still holds
+// the panicking frames while deferred functions run. It returns nil for nil
+// and leaves values that were already captured as they are.
+func Capture(recovered any) *Panic {
+	if recovered == nil {
+		return nil
+	}
+	if p, ok := recovered.(*Panic); ok {
+		return p
+	}
+	return &Panic{Value: recovered, Stack: debug.Stack()}
+}
+
+// secretNamePattern matches variable names whose values are left out of reports
+var secretNamePattern = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|AUTH|HEADERS)`)
+
+// Redact returns a copy of vars with the values of secret-looking variables
+// replaced
+func Redact(vars map[string]string) map[string]string {
+	redacted := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if value != "" && secretNamePattern.MatchString(name) {
+			value = "<redacted>"
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// Report is what gets written about a panic
+type Report struct {
+	Panic          *Panic
+	Version        string
+	Time           time.Time
+	RecentCommands []string
+	Config         map[string]string
+}
+
+// WriteTo writes the report as plain text
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gsh crash report\n\n")
+	fmt.Fprintf(&b, "version: %s\n", r.Version)
+	fmt.Fprintf(&b, "time:    %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "\npanic: %v\n\n%s\n", r.Panic.Value, r.Panic.Stack)
+
+	fmt.Fprintf(&b, "\nrecent commands:\n")
+	for _, command := range r.RecentCommands {
+		fmt.Fprintf(&b, "  %s\n", command)
+	}
+
+	fmt.Fprintf(&b, "\nconfig:\n")
+	names := make([]string, 0, len(r.Config))
+	for name := range r.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s=%s\n", name, r.Config[name])
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Write saves the report in dir and returns the path of the file
+func Write(dir string, report Report) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s.txt", report.Time.Format("20060102-150405")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := report.WriteTo(file); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Reporter turns panics into reports. The callbacks are only called after a
+// panic, and may be nil.
+type Reporter struct {
+	Dir     string
+	Version string
+	// Enabled reports whether a report should be written, so the setting can
+	// change during the session
+	Enabled        func() bool
+	RecentCommands func() []string
+	Config         func() map[string]string
+	// Stderr is where the message pointing to the report goes, os.Stderr if nil
+	Stderr io.Writer
+	// Exit ends the process after a report, os.Exit if nil
+	Exit func(code int)
+}
+
+// Recover is deferred around code that may panic. When reports are enabled it
+// writes one and exits with status 2, like an unrecovered panic. Otherwise the
+// panic goes on untouched.
+func (r *Reporter) Recover() {
+	if r.Enabled != nil && !r.Enabled() {
+		return
+	}
+	p := Capture(recover())
+	if p == nil {
+		return
+	}
+	r.report(p)
+}
+
+func (r *Reporter) report(p *Panic) {
+	stderr := r.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	exit := r.Exit
+	if exit == nil {
+		exit = os.Exit
+	}
+
+	report := Report{Panic: p, Version: r.Version, Time: time.Now()}
+	// The state getters may be what's broken, so don't let them stop the report
+	func() {
+		defer func() { _ = recover() }()
+		if r.RecentCommands != nil {
+			report.RecentCommands = r.RecentCommands()
+		}
+		if r.Config != nil {
+			report.Config = Redact(r.Config())
+		}
+	}()
+
+	path, err := Write(r.Dir, report)
+	if err != nil {
+		fmt.Fprintf(stderr, "gsh crashed: %v\n\n%s\ncould not write a crash report: %v\n", p.Value, p.Stack, err)
+	} else {
+		fmt.Fprintf(stderr, "gsh crashed: %v\n\nA crash report was saved to %s.\nIt stays on this machine, attach it when reporting the bug to help fix it.\n", p.Value, path)
+	}
+	exit(2)
+}
@@ -0,0 +1,107 @@
+package crash
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture(t *testing.T) {
+	assert.Nil(t, Capture(nil))
+
+	p := Capture("boom")
+	require.NotNil(t, p)
+	assert.Equal(t, "boom", p.Value)
+	assert.Contains(t, string(p.Stack), "TestCapture")
+
+	assert.Same(t, p, Capture(p), "an already captured panic keeps its stack")
+}
+
+func TestRedact(t *testing.T) {
+	redacted := Redact(map[string]string{
+		"GSH_FAST_MODEL_API_KEY": "sk-123",
+		"GSH_SLOW_MODEL_HEADERS": `{"Authorization": "Bearer x"}`,
+		"GSH_AUTH_TOKEN":         "",
+		"GSH_PROMPT":             "gsh> ",
+	})
+
+	assert.Equal(t, map[string]string{
+		"GSH_FAST_MODEL_API_KEY": "<redacted>",
+		"GSH_SLOW_MODEL_HEADERS": "<redacted>",
+		"GSH_AUTH_TOKEN":         "",
+		"GSH_PROMPT":             "gsh> ",
+	}, redacted)
+}
+
+func TestReporterWritesReport(t *testing.T) {
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	exitCode := -1
+	reporter := &Reporter{
+		Dir:            dir,
+		Version:        "1.2.3",
+		Enabled:        func() bool { return true },
+		RecentCommands: func() []string { return []string{"ls", "git status"} },
+		Config: func() map[string]string {
+			return map[string]string{"GSH_PROMPT": "> ", "GSH_FAST_MODEL_API_KEY": "sk-123"}
+		},
+		Stderr: &stderr,
+		Exit:   func(code int) { exitCode = code },
+	}
+
+	func() {
+		defer reporter.Recover()
+		panic("boom")
+	}()
+
+	assert.Equal(t, 2, exitCode)
+	assert.Contains(t, stderr.String(), "gsh crashed: boom")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Contains(t, stderr.String(), entries[0].Name())
+
+	data, err := os.ReadFile(dir + "/" + entries[0].Name())
+	require.NoError(t, err)
+	report := string(data)
+	assert.Contains(t, report, "version: 1.2.3")
+	assert.Contains(t, report, "panic: boom")
+	assert.Contains(t, report, "TestReporterWritesReport")
+	assert.Contains(t, report, "  git status\n")
+	assert.Contains(t, report, "  GSH_PROMPT=> \n")
+	assert.Contains(t, report, "  GSH_FAST_MODEL_API_KEY=<redacted>\n")
+	assert.False(t, strings.Contains(report, "sk-123"))
+}
+
+func TestReporterDisabled(t *testing.T) {
+	reporter := &Reporter{
+		Dir:     t.TempDir(),
+		Enabled: func() bool { return false },
+		Exit:    func(code int) { t.Fatal("should not exit") },
+	}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer reporter.Recover()
+		panic("boom")
+	})
+
+	entries, err := os.ReadDir(reporter.Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestReporterWithoutPanic(t *testing.T) {
+	reporter := &Reporter{
+		Dir:  t.TempDir(),
+		Exit: func(code int) { t.Fatal("should not exit") },
+	}
+
+	func() {
+		defer reporter.Recover()
+	}()
+}
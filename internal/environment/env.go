@@ -24,6 +24,10 @@ var (
 	lastFileModTime              time.Time
 	configDir                    = filepath.Join(os.Getenv("HOME"), ".config", "gsh")
 	authorizedCommandsFile       = filepath.Join(configDir, "authorized_commands")
+
+	// Patterns approved for the current session only. These are never written to disk.
+	sessionAuthorizedCommands      []string
+	sessionAuthorizedCommandsMutex sync.RWMutex
 )
 
 // Helper functions for testing
@@ -44,8 +48,15 @@ func SetAuthorizedCommandsFileForTesting(file string) {
 }
 
 const (
-	DEFAULT_PROMPT       = "gsh> "
-	DEFAULT_AGENT_PROMPT = "🤖> "
+	DEFAULT_PROMPT               = "gsh> "
+	DEFAULT_AGENT_PROMPT         = "🤖> "
+	DEFAULT_TRANSCRIPT_MAX_BYTES = 4096
+	DEFAULT_PAGER                = "less -FRX"
+	DEFAULT_PAGER_COMMANDS       = "cat,diff,dmesg,du,env,find,printenv,ps"
+	DEFAULT_DIFF_COMMANDS        = "df,docker,du,free,kubectl,ps,tail,uptime"
+
+	DEFAULT_COMPLETION_MAX_CANDIDATES = 500
+	DEFAULT_RIGHT_PROMPT_TIMEOUT      = 2 * time.Second
 )
 
 func GetHistoryContextLimit(runner *interp.Runner, logger *zap.Logger) int {
@@ -78,6 +89,251 @@ func GetDefaultToYes(runner *interp.Runner) bool {
 	return defaultToYes == "1" || defaultToYes == "true"
 }
 
+// GetSuggestAfterKill returns whether autocomplete suggestions should stay active right after
+// a kill command such as Ctrl+K, instead of being hidden until more input is typed.
+func GetSuggestAfterKill(runner *interp.Runner) bool {
+	suggestAfterKill := strings.ToLower(runner.Vars["GSH_SUGGEST_AFTER_KILL"].String())
+	return suggestAfterKill == "1" || suggestAfterKill == "true"
+}
+
+// GetCombinedInference returns whether predictions and their explanations come from a
+// single LLM request instead of two
+func GetCombinedInference(runner *interp.Runner) bool {
+	combined := strings.ToLower(runner.Vars["GSH_COMBINED_INFERENCE"].String())
+	return combined == "1" || combined == "true"
+}
+
+// GetDiffRepeated returns whether the output of a command run twice in a row should be
+// shown with the changes since the last run highlighted
+func GetDiffRepeated(runner *interp.Runner) bool {
+	diffRepeated := strings.ToLower(runner.Vars["GSH_DIFF_REPEATED"].String())
+	return diffRepeated == "1" || diffRepeated == "true"
+}
+
+// GetSubstitutionPreview returns whether the assistant box should preview what the
+// command substitutions of the typed command expand to
+func GetSubstitutionPreview(runner *interp.Runner) bool {
+	substPreview := strings.ToLower(runner.Vars["GSH_SUBST_PREVIEW"].String())
+	return substPreview == "1" || substPreview == "true"
+}
+
+// GetCrashReport returns whether a panic should be saved as a local crash report
+func GetCrashReport(runner *interp.Runner) bool {
+	crashReport := strings.ToLower(runner.Vars["GSH_CRASH_REPORT"].String())
+	return crashReport == "1" || crashReport == "true"
+}
+
+// GetHistorySearchStyle returns how Ctrl+R searches history, "rich" (default) or "inline"
+func GetHistorySearchStyle(runner *interp.Runner) string {
+	if strings.EqualFold(strings.TrimSpace(runner.Vars["GSH_HISTORY_SEARCH_STYLE"].String()), "inline") {
+		return "inline"
+	}
+	return "rich"
+}
+
+// GetSuggestCase returns how typed casing is matched against ghost-text suggestions:
+// "suggestion" (default), "input" or "sensitive"
+func GetSuggestCase(runner *interp.Runner) string {
+	switch suggestCase := strings.ToLower(strings.TrimSpace(runner.Vars["GSH_SUGGEST_CASE"].String())); suggestCase {
+	case "input", "sensitive":
+		return suggestCase
+	default:
+		return "suggestion"
+	}
+}
+
+// GetPredictionVsCompletion returns the policy for Tab when both a prediction and
+// completions apply, e.g. "prefer-prediction,hide-prediction"
+func GetPredictionVsCompletion(runner *interp.Runner) string {
+	return strings.TrimSpace(runner.Vars["GSH_PREDICTION_VS_COMPLETION"].String())
+}
+
+// GetAutoCompleteAfter returns how many characters of a word open the
+// completion box without Tab, or 0 to complete on Tab only
+func GetAutoCompleteAfter(runner *interp.Runner, logger *zap.Logger) int {
+	rawValue := strings.TrimSpace(runner.Vars["GSH_AUTO_COMPLETE_AFTER"].String())
+	if rawValue == "" {
+		return 0
+	}
+
+	chars, err := strconv.ParseInt(rawValue, 10, 32)
+	if err != nil || chars < 0 {
+		logger.Debug("error parsing GSH_AUTO_COMPLETE_AFTER", zap.String("value", rawValue), zap.Error(err))
+		return 0
+	}
+	return int(chars)
+}
+
+// GetFileCompletionSort returns how file completions are ordered: "name", "mtime",
+// "size", or "auto" to pick by command
+func GetFileCompletionSort(runner *interp.Runner) string {
+	sortMode := strings.ToLower(strings.TrimSpace(runner.Vars["GSH_FILE_COMPLETION_SORT"].String()))
+	if sortMode == "" {
+		return "auto"
+	}
+	return sortMode
+}
+
+// ToggleSettings are the boolean settings that @!toggle can flip at runtime
+var ToggleSettings = []string{
+	"GSH_COMBINED_INFERENCE",
+	"GSH_COMPLETION_MAN",
+	"GSH_COMPLETION_RESPECT_GITIGNORE",
+	"GSH_CRASH_REPORT",
+	"GSH_DEFAULT_TO_YES",
+	"GSH_DIFF_REPEATED",
+	"GSH_DRAFT_AUTOSAVE",
+	"GSH_EXPLANATION_MARKDOWN",
+	"GSH_FOCUS_MODE",
+	"GSH_HISTORY_EXPAND_ALIASES",
+	"GSH_PREDICT_REPO_SCOPED",
+	"GSH_SUGGEST_AFTER_KILL",
+	"GSH_TRANSCRIPT",
+}
+
+// GetAssistantPosition returns where the assistant box goes, "below" (default) or "above" the prompt
+func GetAssistantPosition(runner *interp.Runner) string {
+	if strings.EqualFold(strings.TrimSpace(runner.Vars["GSH_ASSISTANT_POSITION"].String()), "above") {
+		return "above"
+	}
+	return "below"
+}
+
+// GetThinkingText returns the placeholder shown while a prediction loads, or
+// an empty string for none
+func GetThinkingText(runner *interp.Runner) string {
+	return strings.TrimSpace(runner.Vars["GSH_THINKING_TEXT"].String())
+}
+
+// GetSafeMode returns whether gsh runs in safe mode, set by --safe or by
+// GSH_SAFE_MODE in the environment it's started from
+func GetSafeMode(runner *interp.Runner) bool {
+	safeMode := strings.ToLower(runner.Vars["GSH_SAFE_MODE"].String())
+	return safeMode == "1" || safeMode == "true"
+}
+
+// GetCoachEnabled returns whether the coach shows tips, stats and challenges
+func GetCoachEnabled(runner *interp.Runner) bool {
+	coach := strings.ToLower(runner.Vars["GSH_COACH"].String())
+	return coach == "1" || coach == "true"
+}
+
+// GetDraftAutosave returns whether the line being typed is saved to disk, so it
+// can be recovered after a crash or a closed terminal
+func GetDraftAutosave(runner *interp.Runner) bool {
+	draftAutosave := strings.ToLower(runner.Vars["GSH_DRAFT_AUTOSAVE"].String())
+	return draftAutosave == "1" || draftAutosave == "true"
+}
+
+// GetExplanationMarkdown returns whether the bold, inline code and bullets of
+// explanations are rendered in the assistant box
+func GetExplanationMarkdown(runner *interp.Runner) bool {
+	explanationMarkdown := strings.ToLower(runner.Vars["GSH_EXPLANATION_MARKDOWN"].String())
+	return explanationMarkdown == "1" || explanationMarkdown == "true"
+}
+
+// GetWarmupPrediction returns whether to warm up the prediction model when the
+// shell starts: "on", "off", or "auto" (default) to warm up only hosted models
+func GetWarmupPrediction(runner *interp.Runner) string {
+	switch strings.ToLower(strings.TrimSpace(runner.Vars["GSH_WARMUP_PREDICTION"].String())) {
+	case "1", "true", "on":
+		return "on"
+	case "0", "false", "off":
+		return "off"
+	}
+	return "auto"
+}
+
+// GetFocusMode returns whether the assistant box should be hidden, leaving only the prompt line
+func GetFocusMode(runner *interp.Runner) bool {
+	focusMode := strings.ToLower(runner.Vars["GSH_FOCUS_MODE"].String())
+	return focusMode == "1" || focusMode == "true"
+}
+
+// GetPredictRepoScoped returns whether predictions should first look for commands
+// frequently run in the current git repository before asking the LLM
+func GetPredictRepoScoped(runner *interp.Runner) bool {
+	repoScoped := strings.ToLower(runner.Vars["GSH_PREDICT_REPO_SCOPED"].String())
+	return repoScoped == "1" || repoScoped == "true"
+}
+
+// GetPredictIgnore returns the command prefixes, such as editors and REPLs, that
+// shouldn't get predictions or explanations
+func GetPredictIgnore(runner *interp.Runner) []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(runner.Vars["GSH_PREDICT_IGNORE"].String(), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// GetHistoryExpandAliases returns whether history records commands with their
+// aliases expanded, like `git status` for `gs`
+func GetHistoryExpandAliases(runner *interp.Runner) bool {
+	expand := strings.ToLower(runner.Vars["GSH_HISTORY_EXPAND_ALIASES"].String())
+	return expand == "1" || expand == "true"
+}
+
+// GetHistoryIgnore returns the patterns of commands that shouldn't be recorded in
+// history, from a JSON array of regular expressions. Invalid patterns are skipped.
+func GetHistoryIgnore(runner *interp.Runner, logger *zap.Logger) []*regexp.Regexp {
+	value := runner.Vars["GSH_HISTORY_IGNORE"].String()
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	var sources []string
+	if err := json.Unmarshal([]byte(value), &sources); err != nil {
+		logger.Debug("error parsing GSH_HISTORY_IGNORE", zap.Error(err))
+		return nil
+	}
+	var patterns []*regexp.Regexp
+	for _, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			logger.Debug("invalid GSH_HISTORY_IGNORE pattern", zap.String("pattern", source), zap.Error(err))
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// GetPredictionTimeout returns how long a single prediction or explanation request may run
+// before it is abandoned. A value of 0 disables the timeout.
+func GetPredictionTimeout(runner *interp.Runner, logger *zap.Logger) time.Duration {
+	timeoutSeconds, err := strconv.ParseInt(runner.Vars["GSH_PREDICTION_TIMEOUT_SECONDS"].String(), 10, 32)
+	if err != nil {
+		logger.Debug("error parsing GSH_PREDICTION_TIMEOUT_SECONDS", zap.Error(err))
+		timeoutSeconds = 20
+	}
+	if timeoutSeconds < 0 {
+		timeoutSeconds = 0
+	}
+	return time.Duration(timeoutSeconds) * time.Second
+}
+
+// GetCompletionManEnabled returns whether flags should be completed from man pages
+// for commands that have no dedicated completer.
+func GetCompletionManEnabled(runner *interp.Runner) bool {
+	enabled := strings.ToLower(runner.Vars["GSH_COMPLETION_MAN"].String())
+	return enabled == "1" || enabled == "true"
+}
+
+// GetCompletionRespectGitignore returns whether file completions for editors
+// and git leave out the paths the repository's .gitignore ignores
+func GetCompletionRespectGitignore(runner *interp.Runner) bool {
+	enabled := strings.ToLower(runner.Vars["GSH_COMPLETION_RESPECT_GITIGNORE"].String())
+	return enabled == "1" || enabled == "true"
+}
+
+// GetSetTitleFormat returns the format used to set the terminal window title at the prompt
+// and while commands run. An empty format leaves the title to the LLM-generated summary.
+func GetSetTitleFormat(runner *interp.Runner) string {
+	return runner.Vars["GSH_SET_TITLE"].String()
+}
+
 func GetPwd(runner *interp.Runner) string {
 	return runner.Vars["PWD"].String()
 }
@@ -93,7 +349,31 @@ func GetUser(runner *interp.Runner) string {
 	return u
 }
 
-func GetPrompt(runner *interp.Runner, logger *zap.Logger) string {
+// NextShellLevel returns the GSH_SHLVL a new gsh should run with, given the
+// value inherited from its parent. Like bash's SHLVL, the outermost gsh is 1.
+func NextShellLevel(inherited string) int {
+	level, err := strconv.Atoi(strings.TrimSpace(inherited))
+	if err != nil || level < 0 {
+		level = 0
+	}
+	return level + 1
+}
+
+// GetShellLevel returns how deeply this gsh is nested in other gsh sessions
+func GetShellLevel(runner *interp.Runner) int {
+	level, err := strconv.Atoi(runner.Vars["GSH_SHLVL"].String())
+	if err != nil || level < 1 {
+		return 1
+	}
+	return level
+}
+
+// IsSSHSession returns true if the shell is running over SSH
+func IsSSHSession(runner *interp.Runner) bool {
+	return runner.Vars["SSH_CONNECTION"].String() != "" || runner.Vars["SSH_TTY"].String() != ""
+}
+
+func GetPrompt(runner *interp.Runner, logger *zap.Logger, lastExitCode int) string {
 	promptUpdater := runner.Funcs["GSH_UPDATE_PROMPT"]
 	if promptUpdater != nil {
 		err := runner.Run(context.Background(), promptUpdater)
@@ -109,13 +389,39 @@ func GetPrompt(runner *interp.Runner, logger *zap.Logger) string {
 		buildVersion = ""
 	}
 
-	prompt := buildVersion + runner.Vars["GSH_PROMPT"].String()
+	prompt := buildVersion + expandPromptTemplate(runner.Vars["GSH_PROMPT"].String(), lastExitCode)
 	if prompt != "" {
 		return prompt
 	}
 	return DEFAULT_PROMPT
 }
 
+// promptExitPattern matches the {exit:success|failure} segment of GSH_PROMPT
+var promptExitPattern = regexp.MustCompile(`\{exit:([^|{}]*)\|([^{}]*)\}`)
+
+const (
+	promptColorSuccess = "\033[32m"
+	promptColorFailure = "\033[31m"
+	promptColorReset   = "\033[0m"
+)
+
+// expandPromptTemplate replaces each {exit:success|failure} segment with its
+// success text in green if the last command exited with 0, or its failure text
+// in red otherwise. Empty texts render nothing.
+func expandPromptTemplate(prompt string, lastExitCode int) string {
+	return promptExitPattern.ReplaceAllStringFunc(prompt, func(segment string) string {
+		match := promptExitPattern.FindStringSubmatch(segment)
+		text, color := match[1], promptColorSuccess
+		if lastExitCode != 0 {
+			text, color = match[2], promptColorFailure
+		}
+		if text == "" {
+			return ""
+		}
+		return color + text + promptColorReset
+	})
+}
+
 // GetAgentPrompt returns the prompt to use when the agent displays commands
 // If GSH_APROMPT is set, it uses that; otherwise uses DEFAULT_AGENT_PROMPT
 // to differentiate agent commands from user commands
@@ -183,6 +489,63 @@ func getSessionConfigOverride(key string) (string, bool) {
 	return "", false
 }
 
+// ContextType is a kind of context that can be given to the LLM
+type ContextType struct {
+	Name        string
+	Description string
+}
+
+// ContextTypes lists the values accepted by the GSH_CONTEXT_TYPES_FOR_* settings.
+// The names match the context retrievers registered by the interactive shell.
+var ContextTypes = []ContextType{
+	{Name: "system_info", Description: "Operating system and architecture"},
+	{Name: "working_directory", Description: "Current working directory"},
+	{Name: "git_status", Description: "Git status of the current repository"},
+	{Name: "history_concise", Description: "Recent commands, without their directories or exit codes"},
+	{Name: "history_verbose", Description: "Recent commands with their directories and exit codes"},
+}
+
+// ContextTypeSettings are the variables that select context types, and what each is used for
+var ContextTypeSettings = []ContextType{
+	{Name: "GSH_CONTEXT_TYPES_FOR_AGENT", Description: "agent chat"},
+	{Name: "GSH_CONTEXT_TYPES_FOR_PREDICTION_WITH_PREFIX", Description: "predictions while typing"},
+	{Name: "GSH_CONTEXT_TYPES_FOR_PREDICTION_WITHOUT_PREFIX", Description: "predictions on an empty line"},
+	{Name: "GSH_CONTEXT_TYPES_FOR_EXPLANATION", Description: "command explanations"},
+}
+
+// ValidateContextTypes returns a warning for each unknown context type in the
+// GSH_CONTEXT_TYPES_FOR_* settings, since unknown types are otherwise ignored
+func ValidateContextTypes(runner *interp.Runner) []string {
+	var warnings []string
+	for _, setting := range ContextTypeSettings {
+		for _, contextType := range getContextTypes(runner, setting.Name) {
+			if contextType == "" || isKnownContextType(contextType) {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("%s: unknown context type %q, valid types are %s",
+				setting.Name, contextType, strings.Join(contextTypeNames(), ", ")))
+		}
+	}
+	return warnings
+}
+
+// GetContextTypesForSetting returns the context types configured in one of ContextTypeSettings
+func GetContextTypesForSetting(runner *interp.Runner, setting string) []string {
+	return getContextTypes(runner, setting)
+}
+
+func isKnownContextType(name string) bool {
+	return lo.ContainsBy(ContextTypes, func(contextType ContextType) bool {
+		return contextType.Name == name
+	})
+}
+
+func contextTypeNames() []string {
+	return lo.Map(ContextTypes, func(contextType ContextType, _ int) string {
+		return contextType.Name
+	})
+}
+
 func getContextTypes(runner *interp.Runner, key string) []string {
 	contextTypes := strings.ToLower(runner.Vars[key].String())
 	return lo.Map(strings.Split(contextTypes, ","), func(s string, _ int) string {
@@ -243,6 +606,151 @@ func GetIdleSummaryTimeout(runner *interp.Runner, logger *zap.Logger) int {
 	return int(timeout)
 }
 
+// GetOutputCaptureMaxBytes returns how many bytes of stdout to keep from the last
+// command. Zero disables output capture.
+func GetOutputCaptureMaxBytes(runner *interp.Runner, logger *zap.Logger) int {
+	maxBytesStr := runner.Vars["GSH_OUTPUT_CAPTURE_MAX_BYTES"].String()
+	if maxBytesStr == "" {
+		return 0
+	}
+
+	maxBytes, err := strconv.ParseInt(maxBytesStr, 10, 32)
+	if err != nil {
+		logger.Debug("error parsing GSH_OUTPUT_CAPTURE_MAX_BYTES", zap.Error(err))
+		return 0
+	}
+
+	if maxBytes < 0 {
+		return 0
+	}
+	return int(maxBytes)
+}
+
+// GetTranscriptEnabled returns whether commands and their output should be
+// recorded in the session transcript
+func GetTranscriptEnabled(runner *interp.Runner) bool {
+	enabled := strings.ToLower(runner.Vars["GSH_TRANSCRIPT"].String())
+	return enabled == "1" || enabled == "true"
+}
+
+// GetTranscriptMaxBytes returns how many bytes of output each transcript entry keeps
+func GetTranscriptMaxBytes(runner *interp.Runner, logger *zap.Logger) int {
+	maxBytesStr := runner.Vars["GSH_TRANSCRIPT_MAX_BYTES"].String()
+	if maxBytesStr == "" {
+		return DEFAULT_TRANSCRIPT_MAX_BYTES
+	}
+
+	maxBytes, err := strconv.ParseInt(maxBytesStr, 10, 32)
+	if err != nil {
+		logger.Debug("error parsing GSH_TRANSCRIPT_MAX_BYTES", zap.Error(err))
+		return DEFAULT_TRANSCRIPT_MAX_BYTES
+	}
+
+	if maxBytes < 0 {
+		return 0
+	}
+	return int(maxBytes)
+}
+
+// GetCompletionMaxCandidates returns how many completion candidates are shown at
+// most, or 0 for no limit
+func GetCompletionMaxCandidates(runner *interp.Runner) int {
+	maxStr := strings.TrimSpace(runner.Vars["GSH_COMPLETION_MAX_CANDIDATES"].String())
+	if maxStr == "" {
+		return DEFAULT_COMPLETION_MAX_CANDIDATES
+	}
+
+	maxCandidates, err := strconv.Atoi(maxStr)
+	if err != nil {
+		return DEFAULT_COMPLETION_MAX_CANDIDATES
+	}
+	if maxCandidates < 0 {
+		return 0
+	}
+	return maxCandidates
+}
+
+// GetRightPrompt returns the command whose output is shown at the right of the
+// assistant box's top border, or "" for none
+func GetRightPrompt(runner *interp.Runner) string {
+	return strings.TrimSpace(runner.Vars["GSH_RIGHT_PROMPT"].String())
+}
+
+// GetRightPromptTimeout returns how long the GSH_RIGHT_PROMPT command may run
+func GetRightPromptTimeout(runner *interp.Runner, logger *zap.Logger) time.Duration {
+	value := strings.TrimSpace(runner.Vars["GSH_RIGHT_PROMPT_TIMEOUT_SECONDS"].String())
+	if value == "" {
+		return DEFAULT_RIGHT_PROMPT_TIMEOUT
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		logger.Debug("invalid GSH_RIGHT_PROMPT_TIMEOUT_SECONDS, using default", zap.String("value", value))
+		return DEFAULT_RIGHT_PROMPT_TIMEOUT
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// GetCommandTimeout returns how long a command typed at the prompt or run by
+// the agent may run before it's stopped, or 0 for no limit. The value is a
+// number of seconds or a duration like 5m.
+func GetCommandTimeout(runner *interp.Runner, logger *zap.Logger) time.Duration {
+	value := strings.TrimSpace(runner.Vars["GSH_COMMAND_TIMEOUT"].String())
+	if value == "" || value == "0" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil {
+			logger.Debug("invalid GSH_COMMAND_TIMEOUT, running commands without a timeout", zap.String("value", value))
+			return 0
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+	return max(timeout, 0)
+}
+
+// GetPager returns the command to page long command output through, or "" when
+// automatic paging is disabled
+func GetPager(runner *interp.Runner) string {
+	pager := strings.TrimSpace(runner.Vars["GSH_PAGER"].String())
+	switch strings.ToLower(pager) {
+	case "0", "false":
+		return ""
+	case "1", "true":
+		return DEFAULT_PAGER
+	}
+	return pager
+}
+
+// GetPagerCommands returns the commands whose output may go through the pager,
+// DEFAULT_PAGER_COMMANDS unless GSH_PAGER_COMMANDS is set
+func GetPagerCommands(runner *interp.Runner) []string {
+	return getCommandList(runner, "GSH_PAGER_COMMANDS", DEFAULT_PAGER_COMMANDS)
+}
+
+// GetDiffCommands returns the commands GSH_DIFF_REPEATED compares the output of,
+// DEFAULT_DIFF_COMMANDS unless GSH_DIFF_COMMANDS is set
+func GetDiffCommands(runner *interp.Runner) []string {
+	return getCommandList(runner, "GSH_DIFF_COMMANDS", DEFAULT_DIFF_COMMANDS)
+}
+
+// getCommandList splits the comma-separated commands of a variable, or of
+// defaultList when it isn't set. Set but empty means none.
+func getCommandList(runner *interp.Runner, name string, defaultList string) []string {
+	list := defaultList
+	if value := runner.Vars[name]; value.IsSet() {
+		list = value.String()
+	}
+	var commands []string
+	for _, command := range strings.Split(list, ",") {
+		if command = strings.TrimSpace(command); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
 func GetHomeDir(runner *interp.Runner) string {
 	return runner.Vars["HOME"].String()
 }
@@ -305,6 +813,40 @@ func AppendToAuthorizedCommands(commandRegex string) error {
 	return nil
 }
 
+// AppendToSessionAuthorizedCommands approves a command regex for the rest of the
+// current session without persisting it to the authorized_commands file
+func AppendToSessionAuthorizedCommands(commandRegex string) {
+	trimmed := strings.TrimSpace(commandRegex)
+	if trimmed == "" {
+		return
+	}
+
+	sessionAuthorizedCommandsMutex.Lock()
+	defer sessionAuthorizedCommandsMutex.Unlock()
+
+	if lo.Contains(sessionAuthorizedCommands, trimmed) {
+		return
+	}
+	sessionAuthorizedCommands = append(sessionAuthorizedCommands, trimmed)
+}
+
+// GetSessionAuthorizedCommands returns the command regex patterns approved for the current session
+func GetSessionAuthorizedCommands() []string {
+	sessionAuthorizedCommandsMutex.RLock()
+	defer sessionAuthorizedCommandsMutex.RUnlock()
+
+	patterns := make([]string, len(sessionAuthorizedCommands))
+	copy(patterns, sessionAuthorizedCommands)
+	return patterns
+}
+
+// ResetSessionAuthorizedCommands forgets all patterns approved for the current session
+func ResetSessionAuthorizedCommands() {
+	sessionAuthorizedCommandsMutex.Lock()
+	defer sessionAuthorizedCommandsMutex.Unlock()
+	sessionAuthorizedCommands = nil
+}
+
 // LoadAuthorizedCommandsFromFile loads authorized command regex patterns from file
 func LoadAuthorizedCommandsFromFile() ([]string, error) {
 	// Check if file exists
@@ -380,12 +922,14 @@ func WriteAuthorizedCommandsToFile(patterns []string) error {
 	return nil
 }
 
-// IsCommandAuthorized checks if a command matches any of the authorized patterns
+// IsCommandAuthorized checks if a command matches any of the authorized patterns,
+// either persisted in the authorized_commands file or approved for this session
 func IsCommandAuthorized(command string) (bool, error) {
 	patterns, err := LoadAuthorizedCommandsFromFile()
 	if err != nil {
 		return false, err
 	}
+	patterns = append(patterns, GetSessionAuthorizedCommands()...)
 
 	for _, pattern := range patterns {
 		matched, err := regexp.MatchString(pattern, command)
@@ -530,8 +1074,9 @@ func GetApprovedBashCommandRegex(runner *interp.Runner, logger *zap.Logger) []st
 	// Filter out overly broad environment patterns that could bypass file-based security
 	filteredEnvPatterns := filterDangerousPatterns(envPatterns, logger)
 
-	// Combine filtered environment and file patterns
+	// Combine filtered environment, file and session patterns
 	allPatterns := append(filteredEnvPatterns, filePatterns...)
+	allPatterns = append(allPatterns, GetSessionAuthorizedCommands()...)
 
 	// Ensure we return an empty slice rather than nil
 	if allPatterns == nil {
@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -540,6 +541,56 @@ func TestIsCommandAuthorized(t *testing.T) {
 	assert.False(t, authorized)
 }
 
+func TestSessionAuthorizedCommands(t *testing.T) {
+	// Create a temporary config directory for testing
+	tempConfigDir := filepath.Join(os.TempDir(), "gsh_test_session_auth_config")
+	tempAuthorizedFile := filepath.Join(tempConfigDir, "authorized_commands")
+
+	// Override the global variables for testing
+	oldConfigDir := configDir
+	oldAuthorizedFile := authorizedCommandsFile
+	configDir = tempConfigDir
+	authorizedCommandsFile = tempAuthorizedFile
+	ResetSessionAuthorizedCommands()
+	t.Cleanup(func() {
+		configDir = oldConfigDir
+		authorizedCommandsFile = oldAuthorizedFile
+		ResetSessionAuthorizedCommands()
+		assert.NoError(t, os.RemoveAll(tempConfigDir))
+	})
+
+	authorized, err := IsCommandAuthorized("sessioncmd run")
+	assert.NoError(t, err)
+	assert.False(t, authorized)
+
+	AppendToSessionAuthorizedCommands("^sessioncmd run.*")
+	AppendToSessionAuthorizedCommands("^sessioncmd run.*")
+	AppendToSessionAuthorizedCommands("  ")
+	assert.Equal(t, []string{"^sessioncmd run.*"}, GetSessionAuthorizedCommands())
+
+	authorized, err = IsCommandAuthorized("sessioncmd run --fast")
+	assert.NoError(t, err)
+	assert.True(t, authorized)
+
+	authorized, err = IsCommandAuthorized("sessioncmd delete")
+	assert.NoError(t, err)
+	assert.False(t, authorized)
+
+	// Session approvals must never be written to the authorized_commands file
+	_, err = os.Stat(tempAuthorizedFile)
+	assert.True(t, os.IsNotExist(err))
+
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	ResetCacheForTesting()
+	assert.Contains(t, GetApprovedBashCommandRegex(runner, zap.NewNop()), "^sessioncmd run.*")
+
+	ResetSessionAuthorizedCommands()
+	authorized, err = IsCommandAuthorized("sessioncmd run --fast")
+	assert.NoError(t, err)
+	assert.False(t, authorized)
+}
+
 func TestIsCommandAuthorizedInvalidRegex(t *testing.T) {
 	// Create a temporary config directory for testing
 	tempConfigDir := filepath.Join(os.TempDir(), "gsh_test_auth_invalid_config")
@@ -648,7 +699,7 @@ func TestEnvironmentHelperFunctions(t *testing.T) {
 	// PWD may be empty in test environment without shell initialization
 	assert.IsType(t, "", pwd)
 
-	prompt := GetPrompt(runner, logger)
+	prompt := GetPrompt(runner, logger, 0)
 	assert.Equal(t, "gsh> ", prompt) // DEFAULT_PROMPT value
 
 	contextWindow := GetAgentContextWindowTokens(runner, logger)
@@ -718,7 +769,7 @@ func TestEnvironmentHelperFunctionsWithCustomValues(t *testing.T) {
 	cleanLog := ShouldCleanLogFile(runner)
 	assert.True(t, cleanLog)
 
-	prompt := GetPrompt(runner, logger)
+	prompt := GetPrompt(runner, logger, 0)
 	assert.Equal(t, "[dev] custom> ", prompt)
 
 	contextWindow := GetAgentContextWindowTokens(runner, logger)
@@ -850,3 +901,301 @@ func TestSyncVariablesToEnvRemovesUnsetVariables(t *testing.T) {
 	_, exists = dynamicEnv.gshVars["GSH_PROMPT"]
 	assert.False(t, exists, "GSH_PROMPT should be removed from dynamic environment")
 }
+
+func TestShellLevel(t *testing.T) {
+	assert.Equal(t, 1, NextShellLevel(""))
+	assert.Equal(t, 3, NextShellLevel("2"))
+	assert.Equal(t, 1, NextShellLevel("garbage"))
+
+	runner, err := interp.New(interp.Env(expand.ListEnviron()))
+	assert.NoError(t, err)
+	if runner.Vars == nil {
+		runner.Vars = make(map[string]expand.Variable)
+	}
+	assert.Equal(t, 1, GetShellLevel(runner))
+	assert.False(t, IsSSHSession(runner))
+
+	runner.Vars["GSH_SHLVL"] = expand.Variable{Kind: expand.String, Str: "2"}
+	runner.Vars["SSH_CONNECTION"] = expand.Variable{Kind: expand.String, Str: "10.0.0.2 52314 10.0.0.1 22"}
+	assert.Equal(t, 2, GetShellLevel(runner))
+	assert.True(t, IsSSHSession(runner))
+}
+
+func TestPromptExitTemplate(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{
+		"GSH_PROMPT": {Kind: expand.String, Str: "{exit:✓|✗} gsh> "},
+	}
+	logger := zap.NewNop()
+
+	assert.Equal(t, "\033[32m✓\033[0m gsh> ", GetPrompt(runner, logger, 0))
+	assert.Equal(t, "\033[31m✗\033[0m gsh> ", GetPrompt(runner, logger, 127))
+
+	// Either side may be empty, and text without a template is left alone
+	assert.Equal(t, "gsh> ", expandPromptTemplate("{exit:|[err] }gsh> ", 0))
+	assert.Equal(t, "\033[31m[err] \033[0mgsh> ", expandPromptTemplate("{exit:|[err] }gsh> ", 1))
+	assert.Equal(t, "{exit} {notatemplate}", expandPromptTemplate("{exit} {notatemplate}", 1))
+}
+
+func TestValidateContextTypes(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{
+		"GSH_CONTEXT_TYPES_FOR_AGENT":       {Kind: expand.String, Str: "system_info, Git_Status,history_verbose"},
+		"GSH_CONTEXT_TYPES_FOR_EXPLANATION": {Kind: expand.String, Str: "system_info,working_dir"},
+	}
+
+	warnings := ValidateContextTypes(runner)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], `GSH_CONTEXT_TYPES_FOR_EXPLANATION: unknown context type "working_dir"`)
+	assert.Contains(t, warnings[0], "working_directory")
+}
+
+func TestGetPredictIgnore(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Nil(t, GetPredictIgnore(runner))
+
+	runner.Vars["GSH_PREDICT_IGNORE"] = expand.Variable{Kind: expand.String, Str: "vim, top,,git rebase -i "}
+	assert.Equal(t, []string{"vim", "top", "git rebase -i"}, GetPredictIgnore(runner))
+}
+
+func TestGetSubstitutionPreview(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.False(t, GetSubstitutionPreview(runner))
+
+	runner.Vars["GSH_SUBST_PREVIEW"] = expand.Variable{Kind: expand.String, Str: "1"}
+	assert.True(t, GetSubstitutionPreview(runner))
+}
+
+func TestGetAssistantPosition(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Equal(t, "below", GetAssistantPosition(runner))
+
+	runner.Vars["GSH_ASSISTANT_POSITION"] = expand.Variable{Kind: expand.String, Str: "ABOVE"}
+	assert.Equal(t, "above", GetAssistantPosition(runner))
+
+	runner.Vars["GSH_ASSISTANT_POSITION"] = expand.Variable{Kind: expand.String, Str: "left"}
+	assert.Equal(t, "below", GetAssistantPosition(runner))
+}
+
+func TestGetThinkingText(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Equal(t, "", GetThinkingText(runner))
+
+	runner.Vars["GSH_THINKING_TEXT"] = expand.Variable{Kind: expand.String, Str: " thinking… "}
+	assert.Equal(t, "thinking…", GetThinkingText(runner))
+}
+
+func TestGetSafeMode(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.False(t, GetSafeMode(runner))
+
+	runner.Vars["GSH_SAFE_MODE"] = expand.Variable{Kind: expand.String, Str: "true"}
+	assert.True(t, GetSafeMode(runner))
+}
+
+func TestGetExplanationMarkdown(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.False(t, GetExplanationMarkdown(runner))
+
+	runner.Vars["GSH_EXPLANATION_MARKDOWN"] = expand.Variable{Kind: expand.String, Str: "1"}
+	assert.True(t, GetExplanationMarkdown(runner))
+}
+
+func TestGetWarmupPrediction(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Equal(t, "auto", GetWarmupPrediction(runner))
+
+	for value, want := range map[string]string{"1": "on", "true": "on", "0": "off", "off": "off", "auto": "auto", "bogus": "auto"} {
+		runner.Vars["GSH_WARMUP_PREDICTION"] = expand.Variable{Kind: expand.String, Str: value}
+		assert.Equal(t, want, GetWarmupPrediction(runner), value)
+	}
+}
+
+func TestGetHistoryIgnore(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	logger := zap.NewNop()
+	assert.Nil(t, GetHistoryIgnore(runner, logger))
+
+	runner.Vars["GSH_HISTORY_IGNORE"] = expand.Variable{Kind: expand.String, Str: `not json`}
+	assert.Nil(t, GetHistoryIgnore(runner, logger))
+
+	runner.Vars["GSH_HISTORY_IGNORE"] = expand.Variable{Kind: expand.String, Str: `["^ ", "PASSWORD=", "("]`}
+	patterns := GetHistoryIgnore(runner, logger)
+	assert.Len(t, patterns, 2)
+	assert.Equal(t, "^ ", patterns[0].String())
+	assert.Equal(t, "PASSWORD=", patterns[1].String())
+}
+
+func TestGetCombinedInference(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.False(t, GetCombinedInference(runner))
+
+	runner.Vars["GSH_COMBINED_INFERENCE"] = expand.Variable{Kind: expand.String, Str: "True"}
+	assert.True(t, GetCombinedInference(runner))
+
+	runner.Vars["GSH_COMBINED_INFERENCE"] = expand.Variable{Kind: expand.String, Str: "0"}
+	assert.False(t, GetCombinedInference(runner))
+}
+
+func TestGetPredictionVsCompletion(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Equal(t, "", GetPredictionVsCompletion(runner))
+
+	runner.Vars["GSH_PREDICTION_VS_COMPLETION"] = expand.Variable{Kind: expand.String, Str: " prefer-prediction,hide-prediction "}
+	assert.Equal(t, "prefer-prediction,hide-prediction", GetPredictionVsCompletion(runner))
+}
+
+func TestGetAutoCompleteAfter(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	logger := zap.NewNop()
+	assert.Equal(t, 0, GetAutoCompleteAfter(runner, logger))
+
+	runner.Vars["GSH_AUTO_COMPLETE_AFTER"] = expand.Variable{Kind: expand.String, Str: " 3 "}
+	assert.Equal(t, 3, GetAutoCompleteAfter(runner, logger))
+
+	for _, invalid := range []string{"-1", "soon"} {
+		runner.Vars["GSH_AUTO_COMPLETE_AFTER"] = expand.Variable{Kind: expand.String, Str: invalid}
+		assert.Equal(t, 0, GetAutoCompleteAfter(runner, logger))
+	}
+}
+
+func TestRightPromptSettings(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	logger := zap.NewNop()
+
+	assert.Equal(t, "", GetRightPrompt(runner))
+	assert.Equal(t, DEFAULT_RIGHT_PROMPT_TIMEOUT, GetRightPromptTimeout(runner, logger))
+
+	runner.Vars["GSH_RIGHT_PROMPT"] = expand.Variable{Kind: expand.String, Str: " kubectl config current-context "}
+	runner.Vars["GSH_RIGHT_PROMPT_TIMEOUT_SECONDS"] = expand.Variable{Kind: expand.String, Str: "0.5"}
+	assert.Equal(t, "kubectl config current-context", GetRightPrompt(runner))
+	assert.Equal(t, 500*time.Millisecond, GetRightPromptTimeout(runner, logger))
+
+	runner.Vars["GSH_RIGHT_PROMPT_TIMEOUT_SECONDS"] = expand.Variable{Kind: expand.String, Str: "-1"}
+	assert.Equal(t, DEFAULT_RIGHT_PROMPT_TIMEOUT, GetRightPromptTimeout(runner, logger))
+}
+
+func TestGetDiffRepeated(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.False(t, GetDiffRepeated(runner))
+
+	runner.Vars["GSH_DIFF_REPEATED"] = expand.Variable{Kind: expand.String, Str: "true"}
+	assert.True(t, GetDiffRepeated(runner))
+}
+
+func TestGetCrashReport(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.False(t, GetCrashReport(runner))
+
+	runner.Vars["GSH_CRASH_REPORT"] = expand.Variable{Kind: expand.String, Str: "1"}
+	assert.True(t, GetCrashReport(runner))
+}
+
+func TestTranscriptSettings(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	logger := zap.NewNop()
+
+	assert.False(t, GetTranscriptEnabled(runner))
+	assert.Equal(t, DEFAULT_TRANSCRIPT_MAX_BYTES, GetTranscriptMaxBytes(runner, logger))
+
+	runner.Vars["GSH_TRANSCRIPT"] = expand.Variable{Kind: expand.String, Str: "true"}
+	runner.Vars["GSH_TRANSCRIPT_MAX_BYTES"] = expand.Variable{Kind: expand.String, Str: "100"}
+	assert.True(t, GetTranscriptEnabled(runner))
+	assert.Equal(t, 100, GetTranscriptMaxBytes(runner, logger))
+
+	runner.Vars["GSH_TRANSCRIPT_MAX_BYTES"] = expand.Variable{Kind: expand.String, Str: "lots"}
+	assert.Equal(t, DEFAULT_TRANSCRIPT_MAX_BYTES, GetTranscriptMaxBytes(runner, logger))
+}
+
+func TestGetPager(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+
+	assert.Equal(t, "", GetPager(runner))
+
+	runner.Vars["GSH_PAGER"] = expand.Variable{Kind: expand.String, Str: "1"}
+	assert.Equal(t, DEFAULT_PAGER, GetPager(runner))
+
+	runner.Vars["GSH_PAGER"] = expand.Variable{Kind: expand.String, Str: " most "}
+	assert.Equal(t, "most", GetPager(runner))
+
+	runner.Vars["GSH_PAGER"] = expand.Variable{Kind: expand.String, Str: "false"}
+	assert.Equal(t, "", GetPager(runner))
+}
+
+func TestGetPagerCommands(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Contains(t, GetPagerCommands(runner), "cat")
+
+	runner.Vars["GSH_PAGER_COMMANDS"] = expand.Variable{Kind: expand.String, Str: "cat, rg ,,tree"}
+	assert.Equal(t, []string{"cat", "rg", "tree"}, GetPagerCommands(runner))
+
+	// Set but empty opts every command out
+	runner.Vars["GSH_PAGER_COMMANDS"] = expand.Variable{Kind: expand.String, Str: ""}
+	assert.Empty(t, GetPagerCommands(runner))
+}
+
+func TestGetDiffCommands(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Contains(t, GetDiffCommands(runner), "kubectl")
+	assert.NotContains(t, GetDiffCommands(runner), "ls")
+
+	runner.Vars["GSH_DIFF_COMMANDS"] = expand.Variable{Kind: expand.String, Str: "kubectl,gh"}
+	assert.Equal(t, []string{"kubectl", "gh"}, GetDiffCommands(runner))
+
+	runner.Vars["GSH_DIFF_COMMANDS"] = expand.Variable{Kind: expand.String, Str: ""}
+	assert.Empty(t, GetDiffCommands(runner))
+}
+
+func TestGetCompletionMaxCandidates(t *testing.T) {
+	runner, err := interp.New()
+	assert.NoError(t, err)
+	runner.Vars = map[string]expand.Variable{}
+	assert.Equal(t, DEFAULT_COMPLETION_MAX_CANDIDATES, GetCompletionMaxCandidates(runner))
+
+	runner.Vars["GSH_COMPLETION_MAX_CANDIDATES"] = expand.Variable{Kind: expand.String, Str: " 50 "}
+	assert.Equal(t, 50, GetCompletionMaxCandidates(runner))
+
+	runner.Vars["GSH_COMPLETION_MAX_CANDIDATES"] = expand.Variable{Kind: expand.String, Str: "-1"}
+	assert.Equal(t, 0, GetCompletionMaxCandidates(runner))
+
+	runner.Vars["GSH_COMPLETION_MAX_CANDIDATES"] = expand.Variable{Kind: expand.String, Str: "many"}
+	assert.Equal(t, DEFAULT_COMPLETION_MAX_CANDIDATES, GetCompletionMaxCandidates(runner))
+}
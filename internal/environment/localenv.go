@@ -0,0 +1,373 @@
+package environment
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// LocalEnvFileName is the file whose variables are applied while the shell is
+// in its directory or below, like direnv's .envrc
+const LocalEnvFileName = ".gshenv.local"
+
+// allowedLocalEnvsFile stores one "<sha256> <path>" line per allowed file, so
+// editing a file requires allowing it again
+var allowedLocalEnvsFile = filepath.Join(configDir, "allowed_local_envs")
+
+func SetAllowedLocalEnvsFileForTesting(file string) {
+	allowedLocalEnvsFile = file
+}
+
+// LocalEnvChange describes what LocalEnv.Update did
+type LocalEnvChange struct {
+	// Unloaded is the file whose variables were removed
+	Unloaded string
+	// Loaded is the file whose variables were applied
+	Loaded string
+	// Variables are the names set by Loaded
+	Variables []string
+	// Blocked is a file that was found but isn't allowed. It's reported once
+	// until the file or the directory changes.
+	Blocked string
+}
+
+// LocalEnv applies the nearest .gshenv.local on entering a directory and
+// restores the variables it replaced on leaving
+type LocalEnv struct {
+	file      string
+	hash      string
+	previous  map[string]expand.Variable
+	variables []string
+	blocked   string
+}
+
+func NewLocalEnv() *LocalEnv {
+	return &LocalEnv{}
+}
+
+// File returns the applied file, or "" when none is
+func (le *LocalEnv) File() string {
+	return le.file
+}
+
+// Variables returns the names set by the applied file
+func (le *LocalEnv) Variables() []string {
+	return le.variables
+}
+
+// FindLocalEnvFile returns the .gshenv.local in dir or its closest ancestor
+// that has one, or "" if there is none
+func FindLocalEnvFile(dir string) string {
+	for dir != "" {
+		path := filepath.Join(dir, LocalEnvFileName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ""
+}
+
+// Update applies the .gshenv.local that covers pwd, unloading the previously
+// applied file if it no longer applies or has changed
+func (le *LocalEnv) Update(ctx context.Context, runner *interp.Runner, pwd string) (LocalEnvChange, error) {
+	var change LocalEnvChange
+
+	path := FindLocalEnvFile(pwd)
+	var content []byte
+	var hash string
+	if path != "" {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return change, err
+		}
+		hash = hashLocalEnv(content)
+	}
+
+	if path == le.file && hash == le.hash {
+		if path == "" {
+			return change, nil
+		}
+		// Still applied unless the approval was revoked since
+		if allowed, err := isLocalEnvAllowed(path, hash); err != nil || allowed {
+			return change, err
+		}
+	}
+
+	if le.file != "" {
+		change.Unloaded = le.file
+		if err := le.Unload(ctx, runner); err != nil {
+			return change, err
+		}
+	}
+	if path == "" {
+		le.blocked = ""
+		return change, nil
+	}
+
+	allowed, err := isLocalEnvAllowed(path, hash)
+	if err != nil {
+		return change, err
+	}
+	if !allowed {
+		if le.blocked != path+hash {
+			le.blocked = path + hash
+			change.Blocked = path
+		}
+		return change, nil
+	}
+	le.blocked = ""
+
+	if err := le.load(ctx, runner, path, hash, content); err != nil {
+		return change, err
+	}
+	change.Loaded = path
+	change.Variables = le.variables
+	return change, nil
+}
+
+// Unload restores the variables replaced by the applied file
+func (le *LocalEnv) Unload(ctx context.Context, runner *interp.Runner) error {
+	if le.file == "" {
+		return nil
+	}
+
+	var script strings.Builder
+	for _, name := range le.variables {
+		previous := le.previous[name]
+		if !previous.IsSet() {
+			script.WriteString("unset " + name + "\n")
+			continue
+		}
+		quoted, err := syntax.Quote(previous.String(), syntax.LangBash)
+		if err != nil {
+			return err
+		}
+		if previous.Exported {
+			script.WriteString("export ")
+		}
+		script.WriteString(name + "=" + quoted + "\n")
+	}
+
+	le.file = ""
+	le.hash = ""
+	le.previous = nil
+	le.variables = nil
+	return runLocalEnvScript(ctx, runner, script.String())
+}
+
+func (le *LocalEnv) load(ctx context.Context, runner *interp.Runner, path string, hash string, content []byte) error {
+	file, err := syntax.NewParser().Parse(strings.NewReader(string(content)), path)
+	if err != nil {
+		return err
+	}
+	names, err := localEnvAssignments(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	previous := make(map[string]expand.Variable, len(names))
+	for _, name := range names {
+		previous[name] = lookupLocalEnvVar(runner, name)
+	}
+
+	var script strings.Builder
+	if err := syntax.NewPrinter().Print(&script, file); err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		script.WriteString("\nexport " + strings.Join(names, " ") + "\n")
+	}
+	if err := runLocalEnvScript(ctx, runner, script.String()); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	le.file = path
+	le.hash = hash
+	le.previous = previous
+	le.variables = names
+	return nil
+}
+
+// localEnvAssignments checks that file only assigns variables, optionally with
+// export, and returns the sorted names it assigns. Anything that would run a
+// command, including command substitutions, is rejected.
+func localEnvAssignments(file *syntax.File) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, stmt := range file.Stmts {
+		if stmt.Negated || stmt.Background || stmt.Coprocess || len(stmt.Redirs) > 0 {
+			return nil, fmt.Errorf("line %d: only variable assignments are allowed", stmt.Pos().Line())
+		}
+
+		switch cmd := stmt.Cmd.(type) {
+		case *syntax.CallExpr:
+			if len(cmd.Args) > 0 {
+				return nil, fmt.Errorf("line %d: only variable assignments are allowed", stmt.Pos().Line())
+			}
+			for _, assign := range cmd.Assigns {
+				seen[assign.Name.Value] = true
+			}
+		case *syntax.DeclClause:
+			if cmd.Variant.Value != "export" {
+				return nil, fmt.Errorf("line %d: only variable assignments are allowed", stmt.Pos().Line())
+			}
+			for _, assign := range cmd.Args {
+				if assign.Name == nil || assign.Naked {
+					return nil, fmt.Errorf("line %d: only variable assignments are allowed", stmt.Pos().Line())
+				}
+				seen[assign.Name.Value] = true
+			}
+		default:
+			return nil, fmt.Errorf("line %d: only variable assignments are allowed", stmt.Pos().Line())
+		}
+	}
+
+	var err error
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch node := node.(type) {
+		case *syntax.CmdSubst, *syntax.ProcSubst:
+			if err == nil {
+				err = fmt.Errorf("line %d: command substitution is not allowed", node.Pos().Line())
+			}
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// lookupLocalEnvVar returns the current value of a variable. runner.Vars only
+// has the variables once the runner has run something, so fall back to Env.
+func lookupLocalEnvVar(runner *interp.Runner, name string) expand.Variable {
+	if vr, ok := runner.Vars[name]; ok {
+		return vr
+	}
+	if runner.Env != nil {
+		return runner.Env.Get(name)
+	}
+	return expand.Variable{}
+}
+
+// runLocalEnvScript runs the statements one at a time, since running a whole
+// file would exit the shell once it's done
+func runLocalEnvScript(ctx context.Context, runner *interp.Runner, script string) error {
+	var stmts []*syntax.Stmt
+	err := syntax.NewParser().Stmts(strings.NewReader(script), func(stmt *syntax.Stmt) bool {
+		stmts = append(stmts, stmt)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	defer SyncVariablesToEnv(runner)
+	for _, stmt := range stmts {
+		if err := runner.Run(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hashLocalEnv(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AllowLocalEnv approves the current content of the .gshenv.local at path
+func AllowLocalEnv(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines, err := readAllowedLocalEnvs(path)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, hashLocalEnv(content)+" "+path)
+	return writeAllowedLocalEnvs(lines)
+}
+
+// DenyLocalEnv revokes the approval of the .gshenv.local at path
+func DenyLocalEnv(path string) error {
+	lines, err := readAllowedLocalEnvs(path)
+	if err != nil {
+		return err
+	}
+	return writeAllowedLocalEnvs(lines)
+}
+
+func isLocalEnvAllowed(path string, hash string) (bool, error) {
+	file, err := os.Open(allowedLocalEnvsFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if scanner.Text() == hash+" "+path {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// readAllowedLocalEnvs returns the approvals of all files except path
+func readAllowedLocalEnvs(path string) ([]string, error) {
+	file, err := os.Open(allowedLocalEnvsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		_, allowedPath, _ := strings.Cut(scanner.Text(), " ")
+		if allowedPath != path && allowedPath != "" {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func writeAllowedLocalEnvs(lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(allowedLocalEnvsFile), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	return os.WriteFile(allowedLocalEnvsFile, []byte(content), 0600)
+}
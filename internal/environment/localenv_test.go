@@ -0,0 +1,161 @@
+package environment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func setupLocalEnvTest(t *testing.T) (*interp.Runner, string) {
+	oldAllowedFile := allowedLocalEnvsFile
+	allowedLocalEnvsFile = filepath.Join(t.TempDir(), "allowed_local_envs")
+	t.Cleanup(func() {
+		allowedLocalEnvsFile = oldAllowedFile
+	})
+
+	runner, err := interp.New(interp.Env(expand.ListEnviron("HOME=/home/test", "EDITOR=vi")))
+	require.NoError(t, err)
+	return runner, t.TempDir()
+}
+
+func TestFindLocalEnvFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+
+	assert.Equal(t, "", FindLocalEnvFile(nested))
+
+	path := filepath.Join(root, LocalEnvFileName)
+	require.NoError(t, os.WriteFile(path, []byte("FOO=bar\n"), 0644))
+	assert.Equal(t, path, FindLocalEnvFile(nested))
+	assert.Equal(t, path, FindLocalEnvFile(root))
+}
+
+func TestLocalEnvRequiresApproval(t *testing.T) {
+	runner, dir := setupLocalEnvTest(t)
+	path := filepath.Join(dir, LocalEnvFileName)
+	require.NoError(t, os.WriteFile(path, []byte("PROJECT=demo\n"), 0644))
+
+	localEnv := NewLocalEnv()
+	change, err := localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.Equal(t, path, change.Blocked)
+	assert.Empty(t, change.Loaded)
+	assert.False(t, runner.Vars["PROJECT"].IsSet())
+
+	// Only reported once
+	change, err = localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.Empty(t, change.Blocked)
+
+	require.NoError(t, AllowLocalEnv(path))
+	change, err = localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.Equal(t, path, change.Loaded)
+	assert.Equal(t, []string{"PROJECT"}, change.Variables)
+	assert.Equal(t, "demo", runner.Vars["PROJECT"].String())
+	assert.True(t, runner.Vars["PROJECT"].Exported)
+
+	// Editing the file requires allowing it again
+	require.NoError(t, os.WriteFile(path, []byte("PROJECT=other\n"), 0644))
+	change, err = localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.Equal(t, path, change.Unloaded)
+	assert.Equal(t, path, change.Blocked)
+	assert.False(t, runner.Vars["PROJECT"].IsSet())
+}
+
+func TestLocalEnvEnterAndLeave(t *testing.T) {
+	runner, dir := setupLocalEnvTest(t)
+	outside := t.TempDir()
+	nested := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(nested, 0755))
+
+	path := filepath.Join(dir, LocalEnvFileName)
+	require.NoError(t, os.WriteFile(path, []byte("# project settings\nexport EDITOR=nano\nPROJECT_ROOT=$HOME/demo\n"), 0644))
+	require.NoError(t, AllowLocalEnv(path))
+
+	localEnv := NewLocalEnv()
+	change, err := localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"EDITOR", "PROJECT_ROOT"}, change.Variables)
+	assert.Equal(t, "nano", runner.Vars["EDITOR"].String())
+	assert.Equal(t, "/home/test/demo", runner.Vars["PROJECT_ROOT"].String())
+
+	// Subdirectories keep the variables
+	change, err = localEnv.Update(context.Background(), runner, nested)
+	require.NoError(t, err)
+	assert.Equal(t, LocalEnvChange{}, change)
+	assert.Equal(t, path, localEnv.File())
+
+	// Leaving restores what was there before
+	change, err = localEnv.Update(context.Background(), runner, outside)
+	require.NoError(t, err)
+	assert.Equal(t, path, change.Unloaded)
+	assert.Equal(t, "vi", runner.Vars["EDITOR"].String())
+	assert.True(t, runner.Vars["EDITOR"].Exported)
+	assert.False(t, runner.Vars["PROJECT_ROOT"].IsSet())
+	assert.Equal(t, "", localEnv.File())
+}
+
+func TestLocalEnvDenyUnloads(t *testing.T) {
+	runner, dir := setupLocalEnvTest(t)
+	path := filepath.Join(dir, LocalEnvFileName)
+	require.NoError(t, os.WriteFile(path, []byte("PROJECT=demo\n"), 0644))
+	require.NoError(t, AllowLocalEnv(path))
+
+	localEnv := NewLocalEnv()
+	_, err := localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.Equal(t, "demo", runner.Vars["PROJECT"].String())
+
+	require.NoError(t, DenyLocalEnv(path))
+	change, err := localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.Equal(t, path, change.Unloaded)
+	assert.False(t, runner.Vars["PROJECT"].IsSet())
+}
+
+func TestLocalEnvRejectsCommands(t *testing.T) {
+	tests := []string{
+		"echo hi\n",
+		"FOO=bar make\n",
+		"FOO=$(whoami)\n",
+		"export FOO=`id`\n",
+		"FOO=bar > /tmp/out\n",
+		"if true; then FOO=bar; fi\n",
+	}
+
+	for _, content := range tests {
+		runner, dir := setupLocalEnvTest(t)
+		path := filepath.Join(dir, LocalEnvFileName)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		require.NoError(t, AllowLocalEnv(path))
+
+		_, err := NewLocalEnv().Update(context.Background(), runner, dir)
+		assert.Error(t, err, content)
+		assert.False(t, runner.Vars["FOO"].IsSet(), content)
+	}
+}
+
+func TestLocalEnvKeepsShellRunning(t *testing.T) {
+	runner, dir := setupLocalEnvTest(t)
+	path := filepath.Join(dir, LocalEnvFileName)
+	require.NoError(t, os.WriteFile(path, []byte("PROJECT=demo\n"), 0644))
+	require.NoError(t, AllowLocalEnv(path))
+
+	localEnv := NewLocalEnv()
+	_, err := localEnv.Update(context.Background(), runner, dir)
+	require.NoError(t, err)
+	assert.False(t, runner.Exited())
+
+	_, err = localEnv.Update(context.Background(), runner, t.TempDir())
+	require.NoError(t, err)
+	assert.False(t, runner.Exited())
+}
@@ -127,3 +127,15 @@ func GetStatusWithTimeout(dir string, timeout time.Duration) *RepoStatus {
 	defer cancel()
 	return GetStatusWithContext(ctx, dir)
 }
+
+// GetRepoRoot returns the top-level directory of the git repository containing
+// dir, or "" if dir is not inside one
+func GetRepoRoot(ctx context.Context, dir string) string {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bookmark is a named command line saved for quick recall. Unlike aliases,
+// bookmarks keep the full command, and unlike history, they are curated.
+type Bookmark struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+
+	Name      string `gorm:"uniqueIndex"`
+	Command   string
+	Directory string
+}
+
+var bookmarkNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidateBookmarkName checks that a name can be typed and completed as a single word
+func ValidateBookmarkName(name string) error {
+	if !bookmarkNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid bookmark name %q, use letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// SaveBookmark stores command under name, replacing any bookmark with the same name
+func (historyManager *HistoryManager) SaveBookmark(name string, command string, directory string) error {
+	if err := ValidateBookmarkName(name); err != nil {
+		return err
+	}
+
+	bookmark := Bookmark{Name: name, Command: command, Directory: directory}
+	result := historyManager.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"command", "directory", "updated_at"}),
+	}).Create(&bookmark)
+	return result.Error
+}
+
+// GetBookmark returns the bookmark with the given name
+func (historyManager *HistoryManager) GetBookmark(name string) (*Bookmark, error) {
+	var bookmark Bookmark
+	result := historyManager.db.Where("name = ?", name).First(&bookmark)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no bookmark named %q", name)
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &bookmark, nil
+}
+
+// ListBookmarks returns all bookmarks ordered by name
+func (historyManager *HistoryManager) ListBookmarks() ([]Bookmark, error) {
+	var bookmarks []Bookmark
+	result := historyManager.db.Order("name asc").Find(&bookmarks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return bookmarks, nil
+}
+
+// DeleteBookmark removes the bookmark with the given name
+func (historyManager *HistoryManager) DeleteBookmark(name string) error {
+	result := historyManager.db.Where("name = ?", name).Delete(&Bookmark{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no bookmark named %q", name)
+	}
+	return nil
+}
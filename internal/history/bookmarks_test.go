@@ -0,0 +1,35 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookmarks(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, historyManager.SaveBookmark("deploy", "kubectl apply -f k8s/ --context prod", "/src/app"))
+	require.NoError(t, historyManager.SaveBookmark("build", "make build", "/src/app"))
+
+	bookmark, err := historyManager.GetBookmark("deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "kubectl apply -f k8s/ --context prod", bookmark.Command)
+
+	// Saving under an existing name replaces the command
+	require.NoError(t, historyManager.SaveBookmark("deploy", "helm upgrade app ./chart", "/src/app"))
+	bookmarks, err := historyManager.ListBookmarks()
+	require.NoError(t, err)
+	require.Len(t, bookmarks, 2)
+	assert.Equal(t, "build", bookmarks[0].Name)
+	assert.Equal(t, "helm upgrade app ./chart", bookmarks[1].Command)
+
+	require.NoError(t, historyManager.DeleteBookmark("build"))
+	assert.Error(t, historyManager.DeleteBookmark("build"))
+	_, err = historyManager.GetBookmark("build")
+	assert.Error(t, err)
+
+	assert.Error(t, historyManager.SaveBookmark("has space", "ls", "/"))
+}
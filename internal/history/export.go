@@ -0,0 +1,276 @@
+package history
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryFormat is a history file format understood by ExportHistory and ImportHistory
+type HistoryFormat string
+
+const (
+	// HistoryFormatBash is one command per line, optionally preceded by a
+	// "#<unix time>" line as bash writes with HISTTIMEFORMAT set
+	HistoryFormatBash HistoryFormat = "bash"
+	// HistoryFormatZsh is zsh's extended history, ": <unix time>:<duration>;<command>"
+	HistoryFormatZsh HistoryFormat = "zsh"
+	// HistoryFormatJSON is an array of exportedHistoryEntry
+	HistoryFormatJSON HistoryFormat = "json"
+)
+
+// ParseHistoryFormat validates the name of a history format
+func ParseHistoryFormat(name string) (HistoryFormat, error) {
+	switch format := HistoryFormat(strings.ToLower(name)); format {
+	case HistoryFormatBash, HistoryFormatZsh, HistoryFormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown history format %q, expected bash, zsh or json", name)
+	}
+}
+
+// GuessHistoryFormat picks a format from a file name, e.g. .zsh_history is zsh
+func GuessHistoryFormat(path string) HistoryFormat {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(base, ".json"):
+		return HistoryFormatJSON
+	case strings.Contains(base, "zsh"):
+		return HistoryFormatZsh
+	default:
+		return HistoryFormatBash
+	}
+}
+
+// exportedHistoryEntry is the JSON representation of a history entry
+type exportedHistoryEntry struct {
+	Command   string    `json:"command"`
+	Directory string    `json:"directory,omitempty"`
+	ExitCode  *int32    `json:"exit_code,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ExportHistory writes all history entries, oldest first, to w and returns how
+// many were written
+func (historyManager *HistoryManager) ExportHistory(w io.Writer, format HistoryFormat) (int, error) {
+	var entries []HistoryEntry
+	if err := historyManager.db.Order("id asc").Find(&entries).Error; err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	switch format {
+	case HistoryFormatBash:
+		for _, entry := range entries {
+			fmt.Fprintf(bw, "#%d\n%s\n", entry.CreatedAt.Unix(), entry.Command)
+		}
+	case HistoryFormatZsh:
+		for _, entry := range entries {
+			// zsh continues multi-line commands with a trailing backslash
+			command := strings.ReplaceAll(entry.Command, "\n", "\\\n")
+			fmt.Fprintf(bw, ": %d:0;%s\n", entry.CreatedAt.Unix(), command)
+		}
+	case HistoryFormatJSON:
+		exported := make([]exportedHistoryEntry, len(entries))
+		for i, entry := range entries {
+			exported[i] = exportedHistoryEntry{
+				Command:   entry.Command,
+				Directory: entry.Directory,
+				Timestamp: entry.CreatedAt,
+			}
+			if entry.ExitCode.Valid {
+				exitCode := entry.ExitCode.Int32
+				exported[i].ExitCode = &exitCode
+			}
+		}
+		encoder := json.NewEncoder(bw)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(exported); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unknown history format %q", format)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// ImportHistory reads history in the given format from r and adds it to the
+// history, returning how many entries were added. Entries without a timestamp
+// are dated just before the import, keeping their order.
+//
+// Entries already in the history, with the same command, directory and
+// timestamp, are skipped so importing a file twice doesn't duplicate it.
+// Entries without a timestamp can't be told apart that way, so they're
+// skipped when the history already has the command in the same directory.
+func (historyManager *HistoryManager) ImportHistory(r io.Reader, format HistoryFormat) (int, error) {
+	var imported []exportedHistoryEntry
+	var err error
+	switch format {
+	case HistoryFormatBash:
+		imported, err = parseBashHistory(r)
+	case HistoryFormatZsh:
+		imported, err = parseZshHistory(r)
+	case HistoryFormatJSON:
+		err = json.NewDecoder(r).Decode(&imported)
+	default:
+		err = fmt.Errorf("unknown history format %q", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := historyManager.existingImportKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	entries := make([]HistoryEntry, 0, len(imported))
+	for i, item := range imported {
+		if strings.TrimSpace(item.Command) == "" {
+			continue
+		}
+		key := importKey{command: item.Command, directory: item.Directory}
+		if !item.Timestamp.IsZero() {
+			key.createdAt = item.Timestamp.UnixNano()
+		}
+		if existing[key] {
+			continue
+		}
+		entry := HistoryEntry{
+			Command:   item.Command,
+			Directory: item.Directory,
+			CreatedAt: item.Timestamp,
+			UpdatedAt: item.Timestamp,
+		}
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = now.Add(-time.Duration(len(imported)-i) * time.Millisecond)
+			entry.UpdatedAt = entry.CreatedAt
+		}
+		if item.ExitCode != nil {
+			entry.ExitCode = sql.NullInt32{Int32: *item.ExitCode, Valid: true}
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	if err := historyManager.db.CreateInBatches(entries, 500).Error; err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// importKey identifies an entry for ImportHistory, createdAt being zero for
+// any entry with the command in the directory
+type importKey struct {
+	command   string
+	directory string
+	createdAt int64
+}
+
+// existingImportKeys returns the keys of the entries in the history, with and
+// without their timestamp
+func (historyManager *HistoryManager) existingImportKeys() (map[importKey]bool, error) {
+	var rows []HistoryEntry
+	result := historyManager.db.Model(&HistoryEntry{}).Select("command, directory, created_at").Find(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	keys := make(map[importKey]bool, 2*len(rows))
+	for _, row := range rows {
+		keys[importKey{command: row.Command, directory: row.Directory}] = true
+		keys[importKey{command: row.Command, directory: row.Directory, createdAt: row.CreatedAt.UnixNano()}] = true
+	}
+	return keys, nil
+}
+
+// newHistoryScanner returns a line scanner that accepts long history lines
+func newHistoryScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return scanner
+}
+
+func parseBashHistory(r io.Reader) ([]exportedHistoryEntry, error) {
+	var entries []exportedHistoryEntry
+	var timestamp time.Time
+	scanner := newHistoryScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if seconds, ok := strings.CutPrefix(line, "#"); ok {
+			if unix, err := strconv.ParseInt(seconds, 10, 64); err == nil {
+				timestamp = time.Unix(unix, 0)
+				continue
+			}
+		}
+		entries = append(entries, exportedHistoryEntry{Command: line, Timestamp: timestamp})
+		timestamp = time.Time{}
+	}
+	return entries, scanner.Err()
+}
+
+func parseZshHistory(r io.Reader) ([]exportedHistoryEntry, error) {
+	var entries []exportedHistoryEntry
+	var pending []string
+	var timestamp time.Time
+	scanner := newHistoryScanner(r)
+	for scanner.Scan() {
+		line := unmetafyZsh(scanner.Text())
+		if pending == nil {
+			timestamp = time.Time{}
+			// Extended history lines look like ": 1700000000:0;git status"
+			if header, command, ok := strings.Cut(line, ";"); ok && strings.HasPrefix(header, ": ") {
+				seconds, _, _ := strings.Cut(strings.TrimPrefix(header, ": "), ":")
+				if unix, err := strconv.ParseInt(strings.TrimSpace(seconds), 10, 64); err == nil {
+					timestamp = time.Unix(unix, 0)
+					line = command
+				}
+			}
+		}
+
+		if continued, ok := strings.CutSuffix(line, "\\"); ok {
+			pending = append(pending, continued)
+			continue
+		}
+		entries = append(entries, exportedHistoryEntry{
+			Command:   strings.Join(append(pending, line), "\n"),
+			Timestamp: timestamp,
+		})
+		pending = nil
+	}
+	if pending != nil {
+		entries = append(entries, exportedHistoryEntry{Command: strings.Join(pending, "\n"), Timestamp: timestamp})
+	}
+	return entries, scanner.Err()
+}
+
+// unmetafyZsh decodes zsh's history encoding, which writes some bytes as 0x83
+// followed by the byte xor 0x20
+func unmetafyZsh(line string) string {
+	if !strings.Contains(line, "\x83") {
+		return line
+	}
+	decoded := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] == 0x83 && i+1 < len(line) {
+			i++
+			decoded = append(decoded, line[i]^0x20)
+			continue
+		}
+		decoded = append(decoded, line[i])
+	}
+	return string(decoded)
+}
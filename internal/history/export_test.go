@@ -0,0 +1,143 @@
+package history
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExportTestManager(t *testing.T) *HistoryManager {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	run := func(command string, exitCode int, createdAt time.Time) {
+		entry := HistoryEntry{Command: command, Directory: "/src", CreatedAt: createdAt}
+		require.NoError(t, historyManager.db.Create(&entry).Error)
+		_, err := historyManager.FinishCommand(&entry, exitCode)
+		require.NoError(t, err)
+	}
+	run("git status", 0, time.Unix(1700000000, 0))
+	run("for f in *; do\n  echo $f\ndone", 1, time.Unix(1700000100, 0))
+	return historyManager
+}
+
+func TestExportHistory(t *testing.T) {
+	historyManager := newExportTestManager(t)
+
+	var buf bytes.Buffer
+	count, err := historyManager.ExportHistory(&buf, HistoryFormatBash)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "#1700000000\ngit status\n#1700000100\nfor f in *; do\n  echo $f\ndone\n", buf.String())
+
+	buf.Reset()
+	_, err = historyManager.ExportHistory(&buf, HistoryFormatZsh)
+	require.NoError(t, err)
+	assert.Equal(t, ": 1700000000:0;git status\n: 1700000100:0;for f in *; do\\\n  echo $f\\\ndone\n", buf.String())
+
+	buf.Reset()
+	_, err = historyManager.ExportHistory(&buf, HistoryFormatJSON)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `"command": "git status"`)
+	assert.Contains(t, buf.String(), `"exit_code": 1`)
+	assert.Contains(t, buf.String(), `"directory": "/src"`)
+}
+
+func TestImportHistory(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	// zsh extended history, with a multi-line command and a metafied character
+	zshHistory := ": 1700000000:0;git status\n: 1700000100:3;for f in *; do\\\n  echo $f\\\ndone\n: 1700000200:0;echo \xe2\x83\xa6\x83\xb2\n"
+	count, err := historyManager.ImportHistory(strings.NewReader(zshHistory), HistoryFormatZsh)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	entries, err := historyManager.GetAllEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "echo →", entries[0].Command)
+	assert.Equal(t, "for f in *; do\n  echo $f\ndone", entries[1].Command)
+	assert.Equal(t, int64(1700000100), entries[1].CreatedAt.Unix())
+	assert.False(t, entries[1].ExitCode.Valid)
+
+	// bash history, with and without timestamps, keeps its order
+	require.NoError(t, historyManager.ResetHistory())
+	count, err = historyManager.ImportHistory(strings.NewReader("ls -la\n\nmake\n#1700000000\ngit push\n"), HistoryFormatBash)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	recent, err := historyManager.GetRecentEntries("", 10)
+	require.NoError(t, err)
+	require.Len(t, recent, 3)
+	assert.Equal(t, "git push", recent[0].Command)
+	assert.Equal(t, "ls -la", recent[1].Command)
+	assert.Equal(t, "make", recent[2].Command)
+}
+
+func TestImportHistoryTwice(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	bashHistory := "#1700000000\ngit status\n#1700000100\ngit status\nmake\n"
+	count, err := historyManager.ImportHistory(strings.NewReader(bashHistory), HistoryFormatBash)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// The entries of the first import are skipped, dated or not
+	count, err = historyManager.ImportHistory(strings.NewReader(bashHistory), HistoryFormatBash)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// A new run of a command already in the history is still added
+	count, err = historyManager.ImportHistory(strings.NewReader(bashHistory+"#1700000200\ngit status\n"), HistoryFormatBash)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	entries, err := historyManager.GetAllEntries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	// The same goes for a JSON export imported back into its own history
+	var buf bytes.Buffer
+	_, err = historyManager.ExportHistory(&buf, HistoryFormatJSON)
+	require.NoError(t, err)
+	count, err = historyManager.ImportHistory(&buf, HistoryFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestHistoryJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := newExportTestManager(t).ExportHistory(&buf, HistoryFormatJSON)
+	require.NoError(t, err)
+
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	count, err := historyManager.ImportHistory(&buf, HistoryFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	entries, err := historyManager.GetAllEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "for f in *; do\n  echo $f\ndone", entries[0].Command)
+	assert.Equal(t, "/src", entries[0].Directory)
+	assert.Equal(t, int32(1), entries[0].ExitCode.Int32)
+	assert.True(t, entries[0].ExitCode.Valid)
+}
+
+func TestHistoryFormats(t *testing.T) {
+	format, err := ParseHistoryFormat("ZSH")
+	require.NoError(t, err)
+	assert.Equal(t, HistoryFormatZsh, format)
+	_, err = ParseHistoryFormat("fish")
+	assert.Error(t, err)
+
+	assert.Equal(t, HistoryFormatZsh, GuessHistoryFormat("/home/me/.zsh_history"))
+	assert.Equal(t, HistoryFormatJSON, GuessHistoryFormat("backup.JSON"))
+	assert.Equal(t, HistoryFormatBash, GuessHistoryFormat("/home/me/.bash_history"))
+}
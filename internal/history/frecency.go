@@ -0,0 +1,153 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirectoryVisit summarizes how often and how recently commands ran in a directory
+type DirectoryVisit struct {
+	Directory string
+	Count     int
+	LastVisit time.Time
+}
+
+// DirectoryMatch is a visited directory that matched a query, with its frecency score
+type DirectoryMatch struct {
+	Directory string
+	Score     float64
+}
+
+// FrecencyScore weighs how often a directory was used by how recently it was
+// last used, the same way z does: recent visits count for more.
+func FrecencyScore(count int, lastVisit time.Time, now time.Time) float64 {
+	age := now.Sub(lastVisit)
+	switch {
+	case age < time.Hour:
+		return float64(count) * 4
+	case age < 24*time.Hour:
+		return float64(count) * 2
+	case age < 7*24*time.Hour:
+		return float64(count) / 2
+	default:
+		return float64(count) / 4
+	}
+}
+
+// MatchDirectories returns the directories matching every query term, in order
+// and case-insensitively, ranked by frecency. As in z, the last term has to
+// match the final path component, so "z src" prefers ~/src over ~/src/app.
+// An empty query matches all directories.
+func MatchDirectories(visits []DirectoryVisit, query []string, now time.Time) []DirectoryMatch {
+	var matches []DirectoryMatch
+	for _, visit := range visits {
+		if !matchesQuery(visit.Directory, query) {
+			continue
+		}
+		matches = append(matches, DirectoryMatch{
+			Directory: visit.Directory,
+			Score:     FrecencyScore(visit.Count, visit.LastVisit, now),
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Directory < matches[j].Directory
+	})
+	return matches
+}
+
+func matchesQuery(directory string, query []string) bool {
+	lowerDirectory := strings.ToLower(directory)
+	offset := 0
+	for _, term := range query {
+		term = strings.ToLower(term)
+		idx := strings.Index(lowerDirectory[offset:], term)
+		if idx < 0 {
+			return false
+		}
+		offset += idx + len(term)
+	}
+
+	if len(query) > 0 {
+		lastTerm := strings.ToLower(query[len(query)-1])
+		base := strings.ToLower(filepath.Base(directory))
+		if !strings.Contains(base, lastTerm) && !strings.Contains(lastTerm, string(os.PathSeparator)) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetDirectoryVisits aggregates the directories commands were run in
+func (historyManager *HistoryManager) GetDirectoryVisits() ([]DirectoryVisit, error) {
+	var visits []DirectoryVisit
+	// max(created_at) comes back as text, but alongside it SQLite takes the
+	// bare created_at from the same row, which keeps its type
+	result := historyManager.db.Model(&HistoryEntry{}).
+		Select("directory, count(*) AS count, created_at AS last_visit, max(created_at)").
+		Where("directory != ''").
+		Group("directory").
+		Scan(&visits)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return visits, nil
+}
+
+// GetNextDirectoryVisits aggregates the directories commands ran in right after
+// a command ran in from, which is where one usually goes next from there
+func (historyManager *HistoryManager) GetNextDirectoryVisits(from string) ([]DirectoryVisit, error) {
+	// Each entry with the directory of the one before it
+	moves := historyManager.db.Model(&HistoryEntry{}).
+		Select("directory, created_at, lag(directory) OVER (ORDER BY id) AS previous").
+		Where("directory != ''")
+
+	var visits []DirectoryVisit
+	// As in GetDirectoryVisits, the bare created_at comes from the latest row
+	result := historyManager.db.Table("(?) AS moves", moves).
+		Select("directory, count(*) AS count, created_at AS last_visit, max(created_at)").
+		Where("previous = ? AND directory != ?", from, from).
+		Group("directory").
+		Scan(&visits)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return visits, nil
+}
+
+// RankDirectories returns existing directories matching query, best match first
+func (historyManager *HistoryManager) RankDirectories(query []string) ([]DirectoryMatch, error) {
+	visits, err := historyManager.GetDirectoryVisits()
+	if err != nil {
+		return nil, err
+	}
+	return existingDirectories(MatchDirectories(visits, query, time.Now())), nil
+}
+
+// RankNextDirectories returns the existing directories usually gone to next
+// from a directory, most frecent first
+func (historyManager *HistoryManager) RankNextDirectories(from string) ([]DirectoryMatch, error) {
+	visits, err := historyManager.GetNextDirectoryVisits(from)
+	if err != nil {
+		return nil, err
+	}
+	return existingDirectories(MatchDirectories(visits, nil, time.Now())), nil
+}
+
+func existingDirectories(matches []DirectoryMatch) []DirectoryMatch {
+	var existing []DirectoryMatch
+	for _, match := range matches {
+		if info, err := os.Stat(match.Directory); err == nil && info.IsDir() {
+			existing = append(existing, match)
+		}
+	}
+	return existing
+}
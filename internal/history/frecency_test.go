@@ -0,0 +1,233 @@
+package history
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+func TestFrecencyScore(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, 40.0, FrecencyScore(10, now.Add(-time.Minute), now))
+	assert.Equal(t, 20.0, FrecencyScore(10, now.Add(-2*time.Hour), now))
+	assert.Equal(t, 5.0, FrecencyScore(10, now.Add(-2*24*time.Hour), now))
+	assert.Equal(t, 2.5, FrecencyScore(10, now.Add(-30*24*time.Hour), now))
+
+	// A few recent visits can outrank many old ones
+	assert.Greater(t, FrecencyScore(3, now.Add(-time.Minute), now), FrecencyScore(20, now.Add(-60*24*time.Hour), now))
+}
+
+func TestMatchDirectories(t *testing.T) {
+	now := time.Now()
+	visits := []DirectoryVisit{
+		{Directory: "/home/user/src/gsh", Count: 10, LastVisit: now.Add(-time.Minute)},
+		{Directory: "/home/user/src/gsh/internal", Count: 30, LastVisit: now.Add(-2 * time.Hour)},
+		{Directory: "/home/user/src/api", Count: 5, LastVisit: now.Add(-30 * 24 * time.Hour)},
+		{Directory: "/home/user/Documents", Count: 1, LastVisit: now.Add(-time.Minute)},
+	}
+
+	directories := func(matches []DirectoryMatch) []string {
+		result := []string{}
+		for _, match := range matches {
+			result = append(result, match.Directory)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		query    []string
+		expected []string
+	}{
+		{
+			name:     "empty query ranks everything",
+			query:    nil,
+			expected: []string{"/home/user/src/gsh/internal", "/home/user/src/gsh", "/home/user/Documents", "/home/user/src/api"},
+		},
+		{
+			name:     "last term must match the final component",
+			query:    []string{"gsh"},
+			expected: []string{"/home/user/src/gsh"},
+		},
+		{
+			name:     "terms match in order",
+			query:    []string{"src", "int"},
+			expected: []string{"/home/user/src/gsh/internal"},
+		},
+		{
+			name:     "terms out of order don't match",
+			query:    []string{"int", "src"},
+			expected: []string{},
+		},
+		{
+			name:     "case insensitive",
+			query:    []string{"docu"},
+			expected: []string{"/home/user/Documents"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, directories(MatchDirectories(visits, tt.query, now)))
+		})
+	}
+}
+
+func TestGetDirectoryVisits(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	defer historyManager.Close()
+
+	now := time.Now()
+	for _, entry := range []HistoryEntry{
+		{Command: "make", Directory: "/project", CreatedAt: now.Add(-2 * time.Hour)},
+		{Command: "make", Directory: "/project", CreatedAt: now},
+		{Command: "make", Directory: "/project", CreatedAt: now.Add(-time.Hour)},
+		{Command: "ls", Directory: "/other", CreatedAt: now.Add(-time.Minute)},
+		{Command: "ls", CreatedAt: now},
+	} {
+		require.NoError(t, historyManager.db.Create(&entry).Error)
+	}
+
+	visits, err := historyManager.GetDirectoryVisits()
+	require.NoError(t, err)
+	require.Len(t, visits, 2)
+	byDirectory := make(map[string]DirectoryVisit)
+	for _, visit := range visits {
+		byDirectory[visit.Directory] = visit
+	}
+
+	assert.Equal(t, 3, byDirectory["/project"].Count)
+	assert.True(t, now.Equal(byDirectory["/project"].LastVisit), byDirectory["/project"].LastVisit)
+	assert.Equal(t, 1, byDirectory["/other"].Count)
+	assert.True(t, now.Add(-time.Minute).Equal(byDirectory["/other"].LastVisit))
+}
+
+func TestGetNextDirectoryVisits(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	defer historyManager.Close()
+
+	now := time.Now()
+	for _, entry := range []HistoryEntry{
+		{Command: "ls", Directory: "/home", CreatedAt: now.Add(-3 * time.Hour)},
+		{Command: "ls", Directory: "/project", CreatedAt: now.Add(-2 * time.Hour)},
+		{Command: "ls", Directory: "/home", CreatedAt: now.Add(-90 * time.Minute)},
+		// Entries without a directory don't break the sequence
+		{Command: "ls", CreatedAt: now.Add(-80 * time.Minute)},
+		{Command: "ls", Directory: "/project", CreatedAt: now},
+		{Command: "ls", Directory: "/home", CreatedAt: now.Add(-time.Hour)},
+		{Command: "ls", Directory: "/home", CreatedAt: now.Add(-time.Hour)},
+		{Command: "ls", Directory: "/docs", CreatedAt: now.Add(-time.Minute)},
+	} {
+		require.NoError(t, historyManager.db.Create(&entry).Error)
+	}
+
+	visits, err := historyManager.GetNextDirectoryVisits("/home")
+	require.NoError(t, err)
+	require.Len(t, visits, 2)
+	byDirectory := make(map[string]DirectoryVisit)
+	for _, visit := range visits {
+		byDirectory[visit.Directory] = visit
+	}
+
+	assert.Equal(t, 2, byDirectory["/project"].Count)
+	assert.True(t, now.Equal(byDirectory["/project"].LastVisit), byDirectory["/project"].LastVisit)
+	assert.Equal(t, 1, byDirectory["/docs"].Count)
+
+	visits, err = historyManager.GetNextDirectoryVisits("/docs")
+	require.NoError(t, err)
+	assert.Empty(t, visits)
+}
+
+func TestZCommandHandler(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	defer historyManager.Close()
+
+	projectDir := t.TempDir()
+	otherDir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		_, err = historyManager.StartCommand("make", projectDir)
+		require.NoError(t, err)
+	}
+	_, err = historyManager.StartCommand("ls", otherDir)
+	require.NoError(t, err)
+	_, err = historyManager.StartCommand("ls", "/definitely/not/a/real/dir")
+	require.NoError(t, err)
+
+	visits, err := historyManager.GetDirectoryVisits()
+	require.NoError(t, err)
+	assert.Len(t, visits, 3)
+
+	run := func(command string) (string, error) {
+		var stdout strings.Builder
+		runner, err := interp.New(
+			interp.StdIO(nil, &stdout, &strings.Builder{}),
+			interp.ExecHandlers(NewZCommandHandler(historyManager)),
+		)
+		require.NoError(t, err)
+		prog, err := syntax.NewParser().Parse(strings.NewReader(command), "test")
+		require.NoError(t, err)
+		err = runner.Run(context.Background(), prog)
+		return stdout.String(), err
+	}
+
+	// The most frecent existing directory wins when no query narrows it down
+	output, err := run("gsh_z " + projectDir[len(projectDir)-3:])
+	assert.NoError(t, err)
+	assert.Equal(t, projectDir+"\n", output)
+
+	// Listing shows the best match last, and skips missing directories
+	output, err = run("gsh_z -l")
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[1], projectDir))
+
+	// Existing paths are used as is
+	output, err = run("gsh_z " + otherDir)
+	assert.NoError(t, err)
+	assert.Equal(t, otherDir+"\n", output)
+
+	_, err = run("gsh_z no-such-match")
+	status, ok := interp.IsExitStatus(err)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(1), status)
+}
+
+func TestRankNextDirectories(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	defer historyManager.Close()
+
+	home := t.TempDir()
+	project := t.TempDir()
+	docs := t.TempDir()
+	for _, dir := range []string{home, project, project, home, project, home, docs, "/definitely/not/a/real/dir", home, "/definitely/not/a/real/dir"} {
+		_, err = historyManager.StartCommand("ls", dir)
+		require.NoError(t, err)
+	}
+
+	visits, err := historyManager.GetNextDirectoryVisits(home)
+	require.NoError(t, err)
+	assert.Len(t, visits, 3)
+
+	// Staying in the directory isn't moving on, and missing directories are skipped
+	matches, err := historyManager.RankNextDirectories(home)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, project, matches[0].Directory)
+	assert.Equal(t, docs, matches[1].Directory)
+
+	matches, err = historyManager.RankNextDirectories(docs)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
@@ -3,7 +3,7 @@ package history
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/atinylittleshell/gsh/pkg/reverse"
@@ -23,16 +23,36 @@ type HistoryEntry struct {
 	Command   string
 	Directory string
 	ExitCode  sql.NullInt32
+	// Note is a comment on the command added with @!note
+	Note string
+}
+
+// sqliteBusyTimeoutMs is how long a connection waits for another gsh to release
+// a lock on the database before failing with "database is locked"
+const sqliteBusyTimeoutMs = 5000
+
+// sqliteDSN adds the pragmas every connection should run to a database path.
+// WAL journaling lets gsh instances in other terminals read while one of them
+// writes, and the busy timeout makes concurrent writers wait for each other.
+func sqliteDSN(dbFilePath string) string {
+	if dbFilePath == ":memory:" {
+		return dbFilePath
+	}
+
+	separator := "?"
+	if strings.Contains(dbFilePath, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)", dbFilePath, separator, sqliteBusyTimeoutMs)
 }
 
 func NewHistoryManager(dbFilePath string) (*HistoryManager, error) {
-	db, err := gorm.Open(sqlite.Open(dbFilePath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(sqliteDSN(dbFilePath)), &gorm.Config{})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error opening database")
 		return nil, err
 	}
 
-	if err := db.AutoMigrate(&HistoryEntry{}); err != nil {
+	if err := db.AutoMigrate(&HistoryEntry{}, &Bookmark{}); err != nil {
 		return nil, err
 	}
 
@@ -41,6 +61,40 @@ func NewHistoryManager(dbFilePath string) (*HistoryManager, error) {
 	}, nil
 }
 
+// NewInMemoryHistoryManager creates a history manager that only lasts for the
+// session, for when the history file can't be used
+func NewInMemoryHistoryManager() (*HistoryManager, error) {
+	historyManager, err := NewHistoryManager(":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each connection to :memory: is a separate database, so keep a single one
+	sqlDB, err := historyManager.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return historyManager, nil
+}
+
+// DescribeDatabaseError explains why a database file couldn't be opened,
+// recognizing the common cases of a file locked by another process and a
+// corrupt file
+func DescribeDatabaseError(dbFilePath string, err error) string {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "database is locked") || strings.Contains(message, "SQLITE_BUSY") || strings.Contains(message, "SQLITE_LOCKED"):
+		return fmt.Sprintf("%s is locked by another process", dbFilePath)
+	case strings.Contains(message, "file is not a database") || strings.Contains(message, "malformed") ||
+		strings.Contains(message, "SQLITE_NOTADB") || strings.Contains(message, "SQLITE_CORRUPT"):
+		return fmt.Sprintf("%s is corrupt", dbFilePath)
+	default:
+		return fmt.Sprintf("failed to open %s: %s", dbFilePath, message)
+	}
+}
+
 // Close closes the database connection. This should be called when the
 // HistoryManager is no longer needed, especially in tests to allow cleanup
 // of temporary database files on Windows.
@@ -83,6 +137,19 @@ func (historyManager *HistoryManager) FinishCommand(entry *HistoryEntry, exitCod
 	return entry, nil
 }
 
+// SetNote sets the note of an entry, or removes it when note is empty
+func (historyManager *HistoryManager) SetNote(id uint, note string) error {
+	result := historyManager.db.Model(&HistoryEntry{}).Where("id = ?", id).Update("note", note)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no history entry found with id %d", id)
+	}
+
+	return nil
+}
+
 func (historyManager *HistoryManager) GetRecentEntries(directory string, limit int) ([]HistoryEntry, error) {
 	var entries []HistoryEntry
 	var db = historyManager.db
@@ -142,6 +209,27 @@ func (historyManager *HistoryManager) GetRecentEntriesByPrefix(prefix string, li
 	return entries, nil
 }
 
+// SearchCommands returns the latest entry of each distinct command containing
+// query, or with a note containing it, most recent first
+func (historyManager *HistoryManager) SearchCommands(query string, limit int) ([]HistoryEntry, error) {
+	// instr instead of LIKE, since commands often contain _ and %
+	latest := historyManager.db.Model(&HistoryEntry{}).
+		Select("max(id)").
+		Where("instr(command, ?) > 0 OR instr(note, ?) > 0", query, query).
+		Group("command")
+
+	var entries []HistoryEntry
+	result := historyManager.db.Where("id IN (?)", latest).
+		Order("id desc").
+		Limit(limit).
+		Find(&entries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return entries, nil
+}
+
 // GetEntriesSince returns all history entries created after the given time, ordered by creation time (oldest first)
 func (historyManager *HistoryManager) GetEntriesSince(since time.Time) ([]HistoryEntry, error) {
 	var entries []HistoryEntry
@@ -2,9 +2,15 @@ package history
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBasicOperations(t *testing.T) {
@@ -49,6 +55,22 @@ func TestBasicOperations(t *testing.T) {
 	assert.Len(t, nonTargetEntries, 0, "Expected 0 entries")
 }
 
+func TestMultilineCommandRoundTrip(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	assert.NoError(t, err, "Failed to create history manager")
+
+	command := "cat <<EOF\nfirst line\n  indented line\nEOF"
+	entry, err := historyManager.StartCommand(command, "/")
+	assert.NoError(t, err)
+	_, err = historyManager.FinishCommand(entry, 0)
+	assert.NoError(t, err)
+
+	entries, err := historyManager.GetRecentEntries("", 1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, command, entries[0].Command, "Embedded newlines should be preserved")
+}
+
 func TestDeleteEntry(t *testing.T) {
 	historyManager, err := NewHistoryManager(":memory:")
 	assert.NoError(t, err, "Failed to create history manager")
@@ -296,4 +318,131 @@ func TestGetRecentEntriesByPrefix(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, entries, 5)
 	})
-}
\ No newline at end of file
+}
+
+func TestSearchCommands(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	assert.NoError(t, err, "Failed to create history manager")
+
+	for _, command := range []string{"go test ./...", "make build", "go test ./...", "echo 100%_done", "go vet ./..."} {
+		_, err := historyManager.StartCommand(command, "/src")
+		assert.NoError(t, err)
+	}
+
+	entries, err := historyManager.SearchCommands("go ", 10)
+	assert.NoError(t, err)
+	commands := []string{}
+	for _, entry := range entries {
+		commands = append(commands, entry.Command)
+	}
+	// Each command once, most recent first
+	assert.Equal(t, []string{"go vet ./...", "go test ./..."}, commands)
+
+	entries, err = historyManager.SearchCommands("%_", 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "echo 100%_done", entries[0].Command)
+
+	entries, err = historyManager.SearchCommands("", 2)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestSetNote(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	assert.NoError(t, err)
+
+	entry, err := historyManager.StartCommand("make deploy ENV=staging", "/src")
+	assert.NoError(t, err)
+	_, err = historyManager.StartCommand("ls", "/src")
+	assert.NoError(t, err)
+
+	assert.NoError(t, historyManager.SetNote(entry.ID, "the one that actually worked"))
+	assert.ErrorContains(t, historyManager.SetNote(999, "missing"), "no history entry found")
+
+	// Notes are searched along with commands
+	entries, err := historyManager.SearchCommands("actually worked", 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "make deploy ENV=staging", entries[0].Command)
+	assert.Equal(t, "the one that actually worked", entries[0].Note)
+}
+
+func TestDatabaseFallback(t *testing.T) {
+	// A file that isn't a SQLite database can't be used as history
+	dbFile := filepath.Join(t.TempDir(), "history.db")
+	require.NoError(t, os.WriteFile(dbFile, []byte(strings.Repeat("not a database\n", 100)), 0o644))
+
+	_, err := NewHistoryManager(dbFile)
+	require.Error(t, err)
+	assert.Equal(t, dbFile+" is corrupt", DescribeDatabaseError(dbFile, err))
+
+	assert.Equal(t, "/tmp/h.db is locked by another process", DescribeDatabaseError("/tmp/h.db", errors.New("database is locked (5) (SQLITE_BUSY)")))
+	assert.Equal(t, "failed to open /tmp/h.db: disk I/O error", DescribeDatabaseError("/tmp/h.db", errors.New("disk I/O error")))
+
+	// The in-memory fallback keeps working across goroutines
+	historyManager, err := NewInMemoryHistoryManager()
+	require.NoError(t, err)
+	defer historyManager.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := historyManager.StartCommand("echo hi", "/tmp")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	entries, err := historyManager.GetAllEntries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 8)
+}
+
+func TestConcurrentConnections(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "history.db")
+
+	first, err := NewHistoryManager(dbFile)
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := NewHistoryManager(dbFile)
+	require.NoError(t, err)
+	defer second.Close()
+
+	var journalMode string
+	require.NoError(t, first.db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error)
+	assert.Equal(t, "wal", journalMode)
+
+	// Two gsh instances writing at the same time wait for each other instead of failing
+	const writesPerManager = 50
+	var wg sync.WaitGroup
+	for _, historyManager := range []*HistoryManager{first, second} {
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(historyManager *HistoryManager) {
+				defer wg.Done()
+				for j := 0; j < writesPerManager/2; j++ {
+					entry, err := historyManager.StartCommand("echo hi", "/tmp")
+					if !assert.NoError(t, err) {
+						return
+					}
+					_, err = historyManager.FinishCommand(entry, 0)
+					assert.NoError(t, err)
+				}
+			}(historyManager)
+		}
+	}
+	wg.Wait()
+
+	entries, err := second.GetAllEntries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2*writesPerManager)
+}
+
+func TestSqliteDSN(t *testing.T) {
+	assert.Equal(t, ":memory:", sqliteDSN(":memory:"))
+	assert.Equal(t, "/tmp/h.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", sqliteDSN("/tmp/h.db"))
+	assert.Equal(t, "/tmp/h.db?mode=ro&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", sqliteDSN("/tmp/h.db?mode=ro"))
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"time"
+)
+
+// HistoryStats describes the size of the history database
+type HistoryStats struct {
+	Entries           int64
+	TranscriptEntries int64
+	Oldest            time.Time
+	SizeBytes         int64
+	// FreeBytes is space left by deleted rows that Vacuum would reclaim
+	FreeBytes int64
+}
+
+// PruneOlderThan deletes history and transcript entries created before cutoff
+// and returns how many history entries were deleted. The latest entry of each
+// directory is kept, so z and directory completion still know every directory
+// that was visited.
+func (historyManager *HistoryManager) PruneOlderThan(cutoff time.Time) (int64, error) {
+	result := historyManager.db.Exec(
+		`DELETE FROM history_entries WHERE created_at < ? AND id NOT IN (SELECT max(id) FROM history_entries GROUP BY directory)`,
+		cutoff)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if historyManager.db.Migrator().HasTable(&TranscriptEntry{}) {
+		if err := historyManager.db.Where("created_at < ?", cutoff).Delete(&TranscriptEntry{}).Error; err != nil {
+			return result.RowsAffected, err
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// Vacuum rebuilds the database file to reclaim the space left by deleted rows
+func (historyManager *HistoryManager) Vacuum() error {
+	if err := historyManager.db.Exec("VACUUM").Error; err != nil {
+		return err
+	}
+	// Also shrink the WAL file, which otherwise keeps its largest size
+	return historyManager.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error
+}
+
+// GetStats returns the number of entries and the size of the database
+func (historyManager *HistoryManager) GetStats() (HistoryStats, error) {
+	var stats HistoryStats
+	if err := historyManager.db.Model(&HistoryEntry{}).Count(&stats.Entries).Error; err != nil {
+		return stats, err
+	}
+	if historyManager.db.Migrator().HasTable(&TranscriptEntry{}) {
+		if err := historyManager.db.Model(&TranscriptEntry{}).Count(&stats.TranscriptEntries).Error; err != nil {
+			return stats, err
+		}
+	}
+
+	var oldest HistoryEntry
+	result := historyManager.db.Order("id asc").Limit(1).Find(&oldest)
+	if result.Error != nil {
+		return stats, result.Error
+	}
+	if result.RowsAffected > 0 {
+		stats.Oldest = oldest.CreatedAt
+	}
+
+	var pageSize, pageCount, freelistCount int64
+	if err := historyManager.db.Raw("PRAGMA page_size").Scan(&pageSize).Error; err != nil {
+		return stats, err
+	}
+	if err := historyManager.db.Raw("PRAGMA page_count").Scan(&pageCount).Error; err != nil {
+		return stats, err
+	}
+	if err := historyManager.db.Raw("PRAGMA freelist_count").Scan(&freelistCount).Error; err != nil {
+		return stats, err
+	}
+	stats.SizeBytes = pageSize * pageCount
+	stats.FreeBytes = pageSize * freelistCount
+	return stats, nil
+}
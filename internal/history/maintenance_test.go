@@ -0,0 +1,77 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneOlderThan(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	transcriptManager, err := NewTranscriptManager(historyManager.GetDB(), 1024)
+	require.NoError(t, err)
+
+	now := time.Now()
+	old := now.AddDate(-2, 0, 0)
+	add := func(command, directory string, createdAt time.Time) {
+		require.NoError(t, historyManager.db.Create(&HistoryEntry{Command: command, Directory: directory, CreatedAt: createdAt}).Error)
+	}
+	add("make build", "/src/app", old)
+	add("make test", "/src/app", old)
+	add("ls", "/src/archived", old)
+	add("make build", "/src/app", now)
+
+	require.NoError(t, transcriptManager.db.Create(&TranscriptEntry{Command: "make build", CreatedAt: old}).Error)
+	require.NoError(t, transcriptManager.Record("make build", "/src/app", "ok", 0))
+
+	deleted, err := historyManager.PruneOlderThan(now.AddDate(-1, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	// The only entry for /src/archived is kept so the directory is still known
+	entries, err := historyManager.GetAllEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "make build", entries[0].Command)
+	assert.Equal(t, "/src/archived", entries[1].Directory)
+
+	transcript, err := transcriptManager.Search("", 10)
+	require.NoError(t, err)
+	assert.Len(t, transcript, 1)
+}
+
+func TestVacuumAndStats(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	stats, err := historyManager.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Entries)
+	assert.True(t, stats.Oldest.IsZero())
+
+	for i := 0; i < 200; i++ {
+		_, err := historyManager.StartCommand("echo some reasonably long command to take up space", "/tmp")
+		require.NoError(t, err)
+	}
+
+	stats, err = historyManager.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), stats.Entries)
+	assert.False(t, stats.Oldest.IsZero())
+	assert.Positive(t, stats.SizeBytes)
+
+	require.NoError(t, historyManager.ResetHistory())
+	stats, err = historyManager.GetStats()
+	require.NoError(t, err)
+	assert.Positive(t, stats.FreeBytes)
+	sizeBefore := stats.SizeBytes
+
+	require.NoError(t, historyManager.Vacuum())
+	stats, err = historyManager.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.FreeBytes)
+	assert.Less(t, stats.SizeBytes, sizeBefore)
+}
@@ -0,0 +1,40 @@
+package history
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// CommandFrequency is a command and how many times it ran successfully
+type CommandFrequency struct {
+	Command string
+	Count   int
+}
+
+// GetFrequentCommandsUnder returns the successful commands starting with prefix
+// that ran in root or any directory below it, most frequent first. Ties go to
+// the command run most recently.
+func (historyManager *HistoryManager) GetFrequentCommandsUnder(root string, prefix string, limit int) ([]CommandFrequency, error) {
+	root = strings.TrimSuffix(root, "/")
+	if root == "" {
+		return nil, nil
+	}
+	subdirPrefix := root + "/"
+
+	// substr instead of LIKE, since paths and commands often contain _ and %
+	var frequencies []CommandFrequency
+	result := historyManager.db.Model(&HistoryEntry{}).
+		Select("command, count(*) as count").
+		Where("exit_code = 0").
+		Where("directory = ? OR substr(directory, 1, ?) = ?", root, utf8.RuneCountInString(subdirPrefix), subdirPrefix).
+		Where("substr(command, 1, ?) = ?", utf8.RuneCountInString(prefix), prefix).
+		Group("command").
+		Order("count desc, max(id) desc").
+		Limit(limit).
+		Scan(&frequencies)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return frequencies, nil
+}
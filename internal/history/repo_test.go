@@ -0,0 +1,46 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFrequentCommandsUnder(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	run := func(command, directory string, exitCode int) {
+		entry, err := historyManager.StartCommand(command, directory)
+		require.NoError(t, err)
+		_, err = historyManager.FinishCommand(entry, exitCode)
+		require.NoError(t, err)
+	}
+
+	run("make build", "/src/repo_x", 0)
+	run("make test", "/src/repo_x/cmd", 0)
+	run("make test", "/src/repo_x", 0)
+	run("make tset", "/src/repo_x", 2)
+	run("make deploy", "/src/other", 0)
+	run("make deploy", "/src/other", 0)
+	run("make lint", "/src/repo_x2", 0)
+
+	frequencies, err := historyManager.GetFrequentCommandsUnder("/src/repo_x", "make", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []CommandFrequency{
+		{Command: "make test", Count: 2},
+		{Command: "make build", Count: 1},
+	}, frequencies)
+
+	// Ties go to the most recent command
+	run("make build", "/src/repo_x", 0)
+	frequencies, err = historyManager.GetFrequentCommandsUnder("/src/repo_x/", "make ", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []CommandFrequency{{Command: "make build", Count: 2}}, frequencies)
+
+	// Wildcard characters in the prefix are matched literally
+	frequencies, err = historyManager.GetFrequentCommandsUnder("/src/repo_x", "make_", 5)
+	require.NoError(t, err)
+	assert.Empty(t, frequencies)
+}
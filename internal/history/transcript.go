@@ -0,0 +1,99 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// transcriptTruncatedMarker is appended to output cut at the size cap
+const transcriptTruncatedMarker = "\n[output truncated]"
+
+// TranscriptEntry is a command together with what it printed. Unlike history
+// entries, transcript entries keep the output so past sessions can be reviewed.
+type TranscriptEntry struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	SessionID string `gorm:"index"`
+	Command   string
+	Directory string
+	Output    string
+	ExitCode  int
+}
+
+// TranscriptManager records the commands and outputs of a shell session in the
+// history database
+type TranscriptManager struct {
+	db             *gorm.DB
+	sessionID      string
+	maxOutputBytes int
+}
+
+// NewTranscriptManager creates a transcript manager for a new session. Output
+// longer than maxOutputBytes is truncated when recorded.
+func NewTranscriptManager(db *gorm.DB, maxOutputBytes int) (*TranscriptManager, error) {
+	if err := db.AutoMigrate(&TranscriptEntry{}); err != nil {
+		return nil, err
+	}
+
+	return &TranscriptManager{
+		db:             db,
+		sessionID:      fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid()),
+		maxOutputBytes: maxOutputBytes,
+	}, nil
+}
+
+// SetMaxOutputBytes changes the size cap for entries recorded from now on
+func (transcriptManager *TranscriptManager) SetMaxOutputBytes(maxOutputBytes int) {
+	transcriptManager.maxOutputBytes = maxOutputBytes
+}
+
+// Record adds a command and its output to the transcript of the current session
+func (transcriptManager *TranscriptManager) Record(command string, directory string, output string, exitCode int) error {
+	entry := TranscriptEntry{
+		SessionID: transcriptManager.sessionID,
+		Command:   command,
+		Directory: directory,
+		Output:    truncateTranscriptOutput(output, transcriptManager.maxOutputBytes),
+		ExitCode:  exitCode,
+	}
+	return transcriptManager.db.Create(&entry).Error
+}
+
+// Search returns up to limit transcript entries from any session whose command or
+// output contains query, ignoring case, newest first. An empty query returns the
+// most recent entries.
+func (transcriptManager *TranscriptManager) Search(query string, limit int) ([]TranscriptEntry, error) {
+	var entries []TranscriptEntry
+	db := transcriptManager.db
+	if query != "" {
+		// instr rather than LIKE, so % and _ in the query are matched literally
+		db = db.Where("instr(lower(command), lower(?)) > 0 OR instr(lower(output), lower(?)) > 0", query, query)
+	}
+	result := db.Order("id desc").Limit(limit).Find(&entries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return entries, nil
+}
+
+// truncateTranscriptOutput cuts output to at most maxBytes, on a rune boundary
+func truncateTranscriptOutput(output string, maxBytes int) string {
+	if maxBytes <= 0 {
+		return ""
+	}
+	if len(output) <= maxBytes {
+		return output
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return strings.TrimRight(output[:cut], "\n") + transcriptTruncatedMarker
+}
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptRecordAndSearch(t *testing.T) {
+	historyManager, err := NewHistoryManager(":memory:")
+	require.NoError(t, err)
+
+	transcriptManager, err := NewTranscriptManager(historyManager.GetDB(), 16)
+	require.NoError(t, err)
+
+	require.NoError(t, transcriptManager.Record("go test ./...", "/src/app", "FAIL: TestLogin\n", 1))
+	require.NoError(t, transcriptManager.Record("ls", "/src/app", "main.go\n", 0))
+	require.NoError(t, transcriptManager.Record("cat 100%_done.txt", "/src", "", 0))
+
+	// Newest first, searching commands and output regardless of case
+	entries, err := transcriptManager.Search("", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "cat 100%_done.txt", entries[0].Command)
+
+	entries, err = transcriptManager.Search("testlogin", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "go test ./...", entries[0].Command)
+	assert.Equal(t, 1, entries[0].ExitCode)
+	assert.Equal(t, "/src/app", entries[0].Directory)
+
+	// Wildcard characters in the query are matched literally
+	entries, err = transcriptManager.Search("0%_d", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entries, err = transcriptManager.Search("%", 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = transcriptManager.Search("", 1)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Entries from other sessions are searchable too
+	otherSession, err := NewTranscriptManager(historyManager.GetDB(), 16)
+	require.NoError(t, err)
+	entries, err = otherSession.Search("main.go", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, transcriptManager.sessionID, entries[0].SessionID)
+	assert.NotEqual(t, transcriptManager.sessionID, otherSession.sessionID)
+
+	// Output over the size cap is truncated
+	require.NoError(t, otherSession.Record("seq 100", "/src", strings.Repeat("line\n", 20), 0))
+	entries, err = otherSession.Search("seq 100", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "line\nline\nline\nl"+transcriptTruncatedMarker, entries[0].Output)
+}
+
+func TestTruncateTranscriptOutput(t *testing.T) {
+	assert.Equal(t, "short", truncateTranscriptOutput("short", 10))
+	assert.Equal(t, "", truncateTranscriptOutput("anything", 0))
+
+	// Multi-byte characters are not split
+	assert.Equal(t, "a"+transcriptTruncatedMarker, truncateTranscriptOutput("aé", 2))
+}
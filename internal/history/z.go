@@ -0,0 +1,76 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// NewZCommandHandler creates the gsh_z builtin behind the z function, which jumps
+// to the most frecent previously visited directory matching a query. gsh_z
+// prints the best match, or lists the ranked matches with -l or no query; the
+// actual cd happens in the z shell function since builtins can't change the
+// working directory.
+func NewZCommandHandler(historyManager *HistoryManager) func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			if len(args) == 0 || args[0] != "gsh_z" {
+				return next(ctx, args)
+			}
+
+			hc := interp.HandlerCtx(ctx)
+
+			list := false
+			query := []string{}
+			for _, arg := range args[1:] {
+				if arg == "-l" {
+					list = true
+					continue
+				}
+				query = append(query, arg)
+			}
+			if len(query) == 0 {
+				list = true
+			}
+
+			// An existing path is taken as is, so z still works like cd
+			if !list && len(query) == 1 {
+				path := query[0]
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(hc.Dir, path)
+				}
+				if info, err := os.Stat(path); err == nil && info.IsDir() {
+					fmt.Fprintln(hc.Stdout, path)
+					return nil
+				}
+			}
+
+			matches, err := historyManager.RankDirectories(query)
+			if err != nil {
+				return err
+			}
+
+			if list {
+				for i := len(matches) - 1; i >= 0; i-- {
+					fmt.Fprintf(hc.Stdout, "%-10.1f %s\n", matches[i].Score, matches[i].Directory)
+				}
+				return nil
+			}
+
+			for _, match := range matches {
+				// Jumping to where we already are isn't useful when there are other matches
+				if match.Directory == hc.Dir && len(matches) > 1 {
+					continue
+				}
+				fmt.Fprintln(hc.Stdout, match.Directory)
+				return nil
+			}
+
+			fmt.Fprintf(hc.Stderr, "z: no match found\n")
+			return interp.NewExitStatus(1)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+// Package inputrc reads the subset of readline's ~/.inputrc that gsh can
+// apply: single-key bindings of readline functions and a few variables.
+package inputrc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// applicationName is what `$if` compares against, like "Bash" in bash
+const applicationName = "gsh"
+
+// maxIncludeDepth guards against files that include each other
+const maxIncludeDepth = 10
+
+// Binding binds a key, in the format of bubbletea key names such as "ctrl+a"
+// or "alt+f", to a readline function
+type Binding struct {
+	Key      string
+	Function string
+}
+
+// Config is what was read from an inputrc file
+type Config struct {
+	Bindings  []Binding
+	Variables map[string]string
+}
+
+func newConfig() *Config {
+	return &Config{Variables: make(map[string]string)}
+}
+
+// Bool returns a readline boolean variable, which is on when set to "on" or "1"
+func (c *Config) Bool(name string) bool {
+	value, ok := c.Variables[name]
+	if !ok {
+		return false
+	}
+	return value == "" || strings.EqualFold(value, "on") || value == "1"
+}
+
+// EditingMode returns "emacs" or "vi"
+func (c *Config) EditingMode() string {
+	if strings.EqualFold(c.Variables["editing-mode"], "vi") {
+		return "vi"
+	}
+	return "emacs"
+}
+
+// CompletionIgnoreCase reports whether `set completion-ignore-case on` is set
+func (c *Config) CompletionIgnoreCase() bool {
+	return c.Bool("completion-ignore-case")
+}
+
+// DefaultPath returns the file readline would read: $INPUTRC, ~/.inputrc, or
+// /etc/inputrc if there is no ~/.inputrc
+func DefaultPath() string {
+	if path := os.Getenv("INPUTRC"); path != "" {
+		return path
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		path := filepath.Join(home, ".inputrc")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return "/etc/inputrc"
+}
+
+// Load reads an inputrc file. A missing file gives an empty Config.
+func Load(path string, logger *zap.Logger) (*Config, error) {
+	config := newConfig()
+	if err := config.loadFile(path, logger, 0); err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, err
+	}
+	return config, nil
+}
+
+// Parse reads inputrc directives from r. Directives gsh can't apply, such as
+// multi-key sequences, macros or unknown variables, are skipped and logged at
+// debug level.
+func Parse(r io.Reader, logger *zap.Logger) (*Config, error) {
+	config := newConfig()
+	err := config.parse(r, "", logger, 0)
+	return config, err
+}
+
+func (c *Config) loadFile(path string, logger *zap.Logger, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: too many nested $include directives", path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.parse(file, filepath.Dir(path), logger, depth)
+}
+
+func (c *Config) parse(r io.Reader, dir string, logger *zap.Logger, depth int) error {
+	// skipping tracks nested $if blocks; a line applies when none is skipping
+	var skipping []bool
+	skipped := func() bool {
+		for _, skip := range skipping {
+			if skip {
+				return true
+			}
+		}
+		return false
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "$") {
+			directive, arg, _ := strings.Cut(line[1:], " ")
+			arg = strings.TrimSpace(arg)
+			switch directive {
+			case "if":
+				skipping = append(skipping, !c.condition(arg))
+			case "else":
+				if len(skipping) > 0 {
+					skipping[len(skipping)-1] = !skipping[len(skipping)-1]
+				}
+			case "endif":
+				if len(skipping) > 0 {
+					skipping = skipping[:len(skipping)-1]
+				}
+			case "include":
+				if skipped() {
+					continue
+				}
+				path := expandHome(arg)
+				if !filepath.IsAbs(path) && dir != "" {
+					path = filepath.Join(dir, path)
+				}
+				if err := c.loadFile(path, logger, depth+1); err != nil {
+					logger.Debug("inputrc: skipping $include", zap.String("path", path), zap.Error(err))
+				}
+			default:
+				logger.Debug("inputrc: ignoring unknown directive", zap.Int("line", lineNumber), zap.String("directive", line))
+			}
+			continue
+		}
+
+		if skipped() {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "set "); ok {
+			name, value, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			name = strings.ToLower(name)
+			c.Variables[name] = strings.TrimSpace(value)
+			if !supportedVariables[name] {
+				logger.Debug("inputrc: ignoring unsupported variable", zap.Int("line", lineNumber), zap.String("variable", name))
+			}
+			continue
+		}
+
+		binding, err := parseBinding(line)
+		if err != nil {
+			logger.Debug("inputrc: ignoring key binding", zap.Int("line", lineNumber), zap.String("binding", line), zap.Error(err))
+			continue
+		}
+		if _, ok := readlineFunctions[binding.Function]; !ok {
+			logger.Debug("inputrc: ignoring unsupported function", zap.Int("line", lineNumber), zap.String("function", binding.Function))
+			continue
+		}
+		c.Bindings = append(c.Bindings, binding)
+	}
+	return scanner.Err()
+}
+
+// supportedVariables are the variables gsh acts on
+var supportedVariables = map[string]bool{
+	"completion-ignore-case": true,
+	"editing-mode":           true,
+}
+
+// condition evaluates the test of an `$if` directive
+func (c *Config) condition(test string) bool {
+	if value, ok := strings.CutPrefix(test, "mode="); ok {
+		return strings.EqualFold(value, c.EditingMode())
+	}
+	if value, ok := strings.CutPrefix(test, "term="); ok {
+		term := os.Getenv("TERM")
+		return term == value || strings.HasPrefix(term, value+"-")
+	}
+	if name, value, ok := strings.Cut(test, "=="); ok {
+		return strings.EqualFold(c.Variables[strings.TrimSpace(name)], strings.TrimSpace(value))
+	}
+	return strings.EqualFold(test, applicationName)
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// parseBinding parses `"keyseq": function` or `keyname: function`
+func parseBinding(line string) (Binding, error) {
+	var keys []byte
+	var rest string
+	if strings.HasPrefix(line, `"`) {
+		end := closingQuote(line)
+		if end < 0 {
+			return Binding{}, fmt.Errorf("unterminated key sequence")
+		}
+		keys = decodeKeySequence(line[1:end])
+		rest = line[end+1:]
+	} else {
+		name, after, ok := strings.Cut(line, ":")
+		if !ok {
+			return Binding{}, fmt.Errorf("missing colon")
+		}
+		var err error
+		if keys, err = decodeKeyName(strings.TrimSpace(name)); err != nil {
+			return Binding{}, err
+		}
+		rest = ":" + after
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, ":") {
+		return Binding{}, fmt.Errorf("missing colon")
+	}
+	function := strings.TrimSpace(rest[1:])
+	if strings.HasPrefix(function, `"`) || strings.HasPrefix(function, "'") {
+		return Binding{}, fmt.Errorf("macros are not supported")
+	}
+	if fields := strings.Fields(function); len(fields) > 0 {
+		function = fields[0]
+	}
+
+	key, err := keyName(keys)
+	if err != nil {
+		return Binding{}, err
+	}
+	return Binding{Key: key, Function: strings.ToLower(function)}, nil
+}
+
+// closingQuote returns the index of the quote that ends the key sequence
+// starting at line[0], or -1
+func closingQuote(line string) int {
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeKeySequence turns the escapes of a quoted key sequence into the bytes
+// the terminal sends
+func decodeKeySequence(seq string) []byte {
+	var out []byte
+	for i := 0; i < len(seq); i++ {
+		if seq[i] != '\\' || i+1 >= len(seq) {
+			out = append(out, seq[i])
+			continue
+		}
+
+		i++
+		switch c := seq[i]; {
+		case c == 'C' && strings.HasPrefix(seq[i:], "C-") && i+2 < len(seq):
+			i += 2
+			next := seq[i]
+			if next == '\\' && i+1 < len(seq) {
+				i++
+				next = seq[i]
+			}
+			out = append(out, controlByte(next))
+		case c == 'M' && strings.HasPrefix(seq[i:], "M-"):
+			i++
+			out = append(out, 0x1b)
+		case c == 'e':
+			out = append(out, 0x1b)
+		case c == 'a':
+			out = append(out, 0x07)
+		case c == 'b':
+			out = append(out, 0x08)
+		case c == 'd':
+			out = append(out, 0x7f)
+		case c == 'f':
+			out = append(out, 0x0c)
+		case c == 'n':
+			out = append(out, 0x0a)
+		case c == 'r':
+			out = append(out, 0x0d)
+		case c == 't':
+			out = append(out, 0x09)
+		case c == 'v':
+			out = append(out, 0x0b)
+		case c >= '0' && c <= '7':
+			value, n := 0, 0
+			for n < 3 && i+n < len(seq) && seq[i+n] >= '0' && seq[i+n] <= '7' {
+				value = value*8 + int(seq[i+n]-'0')
+				n++
+			}
+			i += n - 1
+			out = append(out, byte(value))
+		case c == 'x':
+			value, n := 0, 0
+			for n < 2 && i+1+n < len(seq) && isHexDigit(seq[i+1+n]) {
+				value = value*16 + hexValue(seq[i+1+n])
+				n++
+			}
+			i += n
+			out = append(out, byte(value))
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) int {
+	switch {
+	case c >= 'a':
+		return int(c-'a') + 10
+	case c >= 'A':
+		return int(c-'A') + 10
+	default:
+		return int(c - '0')
+	}
+}
+
+func controlByte(c byte) byte {
+	if c == '?' {
+		return 0x7f
+	}
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	return c & 0x1f
+}
+
+// keyNameBytes are the symbolic names readline accepts for keys
+var keyNameBytes = map[string]byte{
+	"del": 0x7f, "rubout": 0x7f,
+	"esc": 0x1b, "escape": 0x1b,
+	"lfd": 0x0a, "newline": 0x0a,
+	"ret": 0x0d, "return": 0x0d,
+	"spc": ' ', "space": ' ',
+	"tab": 0x09,
+}
+
+// decodeKeyName turns a key name like Control-u or Meta-Rubout into bytes
+func decodeKeyName(name string) ([]byte, error) {
+	var prefix []byte
+	control := false
+	for {
+		lower := strings.ToLower(name)
+		switch {
+		case strings.HasPrefix(lower, "control-"):
+			control, name = true, name[len("control-"):]
+		case strings.HasPrefix(lower, "c-"):
+			control, name = true, name[len("c-"):]
+		case strings.HasPrefix(lower, "meta-"):
+			prefix, name = []byte{0x1b}, name[len("meta-"):]
+		case strings.HasPrefix(lower, "m-"):
+			prefix, name = []byte{0x1b}, name[len("m-"):]
+		default:
+			var key byte
+			if b, ok := keyNameBytes[lower]; ok {
+				key = b
+			} else if len(name) == 1 {
+				key = name[0]
+			} else {
+				return nil, fmt.Errorf("unknown key name %q", name)
+			}
+			if control {
+				key = controlByte(key)
+			}
+			return append(prefix, key), nil
+		}
+	}
+}
+
+// escapeSequenceKeys are the escape sequences of special keys, without the
+// leading ESC, as bubbletea names them
+var escapeSequenceKeys = map[string]string{
+	"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+	"OA": "up", "OB": "down", "OC": "right", "OD": "left",
+	"[H": "home", "[F": "end", "OH": "home", "OF": "end",
+	"[1~": "home", "[7~": "home", "[4~": "end", "[8~": "end",
+	"[2~": "insert", "[3~": "delete", "[5~": "pgup", "[6~": "pgdown",
+	"[Z":    "shift+tab",
+	"[1;5A": "ctrl+up", "[1;5B": "ctrl+down", "[1;5C": "ctrl+right", "[1;5D": "ctrl+left",
+	"[1;3A": "alt+up", "[1;3B": "alt+down", "[1;3C": "alt+right", "[1;3D": "alt+left",
+	"[1;2A": "shift+up", "[1;2B": "shift+down", "[1;2C": "shift+right", "[1;2D": "shift+left",
+	"[3;3~": "alt+delete", "[3;5~": "ctrl+delete",
+}
+
+// keyName converts the bytes of a key sequence to the bubbletea name of the
+// key. Sequences of several keys, like readline's `\C-x\C-r`, have no single
+// name and are rejected.
+func keyName(keys []byte) (string, error) {
+	switch {
+	case len(keys) == 0:
+		return "", fmt.Errorf("empty key sequence")
+	case len(keys) == 1:
+		return byteKeyName(keys[0]), nil
+	case keys[0] != 0x1b:
+		return "", fmt.Errorf("multi-key sequences are not supported")
+	case len(keys) == 2:
+		return "alt+" + byteKeyName(keys[1]), nil
+	}
+	if name, ok := escapeSequenceKeys[string(keys[1:])]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("multi-key sequences are not supported")
+}
+
+// byteKeyName names a single byte the way bubbletea does
+func byteKeyName(b byte) string {
+	switch b {
+	case 0x00:
+		return "ctrl+@"
+	case 0x09:
+		return "tab"
+	case 0x0d:
+		return "enter"
+	case 0x1b:
+		return "esc"
+	case 0x1c:
+		return `ctrl+\`
+	case 0x1d:
+		return "ctrl+]"
+	case 0x1e:
+		return "ctrl+^"
+	case 0x1f:
+		return "ctrl+_"
+	case 0x7f:
+		return "backspace"
+	}
+	if b < 0x20 {
+		return "ctrl+" + string(rune('a'+b-1))
+	}
+	return string(rune(b))
+}
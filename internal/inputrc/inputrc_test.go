@@ -0,0 +1,116 @@
+package inputrc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseBindings(t *testing.T) {
+	config, err := Parse(strings.NewReader(`
+# comments and blank lines are skipped
+
+"\C-a": end-of-line
+"\M-f": forward-word
+"\ef": backward-word
+"\e[1;5C": forward-word
+"\C-?": backward-kill-word
+"\t": menu-complete
+Control-u: kill-line
+Meta-Rubout: backward-kill-word
+C-p: previous-history
+"\C-x\C-r": re-read-init-file
+"\C-o": "> output"
+"\C-t": transpose-chars
+"\C-y": yank # trailing comment
+`), zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, []Binding{
+		{Key: "ctrl+a", Function: "end-of-line"},
+		{Key: "alt+f", Function: "forward-word"},
+		{Key: "alt+f", Function: "backward-word"},
+		{Key: "ctrl+right", Function: "forward-word"},
+		{Key: "backspace", Function: "backward-kill-word"},
+		{Key: "tab", Function: "menu-complete"},
+		{Key: "ctrl+u", Function: "kill-line"},
+		{Key: "alt+backspace", Function: "backward-kill-word"},
+		{Key: "ctrl+p", Function: "previous-history"},
+		{Key: "ctrl+y", Function: "yank"},
+	}, config.Bindings)
+}
+
+func TestParseVariablesAndConditionals(t *testing.T) {
+	config, err := Parse(strings.NewReader(`
+set completion-ignore-case on
+set bell-style none
+$if mode=emacs
+"\C-b": forward-char
+$else
+"\C-b": backward-char
+$endif
+$if Bash
+"\C-f": backward-char
+$endif
+$if gsh
+  $if mode=vi
+  "\C-e": beginning-of-line
+  $endif
+"\C-n": next-history
+$endif
+`), zap.NewNop())
+	require.NoError(t, err)
+
+	assert.True(t, config.CompletionIgnoreCase())
+	assert.Equal(t, "emacs", config.EditingMode())
+	assert.Equal(t, "none", config.Variables["bell-style"])
+	assert.Equal(t, []Binding{
+		{Key: "ctrl+b", Function: "forward-char"},
+		{Key: "ctrl+n", Function: "next-history"},
+	}, config.Bindings)
+
+	config, err = Parse(strings.NewReader("set editing-mode vi\nset completion-ignore-case Off\n"), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, "vi", config.EditingMode())
+	assert.False(t, config.CompletionIgnoreCase())
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "common"), []byte(`"\C-a": end-of-line`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "inputrc"), []byte("$include common\nset completion-ignore-case on\n"), 0644))
+
+	config, err := Load(filepath.Join(dir, "inputrc"), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []Binding{{Key: "ctrl+a", Function: "end-of-line"}}, config.Bindings)
+	assert.True(t, config.CompletionIgnoreCase())
+
+	config, err = Load(filepath.Join(dir, "missing"), zap.NewNop())
+	require.NoError(t, err)
+	assert.Empty(t, config.Bindings)
+}
+
+func TestApply(t *testing.T) {
+	config, err := Parse(strings.NewReader(`
+"\C-a": end-of-line
+"\C-e": end-of-line
+"\C-w": backward-kill-word
+`), zap.NewNop())
+	require.NoError(t, err)
+
+	keyMap := shellinput.DefaultKeyMap
+	config.Apply(&keyMap)
+
+	assert.Equal(t, []string{"home"}, keyMap.LineStart.Keys())
+	assert.Equal(t, []string{"end", "ctrl+e", "ctrl+a"}, keyMap.LineEnd.Keys())
+	assert.Equal(t, []string{"alt+backspace", "ctrl+w"}, keyMap.DeleteWordBackward.Keys())
+
+	// The defaults are left alone
+	assert.Equal(t, []string{"home", "ctrl+a"}, shellinput.DefaultKeyMap.LineStart.Keys())
+}
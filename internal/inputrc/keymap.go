@@ -0,0 +1,86 @@
+package inputrc
+
+import (
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// readlineFunctions maps the readline functions gsh has an equivalent for to
+// the binding of that action
+var readlineFunctions = map[string]func(*shellinput.KeyMap) *key.Binding{
+	"forward-char":           func(km *shellinput.KeyMap) *key.Binding { return &km.CharacterForward },
+	"backward-char":          func(km *shellinput.KeyMap) *key.Binding { return &km.CharacterBackward },
+	"forward-word":           func(km *shellinput.KeyMap) *key.Binding { return &km.WordForward },
+	"backward-word":          func(km *shellinput.KeyMap) *key.Binding { return &km.WordBackward },
+	"beginning-of-line":      func(km *shellinput.KeyMap) *key.Binding { return &km.LineStart },
+	"end-of-line":            func(km *shellinput.KeyMap) *key.Binding { return &km.LineEnd },
+	"backward-delete-char":   func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteCharacterBackward },
+	"delete-char":            func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteCharacterForward },
+	"backward-kill-word":     func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteWordBackward },
+	"unix-word-rubout":       func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteWordBackward },
+	"kill-word":              func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteWordForward },
+	"kill-line":              func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteAfterCursor },
+	"backward-kill-line":     func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteBeforeCursor },
+	"unix-line-discard":      func(km *shellinput.KeyMap) *key.Binding { return &km.DeleteBeforeCursor },
+	"yank":                   func(km *shellinput.KeyMap) *key.Binding { return &km.Yank },
+	"yank-pop":               func(km *shellinput.KeyMap) *key.Binding { return &km.YankPop },
+	"complete":               func(km *shellinput.KeyMap) *key.Binding { return &km.Complete },
+	"menu-complete":          func(km *shellinput.KeyMap) *key.Binding { return &km.Complete },
+	"menu-complete-backward": func(km *shellinput.KeyMap) *key.Binding { return &km.PrevSuggestion },
+	"previous-history":       func(km *shellinput.KeyMap) *key.Binding { return &km.PrevValue },
+	"next-history":           func(km *shellinput.KeyMap) *key.Binding { return &km.NextValue },
+	"reverse-search-history": func(km *shellinput.KeyMap) *key.Binding { return &km.ReverseSearch },
+	"clear-screen":           func(km *shellinput.KeyMap) *key.Binding { return &km.ClearScreen },
+}
+
+// Apply adds the key bindings to keyMap. A bound key is taken away from the
+// other readline actions, so it only triggers the one it was bound to.
+func (c *Config) Apply(keyMap *shellinput.KeyMap) {
+	for _, binding := range c.Bindings {
+		target := readlineFunctions[binding.Function](keyMap)
+		for _, other := range readlineBindings(keyMap) {
+			if other != target {
+				removeKey(other, binding.Key)
+			}
+		}
+		if !hasKey(target, binding.Key) {
+			target.SetKeys(append(append([]string{}, target.Keys()...), binding.Key)...)
+		}
+	}
+}
+
+// readlineBindings returns each binding that a readline function maps to once
+func readlineBindings(keyMap *shellinput.KeyMap) []*key.Binding {
+	seen := make(map[*key.Binding]bool)
+	var bindings []*key.Binding
+	for _, function := range readlineFunctions {
+		binding := function(keyMap)
+		if !seen[binding] {
+			seen[binding] = true
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings
+}
+
+func hasKey(binding *key.Binding, k string) bool {
+	for _, existing := range binding.Keys() {
+		if existing == k {
+			return true
+		}
+	}
+	return false
+}
+
+func removeKey(binding *key.Binding, k string) {
+	if !hasKey(binding, k) {
+		return
+	}
+	keys := make([]string, 0, len(binding.Keys()))
+	for _, existing := range binding.Keys() {
+		if existing != k {
+			keys = append(keys, existing)
+		}
+	}
+	binding.SetKeys(keys...)
+}
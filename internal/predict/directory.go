@@ -0,0 +1,90 @@
+package predict
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/history"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// NextDirectoryPredictor predicts where a bare `cd ` is going, from the
+// directories usually gone to next from the current one. With repo-scoped
+// prediction, it stays inside the current git repository.
+type NextDirectoryPredictor struct {
+	runner         *interp.Runner
+	historyManager *history.HistoryManager
+	logger         *zap.Logger
+}
+
+func NewNextDirectoryPredictor(
+	runner *interp.Runner,
+	historyManager *history.HistoryManager,
+	logger *zap.Logger,
+) *NextDirectoryPredictor {
+	return &NextDirectoryPredictor{
+		runner:         runner,
+		historyManager: historyManager,
+		logger:         logger,
+	}
+}
+
+func (p *NextDirectoryPredictor) Predict(ctx context.Context, input string) (string, string, error) {
+	if strings.TrimLeft(input, " ") != "cd " {
+		return "", "", nil
+	}
+
+	pwd := environment.GetPwd(p.runner)
+	matches, err := p.historyManager.RankNextDirectories(pwd)
+	if err != nil {
+		p.logger.Debug("failed to query directory history", zap.Error(err))
+		return "", "", err
+	}
+
+	root := ""
+	if environment.GetPredictRepoScoped(p.runner) {
+		root = getRepoRoot(pwd)
+	}
+	for _, match := range matches {
+		if root != "" && !isUnder(match.Directory, root) {
+			continue
+		}
+		p.logger.Debug("predicted next directory", zap.String("directory", match.Directory), zap.Float64("score", match.Score))
+		return input + formatDirectory(match.Directory, pwd, environment.GetHomeDir(p.runner)), "", nil
+	}
+	return "", "", nil
+}
+
+func isUnder(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// formatDirectory shortens dir for the command line, relative to pwd or the
+// home directory when it's inside them
+func formatDirectory(dir string, pwd string, home string) string {
+	if isUnder(dir, pwd) && dir != pwd {
+		rel, _ := filepath.Rel(pwd, dir)
+		if quoted, err := syntax.Quote(rel, syntax.LangBash); err == nil {
+			return quoted
+		}
+		return rel
+	}
+	if home != "" && isUnder(dir, home) && dir != home {
+		rel, _ := filepath.Rel(home, dir)
+		// Quoting would stop ~ from expanding, so it's only used when the
+		// rest needs no quotes
+		if quoted, err := syntax.Quote(rel, syntax.LangBash); err == nil && quoted == rel {
+			return "~" + string(os.PathSeparator) + rel
+		}
+	}
+
+	if quoted, err := syntax.Quote(dir, syntax.LangBash); err == nil {
+		return quoted
+	}
+	return dir
+}
@@ -0,0 +1,97 @@
+package predict
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// newTestRunner returns a runner with the variables of script set
+func newTestRunner(t *testing.T, script string) *interp.Runner {
+	runner, err := interp.New()
+	require.NoError(t, err)
+	prog, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	require.NoError(t, err)
+	require.NoError(t, runner.Run(context.Background(), prog))
+	return runner
+}
+
+func newTestHistory(t *testing.T) *history.HistoryManager {
+	historyManager, err := history.NewHistoryManager(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = historyManager.Close() })
+	return historyManager
+}
+
+// stubRepoRoot makes every directory part of the repository at root
+func stubRepoRoot(t *testing.T, root string) {
+	original := getRepoRoot
+	getRepoRoot = func(string) string { return root }
+	t.Cleanup(func() { getRepoRoot = original })
+}
+
+func TestNextDirectoryPredictor(t *testing.T) {
+	home := t.TempDir()
+	project := filepath.Join(home, "project")
+	sub := filepath.Join(project, "sub")
+	docs := filepath.Join(home, "docs")
+	for _, dir := range []string{sub, docs} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+	}
+
+	historyManager := newTestHistory(t)
+	for _, dir := range []string{project, docs, project, sub, project, docs, project, docs} {
+		_, err := historyManager.StartCommand("ls", dir)
+		require.NoError(t, err)
+	}
+
+	predict := func(script string, input string) string {
+		runner := newTestRunner(t, "HOME="+home+"; "+script)
+		prediction, _, err := NewNextDirectoryPredictor(runner, historyManager, zap.NewNop()).Predict(context.Background(), input)
+		require.NoError(t, err)
+		return prediction
+	}
+
+	// docs is where project usually leads
+	assert.Equal(t, "cd ~/docs", predict("PWD="+project, "cd "))
+	assert.Equal(t, "  cd ~/docs", predict("PWD="+project, "  cd "))
+	assert.Empty(t, predict("PWD="+project, "cd d"))
+	assert.Empty(t, predict("PWD="+project, "ls "))
+
+	// Only the directories inside the repository with repo-scoped prediction
+	stubRepoRoot(t, project)
+	assert.Equal(t, "cd sub", predict("PWD="+project+"; GSH_PREDICT_REPO_SCOPED=1", "cd "))
+
+	// Nothing is known about where home leads
+	assert.Empty(t, predict("PWD="+home, "cd "))
+}
+
+func TestFormatDirectory(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		expected string
+	}{
+		{"below the working directory", "/home/user/project/src", "src"},
+		{"below home", "/home/user/docs", "~/docs"},
+		{"elsewhere", "/var/log", "/var/log"},
+		{"home itself", "/home/user", "/home/user"},
+		{"quoted", "/home/user/project/my dir", "'my dir'"},
+		{"quoted in full to keep ~ expanding", "/home/user/my docs", "'/home/user/my docs'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatDirectory(tt.dir, "/home/user/project", "/home/user"))
+		})
+	}
+}
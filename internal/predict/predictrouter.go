@@ -1,8 +1,15 @@
 package predict
 
-import "strings"
+import (
+	"context"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/pkg/gline"
+)
 
 type PredictRouter struct {
+	DirectoryPredictor *NextDirectoryPredictor
+	RepoPredictor      *RepoHistoryPredictor
 	PrefixPredictor    *LLMPrefixPredictor
 	NullStatePredictor *LLMNullStatePredictor
 }
@@ -17,10 +24,51 @@ func (p *PredictRouter) UpdateContext(context *map[string]string) {
 	}
 }
 
-func (p *PredictRouter) Predict(input string) (string, string, error) {
+func (p *PredictRouter) Predict(ctx context.Context, input string) (string, string, error) {
 	// Skip LLM prediction when input is blank (empty or whitespace only)
 	if strings.TrimSpace(input) == "" {
 		return "", "", nil
 	}
-	return p.PrefixPredictor.Predict(input)
+
+	if prediction, inputContext := p.predictFromHistory(ctx, input); prediction != "" {
+		return prediction, inputContext, nil
+	}
+
+	return p.PrefixPredictor.Predict(ctx, input)
+}
+
+// WarmUp warms up the model behind LLM predictions
+func (p *PredictRouter) WarmUp(ctx context.Context) error {
+	return p.PrefixPredictor.WarmUp(ctx)
+}
+
+// predictFromHistory tries the predictors that only look at history. The next
+// directory after `cd ` and the commands frequently run in the current
+// repository win over the LLM.
+func (p *PredictRouter) predictFromHistory(ctx context.Context, input string) (string, string) {
+	if p.DirectoryPredictor != nil {
+		if prediction, inputContext, err := p.DirectoryPredictor.Predict(ctx, input); err == nil && prediction != "" {
+			return prediction, inputContext
+		}
+	}
+	if p.RepoPredictor != nil {
+		if prediction, inputContext, err := p.RepoPredictor.Predict(ctx, input); err == nil && prediction != "" {
+			return prediction, inputContext
+		}
+	}
+	return "", ""
+}
+
+// PredictAndExplain routes like Predict. Predictions from history come
+// without an explanation, so they are explained separately.
+func (p *PredictRouter) PredictAndExplain(ctx context.Context, input string) (gline.PredictionResult, error) {
+	if strings.TrimSpace(input) == "" {
+		return gline.PredictionResult{}, nil
+	}
+
+	if prediction, inputContext := p.predictFromHistory(ctx, input); prediction != "" {
+		return gline.PredictionResult{Prediction: prediction, InputContext: inputContext}, nil
+	}
+
+	return p.PrefixPredictor.PredictAndExplain(ctx, input)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/atinylittleshell/gsh/internal/environment"
 	"github.com/atinylittleshell/gsh/internal/history"
 	"github.com/atinylittleshell/gsh/internal/utils"
+	"github.com/atinylittleshell/gsh/pkg/gline"
 	openai "github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
 	"mvdan.cc/sh/v3/interp"
@@ -48,17 +49,50 @@ func (p *LLMPrefixPredictor) UpdateContext(context *map[string]string) {
 	p.numHistoryContext = environment.GetContextNumHistoryConcise(p.runner, p.logger)
 }
 
-func (p *LLMPrefixPredictor) Predict(input string) (string, string, error) {
+func (p *LLMPrefixPredictor) Predict(ctx context.Context, input string) (string, string, error) {
+	result, err := p.predict(ctx, input, false)
+	return result.Prediction, result.InputContext, err
+}
+
+// PredictAndExplain predicts the command and explains it in the same request
+func (p *LLMPrefixPredictor) PredictAndExplain(ctx context.Context, input string) (gline.PredictionResult, error) {
+	return p.predict(ctx, input, true)
+}
+
+// WarmUp sends a trivial request to the model, so that the connection is open
+// and the model is loaded by the time the first prediction is needed
+func (p *LLMPrefixPredictor) WarmUp(ctx context.Context) error {
+	request := openai.ChatCompletionRequest{
+		Model: p.modelId,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    "user",
+				Content: "Reply with OK.",
+			},
+		},
+		MaxTokens: 1,
+	}
+	_, err := p.llmClient.CreateChatCompletion(ctx, request)
+	return err
+}
+
+func (p *LLMPrefixPredictor) predict(ctx context.Context, input string, explain bool) (gline.PredictionResult, error) {
 	if strings.HasPrefix(input, "#") {
 		// Don't do prediction for agent chat messages
 		p.logger.Debug("skipping prediction for agent chat message")
-		return "", "", nil
+		return gline.PredictionResult{}, nil
 	}
 
-	schema, err := PREDICTED_COMMAND_SCHEMA.MarshalJSON()
+	responseSchema := PREDICTED_COMMAND_SCHEMA
+	instructions := ""
+	if explain {
+		responseSchema = PREDICTED_AND_EXPLAINED_COMMAND_SCHEMA
+		instructions = "\n* Also give a concise explanation of what the predicted command will do for me"
+	}
+	schema, err := responseSchema.MarshalJSON()
 	if err != nil {
 		p.logger.Error("failed to marshal schema", zap.Error(err))
-		return "", "", err
+		return gline.PredictionResult{}, err
 	}
 
 	matchingHistoryEntries, err := p.historyManager.GetRecentEntriesByPrefix(
@@ -82,7 +116,7 @@ You are asked to predict what the complete bash command is.
 # Instructions
 * Based on the prefix and other context, analyze the my potential intent
 * Your prediction must start with the partial command as a prefix
-* Your prediction must be a valid, single-line, complete bash command
+* Your prediction must be a valid, single-line, complete bash command%s
 
 # Best Practices
 %s
@@ -97,6 +131,7 @@ You are asked to predict what the complete bash command is.
 %s
 
 <prefix>%s</prefix>`,
+		instructions,
 		BEST_PRACTICES,
 		p.contextText,
 		matchingHistoryContext.String(),
@@ -125,18 +160,22 @@ You are asked to predict what the complete bash command is.
 		request.Temperature = float32(*p.temperature)
 	}
 
-	chatCompletion, err := p.llmClient.CreateChatCompletion(context.TODO(), request)
+	chatCompletion, err := p.llmClient.CreateChatCompletion(ctx, request)
 
 	if err != nil {
 		p.logger.Error("LLM API call failed", zap.Error(err))
-		return "", "", err
+		return gline.PredictionResult{}, err
 	}
 
-	prediction := PredictedCommand{}
+	prediction := predictedAndExplainedCommand{}
 	err = json.Unmarshal([]byte(chatCompletion.Choices[0].Message.Content), &prediction)
 	if err != nil {
 		p.logger.Error("failed to unmarshal prediction", zap.Error(err), zap.String("content", chatCompletion.Choices[0].Message.Content))
 	}
 
-	return prediction.PredictedCommand, userMessage, nil
+	return gline.PredictionResult{
+		Prediction:   prediction.PredictedCommand,
+		Explanation:  prediction.Explanation,
+		InputContext: userMessage,
+	}, nil
 }
@@ -0,0 +1,93 @@
+package predict
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/git"
+	"github.com/atinylittleshell/gsh/internal/history"
+	"go.uber.org/zap"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// repoRootTimeout bounds the git lookup of the repository root
+const repoRootTimeout = 500 * time.Millisecond
+
+// getRepoRoot finds the git repository containing a directory, can be replaced in tests
+var getRepoRoot = func(dir string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), repoRootTimeout)
+	defer cancel()
+	return git.GetRepoRoot(ctx, dir)
+}
+
+// RepoHistoryPredictor predicts the command most often run with the same prefix
+// inside the current git repository, so `make` completes to the target usually
+// built in this repository rather than the one built elsewhere.
+type RepoHistoryPredictor struct {
+	runner         *interp.Runner
+	historyManager *history.HistoryManager
+	logger         *zap.Logger
+
+	mu        sync.Mutex
+	rootDir   string
+	rootCache string
+}
+
+func NewRepoHistoryPredictor(
+	runner *interp.Runner,
+	historyManager *history.HistoryManager,
+	logger *zap.Logger,
+) *RepoHistoryPredictor {
+	return &RepoHistoryPredictor{
+		runner:         runner,
+		historyManager: historyManager,
+		logger:         logger,
+	}
+}
+
+func (p *RepoHistoryPredictor) Predict(ctx context.Context, input string) (string, string, error) {
+	if strings.TrimSpace(input) == "" || strings.HasPrefix(input, "#") {
+		return "", "", nil
+	}
+	if !environment.GetPredictRepoScoped(p.runner) {
+		return "", "", nil
+	}
+
+	root := p.repoRoot(environment.GetPwd(p.runner))
+	if root == "" {
+		return "", "", nil
+	}
+
+	frequencies, err := p.historyManager.GetFrequentCommandsUnder(root, input, 1)
+	if err != nil {
+		p.logger.Debug("failed to query repository history", zap.Error(err))
+		return "", "", err
+	}
+	if len(frequencies) == 0 || frequencies[0].Command == input {
+		return "", "", nil
+	}
+
+	p.logger.Debug(
+		"predicted from repository history",
+		zap.String("repo", root),
+		zap.String("command", frequencies[0].Command),
+		zap.Int("count", frequencies[0].Count),
+	)
+	return frequencies[0].Command, "", nil
+}
+
+// repoRoot returns the repository root of dir, remembering it for as long as
+// the working directory doesn't change
+func (p *RepoHistoryPredictor) repoRoot(dir string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if dir != p.rootDir {
+		p.rootDir = dir
+		p.rootCache = getRepoRoot(dir)
+	}
+	return p.rootCache
+}
@@ -0,0 +1,54 @@
+package predict
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRepoHistoryPredictor(t *testing.T) {
+	historyManager := newTestHistory(t)
+	for _, command := range []struct {
+		command   string
+		directory string
+		exitCode  int
+	}{
+		{"make build", "/repo", 0},
+		{"make build", "/repo/cmd", 0},
+		{"make test", "/repo", 0},
+		{"make lint", "/repo", 2},
+		{"make lint", "/repo", 2},
+		{"make install", "/elsewhere", 0},
+		{"make install", "/elsewhere", 0},
+		{"make install", "/elsewhere", 0},
+	} {
+		entry, err := historyManager.StartCommand(command.command, command.directory)
+		require.NoError(t, err)
+		_, err = historyManager.FinishCommand(entry, command.exitCode)
+		require.NoError(t, err)
+	}
+	stubRepoRoot(t, "/repo")
+
+	predict := func(script string, input string) string {
+		runner := newTestRunner(t, script)
+		prediction, _, err := NewRepoHistoryPredictor(runner, historyManager, zap.NewNop()).Predict(context.Background(), input)
+		require.NoError(t, err)
+		return prediction
+	}
+
+	// The most frequent successful command in the repository
+	assert.Equal(t, "make build", predict("PWD=/repo/cmd; GSH_PREDICT_REPO_SCOPED=1", "make "))
+	assert.Equal(t, "make test", predict("PWD=/repo; GSH_PREDICT_REPO_SCOPED=1", "make t"))
+	assert.Empty(t, predict("PWD=/repo; GSH_PREDICT_REPO_SCOPED=1", "make build"))
+	assert.Empty(t, predict("PWD=/repo; GSH_PREDICT_REPO_SCOPED=1", "cargo "))
+
+	// Off unless repo-scoped prediction is on
+	assert.Empty(t, predict("PWD=/repo", "make "))
+
+	// Outside any repository
+	stubRepoRoot(t, "")
+	assert.Empty(t, predict("PWD=/tmp; GSH_PREDICT_REPO_SCOPED=1", "make "))
+}
@@ -16,6 +16,13 @@ type explainedCommand struct {
 
 var EXPLAINED_COMMAND_SCHEMA = utils.GenerateJsonSchema(explainedCommand{})
 
+type predictedAndExplainedCommand struct {
+	PredictedCommand string `json:"predicted_command" description:"The full bash command predicted by the model" required:"true"`
+	Explanation      string `json:"explanation" description:"A concise explanation of what the predicted command will do for me" required:"true"`
+}
+
+var PREDICTED_AND_EXPLAINED_COMMAND_SCHEMA = utils.GenerateJsonSchema(predictedAndExplainedCommand{})
+
 type CompletionCandidates struct {
 	Candidates []string `json:"candidates" description:"A list of valid completion candidates for the current incomplete command. The candidates should complete the current word or be full commands starting with the input prefix." required:"true"`
 }
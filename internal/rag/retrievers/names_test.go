@@ -0,0 +1,26 @@
+package retrievers
+
+import (
+	"testing"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"github.com/atinylittleshell/gsh/internal/rag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetrieverNamesAreKnownContextTypes(t *testing.T) {
+	var known []string
+	for _, contextType := range environment.ContextTypes {
+		known = append(known, contextType.Name)
+	}
+
+	for _, retriever := range []rag.ContextRetriever{
+		SystemInfoContextRetriever{},
+		WorkingDirectoryContextRetriever{},
+		GitStatusContextRetriever{},
+		ConciseHistoryContextRetriever{},
+		VerboseHistoryContextRetriever{},
+	} {
+		assert.Contains(t, known, retriever.Name())
+	}
+}
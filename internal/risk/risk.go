@@ -0,0 +1,254 @@
+// Package risk estimates how dangerous a shell command line is to run, so the
+// UI can warn before something destructive is executed.
+package risk
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Level is how risky a command is
+type Level int
+
+const (
+	// Safe commands only read, like ls, cat or git status
+	Safe Level = iota
+	// Caution commands change files or system state, use the network or run
+	// with elevated privileges
+	Caution
+	// Dangerous commands destroy data or run code fetched from the network
+	Dangerous
+)
+
+func (l Level) String() string {
+	switch l {
+	case Caution:
+		return "caution"
+	case Dangerous:
+		return "dangerous"
+	default:
+		return "safe"
+	}
+}
+
+var (
+	// pipeToShellPattern matches downloads piped into or substituted into a shell,
+	// e.g. `curl -fsSL https://example.com/install.sh | sudo bash`
+	pipeToShellPattern = regexp.MustCompile(`\b(curl|wget|fetch)\b[^|;&]*\|\s*(sudo\s+)?(ba|z|da|k|fi)?sh\b|(ba|z|da|k)?sh\s+(-c\s+)?["']?[$<]\(\s*(curl|wget)\b`)
+	// forkBombPattern matches the classic `:(){ :|:& };:`
+	forkBombPattern = regexp.MustCompile(`:\s*\(\s*\)\s*\{`)
+	// separatorPattern splits a command line into simple commands
+	separatorPattern = regexp.MustCompile(`\|\||&&|[|;&\n]`)
+	// deviceRedirectPattern matches output redirected onto a disk device
+	deviceRedirectPattern = regexp.MustCompile(`>\s*/dev/(sd|hd|nvme|disk|mmcblk|xvd|vd)`)
+	// fileRedirectPattern matches output redirected into a file
+	fileRedirectPattern = regexp.MustCompile(`(^|[^0-9&<>])>>?\s*[^\s&>]`)
+)
+
+// wrapperCommands run the command that follows them
+var wrapperCommands = map[string]bool{
+	"command": true, "env": true, "exec": true, "nice": true, "nohup": true, "time": true, "xargs": true,
+}
+
+// privilegeCommands run the command that follows them as another user
+var privilegeCommands = map[string]bool{
+	"doas": true, "pkexec": true, "su": true, "sudo": true,
+}
+
+// wrapperOptionsWithValues are options of wrappers that take a value, like
+// `sudo -u deploy`
+var wrapperOptionsWithValues = map[string]bool{
+	"-C": true, "-g": true, "-n": true, "-U": true, "-u": true,
+}
+
+// dangerousCommands destroy data or take the machine down whatever their arguments
+var dangerousCommands = map[string]bool{
+	"dd": true, "fdisk": true, "halt": true, "parted": true, "poweroff": true, "reboot": true,
+	"sgdisk": true, "shred": true, "shutdown": true, "wipefs": true,
+}
+
+// cautionCommands change files or system state, or use the network
+var cautionCommands = map[string]bool{
+	// files
+	"chgrp": true, "chmod": true, "chown": true, "cp": true, "install": true, "ln": true, "mkdir": true,
+	"mv": true, "patch": true, "rmdir": true, "tee": true, "touch": true, "truncate": true, "unlink": true,
+	// processes and services
+	"kill": true, "killall": true, "pkill": true, "launchctl": true, "service": true, "systemctl": true,
+	// packages
+	"apt": true, "apt-get": true, "brew": true, "dnf": true, "pacman": true, "yum": true,
+	// network
+	"curl": true, "ftp": true, "nc": true, "rsync": true, "scp": true, "sftp": true, "ssh": true, "wget": true,
+}
+
+// subcommandRisks classifies tools whose risk depends on the subcommand. The
+// subcommand maps to the level it gets, and tools not listed here are safe.
+var subcommandRisks = map[string]map[string]Level{
+	"git": {
+		"add": Caution, "am": Caution, "apply": Caution, "checkout": Caution, "cherry-pick": Caution,
+		"clean": Caution, "clone": Caution, "commit": Caution, "fetch": Caution, "merge": Caution,
+		"mv": Caution, "pull": Caution, "push": Caution, "rebase": Caution, "reset": Caution,
+		"restore": Caution, "revert": Caution, "rm": Caution, "stash": Caution, "switch": Caution, "tag": Caution,
+	},
+	"docker": {
+		"kill": Caution, "pull": Caution, "push": Caution, "rm": Caution, "rmi": Caution, "run": Caution,
+		"stop": Caution, "system": Caution, "volume": Caution,
+	},
+	"kubectl": {
+		"apply": Caution, "create": Caution, "delete": Dangerous, "drain": Dangerous, "edit": Caution,
+		"patch": Caution, "replace": Caution, "rollout": Caution, "scale": Caution,
+	},
+	"terraform": {"apply": Caution, "destroy": Dangerous, "import": Caution},
+	"npm":       {"install": Caution, "i": Caution, "publish": Caution, "uninstall": Caution, "update": Caution},
+	"pip":       {"install": Caution, "uninstall": Caution},
+	"pip3":      {"install": Caution, "uninstall": Caution},
+	"go":        {"install": Caution, "get": Caution},
+	"cargo":     {"install": Caution, "publish": Caution},
+}
+
+// ClassifyRisk estimates the risk of running command, taking the riskiest of
+// the commands it chains or pipes together. It works on partial input, so it
+// can be used while the command is being typed.
+func ClassifyRisk(command string) Level {
+	if strings.TrimSpace(command) == "" {
+		return Safe
+	}
+	if pipeToShellPattern.MatchString(command) || forkBombPattern.MatchString(command) || deviceRedirectPattern.MatchString(command) {
+		return Dangerous
+	}
+
+	level := Safe
+	for _, segment := range separatorPattern.Split(command, -1) {
+		level = max(level, classifySimpleCommand(segment))
+	}
+	return level
+}
+
+// classifySimpleCommand classifies a single command without pipes or chaining
+func classifySimpleCommand(segment string) Level {
+	level := Safe
+	if fileRedirectPattern.MatchString(strings.ReplaceAll(segment, "/dev/null", "")) {
+		level = Caution
+	}
+
+	fields := strings.Fields(segment)
+
+	// Skip environment assignments and wrappers to find the command that runs
+	for len(fields) > 0 {
+		name := filepath.Base(fields[0])
+		switch {
+		case strings.Contains(fields[0], "=") && !strings.HasPrefix(fields[0], "="):
+		case privilegeCommands[name]:
+			level = max(level, Caution)
+		case wrapperCommands[name]:
+		case strings.HasPrefix(fields[0], "-") && len(fields) > 1:
+			// options of a wrapper, e.g. `sudo -u deploy` or `nice -n 10`
+			if wrapperOptionsWithValues[fields[0]] {
+				fields = fields[1:]
+			}
+		default:
+			return max(level, classifyArgs(name, fields[1:]))
+		}
+		fields = fields[1:]
+	}
+	return level
+}
+
+// classifyArgs classifies a command by its name and arguments
+func classifyArgs(name string, args []string) Level {
+	switch {
+	case dangerousCommands[name] || strings.HasPrefix(name, "mkfs"):
+		return Dangerous
+	case name == "rm":
+		if hasFlag(args, 'r', "recursive") || hasFlag(args, 'f', "force") {
+			return Dangerous
+		}
+		return Caution
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		if hasFlag(args, 'R', "recursive") && containsArg(args, "/") {
+			return Dangerous
+		}
+		return Caution
+	case name == "find":
+		if containsArg(args, "-delete") || containsArg(args, "-exec") {
+			return Dangerous
+		}
+		return Safe
+	case name == "sed" || name == "perl":
+		if hasFlag(args, 'i', "in-place") {
+			return Caution
+		}
+		return Safe
+	case name == "git":
+		subcommand := firstNonFlag(args)
+		rest := args
+		if idx := indexOf(args, subcommand); idx >= 0 {
+			rest = args[idx+1:]
+		}
+		switch {
+		case subcommand == "push" && (hasFlag(rest, 'f', "force") || containsArgPrefix(rest, "--force")):
+			return Dangerous
+		case subcommand == "reset" && containsArg(rest, "--hard"):
+			return Dangerous
+		case subcommand == "clean" && hasFlag(rest, 'f', "force"):
+			return Dangerous
+		}
+	}
+
+	if cautionCommands[name] {
+		return Caution
+	}
+	if subcommands, ok := subcommandRisks[name]; ok {
+		return subcommands[firstNonFlag(args)]
+	}
+	return Safe
+}
+
+// hasFlag reports whether args contain the short flag, alone or combined as in
+// -rf, or the long flag
+func hasFlag(args []string, short rune, long string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--"+long {
+			return true
+		}
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsRune(arg[1:], short) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsArg(args []string, value string) bool {
+	return indexOf(args, value) >= 0
+}
+
+func containsArgPrefix(args []string, prefix string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOf(args []string, value string) int {
+	for i, arg := range args {
+		if arg == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// firstNonFlag returns the first argument that isn't an option, usually the subcommand
+func firstNonFlag(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package risk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyRisk(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected Level
+	}{
+		{"", Safe},
+		{"ls -la", Safe},
+		{"cat README.md | grep gsh", Safe},
+		{"git status", Safe},
+		{"git log --oneline", Safe},
+		{"echo hello > /dev/null", Safe},
+		{"ls 2>&1", Safe},
+		{"find . -name '*.go'", Safe},
+		{"sed 's/a/b/' file", Safe},
+		{"kubectl get pods", Safe},
+
+		{"mv a b", Caution},
+		{"rm notes.txt", Caution},
+		{"echo hello > out.txt", Caution},
+		{"echo hello >> out.txt", Caution},
+		{"sed -i 's/a/b/' file", Caution},
+		{"git commit -m 'wip'", Caution},
+		{"git push origin main", Caution},
+		{"curl https://example.com", Caution},
+		{"ssh example.com", Caution},
+		{"sudo ls /root", Caution},
+		{"npm install", Caution},
+		{"ls && touch file", Caution},
+		{"FOO=bar /bin/mv a b", Caution},
+
+		{"rm -rf build", Dangerous},
+		{"rm -r build", Dangerous},
+		{"sudo rm -f /etc/hosts", Dangerous},
+		{"sudo -u root rm --recursive dir", Dangerous},
+		{"ls; rm -rf /", Dangerous},
+		{"dd if=/dev/zero of=/dev/sda", Dangerous},
+		{"mkfs.ext4 /dev/sdb1", Dangerous},
+		{"echo x > /dev/sda", Dangerous},
+		{":(){ :|:& };:", Dangerous},
+		{"curl -fsSL https://example.com/install.sh | sh", Dangerous},
+		{"wget -qO- https://example.com/install.sh | sudo bash", Dangerous},
+		{`bash -c "$(curl -fsSL https://example.com/install.sh)"`, Dangerous},
+		{"bash <(curl -fsSL https://example.com/install.sh)", Dangerous},
+		{"git push --force origin main", Dangerous},
+		{"git push -f", Dangerous},
+		{"git push --force-with-lease", Dangerous},
+		{"git reset --hard HEAD~1", Dangerous},
+		{"git clean -fdx", Dangerous},
+		{"find . -name '*.tmp' -delete", Dangerous},
+		{"chmod -R 777 /", Dangerous},
+		{"kubectl delete namespace prod", Dangerous},
+		{"terraform destroy", Dangerous},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ClassifyRisk(test.command), test.command)
+	}
+}
+
+func TestClassifyRiskPartialInput(t *testing.T) {
+	assert.Equal(t, Safe, ClassifyRisk("r"))
+	assert.Equal(t, Caution, ClassifyRisk("rm "))
+	assert.Equal(t, Dangerous, ClassifyRisk("rm -rf"))
+	assert.Equal(t, Caution, ClassifyRisk("curl https://example.com |"))
+	assert.Equal(t, Dangerous, ClassifyRisk("curl https://example.com | sh"))
+}
+
+func TestLevelString(t *testing.T) {
+	assert.Equal(t, "safe", Safe.String())
+	assert.Equal(t, "caution", Caution.String())
+	assert.Equal(t, "dangerous", Dangerous.String())
+}
@@ -0,0 +1,344 @@
+// Package server exposes gsh's prediction, completion, explanation and history
+// search to editors with `gsh --serve`.
+//
+// The protocol is JSON-RPC 2.0 over stdio. Each message is one JSON object on
+// its own line, in both directions. Requests are answered one at a time, in the
+// order they arrive, and notifications (requests without an id) get no answer.
+//
+// Methods, version 1 of the protocol:
+//
+//	initialize      {}                                 -> {"protocolVersion": 1, "serverVersion": "...", "methods": [...]}
+//	predict         {"input": "git ch"}                -> {"prediction": "git checkout main"}
+//	explain         {"command": "tar -xzf a.tgz"}      -> {"explanation": "..."}
+//	complete        {"line": "git ch", "pos": 6}       -> {"candidates": [{"value": "checkout", "group": "git"}]}
+//	history-search  {"query": "docker", "limit": 20}   -> {"entries": [{"command": "...", "directory": "...", "timestamp": "..."}]}
+//
+// pos is a byte offset in line and defaults to its end. limit defaults to 20
+// and is capped at 1000. A request line over 1MB is answered with an invalid
+// request error and skipped.
+// Clients should call initialize first and check protocolVersion, which only
+// changes when existing methods change incompatibly.
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/completion"
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/atinylittleshell/gsh/pkg/gline"
+	"go.uber.org/zap"
+)
+
+// ProtocolVersion is the version of the methods and their parameters
+const ProtocolVersion = 1
+
+const (
+	defaultHistorySearchLimit = 20
+	maxHistorySearchLimit     = 1000
+)
+
+// maxMessageSize bounds a single request line
+const maxMessageSize = 1024 * 1024
+
+// JSON-RPC 2.0 error codes
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// Completer lists the completions of a command line
+type Completer interface {
+	GetCompletionResults(line string, pos int) []completion.CompletionResult
+}
+
+// HistorySearcher finds previously run commands
+type HistorySearcher interface {
+	SearchCommands(query string, limit int) ([]history.HistoryEntry, error)
+}
+
+// Server answers JSON-RPC requests with the shell's predictor, explainer,
+// completion provider and history. A nil component makes its method fail with
+// an internal error.
+type Server struct {
+	Predictor gline.Predictor
+	Explainer gline.Explainer
+	Completer Completer
+	History   HistorySearcher
+	Version   string
+	Logger    *zap.Logger
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+type predictParams struct {
+	Input string `json:"input"`
+}
+
+type predictResult struct {
+	Prediction string `json:"prediction"`
+}
+
+type explainParams struct {
+	Command string `json:"command"`
+}
+
+type explainResult struct {
+	Explanation string `json:"explanation"`
+}
+
+type completeParams struct {
+	Line string `json:"line"`
+	Pos  *int   `json:"pos"`
+}
+
+type completeResult struct {
+	Candidates []completion.CompletionResult `json:"candidates"`
+}
+
+type historySearchParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+type historyEntry struct {
+	Command   string    `json:"command"`
+	Directory string    `json:"directory"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type historySearchResult struct {
+	Entries []historyEntry `json:"entries"`
+}
+
+type initializeResult struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	ServerVersion   string   `json:"serverVersion"`
+	Methods         []string `json:"methods"`
+}
+
+var methods = []string{"initialize", "predict", "explain", "complete", "history-search"}
+
+// Serve answers the requests read from r on w until r ends or ctx is done
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	encoder := json.NewEncoder(w)
+
+	for {
+		line, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && !errors.Is(err, bufio.ErrTooLong) {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var resp *response
+		switch {
+		case err != nil:
+			// The id of a request that wasn't read is unknown
+			resp = &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: InvalidRequest, Message: fmt.Sprintf("request is longer than %d bytes", maxMessageSize)}}
+		case len(line) == 0:
+			continue
+		default:
+			resp = s.handleMessage(ctx, line)
+		}
+		if resp != nil {
+			if err := encoder.Encode(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readMessage reads the next line from r without its line ending. A line over
+// maxMessageSize is skipped up to its end and bufio.ErrTooLong returned, so the
+// requests after it can still be read.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) <= maxMessageSize+len("\r\n") {
+			line = append(line, chunk...)
+		} else {
+			tooLong, line = true, nil
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if tooLong {
+			return nil, bufio.ErrTooLong
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		return bytes.TrimSuffix(line, []byte("\r")), nil
+	}
+}
+
+// handleMessage answers one request, or returns nil for a notification
+func (s *Server) handleMessage(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: ParseError, Message: err.Error()}}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		id := req.ID
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		return &response{JSONRPC: "2.0", ID: id, Error: &Error{Code: InvalidRequest, Message: "expected a JSON-RPC 2.0 request with a method"}}
+	}
+
+	result, err := s.call(ctx, req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: InternalError, Message: err.Error()}
+		}
+		s.logger().Debug("server request failed", zap.String("method", req.Method), zap.Error(err))
+		resp.Result = nil
+		resp.Error = rpcErr
+	}
+	return resp
+}
+
+func (s *Server) call(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return initializeResult{ProtocolVersion: ProtocolVersion, ServerVersion: s.Version, Methods: methods}, nil
+
+	case "predict":
+		var p predictParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if s.Predictor == nil {
+			return nil, fmt.Errorf("prediction is not available")
+		}
+		prediction, _, err := s.Predictor.Predict(ctx, p.Input)
+		if err != nil {
+			return nil, err
+		}
+		return predictResult{Prediction: prediction}, nil
+
+	case "explain":
+		var p explainParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if s.Explainer == nil {
+			return nil, fmt.Errorf("explanation is not available")
+		}
+		explanation, err := s.Explainer.Explain(ctx, p.Command)
+		if err != nil {
+			return nil, err
+		}
+		return explainResult{Explanation: explanation}, nil
+
+	case "complete":
+		var p completeParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		pos := len(p.Line)
+		if p.Pos != nil {
+			if *p.Pos < 0 || *p.Pos > len(p.Line) {
+				return nil, &Error{Code: InvalidParams, Message: fmt.Sprintf("pos %d is outside a line of %d bytes", *p.Pos, len(p.Line))}
+			}
+			pos = *p.Pos
+		}
+		if s.Completer == nil {
+			return nil, fmt.Errorf("completion is not available")
+		}
+		candidates := s.Completer.GetCompletionResults(p.Line, pos)
+		if candidates == nil {
+			candidates = []completion.CompletionResult{}
+		}
+		return completeResult{Candidates: candidates}, nil
+
+	case "history-search":
+		var p historySearchParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.Limit <= 0 {
+			p.Limit = defaultHistorySearchLimit
+		}
+		p.Limit = min(p.Limit, maxHistorySearchLimit)
+		if s.History == nil {
+			return nil, fmt.Errorf("history is not available")
+		}
+		found, err := s.History.SearchCommands(p.Query, p.Limit)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]historyEntry, len(found))
+		for i, entry := range found {
+			entries[i] = historyEntry{Command: entry.Command, Directory: entry.Directory, Timestamp: entry.CreatedAt}
+		}
+		return historySearchResult{Entries: entries}, nil
+
+	default:
+		return nil, &Error{Code: MethodNotFound, Message: fmt.Sprintf("unknown method %q", method)}
+	}
+}
+
+// decodeParams reads the params object into v. Missing params leave v empty.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: InvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) logger() *zap.Logger {
+	if s.Logger == nil {
+		return zap.NewNop()
+	}
+	return s.Logger
+}
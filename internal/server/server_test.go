@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atinylittleshell/gsh/internal/completion"
+	"github.com/atinylittleshell/gsh/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePredictor struct{}
+
+func (fakePredictor) Predict(ctx context.Context, input string) (string, string, error) {
+	if input == "fail" {
+		return "", "", errors.New("model unavailable")
+	}
+	return input + "eckout main", "", nil
+}
+
+type fakeExplainer struct{}
+
+func (fakeExplainer) Explain(ctx context.Context, input string) (string, error) {
+	return "Explains " + input, nil
+}
+
+type fakeCompleter struct {
+	line string
+	pos  int
+}
+
+func (c *fakeCompleter) GetCompletionResults(line string, pos int) []completion.CompletionResult {
+	c.line, c.pos = line, pos
+	return []completion.CompletionResult{{Value: "checkout", Group: "git"}}
+}
+
+type fakeHistory struct{}
+
+func (fakeHistory) SearchCommands(query string, limit int) ([]history.HistoryEntry, error) {
+	entries := []history.HistoryEntry{
+		{Command: "docker ps", Directory: "/src", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Command: "docker build .", Directory: "/src"},
+	}
+	return entries[:min(limit, len(entries))], nil
+}
+
+func serve(t *testing.T, s *Server, requests ...string) []map[string]any {
+	var out bytes.Buffer
+	err := s.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &out)
+	require.NoError(t, err)
+
+	var responses []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &resp))
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func newTestServer() (*Server, *fakeCompleter) {
+	completer := &fakeCompleter{}
+	return &Server{
+		Predictor: fakePredictor{},
+		Explainer: fakeExplainer{},
+		Completer: completer,
+		History:   fakeHistory{},
+		Version:   "1.2.3",
+	}, completer
+}
+
+func TestServeMethods(t *testing.T) {
+	s, completer := newTestServer()
+
+	responses := serve(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"predict","params":{"input":"git ch"}}`,
+		`{"jsonrpc":"2.0","id":"three","method":"explain","params":{"command":"ls -la"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"complete","params":{"line":"git ch main","pos":6}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"history-search","params":{"query":"docker","limit":1}}`,
+	)
+	require.Len(t, responses, 5)
+
+	assert.Equal(t, map[string]any{
+		"protocolVersion": float64(ProtocolVersion),
+		"serverVersion":   "1.2.3",
+		"methods":         []any{"initialize", "predict", "explain", "complete", "history-search"},
+	}, responses[0]["result"])
+
+	assert.Equal(t, float64(2), responses[1]["id"])
+	assert.Equal(t, map[string]any{"prediction": "git checkout main"}, responses[1]["result"])
+
+	assert.Equal(t, "three", responses[2]["id"])
+	assert.Equal(t, map[string]any{"explanation": "Explains ls -la"}, responses[2]["result"])
+
+	assert.Equal(t, map[string]any{
+		"candidates": []any{map[string]any{"value": "checkout", "group": "git"}},
+	}, responses[3]["result"])
+	assert.Equal(t, "git ch main", completer.line)
+	assert.Equal(t, 6, completer.pos)
+
+	assert.Equal(t, map[string]any{
+		"entries": []any{map[string]any{"command": "docker ps", "directory": "/src", "timestamp": "2024-01-02T03:04:05Z"}},
+	}, responses[4]["result"])
+}
+
+func TestServeCompletePosDefaultsToEndOfLine(t *testing.T) {
+	s, completer := newTestServer()
+
+	serve(t, s, `{"jsonrpc":"2.0","id":1,"method":"complete","params":{"line":"git ch"}}`)
+	assert.Equal(t, 6, completer.pos)
+}
+
+func TestServeErrors(t *testing.T) {
+	s, _ := newTestServer()
+	s.Explainer = nil
+
+	responses := serve(t, s,
+		`not json`,
+		`{"id":1,"method":"predict"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"run"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"complete","params":{"line":"ls","pos":9}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"predict","params":{"input":"fail"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"explain","params":{"command":"ls"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"predict","params":"git"}`,
+	)
+	require.Len(t, responses, 7)
+
+	codes := make([]float64, len(responses))
+	for i, resp := range responses {
+		assert.Nil(t, resp["result"])
+		codes[i] = resp["error"].(map[string]any)["code"].(float64)
+	}
+	assert.Equal(t, []float64{ParseError, InvalidRequest, MethodNotFound, InvalidParams, InternalError, InternalError, InvalidParams}, codes)
+	assert.Nil(t, responses[0]["id"])
+	assert.Equal(t, "model unavailable", responses[4]["error"].(map[string]any)["message"])
+}
+
+func TestServeIgnoresNotifications(t *testing.T) {
+	s, _ := newTestServer()
+
+	responses := serve(t, s,
+		`{"jsonrpc":"2.0","method":"predict","params":{"input":"git ch"}}`,
+		``,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+	)
+	require.Len(t, responses, 1)
+	assert.Equal(t, float64(1), responses[0]["id"])
+}
+
+func TestServeSkipsOversizedRequests(t *testing.T) {
+	s, _ := newTestServer()
+
+	responses := serve(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"predict","params":{"input":"`+strings.Repeat("a", maxMessageSize)+`"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"predict","params":{"input":"git ch"}}`,
+	)
+	require.Len(t, responses, 2)
+
+	// The oversized request gets an error and the connection keeps going
+	assert.Nil(t, responses[0]["id"])
+	assert.Equal(t, float64(InvalidRequest), responses[0]["error"].(map[string]any)["code"])
+	assert.Equal(t, float64(2), responses[1]["id"])
+	assert.Equal(t, map[string]any{"prediction": "git checkout main"}, responses[1]["result"])
+}
+
+type limitHistory struct {
+	limit int
+}
+
+func (h *limitHistory) SearchCommands(query string, limit int) ([]history.HistoryEntry, error) {
+	h.limit = limit
+	return nil, nil
+}
+
+func TestServeCapsHistorySearchLimit(t *testing.T) {
+	s, _ := newTestServer()
+	searcher := &limitHistory{}
+	s.History = searcher
+
+	serve(t, s, `{"jsonrpc":"2.0","id":1,"method":"history-search","params":{"query":"docker","limit":100000000}}`)
+	assert.Equal(t, maxHistorySearchLimit, searcher.limit)
+
+	serve(t, s, `{"jsonrpc":"2.0","id":1,"method":"history-search","params":{"query":"docker"}}`)
+	assert.Equal(t, defaultHistorySearchLimit, searcher.limit)
+}
@@ -19,6 +19,17 @@ var (
 			Foreground(stdout.Color("12")).
 			String()
 	}
+	DIFF_ADDED = func(s string) string {
+		return stdout.String(s).
+			Foreground(stdout.Color("10")).
+			String()
+	}
+	DIFF_REMOVED = func(s string) string {
+		return stdout.String(s).
+			Foreground(stdout.Color("9")).
+			CrossOut().
+			String()
+	}
 	AGENT_QUESTION = func(s string) string {
 		return stdout.String(s).
 			Foreground(stdout.Color("11")).
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/muesli/termenv"
+	"golang.org/x/term"
 )
 
 // FeatureSupport indicates the level of support for a terminal feature.
@@ -50,6 +51,7 @@ type Capabilities struct {
 	IsTmux   bool
 	IsScreen bool
 	IsDumb   bool
+	IsTTY    bool // Whether output goes to a terminal rather than a pipe or file
 }
 
 // Terminal provides safe terminal operations with automatic capability detection.
@@ -73,9 +75,14 @@ func New() *Terminal {
 
 // NewWithOutput creates a new Terminal with the specified termenv output.
 func NewWithOutput(output *termenv.Output) *Terminal {
+	isTTY := false
+	if tty := output.TTY(); tty != nil {
+		isTTY = term.IsTerminal(int(tty.Fd()))
+	}
+
 	return &Terminal{
 		output:       output,
-		capabilities: detectCapabilities(),
+		capabilities: detectCapabilities(isTTY),
 	}
 }
 
@@ -91,7 +98,7 @@ func (t *Terminal) SupportsWindowTitle() bool {
 }
 
 // detectCapabilities detects terminal capabilities based on environment variables.
-func detectCapabilities() Capabilities {
+func detectCapabilities(isTTY bool) Capabilities {
 	term := os.Getenv("TERM")
 	termProgram := os.Getenv("TERM_PROGRAM")
 
@@ -102,6 +109,7 @@ func detectCapabilities() Capabilities {
 		IsTmux:      os.Getenv("TMUX") != "",
 		IsScreen:    os.Getenv("STY") != "",
 		IsDumb:      term == "dumb" || term == "",
+		IsTTY:       isTTY,
 	}
 
 	// Detect window title support
@@ -112,7 +120,8 @@ func detectCapabilities() Capabilities {
 
 // detectWindowTitleSupport determines if the terminal supports window title setting.
 func detectWindowTitleSupport(caps Capabilities) FeatureSupport {
-	if caps.IsDumb {
+	// Escape sequences would end up as garbage in redirected output
+	if caps.IsDumb || !caps.IsTTY {
 		return FeatureUnsupported
 	}
 
@@ -0,0 +1,92 @@
+package termtitle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atinylittleshell/gsh/internal/environment"
+	"go.uber.org/zap"
+)
+
+// TitleVars holds the values available to GSH_SET_TITLE placeholders.
+type TitleVars struct {
+	Command string // {cmd}: the running command, empty at the prompt
+	Cwd     string // {cwd}: the working directory, with $HOME shortened to ~
+	User    string // {user}
+	Host    string // {host}
+}
+
+// FormatTitle expands the {cmd}, {cwd}, {dir}, {user} and {host} placeholders in
+// format. At the prompt {cmd} is empty, so separators left dangling around it
+// are trimmed, letting a single format like "{cmd} - {cwd}" serve both cases.
+func FormatTitle(format string, vars TitleVars) string {
+	command := vars.Command
+	if idx := strings.IndexByte(command, '\n'); idx >= 0 {
+		command = command[:idx] + " ..."
+	}
+
+	dir := filepath.Base(vars.Cwd)
+	if vars.Cwd == "~" {
+		dir = "~"
+	}
+
+	title := strings.NewReplacer(
+		"{cmd}", strings.TrimSpace(command),
+		"{cwd}", vars.Cwd,
+		"{dir}", dir,
+		"{user}", vars.User,
+		"{host}", vars.Host,
+	).Replace(format)
+
+	if command == "" {
+		title = strings.Trim(title, " -:|")
+	}
+	return title
+}
+
+// SetFormattedTitle sets the window title from the GSH_SET_TITLE format, using
+// command while it runs or the prompt context when command is empty. It reports
+// whether a format is configured.
+func (m *Manager) SetFormattedTitle(command string) bool {
+	format := environment.GetSetTitleFormat(m.runner)
+	if format == "" {
+		return false
+	}
+
+	if !m.terminal.SupportsWindowTitle() {
+		return true
+	}
+
+	host, _ := os.Hostname()
+	title := FormatTitle(format, TitleVars{
+		Command: command,
+		Cwd:     shortenHome(environment.GetPwd(m.runner)),
+		User:    environment.GetUser(m.runner),
+		Host:    host,
+	})
+
+	m.mu.Lock()
+	m.currentTitle = title
+	m.mu.Unlock()
+
+	result := m.terminal.SetWindowTitle(title)
+	if !result.Success && result.Error != nil {
+		m.logger.Debug("failed to set window title", zap.Error(result.Error))
+	}
+	return true
+}
+
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if strings.HasPrefix(path, home+string(os.PathSeparator)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
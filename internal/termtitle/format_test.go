@@ -0,0 +1,53 @@
+package termtitle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		vars     TitleVars
+		expected string
+	}{
+		{
+			name:     "running command",
+			format:   "{cmd} - {cwd}",
+			vars:     TitleVars{Command: "make test", Cwd: "~/src/gsh"},
+			expected: "make test - ~/src/gsh",
+		},
+		{
+			name:     "prompt trims dangling separator",
+			format:   "{cmd} - {cwd}",
+			vars:     TitleVars{Cwd: "~/src/gsh"},
+			expected: "~/src/gsh",
+		},
+		{
+			name:     "directory name and host",
+			format:   "{user}@{host}: {dir}",
+			vars:     TitleVars{Cwd: "/var/log", User: "alice", Host: "box"},
+			expected: "alice@box: log",
+		},
+		{
+			name:     "home directory",
+			format:   "{dir}",
+			vars:     TitleVars{Cwd: "~"},
+			expected: "~",
+		},
+		{
+			name:     "multiline command keeps first line",
+			format:   "{cmd}",
+			vars:     TitleVars{Command: "for f in *; do\n  echo $f\ndone"},
+			expected: "for f in *; do ...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatTitle(tt.format, tt.vars))
+		})
+	}
+}
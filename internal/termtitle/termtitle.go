@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/atinylittleshell/gsh/internal/environment"
 	"github.com/atinylittleshell/gsh/internal/termfeatures"
 	"github.com/atinylittleshell/gsh/internal/utils"
 	openai "github.com/sashabaranov/go-openai"
@@ -63,6 +64,11 @@ func (m *Manager) RecordCommand(command string) {
 		return
 	}
 
+	// A GSH_SET_TITLE format takes over the title, so don't summarize commands
+	if environment.GetSetTitleFormat(m.runner) != "" {
+		return
+	}
+
 	// Add to sliding window
 	m.commandWindow = append(m.commandWindow, command)
 	if len(m.commandWindow) > MaxWindowSize {
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// durationUnits are the units ParseDuration adds to time.ParseDuration. A
+// year is 365 days.
+var durationUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// ParseDuration parses a positive duration such as 90d, 6w, 1y or 12h, with
+// days, weeks and years on top of what time.ParseDuration accepts
+func ParseDuration(value string) (time.Duration, error) {
+	var duration time.Duration
+	if unit, ok := durationUnits[value[max(len(value)-1, 0):]]; ok {
+		count, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		duration = time.Duration(count) * unit
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		duration = parsed
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
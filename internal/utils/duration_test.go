@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"6w", 42 * 24 * time.Hour},
+		{"1y", 365 * 24 * time.Hour},
+		{"12h", 12 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		duration, err := ParseDuration(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, duration, tt.value)
+	}
+
+	for _, value := range []string{"", "d", "y", "0d", "-1y", "1.5d", "soon", "-5m"} {
+		_, err := ParseDuration(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"encoding/json"
+	"net"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -25,42 +27,7 @@ const (
 func GetLLMClient(runner *interp.Runner, modelType LLMModelType) (*openai.Client, LLMModelConfig) {
 	varPrefix := "GSH_" + string(modelType) + "_MODEL_"
 
-	// Read provider setting (ollama, openai, openrouter)
-	provider := strings.ToLower(runner.Vars[varPrefix+"PROVIDER"].String())
-	if provider == "" {
-		provider = "ollama" // Default to ollama
-	}
-
-	// Read API key separately from provider
-	apiKey := runner.Vars[varPrefix+"API_KEY"].String()
-
-	// Read base URL (may be overridden by user)
-	baseURL := runner.Vars[varPrefix+"BASE_URL"].String()
-
-	// Set defaults based on provider
-	switch provider {
-	case "openai":
-		if apiKey == "" {
-			apiKey = "sk-" // Placeholder, user should provide real key
-		}
-		if baseURL == "" {
-			baseURL = "https://api.openai.com/v1"
-		}
-	case "openrouter":
-		if apiKey == "" {
-			apiKey = "sk-or-" // Placeholder, user should provide real key
-		}
-		if baseURL == "" {
-			baseURL = "https://openrouter.ai/api/v1"
-		}
-	default: // "ollama" or unknown
-		if apiKey == "" {
-			apiKey = "ollama"
-		}
-		if baseURL == "" {
-			baseURL = "http://localhost:11434/v1/"
-		}
-	}
+	provider, apiKey, baseURL := modelEndpoint(runner, varPrefix)
 
 	modelId := runner.Vars[varPrefix+"ID"].String()
 	if modelId == "" {
@@ -107,3 +74,61 @@ func GetLLMClient(runner *interp.Runner, modelType LLMModelType) (*openai.Client
 		ParallelToolCalls: parallelToolCalls,
 	}
 }
+
+// modelEndpoint returns the provider, API key and base URL of a model, with the
+// defaults of the provider filled in
+func modelEndpoint(runner *interp.Runner, varPrefix string) (provider string, apiKey string, baseURL string) {
+	// Read provider setting (ollama, openai, openrouter)
+	provider = strings.ToLower(runner.Vars[varPrefix+"PROVIDER"].String())
+	if provider == "" {
+		provider = "ollama" // Default to ollama
+	}
+
+	// Read API key separately from provider
+	apiKey = runner.Vars[varPrefix+"API_KEY"].String()
+
+	// Read base URL (may be overridden by user)
+	baseURL = runner.Vars[varPrefix+"BASE_URL"].String()
+
+	// Set defaults based on provider
+	switch provider {
+	case "openai":
+		if apiKey == "" {
+			apiKey = "sk-" // Placeholder, user should provide real key
+		}
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+	case "openrouter":
+		if apiKey == "" {
+			apiKey = "sk-or-" // Placeholder, user should provide real key
+		}
+		if baseURL == "" {
+			baseURL = "https://openrouter.ai/api/v1"
+		}
+	default: // "ollama" or unknown
+		if apiKey == "" {
+			apiKey = "ollama"
+		}
+		if baseURL == "" {
+			baseURL = "http://localhost:11434/v1/"
+		}
+	}
+	return provider, apiKey, baseURL
+}
+
+// IsLocalModel returns whether a model is served from this machine, such as
+// by Ollama on localhost, rather than by a hosted API
+func IsLocalModel(runner *interp.Runner, modelType LLMModelType) bool {
+	_, _, baseURL := modelEndpoint(runner, "GSH_"+string(modelType)+"_MODEL_")
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+func TestIsLocalModel(t *testing.T) {
+	runner, _ := interp.New()
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		want bool
+	}{
+		{"defaults to ollama on localhost", map[string]string{}, true},
+		{"loopback address", map[string]string{"GSH_FAST_MODEL_BASE_URL": "http://127.0.0.1:8080/v1"}, true},
+		{"ollama on another machine", map[string]string{"GSH_FAST_MODEL_BASE_URL": "http://gpu-box:11434/v1/"}, false},
+		{"openai", map[string]string{"GSH_FAST_MODEL_PROVIDER": "openai"}, false},
+		{"openrouter", map[string]string{"GSH_FAST_MODEL_PROVIDER": "openrouter"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner.Vars = map[string]expand.Variable{}
+			for name, value := range tt.vars {
+				runner.Vars[name] = expand.Variable{Kind: expand.String, Str: value}
+			}
+			assert.Equal(t, tt.want, IsLocalModel(runner, FastModel))
+		})
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/atinylittleshell/gsh/internal/git"
 	"github.com/atinylittleshell/gsh/internal/system"
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
+	"github.com/atinylittleshell/gsh/pkg/textwidth"
 	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"go.uber.org/zap"
@@ -34,6 +36,11 @@ type appModel struct {
 	lastPrediction      string
 	predictionStateId   int
 
+	// predictionAlternatives are the ranked predictions of a MultiPredictor,
+	// and alternativeIndex the one shown
+	predictionAlternatives []string
+	alternativeIndex       int
+
 	historyValues []string
 	result        string
 	appState      appState
@@ -46,14 +53,38 @@ type appModel struct {
 
 	// Multiline support
 	multilineState *MultilineState
-	originalPrompt string
-	height         int
+	// recalledMultiline is set when multilineState was loaded from a history entry
+	// rather than typed by the user, so it can be dropped when navigating away
+	recalledMultiline bool
+	// resultMultiline is set when the submitted command spans several lines
+	resultMultiline bool
+	originalPrompt  string
+	height          int
 
 	// LLM status indicator
 	llmIndicator LLMIndicator
 
 	// Border Status
 	borderStatus BorderStatusModel
+	// gitStatus is the repository the border status shows, for Alt+G
+	gitStatus *git.RepoStatus
+
+	// focusMode hides the assistant box below the prompt
+	focusMode bool
+
+	// savedDraft is the command last saved to the draft file
+	savedDraft string
+	// draftRestored is set once the recovered draft was put back in the buffer
+	draftRestored bool
+
+	// Explanation of a command picked in the history search, shown next to the
+	// search results while that command is selected
+	historyExplanationCommand string
+	historyExplanation        string
+
+	// Preview of the command substitutions in substitutionPreviewInput
+	substitutionPreviewInput string
+	substitutionPreview      string
 
 	// Idle summary tracking
 	lastInputTime      time.Time
@@ -69,12 +100,16 @@ type attemptPredictionMsg struct {
 type setPredictionMsg struct {
 	stateId      int
 	prediction   string
+	alternatives []string
+	explanation  string
 	inputContext string
 }
 
 type attemptExplanationMsg struct {
 	stateId    int
 	prediction string
+	// historySearch is set when explaining a command from the history search
+	historySearch bool
 }
 
 // resourceMsg carries updated system resources
@@ -86,6 +121,35 @@ type gitStatusMsg struct {
 	status *git.RepoStatus
 }
 
+// attemptSubstitutionPreviewMsg asks for the substitution preview of input,
+// if it's still what's typed
+type attemptSubstitutionPreviewMsg struct {
+	input string
+}
+
+// substitutionPreviewMsg carries the substitution preview of input
+type substitutionPreviewMsg struct {
+	input   string
+	preview string
+}
+
+// substitutionPreviewTimeout bounds a whole substitution preview
+const substitutionPreviewTimeout = 3 * time.Second
+
+// asyncSegmentMsg carries the value of an async segment of the border
+type asyncSegmentMsg struct {
+	name  string
+	value string
+}
+
+// refreshSegmentMsg asks for an async segment to be fetched again
+type refreshSegmentMsg struct {
+	name string
+}
+
+// defaultSegmentTimeout bounds fetching an async segment without its own timeout
+const defaultSegmentTimeout = 2 * time.Second
+
 // errorMsg wraps an error that occurred during prediction or explanation
 type errorMsg struct {
 	stateId int
@@ -96,8 +160,10 @@ type errorMsg struct {
 var helpHeaderRegex = regexp.MustCompile(`^\*\*[^\*]+\*\* - `)
 
 type setExplanationMsg struct {
-	stateId     int
-	explanation string
+	stateId       int
+	explanation   string
+	command       string
+	historySearch bool
 }
 
 // Idle summary messages
@@ -154,13 +220,27 @@ func initialModel(
 	}
 	textInput.Cursor.SetMode(cursor.CursorStatic)
 	textInput.ShowSuggestions = true
+	textInput.SuggestAfterKill = options.SuggestAfterKill
+	textInput.HistorySearchStyle = options.HistorySearchStyle
+	textInput.SuggestCase = options.SuggestCase
+	textInput.CompletionPolicy = options.CompletionPolicy
+	textInput.AutoCompleteAfter = options.AutoCompleteAfter
 	textInput.CompletionProvider = options.CompletionProvider
+	if options.KeyMap != nil {
+		textInput.KeyMap = *options.KeyMap
+	}
 	textInput.Focus()
 
 	borderStatus := NewBorderStatusModel()
 	borderStatus.UpdateContext(options.User, options.Host, options.CurrentDirectory)
+	borderStatus.UpdateSession(options.ShellLevel, options.SSHSession)
+	segmentNames := make([]string, len(options.RightPrompt))
+	for i, segment := range options.RightPrompt {
+		segmentNames[i] = segment.Name
+	}
+	borderStatus.SetSegments(segmentNames)
 
-	return appModel{
+	m := appModel{
 		predictor: predictor,
 		explainer: explainer,
 		analytics: analytics,
@@ -176,6 +256,7 @@ func initialModel(
 		result:             "",
 		appState:           Active,
 		interrupted:        false, // Explicitly initialize to prevent stateful behavior
+		focusMode:          options.FocusMode,
 
 		predictionStateId: 0,
 
@@ -203,18 +284,44 @@ func initialModel(
 		idleSummaryPending: false,
 		idleSummaryStateId: 0,
 	}
+	if options.RecoveredDraft != nil {
+		m.explanation = draftOffer(options.RecoveredDraft)
+		m.defaultExplanation = m.explanation
+	}
+	if options.Width > 0 {
+		m.setSize(options.Width, options.Height)
+	}
+	return m
+}
+
+// setSize fits the prompt and the boxes below it to the terminal size
+func (m *appModel) setSize(width int, height int) {
+	m.height = height
+	m.textInput.Width = width
+	m.explanationStyle = m.explanationStyle.Width(max(1, width-2))
+	m.completionStyle = m.completionStyle.Width(max(1, width-2))
+	m.borderStatus.SetWidth(max(0, width-2))
 }
 
 func (m appModel) Init() tea.Cmd {
 	cmds := []tea.Cmd{
 		m.llmIndicator.Tick(),
-		func() tea.Msg {
+		m.fetchResources(),
+		m.fetchGitStatus(),
+	}
+	// A recovered draft is offered in place of a prediction for the blank line
+	if m.options.RecoveredDraft == nil {
+		cmds = append(cmds, func() tea.Msg {
 			return attemptPredictionMsg{
 				stateId: m.predictionStateId,
 			}
-		},
-		m.fetchResources(),
-		m.fetchGitStatus(),
+		})
+	}
+	if m.options.DraftFile != "" {
+		cmds = append(cmds, scheduleDraftSave())
+	}
+	for _, segment := range m.options.RightPrompt {
+		cmds = append(cmds, fetchSegment(segment))
 	}
 
 	// Start idle check timer if enabled
@@ -240,18 +347,43 @@ func (m appModel) fetchResources() tea.Cmd {
 	}
 }
 
-func (m appModel) fetchGitStatus() tea.Cmd {
+// fetchInBackground runs fetch off the UI loop with a timeout, for border
+// segments that are too slow to compute before the prompt shows
+func fetchInBackground(timeout time.Duration, fetch func(ctx context.Context) tea.Msg) tea.Cmd {
 	return func() tea.Msg {
-		if m.options.CurrentDirectory == "" {
-			return nil
-		}
-		// Create a context with timeout for git status check
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
+		return fetch(ctx)
+	}
+}
+
+func (m appModel) fetchGitStatus() tea.Cmd {
+	if m.options.CurrentDirectory == "" {
+		return nil
+	}
+	return fetchInBackground(1*time.Second, func(ctx context.Context) tea.Msg {
+		return gitStatusMsg{status: git.GetStatusWithContext(ctx, m.options.CurrentDirectory)}
+	})
+}
 
-		status := git.GetStatusWithContext(ctx, m.options.CurrentDirectory)
-		return gitStatusMsg{status: status}
+func fetchSegment(segment AsyncSegment) tea.Cmd {
+	timeout := segment.Timeout
+	if timeout <= 0 {
+		timeout = defaultSegmentTimeout
 	}
+	return fetchInBackground(timeout, func(ctx context.Context) tea.Msg {
+		return asyncSegmentMsg{name: segment.Name, value: segment.Fetch(ctx)}
+	})
+}
+
+// rightPromptSegment returns the async segment with the given name
+func (m appModel) rightPromptSegment(name string) (AsyncSegment, bool) {
+	for _, segment := range m.options.RightPrompt {
+		if segment.Name == name {
+			return segment, true
+		}
+	}
+	return AsyncSegment{}, false
 }
 
 func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -280,16 +412,43 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case gitStatusMsg:
 		if msg.status != nil {
+			m.gitStatus = msg.status
 			m.borderStatus.UpdateGit(msg.status)
 		}
 		return m, nil
 
+	case attemptSubstitutionPreviewMsg:
+		if msg.input != m.textInput.Value() {
+			return m, nil
+		}
+		preview := m.options.SubstitutionPreview
+		return m, fetchInBackground(substitutionPreviewTimeout, func(ctx context.Context) tea.Msg {
+			return substitutionPreviewMsg{input: msg.input, preview: preview(ctx, msg.input)}
+		})
+
+	case substitutionPreviewMsg:
+		if msg.input == m.textInput.Value() {
+			m.substitutionPreviewInput, m.substitutionPreview = msg.input, msg.preview
+		}
+		return m, nil
+
+	case asyncSegmentMsg:
+		m.borderStatus.UpdateSegment(msg.name, msg.value)
+		if segment, ok := m.rightPromptSegment(msg.name); ok && segment.Refresh > 0 {
+			return m, tea.Tick(segment.Refresh, func(time.Time) tea.Msg {
+				return refreshSegmentMsg{name: msg.name}
+			})
+		}
+		return m, nil
+
+	case refreshSegmentMsg:
+		if segment, ok := m.rightPromptSegment(msg.name); ok {
+			return m, fetchSegment(segment)
+		}
+		return m, nil
+
 	case tea.WindowSizeMsg:
-		m.height = msg.Height
-		m.textInput.Width = msg.Width
-		m.explanationStyle = m.explanationStyle.Width(max(1, msg.Width-2))
-		m.completionStyle = m.completionStyle.Width(max(1, msg.Width-2))
-		m.borderStatus.SetWidth(max(0, msg.Width-2))
+		m.setSize(msg.Width, msg.Height)
 		return m, nil
 
 	case terminateMsg:
@@ -307,6 +466,13 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return model, tea.Batch(cmd, m.llmIndicator.Tick())
 
 	case setPredictionMsg:
+		if msg.stateId == m.predictionStateId {
+			m.predictionAlternatives = msg.alternatives
+			m.alternativeIndex = 0
+		}
+		if msg.explanation != "" {
+			return m.setExplainedPrediction(msg)
+		}
 		return m.setPrediction(msg.stateId, msg.prediction, msg.inputContext)
 
 	case attemptExplanationMsg:
@@ -315,11 +481,20 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case setExplanationMsg:
 		return m.setExplanation(msg)
 
+	case shellinput.ExplainHistoryMsg:
+		if m.historyExplanationCommand == msg.Command && m.historyExplanation != "" {
+			return m, nil
+		}
+		m.historyExplanationCommand = msg.Command
+		m.historyExplanation = ""
+		return m.attemptExplanation(attemptExplanationMsg{stateId: m.predictionStateId, prediction: msg.Command, historySearch: true})
+
 	case errorMsg:
 		if msg.stateId == m.predictionStateId {
 			m.lastError = msg.err
 			m.llmIndicator.SetStatus(LLMStatusError)
 			m.prediction = ""
+			m.borderStatus.UpdatePrediction("")
 			m.explanation = ""
 			m.textInput.SetSuggestions([]string{})
 		}
@@ -328,6 +503,9 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case idleCheckMsg:
 		return m.handleIdleCheck(msg)
 
+	case saveDraftMsg:
+		return m.saveDraft()
+
 	case setIdleSummaryMsg:
 		return m.handleSetIdleSummary(msg)
 
@@ -336,7 +514,7 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// TODO: replace with custom keybindings
 		case "backspace":
-			if !m.textInput.InReverseSearch() {
+			if !m.textInput.InReverseSearch() && !m.textInput.InCommandPalette() {
 				// if the input is already empty, we should clear prediction and restore default tip
 				if m.textInput.Value() == "" {
 					m.dirty = true
@@ -347,7 +525,14 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "enter":
-			if m.textInput.InReverseSearch() {
+			// An inline history search already has the match in the buffer, so
+			// Enter runs it right away like in bash
+			if m.textInput.InReverseSearch() && m.textInput.HistorySearchStyle == shellinput.HistorySearchInline {
+				m.textInput.ExitHistorySearch()
+			}
+
+			// Enter picks the selected entry rather than submitting the line
+			if m.textInput.InReverseSearch() || m.textInput.InKillRingPicker() || m.textInput.InCommandPalette() {
 				break
 			}
 
@@ -364,6 +549,7 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			// We have a complete command - add error handling for GetCompleteCommand
+			m.resultMultiline = len(m.multilineState.GetLines()) > 1
 			result := m.multilineState.GetCompleteCommand()
 			if result == "" && input != "" {
 				// Only treat empty result as error if input was not empty
@@ -377,7 +563,7 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Sequence(terminate, tea.Quit)
 
 		case "ctrl+c":
-			if m.textInput.InReverseSearch() {
+			if m.textInput.InReverseSearch() || m.textInput.InCommandPalette() {
 				break
 			}
 
@@ -389,6 +575,10 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// We do not reset multiline state here so that Gline() can reconstruct the full input
 			return m, tea.Sequence(interrupt, tea.Quit)
 		case "ctrl+d":
+			if m.textInput.InCommandPalette() {
+				break
+			}
+
 			// Handle Ctrl-D: exit shell if on blank line
 			currentInput := m.textInput.Value()
 			if strings.TrimSpace(currentInput) == "" {
@@ -400,6 +590,21 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case "ctrl+l":
 			return m.handleClearScreen()
+		case "alt+m":
+			m.focusMode = !m.focusMode
+			return m, nil
+		case "alt+r":
+			if m.options.RecoveredDraft != nil && !m.draftRestored && !m.textInput.InReverseSearch() && !m.textInput.InCommandPalette() {
+				return m.restoreDraft()
+			}
+		case "alt+g", "alt+G":
+			if !m.textInput.InReverseSearch() && !m.textInput.InCommandPalette() {
+				return m.insertGitName(msg.String() == "alt+G")
+			}
+		case "alt+down":
+			if len(m.predictionAlternatives) > 1 && !m.textInput.InReverseSearch() && !m.textInput.InCommandPalette() {
+				return m.cyclePrediction()
+			}
 		}
 	}
 
@@ -431,19 +636,43 @@ func (m appModel) View() string {
 	// Add the current input line with appropriate prompt
 	inputStr += m.textInput.View()
 
+	// Focus mode drops the assistant box, unless a picker needs it to be usable
+	if m.focusMode && !m.textInput.HistorySearchBoxVisible() && !m.textInput.CompletionBoxVisible() {
+		return inputStr
+	}
+
 	// Determine assistant content
 	var assistantContent string
 
 	// We need to handle truncation manually because lipgloss Height doesn't truncate automatically
 	// Use expanded height when in reverse search mode (close to full screen)
 	availableHeight := m.options.AssistantHeight
-	if m.textInput.InReverseSearch() && m.height > 0 {
+	if m.textInput.HistorySearchBoxVisible() && m.height > 0 {
 		// Use most of terminal height, leaving room for prompt line (2) and borders (2)
 		availableHeight = max(m.options.AssistantHeight, m.height-4)
 	}
 
+	// On a terminal too short for the prompt and the whole box, the box
+	// collapses progressively: coach tips go first, then its content lines,
+	// then everything but the top border
+	shortTerminal := false
+	collapsed := false
+	if m.height > 0 {
+		spareLines := m.height - strings.Count(inputStr, "\n") - 1
+		if spareLines < availableHeight+2 {
+			if spareLines <= 0 {
+				return inputStr
+			}
+			shortTerminal = true
+			collapsed = spareLines == 1
+			availableHeight = max(0, spareLines-2)
+		}
+	}
+
 	// Track if content is pre-formatted (completion/history boxes) and should skip word wrapping
 	isPreformatted := false
+	// Track if the thinking placeholder stands in for the explanation
+	isThinking := false
 
 	// Display error if present
 	if m.lastError != nil {
@@ -462,7 +691,21 @@ func (m appModel) View() string {
 		completionBox := m.textInput.CompletionBoxView(availableHeight, completionWidth)
 		historyBox := m.textInput.HistorySearchBoxView(availableHeight, max(0, m.textInput.Width-2))
 
-		if historyBox != "" {
+		if historyBox != "" && m.historyExplanationCommand != "" && m.textInput.SelectedHistoryCommand() == m.historyExplanationCommand {
+			// Show the explanation of the selected command next to the results
+			halfWidth := max(0, m.textInput.Width-4) / 2
+			explanation := m.historyExplanation
+			if explanation == "" {
+				explanation = "Explaining " + m.historyExplanationCommand + "…"
+			}
+
+			assistantContent = lipgloss.JoinHorizontal(lipgloss.Top,
+				lipgloss.NewStyle().Width(halfWidth).Height(availableHeight).MaxHeight(availableHeight).
+					Render(m.textInput.HistorySearchBoxView(availableHeight, halfWidth)),
+				lipgloss.NewStyle().Width(halfWidth).Height(availableHeight).MaxHeight(availableHeight).PaddingLeft(1).
+					Render(WordwrapWithRuneWidth(m.renderExplanation(explanation), max(1, halfWidth-1))))
+			isPreformatted = true
+		} else if historyBox != "" {
 			assistantContent = historyBox
 			isPreformatted = true
 		} else if completionBox != "" && helpBox != "" {
@@ -496,13 +739,21 @@ func (m appModel) View() string {
 			isPreformatted = true
 		} else if helpBox != "" {
 			assistantContent = helpBox
+		} else if m.substitutionPreview != "" && m.substitutionPreviewInput == m.textInput.Value() {
+			assistantContent = strings.TrimSpace(m.substitutionPreview + "\n" + m.renderExplanation(m.explanation))
+		} else if m.showsThinking() {
+			assistantContent = m.options.ThinkingText
+			isThinking = true
 		} else {
-			assistantContent = m.explanation
+			assistantContent = m.renderExplanation(m.explanation)
 		}
 	}
 
 	// Track if this is a coach tip for styling after word wrap
 	isCoachTip := m.explanation == m.defaultExplanation && m.explanation != ""
+	if isCoachTip && shortTerminal && assistantContent == m.explanation {
+		assistantContent = ""
+	}
 
 	// Render Assistant Box with custom border that includes LLM indicators
 	boxWidth := max(0, m.textInput.Width-2)
@@ -513,7 +764,7 @@ func (m appModel) View() string {
 	innerWidth := max(0, boxWidth-2) // Account for left/right borders
 	// Content area is innerWidth minus 2 spaces for left/right padding
 	contentWidth := innerWidth - 2
-	// Use custom word wrapping that uses GetRuneWidth for accurate Unicode/emoji width calculation
+	// Use custom word wrapping that uses textwidth for accurate Unicode/emoji width calculation
 	// This ensures coach tips with emoji render correctly in the assistant box
 	// Note: Skip word wrapping for completion/history boxes as they are already formatted with proper columns
 	var wrappedContent string
@@ -526,7 +777,7 @@ func (m appModel) View() string {
 	lines := strings.Split(wrappedContent, "\n")
 
 	// Apply faded style to each line of coach tips after word wrapping
-	if isCoachTip {
+	if isCoachTip || isThinking {
 		for i, line := range lines {
 			if line != "" {
 				lines[i] = m.coachTipStyle.Render(line)
@@ -612,11 +863,17 @@ func (m appModel) View() string {
 		contextAvailableWidth = 0
 	}
 
+	// Async segments sit at the right end and get at most half of the space,
+	// so the directory stays visible
+	topRight := m.borderStatus.RenderTopRight(contextAvailableWidth / 2)
+	topRightWidth := textwidth.StringWidth(topRight)
+	contextAvailableWidth -= topRightWidth
+
 	topContext := m.borderStatus.RenderTopContext(contextAvailableWidth)
-	topContextWidth := lipgloss.Width(topContext)
+	topContextWidth := textwidth.StringWidth(topContext)
 
 	// Line filler
-	fillerWidth := topContentWidth - topLeftWidth - topContextWidth
+	fillerWidth := topContentWidth - topLeftWidth - topContextWidth - topRightWidth
 	if fillerWidth < 0 {
 		fillerWidth = 0
 	}
@@ -656,8 +913,13 @@ func (m appModel) View() string {
 			topBar.WriteString(borderStyle.Render(strings.Repeat("─", fillerWidth)))
 		}
 	}
+	topBar.WriteString(topRight)
 	topBar.WriteString(borderStyle.Render("╮"))
 
+	if collapsed {
+		return m.withAssistantBox(inputStr, topBar.String())
+	}
+
 	var result strings.Builder
 	result.WriteString(topBar.String())
 	result.WriteString("\n")
@@ -667,11 +929,10 @@ func (m appModel) View() string {
 	// Content is already wrapped at contentWidth
 	for _, line := range lines {
 		// Truncate or pad line to fit content width
-		// Use stringWidthWithAnsi instead of lipgloss.Width to properly handle emoji
-		lineWidth := stringWidthWithAnsi(line)
+		lineWidth := textwidth.StringWidth(line)
 		if lineWidth > contentWidth {
-			line = truncateWithAnsi(line, contentWidth)
-			lineWidth = stringWidthWithAnsi(line)
+			line = textwidth.Truncate(line, contentWidth)
+			lineWidth = textwidth.StringWidth(line)
 		}
 		padding := max(0, contentWidth-lineWidth)
 		result.WriteString(borderStyle.Render("│"))
@@ -697,8 +958,8 @@ func (m appModel) View() string {
 
 	bottomLeft := m.borderStatus.RenderBottomLeft()
 	bottomCenter := m.borderStatus.RenderBottomCenter()
-	bottomCenterWidth := lipgloss.Width(bottomCenter)
-	bottomLeftWidth := lipgloss.Width(bottomLeft)
+	bottomCenterWidth := textwidth.StringWidth(bottomCenter)
+	bottomLeftWidth := textwidth.StringWidth(bottomLeft)
 
 	indicatorStr := " " + m.llmIndicator.View() + " "
 	// Use the indicator's Width() method which accounts for terminal-specific rendering
@@ -762,68 +1023,15 @@ func (m appModel) View() string {
 	result.WriteString(indicatorStr)
 	result.WriteString(borderStyle.Render("╯"))
 
-	return inputStr + "\n" + result.String()
+	return m.withAssistantBox(inputStr, result.String())
 }
 
-// stringWidthWithAnsi calculates the display width of a string, handling ANSI escape codes
-// Uses terminal-specific probing for emoji characters to get accurate widths
-func stringWidthWithAnsi(s string) int {
-	width := 0
-	inEscape := false
-
-	for _, r := range s {
-		if r == '\x1b' {
-			inEscape = true
-			continue
-		}
-		if inEscape {
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-				inEscape = false
-			}
-			continue
-		}
-
-		width += GetRuneWidth(r)
+// withAssistantBox puts the assistant box below or above the input lines
+func (m appModel) withAssistantBox(inputStr string, box string) string {
+	if m.options.AssistantPosition == AssistantAbove {
+		return box + "\n" + inputStr
 	}
-
-	return width
-}
-
-// truncateWithAnsi truncates a string to maxWidth display columns, handling ANSI escape codes
-// Uses terminal-specific probing for emoji characters to get accurate widths
-func truncateWithAnsi(s string, maxWidth int) string {
-	if maxWidth <= 0 {
-		return ""
-	}
-
-	var result strings.Builder
-	width := 0
-	inEscape := false
-
-	for _, r := range s {
-		if r == '\x1b' {
-			inEscape = true
-			result.WriteRune(r)
-			continue
-		}
-		if inEscape {
-			result.WriteRune(r)
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-				inEscape = false
-			}
-			continue
-		}
-
-		// Check if adding this rune would exceed maxWidth
-		runeWidth := GetRuneWidth(r)
-		if width+runeWidth > maxWidth {
-			break
-		}
-		result.WriteRune(r)
-		width += runeWidth
-	}
-
-	return result.String()
+	return inputStr + "\n" + box
 }
 
 func (m appModel) getFinalOutput() string {
@@ -851,13 +1059,27 @@ func (m appModel) updateTextInput(msg tea.Msg) (appModel, tea.Cmd) {
 	suggestionsCleared := len(oldMatchedSuggestions) > 0 && len(newMatchedSuggestions) == 0
 	m.textInput = updatedTextInput
 
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && !m.textInput.InReverseSearch() && !m.textInput.InCommandPalette() &&
+		(key.Matches(keyMsg, m.textInput.KeyMap.PrevValue) || key.Matches(keyMsg, m.textInput.KeyMap.NextValue)) {
+		m.restoreMultilineHistory()
+	}
+
+	// Update border status with new input, so the badge follows every keystroke
+	if textUpdated {
+		m.borderStatus.UpdateInput(newVal)
+	}
+
+	// Preview substitutions once typing pauses, like predictions
+	if textUpdated && m.options.SubstitutionPreview != nil && (strings.Contains(newVal, "$(") || strings.Contains(newVal, "`")) {
+		cmd = tea.Batch(cmd, tea.Tick(200*time.Millisecond, func(t time.Time) tea.Msg {
+			return attemptSubstitutionPreviewMsg{input: newVal}
+		}))
+	}
+
 	// if the text input has changed, we want to attempt a prediction
 	if textUpdated && m.predictor != nil {
 		m.predictionStateId++
 
-		// Update border status with new input
-		m.borderStatus.UpdateInput(newVal)
-
 		// Clear any existing error when user types
 		m.lastError = nil
 
@@ -926,8 +1148,34 @@ func (m appModel) updateTextInput(msg tea.Msg) (appModel, tea.Cmd) {
 	return m, cmd
 }
 
+// restoreMultilineHistory rebuilds the multiline buffer when history navigation
+// lands on an entry spanning several lines, and drops it again when navigating away
+func (m *appModel) restoreMultilineHistory() {
+	if m.recalledMultiline {
+		m.multilineState.Reset()
+		m.textInput.Prompt = m.originalPrompt
+		m.recalledMultiline = false
+	}
+
+	// Don't clobber a multiline command the user is in the middle of typing
+	if m.multilineState.IsActive() {
+		return
+	}
+
+	lines := m.textInput.HistoryContinuationLines()
+	if len(lines) == 0 {
+		return
+	}
+
+	m.multilineState.LoadLines(lines)
+	m.textInput.Prompt = m.multilineState.ContinuationPrompt() + " "
+	m.recalledMultiline = true
+}
+
 func (m *appModel) clearPrediction() {
 	m.prediction = ""
+	m.predictionAlternatives = nil
+	m.borderStatus.UpdatePrediction("")
 	m.explanation = ""
 	m.lastError = nil
 	m.textInput.SetSuggestions([]string{})
@@ -937,6 +1185,8 @@ func (m *appModel) clearPrediction() {
 // explanation (e.g., coach tips) - used when the input buffer becomes blank
 func (m *appModel) clearPredictionAndRestoreDefault() {
 	m.prediction = ""
+	m.predictionAlternatives = nil
+	m.borderStatus.UpdatePrediction("")
 	m.explanation = m.defaultExplanation
 	m.lastError = nil
 	m.textInput.SetSuggestions([]string{})
@@ -953,8 +1203,18 @@ func (m appModel) setPrediction(stateId int, prediction string, inputContext str
 	}
 
 	m.prediction = prediction
+	m.borderStatus.UpdatePrediction(prediction)
 	m.lastPredictionInput = inputContext
 	m.lastPrediction = prediction
+	// An empty result, like a blank line's, would hide the last real one
+	if prediction != "" || inputContext != "" {
+		recordPredictionContext(PredictionContext{
+			Input:      m.textInput.Value(),
+			Prediction: prediction,
+			Context:    inputContext,
+			At:         time.Now(),
+		})
+	}
 	m.textInput.SetSuggestions([]string{prediction})
 	m.textInput.UpdateHelpInfo()
 
@@ -977,6 +1237,86 @@ func (m appModel) setPrediction(stateId int, prediction string, inputContext str
 	})
 }
 
+// renderExplanation renders the markdown of an explanation when
+// ExplanationMarkdown is set. Coach tips are left alone, they're faded as a
+// whole after wrapping.
+func (m appModel) renderExplanation(explanation string) string {
+	if !m.options.ExplanationMarkdown || explanation == m.defaultExplanation {
+		return explanation
+	}
+	return RenderMarkdown(explanation)
+}
+
+// showsThinking returns whether the thinking placeholder is shown, which is
+// while a prediction is requested and there's nothing else to show yet
+func (m appModel) showsThinking() bool {
+	return m.options.ThinkingText != "" &&
+		m.llmIndicator.GetStatus() == LLMStatusInFlight &&
+		m.prediction == "" &&
+		m.explanation == ""
+}
+
+// insertGitName types the current branch, or with repoName the repository's
+// name, at the cursor. Outside a repository and on a detached HEAD there is
+// nothing to insert.
+func (m appModel) insertGitName(repoName bool) (appModel, tea.Cmd) {
+	if m.gitStatus == nil {
+		return m, nil
+	}
+	name := m.gitStatus.Branch
+	if repoName {
+		name = m.gitStatus.RepoName
+	}
+	if name == "" || name == "detached" || strings.HasPrefix(name, "(") {
+		return m, nil
+	}
+	return m.updateTextInput(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name), Paste: true})
+}
+
+// cyclePrediction shows the next prediction alternative that fits the input
+// as the ghost text and explains it. Alternatives that no longer fit what was
+// typed are skipped.
+func (m appModel) cyclePrediction() (appModel, tea.Cmd) {
+	for range m.predictionAlternatives {
+		m.alternativeIndex = (m.alternativeIndex + 1) % len(m.predictionAlternatives)
+		prediction := m.predictionAlternatives[m.alternativeIndex]
+		m.textInput.SetSuggestions([]string{prediction})
+		if len(m.textInput.MatchedSuggestions()) == 0 {
+			continue
+		}
+
+		m.prediction = prediction
+		m.lastPrediction = prediction
+		m.borderStatus.UpdatePrediction(prediction)
+		m.textInput.UpdateHelpInfo()
+		m.explanation = ""
+		// Drop the explanation of the previous alternative if it's still coming
+		m.predictionStateId++
+		stateId := m.predictionStateId
+		return m, func() tea.Msg {
+			return attemptExplanationMsg{stateId: stateId, prediction: prediction}
+		}
+	}
+
+	m.textInput.SetSuggestions([]string{m.prediction})
+	return m, nil
+}
+
+// setExplainedPrediction sets a prediction that came with its explanation, so
+// no separate explanation is requested. While suggestions are suppressed the
+// typed input is explained instead, which still takes the separate step.
+func (m appModel) setExplainedPrediction(msg setPredictionMsg) (appModel, tea.Cmd) {
+	model, cmd := m.setPrediction(msg.stateId, msg.prediction, msg.inputContext)
+	if cmd == nil || model.textInput.SuggestionsSuppressedUntilInput() {
+		return model, cmd
+	}
+	if !isPredictionIgnored(msg.prediction, model.options.PredictIgnore) {
+		model.explanation = msg.explanation
+	}
+	model.llmIndicator.SetStatus(LLMStatusSuccess)
+	return model, nil
+}
+
 func (m appModel) attemptPrediction(msg attemptPredictionMsg) (tea.Model, tea.Cmd) {
 	if m.predictor == nil {
 		return m, nil
@@ -989,11 +1329,41 @@ func (m appModel) attemptPrediction(msg attemptPredictionMsg) (tea.Model, tea.Cm
 		// Don't show indicator when buffer is empty - just return clean state
 		return m, nil
 	}
+	// Skip commands configured in GSH_PREDICT_IGNORE, e.g. editors and REPLs
+	if isPredictionIgnored(m.textInput.Value(), m.options.PredictIgnore) {
+		return m, nil
+	}
 
 	return m, tea.Cmd(func() tea.Msg {
-		prediction, inputContext, err := m.predictor.Predict(m.textInput.Value())
+		input := m.textInput.Value()
+		startTime := time.Now()
+		// The results only come back through callWithTimeout, since a call that
+		// timed out may still be running
+		type predicted struct {
+			prediction, explanation, inputContext string
+			alternatives                          []string
+		}
+		result, err := callWithTimeout(m.options.PredictionTimeout, func(ctx context.Context) (predicted, error) {
+			if combined, ok := m.predictor.(CombinedPredictor); ok && m.options.CombinedInference {
+				result, err := combined.PredictAndExplain(ctx, input)
+				return predicted{prediction: result.Prediction, explanation: result.Explanation, inputContext: result.InputContext}, err
+			}
+			if multi, ok := m.predictor.(MultiPredictor); ok {
+				alternatives, inputContext, err := multi.PredictAlternatives(ctx, input)
+				result := predicted{inputContext: inputContext, alternatives: alternatives}
+				if len(alternatives) > 0 {
+					result.prediction = alternatives[0]
+				}
+				return result, err
+			}
+			prediction, inputContext, err := m.predictor.Predict(ctx, input)
+			return predicted{prediction: prediction, inputContext: inputContext}, err
+		})
+		prediction, explanation, inputContext, alternatives := result.prediction, result.explanation, result.inputContext, result.alternatives
+		duration := time.Since(startTime)
+		recordPredictionLatency(duration, err)
 		if err != nil {
-			m.logger.Error("gline prediction failed", zap.Error(err))
+			m.logger.Error("gline prediction failed", zap.Error(err), zap.Duration("duration", duration))
 			return errorMsg{stateId: msg.stateId, err: err}
 		}
 
@@ -1002,8 +1372,11 @@ func (m appModel) attemptPrediction(msg attemptPredictionMsg) (tea.Model, tea.Cm
 			zap.Int("stateId", msg.stateId),
 			zap.String("prediction", prediction),
 			zap.String("inputContext", inputContext),
+			zap.Bool("explained", explanation != ""),
+			zap.Duration("duration", duration),
+			zap.Int("inputLength", len(input)),
 		)
-		return setPredictionMsg{stateId: msg.stateId, prediction: prediction, inputContext: inputContext}
+		return setPredictionMsg{stateId: msg.stateId, prediction: prediction, alternatives: alternatives, explanation: explanation, inputContext: inputContext}
 	})
 }
 
@@ -1014,11 +1387,21 @@ func (m appModel) attemptExplanation(msg attemptExplanationMsg) (tea.Model, tea.
 	if msg.stateId != m.predictionStateId {
 		return m, nil
 	}
+	// Commands picked from the history search are explained on request, even
+	// those that don't get predictions
+	if !msg.historySearch && isPredictionIgnored(msg.prediction, m.options.PredictIgnore) {
+		return m, nil
+	}
 
 	return m, tea.Cmd(func() tea.Msg {
-		explanation, err := m.explainer.Explain(msg.prediction)
+		startTime := time.Now()
+		explanation, err := callWithTimeout(m.options.PredictionTimeout, func(ctx context.Context) (string, error) {
+			return m.explainer.Explain(ctx, msg.prediction)
+		})
+		duration := time.Since(startTime)
+		recordExplanationLatency(duration, err)
 		if err != nil {
-			m.logger.Error("gline explanation failed", zap.Error(err))
+			m.logger.Error("gline explanation failed", zap.Error(err), zap.Duration("duration", duration))
 			return errorMsg{stateId: msg.stateId, err: err}
 		}
 
@@ -1026,11 +1409,45 @@ func (m appModel) attemptExplanation(msg attemptExplanationMsg) (tea.Model, tea.
 			"gline explained prediction",
 			zap.Int("stateId", msg.stateId),
 			zap.String("explanation", explanation),
+			zap.Duration("duration", duration),
+			zap.Int("inputLength", len(msg.prediction)),
 		)
-		return setExplanationMsg{stateId: msg.stateId, explanation: explanation}
+		return setExplanationMsg{stateId: msg.stateId, explanation: explanation, command: msg.prediction, historySearch: msg.historySearch}
 	})
 }
 
+// callWithTimeout runs call with a context that expires after timeout. It stops
+// waiting once the context is done, even if call doesn't honor cancellation, so a
+// stalled request can't leave the LLM indicator pulsing forever. The result is
+// only handed over through a buffered channel, so an abandoned call finishing
+// later doesn't touch anything the caller still uses.
+func callWithTimeout[T any](timeout time.Duration, call func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return call(context.Background())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type outcome struct {
+		result T
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := call(ctx)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		var zero T
+		return zero, fmt.Errorf("request timed out after %s: %w", timeout, ctx.Err())
+	}
+}
+
 func (m appModel) handleClearScreen() (tea.Model, tea.Cmd) {
 	// Log the current state before clearing
 	m.logger.Debug("gline handleClearScreen called",
@@ -1050,6 +1467,13 @@ func (m appModel) handleClearScreen() (tea.Model, tea.Cmd) {
 }
 
 func (m appModel) setExplanation(msg setExplanationMsg) (tea.Model, tea.Cmd) {
+	if msg.historySearch {
+		if msg.command == m.historyExplanationCommand {
+			m.historyExplanation = msg.explanation
+		}
+		return m, nil
+	}
+
 	if msg.stateId != m.predictionStateId {
 		m.logger.Debug(
 			"gline discarding explanation",
@@ -1141,6 +1565,23 @@ func (m appModel) handleSetIdleSummary(msg setIdleSummaryMsg) (tea.Model, tea.Cm
 	return m, nil
 }
 
+// GlineResult describes the line entered at a Gline prompt
+type GlineResult struct {
+	// Command is the submitted command, with the lines of a multiline command
+	// joined by newlines
+	Command string
+	// Interrupted is set when the prompt was cancelled with Ctrl+C, in which
+	// case Command is empty
+	Interrupted bool
+	// FromPrediction is set when the command is the prediction, accepted with
+	// Right, Tab or Alt+Right rather than typed out
+	FromPrediction bool
+	// Multiline is set when the command was entered over several lines
+	Multiline bool
+}
+
+// Gline reads a line, returning ErrInterrupted when it's cancelled with
+// Ctrl+C. GlineWithResult also says how the line was entered.
 func Gline(
 	prompt string,
 	historyValues []string,
@@ -1151,13 +1592,53 @@ func Gline(
 	logger *zap.Logger,
 	options Options,
 ) (string, error) {
-	p := tea.NewProgram(
-		initialModel(prompt, historyValues, explanation, predictor, explainer, analytics, logger, options),
-	)
+	result, err := GlineWithResult(prompt, historyValues, explanation, predictor, explainer, analytics, logger, options)
+	if err != nil {
+		return "", err
+	}
+	if result.Interrupted {
+		return "", ErrInterrupted
+	}
+	return result.Command, nil
+}
+
+// GlineWithResult reads a line like Gline, but reports Ctrl+C through
+// GlineResult.Interrupted rather than an error
+func GlineWithResult(
+	prompt string,
+	historyValues []string,
+	explanation string,
+	predictor Predictor,
+	explainer Explainer,
+	analytics PredictionAnalytics,
+	logger *zap.Logger,
+	options Options,
+) (GlineResult, error) {
+	var model tea.Model = initialModel(prompt, historyValues, explanation, predictor, explainer, analytics, logger, options)
+	// With crash reports on, panics are captured instead of printed by
+	// bubbletea, and raised again once the terminal is restored so they reach
+	// the crash reporter with their stack
+	var crashed *crashState
+	if options.CrashReport {
+		crashed = &crashState{}
+		model = crashCapture{model: model, state: crashed}
+	}
+	p := tea.NewProgram(model)
+	if crashed != nil {
+		crashed.program = p
+	}
 
 	m, err := p.Run()
+	if crashed != nil {
+		if r := crashed.captured(); r != nil {
+			panic(r)
+		}
+		if captured, ok := m.(crashCapture); ok {
+			m = captured.model
+		}
+	}
 	if err != nil {
-		return "", err
+		return GlineResult{}, err
 	}
 
 	appModel, ok := m.(appModel)
@@ -1166,6 +1647,17 @@ func Gline(
 		panic("Gline resulted in an unexpected app model")
 	}
 
+	// The line was submitted or cancelled, so there's no draft left to recover
+	if options.DraftFile != "" {
+		if err := ClearDraft(options.DraftFile); err != nil {
+			logger.Warn("failed to remove the input draft", zap.Error(err))
+		}
+	}
+
+	if appModel.focusMode != options.FocusMode && options.FocusModeChanged != nil {
+		options.FocusModeChanged(appModel.focusMode)
+	}
+
 	// Check if the session was interrupted by Ctrl+C
 	if appModel.interrupted {
 		// Reconstruct what was on screen so it persists
@@ -1184,17 +1676,32 @@ func Gline(
 		inputStr += appModel.textInput.Prompt + appModel.textInput.Value() + "^C\n"
 
 		fmt.Print(RESET_CURSOR_COLUMN + inputStr)
-		return "", ErrInterrupted
+		return GlineResult{Interrupted: true}, nil
 	}
 
 	fmt.Print(RESET_CURSOR_COLUMN + appModel.getFinalOutput() + "\n")
 
-	if analytics != nil {
-		err = analytics.NewEntry(appModel.lastPredictionInput, appModel.lastPrediction, appModel.result)
-		if err != nil {
-			logger.Error("failed to log analytics entry", zap.Error(err))
-		}
+	appModel.recordAnalytics()
+
+	return appModel.glineResult(), nil
+}
+
+// glineResult describes the submitted line
+func (m appModel) glineResult() GlineResult {
+	return GlineResult{
+		Command:        m.result,
+		FromPrediction: m.textInput.SuggestionAccepted() && m.lastPrediction != "" && m.result == m.lastPrediction,
+		Multiline:      m.resultMultiline,
 	}
+}
 
-	return appModel.result, nil
+// recordAnalytics records how the last prediction compares to the accepted
+// line, unless the caller records it instead
+func (m appModel) recordAnalytics() {
+	if m.analytics == nil || !m.options.RecordAnalytics {
+		return
+	}
+	if err := m.analytics.NewEntry(m.lastPredictionInput, m.lastPrediction, m.result); err != nil {
+		m.logger.Error("failed to log analytics entry", zap.Error(err))
+	}
 }
@@ -1,6 +1,7 @@
 package gline
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -42,9 +43,13 @@ func newMockPredictor() *mockPredictor {
 	}
 }
 
-func (m *mockPredictor) Predict(input string) (prediction, inputContext string, err error) {
+func (m *mockPredictor) Predict(ctx context.Context, input string) (prediction, inputContext string, err error) {
 	if m.delay > 0 {
-		time.Sleep(m.delay)
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		}
 	}
 
 	prediction, ok := m.predictions[input]
@@ -81,9 +86,13 @@ func newMockExplainer() *mockExplainer {
 	}
 }
 
-func (m *mockExplainer) Explain(prediction string) (string, error) {
+func (m *mockExplainer) Explain(ctx context.Context, prediction string) (string, error) {
 	if m.delay > 0 {
-		time.Sleep(m.delay)
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
 	}
 
 	explanation, ok := m.explanations[prediction]
@@ -250,6 +259,64 @@ func TestApp_PredictionFlow_Integration(t *testing.T) {
 	}
 }
 
+func TestApp_PredictionTimeout_Integration(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	predictor := newMockPredictor()
+	predictor.delay = 5 * time.Second
+	explainer := newMockExplainer()
+	explainer.delay = 5 * time.Second
+
+	options := NewOptions()
+	options.PredictionTimeout = 20 * time.Millisecond
+
+	model := initialModel("> ", []string{}, "", predictor, explainer, newMockAnalytics(), logger, options)
+	model.textInput.SetValue("git")
+
+	model.predictionStateId++
+	updatedModel, cmd := model.attemptPrediction(attemptPredictionMsg{stateId: model.predictionStateId})
+	model = updatedModel.(appModel)
+	require.NotNil(t, cmd)
+
+	start := time.Now()
+	msg := cmd()
+	assert.Less(t, time.Since(start), time.Second)
+	errMsg, ok := msg.(errorMsg)
+	require.True(t, ok, "expected errorMsg, got %T", msg)
+	assert.ErrorIs(t, errMsg.err, context.DeadlineExceeded)
+
+	updatedModel, _ = model.Update(errMsg)
+	model = updatedModel.(appModel)
+	assert.Equal(t, LLMStatusError, model.llmIndicator.GetStatus())
+
+	updatedModel, cmd = model.attemptExplanation(attemptExplanationMsg{stateId: model.predictionStateId, prediction: "git status"})
+	model = updatedModel.(appModel)
+	require.NotNil(t, cmd)
+	msg = cmd()
+	errMsg, ok = msg.(errorMsg)
+	require.True(t, ok, "expected errorMsg, got %T", msg)
+	assert.ErrorIs(t, errMsg.err, context.DeadlineExceeded)
+}
+
+func TestCallWithTimeoutIgnoresUncooperativeCalls(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	result, err := callWithTimeout(10*time.Millisecond, func(ctx context.Context) (string, error) {
+		<-release
+		return "late", nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, result)
+
+	result, err = callWithTimeout(0, func(ctx context.Context) (string, error) {
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		return "done", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "done", result)
+}
+
 func TestCtrlKClearsPredictionAndExplanation(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	predictor := newMockPredictor()
@@ -810,6 +877,24 @@ func TestApp_WindowResize_Integration(t *testing.T) {
 	assert.NotEmpty(t, view, "Expected view to be rendered after resize")
 }
 
+func TestApp_InitialSizeFromOptions(t *testing.T) {
+	options := NewOptions()
+	options.Width = 100
+	options.Height = 30
+
+	model := initialModel("> ", []string{}, "", newMockPredictor(), newMockExplainer(), nil, zaptest.NewLogger(t), options)
+
+	assert.Equal(t, 100, model.textInput.Width, "the first render should already use the terminal width")
+	assert.Equal(t, 30, model.height)
+	assert.Equal(t, 98, model.borderStatus.width)
+
+	// A later resize still takes over
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 60, Height: 20})
+	model = updatedModel.(appModel)
+	assert.Equal(t, 60, model.textInput.Width)
+	assert.Equal(t, 20, model.height)
+}
+
 func TestApp_StateManagement_Integration(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	predictor := newMockPredictor()
@@ -874,9 +959,6 @@ func TestApp_Analytics_Integration(t *testing.T) {
 	options := NewOptions()
 	options.CompletionProvider = completionProvider
 
-	// Test analytics recording directly without calling Gline
-	// to avoid double analytics recording (once by Gline, once manually)
-
 	// Create a model to simulate the state
 	model := initialModel(
 		"> ",
@@ -894,9 +976,8 @@ func TestApp_Analytics_Integration(t *testing.T) {
 	model.lastPrediction = "git status"
 	model.result = "git status"
 
-	// Simulate the analytics recording that would happen in Gline
-	err := analytics.NewEntry(model.lastPredictionInput, model.lastPrediction, model.result)
-	require.NoError(t, err)
+	// Record the entry the way Gline does once the line is accepted
+	model.recordAnalytics()
 
 	// Verify analytics entry was recorded correctly
 	assert.Len(t, analytics.entries, 1, "Expected one analytics entry")
@@ -904,6 +985,11 @@ func TestApp_Analytics_Integration(t *testing.T) {
 	assert.Equal(t, "git", entry.predictionInput, "Expected prediction input to be 'git'")
 	assert.Equal(t, "git status", entry.prediction, "Expected prediction to be 'git status'")
 	assert.Equal(t, "git status", entry.result, "Expected result to be 'git status'")
+
+	// Callers recording the entry themselves turn it off
+	model.options.RecordAnalytics = false
+	model.recordAnalytics()
+	assert.Len(t, analytics.entries, 1, "Expected no entry with RecordAnalytics off")
 }
 
 func TestApp_View_Integration(t *testing.T) {
@@ -985,3 +1071,121 @@ func TestApp_View_Integration(t *testing.T) {
 		})
 	}
 }
+
+// mockCombinedPredictor explains its predictions with the explanations of a
+// mockExplainer
+type mockCombinedPredictor struct {
+	*mockPredictor
+	explainer *mockExplainer
+}
+
+func (m *mockCombinedPredictor) PredictAndExplain(ctx context.Context, input string) (PredictionResult, error) {
+	prediction, inputContext, err := m.Predict(ctx, input)
+	if err != nil || prediction == "" {
+		return PredictionResult{InputContext: inputContext}, err
+	}
+	return PredictionResult{
+		Prediction:   prediction,
+		Explanation:  m.explainer.explanations[prediction],
+		InputContext: inputContext,
+	}, nil
+}
+
+// mockMultiPredictor ranks fixed alternatives for every input
+type mockMultiPredictor struct {
+	*mockPredictor
+	alternatives []string
+}
+
+func (m *mockMultiPredictor) PredictAlternatives(ctx context.Context, input string) ([]string, string, error) {
+	return m.alternatives, "context", nil
+}
+
+func runPrediction(t *testing.T, options Options, predictor Predictor, input string) (appModel, tea.Cmd) {
+	model := initialModel("> ", []string{}, "", predictor, newMockExplainer(), nil, zaptest.NewLogger(t), options)
+	model.textInput.SetValue(input)
+	model.textInput.SetCursor(len(input))
+
+	_, cmd := model.attemptPrediction(attemptPredictionMsg{stateId: model.predictionStateId})
+	require.NotNil(t, cmd)
+	msg, ok := cmd().(setPredictionMsg)
+	require.True(t, ok)
+
+	updatedModel, cmd := model.Update(msg)
+	return updatedModel.(appModel), cmd
+}
+
+func TestCombinedInferenceSkipsSeparateExplanation(t *testing.T) {
+	predictor := &mockCombinedPredictor{mockPredictor: newMockPredictor(), explainer: newMockExplainer()}
+	options := NewOptions()
+	options.CombinedInference = true
+
+	model, cmd := runPrediction(t, options, predictor, "git")
+
+	assert.Equal(t, "git status", model.prediction)
+	assert.Equal(t, "Shows the status of the working directory", model.explanation)
+	assert.Nil(t, cmd, "no separate explanation should be requested")
+	assert.Equal(t, LLMStatusSuccess, model.llmIndicator.GetStatus())
+}
+
+func TestCombinedInferenceDisabledExplainsSeparately(t *testing.T) {
+	predictor := &mockCombinedPredictor{mockPredictor: newMockPredictor(), explainer: newMockExplainer()}
+
+	model, cmd := runPrediction(t, NewOptions(), predictor, "git")
+
+	assert.Equal(t, "git status", model.prediction)
+	assert.Empty(t, model.explanation)
+	require.NotNil(t, cmd)
+	assert.Equal(t, attemptExplanationMsg{stateId: model.predictionStateId, prediction: "git status"}, cmd())
+}
+
+func TestCombinedInferenceWithoutExplanationFallsBack(t *testing.T) {
+	explainer := newMockExplainer()
+	delete(explainer.explanations, "git status")
+	predictor := &mockCombinedPredictor{mockPredictor: newMockPredictor(), explainer: explainer}
+	options := NewOptions()
+	options.CombinedInference = true
+
+	model, cmd := runPrediction(t, options, predictor, "git")
+
+	assert.Equal(t, "git status", model.prediction)
+	require.NotNil(t, cmd)
+	assert.Equal(t, attemptExplanationMsg{stateId: model.predictionStateId, prediction: "git status"}, cmd())
+}
+
+func TestCyclePredictionAlternatives(t *testing.T) {
+	predictor := &mockMultiPredictor{
+		mockPredictor: newMockPredictor(),
+		alternatives:  []string{"git status", "ls -la", "git stash"},
+	}
+
+	model, cmd := runPrediction(t, NewOptions(), predictor, "git")
+	assert.Equal(t, "git status", model.prediction)
+	assert.NotNil(t, cmd, "the first alternative is explained")
+
+	// Alternatives that don't fit the input are skipped
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyDown, Alt: true})
+	model = updated.(appModel)
+	assert.Equal(t, "git stash", model.prediction)
+	assert.Equal(t, []string{"git stash"}, model.textInput.MatchedSuggestions())
+	require.NotNil(t, cmd)
+	msg, ok := cmd().(attemptExplanationMsg)
+	require.True(t, ok)
+	assert.Equal(t, "git stash", msg.prediction)
+	assert.Equal(t, model.predictionStateId, msg.stateId)
+
+	// And it wraps around
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown, Alt: true})
+	model = updated.(appModel)
+	assert.Equal(t, "git status", model.prediction)
+	assert.Equal(t, "git status", model.lastPrediction)
+}
+
+func TestSinglePredictionIgnoresAlternativeKey(t *testing.T) {
+	model, _ := runPrediction(t, NewOptions(), newMockPredictor(), "git")
+	assert.Equal(t, "git status", model.prediction)
+	assert.Empty(t, model.predictionAlternatives)
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyDown, Alt: true})
+	assert.Equal(t, "git status", updated.(appModel).prediction)
+}
@@ -1,10 +1,16 @@
 package gline
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/atinylittleshell/gsh/internal/git"
+	"github.com/atinylittleshell/gsh/pkg/shellinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -203,6 +209,211 @@ func TestAppModelView(t *testing.T) {
 	}
 }
 
+func TestAsyncSegments(t *testing.T) {
+	options := NewOptions()
+	options.Width, options.Height = 80, 20
+	options.RightPrompt = []AsyncSegment{{
+		Name:    "kube",
+		Fetch:   func(ctx context.Context) string { return "minikube" },
+		Refresh: time.Minute,
+	}}
+	model := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options)
+	assert.NotContains(t, model.View(), "minikube")
+
+	msg := fetchSegment(options.RightPrompt[0])()
+	assert.Equal(t, asyncSegmentMsg{name: "kube", value: "minikube"}, msg)
+
+	updated, cmd := model.Update(msg)
+	assert.NotNil(t, cmd, "segments with a refresh interval are fetched again")
+	assert.Contains(t, strings.SplitN(updated.View(), "\n", 3)[1], "minikube", "the value shows on the top border")
+
+	_, cmd = updated.Update(refreshSegmentMsg{name: "kube"})
+	require.NotNil(t, cmd)
+	assert.Equal(t, msg, cmd())
+}
+
+func TestFocusMode(t *testing.T) {
+	logger := zap.NewNop()
+	options := NewOptions()
+	options.FocusMode = true
+	sized, _ := initialModel("test> ", []string{}, "coach tip", nil, nil, nil, logger, options).Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	model := sized.(appModel)
+
+	view := model.View()
+	assert.NotContains(t, view, "╭", "Focus mode should hide the assistant box")
+	assert.NotContains(t, view, "coach tip")
+	assert.NotContains(t, view, "\n", "Focus mode should render only the prompt line")
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}, Alt: true})
+	view = updated.(appModel).View()
+	assert.Contains(t, view, "╭", "Alt+M should bring the assistant box back")
+	assert.Contains(t, view, "coach tip")
+}
+
+func TestAssistantPosition(t *testing.T) {
+	assert.Equal(t, AssistantBelow, ParseAssistantPosition(""))
+	assert.Equal(t, AssistantBelow, ParseAssistantPosition("sideways"))
+	assert.Equal(t, AssistantAbove, ParseAssistantPosition(" Above "))
+
+	options := NewOptions()
+	sized, _ := initialModel("test> ", []string{}, "coach tip", nil, nil, nil, zap.NewNop(), options).Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	lines := strings.Split(sized.(appModel).View(), "\n")
+	assert.True(t, strings.HasPrefix(lines[0], "test> "))
+	assert.Contains(t, lines[len(lines)-1], "╰")
+
+	options.AssistantPosition = AssistantAbove
+	sized, _ = initialModel("test> ", []string{}, "coach tip", nil, nil, nil, zap.NewNop(), options).Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	lines = strings.Split(sized.(appModel).View(), "\n")
+	assert.Contains(t, lines[0], "╭")
+	assert.True(t, strings.HasPrefix(lines[len(lines)-1], "test> "), "the prompt should be the last line")
+}
+
+func TestSubstitutionPreview(t *testing.T) {
+	options := NewOptions()
+	var previewed []string
+	options.SubstitutionPreview = func(ctx context.Context, input string) string {
+		previewed = append(previewed, input)
+		return "$(pwd) → /src"
+	}
+	sized, _ := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options).Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	model := sized.(appModel)
+
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ls")})
+	assert.Nil(t, cmd, "input without substitutions isn't previewed")
+
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" $(pwd)")})
+	// A preview of input that has changed since is dropped
+	updated, cmd = updated.(appModel).Update(attemptSubstitutionPreviewMsg{input: "ls $(pw"})
+	assert.Nil(t, cmd)
+
+	updated, cmd = updated.(appModel).Update(attemptSubstitutionPreviewMsg{input: "ls $(pwd)"})
+	require.NotNil(t, cmd)
+	updated, _ = updated.(appModel).Update(cmd())
+	assert.Equal(t, []string{"ls $(pwd)"}, previewed)
+	assert.Contains(t, updated.(appModel).View(), "$(pwd) → /src")
+
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" ")})
+	assert.NotContains(t, updated.(appModel).View(), "$(pwd) → /src")
+}
+
+func TestThinkingText(t *testing.T) {
+	options := NewOptions()
+	options.ThinkingText = "thinking…"
+	sized, _ := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options).Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	model := sized.(appModel)
+	assert.NotContains(t, model.View(), "thinking…")
+
+	model.llmIndicator.SetStatus(LLMStatusInFlight)
+	assert.Contains(t, model.View(), "thinking…")
+
+	model.explanation = "lists files"
+	assert.NotContains(t, model.View(), "thinking…")
+	assert.Contains(t, model.View(), "lists files")
+
+	model.explanation = ""
+	model.options.ThinkingText = ""
+	assert.NotContains(t, model.View(), "thinking…")
+}
+
+func TestExplanationMarkdown(t *testing.T) {
+	options := NewOptions()
+	sized, _ := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options).Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	model := sized.(appModel)
+	model.explanation = "**Removes** the `build` directory"
+	assert.Contains(t, model.View(), "**Removes** the `build` directory")
+
+	model.options.ExplanationMarkdown = true
+	assert.Contains(t, model.View(), "Removes the build directory")
+}
+
+func TestGlineResultFromPrediction(t *testing.T) {
+	typeLine := func(keys ...tea.KeyMsg) appModel {
+		model := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), NewOptions())
+		updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("git st")})
+		model, _ = updated.(appModel).setPrediction(updated.(appModel).predictionStateId, "git status", "git st")
+		for _, key := range keys {
+			updated, _ = model.Update(key)
+			model = updated.(appModel)
+		}
+		updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		return updated.(appModel)
+	}
+
+	accepted := typeLine(tea.KeyMsg{Type: tea.KeyRight}).glineResult()
+	assert.Equal(t, GlineResult{Command: "git status", FromPrediction: true}, accepted)
+
+	typed := typeLine(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("atus")}).glineResult()
+	assert.Equal(t, GlineResult{Command: "git status"}, typed)
+
+	edited := typeLine(tea.KeyMsg{Type: tea.KeyRight}, tea.KeyMsg{Type: tea.KeyBackspace}).glineResult()
+	assert.Equal(t, GlineResult{Command: "git statu"}, edited)
+}
+
+func TestGlineResultMultiline(t *testing.T) {
+	model := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), NewOptions())
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("echo one \\")})
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("two")})
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	result := updated.(appModel).glineResult()
+	assert.Equal(t, "echo one \\\ntwo", result.Command)
+	assert.True(t, result.Multiline)
+	assert.False(t, result.FromPrediction)
+}
+
+func TestInlineHistorySearchEnterRunsMatch(t *testing.T) {
+	options := NewOptions()
+	options.HistorySearchStyle = shellinput.HistorySearchInline
+	options.RichHistory = []shellinput.HistoryItem{{Command: "make test"}}
+	model := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options)
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("make")})
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	assert.Equal(t, "make test", updated.(appModel).result)
+	assert.False(t, updated.(appModel).textInput.InReverseSearch())
+}
+
+func TestHistorySearchExplainsSelectedCommand(t *testing.T) {
+	options := NewOptions()
+	options.AssistantHeight = 5
+	options.RichHistory = []shellinput.HistoryItem{{Command: "tar -xzf backup.tgz"}, {Command: "ls -la"}}
+	explainer := &recordingExplainer{explanation: "Extracts the gzipped archive"}
+	model := initialModel("test> ", []string{}, "", nil, explainer, nil, zap.NewNop(), options)
+	model.textInput.Width = 120
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	updated, cmd := updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e"), Alt: true})
+	require.NotNil(t, cmd)
+
+	// The key asks for an explanation, which goes through the explainer
+	updated, cmd = updated.(appModel).Update(cmd())
+	assert.Contains(t, updated.(appModel).View(), "Explaining tar -xzf backup.tgz")
+	require.NotNil(t, cmd)
+	updated, _ = updated.(appModel).Update(cmd())
+	model = updated.(appModel)
+
+	assert.Equal(t, []string{"tar -xzf backup.tgz"}, explainer.inputs)
+	assert.True(t, model.textInput.InReverseSearch(), "explaining keeps the search open")
+	assert.Empty(t, model.explanation, "the regular explanation is left alone")
+	assert.Contains(t, model.View(), "Extracts the gzipped archive")
+
+	// Moving to another command hides the explanation
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	assert.NotContains(t, updated.(appModel).View(), "Extracts the gzipped archive")
+}
+
+type recordingExplainer struct {
+	explanation string
+	inputs      []string
+}
+
+func (e *recordingExplainer) Explain(ctx context.Context, input string) (string, error) {
+	e.inputs = append(e.inputs, input)
+	return e.explanation, nil
+}
 
 // Test getFinalOutput
 func TestGetFinalOutput(t *testing.T) {
@@ -279,4 +490,38 @@ func TestHandleClearScreen(t *testing.T) {
 	// ClearScreen() returns a clearScreenMsg (unexported), so we can't type assert
 	// We just verify that the command returns something non-nil
 	assert.NotNil(t, msg, "handleClearScreen should return tea.ClearScreen command")
-}
\ No newline at end of file
+}
+func TestInsertGitName(t *testing.T) {
+	model := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), NewOptions())
+	press := func(keys ...tea.KeyMsg) {
+		for _, key := range keys {
+			updated, _ := model.Update(key)
+			model = updated.(appModel)
+		}
+	}
+
+	// Nothing to insert before the status arrives
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g"), Alt: true})
+	assert.Equal(t, "", model.textInput.Value())
+
+	updated, _ := model.Update(gitStatusMsg{status: &git.RepoStatus{RepoName: "gsh", Branch: "feature/x"}})
+	model = updated.(appModel)
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("git push origin  -f")})
+	for range len(" -f") {
+		press(tea.KeyMsg{Type: tea.KeyLeft})
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g"), Alt: true})
+	assert.Equal(t, "git push origin feature/x -f", model.textInput.Value())
+
+	model.textInput.SetValue("cd ~/src/")
+	model.textInput.CursorEnd()
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G"), Alt: true})
+	assert.Equal(t, "cd ~/src/gsh", model.textInput.Value())
+
+	// A detached HEAD has no branch to insert
+	updated, _ = model.Update(gitStatusMsg{status: &git.RepoStatus{RepoName: "gsh", Branch: "(detached)"}})
+	model = updated.(appModel)
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g"), Alt: true})
+	assert.Equal(t, "cd ~/src/gsh", model.textInput.Value())
+}
@@ -7,7 +7,9 @@ import (
 	"strings"
 
 	"github.com/atinylittleshell/gsh/internal/git"
+	"github.com/atinylittleshell/gsh/internal/risk"
 	"github.com/atinylittleshell/gsh/internal/system"
+	"github.com/atinylittleshell/gsh/pkg/textwidth"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -37,8 +39,8 @@ type BorderStatusModel struct {
 
 	// Input State
 	commandBuffer string
+	prediction    string
 	kind          CommandKind
-	riskScore     int
 	riskLevel     RiskLevel
 
 	// Context State
@@ -47,9 +49,17 @@ type BorderStatusModel struct {
 	cwd       string
 	gitStatus *git.RepoStatus
 
+	// Session State
+	shellLevel int  // how deeply gsh is nested, 1 for the outermost shell
+	sshSession bool // whether the shell runs over SSH
+
 	// Resource State
 	resources *system.Resources
 
+	// Async segments, by name, in the order they're shown
+	segmentNames []string
+	segments     map[string]string
+
 	// Styles
 	styles BorderStyles
 }
@@ -59,6 +69,7 @@ type BorderStyles struct {
 	BadgeAgent   lipgloss.Style
 	BadgeControl lipgloss.Style
 	BadgeSub     lipgloss.Style
+	BadgeSession lipgloss.Style
 
 	RiskCalm    lipgloss.Style
 	RiskWarning lipgloss.Style
@@ -81,6 +92,7 @@ func NewBorderStatusModel() BorderStatusModel {
 		BadgeAgent:   lipgloss.NewStyle().Foreground(lipgloss.Color("75")),  // blue
 		BadgeControl: lipgloss.NewStyle().Foreground(lipgloss.Color("208")), // orange
 		BadgeSub:     lipgloss.NewStyle().Foreground(lipgloss.Color("141")), // purple
+		BadgeSession: lipgloss.NewStyle().Foreground(lipgloss.Color("179")), // gold
 
 		RiskCalm:    lipgloss.NewStyle().Foreground(lipgloss.Color("77")),  // green
 		RiskWarning: lipgloss.NewStyle().Foreground(lipgloss.Color("214")), // amber
@@ -115,12 +127,27 @@ func (m *BorderStatusModel) UpdateInput(input string) {
 	m.computeRisk()
 }
 
+// UpdatePrediction sets the predicted command, so its risk shows before it's accepted
+func (m *BorderStatusModel) UpdatePrediction(prediction string) {
+	if m.prediction == prediction {
+		return
+	}
+	m.prediction = prediction
+	m.computeRisk()
+}
+
 func (m *BorderStatusModel) UpdateContext(user, host, cwd string) {
 	m.user = user
 	m.host = host
 	m.cwd = cwd
 }
 
+// UpdateSession sets the nesting level and whether the shell runs over SSH
+func (m *BorderStatusModel) UpdateSession(shellLevel int, sshSession bool) {
+	m.shellLevel = shellLevel
+	m.sshSession = sshSession
+}
+
 func (m *BorderStatusModel) UpdateGit(status *git.RepoStatus) {
 	m.gitStatus = status
 }
@@ -129,6 +156,21 @@ func (m *BorderStatusModel) UpdateResources(res *system.Resources) {
 	m.resources = res
 }
 
+// SetSegments sets the names of the async segments in the order they're shown.
+// They stay empty until UpdateSegment gives them a value.
+func (m *BorderStatusModel) SetSegments(names []string) {
+	m.segmentNames = names
+	m.segments = make(map[string]string, len(names))
+}
+
+// UpdateSegment sets the text of an async segment
+func (m *BorderStatusModel) UpdateSegment(name, value string) {
+	if m.segments == nil {
+		m.segments = make(map[string]string)
+	}
+	m.segments[name] = value
+}
+
 func (m *BorderStatusModel) classifyCommand() {
 	input := strings.TrimSpace(m.commandBuffer)
 	if strings.HasPrefix(input, "@!") {
@@ -169,45 +211,24 @@ func (m *BorderStatusModel) classifyCommand() {
 	}
 }
 
+// computeRisk rates the risk of running the shell command being typed or the
+// prediction that would be accepted, whichever is riskier
 func (m *BorderStatusModel) computeRisk() {
-	// Simple heuristic implementation
-	score := 0
-	input := m.commandBuffer
-
-	// Destructive flags
-	if strings.Contains(input, "rm -rf") || strings.Contains(input, ":(){") || strings.Contains(input, "mkfs") {
-		score += 5
-	}
-	if strings.Contains(input, "dd if=") || strings.Contains(input, "chmod -R") || strings.Contains(input, "chown -R") {
-		score += 3
-	}
-
-	// Privilege
-	if strings.HasPrefix(input, "sudo") || strings.HasPrefix(input, "doas") || strings.HasPrefix(input, "su -") {
-		score += 2
-	}
-
-	// Network
-	if strings.Contains(input, "curl") || strings.Contains(input, "wget") || strings.Contains(input, "ssh") || strings.Contains(input, "scp") {
-		score += 1
-	}
-
-	// Safe signals
-	if strings.HasPrefix(input, "echo") || strings.HasPrefix(input, "ls") || strings.HasPrefix(input, "pwd") {
-		score -= 1
-	}
-
-	if score < 0 {
-		score = 0
+	level := risk.Safe
+	if m.kind == KindRawShell {
+		level = risk.ClassifyRisk(m.commandBuffer)
+		if predicted := risk.ClassifyRisk(m.prediction); predicted > level {
+			level = predicted
+		}
 	}
-	m.riskScore = score
 
-	if score <= 2 {
-		m.riskLevel = RiskCalm
-	} else if score <= 5 {
-		m.riskLevel = RiskWarning
-	} else {
+	switch level {
+	case risk.Dangerous:
 		m.riskLevel = RiskAlert
+	case risk.Caution:
+		m.riskLevel = RiskWarning
+	default:
+		m.riskLevel = RiskCalm
 	}
 }
 
@@ -221,6 +242,13 @@ func (m BorderStatusModel) RenderTopLeft() string {
 	case KindRawShell:
 		badge = "$"
 		style = m.styles.BadgeRaw
+		// Color the badge too, so a risky command stands out at a glance
+		switch m.riskLevel {
+		case RiskWarning:
+			style = m.styles.RiskWarning
+		case RiskAlert:
+			style = m.styles.RiskAlert
+		}
 	case KindAgentChat:
 		badge = "🤖" // or @
 		style = m.styles.BadgeAgent
@@ -258,7 +286,25 @@ func (m BorderStatusModel) RenderTopLeft() string {
 	}
 
 	// Add space to the left of the badge for consistent spacing
-	return " " + style.Render(badge) + " " + riskStyle.Render(riskBar) + " "
+	result := " " + style.Render(badge) + " " + riskStyle.Render(riskBar) + " "
+	if session := m.sessionBadge(); session != "" {
+		result += m.styles.BadgeSession.Render(session) + " "
+	}
+	return result
+}
+
+// sessionBadge tells nested and remote shells apart, e.g. "L2 SSH" for a gsh
+// started from another gsh on a remote machine. It's empty for a local
+// outermost shell.
+func (m BorderStatusModel) sessionBadge() string {
+	var parts []string
+	if m.shellLevel > 1 {
+		parts = append(parts, fmt.Sprintf("L%d", m.shellLevel))
+	}
+	if m.sshSession {
+		parts = append(parts, "SSH")
+	}
+	return strings.Join(parts, " ")
 }
 
 // TopLeftWidth returns the actual display width of the top-left section.
@@ -287,7 +333,13 @@ func (m BorderStatusModel) TopLeftWidth() int {
 	}
 
 	// Total: leading space + badge + space + riskBar + trailing space
-	return 1 + badgeWidth + 1 + riskBarWidth + 1
+	width := 1 + badgeWidth + 1 + riskBarWidth + 1
+
+	// Session badge is plain ASCII followed by a space
+	if session := m.sessionBadge(); session != "" {
+		width += len(session) + 1
+	}
+	return width
 }
 
 func (m BorderStatusModel) RenderTopContext(maxWidth int) string {
@@ -382,7 +434,7 @@ func (m BorderStatusModel) RenderTopContext(maxWidth int) string {
 	// Calculate widths
 	totalContentWidth := 0
 	for _, item := range items {
-		totalContentWidth += lipgloss.Width(item)
+		totalContentWidth += textwidth.StringWidth(item)
 	}
 
 	// Git status expansion logic removed - git status is now shown as icons with directory
@@ -411,7 +463,7 @@ func (m BorderStatusModel) RenderTopContext(maxWidth int) string {
 	checkFit := func(indices []int) bool {
 		w := 0
 		for _, i := range indices {
-			w += lipgloss.Width(items[i])
+			w += textwidth.StringWidth(items[i])
 		}
 		// Need gaps.
 		// Distribute logic: LeftAnchor --[gap]-- Item1 --[gap]-- Item2 ...
@@ -451,7 +503,7 @@ func (m BorderStatusModel) RenderTopContext(maxWidth int) string {
 
 	contentWidth := 0
 	for _, i := range activeIndices {
-		contentWidth += lipgloss.Width(items[i])
+		contentWidth += textwidth.StringWidth(items[i])
 	}
 
 	totalSpace := maxWidth - contentWidth
@@ -501,6 +553,30 @@ func (m BorderStatusModel) RenderTopContext(maxWidth int) string {
 	return sb.String()
 }
 
+// RenderTopRight renders the async segments that have a value, separated by
+// dividers. Segments are dropped from the left until the rest fit maxWidth.
+func (m BorderStatusModel) RenderTopRight(maxWidth int) string {
+	var values []string
+	for _, name := range m.segmentNames {
+		if value := m.segments[name]; value != "" {
+			values = append(values, value)
+		}
+	}
+
+	for len(values) > 0 {
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = m.styles.ContextGit.Render(value)
+		}
+		rendered := " " + strings.Join(parts, " "+m.styles.Divider.Render("│")+" ") + " "
+		if textwidth.StringWidth(rendered) <= maxWidth {
+			return rendered
+		}
+		values = values[1:]
+	}
+	return ""
+}
+
 func (m BorderStatusModel) RenderBottomLeft() string {
 	if m.resources == nil {
 		return m.styles.ResLabel.Render("C: --% R: --%")
@@ -0,0 +1,73 @@
+package gline
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBorderStatusSessionBadge(t *testing.T) {
+	m := NewBorderStatusModel()
+	m.UpdateSession(1, false)
+	assert.NotContains(t, m.RenderTopLeft(), "L1", "The outermost local shell has no badge")
+	assert.Equal(t, lipgloss.Width(m.RenderTopLeft()), m.TopLeftWidth())
+
+	m.UpdateSession(2, false)
+	assert.Contains(t, m.RenderTopLeft(), "L2")
+	assert.Equal(t, lipgloss.Width(m.RenderTopLeft()), m.TopLeftWidth())
+
+	m.UpdateSession(3, true)
+	assert.Contains(t, m.RenderTopLeft(), "L3 SSH")
+	assert.Equal(t, lipgloss.Width(m.RenderTopLeft()), m.TopLeftWidth())
+
+	m.UpdateSession(1, true)
+	assert.Contains(t, m.RenderTopLeft(), "SSH")
+	assert.NotContains(t, m.RenderTopLeft(), "L1")
+}
+
+func TestBorderStatusRisk(t *testing.T) {
+	m := NewBorderStatusModel()
+
+	m.UpdateInput("ls -la")
+	assert.Equal(t, RiskCalm, m.riskLevel)
+
+	m.UpdateInput("mv a b")
+	assert.Equal(t, RiskWarning, m.riskLevel)
+
+	m.UpdateInput("rm -rf build")
+	assert.Equal(t, RiskAlert, m.riskLevel)
+	assert.Equal(t, lipgloss.Width(m.RenderTopLeft()), m.TopLeftWidth())
+
+	m.UpdateInput("@ rm -rf build for me")
+	assert.Equal(t, RiskCalm, m.riskLevel, "Agent chat isn't run as a shell command")
+}
+
+func TestBorderStatusRiskIncludesPrediction(t *testing.T) {
+	m := NewBorderStatusModel()
+
+	m.UpdateInput("git ")
+	assert.Equal(t, RiskCalm, m.riskLevel)
+
+	m.UpdatePrediction("git reset --hard HEAD~1")
+	assert.Equal(t, RiskAlert, m.riskLevel)
+
+	m.UpdatePrediction("")
+	assert.Equal(t, RiskCalm, m.riskLevel)
+}
+
+func TestBorderStatusTopRight(t *testing.T) {
+	m := NewBorderStatusModel()
+	m.SetSegments([]string{"kube", "aws"})
+	assert.Equal(t, "", m.RenderTopRight(80), "segments without a value are hidden")
+
+	m.UpdateSegment("aws", "prod")
+	assert.Equal(t, " prod ", m.RenderTopRight(80))
+
+	m.UpdateSegment("kube", "minikube")
+	assert.Equal(t, " minikube │ prod ", m.RenderTopRight(80))
+
+	// The leftmost segments are dropped first when there's no room
+	assert.Equal(t, " prod ", m.RenderTopRight(10))
+	assert.Equal(t, "", m.RenderTopRight(3))
+}
@@ -0,0 +1,99 @@
+package gline
+
+import (
+	"sync"
+
+	"github.com/atinylittleshell/gsh/internal/crash"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// crashCapture wraps the app model when crash reports are on. Panics in
+// Update, View and the commands they return quit the program instead of
+// being printed by bubbletea, so GlineWithResult can raise them again for the
+// crash reporter once the terminal is restored.
+type crashCapture struct {
+	model tea.Model
+	state *crashState
+}
+
+// crashState is shared by the copies of crashCapture bubbletea makes
+type crashState struct {
+	mu      sync.Mutex
+	panic   *crash.Panic
+	program *tea.Program
+}
+
+// crashMsg carries a panic from a command's goroutine to Update
+type crashMsg struct {
+	panic *crash.Panic
+}
+
+func (s *crashState) record(p *crash.Panic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.panic == nil {
+		s.panic = p
+	}
+}
+
+func (s *crashState) captured() *crash.Panic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.panic
+}
+
+// wrap makes cmd report a panic as a crashMsg, including the commands of a
+// batch it returns
+func (s *crashState) wrap(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() (msg tea.Msg) {
+		defer func() {
+			if p := crash.Capture(recover()); p != nil {
+				msg = crashMsg{panic: p}
+			}
+		}()
+		msg = cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			wrapped := make(tea.BatchMsg, len(batch))
+			for i, cmd := range batch {
+				wrapped[i] = s.wrap(cmd)
+			}
+			return wrapped
+		}
+		return msg
+	}
+}
+
+func (c crashCapture) Init() tea.Cmd {
+	return c.state.wrap(c.model.Init())
+}
+
+func (c crashCapture) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	if msg, ok := msg.(crashMsg); ok {
+		c.state.record(msg.panic)
+		return c, tea.Quit
+	}
+
+	defer func() {
+		if p := crash.Capture(recover()); p != nil {
+			c.state.record(p)
+			model, cmd = c, tea.Quit
+		}
+	}()
+	next, cmd := c.model.Update(msg)
+	return crashCapture{model: next, state: c.state}, c.state.wrap(cmd)
+}
+
+func (c crashCapture) View() (view string) {
+	defer func() {
+		if p := crash.Capture(recover()); p != nil {
+			c.state.record(p)
+			view = ""
+			// View runs in the event loop, which Quit waits on
+			go c.state.program.Quit()
+		}
+	}()
+	return c.model.View()
+}
@@ -0,0 +1,69 @@
+package gline
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type panickyModel struct {
+	cmd tea.Cmd
+}
+
+func (m panickyModel) Init() tea.Cmd { return m.cmd }
+
+func (m panickyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg == "panic" {
+		panic("update failed")
+	}
+	return m, m.cmd
+}
+
+func (m panickyModel) View() string { return "view" }
+
+func isQuit(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+func TestCrashCaptureUpdate(t *testing.T) {
+	state := &crashState{}
+	model := crashCapture{model: panickyModel{}, state: state}
+
+	next, cmd := model.Update("ok")
+	assert.IsType(t, crashCapture{}, next)
+	assert.Nil(t, cmd)
+	assert.Nil(t, state.captured())
+
+	_, cmd = model.Update("panic")
+	assert.True(t, isQuit(cmd))
+	require.NotNil(t, state.captured())
+	assert.Equal(t, "update failed", state.captured().Value)
+	assert.Contains(t, string(state.captured().Stack), "panickyModel.Update")
+}
+
+func TestCrashCaptureCommands(t *testing.T) {
+	state := &crashState{}
+	failing := func() tea.Msg { panic("command failed") }
+	model := crashCapture{model: panickyModel{cmd: tea.Batch(failing, func() tea.Msg { return "done" })}, state: state}
+
+	// The commands of a batch are wrapped too
+	batch, ok := model.Init()().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "done", batch[1]())
+
+	msg := batch[0]()
+	require.IsType(t, crashMsg{}, msg)
+	assert.Nil(t, state.captured())
+
+	// The panic is recorded once it reaches Update, which quits
+	_, cmd := model.Update(msg)
+	assert.True(t, isQuit(cmd))
+	assert.Equal(t, "command failed", state.captured().Value)
+}
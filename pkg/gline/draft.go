@@ -0,0 +1,137 @@
+package gline
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"go.uber.org/zap"
+)
+
+// draftSaveInterval is how often the line being typed is saved to the draft file
+const draftSaveInterval = 2 * time.Second
+
+// Draft is a command that was being typed, saved so it survives a crash or a
+// closed terminal
+type Draft struct {
+	// Lines are the finished lines of a multiline command
+	Lines []string `json:"lines,omitempty"`
+	// Input is the line being edited
+	Input   string    `json:"input"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// Command returns the draft as it would run, with its lines joined by newlines
+func (d Draft) Command() string {
+	return strings.Join(append(append([]string{}, d.Lines...), d.Input), "\n")
+}
+
+// LoadDraft reads the draft saved at path, if there is one
+func LoadDraft(path string) (Draft, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Draft{}, false
+	}
+	var draft Draft
+	if err := json.Unmarshal(data, &draft); err != nil || strings.TrimSpace(draft.Command()) == "" {
+		return Draft{}, false
+	}
+	return draft, true
+}
+
+// SaveDraft writes draft to path, replacing the file at once so a crash while
+// saving doesn't leave half a draft
+func SaveDraft(path string, draft Draft) error {
+	data, err := json.Marshal(draft)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ClearDraft removes the draft saved at path
+func ClearDraft(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+type saveDraftMsg struct{}
+
+func scheduleDraftSave() tea.Cmd {
+	return tea.Tick(draftSaveInterval, func(time.Time) tea.Msg {
+		return saveDraftMsg{}
+	})
+}
+
+// currentDraft returns what has been typed so far
+func (m appModel) currentDraft() Draft {
+	var lines []string
+	if m.multilineState.IsActive() {
+		lines = m.multilineState.GetLines()
+	}
+	return Draft{Lines: lines, Input: m.textInput.Value()}
+}
+
+// saveDraft saves the line being typed to the draft file when it changed, and
+// removes the file once the line is blank again
+func (m appModel) saveDraft() (appModel, tea.Cmd) {
+	if m.appState != Active {
+		return m, nil
+	}
+
+	draft := m.currentDraft()
+	command := draft.Command()
+	if command != m.savedDraft {
+		var err error
+		if strings.TrimSpace(command) == "" {
+			err = ClearDraft(m.options.DraftFile)
+		} else {
+			draft.SavedAt = time.Now()
+			err = SaveDraft(m.options.DraftFile, draft)
+		}
+		if err != nil {
+			m.logger.Warn("failed to save the input draft", zap.Error(err))
+		} else {
+			m.savedDraft = command
+		}
+	}
+	return m, scheduleDraftSave()
+}
+
+// restoreDraft puts the recovered draft back in the buffer, replacing what's there
+func (m appModel) restoreDraft() (appModel, tea.Cmd) {
+	draft := m.options.RecoveredDraft
+	m.draftRestored = true
+
+	m.multilineState.Reset()
+	m.recalledMultiline = false
+	m.textInput.Prompt = m.originalPrompt
+	if len(draft.Lines) > 0 {
+		m.multilineState.LoadLines(draft.Lines)
+		m.textInput.Prompt = m.multilineState.ContinuationPrompt() + " "
+	}
+
+	// The offer is done with once the draft is back
+	m.defaultExplanation = ""
+	m.explanation = ""
+	m.textInput.SetValue("")
+	// Typed in like a paste, so it's predicted and explained like any input
+	return m.updateTextInput(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(draft.Input), Paste: true})
+}
+
+// draftOffer is shown in the assistant box while a recovered draft can be restored
+func draftOffer(draft *Draft) string {
+	return "📝 Unsaved command from an earlier session, Alt+R to restore it:\n" + draft.Command()
+}
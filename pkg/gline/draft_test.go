@@ -0,0 +1,78 @@
+package gline
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDraftFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drafts", "1.json")
+
+	_, ok := LoadDraft(path)
+	assert.False(t, ok)
+
+	saved := Draft{Lines: []string{"for f in *; do"}, Input: "echo $f", SavedAt: time.Now().Round(time.Second)}
+	require.NoError(t, SaveDraft(path, saved))
+	loaded, ok := LoadDraft(path)
+	require.True(t, ok)
+	assert.Equal(t, "for f in *; do\necho $f", loaded.Command())
+	assert.True(t, saved.SavedAt.Equal(loaded.SavedAt))
+
+	require.NoError(t, ClearDraft(path))
+	require.NoError(t, ClearDraft(path))
+	_, ok = LoadDraft(path)
+	assert.False(t, ok)
+}
+
+func TestDraftAutosave(t *testing.T) {
+	options := NewOptions()
+	options.DraftFile = filepath.Join(t.TempDir(), "1.json")
+	model := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options)
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("echo one \\")})
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("two")})
+	updated, cmd := updated.(appModel).Update(saveDraftMsg{})
+	assert.NotNil(t, cmd, "saving is scheduled again")
+
+	draft, ok := LoadDraft(options.DraftFile)
+	require.True(t, ok)
+	assert.Equal(t, []string{"echo one \\"}, draft.Lines)
+	assert.Equal(t, "two", draft.Input)
+
+	// Once the line is blank there's nothing to recover
+	model = initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options)
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ls")})
+	updated, _ = updated.(appModel).Update(saveDraftMsg{})
+	_, ok = LoadDraft(options.DraftFile)
+	require.True(t, ok)
+
+	updated, _ = updated.(appModel).Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	_, _ = updated.(appModel).Update(saveDraftMsg{})
+	_, ok = LoadDraft(options.DraftFile)
+	assert.False(t, ok)
+}
+
+func TestRestoreRecoveredDraft(t *testing.T) {
+	options := NewOptions()
+	options.RecoveredDraft = &Draft{Lines: []string{"for f in *; do", "echo $f"}, Input: "done"}
+	sized, _ := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), options).Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	model := sized.(appModel)
+	assert.Contains(t, model.View(), "Alt+R to restore")
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r"), Alt: true})
+	model = updated.(appModel)
+	assert.Equal(t, "done", model.textInput.Value())
+	assert.NotContains(t, model.View(), "Alt+R to restore")
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	result := updated.(appModel).glineResult()
+	assert.Equal(t, "for f in *; do\necho $f\ndone", result.Command)
+	assert.True(t, result.Multiline)
+}
@@ -1,11 +1,13 @@
 package gline
 
+import "context"
+
 type Explainer interface {
-	Explain(input string) (string, error)
+	Explain(ctx context.Context, input string) (string, error)
 }
 
 type NoopExplainer struct{}
 
-func (e *NoopExplainer) Explain(input string) (string, error) {
+func (e *NoopExplainer) Explain(ctx context.Context, input string) (string, error) {
 	return "", nil
 }
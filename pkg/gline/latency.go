@@ -0,0 +1,73 @@
+package gline
+
+import (
+	"sync"
+	"time"
+)
+
+// LatencyStats summarizes how long a kind of LLM call has taken
+type LatencyStats struct {
+	Count  int
+	Errors int
+	Total  time.Duration
+	Min    time.Duration
+	Max    time.Duration
+	Last   time.Duration
+}
+
+// Average returns the mean duration of all recorded calls
+func (s LatencyStats) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+func (s *LatencyStats) record(duration time.Duration, err error) {
+	if s.Count == 0 || duration < s.Min {
+		s.Min = duration
+	}
+	if duration > s.Max {
+		s.Max = duration
+	}
+	s.Count++
+	s.Total += duration
+	s.Last = duration
+	if err != nil {
+		s.Errors++
+	}
+}
+
+// sessionLatency lives for the whole shell session, across Gline invocations
+var sessionLatency struct {
+	mu          sync.Mutex
+	prediction  LatencyStats
+	explanation LatencyStats
+}
+
+func recordPredictionLatency(duration time.Duration, err error) {
+	sessionLatency.mu.Lock()
+	defer sessionLatency.mu.Unlock()
+	sessionLatency.prediction.record(duration, err)
+}
+
+func recordExplanationLatency(duration time.Duration, err error) {
+	sessionLatency.mu.Lock()
+	defer sessionLatency.mu.Unlock()
+	sessionLatency.explanation.record(duration, err)
+}
+
+// SessionLatencyStats returns the latency of predictions and explanations made so far in this session
+func SessionLatencyStats() (prediction LatencyStats, explanation LatencyStats) {
+	sessionLatency.mu.Lock()
+	defer sessionLatency.mu.Unlock()
+	return sessionLatency.prediction, sessionLatency.explanation
+}
+
+// ResetSessionLatencyStats clears the accumulated latency stats
+func ResetSessionLatencyStats() {
+	sessionLatency.mu.Lock()
+	defer sessionLatency.mu.Unlock()
+	sessionLatency.prediction = LatencyStats{}
+	sessionLatency.explanation = LatencyStats{}
+}
@@ -0,0 +1,35 @@
+package gline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionLatencyStats(t *testing.T) {
+	ResetSessionLatencyStats()
+	t.Cleanup(ResetSessionLatencyStats)
+
+	recordPredictionLatency(100*time.Millisecond, nil)
+	recordPredictionLatency(300*time.Millisecond, errors.New("timeout"))
+	recordExplanationLatency(50*time.Millisecond, nil)
+
+	prediction, explanation := SessionLatencyStats()
+
+	assert.Equal(t, 2, prediction.Count)
+	assert.Equal(t, 1, prediction.Errors)
+	assert.Equal(t, 100*time.Millisecond, prediction.Min)
+	assert.Equal(t, 300*time.Millisecond, prediction.Max)
+	assert.Equal(t, 300*time.Millisecond, prediction.Last)
+	assert.Equal(t, 200*time.Millisecond, prediction.Average())
+
+	assert.Equal(t, 1, explanation.Count)
+	assert.Equal(t, 50*time.Millisecond, explanation.Average())
+
+	ResetSessionLatencyStats()
+	prediction, _ = SessionLatencyStats()
+	assert.Equal(t, 0, prediction.Count)
+	assert.Equal(t, time.Duration(0), prediction.Average())
+}
@@ -1,6 +1,7 @@
 package gline
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -59,3 +60,70 @@ func TestViewTruncation(t *testing.T) {
 	assert.NotContains(t, view, "Line 4")
 	assert.NotContains(t, view, "Line 5")
 }
+
+func TestViewFitsShortTerminals(t *testing.T) {
+	options := NewOptions()
+	options.AssistantHeight = 3
+
+	// The prompt and the full box need 1 + 3 + 2 lines
+	for height := 1; height <= 8; height++ {
+		model := initialModel("gsh> ", []string{}, "", nil, nil, nil, zap.NewNop(), options)
+		model.height = height
+		model.textInput.Width = 80
+		model.explanation = "Shows the working tree status"
+
+		view := model.View()
+		assert.LessOrEqual(t, strings.Count(view, "\n")+1, height, "view is taller than a terminal of %d lines", height)
+		assert.Contains(t, view, "gsh> ")
+	}
+}
+
+func TestViewCollapsesProgressively(t *testing.T) {
+	options := NewOptions()
+	options.AssistantHeight = 3
+
+	render := func(height int, explanation string, coachTip bool) string {
+		model := initialModel("gsh> ", []string{}, "", nil, nil, nil, zap.NewNop(), options)
+		model.height = height
+		model.textInput.Width = 80
+		model.explanation = explanation
+		if coachTip {
+			model.defaultExplanation = explanation
+		}
+		return model.View()
+	}
+
+	// Tall enough for everything
+	assert.Contains(t, render(6, "Try z to jump", true), "Try z to jump")
+
+	// Coach tips go first, while explanations still get the lines left
+	assert.NotContains(t, render(5, "Try z to jump", true), "Try z to jump")
+	assert.Contains(t, render(5, "Shows the status", false), "Shows the status")
+	assert.Contains(t, render(4, "Shows the status", false), "Shows the status")
+
+	// Then the content, leaving the borders
+	view := render(3, "Shows the status", false)
+	assert.NotContains(t, view, "Shows the status")
+	assert.Contains(t, view, "╰")
+
+	// Then all but the top border
+	view = render(2, "Shows the status", false)
+	assert.Contains(t, view, "╭")
+	assert.NotContains(t, view, "╰")
+
+	// And finally the whole box
+	assert.NotContains(t, render(1, "Shows the status", false), "╭")
+}
+
+func TestViewShortTerminalCountsMultilinePrompt(t *testing.T) {
+	options := NewOptions()
+	options.AssistantHeight = 3
+
+	model := initialModel("gsh> ", []string{}, "", nil, nil, nil, zap.NewNop(), options)
+	model.height = 6
+	model.textInput.Width = 80
+	model.multilineState.LoadLines([]string{"for f in *; do", "  echo $f"})
+
+	view := model.View()
+	assert.LessOrEqual(t, strings.Count(view, "\n")+1, 6)
+}
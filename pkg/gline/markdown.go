@@ -0,0 +1,71 @@
+package gline
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	markdownBoldStyle = lipgloss.NewStyle().Bold(true)
+	markdownCodeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+)
+
+// RenderMarkdown styles the lightweight markdown LLMs like to use in
+// explanations: **bold**, `inline code`, and bullets starting with "- " or
+// "* ". Anything else is left as is. Each word is styled on its own, so
+// WordwrapWithRuneWidth can break a styled span across lines without the
+// style running into the border of the box.
+func RenderMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		content := strings.TrimLeft(line, " ")
+		indent := line[:len(line)-len(content)]
+		if (strings.HasPrefix(content, "- ") || strings.HasPrefix(content, "* ")) && !strings.HasPrefix(content, "**") {
+			content = "• " + content[2:]
+		}
+		lines[i] = indent + renderMarkdownInline(content)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderMarkdownInline styles the bold and code spans of a line. A marker
+// without its closing pair is kept as a literal character.
+func renderMarkdownInline(line string) string {
+	var sb strings.Builder
+	for line != "" {
+		if strings.HasPrefix(line, "`") {
+			if end := strings.Index(line[1:], "`"); end > 0 {
+				sb.WriteString(styleWords(markdownCodeStyle, line[1:1+end]))
+				line = line[end+2:]
+				continue
+			}
+		} else if strings.HasPrefix(line, "**") {
+			if end := strings.Index(line[2:], "**"); end > 0 {
+				sb.WriteString(styleWords(markdownBoldStyle, line[2:2+end]))
+				line = line[end+4:]
+				continue
+			}
+		}
+
+		next := strings.IndexAny(line[1:], "`*")
+		if next < 0 {
+			sb.WriteString(line)
+			break
+		}
+		sb.WriteString(line[:next+1])
+		line = line[next+1:]
+	}
+	return sb.String()
+}
+
+// styleWords renders each space-separated word of s with style
+func styleWords(style lipgloss.Style, s string) string {
+	words := strings.Split(s, " ")
+	for i, word := range words {
+		if word != "" {
+			words[i] = style.Render(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
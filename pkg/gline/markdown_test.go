@@ -0,0 +1,64 @@
+package gline
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atinylittleshell/gsh/pkg/textwidth"
+)
+
+var sgrPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripStyles(s string) string {
+	return sgrPattern.ReplaceAllString(s, "")
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "lists the files", "lists the files"},
+		{"bold", "**Deletes** the branch", "Deletes the branch"},
+		{"inline code", "runs `make test` first", "runs make test first"},
+		{"bullets", "Steps:\n- fetch\n  * rebase", "Steps:\n• fetch\n  • rebase"},
+		{"bold line is not a bullet", "**Note** careful", "Note careful"},
+		{"unclosed markers", "a * b and `c", "a * b and `c"},
+		{"empty spans", "**** and ``", "**** and ``"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, stripStyles(RenderMarkdown(tt.input)))
+		})
+	}
+
+	rendered := RenderMarkdown("**Deletes** `git branch -D`")
+	assert.Contains(t, rendered, "\x1b[")
+	assert.Contains(t, rendered, markdownBoldStyle.Render("Deletes"))
+	// Each word is styled on its own, so wrapping never splits a style
+	for _, word := range []string{"git", "branch", "-D"} {
+		assert.Contains(t, rendered, markdownCodeStyle.Render(word))
+	}
+}
+
+func TestRenderMarkdownWraps(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+
+	wrapped := WordwrapWithRuneWidth(RenderMarkdown("run `go test ./...` to check **every single package**"), 12)
+	for _, line := range strings.Split(wrapped, "\n") {
+		assert.LessOrEqual(t, textwidth.StringWidth(line), 12, line)
+	}
+	assert.Equal(t, "run go test\n./... to\ncheck every\nsingle\npackage", stripStyles(wrapped))
+}
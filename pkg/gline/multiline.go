@@ -134,6 +134,19 @@ func (m *MultilineState) GetCompleteCommand() string {
 	return result
 }
 
+// LoadLines replaces the buffer with lines that were entered earlier and waits
+// for the final line. This is used to restore a multiline command from history.
+func (m *MultilineState) LoadLines(lines []string) {
+	m.Reset()
+	m.buffer.WriteString(strings.Join(lines, "\n"))
+	m.isContinuation = len(lines) > 0
+}
+
+// ContinuationPrompt returns the prompt shown while waiting for more lines
+func (m *MultilineState) ContinuationPrompt() string {
+	return m.continuationChar
+}
+
 // Reset clears the multiline state
 func (m *MultilineState) Reset() {
 	m.buffer.Reset()
@@ -4,7 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestMultilineState_BasicMultiline(t *testing.T) {
@@ -674,3 +676,33 @@ func TestCommandSubstitutionEdgeCases(t *testing.T) {
 		}
 	}
 }
+
+func TestMultilineHistoryRecall(t *testing.T) {
+	model := initialModel("gsh> ", []string{"echo single", "cat <<EOF\nhello\nEOF"}, "", nil, nil, nil, zap.NewNop(), NewOptions())
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyUp})
+	model = updated.(appModel)
+	assert.Equal(t, "echo single", model.textInput.Value())
+	assert.False(t, model.multilineState.IsActive())
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyUp})
+	model = updated.(appModel)
+	assert.Equal(t, "EOF", model.textInput.Value())
+	assert.Equal(t, []string{"cat <<EOF", "hello"}, model.multilineState.GetLines())
+	assert.Equal(t, "> ", model.textInput.Prompt)
+	assert.Contains(t, model.View(), "gsh> cat <<EOF\n> hello\n")
+
+	// Navigating away drops the recalled lines and restores the prompt
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model = updated.(appModel)
+	assert.Equal(t, "echo single", model.textInput.Value())
+	assert.False(t, model.multilineState.IsActive())
+	assert.Equal(t, "gsh> ", model.textInput.Prompt)
+
+	// Submitting a recalled entry produces the original multiline command
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyUp})
+	model = updated.(appModel)
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(appModel)
+	assert.Equal(t, "cat <<EOF\nhello\nEOF", model.result)
+}
@@ -2,6 +2,8 @@ package gline
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/atinylittleshell/gsh/pkg/shellinput"
 )
@@ -9,6 +11,39 @@ import (
 // IdleSummaryGenerator is a function that generates an idle summary
 type IdleSummaryGenerator func(ctx context.Context) (string, error)
 
+// AsyncSegment is a piece of the top border that's too slow to compute before
+// the prompt shows, like the kubernetes context. It's fetched in the background
+// and the border re-renders when the value arrives.
+type AsyncSegment struct {
+	Name string
+	// Fetch returns the text to show, or an empty string to show nothing. It
+	// should give up once ctx is done.
+	Fetch func(ctx context.Context) string
+	// Timeout bounds each fetch, defaultSegmentTimeout if zero
+	Timeout time.Duration
+	// Refresh fetches the segment again at this interval while the prompt is
+	// open. Zero fetches it once.
+	Refresh time.Duration
+}
+
+// AssistantPosition is where the assistant box renders relative to the prompt
+type AssistantPosition int
+
+const (
+	AssistantBelow AssistantPosition = iota
+	// AssistantAbove keeps the prompt on the last line, for terminals whose
+	// prompt sits at the bottom of the screen
+	AssistantAbove
+)
+
+// ParseAssistantPosition reads "below" (the default) or "above"
+func ParseAssistantPosition(value string) AssistantPosition {
+	if strings.EqualFold(strings.TrimSpace(value), "above") {
+		return AssistantAbove
+	}
+	return AssistantBelow
+}
+
 type Options struct {
 	// Deprecated: use AssistantHeight instead
 	MinHeight          int
@@ -19,15 +54,101 @@ type Options struct {
 	User               string
 	Host               string
 
+	// Width and Height are the terminal size when the prompt starts, so the
+	// first render already fits the terminal. Zero waits for the size that
+	// bubbletea reports.
+	Width  int
+	Height int
+
+	// ShellLevel is how deeply gsh is nested in other gsh sessions, 1 for the outermost shell
+	ShellLevel int
+	// SSHSession indicates the shell is running over SSH
+	SSHSession bool
+
+	// RightPrompt lists segments shown at the right end of the top border, in order
+	RightPrompt []AsyncSegment
+
+	// SuggestAfterKill keeps suggestions visible right after a kill command such as Ctrl+K
+	SuggestAfterKill bool
+
 	// IdleSummaryTimeout is the number of seconds of idle time before generating a summary.
 	// Set to 0 to disable idle summaries.
 	IdleSummaryTimeout int
 	// IdleSummaryGenerator is called when the user is idle to generate a summary
 	IdleSummaryGenerator IdleSummaryGenerator
+
+	// HistorySearchStyle selects between the rich list and inline incremental search for Ctrl+R
+	HistorySearchStyle shellinput.HistorySearchStyle
+
+	// SuggestCase controls how typed casing is matched against predictions
+	SuggestCase shellinput.SuggestCase
+
+	// CompletionPolicy decides between the prediction and the completion box on Tab
+	CompletionPolicy shellinput.CompletionPolicy
+
+	// AutoCompleteAfter opens the completion box without Tab once the word being
+	// typed reaches this many characters. 0 disables it.
+	AutoCompleteAfter int
+
+	// AssistantPosition places the assistant box below or above the prompt
+	AssistantPosition AssistantPosition
+
+	// FocusMode hides the assistant box, leaving only the prompt line
+	FocusMode bool
+	// FocusModeChanged is called when the user toggled focus mode, so the choice
+	// can carry over to the next prompt
+	FocusModeChanged func(enabled bool)
+
+	// PredictionTimeout bounds how long a single prediction or explanation may take.
+	// Set to 0 to wait indefinitely.
+	PredictionTimeout time.Duration
+
+	// CombinedInference asks a CombinedPredictor for the prediction and its
+	// explanation at once, instead of explaining the prediction separately
+	CombinedInference bool
+
+	// ThinkingText is shown dimmed in the assistant box while a prediction is
+	// requested and there's no prediction or explanation yet. Empty shows nothing.
+	ThinkingText string
+
+	// ExplanationMarkdown renders the bold, inline code and bullets of
+	// explanations instead of showing their markdown as is
+	ExplanationMarkdown bool
+
+	// SubstitutionPreview returns what the command substitutions of the input
+	// expand to, shown above the explanation. It's called in the background
+	// once typing pauses, and may return an empty string.
+	SubstitutionPreview func(ctx context.Context, input string) string
+
+	// PredictIgnore lists command prefixes that skip prediction and explanation
+	PredictIgnore []string
+
+	// KeyMap replaces the default key bindings, e.g. with those from ~/.inputrc
+	KeyMap *shellinput.KeyMap
+
+	// DraftFile is where the line being typed is saved every few seconds, so
+	// it can be recovered after a crash or a closed terminal. It's removed
+	// once the line is submitted or cancelled. Empty disables saving.
+	DraftFile string
+	// RecoveredDraft is a draft left by an earlier session, offered in the
+	// assistant box and put back in the buffer with Alt+R
+	RecoveredDraft *Draft
+
+	// RecordAnalytics makes Gline record the last prediction and the accepted
+	// line with its PredictionAnalytics. Callers that record the entry
+	// themselves turn it off to avoid recording it twice.
+	RecordAnalytics bool
+
+	// CrashReport makes Gline panic with the stack of a panic in the prompt
+	// once the terminal is restored, instead of letting bubbletea print it, so
+	// the caller's crash reporter can save it
+	CrashReport bool
 }
 
 func NewOptions() Options {
 	return Options{
-		AssistantHeight: 3,
+		AssistantHeight:   3,
+		PredictionTimeout: 20 * time.Second,
+		RecordAnalytics:   true,
 	}
 }
@@ -0,0 +1,46 @@
+package gline
+
+import (
+	"sync"
+	"time"
+)
+
+// PredictionContext is what a prediction was made from, for `@!predict-debug`
+type PredictionContext struct {
+	// Input is what had been typed
+	Input      string
+	Prediction string
+	// Context is what the predictor sent to the model. It's empty for
+	// predictions made from history without the model.
+	Context string
+	At      time.Time
+}
+
+// lastPredictionContext lives for the whole shell session, across Gline invocations
+var lastPredictionContext struct {
+	mu      sync.Mutex
+	context PredictionContext
+	ok      bool
+}
+
+func recordPredictionContext(context PredictionContext) {
+	lastPredictionContext.mu.Lock()
+	defer lastPredictionContext.mu.Unlock()
+	lastPredictionContext.context = context
+	lastPredictionContext.ok = true
+}
+
+// LastPredictionContext returns what the last prediction shown in this session was made from
+func LastPredictionContext() (PredictionContext, bool) {
+	lastPredictionContext.mu.Lock()
+	defer lastPredictionContext.mu.Unlock()
+	return lastPredictionContext.context, lastPredictionContext.ok
+}
+
+// ResetLastPredictionContext forgets the last prediction's context
+func ResetLastPredictionContext() {
+	lastPredictionContext.mu.Lock()
+	defer lastPredictionContext.mu.Unlock()
+	lastPredictionContext.context = PredictionContext{}
+	lastPredictionContext.ok = false
+}
@@ -0,0 +1,39 @@
+package gline
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLastPredictionContext(t *testing.T) {
+	ResetLastPredictionContext()
+	t.Cleanup(ResetLastPredictionContext)
+
+	_, ok := LastPredictionContext()
+	assert.False(t, ok)
+
+	model := initialModel("test> ", []string{}, "", nil, nil, nil, zap.NewNop(), NewOptions())
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("git st")})
+	model, _ = updated.(appModel).setPrediction(updated.(appModel).predictionStateId, "git status", "#Recent commands\ngit add .")
+
+	context, ok := LastPredictionContext()
+	assert.True(t, ok)
+	assert.Equal(t, "git st", context.Input)
+	assert.Equal(t, "git status", context.Prediction)
+	assert.Equal(t, "#Recent commands\ngit add .", context.Context)
+	assert.False(t, context.At.IsZero())
+
+	// Clearing the line doesn't hide the last prediction
+	model.textInput.SetValue("")
+	model, _ = model.setPrediction(model.predictionStateId, "", "")
+	context, _ = LastPredictionContext()
+	assert.Equal(t, "git status", context.Prediction)
+
+	// Stale predictions aren't recorded
+	model.setPrediction(model.predictionStateId-1, "ls", "stale")
+	context, _ = LastPredictionContext()
+	assert.Equal(t, "git status", context.Prediction)
+}
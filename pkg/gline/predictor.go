@@ -1,11 +1,52 @@
 package gline
 
+import (
+	"context"
+	"strings"
+)
+
 type Predictor interface {
-	Predict(input string) (string, string, error)
+	Predict(ctx context.Context, input string) (string, string, error)
+}
+
+// PredictionResult is a prediction together with its explanation
+type PredictionResult struct {
+	Prediction   string
+	Explanation  string
+	InputContext string
+}
+
+// CombinedPredictor is a Predictor that can explain its prediction in the same
+// call. An empty Explanation leaves the prediction to the Explainer.
+type CombinedPredictor interface {
+	Predictor
+	PredictAndExplain(ctx context.Context, input string) (PredictionResult, error)
+}
+
+// MultiPredictor is a Predictor that can rank several predictions, best
+// first. The ghost text starts with the first one and Alt+Down cycles through
+// the rest.
+type MultiPredictor interface {
+	Predictor
+	PredictAlternatives(ctx context.Context, input string) ([]string, string, error)
 }
 
 type NoopPredictor struct{}
 
-func (p *NoopPredictor) Predict(input string) (string, string, error) {
+func (p *NoopPredictor) Predict(ctx context.Context, input string) (string, string, error) {
 	return "", "", nil
 }
+
+// isPredictionIgnored returns whether input starts with one of the ignored
+// command prefixes followed by whitespace. The prefix has to be complete, so
+// "vi" being ignored doesn't stop "vim" from being predicted while it's typed.
+func isPredictionIgnored(input string, ignored []string) bool {
+	input = strings.TrimLeft(input, " \t")
+	for _, prefix := range ignored {
+		rest, ok := strings.CutPrefix(input, prefix)
+		if ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return true
+		}
+	}
+	return false
+}
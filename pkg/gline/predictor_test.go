@@ -0,0 +1,21 @@
+package gline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPredictionIgnored(t *testing.T) {
+	ignored := []string{"vi", "top", "git rebase -i"}
+
+	assert.True(t, isPredictionIgnored("vi ", ignored))
+	assert.True(t, isPredictionIgnored("  top -o cpu", ignored))
+	assert.True(t, isPredictionIgnored("git rebase -i HEAD~3", ignored))
+
+	// The command has to be complete before prediction stops
+	assert.False(t, isPredictionIgnored("vi", ignored))
+	assert.False(t, isPredictionIgnored("vim main.go", ignored))
+	assert.False(t, isPredictionIgnored("git rebase main", ignored))
+	assert.False(t, isPredictionIgnored("vi main.go", nil))
+}
@@ -4,64 +4,6 @@ import (
 	"testing"
 )
 
-func TestParseDSRResponse(t *testing.T) {
-	tests := []struct {
-		name     string
-		response []byte
-		expected int
-	}{
-		{
-			name:     "simple response column 2",
-			response: []byte("\x1b[1;2R"),
-			expected: 2,
-		},
-		{
-			name:     "simple response column 3",
-			response: []byte("\x1b[1;3R"),
-			expected: 3,
-		},
-		{
-			name:     "double digit row and column",
-			response: []byte("\x1b[10;15R"),
-			expected: 15,
-		},
-		{
-			name:     "column 1",
-			response: []byte("\x1b[1;1R"),
-			expected: 1,
-		},
-		{
-			name:     "empty response",
-			response: []byte{},
-			expected: -1,
-		},
-		{
-			name:     "invalid response - no ESC",
-			response: []byte("[1;2R"),
-			expected: -1,
-		},
-		{
-			name:     "invalid response - no bracket",
-			response: []byte("\x1b1;2R"),
-			expected: -1,
-		},
-		{
-			name:     "response with leading garbage",
-			response: []byte("garbage\x1b[5;7R"),
-			expected: 7,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := parseDSRResponse(tt.response)
-			if result != tt.expected {
-				t.Errorf("parseDSRResponse(%q) = %d, want %d", tt.response, result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestGetLightningBoltWidth(t *testing.T) {
 	// Get the width - should be 1 or 2 depending on terminal
 	// In test environment (non-terminal), should return default of 1
@@ -79,17 +21,6 @@ func TestGetLightningBoltWidth(t *testing.T) {
 	}
 }
 
-func TestProbeTerminalCharWidthNonTerminal(t *testing.T) {
-	// When running in a non-terminal context (like tests), probeTerminalCharWidth
-	// should return the default width of 1
-	width := probeTerminalCharWidth('⚡')
-
-	// In test environment (not a terminal), should return default of 1
-	if width != 1 {
-		t.Errorf("probeTerminalCharWidth('⚡') in non-terminal = %d, want 1", width)
-	}
-}
-
 func TestGetRuneWidthZeroWidthCharacters(t *testing.T) {
 	// Zero-width characters should return width 0
 	tests := []struct {
@@ -139,49 +70,6 @@ func TestGetRuneWidthZeroWidthCharacters(t *testing.T) {
 	}
 }
 
-func TestStringWidthWithVariationSelector(t *testing.T) {
-	// The keyboard emoji with variation selector should be calculated correctly
-	// ⌨️ = U+2328 (KEYBOARD) + U+FE0F (VARIATION SELECTOR-16)
-	// The variation selector should have width 0
-	keyboard := "⌨️"
-
-	width := stringWidthWithAnsi(keyboard)
-
-	// Width should be 1 or 2 (depending on terminal), not 2 or 3
-	// In test environment (non-terminal), keyboard base char has width 1 per runewidth
-	// Variation selector has width 0
-	// So total should be 1
-	if width != 1 {
-		t.Errorf("stringWidthWithAnsi(%q) = %d, want 1 (in test environment)", keyboard, width)
-	}
-}
-
-func TestCoachTipWithEmojiAlignment(t *testing.T) {
-	// Simulate the coach tip scenario that was causing misalignment
-	// Line 1: "⌨️ Alias for exit" (keyboard emoji with variation selector)
-	// Line 2: "You use 'exit' often."
-	// Both lines should calculate width correctly for right-alignment
-
-	line1 := "⌨️ Alias for exit"
-	line2 := "You use 'exit' often."
-
-	width1 := stringWidthWithAnsi(line1)
-	width2 := stringWidthWithAnsi(line2)
-
-	// In test environment:
-	// Line 1: ⌨(1) + ️(0) + space(1) + "Alias for exit"(14) = 16
-	// Line 2: "You use 'exit' often." = 21
-	expectedWidth1 := 16
-	expectedWidth2 := 21
-
-	if width1 != expectedWidth1 {
-		t.Errorf("stringWidthWithAnsi(%q) = %d, want %d", line1, width1, expectedWidth1)
-	}
-	if width2 != expectedWidth2 {
-		t.Errorf("stringWidthWithAnsi(%q) = %d, want %d", line2, width2, expectedWidth2)
-	}
-}
-
 func TestWordwrapWithRuneWidth(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,48 @@
+package shellinput
+
+import (
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// autoCompleteDelay is how long typing has to pause before AutoCompleteAfter
+// opens the completion box, so it doesn't flicker on every keystroke
+const autoCompleteDelay = 150 * time.Millisecond
+
+// autoCompleteMsg opens the completion box after a pause in typing, unless a
+// key was pressed since it was scheduled
+type autoCompleteMsg struct {
+	requestID int
+}
+
+// scheduleAutoComplete returns the command opening the completion box once
+// typing pauses, if the word at the cursor is at least AutoCompleteAfter
+// characters long
+func (m *Model) scheduleAutoComplete() tea.Cmd {
+	if m.AutoCompleteAfter <= 0 || m.CompletionProvider == nil {
+		return nil
+	}
+	start, _ := m.getWordBoundary()
+	if utf8.RuneCountInString(m.Value()[start:m.Position()]) < m.AutoCompleteAfter {
+		return nil
+	}
+
+	requestID := m.autoCompleteRequestID
+	return tea.Tick(autoCompleteDelay, func(time.Time) tea.Msg {
+		return autoCompleteMsg{requestID: requestID}
+	})
+}
+
+// handleAutoComplete opens the completion box without changing the line, so
+// typing carries on as usual and Tab picks a candidate. It stays closed when
+// Tab would accept the prediction instead.
+func (m *Model) handleAutoComplete(msg autoCompleteMsg) tea.Cmd {
+	if msg.requestID != m.autoCompleteRequestID || m.completion.active || m.tabAcceptsPrediction() {
+		return nil
+	}
+
+	m.completion.auto = true
+	return m.handleCompletion()
+}
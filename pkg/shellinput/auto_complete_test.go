@@ -0,0 +1,77 @@
+package shellinput
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAutoCompleteModel() Model {
+	model := New()
+	model.Focus()
+	model.CompletionProvider = &mockCompletionProvider{}
+	model.AutoCompleteAfter = 2
+	return model
+}
+
+func TestScheduleAutoComplete(t *testing.T) {
+	model := newAutoCompleteModel()
+	model.SetValue("g")
+	assert.Nil(t, model.scheduleAutoComplete(), "the word is too short")
+
+	model.SetValue("gi")
+	model.CursorEnd()
+	cmd := model.scheduleAutoComplete()
+	assert.NotNil(t, cmd)
+	assert.Equal(t, autoCompleteMsg{requestID: model.autoCompleteRequestID}, cmd())
+
+	model.AutoCompleteAfter = 0
+	assert.Nil(t, model.scheduleAutoComplete(), "disabled")
+}
+
+func TestAutoCompleteOpensBoxWithoutChangingLine(t *testing.T) {
+	model := newAutoCompleteModel()
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("gi")})
+
+	model, _ = model.Update(autoCompleteMsg{requestID: model.autoCompleteRequestID})
+	assert.True(t, model.completion.active)
+	assert.Equal(t, "gi", model.Value())
+	assert.Contains(t, model.CompletionBoxView(5, 80), "gist")
+
+	// Tab picks the first candidate
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, "git", model.Value())
+}
+
+func TestAutoCompleteShowsSingleCandidate(t *testing.T) {
+	model := newAutoCompleteModel()
+	model.CompletionProvider = &quotedPathCompletionProvider{}
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(`cat "my big`)})
+
+	model, _ = model.Update(autoCompleteMsg{requestID: model.autoCompleteRequestID})
+	assert.Equal(t, `cat "my big`, model.Value(), "a single candidate isn't applied either")
+	assert.Contains(t, model.CompletionBoxView(5, 80), "my big file.txt")
+}
+
+func TestAutoCompleteDroppedAfterKeyPress(t *testing.T) {
+	model := newAutoCompleteModel()
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("gi")})
+	requestID := model.autoCompleteRequestID
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	model, _ = model.Update(autoCompleteMsg{requestID: requestID})
+	assert.False(t, model.completion.active)
+}
+
+func TestAutoCompleteRespectsPreferPrediction(t *testing.T) {
+	model := newAutoCompleteModel()
+	model.ShowSuggestions = true
+	model.CompletionPolicy = CompletionPolicy{TabAcceptsPrediction: true}
+	model.SetSuggestions([]string{"git status"})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("gi")})
+
+	// Tab would accept the prediction, so the box stays closed
+	model, _ = model.Update(autoCompleteMsg{requestID: model.autoCompleteRequestID})
+	assert.False(t, model.completion.active)
+}
@@ -0,0 +1,122 @@
+package shellinput
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sahilm/fuzzy"
+)
+
+// PaletteProvider is implemented by completion providers that can list the
+// features offered in the command palette, such as @! commands and macros.
+type PaletteProvider interface {
+	GetPaletteEntries() []CompletionCandidate
+}
+
+// InCommandPalette returns true while the command palette is open.
+func (m Model) InCommandPalette() bool {
+	return m.completion.active && m.completion.commandPalette
+}
+
+// paletteSource lets fuzzy search entries by value
+type paletteSource []CompletionCandidate
+
+func (s paletteSource) String(i int) string {
+	return s[i].Value
+}
+
+func (s paletteSource) Len() int {
+	return len(s)
+}
+
+// openCommandPalette lists the palette entries in the completion info box.
+// Typing filters them, Up/Down, TAB or Shift+TAB move through them, Enter puts
+// the selected one in the buffer and Escape closes the palette. The buffer is
+// left alone until an entry is accepted.
+func (m *Model) openCommandPalette() {
+	provider, ok := m.CompletionProvider.(PaletteProvider)
+	if !ok {
+		return
+	}
+	entries := provider.GetPaletteEntries()
+	if len(entries) == 0 {
+		return
+	}
+
+	m.resetCompletion()
+	m.completion.active = true
+	m.completion.commandPalette = true
+	m.completion.activateInfoBox(m.Value())
+	m.paletteEntries = entries
+	m.paletteQuery = ""
+	m.filterCommandPalette()
+}
+
+// filterCommandPalette shows the entries matching the query, best match first.
+// Values match fuzzily, followed by entries whose description contains the
+// query, so "index" finds @!rehash.
+func (m *Model) filterCommandPalette() {
+	if m.paletteQuery == "" {
+		m.completion.suggestions = m.paletteEntries
+	} else {
+		matched := make(map[int]bool)
+		var suggestions []CompletionCandidate
+		for _, match := range fuzzy.FindFrom(m.paletteQuery, paletteSource(m.paletteEntries)) {
+			matched[match.Index] = true
+			suggestions = append(suggestions, m.paletteEntries[match.Index])
+		}
+		query := strings.ToLower(m.paletteQuery)
+		for i, entry := range m.paletteEntries {
+			if !matched[i] && strings.Contains(strings.ToLower(entry.Description), query) {
+				suggestions = append(suggestions, entry)
+			}
+		}
+		m.completion.suggestions = suggestions
+	}
+
+	m.completion.selected = -1
+	if len(m.completion.suggestions) > 0 {
+		m.completion.selected = 0
+	}
+	m.updateHelpInfo()
+}
+
+// updateCommandPalette handles a key press while the palette is open
+func (m *Model) updateCommandPalette(msg tea.KeyMsg) {
+	switch {
+	case msg.String() == "esc" || msg.String() == "escape" || msg.String() == "ctrl+c" || msg.String() == "ctrl+g" ||
+		key.Matches(msg, m.KeyMap.CommandPalette):
+		m.closeCommandPalette()
+	case msg.String() == "enter":
+		selected := m.completion.currentSuggestion()
+		m.closeCommandPalette()
+		if selected != "" {
+			m.SetValue(selected + " ")
+			m.CursorEnd()
+			m.updateSuggestions()
+		}
+	case key.Matches(msg, m.KeyMap.NextValue) || key.Matches(msg, m.KeyMap.Complete):
+		m.completion.nextSuggestion()
+	case key.Matches(msg, m.KeyMap.PrevValue) || key.Matches(msg, m.KeyMap.PrevSuggestion):
+		m.completion.prevSuggestion()
+	case key.Matches(msg, m.KeyMap.DeleteCharacterBackward):
+		if runes := []rune(m.paletteQuery); len(runes) > 0 {
+			m.paletteQuery = string(runes[:len(runes)-1])
+			m.filterCommandPalette()
+		}
+		return
+	case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+		m.paletteQuery += string(msg.Runes)
+		m.filterCommandPalette()
+		return
+	}
+	m.updateHelpInfo()
+}
+
+// closeCommandPalette closes the palette, leaving the buffer as it was
+func (m *Model) closeCommandPalette() {
+	m.resetCompletion()
+	m.paletteEntries = nil
+	m.paletteQuery = ""
+}
@@ -1,5 +1,11 @@
 package shellinput
 
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
 // CompletionCandidate represents a single completion suggestion
 type CompletionCandidate struct {
 	Value       string // The actual value to insert
@@ -19,6 +25,60 @@ type CompletionProvider interface {
 	GetHelpInfo(line string, pos int) string
 }
 
+// TruncatingCompletionProvider is a CompletionProvider that may leave out
+// candidates beyond a limit. OmittedCompletions returns how many the last
+// GetCompletions call left out, so the completion box can say so.
+type TruncatingCompletionProvider interface {
+	CompletionProvider
+	OmittedCompletions() int
+}
+
+// HeadedCompletionProvider is a CompletionProvider that may head its
+// candidates with a line of their own, e.g. to tell that they correct what was
+// typed rather than complete it. CompletionHeader returns the heading of the
+// last GetCompletions call, or "" for none.
+type HeadedCompletionProvider interface {
+	CompletionProvider
+	CompletionHeader() string
+}
+
+// CompletionBatch is a batch of candidates from an AsyncCompletionProvider,
+// with how many were left out of it and the heading for them. These come with
+// the candidates instead of through OmittedCompletions and CompletionHeader,
+// since a completion still running may be overtaken by a newer one.
+type CompletionBatch struct {
+	Candidates []CompletionCandidate
+	Omitted    int
+	Header     string
+}
+
+// AsyncCompletionProvider is a CompletionProvider whose completions may take a
+// while, e.g. because they wait on a subprocess or the network. The completion
+// box shows the batches sent on the returned channel as they arrive, and the
+// provider closes the channel once it's done. ctx is canceled when the
+// completion is abandoned.
+type AsyncCompletionProvider interface {
+	CompletionProvider
+	GetCompletionsAsync(ctx context.Context, line string, pos int) <-chan CompletionBatch
+}
+
+// completionBatchMsg carries a batch of candidates from an
+// AsyncCompletionProvider, or the end of them when done is set
+type completionBatchMsg struct {
+	requestID int
+	batch     CompletionBatch
+	done      bool
+	batches   <-chan CompletionBatch
+}
+
+// waitForCompletionBatch waits for the next batch of an async completion
+func waitForCompletionBatch(requestID int, batches <-chan CompletionBatch) tea.Cmd {
+	return func() tea.Msg {
+		batch, ok := <-batches
+		return completionBatchMsg{requestID: requestID, batch: batch, done: !ok, batches: batches}
+	}
+}
+
 // completionState tracks the state of completion suggestions
 type completionState struct {
 	active       bool
@@ -31,9 +91,25 @@ type completionState struct {
 	originalText string // the original text before completion started
 	helpInfo     string // help information to display for special commands
 	showHelpBox  bool   // whether to show the help info box
+	omitted      int    // number of candidates the provider left out
+	header       string // heading the provider gave the candidates
+
+	loading   bool               // whether an async provider is still sending candidates
+	requestID int                // identifies the async completion batches belong to
+	cancel    context.CancelFunc // abandons the async completion
+
+	auto      bool               // whether AutoCompleteAfter opened the box, so nothing is applied until Tab
+
+	killRingPicker bool // whether the suggestions are kill ring entries being picked from
+	commandPalette bool // whether the suggestions are command palette entries
+
+	historyCompletion bool // whether the suggestions are history commands matching the line
 }
 
 func (cs *completionState) reset() {
+	if cs.cancel != nil {
+		cs.cancel()
+	}
 	cs.active = false
 	cs.suggestions = nil
 	cs.selected = -1
@@ -44,6 +120,14 @@ func (cs *completionState) reset() {
 	cs.originalText = ""
 	cs.helpInfo = ""
 	cs.showHelpBox = false
+	cs.omitted = 0
+	cs.header = ""
+	cs.loading = false
+	cs.cancel = nil
+	cs.auto = false
+	cs.killRingPicker = false
+	cs.commandPalette = false
+	cs.historyCompletion = false
 }
 
 func (cs *completionState) nextSuggestion() string {
@@ -77,9 +161,12 @@ func (cs *completionState) hasMultipleCompletions() bool {
 	return len(cs.suggestions) > 1
 }
 
-// shouldShowInfoBox returns true if the info box should be displayed
+// shouldShowInfoBox returns true if the info box should be displayed. The
+// command palette keeps it open even when the filter leaves a single entry,
+// an async completion while candidates are loading, and a box opened by
+// AutoCompleteAfter since its single candidate isn't applied.
 func (cs *completionState) shouldShowInfoBox() bool {
-	return cs.active && cs.showInfoBox && cs.hasMultipleCompletions()
+	return cs.active && cs.showInfoBox && (cs.hasMultipleCompletions() || cs.commandPalette || cs.loading || cs.auto)
 }
 
 // shouldShowHelpBox returns true if the help box should be displayed
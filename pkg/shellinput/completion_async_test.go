@@ -0,0 +1,111 @@
+package shellinput
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asyncCompletionProvider hands out a channel per request, so tests decide
+// when batches arrive
+type asyncCompletionProvider struct {
+	mockCompletionProvider
+	batches chan CompletionBatch
+	ctx     context.Context
+}
+
+func (p *asyncCompletionProvider) GetCompletionsAsync(ctx context.Context, line string, pos int) <-chan CompletionBatch {
+	p.batches = make(chan CompletionBatch, 2)
+	p.ctx = ctx
+	return p.batches
+}
+
+func newAsyncCompletionModel(value string) (Model, *asyncCompletionProvider) {
+	provider := &asyncCompletionProvider{}
+	model := New()
+	model.Focus()
+	model.CompletionProvider = provider
+	model.SetValue(value)
+	return model, provider
+}
+
+func TestAsyncCompletionShowsPartialResults(t *testing.T) {
+	model, provider := newAsyncCompletionModel("docker run ")
+
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	require.NotNil(t, cmd)
+	assert.True(t, model.CompletionBoxVisible())
+	assert.Equal(t, "   loading...", model.CompletionBoxView(4, 80))
+
+	provider.batches <- CompletionBatch{Candidates: []CompletionCandidate{{Value: "alpine"}, {Value: "ubuntu"}}}
+	model, cmd = model.Update(cmd())
+	require.NotNil(t, cmd)
+	view := model.CompletionBoxView(4, 80)
+	assert.Contains(t, view, "alpine")
+	assert.Contains(t, view, "loading...")
+
+	provider.batches <- CompletionBatch{Candidates: []CompletionCandidate{{Value: "debian"}}}
+	close(provider.batches)
+	model, cmd = model.Update(cmd())
+	require.NotNil(t, cmd)
+	model, cmd = model.Update(cmd())
+	assert.Nil(t, cmd)
+
+	view = model.CompletionBoxView(4, 80)
+	assert.Contains(t, view, "debian")
+	assert.NotContains(t, view, "loading...")
+	assert.Equal(t, "docker run ", model.Value())
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, "docker run alpine", model.Value())
+}
+
+func TestAsyncCompletionSingleCandidate(t *testing.T) {
+	model, provider := newAsyncCompletionModel("docker run alp")
+
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	provider.batches <- CompletionBatch{Candidates: []CompletionCandidate{{Value: "alpine"}}}
+	close(provider.batches)
+	model, cmd = model.Update(cmd())
+	model, _ = model.Update(cmd())
+
+	assert.Equal(t, "docker run alpine", model.Value())
+	assert.False(t, model.CompletionBoxVisible())
+}
+
+func TestAsyncCompletionDroppedAfterTyping(t *testing.T) {
+	model, provider := newAsyncCompletionModel("docker run ")
+
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	assert.False(t, model.CompletionBoxVisible())
+
+	provider.batches <- CompletionBatch{Candidates: []CompletionCandidate{{Value: "alpine"}, {Value: "ubuntu"}}}
+	model, cmd = model.Update(cmd())
+	assert.Nil(t, cmd)
+	assert.False(t, model.CompletionBoxVisible())
+	assert.Equal(t, "docker run u", model.Value())
+	assert.ErrorIs(t, provider.ctx.Err(), context.Canceled)
+}
+
+func TestAsyncCompletionOmittedAndHeader(t *testing.T) {
+	model, provider := newAsyncCompletionModel("gti")
+
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	provider.batches <- CompletionBatch{
+		Candidates: []CompletionCandidate{{Value: "git"}, {Value: "gist"}},
+		Omitted:    3,
+		Header:     "(did you mean)",
+	}
+	close(provider.batches)
+	model, cmd = model.Update(cmd())
+	model, _ = model.Update(cmd())
+
+	view := model.CompletionBoxView(5, 80)
+	assert.Contains(t, view, "(did you mean)")
+	assert.Contains(t, view, "... 3 more, refine your input")
+	assert.Equal(t, "gti", model.Value())
+}
@@ -115,3 +115,98 @@ func TestCompletionBoxView_Paging(t *testing.T) {
 	// Ensure "1" is NOT present
 	assert.NotContains(t, view, " 1 ")
 }
+
+func TestCompletionBoxView_OmittedCandidates(t *testing.T) {
+	m := setupCompletionModel([]string{"A", "B", "C", "D"})
+	m.completion.omitted = 120
+
+	view := m.CompletionBoxView(3, 100)
+
+	// The last row of the box tells how many candidates were left out
+	lines := strings.Split(view, "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Contains(t, lines[0], "A")
+	assert.Contains(t, lines[2], "... 120 more, refine your input")
+}
+
+type truncatingProvider struct {
+	candidates []CompletionCandidate
+	omitted    int
+}
+
+func (p *truncatingProvider) GetCompletions(line string, pos int) []CompletionCandidate {
+	return p.candidates
+}
+
+func (p *truncatingProvider) GetHelpInfo(line string, pos int) string {
+	return ""
+}
+
+func (p *truncatingProvider) OmittedCompletions() int {
+	return p.omitted
+}
+
+func TestCompletionOmittedFromProvider(t *testing.T) {
+	m := New()
+	m.Focus()
+	m.CompletionProvider = &truncatingProvider{
+		candidates: []CompletionCandidate{{Value: "alpha"}, {Value: "beta"}},
+		omitted:    7,
+	}
+
+	m.handleCompletion()
+
+	assert.Equal(t, 7, m.completion.omitted)
+	assert.Contains(t, m.CompletionBoxView(4, 100), "... 7 more, refine your input")
+}
+
+func TestCompletionBoxView_Header(t *testing.T) {
+	m := setupCompletionModel([]string{"A", "B", "C", "D"})
+	m.completion.header = "(did you mean)"
+
+	view := m.CompletionBoxView(3, 100)
+
+	// The heading takes the first row of the box
+	lines := strings.Split(view, "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Contains(t, lines[0], "(did you mean)")
+	assert.Contains(t, lines[1], "A")
+
+	// Without room for it, the heading is left out
+	assert.NotContains(t, m.CompletionBoxView(1, 100), "(did you mean)")
+}
+
+type headedProvider struct {
+	candidates []CompletionCandidate
+	header     string
+}
+
+func (p *headedProvider) GetCompletions(line string, pos int) []CompletionCandidate {
+	return p.candidates
+}
+
+func (p *headedProvider) GetHelpInfo(line string, pos int) string {
+	return ""
+}
+
+func (p *headedProvider) CompletionHeader() string {
+	return p.header
+}
+
+func TestCompletionHeaderFromProvider(t *testing.T) {
+	m := New()
+	m.Focus()
+	m.SetValue("gti")
+	m.CompletionProvider = &headedProvider{
+		candidates: []CompletionCandidate{{Value: "git"}, {Value: "gist"}},
+		header:     "(did you mean)",
+	}
+
+	m.handleCompletion()
+
+	assert.Equal(t, "(did you mean)", m.completion.header)
+	assert.Contains(t, m.CompletionBoxView(4, 100), "(did you mean)")
+
+	m.resetCompletion()
+	assert.Empty(t, m.completion.header)
+}
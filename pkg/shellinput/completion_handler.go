@@ -1,11 +1,16 @@
 package shellinput
 
 import (
+	"context"
 	"strings"
 	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
-// getWordBoundary returns the start and end position of the word at the cursor
+// getWordBoundary returns the start and end position of the word at the cursor.
+// Spaces inside quotes or escaped with a backslash don't end a word, so for
+// `cat "my fil` the word is `"my fil`.
 func (m *Model) getWordBoundary() (start, end int) {
 	value := m.Value()
 	if len(value) == 0 {
@@ -15,115 +20,77 @@ func (m *Model) getWordBoundary() (start, end int) {
 	// Get cursor position
 	pos := m.Position()
 
-	// Find start of word
-	start = pos
-	for start > 0 && !unicode.IsSpace(rune(value[start-1])) {
-		start--
+	// Find start of word, scanning from the beginning to track quotes
+	quoteChar := byte(0)
+	escaped := false
+	for i := 0; i < pos; i++ {
+		c := value[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoteChar != '\'':
+			escaped = true
+		case quoteChar != 0:
+			if c == quoteChar {
+				quoteChar = 0
+			}
+		case c == '\'' || c == '"':
+			quoteChar = c
+		case unicode.IsSpace(rune(c)):
+			start = i + 1
+		}
 	}
 
 	// Find end of word
 	end = pos
-	for end < len(value) && !unicode.IsSpace(rune(value[end])) {
+	for end < len(value) && (quoteChar != 0 || escaped || !unicode.IsSpace(rune(value[end]))) {
+		c := value[end]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoteChar != '\'':
+			escaped = true
+		case quoteChar != 0:
+			if c == quoteChar {
+				quoteChar = 0
+			}
+		case c == '\'' || c == '"':
+			quoteChar = c
+		}
 		end++
 	}
 
 	return start, end
 }
 
-// handleCompletion handles the TAB key press for completion
-func (m *Model) handleCompletion() {
-	if m.CompletionProvider == nil {
-		return
-	}
-
+// handleCompletion handles the TAB key press for completion. It returns the
+// command waiting for candidates when the provider is asynchronous.
+func (m *Model) handleCompletion() tea.Cmd {
 	if !m.completion.active {
-		// Start a new completion
-		start, end := m.getWordBoundary()
-		suggestions := m.CompletionProvider.GetCompletions(m.Value(), m.Position())
-		if len(suggestions) == 0 {
-			m.resetCompletion() // Ensure completion state is reset
-			return
-		}
-
-		// Check for context-sensitive completions (#/ and #! prefixes)
-		value := m.Value()
-		if len(value) >= 2 {
-			if value[:2] == "#/" || value[:2] == "#!" {
-				// For context-sensitive completions, use the beginning of the prefix
-				start = 0
-			}
-		}
-
-		// Check if this is a multi-word completion by examining the suggestions
-		// If suggestions contain spaces, it might be a full phrase completion
-		isMultiWord := false
-		for _, suggestion := range suggestions {
-			if strings.Contains(suggestion.Value, " ") {
-				isMultiWord = true
-				break
-			}
-		}
-
-		// For multi-word completions, we need to find the start of the command
-		if isMultiWord {
-			// Find the start of the current command (go back to beginning of line or last space)
-			line := m.Value()
-			pos := m.Position()
-			commandStart := pos
-			for commandStart > 0 && !unicode.IsSpace(rune(line[commandStart-1])) {
-				commandStart--
-			}
-			// If there's a space before this word, go back to find the start of the command
-			if commandStart > 0 {
-				// Find the start of the previous word
-				prevWordStart := commandStart - 1
-				for prevWordStart > 0 && unicode.IsSpace(rune(line[prevWordStart-1])) {
-					prevWordStart--
-				}
-				for prevWordStart > 0 && !unicode.IsSpace(rune(line[prevWordStart-1])) {
-					prevWordStart--
-				}
-				// If the suggestion starts with the same prefix as the current command, use the full command
-				if len(suggestions) > 0 && strings.HasPrefix(suggestions[0].Value, line[prevWordStart:commandStart]) {
-					start = prevWordStart
-				}
-			}
+		if m.CompletionProvider == nil {
+			return nil
 		}
-
-		m.completion.active = true
-		m.completion.suggestions = suggestions
-		m.completion.selected = -1
-		m.completion.prefix = m.Value()[start:m.Position()]
-		m.completion.startPos = start // Use the actual start position from word boundary
-		m.completion.endPos = end     // Store the end position as well
-
-		// Activate info box if there are multiple completions
-		if len(suggestions) > 1 {
-			m.completion.activateInfoBox(m.Value())
+		// Start a new completion
+		if async, ok := m.CompletionProvider.(AsyncCompletionProvider); ok {
+			return m.startAsyncCompletion(async)
 		}
-
-		if len(suggestions) == 1 {
-			m.completion.selected = 0
-			m.applySuggestion(suggestions[0].Value)
-			m.updateHelpInfo()
-			return
+		start, end := m.getWordBoundary()
+		batch := CompletionBatch{Candidates: m.CompletionProvider.GetCompletions(m.Value(), m.Position())}
+		if truncating, ok := m.CompletionProvider.(TruncatingCompletionProvider); ok {
+			batch.Omitted = truncating.OmittedCompletions()
 		}
-
-		commonPrefix := longestCommonPrefix(suggestions)
-		if len(commonPrefix) > len(m.completion.prefix) {
-			m.completion.prefix = commonPrefix
-			m.completion.endPos = m.completion.startPos + len(commonPrefix)
-			m.applySuggestion(commonPrefix)
-			return
+		if headed, ok := m.CompletionProvider.(HeadedCompletionProvider); ok {
+			batch.Header = headed.CompletionHeader()
 		}
-
-		return
+		m.beginCompletion(batch.Candidates, start, end)
+		m.completion.omitted, m.completion.header = batch.Omitted, batch.Header
+		return nil
 	}
 
 	// Get next suggestion (this works for both initial and subsequent TAB presses)
 	suggestion := m.completion.nextSuggestion()
 	if suggestion == "" {
-		return
+		return nil
 	}
 
 	// Note: We intentionally do NOT recalculate startPos when cycling through completions.
@@ -137,11 +104,153 @@ func (m *Model) handleCompletion() {
 
 	// Update help info for the selected completion
 	m.updateHelpInfo()
+	return nil
+}
+
+// startAsyncCompletion opens the completion box in its loading state and
+// returns the command waiting for the first batch of candidates
+func (m *Model) startAsyncCompletion(provider AsyncCompletionProvider) tea.Cmd {
+	start, end := m.getWordBoundary()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	auto := m.completion.auto
+	m.completion.reset()
+	m.completion.auto = auto
+	m.completion.requestID++
+	m.completion.active = true
+	m.completion.loading = true
+	m.completion.cancel = cancel
+	m.completion.selected = -1
+	m.completion.prefix = m.Value()[start:m.Position()]
+	m.completion.startPos = start
+	m.completion.endPos = end
+	m.completion.activateInfoBox(m.Value())
+
+	return waitForCompletionBatch(m.completion.requestID, provider.GetCompletionsAsync(ctx, m.Value(), m.Position()))
+}
+
+// handleCompletionBatch adds a batch of async candidates to the box. Once the
+// provider is done, the candidates are completed like synchronous ones, unless
+// one is already selected.
+func (m *Model) handleCompletionBatch(msg completionBatchMsg) tea.Cmd {
+	// Batches of a completion that was since closed or replaced are dropped
+	if !m.completion.loading || msg.requestID != m.completion.requestID {
+		return nil
+	}
+	if !msg.done {
+		m.completion.suggestions = append(m.completion.suggestions, msg.batch.Candidates...)
+		m.completion.omitted += msg.batch.Omitted
+		if msg.batch.Header != "" {
+			m.completion.header = msg.batch.Header
+		}
+		return waitForCompletionBatch(msg.requestID, msg.batches)
+	}
+
+	m.completion.loading = false
+	if m.completion.selected >= 0 {
+		return nil
+	}
+	suggestions, start, end, auto := m.completion.suggestions, m.completion.startPos, m.completion.endPos, m.completion.auto
+	omitted, header := m.completion.omitted, m.completion.header
+	m.resetCompletion()
+	m.completion.auto = auto
+	m.beginCompletion(suggestions, start, end)
+	m.completion.omitted, m.completion.header = omitted, header
+	m.updateHelpInfo()
+	return nil
+}
+
+// beginCompletion starts completing the word between start and end with the
+// provider's suggestions
+func (m *Model) beginCompletion(suggestions []CompletionCandidate, start int, end int) {
+	if len(suggestions) == 0 {
+		m.resetCompletion() // Ensure completion state is reset
+		return
+	}
+
+	// Check for context-sensitive completions (#/ and #! prefixes)
+	value := m.Value()
+	if len(value) >= 2 {
+		if value[:2] == "#/" || value[:2] == "#!" {
+			// For context-sensitive completions, use the beginning of the prefix
+			start = 0
+		}
+	}
+
+	// Check if this is a multi-word completion by examining the suggestions
+	// If suggestions contain spaces, it might be a full phrase completion
+	isMultiWord := false
+	for _, suggestion := range suggestions {
+		if strings.Contains(suggestion.Value, " ") {
+			isMultiWord = true
+			break
+		}
+	}
+
+	// For multi-word completions, we need to find the start of the command.
+	// A word that already spans spaces is a quoted argument, not a command.
+	wordSpansSpaces := strings.ContainsFunc(m.Value()[start:m.Position()], unicode.IsSpace)
+	if isMultiWord && !wordSpansSpaces {
+		// Find the start of the current command (go back to beginning of line or last space)
+		line := m.Value()
+		pos := m.Position()
+		commandStart := pos
+		for commandStart > 0 && !unicode.IsSpace(rune(line[commandStart-1])) {
+			commandStart--
+		}
+		// If there's a space before this word, go back to find the start of the command
+		if commandStart > 0 {
+			// Find the start of the previous word
+			prevWordStart := commandStart - 1
+			for prevWordStart > 0 && unicode.IsSpace(rune(line[prevWordStart-1])) {
+				prevWordStart--
+			}
+			for prevWordStart > 0 && !unicode.IsSpace(rune(line[prevWordStart-1])) {
+				prevWordStart--
+			}
+			// If the suggestion starts with the same prefix as the current command, use the full command
+			if len(suggestions) > 0 && strings.HasPrefix(suggestions[0].Value, line[prevWordStart:commandStart]) {
+				start = prevWordStart
+			}
+		}
+	}
+
+	m.completion.active = true
+	m.completion.suggestions = suggestions
+	m.completion.omitted = 0
+	m.completion.header = ""
+	m.completion.selected = -1
+	m.completion.prefix = m.Value()[start:m.Position()]
+	m.completion.startPos = start // Use the actual start position from word boundary
+	m.completion.endPos = end     // Store the end position as well
+
+	// Activate info box if there are multiple completions
+	if len(suggestions) > 1 || m.completion.auto {
+		m.completion.activateInfoBox(m.Value())
+	}
+	if m.completion.auto {
+		return
+	}
+
+	if len(suggestions) == 1 {
+		m.completion.selected = 0
+		m.applySuggestion(suggestions[0].Value)
+		m.updateHelpInfo()
+		return
+	}
+
+	commonPrefix := longestCommonPrefix(suggestions)
+	if len(commonPrefix) > len(m.completion.prefix) {
+		m.completion.prefix = commonPrefix
+		m.completion.endPos = m.completion.startPos + len(commonPrefix)
+		m.applySuggestion(commonPrefix)
+		return
+	}
 }
 
 // handleBackwardCompletion handles the Shift+TAB key press for completion
 func (m *Model) handleBackwardCompletion() {
-	if m.CompletionProvider == nil || !m.completion.active {
+	if !m.completion.active {
 		return
 	}
 
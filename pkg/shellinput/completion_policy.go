@@ -0,0 +1,51 @@
+package shellinput
+
+import "strings"
+
+// CompletionPolicy decides between the ghost-text prediction and the
+// completion box when both could apply
+type CompletionPolicy struct {
+	// TabAcceptsPrediction makes the Complete key accept the prediction shown
+	// after the cursor instead of opening the completion box. Without a
+	// prediction, or once the box is open, it completes as usual.
+	TabAcceptsPrediction bool
+	// HidePredictionWhileCompleting hides the ghost text while the completion
+	// box is open, so only the selected candidate is previewed
+	HidePredictionWhileCompleting bool
+}
+
+// ParseCompletionPolicy reads a comma-separated list of prefer-completion
+// (the default) or prefer-prediction, and optionally hide-prediction. Unknown
+// words are ignored.
+func ParseCompletionPolicy(value string) CompletionPolicy {
+	var policy CompletionPolicy
+	for _, word := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(word)) {
+		case "prefer-prediction":
+			policy.TabAcceptsPrediction = true
+		case "prefer-completion":
+			policy.TabAcceptsPrediction = false
+		case "hide-prediction":
+			policy.HidePredictionWhileCompleting = true
+		}
+	}
+	return policy
+}
+
+// showsPrediction returns whether the ghost text is displayed
+func (m *Model) showsPrediction() bool {
+	if m.CompletionPolicy.HidePredictionWhileCompleting && m.completion.active {
+		return false
+	}
+	return m.canAcceptSuggestion()
+}
+
+// tabAcceptsPrediction returns whether the Complete key should accept the
+// prediction rather than complete
+func (m *Model) tabAcceptsPrediction() bool {
+	if !m.CompletionPolicy.TabAcceptsPrediction || m.completion.active || !m.canAcceptSuggestion() {
+		return false
+	}
+	value := m.values[m.selectedValueIndex]
+	return m.pos == len(value) && len(m.matchedSuggestions[m.currentSuggestionIndex]) > len(value)
+}
@@ -0,0 +1,71 @@
+package shellinput
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicyModel(policy CompletionPolicy) Model {
+	model := New()
+	model.Focus()
+	model.ShowSuggestions = true
+	model.CompletionProvider = &mockCompletionProvider{}
+	model.CompletionPolicy = policy
+	model.SetSuggestions([]string{"git checkout main"})
+	model.SetValue("git ch")
+	model.updateSuggestions()
+	return model
+}
+
+func TestParseCompletionPolicy(t *testing.T) {
+	assert.Equal(t, CompletionPolicy{}, ParseCompletionPolicy(""))
+	assert.Equal(t, CompletionPolicy{}, ParseCompletionPolicy("prefer-completion"))
+	assert.Equal(t, CompletionPolicy{TabAcceptsPrediction: true}, ParseCompletionPolicy(" Prefer-Prediction "))
+	assert.Equal(t,
+		CompletionPolicy{TabAcceptsPrediction: true, HidePredictionWhileCompleting: true},
+		ParseCompletionPolicy("prefer-prediction, hide-prediction, bogus"))
+}
+
+func TestTabPrefersCompletionByDefault(t *testing.T) {
+	model := newPolicyModel(CompletionPolicy{})
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	assert.True(t, model.completion.active)
+	assert.NotEqual(t, "git checkout main", model.Value())
+}
+
+func TestTabAcceptsPrediction(t *testing.T) {
+	model := newPolicyModel(CompletionPolicy{TabAcceptsPrediction: true})
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	assert.False(t, model.completion.active)
+	assert.Equal(t, "git checkout main", model.Value())
+	assert.Equal(t, len("git checkout main"), model.Position())
+}
+
+func TestTabCompletesWhenCursorIsNotAtTheEnd(t *testing.T) {
+	model := newPolicyModel(CompletionPolicy{TabAcceptsPrediction: true})
+	model.SetCursor(3)
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	assert.NotEqual(t, "git checkout main", model.Value())
+}
+
+func TestHidePredictionWhileCompleting(t *testing.T) {
+	model := newPolicyModel(CompletionPolicy{})
+	model.SetSuggestions([]string{"git checkout main"})
+	model.handleCompletion()
+	model.updateSuggestions()
+	assert.True(t, model.completion.active)
+	assert.True(t, model.showsPrediction())
+	assert.Contains(t, model.View(), " main")
+
+	model.CompletionPolicy.HidePredictionWhileCompleting = true
+	assert.False(t, model.showsPrediction())
+	assert.NotContains(t, model.View(), " main")
+}
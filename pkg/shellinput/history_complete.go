@@ -0,0 +1,66 @@
+package shellinput
+
+import (
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// historyCompletionLimit caps how many history commands history completion lists
+const historyCompletionLimit = 100
+
+// InHistoryCompletion returns true while the completion box lists history commands.
+func (m Model) InHistoryCompletion() bool {
+	return m.completion.active && m.completion.historyCompletion
+}
+
+// historyCompletions returns the history commands that start with prefix,
+// newest first and each once, described by where and when they last ran
+func (m Model) historyCompletions(prefix string) []CompletionCandidate {
+	seen := make(map[string]bool)
+	var candidates []CompletionCandidate
+	for _, item := range m.historyItems {
+		if seen[item.Command] || item.Command == prefix || !strings.HasPrefix(item.Command, prefix) {
+			continue
+		}
+		seen[item.Command] = true
+
+		var details []string
+		if item.Directory != "" {
+			details = append(details, item.Directory)
+		}
+		if !item.Timestamp.IsZero() {
+			details = append(details, humanize.Time(item.Timestamp))
+		}
+		candidates = append(candidates, CompletionCandidate{
+			Value:       item.Command,
+			Display:     strings.ReplaceAll(item.Command, "\n", "⏎"),
+			Description: strings.Join(details, ", "),
+		})
+		if len(candidates) == historyCompletionLimit {
+			break
+		}
+	}
+	return candidates
+}
+
+// openHistoryCompletion lists the history commands that start with the line in
+// the completion box. It works like TAB completion of the whole line: pressing
+// the key again, TAB or Shift+TAB move through the commands, Enter keeps the
+// selected one and Escape restores the line.
+func (m *Model) openHistoryCompletion() {
+	if m.InHistoryCompletion() {
+		if suggestion := m.completion.nextSuggestion(); suggestion != "" {
+			m.applySuggestion(suggestion)
+		}
+		return
+	}
+
+	candidates := m.historyCompletions(m.Value())
+	if len(candidates) == 0 {
+		return
+	}
+	m.resetCompletion()
+	m.completion.historyCompletion = true
+	m.beginCompletion(candidates, 0, len(m.Value()))
+}
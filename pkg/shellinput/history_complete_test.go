@@ -0,0 +1,83 @@
+package shellinput
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryCompletion(t *testing.T) {
+	model := New()
+	model.Focus()
+	now := time.Now()
+	model.SetRichHistory([]HistoryItem{
+		{Command: "git push origin main", Directory: "/src/app", Timestamp: now.Add(-time.Hour)},
+		{Command: "git pull", Directory: "/src/app", Timestamp: now.Add(-2 * time.Hour)},
+		{Command: "ls -la", Directory: "/tmp"},
+		{Command: "git push origin main", Directory: "/src/lib", Timestamp: now.Add(-3 * time.Hour)},
+	})
+	model.SetValue("git p")
+	model.CursorEnd()
+
+	altSlash := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}, Alt: true}
+	updatedModel, _ := model.Update(altSlash)
+	assert.True(t, updatedModel.InHistoryCompletion())
+	assert.Equal(t, "git pu", updatedModel.Value(), "The common prefix of the matches should be filled in")
+
+	box := updatedModel.CompletionBoxView(5, 100)
+	assert.Contains(t, box, "git push origin main")
+	assert.Contains(t, box, "/src/app, 1 hour ago", "Matches should say where and when they ran")
+	assert.Contains(t, box, "git pull")
+	assert.NotContains(t, box, "ls -la")
+
+	updatedModel, _ = updatedModel.Update(altSlash)
+	assert.Equal(t, "git push origin main", updatedModel.Value(), "Alt+/ again should move to the next match")
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, "git pull", updatedModel.Value())
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	assert.Equal(t, "git push origin main", updatedModel.Value())
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.False(t, updatedModel.InHistoryCompletion())
+	assert.Equal(t, "git push origin main", updatedModel.Value())
+	assert.Equal(t, len("git push origin main"), updatedModel.Position())
+
+	// Escape restores the line as it was typed
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("git")})
+	updatedModel, _ = updatedModel.Update(altSlash)
+	updatedModel, _ = updatedModel.Update(altSlash)
+	assert.Equal(t, "git push origin main", updatedModel.Value())
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.False(t, updatedModel.InHistoryCompletion())
+	assert.Equal(t, "git", updatedModel.Value())
+
+	// A single match is filled in right away
+	updatedModel.SetValue("ls")
+	updatedModel.CursorEnd()
+	updatedModel, _ = updatedModel.Update(altSlash)
+	assert.Equal(t, "ls -la", updatedModel.Value())
+
+	// Nothing happens without a match
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("make")})
+	updatedModel, _ = updatedModel.Update(altSlash)
+	assert.False(t, updatedModel.InHistoryCompletion())
+	assert.Equal(t, "make", updatedModel.Value())
+}
+
+func TestHistoryCompletionsAreDeduplicated(t *testing.T) {
+	model := New()
+	model.SetRichHistory([]HistoryItem{
+		{Command: "make test", Directory: "/a"},
+		{Command: "make"},
+		{Command: "make test", Directory: "/b"},
+	})
+
+	candidates := model.historyCompletions("make")
+	assert.Equal(t, []string{"make test"}, []string{candidates[0].Value})
+	assert.Len(t, candidates, 1, "The line itself and repeated commands should be left out")
+	assert.Equal(t, "/a", candidates[0].Description, "The latest run should describe the command")
+}
@@ -5,10 +5,14 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/atinylittleshell/gsh/pkg/textwidth"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dustin/go-humanize"
-	"github.com/muesli/ansi"
 	"github.com/sahilm/fuzzy"
 )
 
@@ -17,6 +21,20 @@ type HistoryItem struct {
 	Command   string
 	Directory string
 	Timestamp time.Time
+	// Note is the annotation added to the command with @!note, if any
+	Note string
+}
+
+// historyNoteSeparator separates a command from its note in the history search
+const historyNoteSeparator = " — "
+
+// searchText returns the text a history search query is matched against,
+// which includes the note so commands can be found by what they were for
+func (item HistoryItem) searchText() string {
+	if item.Note == "" {
+		return item.Command
+	}
+	return item.Command + historyNoteSeparator + item.Note
 }
 
 // HistoryFilterMode defines the scope of history search
@@ -59,13 +77,68 @@ func (m HistorySortMode) String() string {
 	}
 }
 
+// HistoryMatchMode defines how the query is matched against history
+type HistoryMatchMode int
+
+const (
+	// HistoryMatchFuzzy matches the query as a subsequence, tolerating a typo
+	// in longer queries
+	HistoryMatchFuzzy HistoryMatchMode = iota
+	// HistoryMatchSubstring matches the query as a contiguous substring
+	HistoryMatchSubstring
+)
+
+func (m HistoryMatchMode) String() string {
+	if m == HistoryMatchSubstring {
+		return "Substring"
+	}
+	return "Fuzzy"
+}
+
+// minTypoQueryLength is the shortest query for which fuzzy matching also
+// finds commands with a typo, since shorter queries would match nearly anything
+const minTypoQueryLength = 4
+
+// HistorySearchStyle selects how Ctrl+R searches history
+type HistorySearchStyle int
+
+const (
+	// HistorySearchRich shows matches in a list below the prompt
+	HistorySearchRich HistorySearchStyle = iota
+	// HistorySearchInline fills the buffer with the newest match as you type,
+	// like bash and zsh; pressing Ctrl+R again moves to older matches
+	HistorySearchInline
+)
+
+// ParseHistorySearchStyle returns the style named by value, defaulting to rich
+func ParseHistorySearchStyle(value string) HistorySearchStyle {
+	if strings.EqualFold(strings.TrimSpace(value), "inline") {
+		return HistorySearchInline
+	}
+	return HistorySearchRich
+}
+
 // historySearchState tracks the state of the rich history search
 type historySearchState struct {
 	filteredIndices []int // indices into Model.historyItems
 	selected        int   // index into filteredIndices
 	filterMode      HistoryFilterMode
 	sortMode        HistorySortMode
+	matchMode       HistoryMatchMode
 	currentDir      string // used for filtering by directory
+	// matchedIndexes holds the byte offsets of the matched characters of each
+	// filtered command, keyed by index into Model.historyItems
+	matchedIndexes map[int][]int
+}
+
+// HistorySearchBoxVisible returns true while the rich history search list is shown
+func (m Model) HistorySearchBoxVisible() bool {
+	return m.inReverseSearch && m.HistorySearchStyle != HistorySearchInline
+}
+
+// ExitHistorySearch leaves history search, keeping the buffer as it is
+func (m *Model) ExitHistorySearch() {
+	m.inReverseSearch = false
 }
 
 // SetRichHistory sets the history items for the rich search
@@ -80,7 +153,7 @@ func (m *Model) SetCurrentDirectory(dir string) {
 
 // HistorySearchBoxView renders the history search box
 func (m Model) HistorySearchBoxView(height, width int) string {
-	if !m.inReverseSearch {
+	if !m.HistorySearchBoxVisible() {
 		return ""
 	}
 
@@ -106,21 +179,25 @@ func (m Model) HistorySearchBoxView(height, width int) string {
 	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))    // Dim gray for metadata
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("243"))   // Slightly brighter for help
 
+	matchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+
 	// Render Header
-	// e.g. "Filter: All | Sort: Recent | 35 matches"
+	// e.g. "Filter: All | Sort: Recent | Match: Fuzzy | 35 matches"
 	filterText := fmt.Sprintf("Filter: %s", m.historySearchState.filterMode.String())
 	sortText := fmt.Sprintf("Sort: %s", m.historySearchState.sortMode.String())
+	matchModeText := fmt.Sprintf("Match: %s", m.historySearchState.matchMode.String())
 	matchCount := len(m.historySearchState.filteredIndices)
-	header := headerStyle.Render(fmt.Sprintf("%s | %s | %d matches",
+	header := headerStyle.Render(fmt.Sprintf("%s | %s | %s | %d matches",
 		filterStyle.Render(filterText),
 		filterStyle.Render(sortText),
+		filterStyle.Render(matchModeText),
 		matchCount))
 	content.WriteString(header + "\n")
 
 	if matchCount == 0 {
 		content.WriteString(lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("240")).Render("No history matches found"))
 		content.WriteString("\n")
-		helpText := "Ctrl+F: Filter | Ctrl+O: Sort | Enter: Select | Esc: Cancel"
+		helpText := "Ctrl+F: Filter | Ctrl+O: Sort | Ctrl+T: Match | Alt+E: Explain | Enter: Select | Esc: Cancel"
 		content.WriteString(helpStyle.Render(helpText))
 		return content.String()
 	}
@@ -195,25 +272,34 @@ func (m Model) HistorySearchBoxView(height, width int) string {
 			cmdWidth = 10 // Minimum width
 		}
 
-		cmdStr := item.Command
+		cmdStr := item.searchText()
+		// Only characters of the original command are highlighted, not the
+		// note, ellipsis or padding
+		highlightLimit := len(item.Command)
 		// Simple truncation
-		if ansi.PrintableRuneWidth(cmdStr) > cmdWidth {
+		if cmdStrWidth := textwidth.StringWidth(cmdStr); cmdStrWidth > cmdWidth {
 			// truncate
-			runes := []rune(cmdStr)
-			if len(runes) > cmdWidth-1 {
-				cmdStr = string(runes[:cmdWidth-1]) + "…"
-			}
+			truncated := textwidth.Truncate(cmdStr, cmdWidth-1)
+			cmdStr = truncated + "…"
+			highlightLimit = min(highlightLimit, len(truncated))
 		} else {
-			// pad
-			cmdStr = fmt.Sprintf("%-*s", cmdWidth, cmdStr)
+			// pad, by width so wide characters stay aligned
+			cmdStr += strings.Repeat(" ", cmdWidth-cmdStrWidth)
 		}
 
-		line := ""
+		rowStyle := normalStyle
 		if isRowSelected {
-			line = selectedStyle.Render(prefix + cmdStr) + "  " + dimStyle.Render(timeStr)
+			rowStyle = selectedStyle
+		}
+		matched := m.historySearchState.matchedIndexes[originalIdx]
+		var cmdView string
+		if item.Note != "" {
+			// The note is dimmed so it reads as a comment on the command
+			cmdView = highlightMatches(cmdStr[:highlightLimit], matched, highlightLimit, rowStyle, matchStyle) + dimStyle.Render(cmdStr[highlightLimit:])
 		} else {
-			line = normalStyle.Render(prefix + cmdStr) + "  " + dimStyle.Render(timeStr)
+			cmdView = highlightMatches(cmdStr, matched, highlightLimit, rowStyle, matchStyle)
 		}
+		line := rowStyle.Render(prefix) + cmdView + "  " + dimStyle.Render(timeStr)
 
 		content.WriteString(line)
 		if i < endIdx-1 {
@@ -223,12 +309,45 @@ func (m Model) HistorySearchBoxView(height, width int) string {
 
 	// Add help footer
 	content.WriteString("\n")
-	helpText := "Ctrl+F: Filter | Ctrl+O: Sort | Enter: Select | Esc: Cancel"
+	helpText := "Ctrl+F: Filter | Ctrl+O: Sort | Ctrl+T: Match | Alt+E: Explain | Enter: Select | Esc: Cancel"
 	content.WriteString(helpStyle.Render(helpText))
 
 	return content.String()
 }
 
+// ExplainHistoryMsg asks for an explanation of the command selected in the
+// history search
+type ExplainHistoryMsg struct {
+	Command string
+}
+
+// SelectedHistoryCommand returns the command selected in the history search,
+// or "" when nothing is selected
+func (m Model) SelectedHistoryCommand() string {
+	if !m.inReverseSearch {
+		return ""
+	}
+	selected := m.historySearchState.selected
+	if selected < 0 || selected >= len(m.historySearchState.filteredIndices) {
+		return ""
+	}
+	originalIdx := m.historySearchState.filteredIndices[selected]
+	if originalIdx < 0 || originalIdx >= len(m.historyItems) {
+		return ""
+	}
+	return m.historyItems[originalIdx].Command
+}
+
+func (m Model) explainHistorySelection() tea.Cmd {
+	command := m.SelectedHistoryCommand()
+	if command == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		return ExplainHistoryMsg{Command: command}
+	}
+}
+
 // updateHistorySearch updates the filtered list based on the query and filter mode
 func (m *Model) updateHistorySearch() {
 	query := m.reverseSearchQuery
@@ -264,6 +383,21 @@ func (m *Model) updateHistorySearch() {
 		}
 	}
 
+	if m.HistorySearchStyle == HistorySearchInline {
+		// Classic incremental search: substring matches, newest first
+		m.historySearchState.filteredIndices = nil
+		if query != "" {
+			for _, i := range candidates {
+				if strings.Contains(m.historyItems[i].searchText(), query) {
+					m.historySearchState.filteredIndices = append(m.historySearchState.filteredIndices, i)
+				}
+			}
+		}
+		m.historySearchState.selected = 0
+		return
+	}
+
+	m.historySearchState.matchedIndexes = nil
 	if query == "" {
 		// Sort candidates if needed
 		switch m.historySearchState.sortMode {
@@ -282,40 +416,186 @@ func (m *Model) updateHistorySearch() {
 		return
 	}
 
-	// Fuzzy search on candidates
 	// We need to create a source that maps candidates back to historyItems
 	source := historySourceSubset{
 		indices: candidates,
 		items:   m.historyItems,
 	}
 
-	matches := fuzzy.FindFrom(query, source)
+	var matches fuzzy.Matches
+	var typoMatches fuzzy.Matches
+	if m.historySearchState.matchMode == HistoryMatchSubstring {
+		matches = findSubstringMatches(query, source)
+	} else {
+		matches = fuzzy.FindFrom(query, source)
+		typoMatches = findTypoMatches(query, source, matches)
+	}
 
-	// Sort matches based on sort mode
-	switch m.historySearchState.sortMode {
-	case HistorySortRecent:
-		// Sort by index in candidates (which preserves original time-descending order, i.e. Newest First)
-		// Assuming historyItems are ordered Newest First (index 0 is newest),
-		// then lower index means more recent.
-		sort.SliceStable(matches, func(i, j int) bool {
-			return matches[i].Index < matches[j].Index
-		})
-	case HistorySortAlphabetical:
-		sort.SliceStable(matches, func(i, j int) bool {
-			return matches[i].Str < matches[j].Str
-		})
-	case HistorySortRelevance:
-		// Already sorted by fuzzy score
+	// Sort matches based on sort mode. Matches with a typo always come after
+	// the exact ones.
+	for _, group := range []fuzzy.Matches{matches, typoMatches} {
+		switch m.historySearchState.sortMode {
+		case HistorySortRecent:
+			// Sort by index in candidates (which preserves original time-descending order, i.e. Newest First)
+			// Assuming historyItems are ordered Newest First (index 0 is newest),
+			// then lower index means more recent.
+			sort.SliceStable(group, func(i, j int) bool {
+				return group[i].Index < group[j].Index
+			})
+		case HistorySortAlphabetical:
+			sort.SliceStable(group, func(i, j int) bool {
+				return group[i].Str < group[j].Str
+			})
+		case HistorySortRelevance:
+			sort.SliceStable(group, func(i, j int) bool {
+				return group[i].Score > group[j].Score
+			})
+		}
 	}
+	matches = append(matches, typoMatches...)
 
 	m.historySearchState.filteredIndices = make([]int, len(matches))
+	m.historySearchState.matchedIndexes = make(map[int][]int, len(matches))
 	for i, match := range matches {
 		// match.Index is index into 'candidates', so we need candidates[match.Index]
 		m.historySearchState.filteredIndices[i] = candidates[match.Index]
+		m.historySearchState.matchedIndexes[candidates[match.Index]] = match.MatchedIndexes
 	}
 	m.historySearchState.selected = 0
 }
 
+// findSubstringMatches returns the entries of source containing query, ignoring
+// case. Matches closer to the start of shorter commands score higher.
+func findSubstringMatches(query string, source historySourceSubset) fuzzy.Matches {
+	var matches fuzzy.Matches
+	for i := 0; i < source.Len(); i++ {
+		command := source.String(i)
+		offset, length := indexFold(command, query)
+		if offset < 0 {
+			continue
+		}
+
+		var matched []int
+		for j := range command[offset : offset+length] {
+			matched = append(matched, offset+j)
+		}
+		matches = append(matches, fuzzy.Match{
+			Str:            command,
+			Index:          i,
+			MatchedIndexes: matched,
+			Score:          -offset - len(command),
+		})
+	}
+	return matches
+}
+
+// indexFold returns the byte offset and length of the first occurrence of
+// query in s, ignoring case, or -1 if there is none
+func indexFold(s, query string) (int, int) {
+	for start := range s {
+		end := start
+		matched := true
+		for _, queryRune := range query {
+			if end >= len(s) {
+				matched = false
+				break
+			}
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if !strings.EqualFold(string(r), string(queryRune)) {
+				matched = false
+				break
+			}
+			end += size
+		}
+		if matched {
+			return start, end - start
+		}
+	}
+	return -1, 0
+}
+
+// findTypoMatches finds entries that don't match query but would with one of
+// its characters left out, which covers a mistyped, extra or swapped character.
+// The characters have to match close together, so the result is still what
+// the query was meant to find rather than any command sharing its letters.
+func findTypoMatches(query string, source historySourceSubset, exact fuzzy.Matches) fuzzy.Matches {
+	runes := []rune(query)
+	if len(runes) < minTypoQueryLength {
+		return nil
+	}
+
+	matchedExactly := make(map[int]bool, len(exact))
+	for _, match := range exact {
+		matchedExactly[match.Index] = true
+	}
+
+	best := make(map[int]fuzzy.Match)
+	for skip := range runes {
+		variant := string(runes[:skip]) + string(runes[skip+1:])
+		for _, match := range fuzzy.FindFromNoSort(variant, source) {
+			if matchedExactly[match.Index] {
+				continue
+			}
+			span := match.MatchedIndexes[len(match.MatchedIndexes)-1] - match.MatchedIndexes[0] + 1
+			if span > len(variant)+2 {
+				continue
+			}
+			if previous, ok := best[match.Index]; !ok || match.Score > previous.Score {
+				best[match.Index] = match
+			}
+		}
+	}
+
+	matches := make(fuzzy.Matches, 0, len(best))
+	for _, match := range best {
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Index < matches[j].Index
+	})
+	return matches
+}
+
+// highlightMatches renders text with the characters at the matched byte offsets
+// below limit in the highlight style and the rest in the base style
+func highlightMatches(text string, matched []int, limit int, base, highlight lipgloss.Style) string {
+	if len(matched) == 0 {
+		return base.Render(text)
+	}
+
+	isMatched := make(map[int]bool, len(matched))
+	for _, offset := range matched {
+		if offset < limit {
+			isMatched[offset] = true
+		}
+	}
+
+	// Render runs of characters with the same style together
+	var sb strings.Builder
+	var run strings.Builder
+	runHighlighted := false
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if runHighlighted {
+			sb.WriteString(highlight.Render(run.String()))
+		} else {
+			sb.WriteString(base.Render(run.String()))
+		}
+		run.Reset()
+	}
+	for offset, r := range text {
+		if isMatched[offset] != runHighlighted {
+			flush()
+			runHighlighted = isMatched[offset]
+		}
+		run.WriteRune(r)
+	}
+	flush()
+	return sb.String()
+}
+
 // historySourceSubset adapts a subset of HistoryItems for fuzzy matching
 type historySourceSubset struct {
 	indices []int
@@ -323,7 +603,7 @@ type historySourceSubset struct {
 }
 
 func (h historySourceSubset) String(i int) string {
-	return h.items[h.indices[i]].Command
+	return h.items[h.indices[i]].searchText()
 }
 
 func (h historySourceSubset) Len() int {
@@ -357,6 +637,16 @@ func (m *Model) toggleHistoryFilter() {
 	m.updateHistorySearch()
 }
 
+// toggleHistoryMatchMode switches between fuzzy and substring matching
+func (m *Model) toggleHistoryMatchMode() {
+	if m.historySearchState.matchMode == HistoryMatchFuzzy {
+		m.historySearchState.matchMode = HistoryMatchSubstring
+	} else {
+		m.historySearchState.matchMode = HistoryMatchFuzzy
+	}
+	m.updateHistorySearch()
+}
+
 // toggleHistorySort cycles through sort modes
 func (m *Model) toggleHistorySort() {
 	switch m.historySearchState.sortMode {
@@ -371,3 +661,53 @@ func (m *Model) toggleHistorySort() {
 	}
 	m.updateHistorySearch()
 }
+
+// updateInlineHistorySearch handles keys during an inline search. The buffer
+// always holds the selected match, so accepting just leaves search mode.
+func (m Model) updateInlineHistorySearch(msg tea.KeyMsg) Model {
+	switch {
+	case key.Matches(msg, m.KeyMap.ReverseSearch):
+		// Move on to the next older match, staying on the oldest one
+		if m.historySearchState.selected < len(m.historySearchState.filteredIndices)-1 {
+			m.historySearchState.selected++
+		}
+		m.showInlineHistoryMatch()
+	case msg.String() == "ctrl+g" || msg.String() == "ctrl+c" || msg.String() == "escape" || msg.String() == "esc":
+		m.inReverseSearch = false
+		m.SetValue(string(m.reverseSearchOriginal))
+		m.SetCursor(m.reverseSearchOriginalPos)
+	case key.Matches(msg, m.KeyMap.DeleteCharacterBackward):
+		if len(m.reverseSearchQuery) > 0 {
+			runes := []rune(m.reverseSearchQuery)
+			m.reverseSearchQuery = string(runes[:len(runes)-1])
+			m.updateHistorySearch()
+			m.showInlineHistoryMatch()
+		}
+	case len(msg.Runes) > 0 && unicode.IsPrint(msg.Runes[0]) && !msg.Alt:
+		m.reverseSearchQuery += string(msg.Runes)
+		m.updateHistorySearch()
+		m.showInlineHistoryMatch()
+	default:
+		// Any other key, such as Enter or an arrow, ends the search on the
+		// current match and then does what it normally does
+		m.inReverseSearch = false
+		updated, _ := m.Update(msg)
+		return updated
+	}
+	return m
+}
+
+// showInlineHistoryMatch puts the selected match in the buffer, with the cursor
+// at the start of the matched text. Without a match the buffer is left as is,
+// like bash's failing search.
+func (m *Model) showInlineHistoryMatch() {
+	idx := m.historySearchState.selected
+	if idx < 0 || idx >= len(m.historySearchState.filteredIndices) {
+		return
+	}
+	command := m.historyItems[m.historySearchState.filteredIndices[idx]].Command
+	m.SetValue(command)
+	if offset := strings.LastIndex(command, m.reverseSearchQuery); offset >= 0 {
+		m.SetCursor(len([]rune(command[:offset])))
+	}
+}
@@ -1,10 +1,12 @@
 package shellinput
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -137,3 +139,179 @@ func TestRichHistorySearchCancel(t *testing.T) {
 	updatedModel, _ = updatedModel.Update(msg)
 	assert.False(t, updatedModel.inReverseSearch)
 }
+
+func TestInlineHistorySearch(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.HistorySearchStyle = HistorySearchInline
+	model.SetRichHistory([]HistoryItem{
+		{Command: "git push origin main"},
+		{Command: "make test"},
+		{Command: "git pull"},
+		{Command: "git push origin main"},
+	})
+	model.SetValue("draft")
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.True(t, updatedModel.InReverseSearch())
+	assert.False(t, updatedModel.HistorySearchBoxVisible(), "Inline search has no list")
+	assert.Empty(t, updatedModel.HistorySearchBoxView(5, 80))
+
+	// The newest match fills the buffer as the query is typed
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("git p")})
+	assert.Equal(t, "git push origin main", updatedModel.Value())
+	assert.Equal(t, 0, updatedModel.Position(), "Cursor should sit on the matched text")
+
+	// Ctrl+R again moves to older matches, skipping duplicates, and stops at the oldest
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.Equal(t, "git pull", updatedModel.Value())
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.Equal(t, "git pull", updatedModel.Value())
+
+	// A query without matches keeps the last match
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	assert.Equal(t, "git pull", updatedModel.Value())
+	assert.Contains(t, updatedModel.View(), "failed reverse-i-search")
+
+	// Escape restores the line from before the search
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.False(t, updatedModel.InReverseSearch())
+	assert.Equal(t, "draft", updatedModel.Value())
+
+	// Editing keys end the search and keep the match
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("make")})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEnd})
+	assert.False(t, updatedModel.InReverseSearch())
+	assert.Equal(t, "make test", updatedModel.Value())
+	assert.Equal(t, len("make test"), updatedModel.Position())
+}
+
+func TestHistorySearchTypoTolerance(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.SetRichHistory([]HistoryItem{
+		{Command: "git status"},
+		{Command: "make test"},
+		{Command: "ls -la /var/log/messages"},
+	})
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("gti status")})
+	assert.Equal(t, []int{0}, updatedModel.historySearchState.filteredIndices)
+
+	// A substituted character is tolerated too
+	updatedModel.reverseSearchQuery = "maks test"
+	updatedModel.updateHistorySearch()
+	assert.Equal(t, []int{1}, updatedModel.historySearchState.filteredIndices)
+
+	// Characters spread far apart are not a typo match
+	updatedModel.reverseSearchQuery = "gxas"
+	updatedModel.updateHistorySearch()
+	assert.Empty(t, updatedModel.historySearchState.filteredIndices)
+
+	// Short queries are matched exactly
+	updatedModel.reverseSearchQuery = "gx"
+	updatedModel.updateHistorySearch()
+	assert.Empty(t, updatedModel.historySearchState.filteredIndices)
+}
+
+func TestHistorySearchRanking(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.SetRichHistory([]HistoryItem{
+		{Command: "gsh status"}, // too different even with a typo
+		{Command: "git status"}, // exact match
+		{Command: "gti status"}, // only matches with a typo
+	})
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("git st")})
+
+	// Recent order for exact matches, then typo matches
+	assert.Equal(t, []int{1, 2}, updatedModel.historySearchState.filteredIndices)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, updatedModel.historySearchState.matchedIndexes[1])
+
+	// Relevance puts the best scored exact match first
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	assert.Equal(t, HistorySortRelevance, updatedModel.historySearchState.sortMode)
+	assert.Equal(t, 1, updatedModel.historySearchState.filteredIndices[0])
+	assert.Equal(t, 2, updatedModel.historySearchState.filteredIndices[len(updatedModel.historySearchState.filteredIndices)-1])
+}
+
+func TestHistorySearchSubstringMode(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.SetRichHistory([]HistoryItem{
+		{Command: "git commit"},
+		{Command: "go test ./..."},
+		{Command: "GO111MODULE=on go build"},
+	})
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("go")})
+	assert.Len(t, updatedModel.historySearchState.filteredIndices, 3)
+
+	// Ctrl+T switches to substring matching, which ignores case
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	assert.Equal(t, HistoryMatchSubstring, updatedModel.historySearchState.matchMode)
+	assert.Equal(t, []int{1, 2}, updatedModel.historySearchState.filteredIndices)
+	assert.Equal(t, []int{0, 1}, updatedModel.historySearchState.matchedIndexes[2])
+	assert.Contains(t, updatedModel.HistorySearchBoxView(10, 80), "Match: Substring")
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	assert.Equal(t, HistoryMatchFuzzy, updatedModel.historySearchState.matchMode)
+	assert.Len(t, updatedModel.historySearchState.filteredIndices, 3)
+}
+
+func TestHighlightMatches(t *testing.T) {
+	base := lipgloss.NewStyle()
+	marker := lipgloss.NewStyle().Transform(strings.ToUpper)
+	assert.Equal(t, "git status", highlightMatches("git status", nil, 10, base, marker))
+	assert.Equal(t, "GIt STatus", highlightMatches("git status", []int{0, 1, 4, 5}, 10, base, marker))
+
+	// Offsets at or past the limit, such as an ellipsis, are not highlighted
+	assert.Equal(t, "GIt…", highlightMatches("git…", []int{0, 1, 3}, 3, base, marker))
+}
+
+func TestRichHistorySearchExplain(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.SetRichHistory([]HistoryItem{{Command: "tar -xzf backup.tgz"}})
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.Equal(t, "tar -xzf backup.tgz", updatedModel.SelectedHistoryCommand())
+
+	// Alt+E asks for an explanation and stays in the search
+	updatedModel, cmd := updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e"), Alt: true})
+	assert.True(t, updatedModel.inReverseSearch)
+	assert.Equal(t, "", updatedModel.Value())
+	if assert.NotNil(t, cmd) {
+		assert.Equal(t, ExplainHistoryMsg{Command: "tar -xzf backup.tgz"}, cmd())
+	}
+
+	// Nothing to explain without a match
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("zzz")})
+	assert.Equal(t, "", updatedModel.SelectedHistoryCommand())
+	_, cmd = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e"), Alt: true})
+	assert.Nil(t, cmd)
+}
+
+func TestHistorySearchNotes(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.SetRichHistory([]HistoryItem{
+		{Command: "ls"},
+		{Command: "make deploy ENV=staging", Note: "the one that actually worked"},
+	})
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.Contains(t, updatedModel.HistorySearchBoxView(10, 80), "make deploy ENV=staging — the one that actually worked")
+
+	// Commands can be found by their note
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("actually worked")})
+	assert.Equal(t, []int{1}, updatedModel.historySearchState.filteredIndices)
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "make deploy ENV=staging", updatedModel.Value(), "The note isn't part of the command")
+}
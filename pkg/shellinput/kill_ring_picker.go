@@ -0,0 +1,109 @@
+package shellinput
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KillRing returns the contents of the kill ring, most recently killed first.
+func (m Model) KillRing() []string {
+	entries := make([]string, len(m.killRing))
+	for i, killed := range m.killRing {
+		entries[i] = string(killed)
+	}
+	return entries
+}
+
+// InKillRingPicker returns true while the kill ring picker is open.
+func (m Model) InKillRingPicker() bool {
+	return m.completion.active && m.completion.killRingPicker
+}
+
+// openKillRingPicker lists the kill ring in the completion info box and
+// previews the selected entry at the cursor. Pressing the picker key again,
+// TAB or Shift+TAB moves through the entries, Enter keeps the selected one and
+// Escape restores the original line.
+func (m *Model) openKillRingPicker() {
+	if m.InKillRingPicker() {
+		m.moveKillRingSelection(1)
+		return
+	}
+
+	if len(m.killRing) == 0 {
+		return
+	}
+	if len(m.killRing) == 1 {
+		m.yankKillBuffer()
+		return
+	}
+
+	suggestions := make([]CompletionCandidate, len(m.killRing))
+	for i, killed := range m.killRing {
+		entry := string(killed)
+		suggestions[i] = CompletionCandidate{
+			Value:       entry,
+			Display:     strings.ReplaceAll(entry, "\n", "⏎"),
+			Description: fmt.Sprintf("#%d", i+1),
+		}
+	}
+
+	m.resetCompletion()
+	m.completion.active = true
+	m.completion.killRingPicker = true
+	m.completion.suggestions = suggestions
+	m.completion.startPos = m.pos
+	m.completion.endPos = m.pos
+	m.completion.activateInfoBox(m.Value())
+	m.completion.selected = 0
+	m.applyKillRingSelection()
+}
+
+// moveKillRingSelection selects the next (delta > 0) or previous entry
+func (m *Model) moveKillRingSelection(delta int) {
+	if delta > 0 {
+		m.completion.nextSuggestion()
+	} else {
+		m.completion.prevSuggestion()
+	}
+	m.applyKillRingSelection()
+}
+
+// applyKillRingSelection replaces the previewed entry with the selected one.
+// Unlike applySuggestion, positions are in runes since kill ring entries often
+// contain multi-byte text.
+func (m *Model) applyKillRingSelection() {
+	entry := m.killRing[m.completion.selected]
+	value := m.values[m.selectedValueIndex]
+	start := clamp(m.completion.startPos, 0, len(value))
+	end := clamp(m.completion.endPos, start, len(value))
+
+	newValue := make([]rune, 0, len(value)-end+start+len(entry))
+	newValue = append(newValue, value[:start]...)
+	newValue = append(newValue, entry...)
+	newValue = append(newValue, value[end:]...)
+
+	m.Err = m.validate(newValue)
+	m.values[0] = newValue
+	m.selectedValueIndex = 0
+	m.SetCursor(start + len(entry))
+	m.completion.endPos = start + len(entry)
+}
+
+// acceptKillRingSelection closes the picker keeping the selected entry. The
+// entry counts as a yank, so yank-pop keeps cycling from it.
+func (m *Model) acceptKillRingSelection() {
+	m.killRingIndex = m.completion.selected
+	m.lastYankStart = m.completion.startPos
+	m.lastYankEnd = m.completion.endPos
+	m.resetCompletion()
+	m.lastYankActive = true
+	m.lastCommandWasKill = false
+}
+
+// cancelKillRingPicker closes the picker and restores the line as it was
+func (m *Model) cancelKillRingPicker() {
+	start := m.completion.startPos
+	originalText := m.completion.cancelCompletion()
+	m.SetValue(originalText)
+	m.SetCursor(start)
+}
@@ -33,15 +33,13 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/atinylittleshell/gsh/pkg/textwidth"
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/runeutil"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/muesli/ansi"
-	"github.com/muesli/reflow/wrap"
-	"github.com/rivo/uniseg"
 )
 
 // Internal messages for clipboard operations.
@@ -74,6 +72,7 @@ type KeyMap struct {
 	CharacterForward        key.Binding
 	CharacterBackward       key.Binding
 	WordForward             key.Binding
+	AcceptSuggestionEdit    key.Binding
 	WordBackward            key.Binding
 	DeleteWordBackward      key.Binding
 	DeleteWordForward       key.Binding
@@ -86,6 +85,9 @@ type KeyMap struct {
 	Paste                   key.Binding
 	Yank                    key.Binding
 	YankPop                 key.Binding
+	KillRingPicker          key.Binding
+	HistoryComplete         key.Binding
+	CommandPalette          key.Binding
 	NextValue               key.Binding
 	PrevValue               key.Binding
 	Complete                key.Binding
@@ -93,6 +95,7 @@ type KeyMap struct {
 	ClearScreen             key.Binding
 	ReverseSearch           key.Binding
 	HistorySort             key.Binding
+	HistoryExplain          key.Binding
 }
 
 // DefaultKeyMap is the default set of key bindings for navigating and acting
@@ -101,6 +104,7 @@ var DefaultKeyMap = KeyMap{
 	CharacterForward:        key.NewBinding(key.WithKeys("right", "ctrl+f")),
 	CharacterBackward:       key.NewBinding(key.WithKeys("left", "ctrl+b")),
 	WordForward:             key.NewBinding(key.WithKeys("alt+right", "ctrl+right", "alt+f")),
+	AcceptSuggestionEdit:    key.NewBinding(key.WithKeys("alt+right")),
 	WordBackward:            key.NewBinding(key.WithKeys("alt+left", "ctrl+left", "alt+b")),
 	DeleteWordBackward:      key.NewBinding(key.WithKeys("alt+backspace", "ctrl+w")),
 	DeleteWordForward:       key.NewBinding(key.WithKeys("alt+delete", "alt+d")),
@@ -115,11 +119,15 @@ var DefaultKeyMap = KeyMap{
 	Paste:                   key.NewBinding(key.WithKeys("ctrl+v")),
 	Yank:                    key.NewBinding(key.WithKeys("ctrl+y")),
 	YankPop:                 key.NewBinding(key.WithKeys("alt+y")),
+	KillRingPicker:          key.NewBinding(key.WithKeys("alt+k")),
+	HistoryComplete:         key.NewBinding(key.WithKeys("alt+/")),
+	CommandPalette:          key.NewBinding(key.WithKeys("ctrl+@")), // Ctrl+Space
 	NextValue:               key.NewBinding(key.WithKeys("down", "ctrl+n")),
 	PrevValue:               key.NewBinding(key.WithKeys("up", "ctrl+p")),
 	ClearScreen:             key.NewBinding(key.WithKeys("ctrl+l")),
 	ReverseSearch:           key.NewBinding(key.WithKeys("ctrl+r")),
 	HistorySort:             key.NewBinding(key.WithKeys("ctrl+o")),
+	HistoryExplain:          key.NewBinding(key.WithKeys("alt+e")),
 }
 
 const (
@@ -207,6 +215,33 @@ type Model struct {
 	// Should the input suggest to complete
 	ShowSuggestions bool
 
+	// SuggestAfterKill keeps suggestions active right after a kill command
+	// instead of suppressing them until the user enters more text.
+	SuggestAfterKill bool
+
+	// CompletionPolicy decides whether Tab accepts the prediction or opens the
+	// completion box, and whether the prediction shows while the box is open
+	CompletionPolicy CompletionPolicy
+
+	// AutoCompleteAfter opens the completion box by itself once typing pauses
+	// on a word of at least this many characters. 0 leaves it to Tab.
+	AutoCompleteAfter int
+	// autoCompleteRequestID identifies the latest key press, so an automatic
+	// completion scheduled before it is dropped
+	autoCompleteRequestID int
+
+	// HistorySearchStyle selects between the rich list picker and classic
+	// incremental search for Ctrl+R.
+	HistorySearchStyle HistorySearchStyle
+
+	// SuggestCase controls how the casing of typed text is matched against
+	// suggestions and shown in the ghost text.
+	SuggestCase SuggestCase
+
+	// suggestionAccepted is set once a suggestion has been accepted into the
+	// value, and cleared when the value is replaced
+	suggestionAccepted bool
+
 	// suppressSuggestionsUntilInput temporarily disables autocomplete hints
 	// until the user enters more text. This is used, for example, when the
 	// user trims the line with Ctrl+K so that ghost text and help reflect
@@ -224,13 +259,26 @@ type Model struct {
 	values             [][]rune
 	selectedValueIndex int
 
+	// historyContinuations holds the leading lines of history entries that
+	// span multiple lines, keyed by their index in values. The corresponding
+	// value only holds the last line, which is what the user edits.
+	historyContinuations map[int][]string
+
 	// Reverse search state
 	inReverseSearch    bool
 	reverseSearchQuery string
+	// reverseSearchOriginal is the line before an inline search started, so
+	// cancelling can restore it
+	reverseSearchOriginal    []rune
+	reverseSearchOriginalPos int
 
 	// Rich history search
 	historyItems       []HistoryItem
 	historySearchState historySearchState
+
+	// Command palette entries and the query filtering them
+	paletteEntries []CompletionCandidate
+	paletteQuery   string
 }
 
 // New creates a new model with default settings.
@@ -261,6 +309,13 @@ func (m *Model) SetValue(s string) {
 	runes := m.san().Sanitize([]rune(s))
 	err := m.validate(runes)
 	m.setValueInternal(runes, err)
+	m.suggestionAccepted = false
+}
+
+// SuggestionAccepted returns whether a suggestion was accepted into the value
+// since it was last set, with Right, Tab or Alt+Right.
+func (m Model) SuggestionAccepted() bool {
+	return m.suggestionAccepted
 }
 
 func (m *Model) setValueInternal(runes []rune, err error) {
@@ -291,6 +346,11 @@ func (m Model) InReverseSearch() bool {
 	return m.inReverseSearch
 }
 
+// CompletionBoxVisible returns true while the completion info box lists candidates.
+func (m Model) CompletionBoxVisible() bool {
+	return m.completion.shouldShowInfoBox()
+}
+
 // HistorySearchBoxView returns the rendered history search box if active.
 // Note: This is a wrapper to allow the method to be called from the interface/package level if needed,
 // but the actual implementation is in history_search.go.
@@ -354,11 +414,20 @@ func (m *Model) SetSuggestions(suggestions []string) {
 	m.updateSuggestions()
 }
 
-// SetHistoryValues sets the suggestions for the input.
+// SetHistoryValues sets the history values that can be navigated with the
+// up and down arrow keys. Entries containing newlines keep their structure:
+// the last line becomes the editable value and the lines before it are
+// available through HistoryContinuationLines.
 func (m *Model) SetHistoryValues(historyValues []string) {
 	m.values = append([][]rune{m.values[0]}, make([][]rune, len(historyValues))...)
+	m.historyContinuations = make(map[int][]string)
 
 	for i, s := range historyValues {
+		if lines := strings.Split(strings.TrimRight(s, "\n"), "\n"); len(lines) > 1 {
+			m.historyContinuations[i+1] = lines[:len(lines)-1]
+			m.values[i+1] = m.san().Sanitize([]rune(lines[len(lines)-1]))
+			continue
+		}
 		m.values[i+1] = m.san().Sanitize([]rune(s))
 	}
 
@@ -368,6 +437,15 @@ func (m *Model) SetHistoryValues(historyValues []string) {
 	}
 }
 
+// HistoryContinuationLines returns the lines preceding the current value when
+// the selected history entry spans multiple lines, or nil otherwise.
+func (m Model) HistoryContinuationLines() []string {
+	if m.selectedValueIndex == 0 {
+		return nil
+	}
+	return m.historyContinuations[m.selectedValueIndex]
+}
+
 // rsan initializes or retrieves the rune sanitizer.
 func (m *Model) san() runeutil.Sanitizer {
 	if m.rsan == nil {
@@ -468,9 +546,11 @@ func (m *Model) recordKill(killed []rune, direction killDirection) {
 
 	m.lastKillDirection = direction
 	m.lastYankActive = false
-	m.suppressSuggestionsUntilInput = true
-	m.matchedSuggestions = [][]rune{}
-	m.currentSuggestionIndex = 0
+	if !m.SuggestAfterKill {
+		m.suppressSuggestionsUntilInput = true
+		m.matchedSuggestions = [][]rune{}
+		m.currentSuggestionIndex = 0
+	}
 	m.resetCompletion()
 }
 
@@ -688,7 +768,7 @@ func (m *Model) wordForward() {
 func (m Model) echoTransform(v string) string {
 	switch m.EchoMode {
 	case EchoPassword:
-		return strings.Repeat(string(m.EchoCharacter), uniseg.StringWidth(v))
+		return strings.Repeat(string(m.EchoCharacter), textwidth.StringWidth(v))
 	case EchoNone:
 		return ""
 	case EchoNormal:
@@ -707,10 +787,16 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	// Let's remember where the position of the cursor currently is so that if
 	// the cursor position changes, we can reset the blink.
 	oldPos := m.pos
+	var autoComplete tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.autoCompleteRequestID++
+
 		// Handle reverse search specific keys
+		if m.inReverseSearch && m.HistorySearchStyle == HistorySearchInline {
+			return m.updateInlineHistorySearch(msg), nil
+		}
 		if m.inReverseSearch {
 			switch {
 			case key.Matches(msg, m.KeyMap.ReverseSearch):
@@ -738,6 +824,13 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			case key.Matches(msg, m.KeyMap.HistorySort):
 				m.toggleHistorySort()
 				return m, nil
+			// Toggle between fuzzy and substring matching with Ctrl+T
+			case msg.String() == "ctrl+t":
+				m.toggleHistoryMatchMode()
+				return m, nil
+			// Explain the selected command instead of inserting it
+			case key.Matches(msg, m.KeyMap.HistoryExplain):
+				return m, m.explainHistorySelection()
 			// Left/Right: Accept and edit?
 			case key.Matches(msg, m.KeyMap.CharacterBackward), key.Matches(msg, m.KeyMap.CharacterForward):
 				m.acceptRichReverseSearch()
@@ -765,6 +858,38 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			}
 		}
 
+		if m.InCommandPalette() {
+			m.updateCommandPalette(msg)
+			return m, nil
+		}
+
+		// The kill ring picker reuses the completion state but has its own keys
+		if m.InKillRingPicker() {
+			switch {
+			case msg.String() == "escape" || msg.String() == "esc":
+				m.cancelKillRingPicker()
+				return m, nil
+			case msg.String() == "enter":
+				m.acceptKillRingSelection()
+				m.updateSuggestions()
+				return m, nil
+			case key.Matches(msg, m.KeyMap.KillRingPicker) || key.Matches(msg, m.KeyMap.Complete):
+				m.moveKillRingSelection(1)
+				return m, nil
+			case key.Matches(msg, m.KeyMap.PrevSuggestion):
+				m.moveKillRingSelection(-1)
+				return m, nil
+			}
+			// Any other key keeps the previewed entry and is handled as usual
+			m.acceptKillRingSelection()
+		}
+
+		// Escape puts back the line history completion replaced
+		if m.InHistoryCompletion() && (msg.String() == "esc" || msg.String() == "escape") {
+			m.cancelCompletion()
+			return m, nil
+		}
+
 		// Handle completion-specific keys first
 		if m.completion.active {
 			switch msg.String() {
@@ -784,15 +909,18 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			}
 		}
 
-		// Reset completion state for any key except TAB, Shift+TAB, Escape, and Enter
+		// Reset completion state for any key except TAB, Shift+TAB, Escape, and
+		// Enter, and the history completion key while it lists history
 		if !key.Matches(msg, m.KeyMap.Complete) && !key.Matches(msg, m.KeyMap.PrevSuggestion) &&
-			msg.String() != "escape" && msg.String() != "enter" {
+			msg.String() != "escape" && msg.String() != "enter" &&
+			!(key.Matches(msg, m.KeyMap.HistoryComplete) && m.InHistoryCompletion()) {
 			m.resetCompletion()
 		}
 
 		killCommand := key.Matches(msg, m.KeyMap.DeleteBeforeCursor) || key.Matches(msg, m.KeyMap.DeleteAfterCursor) ||
 			key.Matches(msg, m.KeyMap.DeleteWordBackward) || key.Matches(msg, m.KeyMap.DeleteWordForward)
-		yankCommand := key.Matches(msg, m.KeyMap.Yank) || key.Matches(msg, m.KeyMap.YankPop)
+		yankCommand := key.Matches(msg, m.KeyMap.Yank) || key.Matches(msg, m.KeyMap.YankPop) ||
+			key.Matches(msg, m.KeyMap.KillRingPicker)
 
 		if m.suppressSuggestionsUntilInput && !killCommand {
 			m.suppressSuggestionsUntilInput = false
@@ -802,9 +930,10 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case key.Matches(msg, m.KeyMap.ReverseSearch):
 			m.toggleReverseSearch()
 			return m, nil
+		case key.Matches(msg, m.KeyMap.Complete) && m.tabAcceptsPrediction():
+			m.acceptSuggestion()
 		case key.Matches(msg, m.KeyMap.Complete):
-			m.handleCompletion()
-			return m, nil
+			return m, m.handleCompletion()
 		case key.Matches(msg, m.KeyMap.PrevSuggestion) && m.completion.active:
 			m.handleBackwardCompletion()
 			return m, nil
@@ -827,20 +956,15 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			if m.pos > 0 {
 				m.SetCursor(m.pos - 1)
 			}
+		case key.Matches(msg, m.KeyMap.AcceptSuggestionEdit) && m.canAcceptSuggestion():
+			m.acceptSuggestionForEditing()
 		case key.Matches(msg, m.KeyMap.WordForward):
 			m.wordForward()
 		case key.Matches(msg, m.KeyMap.CharacterForward):
 			if m.pos < len(m.values[m.selectedValueIndex]) {
 				m.SetCursor(m.pos + 1)
 			} else if m.canAcceptSuggestion() {
-				newValue := cloneConcatRunes(
-					m.values[m.selectedValueIndex],
-					m.matchedSuggestions[m.currentSuggestionIndex][len(m.values[m.selectedValueIndex]):],
-				)
-				m.Err = m.validate(newValue)
-				m.values[0] = newValue
-				m.selectedValueIndex = 0
-				m.CursorEnd()
+				m.acceptSuggestion()
 			}
 		case key.Matches(msg, m.KeyMap.LineStart):
 			m.CursorStart()
@@ -863,6 +987,13 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.yankKillBuffer()
 		case key.Matches(msg, m.KeyMap.YankPop):
 			m.yankPop()
+		case key.Matches(msg, m.KeyMap.KillRingPicker):
+			m.openKillRingPicker()
+		case key.Matches(msg, m.KeyMap.CommandPalette):
+			m.openCommandPalette()
+			return m, nil
+		case key.Matches(msg, m.KeyMap.HistoryComplete):
+			m.openHistoryCompletion()
 		case key.Matches(msg, m.KeyMap.DeleteWordForward):
 			m.deleteWordForward()
 		case key.Matches(msg, m.KeyMap.NextValue):
@@ -877,6 +1008,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		default:
 			// Input one or more regular characters.
 			m.insertRunesFromUserInput(msg.Runes)
+			autoComplete = m.scheduleAutoComplete()
 		}
 
 		if !killCommand && !yankCommand {
@@ -894,6 +1026,12 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		// Update help info for special commands
 		m.updateHelpInfo()
 
+	case completionBatchMsg:
+		return m, m.handleCompletionBatch(msg)
+
+	case autoCompleteMsg:
+		return m, m.handleAutoComplete(msg)
+
 	case pasteMsg:
 		m.insertRunesFromUserInput([]rune(msg))
 
@@ -901,7 +1039,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		m.Err = msg
 	}
 
-	var cmds []tea.Cmd
+	cmds := []tea.Cmd{autoComplete}
 	var cmd tea.Cmd
 
 	m.Cursor, cmd = m.Cursor.Update(msg)
@@ -937,6 +1075,9 @@ func (m Model) View() string {
 
 		return m.ReverseSearchPromptStyle.Render(fmt.Sprintf("%s`%s': %s", prefix, m.reverseSearchQuery, matchText))
 	}
+	if m.InCommandPalette() {
+		return m.ReverseSearchPromptStyle.Render(fmt.Sprintf("(palette)`%s': %s", m.paletteQuery, m.completion.currentSuggestion()))
+	}
 
 	styleText := m.TextStyle.Inline(true).Render
 
@@ -951,7 +1092,7 @@ func (m Model) View() string {
 		v += styleText(m.echoTransform(string(value[pos+1:]))) // text after cursor
 		v += m.completionView(0)                               // suggested completion
 	} else {
-		if m.canAcceptSuggestion() {
+		if m.showsPrediction() {
 			suggestion := m.matchedSuggestions[m.currentSuggestionIndex]
 			if len(value) < len(suggestion) {
 				m.Cursor.TextStyle = m.CompletionStyle
@@ -969,7 +1110,7 @@ func (m Model) View() string {
 		v += m.completionSuffixView() // suffix from active completion (e.g., "/" for directories)
 	}
 
-	totalWidth := uniseg.StringWidth(v)
+	totalWidth := textwidth.StringWidth(v)
 
 	// If a max width is set, we need to respect the horizontal boundary
 	if m.Width > 0 {
@@ -981,7 +1122,7 @@ func (m Model) View() string {
 			}
 			v += styleText(strings.Repeat(" ", padding))
 		} else {
-			v = wrap.String(v, m.Width)
+			v = textwidth.Wrap(v, m.Width)
 		}
 	}
 
@@ -1057,7 +1198,7 @@ func (m Model) completionView(offset int) string {
 		style = m.CompletionStyle.Inline(true).Render
 	)
 
-	if m.canAcceptSuggestion() {
+	if m.showsPrediction() {
 		suggestion := m.matchedSuggestions[m.currentSuggestionIndex]
 		if len(value) < len(suggestion) {
 			return style(string(suggestion[len(value)+offset:]))
@@ -1096,11 +1237,33 @@ func (m Model) CompletionBoxView(height int, width int) string {
 		height = 4 // default fallback
 	}
 
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	totalItems := len(m.completion.suggestions)
 	if totalItems == 0 {
+		if m.completion.loading {
+			return footerStyle.Render("   loading...")
+		}
 		return ""
 	}
 
+	// Keep the last row to tell that more candidates are coming, or how many
+	// were left out
+	footer := ""
+	if m.completion.loading {
+		footer = "   loading..."
+	} else if m.completion.omitted > 0 {
+		footer = fmt.Sprintf("   ... %d more, refine your input", m.completion.omitted)
+	}
+	if footer != "" && height > 1 {
+		height--
+	}
+	// The provider's heading takes the first row, if there's room for it
+	header := ""
+	if m.completion.header != "" && height > 1 {
+		header = m.completion.header
+		height--
+	}
+
 	// Check if we need to show descriptions (Zsh style)
 	hasDescriptions := false
 	maxCandidateWidth := 0
@@ -1110,12 +1273,11 @@ func (m Model) CompletionBoxView(height int, width int) string {
 			hasDescriptions = true
 		}
 
-		// Use ansi.PrintableRuneWidth to get visual width without ANSI codes
 		displayWidth := 0
 		if s.Display != "" {
-			displayWidth = ansi.PrintableRuneWidth(s.Display)
+			displayWidth = textwidth.StringWidth(s.Display)
 		} else {
-			displayWidth = ansi.PrintableRuneWidth(s.Value)
+			displayWidth = textwidth.StringWidth(s.Value)
 		}
 		if displayWidth > maxCandidateWidth {
 			maxCandidateWidth = displayWidth
@@ -1166,6 +1328,10 @@ func (m Model) CompletionBoxView(height int, width int) string {
 	}
 
 	var content strings.Builder
+	if header != "" {
+		content.WriteString(footerStyle.Render("   " + header))
+		content.WriteString("\n")
+	}
 
 	// Render rows
 	for r := 0; r < height; r++ {
@@ -1200,16 +1366,14 @@ func (m Model) CompletionBoxView(height int, width int) string {
 			if hasDescriptions {
 				// Render as two columns: Candidate | Description
 				// Pad the candidate to align descriptions
-				// Use ansi.PrintableRuneWidth to get visual width without ANSI codes
-				visualWidth := ansi.PrintableRuneWidth(displayText)
+						visualWidth := textwidth.StringWidth(displayText)
 				padding := maxCandidateWidth - visualWidth + 2
 				itemStr += strings.Repeat(" ", padding)
 				itemStr += lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(candidate.Description)
 			} else {
 				// Pad the column (except the last one)
 				if c < numColumns-1 {
-					// Use ansi.PrintableRuneWidth to get visual width without ANSI codes
-					itemWidth := ansi.PrintableRuneWidth(itemStr)
+								itemWidth := textwidth.StringWidth(itemStr)
 					if itemWidth < maxItemWidth {
 						itemStr += strings.Repeat(" ", maxItemWidth-itemWidth)
 					} else {
@@ -1230,6 +1394,11 @@ func (m Model) CompletionBoxView(height int, width int) string {
 		}
 	}
 
+	if footer != "" {
+		content.WriteString("\n")
+		content.WriteString(footerStyle.Render(footer))
+	}
+
 	return content.String()
 }
 
@@ -1280,6 +1449,47 @@ func (m *Model) CurrentSuggestion() string {
 	return string(m.matchedSuggestions[m.currentSuggestionIndex])
 }
 
+// acceptSuggestion completes the value with the rest of the suggestion and
+// moves the cursor to its end
+func (m *Model) acceptSuggestion() {
+	newValue := cloneConcatRunes(
+		m.values[m.selectedValueIndex],
+		m.matchedSuggestions[m.currentSuggestionIndex][len(m.values[m.selectedValueIndex]):],
+	)
+	m.Err = m.validate(newValue)
+	m.values[0] = newValue
+	m.selectedValueIndex = 0
+	m.suggestionAccepted = true
+	m.CursorEnd()
+}
+
+// acceptSuggestionForEditing replaces the value with the whole suggestion but,
+// unlike accepting it with Right, leaves the cursor where the suggestion starts
+// to differ from what was typed, so it can be tweaked before running.
+func (m *Model) acceptSuggestionForEditing() {
+	value := m.values[m.selectedValueIndex]
+	suggestion := m.matchedSuggestions[m.currentSuggestionIndex]
+	divergence := suggestionDivergence(value, suggestion)
+
+	newValue := cloneConcatRunes(suggestion, nil)
+	m.Err = m.validate(newValue)
+	m.values[0] = newValue
+	m.selectedValueIndex = 0
+	m.suggestionAccepted = true
+	m.SetCursor(divergence)
+}
+
+// suggestionDivergence returns the index of the first rune where suggestion
+// differs from value, or the length of value if it's a prefix of suggestion.
+func suggestionDivergence(value, suggestion []rune) int {
+	for i := range value {
+		if i >= len(suggestion) || value[i] != suggestion[i] {
+			return i
+		}
+	}
+	return len(value)
+}
+
 // canAcceptSuggestion returns whether there is an acceptable suggestion to
 // autocomplete the current value.
 func (m *Model) canAcceptSuggestion() bool {
@@ -1304,10 +1514,8 @@ func (m *Model) updateSuggestions() {
 
 	matches := [][]rune{}
 	for _, s := range m.suggestions {
-		suggestion := string(s)
-
-		if strings.HasPrefix(strings.ToLower(suggestion), strings.ToLower(string(m.values[m.selectedValueIndex]))) {
-			matches = append(matches, []rune(suggestion))
+		if match, ok := matchSuggestion(m.values[m.selectedValueIndex], string(s), m.SuggestCase); ok {
+			matches = append(matches, match)
 		}
 	}
 	if !reflect.DeepEqual(matches, m.matchedSuggestions) {
@@ -1368,6 +1576,8 @@ func (m *Model) toggleReverseSearch() {
 	} else {
 		m.inReverseSearch = true
 		m.reverseSearchQuery = ""
+		m.reverseSearchOriginal = cloneRunes(m.values[m.selectedValueIndex])
+		m.reverseSearchOriginalPos = m.pos
 		m.updateHistorySearch()
 	}
 }
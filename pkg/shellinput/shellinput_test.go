@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/atinylittleshell/gsh/pkg/textwidth"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -37,6 +38,21 @@ func (m *mockCompletionProvider) GetHelpInfo(line string, pos int) string {
 	return ""
 }
 
+// quotedPathCompletionProvider completes paths the way the shell provider does
+// while a quote is still open
+type quotedPathCompletionProvider struct{}
+
+func (q *quotedPathCompletionProvider) GetCompletions(line string, pos int) []CompletionCandidate {
+	if strings.HasPrefix(line, "cat \"my big") {
+		return []CompletionCandidate{{Value: "\"my big file.txt\""}}
+	}
+	return nil
+}
+
+func (q *quotedPathCompletionProvider) GetHelpInfo(line string, pos int) string {
+	return ""
+}
+
 type trackingCompletionProvider struct {
 	lastLine string
 	lastPos  int
@@ -120,6 +136,30 @@ func TestCompletion(t *testing.T) {
 	assert.False(t, updatedModel.completion.active, "Completion should not be active when no suggestions available")
 }
 
+func TestCompletionInsideOpenQuote(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.CompletionProvider = &quotedPathCompletionProvider{}
+
+	// The whole quoted word is replaced, not just the text after the last space
+	model.SetValue(`cat "my big fi`)
+	model.CursorEnd()
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, `cat "my big file.txt"`, updatedModel.Value())
+	assert.Equal(t, len(`cat "my big file.txt"`), updatedModel.Position())
+
+	updatedModel.SetCursor(len(`cat "my b`))
+	start, end := updatedModel.getWordBoundary()
+	assert.Equal(t, 4, start)
+	assert.Equal(t, len(`cat "my big file.txt"`), end)
+
+	model.SetValue(`cat my\ big\ fi`)
+	model.CursorEnd()
+	start, end = model.getWordBoundary()
+	assert.Equal(t, 4, start)
+	assert.Equal(t, len(`cat my\ big\ fi`), end)
+}
+
 func TestUpdate(t *testing.T) {
 	model := New()
 	model.Focus()
@@ -259,6 +299,98 @@ func TestCtrlKTruncatesSuggestionsAndHelp(t *testing.T) {
 	assert.Equal(t, updatedModel.Position(), provider.lastPos, "Help should use the truncated cursor position")
 }
 
+func TestSuggestAfterKill(t *testing.T) {
+	tests := []struct {
+		name              string
+		suggestAfterKill  bool
+		expectSuppressed  bool
+		expectSuggestions bool
+	}{
+		{
+			name:              "default suppresses suggestions until new input",
+			suggestAfterKill:  false,
+			expectSuppressed:  true,
+			expectSuggestions: false,
+		},
+		{
+			name:              "enabled keeps suggestions active after kill",
+			suggestAfterKill:  true,
+			expectSuppressed:  false,
+			expectSuggestions: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := New()
+			model.Focus()
+			model.ShowSuggestions = true
+			model.SuggestAfterKill = tt.suggestAfterKill
+			model.SetSuggestions([]string{"hello world"})
+			model.SetValue("hello there")
+			model.SetCursor(len("hello"))
+
+			updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlK})
+
+			assert.Equal(t, "hello", updatedModel.Value())
+			require.Len(t, updatedModel.killRing, 1, "The kill ring should be updated in both modes")
+			assert.Equal(t, tt.expectSuppressed, updatedModel.SuggestionsSuppressedUntilInput())
+			if tt.expectSuggestions {
+				assert.Equal(t, []string{"hello world"}, updatedModel.MatchedSuggestions())
+			} else {
+				assert.Empty(t, updatedModel.MatchedSuggestions())
+			}
+
+			// Typing always brings suggestions back
+			updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{' '}})
+			assert.False(t, updatedModel.SuggestionsSuppressedUntilInput())
+			assert.Equal(t, []string{"hello world"}, updatedModel.MatchedSuggestions())
+		})
+	}
+}
+
+func TestAcceptSuggestionForEditing(t *testing.T) {
+	altRight := tea.KeyMsg{Type: tea.KeyRight, Alt: true}
+
+	t.Run("accepts the whole suggestion and keeps the cursor at the divergence point", func(t *testing.T) {
+		model := New()
+		model.Focus()
+		model.ShowSuggestions = true
+		model.SetSuggestions([]string{"git commit -m \"fix\""})
+		model.SetValue("git co")
+
+		updatedModel, _ := model.Update(altRight)
+
+		assert.Equal(t, "git commit -m \"fix\"", updatedModel.Value())
+		assert.Equal(t, len("git co"), updatedModel.Position())
+	})
+
+	t.Run("cursor stops at the first case difference", func(t *testing.T) {
+		model := New()
+		model.Focus()
+		model.ShowSuggestions = true
+		model.SetSuggestions([]string{"Makefile"})
+		model.SetValue("mak")
+
+		updatedModel, _ := model.Update(altRight)
+
+		assert.Equal(t, "Makefile", updatedModel.Value())
+		assert.Equal(t, 0, updatedModel.Position())
+	})
+
+	t.Run("moves forward a word without a suggestion", func(t *testing.T) {
+		model := New()
+		model.Focus()
+		model.SetValue("echo hello world")
+		model.SetCursor(0)
+
+		updatedModel, _ := model.Update(altRight)
+
+		assert.Equal(t, "echo hello world", updatedModel.Value())
+		assert.Equal(t, len("echo"), updatedModel.Position())
+	})
+}
+
 func TestCtrlUAndCtrlWRespectSuggestionsAndYank(t *testing.T) {
 	provider := &trackingCompletionProvider{}
 
@@ -343,3 +475,185 @@ func TestKillRingAppendAndYankPop(t *testing.T) {
 	updatedModel, _ = updatedModel.Update(msg)
 	assert.Equal(t, "alpha beta  world mars", updatedModel.Value(), "Alt+Y should yank-pop to the previous kill")
 }
+
+func TestKillRingPicker(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.killRing = [][]rune{[]rune("gamma"), []rune("beta"), []rune("alpha")}
+	model.SetValue("echo ")
+	model.SetCursor(5)
+
+	assert.Equal(t, []string{"gamma", "beta", "alpha"}, model.KillRing())
+
+	altK := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}, Alt: true}
+	updatedModel, _ := model.Update(altK)
+	assert.True(t, updatedModel.InKillRingPicker())
+	assert.Equal(t, "echo gamma", updatedModel.Value(), "Opening the picker should preview the latest kill")
+	assert.Contains(t, updatedModel.CompletionBoxView(5, 80), "beta", "The picker should list every entry")
+
+	updatedModel, _ = updatedModel.Update(altK)
+	assert.Equal(t, "echo beta", updatedModel.Value(), "Alt+K again should move to the next entry")
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	assert.Equal(t, "echo gamma", updatedModel.Value(), "Shift+Tab should move back")
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyTab})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, "echo alpha", updatedModel.Value())
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.False(t, updatedModel.InKillRingPicker())
+	assert.Equal(t, "echo alpha", updatedModel.Value(), "Enter should keep the selected entry")
+	assert.Equal(t, 10, updatedModel.Position())
+
+	// Yank-pop continues from the picked entry
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}, Alt: true})
+	assert.Equal(t, "echo gamma", updatedModel.Value())
+
+	// Escape restores the line as it was before the picker opened
+	updatedModel.SetValue("echo ")
+	updatedModel.SetCursor(5)
+	updatedModel, _ = updatedModel.Update(altK)
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.False(t, updatedModel.InKillRingPicker())
+	assert.Equal(t, "echo ", updatedModel.Value())
+	assert.Equal(t, 5, updatedModel.Position())
+
+	// Typing keeps the previewed entry and inserts after it
+	updatedModel, _ = updatedModel.Update(altK)
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'!'}})
+	assert.False(t, updatedModel.InKillRingPicker())
+	assert.Equal(t, "echo gamma!", updatedModel.Value())
+}
+
+// paletteCompletionProvider also lists command palette entries
+type paletteCompletionProvider struct {
+	mockCompletionProvider
+}
+
+func (p *paletteCompletionProvider) GetPaletteEntries() []CompletionCandidate {
+	return []CompletionCandidate{
+		{Value: "@!config", Description: "Open the configuration menu"},
+		{Value: "@!coach heatmap", Description: "View daily activity"},
+		{Value: "@!rehash", Description: "Rebuild the index of commands in PATH"},
+	}
+}
+
+func TestCommandPalette(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.SetValue("ls")
+	model.SetCursor(2)
+
+	// Without a palette provider, Ctrl+Space does nothing
+	ctrlSpace := tea.KeyMsg{Type: tea.KeyCtrlAt}
+	updatedModel, _ := model.Update(ctrlSpace)
+	assert.False(t, updatedModel.InCommandPalette())
+
+	model.CompletionProvider = &paletteCompletionProvider{}
+	updatedModel, _ = model.Update(ctrlSpace)
+	require.True(t, updatedModel.InCommandPalette())
+	assert.Equal(t, "ls", updatedModel.Value(), "Opening the palette leaves the buffer alone")
+	box := updatedModel.CompletionBoxView(5, 100)
+	assert.Contains(t, box, "@!config")
+	assert.Contains(t, box, "Rebuild the index")
+
+	// Typing filters fuzzily, also by description
+	for _, r := range "heat" {
+		updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	assert.Equal(t, "ls", updatedModel.Value(), "Typing goes to the palette query")
+	assert.Contains(t, updatedModel.View(), "@!coach heatmap")
+	assert.NotContains(t, updatedModel.CompletionBoxView(5, 100), "@!config")
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("index")})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.False(t, updatedModel.InCommandPalette())
+	assert.Equal(t, "@!rehash ", updatedModel.Value(), "Enter puts the selected entry in the buffer")
+	assert.Equal(t, 9, updatedModel.Position())
+
+	// Escape closes the palette without touching the buffer
+	updatedModel, _ = updatedModel.Update(ctrlSpace)
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.False(t, updatedModel.InCommandPalette())
+	assert.Equal(t, "@!rehash ", updatedModel.Value())
+}
+
+func TestMultilineHistoryValues(t *testing.T) {
+	model := New()
+	model.Focus()
+	model.SetHistoryValues([]string{"ls -la", "cat <<EOF\nline one\nEOF"})
+
+	assert.Nil(t, model.HistoryContinuationLines(), "Current input has no continuation lines")
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyUp})
+	assert.Equal(t, "ls -la", updatedModel.Value())
+	assert.Nil(t, updatedModel.HistoryContinuationLines(), "Single-line entries have no continuation lines")
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyUp})
+	assert.Equal(t, "EOF", updatedModel.Value(), "The last line of a multiline entry should be editable")
+	assert.Equal(t, []string{"cat <<EOF", "line one"}, updatedModel.HistoryContinuationLines())
+
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, "ls -la", updatedModel.Value())
+	assert.Nil(t, updatedModel.HistoryContinuationLines())
+}
+
+func TestSuggestCase(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        SuggestCase
+		value       string
+		expected    []string
+		current string
+	}{
+		{"suggestion keeps the suggestion's casing", SuggestCaseSuggestion, "GIT", []string{"git status"}, "git status"},
+		{"input adopts the typed casing", SuggestCaseInput, "GIT", []string{"GIT status"}, "GIT status"},
+		{"sensitive skips other casings", SuggestCaseSensitive, "GIT", []string{}, ""},
+		{"sensitive matches the same casing", SuggestCaseSensitive, "gi", []string{"git status"}, "git status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := New()
+			model.Focus()
+			model.ShowSuggestions = true
+			model.SuggestCase = tt.mode
+			model.SetSuggestions([]string{"git status"})
+			model.SetValue(tt.value)
+			model.updateSuggestions()
+
+			assert.Equal(t, tt.expected, model.MatchedSuggestions())
+			assert.Equal(t, tt.current, model.CurrentSuggestion())
+		})
+	}
+
+	assert.Equal(t, SuggestCaseInput, ParseSuggestCase(" Input "))
+	assert.Equal(t, SuggestCaseSensitive, ParseSuggestCase("sensitive"))
+	assert.Equal(t, SuggestCaseSuggestion, ParseSuggestCase("bogus"))
+}
+
+func TestViewPadsWideCharactersByWidth(t *testing.T) {
+	for _, tt := range []struct {
+		prompt string
+		value  string
+	}{
+		{prompt: "日本語> ", value: "ls"},
+		{prompt: "~/项目 ❯ ", value: "cat 文件.txt"},
+		{prompt: "> ", value: "echo 👨‍👩‍👧"},
+	} {
+		model := New()
+		model.Prompt = tt.prompt
+		model.Width = 40
+		model.Focus()
+		model.SetValue(tt.value)
+
+		// The line is padded out to exactly the width, so nothing after it shifts
+		assert.Equal(t, 40, textwidth.StringWidth(model.View()), "prompt %q, value %q", tt.prompt, tt.value)
+	}
+}
@@ -0,0 +1,47 @@
+package shellinput
+
+import "strings"
+
+// SuggestCase selects how the case of typed text is matched against suggestions
+type SuggestCase int
+
+const (
+	// SuggestCaseSuggestion matches case-insensitively and keeps the
+	// suggestion's own casing
+	SuggestCaseSuggestion SuggestCase = iota
+	// SuggestCaseInput matches case-insensitively but shows the matched prefix
+	// as it was typed, so accepting the suggestion keeps the user's casing
+	SuggestCaseInput
+	// SuggestCaseSensitive only matches suggestions with the same casing
+	SuggestCaseSensitive
+)
+
+// ParseSuggestCase returns the mode named by value, defaulting to suggestion
+func ParseSuggestCase(value string) SuggestCase {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "input":
+		return SuggestCaseInput
+	case "sensitive":
+		return SuggestCaseSensitive
+	default:
+		return SuggestCaseSuggestion
+	}
+}
+
+// matchSuggestion returns the suggestion to show for value, or false if
+// suggestion doesn't start with value under the given mode
+func matchSuggestion(value []rune, suggestion string, mode SuggestCase) ([]rune, bool) {
+	typed := string(value)
+	if mode == SuggestCaseSensitive {
+		return []rune(suggestion), strings.HasPrefix(suggestion, typed)
+	}
+	if !strings.HasPrefix(strings.ToLower(suggestion), strings.ToLower(typed)) {
+		return nil, false
+	}
+
+	runes := []rune(suggestion)
+	if mode == SuggestCaseInput && len(runes) >= len(value) {
+		return cloneConcatRunes(value, runes[len(value):]), true
+	}
+	return runes, true
+}
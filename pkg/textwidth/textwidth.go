@@ -0,0 +1,120 @@
+// Package textwidth measures how many terminal columns text takes up. The
+// prompt, the input line, completions and the assistant box all measure text
+// with it, so they agree on where the cursor is.
+package textwidth
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// StringWidth returns the number of columns s takes up in the terminal.
+// ANSI escape sequences take none. A grapheme cluster, such as a letter with
+// combining marks or an emoji ZWJ sequence like 👨‍👩‍👧, is drawn as a single
+// glyph as wide as its first rune.
+func StringWidth(s string) int {
+	width := 0
+	ForEachSegment(s, func(segment string, segmentWidth int) bool {
+		width += segmentWidth
+		return true
+	})
+	return width
+}
+
+// Truncate cuts s down to at most maxWidth columns, keeping the ANSI escape
+// sequences before the cut and never splitting a grapheme cluster
+func Truncate(s string, maxWidth int) string {
+	if maxWidth <= 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	width := 0
+	ForEachSegment(s, func(segment string, segmentWidth int) bool {
+		if width+segmentWidth > maxWidth {
+			return false
+		}
+		sb.WriteString(segment)
+		width += segmentWidth
+		return true
+	})
+	return sb.String()
+}
+
+// Wrap breaks s into lines of at most width columns, wherever the limit falls
+// rather than between words. Existing line breaks are kept.
+func Wrap(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	lineWidth := 0
+	ForEachSegment(s, func(segment string, segmentWidth int) bool {
+		if segment == "\n" || segment == "\r\n" {
+			sb.WriteString(segment)
+			lineWidth = 0
+			return true
+		}
+		if lineWidth+segmentWidth > width && lineWidth > 0 {
+			sb.WriteByte('\n')
+			lineWidth = 0
+		}
+		sb.WriteString(segment)
+		lineWidth += segmentWidth
+		return true
+	})
+	return sb.String()
+}
+
+// ClusterWidth returns the width of a single grapheme cluster
+func ClusterWidth(cluster string) int {
+	if cluster == "" {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(cluster)
+	return RuneWidth(r)
+}
+
+// ForEachSegment calls fn with each ANSI escape sequence, which has no width,
+// and each grapheme cluster of s in order, until fn returns false. It's for
+// callers that lay out text themselves, such as word wrapping.
+func ForEachSegment(s string, fn func(segment string, width int) bool) {
+	state := -1
+	for len(s) > 0 {
+		if s[0] == '\x1b' {
+			end := escapeEnd(s)
+			if !fn(s[:end], 0) {
+				return
+			}
+			s = s[end:]
+			state = -1
+			continue
+		}
+
+		var cluster string
+		cluster, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+		// A cluster never includes an escape sequence that follows it
+		if i := strings.IndexByte(cluster, '\x1b'); i > 0 {
+			s = cluster[i:] + s
+			cluster = cluster[:i]
+			state = -1
+		}
+		if !fn(cluster, ClusterWidth(cluster)) {
+			return
+		}
+	}
+}
+
+// escapeEnd returns the length of the escape sequence at the start of s,
+// which ends with the first letter after the escape character
+func escapeEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		if (s[i] >= 'a' && s[i] <= 'z') || (s[i] >= 'A' && s[i] <= 'Z') {
+			return i + 1
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,95 @@
+package textwidth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringWidthWithVariationSelector(t *testing.T) {
+	// The keyboard emoji with variation selector should be calculated correctly
+	// ⌨️ = U+2328 (KEYBOARD) + U+FE0F (VARIATION SELECTOR-16)
+	// The variation selector should have width 0
+	keyboard := "⌨️"
+
+	width := StringWidth(keyboard)
+
+	// Width should be 1 or 2 (depending on terminal), not 2 or 3
+	// In test environment (non-terminal), keyboard base char has width 1 per runewidth
+	// Variation selector has width 0
+	// So total should be 1
+	if width != 1 {
+		t.Errorf("StringWidth(%q) = %d, want 1 (in test environment)", keyboard, width)
+	}
+}
+
+func TestCoachTipWithEmojiAlignment(t *testing.T) {
+	// Simulate the coach tip scenario that was causing misalignment
+	// Line 1: "⌨️ Alias for exit" (keyboard emoji with variation selector)
+	// Line 2: "You use 'exit' often."
+	// Both lines should calculate width correctly for right-alignment
+
+	line1 := "⌨️ Alias for exit"
+	line2 := "You use 'exit' often."
+
+	width1 := StringWidth(line1)
+	width2 := StringWidth(line2)
+
+	// In test environment:
+	// Line 1: ⌨(1) + ️(0) + space(1) + "Alias for exit"(14) = 16
+	// Line 2: "You use 'exit' often." = 21
+	expectedWidth1 := 16
+	expectedWidth2 := 21
+
+	if width1 != expectedWidth1 {
+		t.Errorf("StringWidth(%q) = %d, want %d", line1, width1, expectedWidth1)
+	}
+	if width2 != expectedWidth2 {
+		t.Errorf("StringWidth(%q) = %d, want %d", line2, width2, expectedWidth2)
+	}
+}
+
+func TestStringWidthWideCharacters(t *testing.T) {
+	// Japanese and Chinese characters take two columns
+	assert.Equal(t, 8, StringWidth("日本語> "))
+	assert.Equal(t, 9, StringWidth("~/项目 ❯ "))
+	assert.Equal(t, 6, StringWidth("한국어"))
+	assert.Equal(t, 8, StringWidth("ｇｓｈ> "), "fullwidth letters")
+	assert.Equal(t, 8, StringWidth("\x1b[1;32m日本語\x1b[0m> "), "escape sequences take no space")
+}
+
+func TestStringWidthGraphemeClusters(t *testing.T) {
+	// Combining marks add nothing to the letter they're on
+	assert.Equal(t, 4, StringWidth("café"))
+	assert.Equal(t, 2, StringWidth("が"))
+
+	// Multi-codepoint emoji are one glyph as wide as their first rune, whatever
+	// the terminal makes that
+	emoji := RuneWidth('👨')
+	assert.Equal(t, emoji, StringWidth("👨‍👩‍👧"), "ZWJ sequence")
+	assert.Equal(t, emoji, StringWidth("👍🏽"), "skin tone modifier")
+	assert.Equal(t, emoji+3, StringWidth("👨‍👩‍👧 ok"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "", Truncate("abc", 0))
+	assert.Equal(t, "ab", Truncate("abc", 2))
+	assert.Equal(t, "abc", Truncate("abc", 10))
+
+	// Wide characters aren't cut in half
+	assert.Equal(t, "日本", Truncate("日本語", 5))
+	assert.Equal(t, 4, StringWidth(Truncate("日本語", 5)))
+
+	// Clusters stay whole, and escapes before the cut are kept
+	assert.Equal(t, "café", Truncate("cafés", 4))
+	assert.Equal(t, "👨‍👩‍👧", Truncate("👨‍👩‍👧👨‍👩‍👧", RuneWidth('👨')))
+	assert.Equal(t, "\x1b[31mre", Truncate("\x1b[31mred\x1b[0m", 2))
+}
+
+func TestWrap(t *testing.T) {
+	assert.Equal(t, "abc", Wrap("abc", 0))
+	assert.Equal(t, "abc\ndef\ng", Wrap("abcdefg", 3))
+	assert.Equal(t, "ab\ncd\nef", Wrap("ab\ncdef", 2))
+	assert.Equal(t, "日本\n語", Wrap("日本語", 5))
+	assert.Equal(t, "\x1b[31mab\nc\x1b[0m", Wrap("\x1b[31mabc\x1b[0m", 2))
+}